@@ -29,6 +29,8 @@ import (
 var (
 	configFile string
 	logLevel   string
+	// version of the build, reported by the OpenAPI document unless the config sets one
+	version string
 )
 
 var RootCmd = &cobra.Command{
@@ -57,6 +59,9 @@ var RootCmd = &cobra.Command{
 			fmt.Println(err)
 			return
 		}
+		if config.API.OpenAPI.Version == "" {
+			config.API.OpenAPI.Version = version
+		}
 		starHost := host.APIHost{}
 		err = starHost.Launch(config, []vf.IVendorFactory{
 			svf.SymphonyVendorFactory{},
@@ -73,6 +78,7 @@ var RootCmd = &cobra.Command{
 }
 
 func Execute(versiong string) {
+	version = versiong
 	fmt.Println(constants.EulaMessage)
 	fmt.Println()
 	if err := RootCmd.Execute(); err != nil {
@@ -78,10 +78,12 @@ func SystemReservedAnnotationsByPostfixes() []string {
 
 func SystemReservedLabels() []string {
 	return []string{
+		ActivationDepth,
 		CampaignVersion,
 		DisplayName,
 		ProviderName,
 		ManagerMetaKey,
+		ParentActivation,
 		ParentName,
 		RootResource,
 		SolutionVersion,
@@ -104,6 +106,8 @@ const (
 	RootResource       = "rootResource"
 	RootResourceUid    = "rootResourceUid"
 	ParentName         = "parentName"
+	ParentActivation   = "parentActivation"
+	ActivationDepth    = "activationDepth"
 	StatusMessage      = "statusMessage"
 	SolutionVersion           = "solutionversion"
 	SolutionVersionUid        = "solutionversionUid"
@@ -69,7 +69,7 @@ func (s *ActivationsCleanupManager) Poll() []error {
 	}
 	ret := []error{}
 	for _, activation := range activations {
-		if activation.Status.Status != v1alpha2.Done {
+		if activation.Status.Status != v1alpha2.Done && activation.Status.Status != v1alpha2.Cancelled {
 			continue
 		}
 		if activation.Status.UpdateTime == "" {
@@ -16,6 +16,7 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/watch"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -186,6 +187,25 @@ func (m *ActivationsManager) DeleteState(ctx context.Context, name string, names
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
 	log.InfofCtx(ctx, "Delete activation state %s in namespace %s", name, namespace)
+	// cancel the child activations started by the campaign stage provider along with their parent
+	var children []interface{}
+	children, err = states.ListObjectStateWithLabels(ctx, m.StateProvider, validation.Activation, namespace, map[string]string{constants.ParentActivation: name}, 0)
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to list child activations of activation %s in namespace %s: %v", name, namespace, err)
+		return err
+	}
+	for _, body := range children {
+		var child model.ActivationState
+		child, err = getActivationState(body)
+		if err != nil {
+			return err
+		}
+		log.InfofCtx(ctx, "Delete child activation %s of activation %s in namespace %s", child.ObjectMeta.Name, name, namespace)
+		err = m.DeleteState(ctx, child.ObjectMeta.Name, namespace)
+		if err != nil && !v1alpha2.IsNotFound(err) {
+			return err
+		}
+	}
 	err = m.StateProvider.Delete(ctx, states.DeleteRequest{
 		ID: name,
 		Metadata: map[string]interface{}{
@@ -200,6 +220,12 @@ func (m *ActivationsManager) DeleteState(ctx context.Context, name string, names
 }
 
 func (t *ActivationsManager) ListState(ctx context.Context, namespace string) ([]model.ActivationState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the activations, and returns the token of the next page
+func (t *ActivationsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.ActivationState, string, error) {
 	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
 		"method": "ListSpec",
 	})
@@ -217,24 +243,46 @@ func (t *ActivationsManager) ListState(ctx context.Context, namespace string) ([
 			"namespace": namespace,
 			"kind":      "Activation",
 		},
+		ListOptions: options,
 	}
 	var activations []states.StateEntry
-	activations, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	activations, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.ActivationState, 0)
 	for _, t := range activations {
 		var rt model.ActivationState
 		rt, err = getActivationState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
 	log.InfofCtx(ctx, "List activation state for namespace %s get total count %d", namespace, len(ret))
-	return ret, nil
+	return ret, next, nil
+}
+
+// WatchState streams the activations and their changes
+func (t *ActivationsManager) WatchState(ctx context.Context, namespace string) (<-chan model.WatchEvent, error) {
+	return watch.WatchStates(ctx, t.StateProvider, states.WatchRequest{
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.WorkflowGroup,
+			"resource":  "activations",
+			"namespace": namespace,
+			"kind":      "Activation",
+		},
+	}, func(entry states.StateEntry) (interface{}, error) {
+		state, err := getActivationState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		state.ObjectMeta.UpdateEtag(entry.ETag)
+		return state, nil
+	})
 }
 func (t *ActivationsManager) ReportStatus(ctx context.Context, name string, namespace string, current model.ActivationStatus) error {
 	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
@@ -302,6 +350,12 @@ func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string,
 		return err
 	}
 
+	if activationState.Status.Status == v1alpha2.Cancelled {
+		// stages that were running when the activation was cancelled report back after the fact
+		log.InfofCtx(ctx, "Activation %s in namespace %s is cancelled, discarding status of stage %s", name, namespace, current.Stage)
+		return nil
+	}
+
 	activationState.Status.UpdateTime = time.Now().Format(time.RFC3339) // TODO: is this correct? Shouldn't it be reported?
 
 	err = mergeStageStatus(ctx, &activationState, current)
@@ -336,9 +390,152 @@ func (t *ActivationsManager) ReportStageStatus(ctx context.Context, name string,
 		log.ErrorfCtx(ctx, "Failed to update status in state store for activation %s in namespace %s: %v", name, namespace, err)
 		return err
 	}
+	err = t.publishChildResult(ctx, activationState)
+	return err
+}
+
+// publishChildResult publishes the result of a finished activation that was started by another
+// activation, so that the stage waiting for it can resume
+func (t *ActivationsManager) publishChildResult(ctx context.Context, activationState model.ActivationState) error {
+	parent := activationState.ObjectMeta.Labels[constants.ParentActivation]
+	if parent == "" || !activationState.IsFinished() {
+		return nil
+	}
+	log.InfofCtx(ctx, "Child activation %s of activation %s in namespace %s finished as %s", activationState.ObjectMeta.Name, parent, activationState.ObjectMeta.Namespace, activationState.Status.StatusMessage)
+	err := t.Context.Publish("child-activation", v1alpha2.Event{
+		Body:    model.NewChildActivationResult(activationState),
+		Context: ctx,
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to publish result of child activation %s in namespace %s: %v", activationState.ObjectMeta.Name, activationState.ObjectMeta.Namespace, err)
+	}
+	return err
+}
+
+// ControlActivation cancels, pauses or resumes a started activation along with the child activations
+// it started. The stage vendor acts on running stages when it receives the activation-control event.
+func (t *ActivationsManager) ControlActivation(ctx context.Context, control model.ActivationControl) error {
+	ctx, span := observability.StartSpan("Activations Manager", ctx, &map[string]string{
+		"method": "ControlActivation",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "ControlActivation %s activation %s in namespace %s", control.Action, control.Activation, control.Namespace)
+
+	var activationState model.ActivationState
+	activationState, err = t.applyControl(ctx, control)
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to %s activation %s in namespace %s: %v", control.Action, control.Activation, control.Namespace, err)
+		return err
+	}
+	err = t.Context.Publish("activation-control", v1alpha2.Event{
+		Body:    control,
+		Context: ctx,
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to publish %s of activation %s in namespace %s: %v", control.Action, control.Activation, control.Namespace, err)
+		return err
+	}
+	err = t.publishChildResult(ctx, activationState)
+	if err != nil {
+		return err
+	}
+
+	var children []interface{}
+	children, err = states.ListObjectStateWithLabels(ctx, t.StateProvider, validation.Activation, control.Namespace, map[string]string{constants.ParentActivation: control.Activation}, 0)
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to list child activations of activation %s in namespace %s: %v", control.Activation, control.Namespace, err)
+		return err
+	}
+	for _, body := range children {
+		var child model.ActivationState
+		child, err = getActivationState(body)
+		if err != nil {
+			return err
+		}
+		childControl := control
+		childControl.Activation = child.ObjectMeta.Name
+		cErr := t.ControlActivation(ctx, childControl)
+		// children that have finished, or are already in the requested state, are left alone
+		if cErr != nil && !v1alpha2.IsNotFound(cErr) && v1alpha2.GetErrorState(cErr) != v1alpha2.Conflict {
+			err = cErr
+			return err
+		}
+	}
 	return nil
 }
 
+// applyControl records a cancel, pause or resume request in the status of an activation
+func (t *ActivationsManager) applyControl(ctx context.Context, control model.ActivationControl) (model.ActivationState, error) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	activationState, err := t.GetState(ctx, control.Activation, control.Namespace)
+	if err != nil {
+		return model.ActivationState{}, err
+	}
+	if activationState.IsFinished() {
+		return model.ActivationState{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s has already finished as %s", control.Activation, activationState.Status.Status.String()), v1alpha2.Conflict)
+	}
+
+	switch control.Action {
+	case model.ActivationCancel:
+		activationState.Status.PauseRequested = false
+		if len(activationState.Status.StageHistory) > 0 {
+			latest := &activationState.Status.StageHistory[len(activationState.Status.StageHistory)-1]
+			switch latest.Status {
+			case v1alpha2.Running, v1alpha2.Paused, v1alpha2.Untouched:
+				latest.NextStage = ""
+				latest.Status = v1alpha2.Cancelled
+				latest.StatusMessage = v1alpha2.Cancelled.String()
+				latest.ErrorMessage = fmt.Sprintf("activation is cancelled by '%s'", control.User)
+				latest.IsActive = false
+			}
+		}
+		activationState.Status.Status = v1alpha2.Cancelled
+		activationState.Status.StatusMessage = v1alpha2.Cancelled.String()
+	case model.ActivationPause:
+		if activationState.Status.PauseRequested {
+			return model.ActivationState{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is already paused", control.Activation), v1alpha2.Conflict)
+		}
+		activationState.Status.PauseRequested = true
+	case model.ActivationResume:
+		if !activationState.Status.PauseRequested {
+			return model.ActivationState{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is not paused", control.Activation), v1alpha2.Conflict)
+		}
+		activationState.Status.PauseRequested = false
+	default:
+		return model.ActivationState{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("action %s is not supported", control.Action), v1alpha2.BadRequest)
+	}
+
+	activationState.Status.UpdateTime = time.Now().Format(time.RFC3339)
+	if activationState.ObjectMeta.Labels == nil {
+		activationState.ObjectMeta.Labels = make(map[string]string)
+	}
+	activationState.ObjectMeta.Labels[constants.StatusMessage] = utils.ConvertStringToValidLabel(activationState.Status.Status.String())
+
+	_, err = t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   activationState.ObjectMeta.Name,
+			Body: activationState,
+			ETag: activationState.ObjectMeta.ETag,
+		},
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.WorkflowGroup,
+			"resource":  "activations",
+			"namespace": activationState.ObjectMeta.Namespace,
+			"kind":      "Activation",
+		},
+	})
+	if err != nil {
+		return model.ActivationState{}, err
+	}
+	return activationState, nil
+}
+
 func mergeStageStatus(ctx context.Context, activationState *model.ActivationState, current model.StageStatus) error {
 	if current.Outputs["__site"] == nil {
 		// The StageStatus is triggered locally
@@ -9,9 +9,13 @@ package activations
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -172,6 +176,96 @@ func TestUpdateStageStatusRemote(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func TestDeleteActivationDeletesChildren(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	ctx := context.Background()
+	err := manager.UpsertState(ctx, "parent", model.ActivationState{Spec: &model.ActivationSpec{}})
+	assert.Nil(t, err)
+	err = manager.UpsertState(ctx, "child", model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Labels: map[string]string{
+				constants.ParentActivation: "parent",
+			},
+		},
+		Spec: &model.ActivationSpec{},
+	})
+	assert.Nil(t, err)
+	err = manager.UpsertState(ctx, "other", model.ActivationState{Spec: &model.ActivationSpec{}})
+	assert.Nil(t, err)
+
+	err = manager.DeleteState(ctx, "parent", "default")
+	assert.Nil(t, err)
+	_, err = manager.GetState(ctx, "child", "default")
+	assert.True(t, v1alpha2.IsNotFound(err))
+	_, err = manager.GetState(ctx, "other", "default")
+	assert.Nil(t, err)
+}
+
+func TestReportStageStatusPublishesChildResult(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	pubSubProvider := &memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	manager.Context = &contexts.ManagerContext{}
+	manager.Context.Init(nil, pubSubProvider)
+	results := make(chan model.ChildActivationResult, 2)
+	pubSubProvider.Subscribe("child-activation", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			results <- event.Body.(model.ChildActivationResult)
+			return nil
+		},
+	})
+
+	ctx := context.Background()
+	err := manager.UpsertState(ctx, "child", model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Labels: map[string]string{
+				constants.ParentActivation: "parent",
+			},
+		},
+		Spec: &model.ActivationSpec{},
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(ctx, "child", "default", model.StageStatus{
+		Stage:         "drain",
+		Status:        v1alpha2.Running,
+		StatusMessage: v1alpha2.Running.String(),
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(ctx, "child", "default", model.StageStatus{
+		Stage:         "drain",
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+		Outputs: map[string]interface{}{
+			"drained":      true,
+			"__activation": "child",
+		},
+	})
+	assert.Nil(t, err)
+
+	select {
+	case result := <-results:
+		assert.Equal(t, "child", result.Activation)
+		assert.Equal(t, "parent", result.Parent)
+		assert.Equal(t, v1alpha2.Done, result.Status)
+		assert.Equal(t, map[string]interface{}{"drained": true}, result.Outputs)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "child activation result wasn't published")
+	}
+	select {
+	case <-results:
+		assert.Fail(t, "running child activation shouldn't publish a result")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 /*
 func TestCreateActivationWithMissingCampaignVersion(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
@@ -277,3 +371,83 @@ func TestUpdateActivationWithRunningStatus(t *testing.T) {
 	assert.Contains(t, err.Error(), "spec is immutable: stage doesn't match")
 }
 */
+
+func TestControlActivationCascadesToChildren(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	pubSubProvider := &memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager := ActivationsManager{
+		StateProvider: stateProvider,
+	}
+	manager.Context = &contexts.ManagerContext{}
+	manager.Context.Init(nil, pubSubProvider)
+	results := make(chan model.ChildActivationResult, 1)
+	pubSubProvider.Subscribe("child-activation", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			results <- event.Body.(model.ChildActivationResult)
+			return nil
+		},
+	})
+
+	ctx := context.Background()
+	running := model.StageStatus{
+		Stage:         "deploy",
+		Status:        v1alpha2.Running,
+		StatusMessage: v1alpha2.Running.String(),
+	}
+	err := manager.UpsertState(ctx, "parent", model.ActivationState{Spec: &model.ActivationSpec{}})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(ctx, "parent", "default", running)
+	assert.Nil(t, err)
+	err = manager.UpsertState(ctx, "child", model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Labels: map[string]string{
+				constants.ParentActivation: "parent",
+			},
+		},
+		Spec: &model.ActivationSpec{},
+	})
+	assert.Nil(t, err)
+	err = manager.ReportStageStatus(ctx, "child", "default", running)
+	assert.Nil(t, err)
+
+	err = manager.ControlActivation(ctx, model.ActivationControl{Activation: "parent", Namespace: "default", Action: model.ActivationPause})
+	assert.Nil(t, err)
+	child, err := manager.GetState(ctx, "child", "default")
+	assert.Nil(t, err)
+	assert.True(t, child.Status.PauseRequested)
+
+	err = manager.ControlActivation(ctx, model.ActivationControl{Activation: "parent", Namespace: "default", Action: model.ActivationCancel})
+	assert.Nil(t, err)
+	for _, name := range []string{"parent", "child"} {
+		state, err := manager.GetState(ctx, name, "default")
+		assert.Nil(t, err)
+		assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+		assert.False(t, state.Status.PauseRequested)
+		assert.Equal(t, v1alpha2.Cancelled, state.Status.StageHistory[0].Status)
+	}
+	select {
+	case result := <-results:
+		assert.Equal(t, "child", result.Activation)
+		assert.Equal(t, v1alpha2.Cancelled, result.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("child-activation event was not published")
+	}
+
+	// stages that were running when the activation was cancelled don't change its status
+	err = manager.ReportStageStatus(ctx, "parent", "default", model.StageStatus{
+		Stage:         "deploy",
+		NextStage:     "verify",
+		Status:        v1alpha2.Done,
+		StatusMessage: v1alpha2.Done.String(),
+	})
+	assert.Nil(t, err)
+	state, err := manager.GetState(ctx, "parent", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.Equal(t, "", state.Status.StageHistory[0].NextStage)
+
+	err = manager.ControlActivation(ctx, model.ActivationControl{Activation: "parent", Namespace: "default", Action: model.ActivationResume})
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+}
@@ -0,0 +1,336 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package activations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+const recurringActivationLabel = "recurringActivation"
+
+// RecurringActivationsManager stores recurring activations and creates a new activation
+// every time one of their cron schedules fires.
+type RecurringActivationsManager struct {
+	managers.Manager
+	StateProvider states.IStateProvider
+	// ActivationsManager is used to create the activations. The activations vendor wires it
+	// to the activations manager it serves so both share the same state.
+	ActivationsManager *ActivationsManager
+}
+
+func (s *RecurringActivationsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	stateprovider, err := managers.GetPersistentStateProvider(config, providers)
+	if err == nil {
+		s.StateProvider = stateprovider
+	} else {
+		return err
+	}
+	return nil
+}
+
+func (s *RecurringActivationsManager) Enabled() bool {
+	return s.ActivationsManager != nil
+}
+
+func (s *RecurringActivationsManager) Reconcil() []error {
+	return nil
+}
+
+func (m *RecurringActivationsManager) GetState(ctx context.Context, name string, namespace string) (model.RecurringActivationState, error) {
+	ctx, span := observability.StartSpan("Recurring Activations Manager", ctx, &map[string]string{
+		"method": "GetState",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "Get recurring activation state %s in namespace %s", name, namespace)
+
+	var entry states.StateEntry
+	entry, err = m.StateProvider.Get(ctx, states.GetRequest{
+		ID:       name,
+		Metadata: recurringActivationMetadata(namespace),
+	})
+	if err != nil {
+		return model.RecurringActivationState{}, err
+	}
+	var ret model.RecurringActivationState
+	ret, err = getRecurringActivationState(entry.Body)
+	if err != nil {
+		log.ErrorfCtx(ctx, "Failed to convert to recurring activation state for %s in namespace %s: %v", name, namespace, err)
+		return model.RecurringActivationState{}, err
+	}
+	ret.ObjectMeta.UpdateEtag(entry.ETag)
+	return ret, nil
+}
+
+func getRecurringActivationState(body interface{}) (model.RecurringActivationState, error) {
+	var state model.RecurringActivationState
+	bytes, _ := json.Marshal(body)
+	err := json.Unmarshal(bytes, &state)
+	if err != nil {
+		return model.RecurringActivationState{}, err
+	}
+	if state.Spec == nil {
+		state.Spec = &model.RecurringActivationSpec{}
+	}
+	if state.Status == nil {
+		state.Status = &model.RecurringActivationStatus{}
+	}
+	return state, nil
+}
+
+func recurringActivationMetadata(namespace string) map[string]interface{} {
+	return map[string]interface{}{
+		"version":   "v1",
+		"group":     model.WorkflowGroup,
+		"resource":  "recurringactivations",
+		"namespace": namespace,
+		"kind":      "RecurringActivation",
+	}
+}
+
+func (m *RecurringActivationsManager) UpsertState(ctx context.Context, name string, state model.RecurringActivationState) error {
+	ctx, span := observability.StartSpan("Recurring Activations Manager", ctx, &map[string]string{
+		"method": "UpsertState",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "Upsert recurring activation state %s in namespace %s", name, state.ObjectMeta.Namespace)
+
+	if state.ObjectMeta.Name != "" && state.ObjectMeta.Name != name {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("Name in metadata (%s) does not match name in request (%s)", state.ObjectMeta.Name, name), v1alpha2.BadRequest)
+		return err
+	}
+	state.ObjectMeta.FixNames(name)
+
+	if state.Spec == nil || state.Spec.CampaignVersion == "" {
+		err = v1alpha2.NewCOAError(nil, "recurring activation must specify a campaign version", v1alpha2.BadRequest)
+		return err
+	}
+	var next time.Time
+	next, err = state.Spec.NextScheduleTime(time.Now())
+	if err != nil {
+		err = v1alpha2.NewCOAError(err, fmt.Sprintf("invalid schedule for recurring activation %s", name), v1alpha2.BadRequest)
+		return err
+	}
+
+	// keep the firing history, but restart the schedule if the spec has changed
+	status := &model.RecurringActivationStatus{}
+	changed := true
+	oldState, getStateErr := m.GetState(ctx, state.ObjectMeta.Name, state.ObjectMeta.Namespace)
+	if getStateErr == nil {
+		state.ObjectMeta.PreserveSystemMetadata(oldState.ObjectMeta)
+		*status = *oldState.Status
+		equal, _ := state.Spec.DeepEquals(*oldState.Spec)
+		changed = !equal
+	}
+	if changed || status.NextScheduleTime == "" {
+		status.NextScheduleTime = next.UTC().Format(time.RFC3339)
+	}
+	state.Status = status
+
+	_, err = m.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: name,
+			Body: map[string]interface{}{
+				"apiVersion": model.WorkflowGroup + "/v1",
+				"kind":       "RecurringActivation",
+				"metadata":   state.ObjectMeta,
+				"spec":       state.Spec,
+				"status":     state.Status,
+			},
+			ETag: state.ObjectMeta.ETag,
+		},
+		Metadata: recurringActivationMetadata(state.ObjectMeta.Namespace),
+	})
+	return err
+}
+
+func (m *RecurringActivationsManager) DeleteState(ctx context.Context, name string, namespace string) error {
+	ctx, span := observability.StartSpan("Recurring Activations Manager", ctx, &map[string]string{
+		"method": "DeleteState",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "Delete recurring activation state %s in namespace %s", name, namespace)
+	err = m.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID:       name,
+		Metadata: recurringActivationMetadata(namespace),
+	})
+	return err
+}
+
+func (m *RecurringActivationsManager) ListState(ctx context.Context, namespace string) ([]model.RecurringActivationState, error) {
+	ret, _, err := m.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the recurring activations, and returns the token of the next page
+func (m *RecurringActivationsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.RecurringActivationState, string, error) {
+	ctx, span := observability.StartSpan("Recurring Activations Manager", ctx, &map[string]string{
+		"method": "ListState",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, "List recurring activation state for namespace %s", namespace)
+
+	var entries []states.StateEntry
+	var next string
+	entries, next, err = m.StateProvider.List(ctx, states.ListRequest{
+		Metadata:    recurringActivationMetadata(namespace),
+		ListOptions: options,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	ret := make([]model.RecurringActivationState, 0)
+	for _, entry := range entries {
+		var rt model.RecurringActivationState
+		rt, err = getRecurringActivationState(entry.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		rt.ObjectMeta.UpdateEtag(entry.ETag)
+		ret = append(ret, rt)
+	}
+	return ret, next, nil
+}
+
+// Poll creates an activation for every recurring activation whose next schedule time has passed.
+// Runs missed while the manager was down are not replayed; only the latest one fires.
+func (s *RecurringActivationsManager) Poll() []error {
+	ctx, span := observability.StartSpan("Recurring Activations Manager", context.Background(), &map[string]string{
+		"method": "Poll",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	var list []model.RecurringActivationState
+	list, err = s.ListState(ctx, "")
+	if err != nil {
+		return []error{err}
+	}
+	ret := []error{}
+	now := time.Now()
+	for _, recurring := range list {
+		if recurring.Spec.Suspend || recurring.Status.NextScheduleTime == "" {
+			continue
+		}
+		scheduled, parseErr := time.Parse(time.RFC3339, recurring.Status.NextScheduleTime)
+		if parseErr != nil {
+			log.ErrorfCtx(ctx, "M (Recurring Activations): cannot parse next schedule time of %s: %v", recurring.ObjectMeta.Name, parseErr)
+			ret = append(ret, parseErr)
+			continue
+		}
+		if scheduled.After(now) {
+			continue
+		}
+		if fireErr := s.fire(ctx, recurring, scheduled, now); fireErr != nil {
+			log.ErrorfCtx(ctx, "M (Recurring Activations): failed to create activation for %s: %v", recurring.ObjectMeta.Name, fireErr)
+			ret = append(ret, fireErr)
+		}
+	}
+	return ret
+}
+
+func (s *RecurringActivationsManager) fire(ctx context.Context, recurring model.RecurringActivationState, scheduled time.Time, now time.Time) error {
+	namespace := recurring.ObjectMeta.Namespace
+	// the activation name is derived from the schedule time, so a run is only created once
+	name := fmt.Sprintf("%s-%d", recurring.ObjectMeta.Name, scheduled.Unix())
+
+	if _, err := s.ActivationsManager.GetState(ctx, name, namespace); err != nil {
+		if !v1alpha2.IsNotFound(err) {
+			return err
+		}
+		log.InfofCtx(ctx, "M (Recurring Activations): creating activation %s for recurring activation %s", name, recurring.ObjectMeta.Name)
+		err = s.ActivationsManager.UpsertState(ctx, name, model.ActivationState{
+			ObjectMeta: model.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+				Labels: map[string]string{
+					recurringActivationLabel: recurring.ObjectMeta.Name,
+				},
+			},
+			Spec: &model.ActivationSpec{
+				CampaignVersion: recurring.Spec.CampaignVersion,
+				Stage:           recurring.Spec.Stage,
+				Inputs:          recurring.Spec.Inputs,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		entry, err := s.ActivationsManager.GetState(ctx, name, namespace)
+		if err != nil {
+			return err
+		}
+		if entry.Status.UpdateTime == "" && entry.ObjectMeta.Labels[constants.StatusMessage] == "" {
+			err = s.Context.Publish("activation", v1alpha2.Event{
+				Body: v1alpha2.ActivationData{
+					CampaignVersion:      recurring.Spec.CampaignVersion,
+					ActivationGeneration: entry.ObjectMeta.ETag,
+					Activation:           name,
+					Stage:                recurring.Spec.Stage,
+					Inputs:               recurring.Spec.Inputs,
+					Namespace:            namespace,
+				},
+				Context: ctx,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	next, err := recurring.Spec.NextScheduleTime(now)
+	if err != nil {
+		return err
+	}
+	recurring.Status.LastScheduleTime = scheduled.UTC().Format(time.RFC3339)
+	recurring.Status.NextScheduleTime = next.UTC().Format(time.RFC3339)
+	recurring.Status.LastActivation = name
+	recurring.Status.ActivationCount++
+	_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: recurring.ObjectMeta.Name,
+			Body: map[string]interface{}{
+				"apiVersion": model.WorkflowGroup + "/v1",
+				"kind":       "RecurringActivation",
+				"metadata":   recurring.ObjectMeta,
+				"spec":       recurring.Spec,
+				"status":     recurring.Status,
+			},
+			ETag: recurring.ObjectMeta.ETag,
+		},
+		Metadata: recurringActivationMetadata(namespace),
+	})
+	return err
+}
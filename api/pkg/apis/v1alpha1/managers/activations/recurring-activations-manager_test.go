@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package activations
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func createRecurringActivationsManager() RecurringActivationsManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	activationsStateProvider := &memorystate.MemoryStateProvider{}
+	activationsStateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := RecurringActivationsManager{
+		StateProvider: stateProvider,
+		ActivationsManager: &ActivationsManager{
+			StateProvider: activationsStateProvider,
+		},
+	}
+	manager.Context = &contexts.ManagerContext{}
+	return manager
+}
+
+func TestCreateGetDeleteRecurringActivation(t *testing.T) {
+	manager := createRecurringActivationsManager()
+	ctx := context.Background()
+
+	err := manager.UpsertState(ctx, "nightly", model.RecurringActivationState{
+		Spec: &model.RecurringActivationSpec{
+			CampaignVersion: "drift-check-v-v1",
+			Schedule:        "0 2 * * *",
+			TimeZone:        "Europe/Berlin",
+		},
+	})
+	assert.Nil(t, err)
+
+	state, err := manager.GetState(ctx, "nightly", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "nightly", state.ObjectMeta.Name)
+	next, err := time.Parse(time.RFC3339, state.Status.NextScheduleTime)
+	assert.Nil(t, err)
+	assert.True(t, next.After(time.Now()))
+
+	list, err := manager.ListState(ctx, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list))
+
+	err = manager.DeleteState(ctx, "nightly", "default")
+	assert.Nil(t, err)
+	_, err = manager.GetState(ctx, "nightly", "default")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestUpsertRecurringActivationWithInvalidSchedule(t *testing.T) {
+	manager := createRecurringActivationsManager()
+	ctx := context.Background()
+
+	for _, spec := range []model.RecurringActivationSpec{
+		{CampaignVersion: "campaign-v-v1", Schedule: "every night"},
+		{CampaignVersion: "campaign-v-v1", Schedule: "2024-01-01T00:00:00Z"},
+		{CampaignVersion: "campaign-v-v1", Schedule: "0 2 * * *", TimeZone: "Not/AZone"},
+		{Schedule: "0 2 * * *"},
+	} {
+		err := manager.UpsertState(ctx, "invalid", model.RecurringActivationState{Spec: &spec})
+		assert.NotNil(t, err)
+		assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err))
+	}
+}
+
+func TestPollCreatesActivationWhenScheduleIsDue(t *testing.T) {
+	manager := createRecurringActivationsManager()
+	ctx := context.Background()
+
+	err := manager.UpsertState(ctx, "nightly", model.RecurringActivationState{
+		Spec: &model.RecurringActivationSpec{
+			CampaignVersion: "drift-check-v-v1",
+			Stage:           "check",
+			Inputs:          map[string]interface{}{"site": "site1"},
+			Schedule:        "0 2 * * *",
+		},
+	})
+	assert.Nil(t, err)
+
+	// nothing is due right after creation
+	errs := manager.Poll()
+	assert.Empty(t, errs)
+	activations, err := manager.ActivationsManager.ListState(ctx, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(activations))
+
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager.Context.Init(nil, &pubSubProvider)
+	published := make(chan v1alpha2.ActivationData, 1)
+	manager.Context.Subscribe("activation", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var activation v1alpha2.ActivationData
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &activation)
+			assert.Nil(t, err)
+			published <- activation
+			return nil
+		},
+	})
+
+	due := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	setNextScheduleTime(t, manager, "nightly", due)
+
+	errs = manager.Poll()
+	assert.Empty(t, errs)
+	activations, err = manager.ActivationsManager.ListState(ctx, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(activations))
+	assert.Equal(t, "drift-check-v-v1", activations[0].Spec.CampaignVersion)
+	assert.Equal(t, "check", activations[0].Spec.Stage)
+	assert.Equal(t, "site1", activations[0].Spec.Inputs["site"])
+	assert.Equal(t, "nightly", activations[0].ObjectMeta.Labels[recurringActivationLabel])
+
+	select {
+	case activation := <-published:
+		assert.Equal(t, activations[0].ObjectMeta.Name, activation.Activation)
+		assert.Equal(t, "drift-check-v-v1", activation.CampaignVersion)
+	case <-time.After(5 * time.Second):
+		t.Fatal("activation event was not published")
+	}
+
+	state, err := manager.GetState(ctx, "nightly", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, activations[0].ObjectMeta.Name, state.Status.LastActivation)
+	assert.Equal(t, due.Format(time.RFC3339), state.Status.LastScheduleTime)
+	assert.Equal(t, 1, state.Status.ActivationCount)
+	next, err := time.Parse(time.RFC3339, state.Status.NextScheduleTime)
+	assert.Nil(t, err)
+	assert.True(t, next.After(time.Now()))
+
+	// a second poll doesn't create another activation for the same run
+	errs = manager.Poll()
+	assert.Empty(t, errs)
+	activations, err = manager.ActivationsManager.ListState(ctx, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(activations))
+}
+
+func TestPollSkipsSuspendedRecurringActivation(t *testing.T) {
+	manager := createRecurringActivationsManager()
+	ctx := context.Background()
+
+	err := manager.UpsertState(ctx, "weekly", model.RecurringActivationState{
+		Spec: &model.RecurringActivationSpec{
+			CampaignVersion: "patch-v-v1",
+			Schedule:        "0 22 * * sat",
+			Suspend:         true,
+		},
+	})
+	assert.Nil(t, err)
+	setNextScheduleTime(t, manager, "weekly", time.Now().Add(-time.Minute))
+
+	errs := manager.Poll()
+	assert.Empty(t, errs)
+	activations, err := manager.ActivationsManager.ListState(ctx, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(activations))
+}
+
+func setNextScheduleTime(t *testing.T, manager RecurringActivationsManager, name string, next time.Time) {
+	state, err := manager.GetState(context.Background(), name, "default")
+	assert.Nil(t, err)
+	state.Status.NextScheduleTime = next.UTC().Format(time.RFC3339)
+	_, err = manager.StateProvider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: name,
+			Body: map[string]interface{}{
+				"metadata": state.ObjectMeta,
+				"spec":     state.Spec,
+				"status":   state.Status,
+			},
+		},
+		Metadata: recurringActivationMetadata("default"),
+	})
+	assert.Nil(t, err)
+}
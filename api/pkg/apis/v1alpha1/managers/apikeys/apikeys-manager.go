@@ -0,0 +1,524 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// KeyPrefix starts every API key, so that they can be told apart from JWTs
+	KeyPrefix = "symk_"
+	// UserPrefix starts the user name of callers that use an API key
+	UserPrefix = "apikey:"
+	// TrailType is the type of the trails of changes to API keys
+	TrailType = "apikeys.symphony/v1"
+	// ApiKeys is the state resource of API keys
+	ApiKeys = "ApiKeys"
+
+	defaultLifetimeSeconds = 90 * 24 * 3600
+	secretLength           = 32
+	// the last use of a key is saved at most this often, so that a busy key doesn't write
+	// its state on every request
+	lastUsedInterval = time.Minute
+	// keys also have an ID prefix, for the state providers that don't keep resources apart
+	keyIDPrefix = "apikey."
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type ApiKeysManager struct {
+	managers.Manager
+	StateProvider states.IStateProvider
+	// DefaultLifetime is the lifetime of keys that are created without an expiry, or 0 for
+	// keys that don't expire
+	DefaultLifetime time.Duration
+	now             func() time.Time
+}
+
+type ApiKeyState struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Roles       []string   `json:"roles,omitempty"`
+	Namespaces  []string   `json:"namespaces,omitempty"`
+	SecretHash  string     `json:"secretHash"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CreatedBy   string     `json:"createdBy,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	RotatedAt   *time.Time `json:"rotatedAt,omitempty"`
+	Revoked     bool       `json:"revoked,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	// PreviousSecretHash is the hash of the secret before the last rotation, which is still
+	// accepted until PreviousExpiresAt
+	PreviousSecretHash string     `json:"previousSecretHash,omitempty"`
+	PreviousExpiresAt  *time.Time `json:"previousExpiresAt,omitempty"`
+}
+
+// ApiKey is an API key as it's returned by the API, without its secret
+type ApiKey struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Roles       []string   `json:"roles"`
+	Namespaces  []string   `json:"namespaces"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CreatedBy   string     `json:"createdBy,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	RotatedAt   *time.Time `json:"rotatedAt,omitempty"`
+	Revoked     bool       `json:"revoked"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+}
+
+// IssuedApiKey is an API key with its key, which is only returned when the key is created or
+// rotated
+type IssuedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
+
+// ApiKeySpec is the request to create an API key. Roles are the roles of the callers that
+// use the key, and Namespaces limits them to these namespaces. A key without namespaces can
+// be used in any namespace.
+type ApiKeySpec struct {
+	Description string     `json:"description,omitempty"`
+	Roles       []string   `json:"roles,omitempty"`
+	Namespaces  []string   `json:"namespaces,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// RotateRequest is the request to rotate an API key. The old key is still accepted for
+// GracePeriodSeconds, so that its users can switch to the new key.
+type RotateRequest struct {
+	GracePeriodSeconds int `json:"gracePeriodSeconds,omitempty"`
+}
+
+func (s *ApiKeysManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	// keys that are kept in a volatile state would be lost when Symphony restarts
+	stateprovider, err := managers.GetPersistentStateProvider(config, providers)
+	if err == nil {
+		s.StateProvider = stateprovider
+	} else {
+		log.Errorf(" M (ApiKeys): failed to get state provider %+v", err)
+		return err
+	}
+
+	lifetimeSeconds := defaultLifetimeSeconds
+	if v, ok := config.Properties["defaultLifetimeSeconds"]; ok && strings.TrimSpace(v) != "" {
+		lifetimeSeconds, err = strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || lifetimeSeconds < 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid defaultLifetimeSeconds '%s', expected a non-negative integer", v), v1alpha2.BadConfig)
+		}
+	}
+	s.DefaultLifetime = time.Duration(lifetimeSeconds) * time.Second
+	return nil
+}
+
+func (t *ApiKeysManager) currentTime() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now().UTC()
+}
+
+// CreateApiKey creates an API key, and returns it with its key. The key can't be read again
+// later, as only a hash of its secret is kept.
+func (t *ApiKeysManager) CreateApiKey(ctx context.Context, name string, spec ApiKeySpec, createdBy string) (IssuedApiKey, error) {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "CreateApiKey",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (ApiKeys): CreateApiKey name %s", name)
+
+	if !namePattern.MatchString(name) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid API key name '%s', expected lower case letters, digits and dashes", name), v1alpha2.BadRequest)
+		return IssuedApiKey{}, err
+	}
+	now := t.currentTime()
+	if spec.ExpiresAt != nil && !spec.ExpiresAt.After(now) {
+		err = v1alpha2.NewCOAError(nil, "expiresAt must be in the future", v1alpha2.BadRequest)
+		return IssuedApiKey{}, err
+	}
+	state := ApiKeyState{
+		Name:        name,
+		Description: spec.Description,
+		Roles:       spec.Roles,
+		Namespaces:  spec.Namespaces,
+		ExpiresAt:   spec.ExpiresAt,
+		CreatedAt:   now,
+		CreatedBy:   createdBy,
+	}
+	if state.ExpiresAt == nil && t.DefaultLifetime > 0 {
+		expiresAt := now.Add(t.DefaultLifetime)
+		state.ExpiresAt = &expiresAt
+	}
+	var secret string
+	secret, err = newSecret()
+	if err != nil {
+		return IssuedApiKey{}, err
+	}
+	state.SecretHash = hashSecret(secret)
+	// the empty ETag only creates the key if it doesn't exist yet
+	err = t.saveApiKey(ctx, state, "")
+	if err != nil {
+		if v1alpha2.GetErrorState(err) == v1alpha2.Conflict {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("API key '%s' already exists", name), v1alpha2.Conflict)
+		}
+		return IssuedApiKey{}, err
+	}
+	return IssuedApiKey{ApiKey: toApiKey(state), Key: KeyPrefix + name + "." + secret}, nil
+}
+
+// RotateApiKey replaces the secret of an API key, and returns the new key. The old key is
+// still accepted for the grace period.
+func (t *ApiKeysManager) RotateApiKey(ctx context.Context, name string, gracePeriod time.Duration) (IssuedApiKey, error) {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "RotateApiKey",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (ApiKeys): RotateApiKey name %s", name)
+
+	if gracePeriod < 0 {
+		err = v1alpha2.NewCOAError(nil, "grace period must not be negative", v1alpha2.BadRequest)
+		return IssuedApiKey{}, err
+	}
+	var state ApiKeyState
+	var etag string
+	state, etag, err = t.getApiKeyState(ctx, name)
+	if err != nil {
+		return IssuedApiKey{}, err
+	}
+	if state.Revoked {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("API key '%s' is revoked", name), v1alpha2.BadRequest)
+		return IssuedApiKey{}, err
+	}
+	var secret string
+	secret, err = newSecret()
+	if err != nil {
+		return IssuedApiKey{}, err
+	}
+	now := t.currentTime()
+	state.PreviousSecretHash = ""
+	state.PreviousExpiresAt = nil
+	if gracePeriod > 0 {
+		previousExpiresAt := now.Add(gracePeriod)
+		state.PreviousSecretHash = state.SecretHash
+		state.PreviousExpiresAt = &previousExpiresAt
+	}
+	state.SecretHash = hashSecret(secret)
+	state.RotatedAt = &now
+	err = t.saveApiKey(ctx, state, etag)
+	if err != nil {
+		return IssuedApiKey{}, err
+	}
+	return IssuedApiKey{ApiKey: toApiKey(state), Key: KeyPrefix + name + "." + secret}, nil
+}
+
+// RevokeApiKey revokes an API key. A revoked key is kept, so that it shows up when keys are
+// listed, until it's deleted.
+func (t *ApiKeysManager) RevokeApiKey(ctx context.Context, name string) (ApiKey, error) {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "RevokeApiKey",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (ApiKeys): RevokeApiKey name %s", name)
+
+	var state ApiKeyState
+	var etag string
+	state, etag, err = t.getApiKeyState(ctx, name)
+	if err != nil {
+		return ApiKey{}, err
+	}
+	if !state.Revoked {
+		now := t.currentTime()
+		state.Revoked = true
+		state.RevokedAt = &now
+		state.PreviousSecretHash = ""
+		state.PreviousExpiresAt = nil
+		err = t.saveApiKey(ctx, state, etag)
+		if err != nil {
+			return ApiKey{}, err
+		}
+	}
+	return toApiKey(state), nil
+}
+
+func (t *ApiKeysManager) DeleteApiKey(ctx context.Context, name string) error {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "DeleteApiKey",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (ApiKeys): DeleteApiKey name %s", name)
+
+	err = t.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID:       keyIDPrefix + name,
+		Metadata: stateMetadata(),
+	})
+	if err != nil {
+		log.DebugfCtx(ctx, " M (ApiKeys) : failed to delete API key %s", err)
+		return err
+	}
+	return nil
+}
+
+func (t *ApiKeysManager) GetApiKey(ctx context.Context, name string) (ApiKey, error) {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "GetApiKey",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugfCtx(ctx, " M (ApiKeys): GetApiKey name %s", name)
+
+	var state ApiKeyState
+	state, _, err = t.getApiKeyState(ctx, name)
+	if err != nil {
+		return ApiKey{}, err
+	}
+	return toApiKey(state), nil
+}
+
+// ListApiKeys returns the API keys sorted by name
+func (t *ApiKeysManager) ListApiKeys(ctx context.Context) ([]ApiKey, error) {
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "ListApiKeys",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugCtx(ctx, " M (ApiKeys): ListApiKeys")
+
+	var entries []states.StateEntry
+	entries, _, err = t.StateProvider.List(ctx, states.ListRequest{
+		Metadata: stateMetadata(),
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (ApiKeys): failed to list API keys, err: %v", err)
+		return nil, err
+	}
+	ret := make([]ApiKey, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.ID, keyIDPrefix) {
+			continue
+		}
+		var state ApiKeyState
+		state, err = getApiKeyState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, toApiKey(state))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// ValidateToken checks an API key that is used as a bearer token, and returns the caller it
+// stands for. It implements v1alpha2.ITokenValidator, so tokens that don't start with the key
+// prefix are left to the JWT middleware.
+func (t *ApiKeysManager) ValidateToken(ctx context.Context, token string) (v1alpha2.Principal, bool, error) {
+	if !strings.HasPrefix(token, KeyPrefix) {
+		return v1alpha2.Principal{}, false, nil
+	}
+	ctx, span := observability.StartSpan("ApiKeys Manager", ctx, &map[string]string{
+		"method": "ValidateToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	name, secret, ok := parseKey(token)
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, "API key is malformed", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	var state ApiKeyState
+	var etag string
+	state, etag, err = t.getApiKeyState(ctx, name)
+	if err != nil {
+		log.DebugfCtx(ctx, " M (ApiKeys) : failed to get API key %s, err: %v", name, err)
+		err = v1alpha2.NewCOAError(nil, "API key is not valid", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	now := t.currentTime()
+	if !matchSecret(state, secret, now) {
+		err = v1alpha2.NewCOAError(nil, "API key is not valid", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	if state.Revoked {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("API key '%s' is revoked", name), v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	if state.ExpiresAt != nil && !now.Before(*state.ExpiresAt) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("API key '%s' has expired", name), v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	if state.LastUsedAt == nil || now.Sub(*state.LastUsedAt) >= lastUsedInterval {
+		t.touch(ctx, state, etag, now)
+	}
+	return v1alpha2.Principal{
+		User:       UserPrefix + name,
+		Roles:      state.Roles,
+		Namespaces: state.Namespaces,
+	}, true, nil
+}
+
+// touch saves the last use of a key. It's only saved if the key hasn't changed since it was
+// read, so that a key that is rotated, revoked or deleted meanwhile isn't saved again.
+func (t *ApiKeysManager) touch(ctx context.Context, state ApiKeyState, etag string, now time.Time) {
+	state.LastUsedAt = &now
+	if err := t.saveApiKey(ctx, state, etag); err != nil && v1alpha2.GetErrorState(err) != v1alpha2.Conflict {
+		log.ErrorfCtx(ctx, " M (ApiKeys) : failed to save last use of API key %s, err: %v", state.Name, err)
+	}
+}
+
+// parseKey splits a key into the name of the key and its secret
+func parseKey(token string) (string, string, bool) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	name := token[len(KeyPrefix):i]
+	secret := token[i+1:]
+	if !namePattern.MatchString(name) || secret == "" {
+		return "", "", false
+	}
+	return name, secret, true
+}
+
+// matchSecret compares a secret with the current secret of a key, and with the secret before
+// the last rotation while its grace period lasts
+func matchSecret(state ApiKeyState, secret string, now time.Time) bool {
+	hash := []byte(hashSecret(secret))
+	if subtle.ConstantTimeCompare(hash, []byte(state.SecretHash)) == 1 {
+		return true
+	}
+	return state.PreviousSecretHash != "" && state.PreviousExpiresAt != nil && now.Before(*state.PreviousExpiresAt) &&
+		subtle.ConstantTimeCompare(hash, []byte(state.PreviousSecretHash)) == 1
+}
+
+func newSecret() (string, error) {
+	data := make([]byte, secretLength)
+	if _, err := rand.Read(data); err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to generate API key", v1alpha2.InternalError)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashSecret hashes the secret of a key. Secrets are random, so they don't need the slow
+// hashes of passwords.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// stateMetadata is the metadata of the state entries of keys, which are kept in the default
+// namespace, as their names are global
+func stateMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"namespace": constants.DefaultScope,
+		"group":     model.FabricGroup,
+		"version":   "v1",
+		"resource":  ApiKeys,
+	}
+}
+
+// getApiKeyState returns a key with its ETag
+func (t *ApiKeysManager) getApiKeyState(ctx context.Context, name string) (ApiKeyState, string, error) {
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID:       keyIDPrefix + name,
+		Metadata: stateMetadata(),
+	})
+	if err != nil {
+		return ApiKeyState{}, "", err
+	}
+	state, err := getApiKeyState(entry.Body)
+	return state, entry.ETag, err
+}
+
+// saveApiKey saves a key if it hasn't changed since it was read with the ETag, or, with an
+// empty ETag, if it doesn't exist yet. Updates of a key on any replica are kept apart this way.
+func (t *ApiKeysManager) saveApiKey(ctx context.Context, state ApiKeyState, etag string) error {
+	_, err := t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   keyIDPrefix + state.Name,
+			Body: state,
+		},
+		ETag:     &etag,
+		Metadata: stateMetadata(),
+	})
+	return err
+}
+
+func getApiKeyState(body interface{}) (ApiKeyState, error) {
+	var state ApiKeyState
+	bytes, _ := json.Marshal(body)
+	err := json.Unmarshal(bytes, &state)
+	if err != nil {
+		return ApiKeyState{}, v1alpha2.NewCOAError(err, "failed to read API key state", v1alpha2.InternalError)
+	}
+	return state, nil
+}
+
+func toApiKey(state ApiKeyState) ApiKey {
+	roles := state.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	namespaces := state.Namespaces
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+	return ApiKey{
+		Name:        state.Name,
+		Description: state.Description,
+		Roles:       roles,
+		Namespaces:  namespaces,
+		ExpiresAt:   state.ExpiresAt,
+		CreatedAt:   state.CreatedAt,
+		CreatedBy:   state.CreatedBy,
+		LastUsedAt:  state.LastUsedAt,
+		RotatedAt:   state.RotatedAt,
+		Revoked:     state.Revoked,
+		RevokedAt:   state.RevokedAt,
+	}
+}
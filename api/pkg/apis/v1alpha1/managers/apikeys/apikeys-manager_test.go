@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package apikeys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func initManager(t *testing.T, properties map[string]string) *ApiKeysManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &ApiKeysManager{}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	for k, v := range properties {
+		config.Properties[k] = v
+	}
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, config, providers)
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestInit(t *testing.T) {
+	manager := initManager(t, nil)
+	assert.Equal(t, 90*24*time.Hour, manager.DefaultLifetime)
+
+	manager = initManager(t, map[string]string{"defaultLifetimeSeconds": "0"})
+	assert.Equal(t, time.Duration(0), manager.DefaultLifetime)
+}
+
+func TestInitInvalidLifetime(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ApiKeysManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+			"defaultLifetimeSeconds":    "-1",
+		},
+	}, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestInitRequiresPersistentState(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := ApiKeysManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.NotNil(t, err)
+}
+
+func TestCreateAndValidate(t *testing.T) {
+	manager := initManager(t, nil)
+	key, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{
+		Description: "CI pipeline",
+		Roles:       []string{"operator"},
+		Namespaces:  []string{"ci"},
+	}, "admin")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(key.Key, "symk_ci."))
+	assert.Equal(t, "admin", key.CreatedBy)
+	assert.NotNil(t, key.ExpiresAt)
+
+	principal, ok, err := manager.ValidateToken(context.Background(), key.Key)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "apikey:ci", principal.User)
+	assert.Equal(t, []string{"operator"}, principal.Roles)
+	assert.Equal(t, []string{"ci"}, principal.Namespaces)
+
+	// the last use is saved
+	apiKey, err := manager.GetApiKey(context.Background(), "ci")
+	assert.Nil(t, err)
+	assert.NotNil(t, apiKey.LastUsedAt)
+
+	_, ok, err = manager.ValidateToken(context.Background(), key.Key+"x")
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+
+	_, ok, err = manager.ValidateToken(context.Background(), "symk_other."+strings.Split(key.Key, ".")[1])
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+
+	_, ok, err = manager.ValidateToken(context.Background(), "symk_")
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+
+	// JWTs are left to the JWT middleware
+	_, ok, err = manager.ValidateToken(context.Background(), "eyJhbGciOiJIUzI1NiJ9.e30.sig")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestCreateInvalid(t *testing.T) {
+	manager := initManager(t, nil)
+	_, err := manager.CreateApiKey(context.Background(), "Not_Valid", ApiKeySpec{}, "")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	past := time.Now().Add(-time.Hour)
+	_, err = manager.CreateApiKey(context.Background(), "past", ApiKeySpec{ExpiresAt: &past}, "")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	_, err = manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+	_, err = manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{}, "")
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+}
+
+func TestExpiry(t *testing.T) {
+	manager := initManager(t, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+	expiresAt := now.Add(time.Hour)
+	key, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{ExpiresAt: &expiresAt}, "")
+	assert.Nil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), key.Key)
+	assert.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	_, ok, err := manager.ValidateToken(context.Background(), key.Key)
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+}
+
+func TestNoDefaultLifetime(t *testing.T) {
+	manager := initManager(t, map[string]string{"defaultLifetimeSeconds": "0"})
+	key, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+	assert.Nil(t, key.ExpiresAt)
+}
+
+func TestRevoke(t *testing.T) {
+	manager := initManager(t, nil)
+	key, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+	apiKey, err := manager.RevokeApiKey(context.Background(), "ci")
+	assert.Nil(t, err)
+	assert.True(t, apiKey.Revoked)
+	assert.NotNil(t, apiKey.RevokedAt)
+
+	_, ok, err := manager.ValidateToken(context.Background(), key.Key)
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+
+	_, err = manager.RotateApiKey(context.Background(), "ci", 0)
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	_, err = manager.RevokeApiKey(context.Background(), "missing")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestRotate(t *testing.T) {
+	manager := initManager(t, nil)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+	oldKey, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+
+	newKey, err := manager.RotateApiKey(context.Background(), "ci", time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, oldKey.Key, newKey.Key)
+	assert.NotNil(t, newKey.RotatedAt)
+
+	// both keys work during the grace period
+	_, _, err = manager.ValidateToken(context.Background(), oldKey.Key)
+	assert.Nil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), newKey.Key)
+	assert.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	_, _, err = manager.ValidateToken(context.Background(), oldKey.Key)
+	assert.NotNil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), newKey.Key)
+	assert.Nil(t, err)
+
+	// without a grace period, the old key stops working right away
+	newerKey, err := manager.RotateApiKey(context.Background(), "ci", 0)
+	assert.Nil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), newKey.Key)
+	assert.NotNil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), newerKey.Key)
+	assert.Nil(t, err)
+}
+
+func TestListAndDelete(t *testing.T) {
+	manager := initManager(t, nil)
+	_, err := manager.CreateApiKey(context.Background(), "b", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+	key, err := manager.CreateApiKey(context.Background(), "a", ApiKeySpec{}, "")
+	assert.Nil(t, err)
+
+	keys, err := manager.ListApiKeys(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(keys))
+	assert.Equal(t, "a", keys[0].Name)
+	assert.Equal(t, "b", keys[1].Name)
+
+	err = manager.DeleteApiKey(context.Background(), "a")
+	assert.Nil(t, err)
+	_, _, err = manager.ValidateToken(context.Background(), key.Key)
+	assert.NotNil(t, err)
+	_, err = manager.GetApiKey(context.Background(), "a")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestTouchDoesNotUndoRevoke(t *testing.T) {
+	manager := initManager(t, nil)
+	key, err := manager.CreateApiKey(context.Background(), "ci", ApiKeySpec{Roles: []string{"operator"}}, "admin")
+	assert.Nil(t, err)
+	state, etag, err := manager.getApiKeyState(context.Background(), "ci")
+	assert.Nil(t, err)
+
+	// the key is revoked on another replica while it's validated here
+	_, err = manager.RevokeApiKey(context.Background(), "ci")
+	assert.Nil(t, err)
+	manager.touch(context.Background(), state, etag, time.Now().UTC())
+	_, _, err = manager.ValidateToken(context.Background(), key.Key)
+	assert.NotNil(t, err)
+	revoked, err := manager.GetApiKey(context.Background(), "ci")
+	assert.Nil(t, err)
+	assert.True(t, revoked.Revoked)
+	assert.Nil(t, revoked.LastUsedAt)
+}
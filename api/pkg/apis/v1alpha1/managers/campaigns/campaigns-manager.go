@@ -112,6 +112,12 @@ func (t *CampaignsManager) UpsertState(ctx context.Context, name string, state m
 }
 
 func (t *CampaignsManager) ListState(ctx context.Context, namespace string) ([]model.CampaignState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the campaigns, and returns the token of the next page
+func (t *CampaignsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.CampaignState, string, error) {
 	ctx, span := observability.StartSpan("CampaignsManager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -127,23 +133,25 @@ func (t *CampaignsManager) ListState(ctx context.Context, namespace string) ([]m
 			"namespace": namespace,
 			"kind":      "Campaign",
 		},
+		ListOptions: options,
 	}
 	var campaigns []states.StateEntry
-	campaigns, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	campaigns, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.CampaignState, 0)
 	for _, t := range campaigns {
 		var rt model.CampaignState
 		rt, err = getCampaignState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getCampaignState(body interface{}) (model.CampaignState, error) {
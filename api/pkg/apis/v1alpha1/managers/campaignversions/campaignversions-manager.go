@@ -47,8 +47,8 @@ func (s *CampaignVersionsManager) Init(context *contexts.VendorContext, config m
 	s.needValidate = managers.NeedObjectValidate(config, providers)
 	if s.needValidate {
 		// Turn off validation of differnt types: https://github.com/eclipse-symphony/symphony/issues/445
-		//s.CampaignVersionValidator = validation.NewCampaignVersionValidator(s.CampaignLookup, s.CampaignVersionActivationsLookup)
-		s.CampaignVersionValidator = validation.NewCampaignVersionValidator(nil, nil)
+		//s.CampaignVersionValidator = validation.NewCampaignVersionValidator(s.CampaignLookup, s.CampaignVersionActivationsLookup, s.CampaignVersionLookup)
+		s.CampaignVersionValidator = validation.NewCampaignVersionValidator(nil, nil, s.CampaignVersionLookup)
 	}
 	return nil
 }
@@ -186,6 +186,12 @@ func (m *CampaignVersionsManager) DeleteState(ctx context.Context, name string,
 }
 
 func (t *CampaignVersionsManager) ListState(ctx context.Context, namespace string) ([]model.CampaignVersionState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the campaign versions, and returns the token of the next page
+func (t *CampaignVersionsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.CampaignVersionState, string, error) {
 	ctx, span := observability.StartSpan("CampaignVersions Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -201,24 +207,26 @@ func (t *CampaignVersionsManager) ListState(ctx context.Context, namespace strin
 			"namespace": namespace,
 			"kind":      "CampaignVersion",
 		},
+		ListOptions: options,
 	}
 	var campaignversions []states.StateEntry
-	campaignversions, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	campaignversions, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.CampaignVersionState, 0)
 	for _, t := range campaignversions {
 		var rt model.CampaignVersionState
 		rt, err = getCampaignVersionState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
 	log.InfofCtx(ctx, "List campaignversion state for namespace %s get total count %d", namespace, len(ret))
-	return ret, nil
+	return ret, next, nil
 }
 
 func (t *CampaignVersionsManager) ValidateDelete(ctx context.Context, name string, namespace string) error {
@@ -230,6 +238,10 @@ func (t *CampaignVersionsManager) CampaignLookup(ctx context.Context, name strin
 	return states.GetObjectState(ctx, t.StateProvider, validation.Campaign, name, namespace)
 }
 
+func (t *CampaignVersionsManager) CampaignVersionLookup(ctx context.Context, name string, namespace string) (interface{}, error) {
+	return states.GetObjectState(ctx, t.StateProvider, validation.CampaignVersion, name, namespace)
+}
+
 func (t *CampaignVersionsManager) CampaignVersionActivationsLookup(ctx context.Context, name string, namespace string) (bool, error) {
 	activationList, err := states.ListObjectStateWithLabels(ctx, t.StateProvider, validation.Activation, namespace, map[string]string{constants.CampaignVersion: name, constants.StatusMessage: v1alpha2.Running.String()}, 1)
 	if err != nil {
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,6 +36,65 @@ func TestCreateGetDeleteCampaignVersionSpec(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func campaignStageCampaignVersion(rootResource string, invoked string) model.CampaignVersionState {
+	return model.CampaignVersionState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      rootResource + "-v-v1",
+			Namespace: "default",
+		},
+		Spec: &model.CampaignVersionSpec{
+			RootResource: rootResource,
+			FirstStage:   "invoke",
+			Stages: map[string]model.StageSpec{
+				"invoke": {
+					Name:     "invoke",
+					Provider: "providers.stage.campaign",
+					Inputs: map[string]interface{}{
+						"campaignVersion": invoked,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCreateCampaignVersionWithRecursiveCampaignStage(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := CampaignVersionsManager{
+		StateProvider: stateProvider,
+		needValidate:  true,
+	}
+	manager.CampaignVersionValidator = validation.NewCampaignVersionValidator(nil, nil, manager.CampaignVersionLookup)
+
+	err := manager.UpsertState(context.Background(), "verify-v-v1", model.CampaignVersionState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "verify-v-v1",
+			Namespace: "default",
+		},
+		Spec: &model.CampaignVersionSpec{
+			RootResource: "verify",
+		},
+	})
+	assert.Nil(t, err)
+	err = manager.UpsertState(context.Background(), "drain-v-v1", campaignStageCampaignVersion("drain", "verify:v1"))
+	assert.Nil(t, err)
+	err = manager.UpsertState(context.Background(), "self-v-v1", campaignStageCampaignVersion("self", "self:v1"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "campaign stage invokes the campaignversion recursively")
+
+	// upgrade -> drain -> upgrade
+	err = manager.UpsertState(context.Background(), "upgrade-v-v1", campaignStageCampaignVersion("upgrade", "drain:v1"))
+	assert.Nil(t, err)
+	err = manager.UpsertState(context.Background(), "drain-v-v1", campaignStageCampaignVersion("drain", "upgrade:v1"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "campaign stage invokes the campaignversion recursively")
+
+	// expressions are resolved at runtime and aren't followed
+	err = manager.UpsertState(context.Background(), "drain-v-v1", campaignStageCampaignVersion("drain", "${{$input(target)}}"))
+	assert.Nil(t, err)
+}
+
 /*
 func TestCreateCampaignVersionWithMissingContainer(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
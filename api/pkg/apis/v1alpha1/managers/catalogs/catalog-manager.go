@@ -112,6 +112,12 @@ func (t *CatalogsManager) UpsertState(ctx context.Context, name string, state mo
 }
 
 func (t *CatalogsManager) ListState(ctx context.Context, namespace string) ([]model.CatalogState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the catalogs, and returns the token of the next page
+func (t *CatalogsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.CatalogState, string, error) {
 	ctx, span := observability.StartSpan("CatalogsManager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -127,23 +133,25 @@ func (t *CatalogsManager) ListState(ctx context.Context, namespace string) ([]mo
 			"namespace": namespace,
 			"kind":      "Catalog",
 		},
+		ListOptions: options,
 	}
 	var catalogs []states.StateEntry
-	catalogs, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	catalogs, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.CatalogState, 0)
 	for _, t := range catalogs {
 		var rt model.CatalogState
 		rt, err = getCatalogState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getCatalogState(body interface{}) (model.CatalogState, error) {
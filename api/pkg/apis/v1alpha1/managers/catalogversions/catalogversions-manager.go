@@ -14,6 +14,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/watch"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -208,6 +209,12 @@ func (m *CatalogVersionsManager) DeleteState(ctx context.Context, name string, n
 }
 
 func (t *CatalogVersionsManager) ListState(ctx context.Context, namespace string, filterType string, filterValue string) ([]model.CatalogVersionState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, filterType, filterValue, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the catalog versions, and returns the token of the next page
+func (t *CatalogVersionsManager) ListStatePage(ctx context.Context, namespace string, filterType string, filterValue string, options states.ListOptions) ([]model.CatalogVersionState, string, error) {
 	ctx, span := observability.StartSpan("CatalogVersions Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -223,25 +230,49 @@ func (t *CatalogVersionsManager) ListState(ctx context.Context, namespace string
 			"namespace": namespace,
 			"kind":      "CatalogVersion",
 		},
+		ListOptions: options,
 	}
 	listRequest.FilterType = filterType
 	listRequest.FilterValue = filterValue
 	var catalogversions []states.StateEntry
-	catalogversions, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	catalogversions, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.CatalogVersionState, 0)
 	for _, t := range catalogversions {
 		var rt model.CatalogVersionState
 		rt, err = getCatalogVersionState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
+}
+
+// WatchState streams the catalog versions and their changes
+func (t *CatalogVersionsManager) WatchState(ctx context.Context, namespace string, filterType string, filterValue string) (<-chan model.WatchEvent, error) {
+	return watch.WatchStates(ctx, t.StateProvider, states.WatchRequest{
+		FilterType:  filterType,
+		FilterValue: filterValue,
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.FederationGroup,
+			"resource":  "catalogversions",
+			"namespace": namespace,
+			"kind":      "CatalogVersion",
+		},
+	}, func(entry states.StateEntry) (interface{}, error) {
+		state, err := getCatalogVersionState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		state.ObjectMeta.UpdateEtag(entry.ETag)
+		return state, nil
+	})
 }
 func (g *CatalogVersionsManager) setProviderDataIfNecessary(ctx context.Context, namespace string) error {
 	if !g.GraphProvider.IsPure() {
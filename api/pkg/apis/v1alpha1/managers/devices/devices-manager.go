@@ -118,6 +118,12 @@ func (t *DevicesManager) UpsertState(ctx context.Context, name string, state mod
 }
 
 func (t *DevicesManager) ListState(ctx context.Context, namespace string) ([]model.DeviceState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the devices, and returns the token of the next page
+func (t *DevicesManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.DeviceState, string, error) {
 	ctx, span := observability.StartSpan("Devices Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -134,12 +140,14 @@ func (t *DevicesManager) ListState(ctx context.Context, namespace string) ([]mod
 			"kind":      "Device",
 			"namespace": namespace,
 		},
+		ListOptions: options,
 	}
 	var devices []states.StateEntry
-	devices, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	devices, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
 		log.ErrorfCtx(ctx, " M (Devices): failed to list state, error: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.DeviceState, 0)
 	for _, t := range devices {
@@ -147,12 +155,12 @@ func (t *DevicesManager) ListState(ctx context.Context, namespace string) ([]mod
 		rt, err = getDeviceState(t.Body)
 		if err != nil {
 			log.ErrorfCtx(ctx, " M (Devices): ListState failed to get device state %s, error: %v", t.ID, err)
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getDeviceState(body interface{}) (model.DeviceState, error) {
@@ -0,0 +1,746 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package enrollment
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// JoinTokenPrefix starts every join token
+	JoinTokenPrefix = "symj_"
+	// TokenPrefix starts the tokens of enrolled targets, so that they can be told apart from
+	// JWTs and API keys
+	TokenPrefix = "symt_"
+	// UserPrefix starts the user name of enrolled targets, which is followed by
+	// "<namespace>/<target>"
+	UserPrefix = "target:"
+	// TargetRole is the role of enrolled targets
+	TargetRole = "target"
+	// TrailType is the type of the trails of join tokens and enrollments
+	TrailType = "enrollment.symphony/v1"
+
+	defaultJoinTokenLifetimeSeconds = 24 * 3600
+	secretLength                    = 32
+
+	// JoinTokens and Credentials are the state resources of join tokens and of the credentials
+	// of enrolled targets
+	JoinTokens  = "jointokens"
+	Credentials = "credentials"
+
+	// join tokens and credentials also have an ID prefix, for the state providers that don't
+	// keep resources apart
+	joinTokenIDPrefix  = "jointoken."
+	credentialIDPrefix = "credential."
+)
+
+// resourceKinds are the kinds of the objects of the state resources
+var resourceKinds = map[string]string{
+	JoinTokens:  "JoinToken",
+	Credentials: "Credential",
+}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type EnrollmentManager struct {
+	managers.Manager
+	StateProvider states.IStateProvider
+	// JoinTokenLifetime is the lifetime of join tokens that are created without an expiry
+	JoinTokenLifetime time.Duration
+	// TokenLifetime is the lifetime of the tokens of targets, or 0 for tokens that don't
+	// expire
+	TokenLifetime time.Duration
+	// TargetsRoute and QueueRoute are the routes of the targets vendor and of the queue of
+	// the solution vendor, which enrolled targets may call for themselves
+	TargetsRoute string
+	QueueRoute   string
+	now          func() time.Time
+}
+
+type JoinTokenState struct {
+	Name       string            `json:"name"`
+	Namespace  string            `json:"namespace"`
+	Target     string            `json:"target,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	SecretHash string            `json:"secretHash"`
+	ExpiresAt  time.Time         `json:"expiresAt"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	CreatedBy  string            `json:"createdBy,omitempty"`
+	UsedAt     *time.Time        `json:"usedAt,omitempty"`
+	UsedBy     string            `json:"usedBy,omitempty"`
+}
+
+// JoinToken is a join token as it's returned by the API, without its secret
+type JoinToken struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Target    string            `json:"target,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	CreatedAt time.Time         `json:"createdAt"`
+	CreatedBy string            `json:"createdBy,omitempty"`
+	UsedAt    *time.Time        `json:"usedAt,omitempty"`
+	UsedBy    string            `json:"usedBy,omitempty"`
+}
+
+// IssuedJoinToken is a join token with its token, which is only returned when it's created
+type IssuedJoinToken struct {
+	JoinToken
+	Token string `json:"token"`
+}
+
+// JoinTokenSpec is the request to create a join token. The token enrolls the target with the
+// name Target, or any target with all the Labels, in the namespace.
+type JoinTokenSpec struct {
+	Namespace string            `json:"namespace,omitempty"`
+	Target    string            `json:"target,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	ExpiresAt *time.Time        `json:"expiresAt,omitempty"`
+}
+
+// EnrollRequest is the request of an agent to trade a join token for the token of its target
+type EnrollRequest struct {
+	JoinToken string `json:"joinToken"`
+	// Target is the name of the target. It can be left out if the join token is bound to a
+	// target name.
+	Target string `json:"target,omitempty"`
+}
+
+type CredentialState struct {
+	Target     string     `json:"target"`
+	Namespace  string     `json:"namespace"`
+	SecretHash string     `json:"secretHash"`
+	JoinToken  string     `json:"joinToken"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Credential is the credential of an enrolled target as it's returned by the API
+type Credential struct {
+	Target    string     `json:"target"`
+	Namespace string     `json:"namespace"`
+	JoinToken string     `json:"joinToken"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// IssuedCredential is returned to the agent of a target that enrolls. AccessToken is the bearer
+// token of the target, which is only returned once.
+type IssuedCredential struct {
+	AccessToken string     `json:"accessToken"`
+	TokenType   string     `json:"tokenType"`
+	Target      string     `json:"target"`
+	Namespace   string     `json:"namespace"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+func (s *EnrollmentManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	// credentials that are kept in a volatile state would be lost when Symphony restarts
+	stateprovider, err := managers.GetPersistentStateProvider(config, providers)
+	if err == nil {
+		s.StateProvider = stateprovider
+	} else {
+		log.Errorf(" M (Enrollment): failed to get state provider %+v", err)
+		return err
+	}
+	// join tokens are used once and created once only if concurrent writes are kept apart
+	if !states.SupportsConditionalUpsert(stateprovider) {
+		log.Errorf(" M (Enrollment): state provider doesn't support conditional upserts")
+		return v1alpha2.NewCOAError(nil, "enrollment requires a persistent state provider that supports conditional upserts", v1alpha2.BadConfig)
+	}
+
+	joinTokenLifetime, err := readSeconds(config.Properties, "joinTokenLifetimeSeconds", defaultJoinTokenLifetimeSeconds)
+	if err != nil {
+		return err
+	}
+	if joinTokenLifetime == 0 {
+		return v1alpha2.NewCOAError(nil, "joinTokenLifetimeSeconds must be positive, join tokens must expire", v1alpha2.BadConfig)
+	}
+	s.JoinTokenLifetime = joinTokenLifetime
+	s.TokenLifetime, err = readSeconds(config.Properties, "tokenLifetimeSeconds", 0)
+	if err != nil {
+		return err
+	}
+	s.TargetsRoute = "targets"
+	if v, ok := config.Properties["targetsRoute"]; ok && strings.TrimSpace(v) != "" {
+		s.TargetsRoute = strings.Trim(strings.TrimSpace(v), "/")
+	}
+	s.QueueRoute = "solutionversion/queue"
+	if v, ok := config.Properties["queueRoute"]; ok && strings.TrimSpace(v) != "" {
+		s.QueueRoute = strings.Trim(strings.TrimSpace(v), "/")
+	}
+	return nil
+}
+
+func readSeconds(properties map[string]string, key string, defaultSeconds int) (time.Duration, error) {
+	seconds := defaultSeconds
+	if v, ok := properties[key]; ok && strings.TrimSpace(v) != "" {
+		var err error
+		seconds, err = strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || seconds < 0 {
+			return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid %s '%s', expected a non-negative integer", key, v), v1alpha2.BadConfig)
+		}
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (t *EnrollmentManager) currentTime() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now().UTC()
+}
+
+// CreateJoinToken creates a join token, and returns it with its token. A name is generated if
+// it's empty.
+func (t *EnrollmentManager) CreateJoinToken(ctx context.Context, name string, spec JoinTokenSpec, createdBy string) (IssuedJoinToken, error) {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "CreateJoinToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Enrollment): CreateJoinToken name %s, target %s", name, spec.Target)
+
+	if name == "" {
+		id := make([]byte, 6)
+		if _, err = rand.Read(id); err != nil {
+			err = v1alpha2.NewCOAError(err, "failed to generate join token name", v1alpha2.InternalError)
+			return IssuedJoinToken{}, err
+		}
+		name = "join-" + hex.EncodeToString(id)
+	}
+	if !namePattern.MatchString(name) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid join token name '%s', expected lower case letters, digits and dashes", name), v1alpha2.BadRequest)
+		return IssuedJoinToken{}, err
+	}
+	if spec.Target == "" && len(spec.Labels) == 0 {
+		err = v1alpha2.NewCOAError(nil, "a join token must be bound to a target or to labels", v1alpha2.BadRequest)
+		return IssuedJoinToken{}, err
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = "default"
+	}
+	now := t.currentTime()
+	expiresAt := now.Add(t.JoinTokenLifetime)
+	if spec.ExpiresAt != nil {
+		if !spec.ExpiresAt.After(now) {
+			err = v1alpha2.NewCOAError(nil, "expiresAt must be in the future", v1alpha2.BadRequest)
+			return IssuedJoinToken{}, err
+		}
+		expiresAt = *spec.ExpiresAt
+	}
+	var secret string
+	secret, err = newSecret(secretLength)
+	if err != nil {
+		return IssuedJoinToken{}, err
+	}
+	state := JoinTokenState{
+		Name:       name,
+		Namespace:  spec.Namespace,
+		Target:     spec.Target,
+		Labels:     spec.Labels,
+		SecretHash: hashSecret(secret),
+		ExpiresAt:  expiresAt,
+		CreatedAt:  now,
+		CreatedBy:  createdBy,
+	}
+	// the empty ETag only creates the join token if it doesn't exist yet
+	created := ""
+	err = t.upsert(ctx, JoinTokens, constants.DefaultScope, joinTokenIDPrefix+name, state, &created)
+	if err != nil {
+		if v1alpha2.GetErrorState(err) == v1alpha2.Conflict {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' already exists", name), v1alpha2.Conflict)
+		}
+		return IssuedJoinToken{}, err
+	}
+	return IssuedJoinToken{JoinToken: toJoinToken(state), Token: JoinTokenPrefix + name + "." + secret}, nil
+}
+
+func (t *EnrollmentManager) GetJoinToken(ctx context.Context, name string) (JoinToken, error) {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "GetJoinToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugfCtx(ctx, " M (Enrollment): GetJoinToken name %s", name)
+
+	var state JoinTokenState
+	state, _, err = t.getJoinTokenState(ctx, name)
+	if err != nil {
+		return JoinToken{}, err
+	}
+	return toJoinToken(state), nil
+}
+
+// ListJoinTokens returns the join tokens sorted by name, including the ones that are used or
+// expired until they're deleted
+func (t *EnrollmentManager) ListJoinTokens(ctx context.Context) ([]JoinToken, error) {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "ListJoinTokens",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugCtx(ctx, " M (Enrollment): ListJoinTokens")
+
+	ret := make([]JoinToken, 0)
+	err = t.list(ctx, JoinTokens, joinTokenIDPrefix, constants.DefaultScope, func(body interface{}) error {
+		var state JoinTokenState
+		if err := readState(body, &state); err != nil {
+			return err
+		}
+		ret = append(ret, toJoinToken(state))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+func (t *EnrollmentManager) DeleteJoinToken(ctx context.Context, name string) error {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "DeleteJoinToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Enrollment): DeleteJoinToken name %s", name)
+
+	err = t.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID:       joinTokenIDPrefix + name,
+		Metadata: stateMetadata(JoinTokens, constants.DefaultScope),
+	})
+	return err
+}
+
+// TargetLabels returns the labels of a registered target
+type TargetLabels func(ctx context.Context, namespace string, target string) (map[string]string, error)
+
+// Enroll trades a join token for the token of a target. The join token can only be used once,
+// before it expires, for a target it's bound to. A join token that is bound to labels can only
+// enroll a registered target with the labels, which are read with targetLabels. Enrolling a
+// target again replaces its token.
+func (t *EnrollmentManager) Enroll(ctx context.Context, request EnrollRequest, targetLabels TargetLabels) (IssuedCredential, error) {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "Enroll",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	name, secret, ok := parseJoinToken(request.JoinToken)
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, "join token is malformed", v1alpha2.Unauthorized)
+		return IssuedCredential{}, err
+	}
+	log.InfofCtx(ctx, " M (Enrollment): Enroll target %s with join token %s", request.Target, name)
+
+	var state JoinTokenState
+	var etag string
+	state, etag, err = t.getJoinTokenState(ctx, name)
+	if err != nil {
+		log.DebugfCtx(ctx, " M (Enrollment) : failed to get join token %s, err: %v", name, err)
+		err = v1alpha2.NewCOAError(nil, "join token is not valid", v1alpha2.Unauthorized)
+		return IssuedCredential{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(state.SecretHash)) != 1 {
+		err = v1alpha2.NewCOAError(nil, "join token is not valid", v1alpha2.Unauthorized)
+		return IssuedCredential{}, err
+	}
+	now := t.currentTime()
+	if state.UsedAt != nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' has already been used", name), v1alpha2.Unauthorized)
+		return IssuedCredential{}, err
+	}
+	if !now.Before(state.ExpiresAt) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' has expired", name), v1alpha2.Unauthorized)
+		return IssuedCredential{}, err
+	}
+	target := request.Target
+	if target == "" {
+		target = state.Target
+	}
+	if target == "" || (state.Target != "" && target != state.Target) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' is not for target '%s'", name, target), v1alpha2.Forbidden)
+		return IssuedCredential{}, err
+	}
+	if len(state.Labels) > 0 {
+		var labels map[string]string
+		labels, err = targetLabels(ctx, state.Namespace, target)
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Enrollment) : failed to get target %s, err: %v", target, err)
+			if v1alpha2.IsNotFound(err) {
+				err = v1alpha2.NewCOAError(nil, fmt.Sprintf("target '%s' is not registered in namespace '%s'", target, state.Namespace), v1alpha2.Forbidden)
+			}
+			return IssuedCredential{}, err
+		}
+		err = matchTargetLabels(name, target, state.Labels, labels)
+		if err != nil {
+			return IssuedCredential{}, err
+		}
+	}
+
+	// the join token is used up before the credential is saved, so that it can't be used twice.
+	// It's only saved if it hasn't changed since it was read, so that only one of the agents
+	// that use it at the same time, on any replica, gets a credential.
+	state.UsedAt = &now
+	state.UsedBy = target
+	err = t.upsert(ctx, JoinTokens, constants.DefaultScope, joinTokenIDPrefix+name, state, &etag)
+	if err != nil {
+		if v1alpha2.GetErrorState(err) == v1alpha2.Conflict {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' has already been used", name), v1alpha2.Unauthorized)
+		}
+		return IssuedCredential{}, err
+	}
+	secret, err = newSecret(secretLength)
+	if err != nil {
+		return IssuedCredential{}, err
+	}
+	credential := CredentialState{
+		Target:     target,
+		Namespace:  state.Namespace,
+		SecretHash: hashSecret(secret),
+		JoinToken:  name,
+		CreatedAt:  now,
+	}
+	if t.TokenLifetime > 0 {
+		expiresAt := now.Add(t.TokenLifetime)
+		credential.ExpiresAt = &expiresAt
+	}
+	err = t.upsert(ctx, Credentials, state.Namespace, credentialIDPrefix+target, credential, nil)
+	if err != nil {
+		return IssuedCredential{}, err
+	}
+	return IssuedCredential{
+		AccessToken: TokenPrefix + state.Namespace + "." + target + "." + secret,
+		TokenType:   "Bearer",
+		Target:      target,
+		Namespace:   state.Namespace,
+		ExpiresAt:   credential.ExpiresAt,
+	}, nil
+}
+
+// ListCredentials returns the credentials of the enrolled targets in a namespace, or in all
+// namespaces if it's empty
+func (t *EnrollmentManager) ListCredentials(ctx context.Context, namespace string) ([]Credential, error) {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "ListCredentials",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugfCtx(ctx, " M (Enrollment): ListCredentials namespace %s", namespace)
+
+	ret := make([]Credential, 0)
+	err = t.list(ctx, Credentials, credentialIDPrefix, namespace, func(body interface{}) error {
+		var state CredentialState
+		if err := readState(body, &state); err != nil {
+			return err
+		}
+		ret = append(ret, toCredential(state))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Namespace != ret[j].Namespace {
+			return ret[i].Namespace < ret[j].Namespace
+		}
+		return ret[i].Target < ret[j].Target
+	})
+	return ret, nil
+}
+
+// RevokeCredential deletes the credential of a target, so that its token isn't accepted anymore.
+// The target has to be enrolled again with a new join token.
+func (t *EnrollmentManager) RevokeCredential(ctx context.Context, namespace string, target string) error {
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "RevokeCredential",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Enrollment): RevokeCredential target %s in namespace %s", target, namespace)
+
+	err = t.StateProvider.Delete(ctx, states.DeleteRequest{
+		ID:       credentialIDPrefix + target,
+		Metadata: stateMetadata(Credentials, namespace),
+	})
+	return err
+}
+
+// ValidateToken checks the token of an enrolled target, and returns the target as a caller
+// that is limited to its own routes. It implements v1alpha2.ITokenValidator, so tokens that
+// don't start with the token prefix are left to the JWT middleware.
+func (t *EnrollmentManager) ValidateToken(ctx context.Context, token string) (v1alpha2.Principal, bool, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return v1alpha2.Principal{}, false, nil
+	}
+	ctx, span := observability.StartSpan("Enrollment Manager", ctx, &map[string]string{
+		"method": "ValidateToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
+	namespace, target, secret, ok := parseToken(token)
+	if !ok {
+		err = v1alpha2.NewCOAError(nil, "target token is malformed", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	var entry states.StateEntry
+	entry, err = t.StateProvider.Get(ctx, states.GetRequest{
+		ID:       credentialIDPrefix + target,
+		Metadata: stateMetadata(Credentials, namespace),
+	})
+	if err != nil {
+		log.DebugfCtx(ctx, " M (Enrollment) : failed to get credential of target %s, err: %v", target, err)
+		err = v1alpha2.NewCOAError(nil, "target token is not valid", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	var state CredentialState
+	if err = readState(entry.Body, &state); err != nil {
+		return v1alpha2.Principal{}, true, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(state.SecretHash)) != 1 {
+		err = v1alpha2.NewCOAError(nil, "target token is not valid", v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	if state.ExpiresAt != nil && !t.currentTime().Before(*state.ExpiresAt) {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("token of target '%s' has expired", target), v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	return v1alpha2.Principal{
+		User:       UserPrefix + namespace + "/" + target,
+		Roles:      []string{TargetRole},
+		Namespaces: []string{namespace},
+		Policy:     t.targetPolicy(namespace, target),
+	}, true, nil
+}
+
+// targetPolicy limits a target to its heartbeats, its status and its queue
+func (t *EnrollmentManager) targetPolicy(namespace string, target string) *v1alpha2.RBACPolicy {
+	subjects := []v1alpha2.RBACSubject{{User: UserPrefix + namespace + "/" + target}}
+	return &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:   subjects,
+				Verbs:      []string{v1alpha2.VerbUpdate},
+				Resources:  []string{t.TargetsRoute + "/ping", t.TargetsRoute + "/status"},
+				Namespaces: []string{namespace},
+				Names:      []string{target},
+			},
+			{
+				Subjects:   subjects,
+				Verbs:      []string{v1alpha2.VerbGet, v1alpha2.VerbUpdate},
+				Resources:  []string{t.QueueRoute},
+				Namespaces: []string{namespace},
+				Parameters: map[string]string{"instance": target},
+			},
+		},
+	}
+}
+
+// matchTargetLabels checks that a target has the labels a join token is bound to
+func matchTargetLabels(joinToken string, target string, required map[string]string, labels map[string]string) error {
+	for k, v := range required {
+		if labels[k] != v {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("join token '%s' is not for target '%s', which doesn't have the label %s=%s", joinToken, target, k, v), v1alpha2.Forbidden)
+		}
+	}
+	return nil
+}
+
+// parseJoinToken splits a join token into its name and its secret
+func parseJoinToken(token string) (string, string, bool) {
+	if !strings.HasPrefix(token, JoinTokenPrefix) {
+		return "", "", false
+	}
+	i := strings.LastIndex(token, ".")
+	if i < 0 {
+		return "", "", false
+	}
+	name := token[len(JoinTokenPrefix):i]
+	secret := token[i+1:]
+	if !namePattern.MatchString(name) || secret == "" {
+		return "", "", false
+	}
+	return name, secret, true
+}
+
+// parseToken splits the token of a target into its namespace, its target and its secret.
+// Namespaces don't have dots, and secrets are base64 without dots, so target names can.
+func parseToken(token string) (string, string, string, bool) {
+	rest := token[len(TokenPrefix):]
+	i := strings.Index(rest, ".")
+	j := strings.LastIndex(rest, ".")
+	if i <= 0 || j <= i+1 || j == len(rest)-1 {
+		return "", "", "", false
+	}
+	return rest[:i], rest[i+1 : j], rest[j+1:], true
+}
+
+func newSecret(length int) (string, error) {
+	data := make([]byte, length)
+	if _, err := rand.Read(data); err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to generate token", v1alpha2.InternalError)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// hashSecret hashes the secret of a token. Secrets are random, so they don't need the slow
+// hashes of passwords.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// stateMetadata is the metadata of the state entries of a resource in a namespace. Join
+// tokens are kept in the default namespace, as their names are global.
+func stateMetadata(resource string, namespace string) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"group":    model.FabricGroup,
+		"version":  "v1",
+		"resource": resource,
+		"kind":     resourceKinds[resource],
+	}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return metadata
+}
+
+// getJoinTokenState returns a join token with its ETag
+func (t *EnrollmentManager) getJoinTokenState(ctx context.Context, name string) (JoinTokenState, string, error) {
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID:       joinTokenIDPrefix + name,
+		Metadata: stateMetadata(JoinTokens, constants.DefaultScope),
+	})
+	if err != nil {
+		return JoinTokenState{}, "", err
+	}
+	var state JoinTokenState
+	err = readState(entry.Body, &state)
+	return state, entry.ETag, err
+}
+
+// upsert saves an entry. An entry with an ETag is only saved if it hasn't changed since it was
+// read with the ETag, or, with an empty ETag, if it doesn't exist yet.
+func (t *EnrollmentManager) upsert(ctx context.Context, resource string, namespace string, id string, body interface{}, etag *string) error {
+	_, err := t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   id,
+			Body: stateBody(id, namespace, body),
+		},
+		ETag:     etag,
+		Metadata: stateMetadata(resource, namespace),
+	})
+	return err
+}
+
+// list calls read with the bodies of the entries of a resource whose ID has the prefix, in a
+// namespace or in all namespaces if it's empty
+func (t *EnrollmentManager) list(ctx context.Context, resource string, prefix string, namespace string, read func(body interface{}) error) error {
+	entries, _, err := t.StateProvider.List(ctx, states.ListRequest{
+		Metadata: stateMetadata(resource, namespace),
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Enrollment): failed to list states, err: %v", err)
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.ID, prefix) {
+			if err = read(entry.Body); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stateBody is the body of the entry of a join token or credential. The state is the spec of
+// the entry, so that it's kept by the state providers that store objects.
+func stateBody(id string, namespace string, state interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":      id,
+			"namespace": namespace,
+		},
+		"spec": state,
+	}
+}
+
+func readState(body interface{}, state interface{}) error {
+	bytes, _ := json.Marshal(body)
+	var object struct {
+		Spec json.RawMessage `json:"spec"`
+	}
+	if err := json.Unmarshal(bytes, &object); err != nil || len(object.Spec) == 0 {
+		return v1alpha2.NewCOAError(err, "failed to read enrollment state", v1alpha2.InternalError)
+	}
+	if err := json.Unmarshal(object.Spec, state); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to read enrollment state", v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func toJoinToken(state JoinTokenState) JoinToken {
+	return JoinToken{
+		Name:      state.Name,
+		Namespace: state.Namespace,
+		Target:    state.Target,
+		Labels:    state.Labels,
+		ExpiresAt: state.ExpiresAt,
+		CreatedAt: state.CreatedAt,
+		CreatedBy: state.CreatedBy,
+		UsedAt:    state.UsedAt,
+		UsedBy:    state.UsedBy,
+	}
+}
+
+func toCredential(state CredentialState) Credential {
+	return Credential{
+		Target:    state.Target,
+		Namespace: state.Namespace,
+		JoinToken: state.JoinToken,
+		ExpiresAt: state.ExpiresAt,
+		CreatedAt: state.CreatedAt,
+	}
+}
@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package enrollment
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func initManager(t *testing.T, properties map[string]string) *EnrollmentManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &EnrollmentManager{}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	for k, v := range properties {
+		config.Properties[k] = v
+	}
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, config, providers)
+	assert.Nil(t, err)
+	return manager
+}
+
+func noTarget(ctx context.Context, namespace string, target string) (map[string]string, error) {
+	return nil, v1alpha2.NewCOAError(nil, "not found", v1alpha2.NotFound)
+}
+
+func labels(targetLabels map[string]string) TargetLabels {
+	return func(ctx context.Context, namespace string, target string) (map[string]string, error) {
+		return targetLabels, nil
+	}
+}
+
+func TestInit(t *testing.T) {
+	manager := initManager(t, nil)
+	assert.Equal(t, 24*time.Hour, manager.JoinTokenLifetime)
+	assert.Equal(t, time.Duration(0), manager.TokenLifetime)
+	assert.Equal(t, "targets", manager.TargetsRoute)
+	assert.Equal(t, "solutionversion/queue", manager.QueueRoute)
+
+	manager = initManager(t, map[string]string{"tokenLifetimeSeconds": "3600", "queueRoute": "/solution/queue/"})
+	assert.Equal(t, time.Hour, manager.TokenLifetime)
+	assert.Equal(t, "solution/queue", manager.QueueRoute)
+}
+
+func TestInitJoinTokensMustExpire(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := EnrollmentManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+			"joinTokenLifetimeSeconds":  "0",
+		},
+	}, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+// unconditionalStateProvider hides that its state provider supports conditional upserts
+type unconditionalStateProvider struct {
+	states.IStateProvider
+}
+
+func TestInitRequiresConditionalUpserts(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := EnrollmentManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{"StateProvider": &unconditionalStateProvider{IStateProvider: stateProvider}})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestInitRequiresPersistentState(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := EnrollmentManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate": "StateProvider",
+		},
+	}, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.NotNil(t, err)
+}
+
+func TestCreateJoinToken(t *testing.T) {
+	manager := initManager(t, nil)
+	token, err := manager.CreateJoinToken(context.Background(), "", JoinTokenSpec{Target: "t1"}, "admin")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(token.Name, "join-"))
+	assert.True(t, strings.HasPrefix(token.Token, JoinTokenPrefix+token.Name+"."))
+	assert.Equal(t, "default", token.Namespace)
+	assert.Equal(t, "admin", token.CreatedBy)
+
+	_, err = manager.CreateJoinToken(context.Background(), token.Name, JoinTokenSpec{Target: "t1"}, "admin")
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+	_, err = manager.CreateJoinToken(context.Background(), "any", JoinTokenSpec{}, "admin")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+	past := time.Now().Add(-time.Minute)
+	_, err = manager.CreateJoinToken(context.Background(), "past", JoinTokenSpec{Target: "t1", ExpiresAt: &past}, "admin")
+	assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+
+	tokens, err := manager.ListJoinTokens(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tokens))
+
+	assert.Nil(t, manager.DeleteJoinToken(context.Background(), token.Name))
+	_, err = manager.GetJoinToken(context.Background(), token.Name)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestEnrollAndValidate(t *testing.T) {
+	manager := initManager(t, nil)
+	token, err := manager.CreateJoinToken(context.Background(), "t1-join", JoinTokenSpec{Namespace: "line-3", Target: "t1"}, "admin")
+	assert.Nil(t, err)
+
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token, Target: "t2"}, noTarget)
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+
+	credential, err := manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, noTarget)
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", credential.Target)
+	assert.Equal(t, "line-3", credential.Namespace)
+	assert.Equal(t, "Bearer", credential.TokenType)
+	assert.Nil(t, credential.ExpiresAt)
+
+	// join tokens can only be used once
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, noTarget)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	used, err := manager.GetJoinToken(context.Background(), "t1-join")
+	assert.Nil(t, err)
+	assert.NotNil(t, used.UsedAt)
+	assert.Equal(t, "t1", used.UsedBy)
+
+	principal, ok, err := manager.ValidateToken(context.Background(), credential.AccessToken)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "target:line-3/t1", principal.User)
+	assert.Equal(t, []string{TargetRole}, principal.Roles)
+	assert.Equal(t, []string{"line-3"}, principal.Namespaces)
+
+	// the target can only call its own routes
+	allowed := []v1alpha2.AccessRequest{
+		{Verb: v1alpha2.VerbUpdate, Resource: "targets/ping", Name: "t1", Namespace: "line-3"},
+		{Verb: v1alpha2.VerbUpdate, Resource: "targets/status", Name: "t1", Namespace: "line-3"},
+		{Verb: v1alpha2.VerbGet, Resource: "solutionversion/queue", Namespace: "line-3", Parameters: map[string]string{"instance": "t1"}},
+	}
+	denied := []v1alpha2.AccessRequest{
+		{Verb: v1alpha2.VerbUpdate, Resource: "targets/ping", Name: "t2", Namespace: "line-3"},
+		{Verb: v1alpha2.VerbUpdate, Resource: "targets/status", Name: "t1", Namespace: "default"},
+		{Verb: v1alpha2.VerbGet, Resource: "solutionversion/queue", Namespace: "line-3", Parameters: map[string]string{"instance": "t2"}},
+		{Verb: v1alpha2.VerbList, Resource: "targets", Namespace: "line-3"},
+		{Verb: v1alpha2.VerbUpdate, Resource: "targets", Name: "t1", Namespace: "line-3"},
+	}
+	for _, request := range allowed {
+		request.User, request.Roles = principal.User, principal.Roles
+		assert.True(t, principal.Policy.Check(request).Allowed, "%+v", request)
+	}
+	for _, request := range denied {
+		request.User, request.Roles = principal.User, principal.Roles
+		assert.False(t, principal.Policy.Check(request).Allowed, "%+v", request)
+	}
+
+	_, ok, _ = manager.ValidateToken(context.Background(), "eyJhbGciOiJIUzI1NiJ9.e30.sig")
+	assert.False(t, ok)
+	_, ok, err = manager.ValidateToken(context.Background(), credential.AccessToken+"x")
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	credentials, err := manager.ListCredentials(context.Background(), "line-3")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(credentials))
+	assert.Equal(t, "t1-join", credentials[0].JoinToken)
+	credentials, err = manager.ListCredentials(context.Background(), "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(credentials))
+
+	assert.Nil(t, manager.RevokeCredential(context.Background(), "line-3", "t1"))
+	_, ok, err = manager.ValidateToken(context.Background(), credential.AccessToken)
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+}
+
+func TestEnrollWithLabels(t *testing.T) {
+	manager := initManager(t, nil)
+	token, err := manager.CreateJoinToken(context.Background(), "line-3", JoinTokenSpec{Labels: map[string]string{"line": "3"}}, "admin")
+	assert.Nil(t, err)
+
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, labels(map[string]string{"line": "3"}))
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token, Target: "t1"}, labels(map[string]string{"line": "4"}))
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+	// targets that aren't registered don't have labels
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token, Target: "t1"}, noTarget)
+	assert.Equal(t, v1alpha2.Forbidden, err.(v1alpha2.COAError).State)
+	credential, err := manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token, Target: "t1"}, labels(map[string]string{"line": "3", "site": "a"}))
+	assert.Nil(t, err)
+	assert.Equal(t, "t1", credential.Target)
+}
+
+func TestEnrollExpired(t *testing.T) {
+	manager := initManager(t, map[string]string{"tokenLifetimeSeconds": "60"})
+	now := time.Now().UTC()
+	manager.now = func() time.Time { return now }
+	token, err := manager.CreateJoinToken(context.Background(), "t1-join", JoinTokenSpec{Target: "t1"}, "admin")
+	assert.Nil(t, err)
+
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: strings.Replace(token.Token, ".", ".x", 1)}, noTarget)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	now = now.Add(25 * time.Hour)
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, noTarget)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	assert.Contains(t, err.Error(), "expired")
+
+	token, err = manager.CreateJoinToken(context.Background(), "t1-join-2", JoinTokenSpec{Target: "t1"}, "admin")
+	assert.Nil(t, err)
+	credential, err := manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, noTarget)
+	assert.Nil(t, err)
+	assert.NotNil(t, credential.ExpiresAt)
+	_, _, err = manager.ValidateToken(context.Background(), credential.AccessToken)
+	assert.Nil(t, err)
+	now = now.Add(time.Minute)
+	_, _, err = manager.ValidateToken(context.Background(), credential.AccessToken)
+	assert.NotNil(t, err)
+}
+
+// consumingStateProvider uses up a join token right after it's read, as another replica would
+type consumingStateProvider struct {
+	states.IStateProvider
+}
+
+func (s *consumingStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
+	entry, err := s.IStateProvider.Get(ctx, request)
+	if err == nil && request.Metadata["resource"] == JoinTokens {
+		var state JoinTokenState
+		readState(entry.Body, &state)
+		now := time.Now().UTC()
+		state.UsedAt = &now
+		state.UsedBy = "other"
+		_, err = s.IStateProvider.Upsert(ctx, states.UpsertRequest{
+			Value:    states.StateEntry{ID: request.ID, Body: stateBody(request.ID, constants.DefaultScope, state)},
+			ETag:     &entry.ETag,
+			Metadata: request.Metadata,
+		})
+	}
+	return entry, err
+}
+
+func TestEnrollJoinTokenUsedConcurrently(t *testing.T) {
+	manager := initManager(t, nil)
+	token, err := manager.CreateJoinToken(context.Background(), "t1-join", JoinTokenSpec{Target: "t1"}, "admin")
+	assert.Nil(t, err)
+
+	manager.StateProvider = &consumingStateProvider{IStateProvider: manager.StateProvider}
+	_, err = manager.Enroll(context.Background(), EnrollRequest{JoinToken: token.Token}, noTarget)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+	assert.Contains(t, err.Error(), "already been used")
+	credentials, err := manager.ListCredentials(context.Background(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(credentials))
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package instancehistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+
+	observability "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+// InstanceHistoryManager keeps the revisions of instances that have been successfully deployed.
+// It works with any persistent state provider.
+type InstanceHistoryManager struct {
+	managers.Manager
+	StateProvider states.IStateProvider
+}
+
+func (s *InstanceHistoryManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	stateprovider, err := managers.GetPersistentStateProvider(config, providers)
+	if err == nil {
+		s.StateProvider = stateprovider
+	} else {
+		return err
+	}
+	return nil
+}
+
+// saveHistoryAttempts is how many times SaveHistory numbers a revision again when another
+// revision has been saved with the same number in the meantime
+const saveHistoryAttempts = 5
+
+// SaveHistory records a new revision of the instance. If the snapshot is identical to the latest
+// revision, no new revision is created and the latest revision is returned. Revisions are created
+// only if they don't exist yet, so concurrent saves get different revision numbers.
+func (t *InstanceHistoryManager) SaveHistory(ctx context.Context, name string, namespace string, spec model.InstanceHistorySpec) (model.InstanceHistoryState, error) {
+	ctx, span := observability.StartSpan("Instance History Manager", ctx, &map[string]string{
+		"method": "SaveHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	spec.RootResource = name
+	spec.Hash = spec.ComputeHash()
+	if spec.Time.IsZero() {
+		spec.Time = time.Now().UTC()
+	}
+	for attempt := 1; ; attempt++ {
+		var revisions []model.InstanceHistoryState
+		revisions, err = t.ListHistory(ctx, name, namespace)
+		if err != nil {
+			return model.InstanceHistoryState{}, err
+		}
+		spec.Revision = 1
+		if len(revisions) > 0 {
+			latest := revisions[len(revisions)-1]
+			if latest.Spec.Hash == spec.Hash {
+				log.DebugfCtx(ctx, " M (InstanceHistory): instance %s is unchanged since revision %d, skip saving history", name, latest.Spec.Revision)
+				return latest, nil
+			}
+			spec.Revision = latest.Spec.Revision + 1
+		}
+
+		var history model.InstanceHistoryState
+		history, err = t.createRevision(ctx, name, namespace, spec)
+		if err == nil {
+			log.InfofCtx(ctx, " M (InstanceHistory): saved revision %d of instance %s", spec.Revision, name)
+			return history, nil
+		}
+		if v1alpha2.GetErrorState(err) != v1alpha2.Conflict || attempt == saveHistoryAttempts {
+			log.ErrorfCtx(ctx, " M (InstanceHistory): failed to save history of instance %s: %+v", name, err)
+			return model.InstanceHistoryState{}, err
+		}
+		log.InfofCtx(ctx, " M (InstanceHistory): revision %d of instance %s has been saved by another writer, numbering it again (attempt %d/%d)", spec.Revision, name, attempt, saveHistoryAttempts)
+	}
+}
+
+// createRevision upserts a revision that doesn't exist yet. It fails with a Conflict if the
+// revision already exists.
+func (t *InstanceHistoryManager) createRevision(ctx context.Context, name string, namespace string, spec model.InstanceHistorySpec) (model.InstanceHistoryState, error) {
+	history := model.InstanceHistoryState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      getHistoryName(name, spec.Revision),
+			Namespace: namespace,
+			Labels: map[string]string{
+				constants.RootResource: name,
+			},
+		},
+		Spec: &spec,
+	}
+	body := map[string]interface{}{
+		"apiVersion": model.SolutionVersionGroup + "/v1",
+		"kind":       "InstanceHistory",
+		"metadata":   history.ObjectMeta,
+		"spec":       history.Spec,
+	}
+	createOnly := ""
+	_, err := t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   history.ObjectMeta.Name,
+			Body: body,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+			"group":     model.SolutionVersionGroup,
+			"version":   "v1",
+			"resource":  "instancehistories",
+			"kind":      "InstanceHistory",
+		},
+		ETag: &createOnly,
+	})
+	if err != nil {
+		return model.InstanceHistoryState{}, err
+	}
+	return history, nil
+}
+
+// ListHistory returns the revisions of an instance, ordered from the oldest to the latest.
+func (t *InstanceHistoryManager) ListHistory(ctx context.Context, name string, namespace string) ([]model.InstanceHistoryState, error) {
+	ctx, span := observability.StartSpan("Instance History Manager", ctx, &map[string]string{
+		"method": "ListHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var entries []states.StateEntry
+	entries, _, err = t.StateProvider.List(ctx, states.ListRequest{
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.SolutionVersionGroup,
+			"resource":  "instancehistories",
+			"namespace": namespace,
+			"kind":      "InstanceHistory",
+		},
+		FilterType:  "label",
+		FilterValue: fmt.Sprintf("%s=%s", constants.RootResource, name),
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			err = nil
+			return []model.InstanceHistoryState{}, nil
+		}
+		return nil, err
+	}
+	ret := make([]model.InstanceHistoryState, 0)
+	for _, entry := range entries {
+		var history model.InstanceHistoryState
+		history, err = getInstanceHistoryState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		if history.Spec.RootResource != name {
+			continue
+		}
+		history.ObjectMeta.UpdateEtag(entry.ETag)
+		ret = append(ret, history)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Spec.Revision < ret[j].Spec.Revision
+	})
+	return ret, nil
+}
+
+// GetHistory returns a single revision of an instance.
+func (t *InstanceHistoryManager) GetHistory(ctx context.Context, name string, revision int, namespace string) (model.InstanceHistoryState, error) {
+	ctx, span := observability.StartSpan("Instance History Manager", ctx, &map[string]string{
+		"method": "GetHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var entry states.StateEntry
+	entry, err = t.StateProvider.Get(ctx, states.GetRequest{
+		ID: getHistoryName(name, revision),
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.SolutionVersionGroup,
+			"resource":  "instancehistories",
+			"namespace": namespace,
+			"kind":      "InstanceHistory",
+		},
+	})
+	if err != nil {
+		return model.InstanceHistoryState{}, err
+	}
+	var ret model.InstanceHistoryState
+	ret, err = getInstanceHistoryState(entry.Body)
+	if err != nil {
+		return model.InstanceHistoryState{}, err
+	}
+	ret.ObjectMeta.UpdateEtag(entry.ETag)
+	return ret, nil
+}
+
+// DeleteHistory removes all revisions of an instance.
+func (t *InstanceHistoryManager) DeleteHistory(ctx context.Context, name string, namespace string) error {
+	ctx, span := observability.StartSpan("Instance History Manager", ctx, &map[string]string{
+		"method": "DeleteHistory",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var revisions []model.InstanceHistoryState
+	revisions, err = t.ListHistory(ctx, name, namespace)
+	if err != nil {
+		return err
+	}
+	for _, revision := range revisions {
+		err = t.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: revision.ObjectMeta.Name,
+			Metadata: map[string]interface{}{
+				"namespace": namespace,
+				"group":     model.SolutionVersionGroup,
+				"version":   "v1",
+				"resource":  "instancehistories",
+				"kind":      "InstanceHistory",
+			},
+		})
+		if err != nil && !v1alpha2.IsNotFound(err) {
+			return err
+		}
+	}
+	err = nil
+	return nil
+}
+
+func getHistoryName(name string, revision int) string {
+	return fmt.Sprintf("%s%s%d", name, constants.ResourceSeperator, revision)
+}
+
+func getInstanceHistoryState(body interface{}) (model.InstanceHistoryState, error) {
+	var historyState model.InstanceHistoryState
+	bytes, _ := json.Marshal(body)
+	err := json.Unmarshal(bytes, &historyState)
+	if err != nil {
+		return model.InstanceHistoryState{}, err
+	}
+	if historyState.Spec == nil {
+		historyState.Spec = &model.InstanceHistorySpec{}
+	}
+	return historyState, nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package instancehistory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/stretchr/testify/assert"
+)
+
+func createInstanceHistoryManager() InstanceHistoryManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	return InstanceHistoryManager{
+		StateProvider: stateProvider,
+	}
+}
+
+func historySpec(solutionVersion string) model.InstanceHistorySpec {
+	return model.InstanceHistorySpec{
+		Instance: model.InstanceSpec{
+			SolutionVersion: solutionVersion,
+		},
+		SolutionVersion: model.SolutionVersionState{
+			ObjectMeta: model.ObjectMeta{
+				Name: solutionVersion,
+			},
+			Spec: &model.SolutionVersionSpec{
+				Components: []model.ComponentSpec{
+					{
+						Name: "component",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSaveListGetDeleteHistory(t *testing.T) {
+	manager := createInstanceHistoryManager()
+	ctx := context.Background()
+
+	history, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, history.Spec.Revision)
+	assert.Equal(t, "test-v-1", history.ObjectMeta.Name)
+	assert.Equal(t, "test", history.ObjectMeta.Labels["rootResource"])
+	assert.NotEmpty(t, history.Spec.Hash)
+
+	history, err = manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v2"))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, history.Spec.Revision)
+
+	list, err := manager.ListHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, 1, list[0].Spec.Revision)
+	assert.Equal(t, 2, list[1].Spec.Revision)
+
+	history, err = manager.GetHistory(ctx, "test", 1, "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "solution-v-v1", history.Spec.Instance.SolutionVersion)
+	assert.Equal(t, "solution-v-v1", history.Spec.SolutionVersion.ObjectMeta.Name)
+
+	err = manager.DeleteHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	list, err = manager.ListHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list))
+	_, err = manager.GetHistory(ctx, "test", 1, "default")
+	assert.NotNil(t, err)
+}
+
+func TestSaveHistorySkipsUnchangedRevision(t *testing.T) {
+	manager := createInstanceHistoryManager()
+	ctx := context.Background()
+
+	first, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+	second, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+	assert.Equal(t, first.Spec.Revision, second.Spec.Revision)
+
+	list, err := manager.ListHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list))
+}
+
+func TestListHistoryOnlyReturnsRevisionsOfInstance(t *testing.T) {
+	manager := createInstanceHistoryManager()
+	ctx := context.Background()
+
+	_, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+	_, err = manager.SaveHistory(ctx, "other", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+
+	list, err := manager.ListHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list))
+	assert.Equal(t, "test", list[0].Spec.RootResource)
+
+	list, err = manager.ListHistory(ctx, "missing", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list))
+}
+
+// racingStateProvider saves a revision of another writer right before the first upsert
+type racingStateProvider struct {
+	*memorystate.MemoryStateProvider
+	race func()
+}
+
+func (r *racingStateProvider) Upsert(ctx context.Context, request states.UpsertRequest) (string, error) {
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+	return r.MemoryStateProvider.Upsert(ctx, request)
+}
+
+func TestSaveHistoryNumbersRevisionAgainOnConflict(t *testing.T) {
+	manager := createInstanceHistoryManager()
+	ctx := context.Background()
+	_, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v1"))
+	assert.Nil(t, err)
+
+	other := manager
+	provider := &racingStateProvider{MemoryStateProvider: manager.StateProvider.(*memorystate.MemoryStateProvider)}
+	provider.race = func() {
+		_, err := other.SaveHistory(ctx, "test", "default", historySpec("solution-v-v2"))
+		assert.Nil(t, err)
+	}
+	manager.StateProvider = provider
+
+	history, err := manager.SaveHistory(ctx, "test", "default", historySpec("solution-v-v3"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, history.Spec.Revision)
+
+	list, err := manager.ListHistory(ctx, "test", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, "solution-v-v2", list[1].Spec.Instance.SolutionVersion)
+	assert.Equal(t, "solution-v-v3", list[2].Spec.Instance.SolutionVersion)
+}
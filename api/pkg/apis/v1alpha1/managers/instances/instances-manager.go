@@ -13,6 +13,7 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/watch"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -137,6 +138,12 @@ func (t *InstancesManager) UpsertState(ctx context.Context, name string, state m
 }
 
 func (t *InstancesManager) ListState(ctx context.Context, namespace string) ([]model.InstanceState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the instances, and returns the token of the next page
+func (t *InstancesManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.InstanceState, string, error) {
 	ctx, span := observability.StartSpan("Instances Manager", ctx, &map[string]string{
 		"method": "ListSpec",
 	})
@@ -152,23 +159,45 @@ func (t *InstancesManager) ListState(ctx context.Context, namespace string) ([]m
 			"namespace": namespace,
 			"kind":      "Instance",
 		},
+		ListOptions: options,
 	}
 	var instances []states.StateEntry
-	instances, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	instances, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.InstanceState, 0)
 	for _, t := range instances {
 		var rt model.InstanceState
 		rt, err = getInstanceState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
+}
+
+// WatchState streams the instances and their changes
+func (t *InstancesManager) WatchState(ctx context.Context, namespace string) (<-chan model.WatchEvent, error) {
+	return watch.WatchStates(ctx, t.StateProvider, states.WatchRequest{
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.SolutionVersionGroup,
+			"resource":  "instances",
+			"namespace": namespace,
+			"kind":      "Instance",
+		},
+	}, func(entry states.StateEntry) (interface{}, error) {
+		state, err := getInstanceState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		state.ObjectMeta.UpdateEtag(entry.ETag)
+		return state, nil
+	})
 }
 
 func getInstanceState(body interface{}) (model.InstanceState, error) {
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
@@ -28,7 +29,11 @@ import (
 
 var log = logger.NewLogger("coa.runtime")
 
-const Scheduled = "Scheduled"
+const (
+	Scheduled = "Scheduled"
+	// ScheduledJob is the resource of the jobs that are queued once they're due
+	ScheduledJob = "ScheduledJob"
+)
 
 type JobsManager struct {
 	managers.Manager
@@ -268,6 +273,57 @@ func (s *JobsManager) pollSchedules() []error {
 			}
 		}
 	}
+	return s.pollScheduledJobs(ctx)
+}
+
+// pollScheduledJobs queues the scheduled jobs that are due
+func (s *JobsManager) pollScheduledJobs(ctx context.Context) []error {
+	list, _, err := s.PersistentStateProvider.List(ctx, states.ListRequest{
+		Metadata: map[string]interface{}{
+			"group":    model.SolutionVersionGroup,
+			"version":  "v1",
+			"resource": ScheduledJob,
+		},
+	})
+	if err != nil {
+		return []error{err}
+	}
+
+	now := time.Now().UTC()
+	for _, entry := range list {
+		var scheduled v1alpha2.ScheduledJobData
+		entryData, _ := json.Marshal(entry.Body)
+		err = json.Unmarshal(entryData, &scheduled)
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Job): get bad scheduled job %s from state store", entry.ID)
+			continue
+		}
+		if scheduled.Due.After(now) {
+			continue
+		}
+		log.InfofCtx(ctx, " M (Job): queueing scheduled %s job %s", scheduled.ObjectType, scheduled.Job.Id)
+		err = s.Context.Publish("job", v1alpha2.Event{
+			Metadata: map[string]string{
+				"objectType": scheduled.ObjectType,
+				"namespace":  scheduled.Namespace,
+			},
+			Body:    scheduled.Job,
+			Context: ctx,
+		})
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Job): error publishing scheduled %s job %s: %s", scheduled.ObjectType, scheduled.Job.Id, err.Error())
+			continue
+		}
+		s.PersistentStateProvider.Delete(ctx, states.DeleteRequest{
+			ID: entry.ID,
+			Metadata: map[string]interface{}{
+				"namespace": scheduled.Namespace,
+				"group":     model.SolutionVersionGroup,
+				"version":   "v1",
+				"resource":  ScheduledJob,
+			},
+		})
+	}
 	return nil
 }
 
@@ -381,6 +437,11 @@ func (s *JobsManager) HandleScheduleEvent(ctx context.Context, event v1alpha2.Ev
 	defer observ_utils.CloseSpanWithError(span, &err)
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
+	if objectType, ok := event.Metadata["objectType"]; ok {
+		err = s.scheduleJob(ctx, objectType, event)
+		return err
+	}
+
 	var activationData v1alpha2.ActivationData
 	jData, _ := json.Marshal(event.Body)
 	err = json.Unmarshal(jData, &activationData)
@@ -388,6 +449,12 @@ func (s *JobsManager) HandleScheduleEvent(ctx context.Context, event v1alpha2.Ev
 		log.ErrorfCtx(ctx, " M (Job): schedule event body is not an activation data: %v", event.Body)
 		return v1alpha2.NewCOAError(nil, "event body is not an activation data", v1alpha2.BadRequest)
 	}
+	// cron schedules are stored as the timestamp of their next activation
+	err = activationData.ResolveSchedule(time.Now())
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Job): unable to resolve schedule %s for activation %s: %s", activationData.Schedule, activationData.Activation, err.Error())
+		return v1alpha2.NewCOAError(err, "invalid schedule", v1alpha2.BadRequest)
+	}
 	key := fmt.Sprintf("sch_%s-%s", activationData.CampaignVersion, activationData.Activation)
 	_, err = s.PersistentStateProvider.Upsert(ctx, states.UpsertRequest{
 		Value: states.StateEntry{
@@ -406,6 +473,41 @@ func (s *JobsManager) HandleScheduleEvent(ctx context.Context, event v1alpha2.Ev
 	}
 	return err
 }
+
+// scheduleJob stores a job that is queued once it's due. The jobs are keyed by their object, so
+// scheduling the job of an object again replaces the earlier one instead of queueing both.
+func (s *JobsManager) scheduleJob(ctx context.Context, objectType string, event v1alpha2.Event) error {
+	var scheduled v1alpha2.ScheduledJobData
+	jData, _ := json.Marshal(event.Body)
+	err := json.Unmarshal(jData, &scheduled)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Job): schedule event body is not a scheduled job: %v", event.Body)
+		return v1alpha2.NewCOAError(nil, "event body is not a scheduled job", v1alpha2.BadRequest)
+	}
+	scheduled.ObjectType = objectType
+	scheduled.Namespace = model.ReadProperty(event.Metadata, "namespace", nil)
+	if scheduled.Namespace == "" {
+		scheduled.Namespace = "default"
+	}
+	key := fmt.Sprintf("job_%s-%s", objectType, scheduled.Job.Id)
+	_, err = s.PersistentStateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   key,
+			Body: scheduled,
+		},
+		Metadata: map[string]interface{}{
+			"namespace": scheduled.Namespace,
+			"group":     model.SolutionVersionGroup,
+			"version":   "v1",
+			"resource":  ScheduledJob,
+		},
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Job): error upserting scheduled job %s: %s", key, err.Error())
+	}
+	return err
+}
+
 func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event) error {
 	ctx, span := observability.StartSpan("Job Manager", ctx, &map[string]string{
 		"method": "HandleJobEvent",
@@ -482,11 +584,15 @@ func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event)
 			//call api
 			switch job.Action {
 			case v1alpha2.JobUpdate:
-				_, err = s.apiClient.Reconcile(ctx, deployment, false, namespace, s.user, s.password)
+				var summary model.SummarySpec
+				summary, err = s.apiClient.Reconcile(ctx, deployment, false, namespace, s.user, s.password)
 				if err != nil {
 					log.ErrorfCtx(ctx, " M (Job): error reconciling instance %s: %s", instanceName, err.Error())
 					return err
 				} else {
+					if summary.AllAssignedDeployed {
+						s.saveInstanceHistory(ctx, instance, solutionversion, targetCandidates, namespace)
+					}
 					s.VolatileStateProvider.Upsert(ctx, states.UpsertRequest{
 						Value: states.StateEntry{
 							ID: "i_" + instance.ObjectMeta.Name,
@@ -587,11 +693,16 @@ func (s *JobsManager) HandleJobEvent(ctx context.Context, event v1alpha2.Event)
 			log.InfofCtx(ctx, " M (Job): handling deployment spec: %s", model.GetDeploymentSpecForLog(deployment))
 
 			if job.Action == v1alpha2.JobUpdate {
-				_, err = s.apiClient.Reconcile(ctx, *deployment, false, namespace, s.user, s.password)
+				var summary model.SummarySpec
+				summary, err = s.apiClient.Reconcile(ctx, *deployment, false, namespace, s.user, s.password)
 				if err != nil {
 					log.ErrorfCtx(ctx, " M (Job): error reconciling deployment: %s", err.Error())
 					return err
 				} else {
+					// a requeued deployment, like a paused rollout that resumes, is deployed once all its waves are
+					if summary.AllAssignedDeployed {
+						s.saveInstanceHistory(ctx, deployment.Instance, deployment.SolutionVersion, deploymentTargets(*deployment), namespace)
+					}
 					// TODO: how to handle status updates?
 					s.VolatileStateProvider.Upsert(ctx, states.UpsertRequest{
 						Value: states.StateEntry{
@@ -637,3 +748,40 @@ func getLastSuccessTime(body interface{}) (LastSuccessTime, error) {
 	}
 	return lastSuccessTime, nil
 }
+
+// deploymentTargets returns the targets of a deployment, ordered by name
+func deploymentTargets(deployment model.DeploymentSpec) []model.TargetState {
+	names := make([]string, 0, len(deployment.Targets))
+	for name := range deployment.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]model.TargetState, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, deployment.Targets[name])
+	}
+	return ret
+}
+
+// saveInstanceHistory records a revision of a successfully deployed instance. Failures are logged
+// but don't fail the job, as the deployment itself has succeeded.
+func (s *JobsManager) saveInstanceHistory(ctx context.Context, instance model.InstanceState, solutionversion model.SolutionVersionState, targets []model.TargetState, namespace string) {
+	if instance.Spec == nil {
+		return
+	}
+	err := s.Context.Publish("instance-history", v1alpha2.Event{
+		Metadata: map[string]string{
+			"instance":  instance.ObjectMeta.Name,
+			"namespace": namespace,
+		},
+		Body: model.InstanceHistorySpec{
+			Instance:        *instance.Spec,
+			SolutionVersion: solutionversion,
+			Targets:         targets,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		log.WarnfCtx(ctx, " M (Job): failed to save history of instance %s: %s", instance.ObjectMeta.Name, err.Error())
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/stretchr/testify/assert"
@@ -137,6 +138,205 @@ func TestHandleScheduleEvent(t *testing.T) {
 	assert.NotNil(t, schedule)
 }
 
+func TestHandleScheduleEventWithCron(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	jobManager := JobsManager{}
+	err := jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate":   "state",
+			"providers.persistentstate": "state",
+			"baseUrl":                   "http://localhost:8082/v1alpha2/",
+			"password":                  "",
+			"user":                      "admin",
+			"interval":                  "#15",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+	})
+	assert.Nil(t, err)
+	err = jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+		Body: v1alpha2.ActivationData{CampaignVersion: "campaignversion1", Activation: "activation1", Schedule: "*/5 * * * *"},
+	})
+	assert.Nil(t, err)
+
+	entry, err := stateProvider.Get(context.Background(), states.GetRequest{ID: "sch_campaignversion1-activation1"})
+	assert.Nil(t, err)
+	var activationData v1alpha2.ActivationData
+	jData, _ := json.Marshal(entry.Body)
+	err = json.Unmarshal(jData, &activationData)
+	assert.Nil(t, err)
+	fireTime, err := time.Parse(time.RFC3339, activationData.Schedule)
+	assert.Nil(t, err)
+	assert.True(t, fireTime.After(time.Now()))
+	assert.Equal(t, 0, fireTime.Minute()%5)
+
+	err = jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+		Body: map[string]interface{}{"campaignversion": "campaignversion1", "activation": "activation2", "schedule": "0 0 30 2 *"},
+	})
+	assert.NotNil(t, err)
+}
+
+func TestScheduledJobIsQueuedOnceDue(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	jobManager := JobsManager{}
+	err := jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate":   "state",
+			"providers.persistentstate": "state",
+			"user":                      "admin",
+			"password":                  "",
+			"schedule.enabled":          "true",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+	})
+	assert.Nil(t, err)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	jobManager.Context.Init(nil, &pubSubProvider)
+	queued := make(chan v1alpha2.Event, 2)
+	jobManager.Context.Subscribe("job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			queued <- event
+			return nil
+		},
+	})
+
+	schedule := func(due time.Time) {
+		err := jobManager.HandleScheduleEvent(context.Background(), v1alpha2.Event{
+			Metadata: map[string]string{"objectType": "deployment", "namespace": "ns1"},
+			Body: v1alpha2.ScheduledJobData{
+				Due: due,
+				Job: v1alpha2.JobData{Id: "instance1", Scope: "ns1", Action: v1alpha2.JobUpdate},
+			},
+		})
+		assert.Nil(t, err)
+	}
+
+	// scheduling the job of the same object again replaces it
+	schedule(time.Now().UTC().Add(time.Hour))
+	schedule(time.Now().UTC().Add(time.Hour))
+	entries, _, err := stateProvider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Empty(t, jobManager.Poll())
+	assert.Equal(t, 0, len(queued))
+
+	schedule(time.Now().UTC().Add(-time.Second))
+	assert.Empty(t, jobManager.Poll())
+	select {
+	case event := <-queued:
+		assert.Equal(t, "deployment", event.Metadata["objectType"])
+		assert.Equal(t, "ns1", event.Metadata["namespace"])
+		var job v1alpha2.JobData
+		jData, _ := json.Marshal(event.Body)
+		assert.Nil(t, json.Unmarshal(jData, &job))
+		assert.Equal(t, "instance1", job.Id)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the scheduled job isn't queued")
+	}
+
+	// a job is queued once
+	assert.Empty(t, jobManager.Poll())
+	select {
+	case <-queued:
+		assert.Fail(t, "the scheduled job is queued twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRequeuedDeploymentJobSavesHistory(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+
+	allDeployed := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/solutionversion/reconcile":
+			response = model.SummarySpec{AllAssignedDeployed: allDeployed}
+		default:
+			response = utils.AuthResponse{AccessToken: "test-token", TokenType: "Bearer"}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	jobManager := JobsManager{}
+	err := jobManager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.volatilestate":   "state",
+			"providers.persistentstate": "state",
+			"baseUrl":                   ts.URL + "/",
+			"user":                      "admin",
+			"password":                  "",
+		},
+	}, map[string]providers.IProvider{
+		"state": stateProvider,
+	})
+	assert.Nil(t, err)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	jobManager.Context.Init(nil, &pubSubProvider)
+	saved := make(chan v1alpha2.Event, 2)
+	jobManager.Context.Subscribe("instance-history", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			saved <- event
+			return nil
+		},
+	})
+
+	data, _ := json.Marshal(model.DeploymentSpec{
+		Instance: model.InstanceState{
+			ObjectMeta: model.ObjectMeta{Name: "instance1", Namespace: "ns1"},
+			Spec:       &model.InstanceSpec{SolutionVersion: "solution-v-v1"},
+		},
+		SolutionVersion: model.SolutionVersionState{
+			ObjectMeta: model.ObjectMeta{Name: "solution-v-v1", Namespace: "ns1"},
+			Spec:       &model.SolutionVersionSpec{},
+		},
+		Targets: map[string]model.TargetState{
+			"target2": {ObjectMeta: model.ObjectMeta{Name: "target2"}},
+			"target1": {ObjectMeta: model.ObjectMeta{Name: "target1"}},
+		},
+	})
+	handle := func() {
+		err := jobManager.HandleJobEvent(context.Background(), v1alpha2.Event{
+			Metadata: map[string]string{"objectType": "deployment", "namespace": "ns1"},
+			Body:     v1alpha2.JobData{Id: "instance1", Scope: "ns1", Action: v1alpha2.JobUpdate, Data: data},
+		})
+		assert.Nil(t, err)
+	}
+
+	// a rollout that pauses again isn't deployed yet
+	handle()
+	select {
+	case <-saved:
+		assert.Fail(t, "the history of a paused rollout is saved")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	allDeployed = true
+	handle()
+	select {
+	case event := <-saved:
+		assert.Equal(t, "instance1", event.Metadata["instance"])
+		assert.Equal(t, "ns1", event.Metadata["namespace"])
+		history := event.Body.(model.InstanceHistorySpec)
+		assert.Equal(t, "solution-v-v1", history.Instance.SolutionVersion)
+		assert.Equal(t, "solution-v-v1", history.SolutionVersion.ObjectMeta.Name)
+		assert.Equal(t, 2, len(history.Targets))
+		assert.Equal(t, "target1", history.Targets[0].ObjectMeta.Name)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the history of the resumed rollout isn't saved")
+	}
+}
+
 func TestHandleheartbeatEvent(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
 	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
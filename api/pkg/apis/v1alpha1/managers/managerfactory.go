@@ -8,12 +8,15 @@ package managers
 
 import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/apikeys"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaigns"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaignversions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogversions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/devices"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instancehistory"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instances"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/jobs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/models"
@@ -65,8 +68,14 @@ func (c *SymphonyManagerFactory) CreateManager(config cm.ManagerConfig) (cm.IMan
 		manager = &solutions.SolutionsManager{}
 	case "managers.symphony.instances":
 		manager = &instances.InstancesManager{}
+	case "managers.symphony.instancehistory":
+		manager = &instancehistory.InstanceHistoryManager{}
 	case "managers.symphony.users":
 		manager = &users.UsersManager{}
+	case "managers.symphony.apikeys":
+		manager = &apikeys.ApiKeysManager{}
+	case "managers.symphony.enrollment":
+		manager = &enrollment.EnrollmentManager{}
 	case "managers.symphony.jobs":
 		manager = &jobs.JobsManager{}
 	case "managers.symphony.campaignversions":
@@ -81,6 +90,8 @@ func (c *SymphonyManagerFactory) CreateManager(config cm.ManagerConfig) (cm.IMan
 		manager = &activations.ActivationsManager{}
 	case "managers.symphony.activationscleanup":
 		manager = &activations.ActivationsCleanupManager{}
+	case "managers.symphony.recurringactivations":
+		manager = &activations.RecurringActivationsManager{}
 	case "managers.symphony.stage":
 		manager = &stage.StageManager{}
 	case "managers.symphony.configs":
@@ -10,10 +10,13 @@ import (
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/apikeys"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/campaignversions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/catalogversions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/devices"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instancehistory"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instances"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/jobs"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/models"
@@ -51,12 +54,16 @@ func TestCreateManager(t *testing.T) {
 	testCreateManager[*devices.DevicesManager](t, getDevicesManagerConfig())
 	testCreateManager[*solutionversions.SolutionVersionsManager](t, getSolutionVersionsManagerConfig())
 	testCreateManager[*instances.InstancesManager](t, getInstancesManagerConfig())
+	testCreateManager[*instancehistory.InstanceHistoryManager](t, getInstanceHistoryManagerConfig())
 	testCreateManager[*users.UsersManager](t, getUsersManagerConfig())
+	testCreateManager[*apikeys.ApiKeysManager](t, getApiKeysManagerConfig())
+	testCreateManager[*enrollment.EnrollmentManager](t, getEnrollmentManagerConfig())
 	testCreateManager[*jobs.JobsManager](t, getJobsManagerConfig())
 	testCreateManager[*campaignversions.CampaignVersionsManager](t, getCampaignVersionsManagerConfig())
 	testCreateManager[*catalogversions.CatalogVersionsManager](t, getCatalogVersionsManagerConfig())
 	testCreateManager[*activations.ActivationsManager](t, getActivationsManagerConfig())
 	testCreateManager[*activations.ActivationsCleanupManager](t, getActivationsCleanupManagerConfig())
+	testCreateManager[*activations.RecurringActivationsManager](t, getRecurringActivationsManagerConfig())
 	testCreateManager[*stage.StageManager](t, getStageManagerConfig())
 	testCreateManager[*configs.ConfigsManager](t, getConfigsManagerConfig())
 	testCreateManager[*sites.SitesManager](t, getSitesManagerConfig())
@@ -218,6 +225,21 @@ func getInstancesManagerConfig() cm.ManagerConfig {
 	}
 }
 
+func getInstanceHistoryManagerConfig() cm.ManagerConfig {
+	// symphony-api-no-k8s.json
+	return cm.ManagerConfig{
+		Type: "managers.symphony.instancehistory",
+		Properties: map[string]string{
+			"providers.persistentstate": "mem-state",
+		},
+		Providers: map[string]cm.ProviderConfig{
+			"mem-state": {
+				Type: "providers.symphony.state",
+			},
+		},
+	}
+}
+
 func getUsersManagerConfig() cm.ManagerConfig {
 	// symphony-api-no-k8s.json
 	return cm.ManagerConfig{
@@ -233,6 +255,34 @@ func getUsersManagerConfig() cm.ManagerConfig {
 	}
 }
 
+func getApiKeysManagerConfig() cm.ManagerConfig {
+	return cm.ManagerConfig{
+		Type: "managers.symphony.apikeys",
+		Properties: map[string]string{
+			"providers.persistentstate": "mem-state",
+		},
+		Providers: map[string]cm.ProviderConfig{
+			"mem-state": {
+				Type: "providers.symphony.state",
+			},
+		},
+	}
+}
+
+func getEnrollmentManagerConfig() cm.ManagerConfig {
+	return cm.ManagerConfig{
+		Type: "managers.symphony.enrollment",
+		Properties: map[string]string{
+			"providers.persistentstate": "mem-state",
+		},
+		Providers: map[string]cm.ProviderConfig{
+			"mem-state": {
+				Type: "providers.symphony.state",
+			},
+		},
+	}
+}
+
 func getJobsManagerConfig() cm.ManagerConfig {
 	// symphony-api-no-k8s.json
 	return cm.ManagerConfig{
@@ -319,6 +369,22 @@ func getActivationsCleanupManagerConfig() cm.ManagerConfig {
 	}
 }
 
+func getRecurringActivationsManagerConfig() cm.ManagerConfig {
+	// symphony-api-no-k8s.json
+	return cm.ManagerConfig{
+		Type: "managers.symphony.recurringactivations",
+		Properties: map[string]string{
+			"providers.persistentstate": "mem-state",
+			"singleton":                 "true",
+		},
+		Providers: map[string]cm.ProviderConfig{
+			"mem-state": {
+				Type: "providers.symphony.state",
+			},
+		},
+	}
+}
+
 func getStageManagerConfig() cm.ManagerConfig {
 	// symphony-api-no-k8s.json
 	return cm.ManagerConfig{
@@ -114,6 +114,12 @@ func (t *ModelsManager) UpsertState(ctx context.Context, name string, state mode
 }
 
 func (t *ModelsManager) ListState(ctx context.Context, namespace string) ([]model.ModelState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the models, and returns the token of the next page
+func (t *ModelsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.ModelState, string, error) {
 	ctx, span := observability.StartSpan("Models Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -130,12 +136,14 @@ func (t *ModelsManager) ListState(ctx context.Context, namespace string) ([]mode
 			"kind":      "Model",
 			"namespace": namespace,
 		},
+		ListOptions: options,
 	}
 	var models []states.StateEntry
-	models, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	models, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
 		log.ErrorfCtx(ctx, " M (Models): failed to ListState, err: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.ModelState, 0)
 	for _, t := range models {
@@ -143,12 +151,12 @@ func (t *ModelsManager) ListState(ctx context.Context, namespace string) ([]mode
 		rt, err = getModelState(t.Body)
 		if err != nil {
 			log.ErrorfCtx(ctx, " M (Models): failed to getModelState, err: %v", err)
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getModelState(body interface{}) (model.ModelState, error) {
@@ -236,6 +236,12 @@ func (m *SitesManager) DeleteSpec(ctx context.Context, name string) error {
 }
 
 func (t *SitesManager) ListState(ctx context.Context) ([]model.SiteState, error) {
+	ret, _, err := t.ListStatePage(ctx, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the sites, and returns the token of the next page
+func (t *SitesManager) ListStatePage(ctx context.Context, options states.ListOptions) ([]model.SiteState, string, error) {
 	ctx, span := observability.StartSpan("Sites Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -249,23 +255,25 @@ func (t *SitesManager) ListState(ctx context.Context) ([]model.SiteState, error)
 			"group":    model.FederationGroup,
 			"resource": "sites",
 		},
+		ListOptions: options,
 	}
 	var sites []states.StateEntry
-	sites, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	sites, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.SiteState, 0)
 	for _, t := range sites {
 		var rt model.SiteState
 		rt, err = getSiteState(t.ID, t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 func (s *SitesManager) Enabled() bool {
 	return s.VendorContext.SiteInfo.ParentSite.BaseUrl != ""
@@ -112,6 +112,12 @@ func (t *SkillsManager) UpsertState(ctx context.Context, name string, state mode
 }
 
 func (t *SkillsManager) ListState(ctx context.Context, namespace string) ([]model.SkillState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the skills, and returns the token of the next page
+func (t *SkillsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.SkillState, string, error) {
 	ctx, span := observability.StartSpan("Skills Manager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -128,12 +134,14 @@ func (t *SkillsManager) ListState(ctx context.Context, namespace string) ([]mode
 			"kind":      "Skill",
 			"namespace": namespace,
 		},
+		ListOptions: options,
 	}
 	var models []states.StateEntry
-	models, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	models, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
 		log.ErrorfCtx(ctx, " M (Skills): failed to list state, err: %v", err)
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.SkillState, 0)
 	for _, t := range models {
@@ -141,12 +149,12 @@ func (t *SkillsManager) ListState(ctx context.Context, namespace string) ([]mode
 		rt, err = getSkillState(t.Body)
 		if err != nil {
 			log.ErrorfCtx(ctx, " M (Models): failed to get skill state, err: %v", err)
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getSkillState(body interface{}) (model.SkillState, error) {
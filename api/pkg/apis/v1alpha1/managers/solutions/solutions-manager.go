@@ -112,6 +112,12 @@ func (t *SolutionsManager) UpsertState(ctx context.Context, name string, state m
 }
 
 func (t *SolutionsManager) ListState(ctx context.Context, namespace string) ([]model.SolutionState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the solutions, and returns the token of the next page
+func (t *SolutionsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.SolutionState, string, error) {
 	ctx, span := observability.StartSpan("SolutionsManager", ctx, &map[string]string{
 		"method": "ListState",
 	})
@@ -127,23 +133,25 @@ func (t *SolutionsManager) ListState(ctx context.Context, namespace string) ([]m
 			"namespace": namespace,
 			"kind":      "Solution",
 		},
+		ListOptions: options,
 	}
 	var solutions []states.StateEntry
-	solutions, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	solutions, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.SolutionState, 0)
 	for _, t := range solutions {
 		var rt model.SolutionState
 		rt, err = getSolutionState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
 }
 
 func getSolutionState(body interface{}) (model.SolutionState, error) {
@@ -52,7 +52,7 @@ func PlanForDeployment(deployment model.DeploymentSpec, state model.DeploymentSt
 			}
 		}
 	}
-	return ret.RevisedForDeletion(), nil
+	return ret.RevisedForDeletion().WithDependencies(), nil
 }
 
 func NewDeploymentState(deployment model.DeploymentSpec) (model.DeploymentState, error) {
@@ -134,6 +134,17 @@ func MergeDeploymentStates(previous *model.DeploymentState, current model.Deploy
 	}
 	return current
 }
+func copyDeploymentState(state model.DeploymentState) model.DeploymentState {
+	ret := model.DeploymentState{
+		Components:      append([]model.ComponentSpec{}, state.Components...),
+		Targets:         append([]model.TargetDesc{}, state.Targets...),
+		TargetComponent: make(map[string]string, len(state.TargetComponent)),
+	}
+	for k, v := range state.TargetComponent {
+		ret.TargetComponent[k] = v
+	}
+	return ret
+}
 func findComponentProviders(component string, deployment model.DeploymentSpec) map[string]model.TargetState {
 	ret := make(map[string]model.TargetState)
 	for k, v := range deployment.Assignments {
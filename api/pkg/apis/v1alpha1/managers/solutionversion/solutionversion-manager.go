@@ -12,7 +12,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,7 @@ import (
 	config "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/keylock"
 	secret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 )
 
@@ -52,6 +55,7 @@ const (
 
 	Summary         = "Summary"
 	DeploymentState = "DeployState"
+	FencingToken    = "FencingToken"
 )
 
 type SolutionVersionManager struct {
@@ -64,6 +68,13 @@ type SolutionVersionManager struct {
 	TargetNames     []string
 	TargetNamespace string
 	ApiClientHttp   api_utils.ApiClient
+	// MaxConcurrentSteps is the maximum number of independent deployment steps applied at the same time
+	MaxConcurrentSteps int
+}
+
+// fencingState is the latest fencing token of the instance lock that saved the state of an instance
+type fencingState struct {
+	Token int64 `json:"token"`
 }
 
 type SolutionVersionManagerDeploymentState struct {
@@ -128,6 +139,15 @@ func (s *SolutionVersionManager) Init(context *contexts.VendorContext, config ma
 		s.TargetNamespace = v
 	}
 
+	s.MaxConcurrentSteps = 1
+	if v, ok := config.Properties["maxConcurrentSteps"]; ok && strings.TrimSpace(v) != "" {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n < 1 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid maxConcurrentSteps '%s', expected a positive integer", v), v1alpha2.BadConfig)
+		}
+		s.MaxConcurrentSteps = n
+	}
+
 	if s.IsTarget {
 		if len(s.TargetNames) == 0 {
 			return errors.New("target mode is set but target name is not set")
@@ -248,6 +268,19 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 		return summary, err
 	}
 
+	// a rollout that paused between waves continues after the waves that already succeeded
+	var pausedSummary *model.SummarySpec
+	if deployment.Instance.Spec.RolloutStrategy != nil && !remove && targetName == "" && !s.IsTarget {
+		pausedSummary = s.getPausedRollout(ctx, summaryId, deployment, namespace)
+		if pausedSummary != nil && time.Now().UTC().Before(*pausedSummary.Rollout.ResumeAt) {
+			log.InfofCtx(ctx, " M (SolutionVersion): rollout is paused until %s", pausedSummary.Rollout.ResumeAt.Format(time.RFC3339))
+			s.requeueDeployment(ctx, deployment, namespace, *pausedSummary.Rollout.ResumeAt)
+			return *pausedSummary, nil
+		}
+	}
+	queuedDeployment := deployment
+	paused := false
+
 	err = s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
 	if err != nil {
 		log.ErrorfCtx(ctx, " M (SolutionVersion): failed to save summary progress: %+v", err)
@@ -255,6 +288,11 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 	}
 	defer func() {
 		if r := recover(); r == nil {
+			if paused {
+				// the summary stays in progress until the rollout continues
+				s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
+				return
+			}
 			log.DebugfCtx(ctx, " M (SolutionVersion): Reconcile conclude Summary. Namespace: %v, deployment instance: %v, summary message: %v", namespace, deployment.Instance, summary.SummaryMessage)
 			s.concludeSummary(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
 		} else {
@@ -338,6 +376,7 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 	someStepsRan := false
 
 	targetResult := make(map[string]int)
+	touchedTargets := make(map[string]bool)
 
 	summary.PlannedDeployment = 0
 	for _, step := range plan.Steps {
@@ -353,149 +392,246 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 	// DO NOT REMOVE THIS COMMENT
 	// gofail: var beforeProviders string
 
+	// wavePlan holds the steps of the current rollout wave, or the whole plan without a rollout strategy
+	wavePlan := plan
+	var testState model.DeploymentState
+	if previousDesiredState != nil {
+		testState = MergeDeploymentStates(&previousDesiredState.State, currentState)
+	}
 	plannedCount := 0
 	planSuccessCount := 0
-	for _, step := range plan.Steps {
+	var stepError error
+	var fatalError error
+
+	// applyStep runs on a worker goroutine, so it works on its own copy of the deployment and
+	// leaves the summary to be updated by onStepDone
+	applyStep := func(index int) stepResult {
+		step := wavePlan.Steps[index]
+		result := stepResult{index: index}
 		log.DebugfCtx(ctx, " M (SolutionVersion): processing step with Role %s on target %s", step.Role, step.Target)
 		for _, component := range step.Components {
 			log.DebugfCtx(ctx, " M (SolutionVersion): processing component %s with action %s", component.Component.Name, component.Action)
 		}
 		if s.IsTarget && !api_utils.ContainsString(s.TargetNames, step.Target) {
-			continue
+			result.filtered = true
+			return result
 		}
 
 		if targetName != "" && targetName != step.Target {
-			continue
+			result.filtered = true
+			return result
 		}
 
-		plannedCount++
-
-		dep.ActiveTarget = step.Target
+		stepDep := dep
+		stepDep.ActiveTarget = step.Target
+		instanceSpec := *dep.Instance.Spec
+		instanceSpec.Metadata = make(map[string]string, len(col)+1)
+		for k, v := range col {
+			instanceSpec.Metadata[k] = v
+		}
 		agent := findAgentFromDeploymentState(mergedState, step.Target)
 		if agent != "" {
-			col[ENV_NAME] = agent
-		} else {
-			delete(col, ENV_NAME)
-		}
-		var override tgt.ITargetProvider
-		role := step.Role
-		if role == "container" {
-			role = "instance"
-		}
-		if v, ok := s.TargetProviders[role]; ok {
-			override = v
+			instanceSpec.Metadata[ENV_NAME] = agent
 		}
-		var provider providers.IProvider
-		if override == nil {
-			targetSpec := s.getTargetStateForStep(step, deployment, previousDesiredState)
-			provider, err = sp.CreateProviderForTargetRole(s.Context, step.Role, targetSpec, override)
-			if err != nil {
-				summary.SummaryMessage = "failed to create provider:" + err.Error()
-				log.ErrorfCtx(ctx, " M (SolutionVersion): failed to create provider: %+v", err)
-				return summary, err
-			}
-		} else {
-			provider = override
+		stepDep.Instance.Spec = &instanceSpec
+
+		provider, err := s.getProviderForStep(step, s.getTargetStateForStep(step, deployment, previousDesiredState))
+		if err != nil {
+			result.providerError = err
+			return result
 		}
-		var stepError error
-		var componentResults = make(map[string]model.ComponentResultSpec)
 		if previousDesiredState != nil {
-			testState := MergeDeploymentStates(&previousDesiredState.State, currentState)
-			if s.canSkipStep(ctx, step, step.Target, provider.(tgt.ITargetProvider), previousDesiredState.State.Components, testState) {
-				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults})
+			if s.canSkipStep(ctx, step, step.Target, provider, previousDesiredState.State.Components, testState) {
 				log.InfofCtx(ctx, " M (SolutionVersion): skipping step with role %s on target %s", step.Role, step.Target)
-				targetResult[step.Target] = 1
-				planSuccessCount++
-				summary.CurrentDeployed += len(step.Components)
-				continue
+				result.skipped = true
+				result.componentResults = make(map[string]model.ComponentResultSpec)
+				return result
 			}
 		}
 		log.DebugfCtx(ctx, " M (SolutionVersion): applying step with Role %s on target %s", step.Role, step.Target)
-		someStepsRan = true
 		retryCount := 1
 		//TODO: set to 1 for now. Although retrying can help to handle transient errors, in more cases
 		// an error condition can't be resolved quickly.
-
-		// for _, component := range step.Components {
-		// 	for k, v := range component.Component.Properties {
-		// 		if strV, ok := v.(string); ok {
-		// 			parser := api_utils.NewParser(strV)
-		// 			eCtx := s.VendorContext.EvaluationContext.Clone()
-		// 			eCtx.DeploymentSpec = deployment
-		// 			eCtx.Component = component.Component.Name
-		// 			val, err := parser.Eval(*eCtx)
-		// 			if err == nil {
-		// 				component.Component.Properties[k] = val
-		// 			} else {
-		// 				log.ErrorfCtx(ctx, " M (SolutionVersion): failed to evaluate property: %+v", err)
-		// 				summary.SummaryMessage = fmt.Sprintf("failed to evaluate property '%s' on component '%s: %s", k, component.Component.Name, err.Error())
-		// 				s.saveSummary(ctx, deployment, summary)
-		// 				return summary, err
-		// 			}
-		// 		}
-		// 	}
-		// }
-
-		defaultScope := deployment.Instance.Spec.Scope
-		// ensure to restore the original scope defined in instance in case the scope is changed during the deployment
-		defer func() {
-			deployment.Instance.Spec.Scope = defaultScope
-		}()
 		for i := 0; i < retryCount; i++ {
-			deployment.Instance.Spec.Scope = getCurrentApplicationScope(ctx, deployment.Instance, deployment.Targets[step.Target])
-			componentResults, stepError = (provider.(tgt.ITargetProvider)).Apply(ctx, dep, step, deployment.IsDryRun)
-			if stepError == nil {
-				targetResult[step.Target] = 1
-				summary.AllAssignedDeployed = plannedCount == planSuccessCount
-				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults})
-				err = s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
-				if err != nil {
-					log.ErrorfCtx(ctx, " M (SolutionVersion): failed to save summary progress: %+v", err)
-					return summary, err
-				}
+			instanceSpec.Scope = getCurrentApplicationScope(ctx, deployment.Instance, deployment.Targets[step.Target])
+			result.componentResults, result.err = provider.Apply(ctx, stepDep, step, deployment.IsDryRun)
+			if result.err == nil {
 				break
-			} else {
-				targetResult[step.Target] = 0
-				summary.AllAssignedDeployed = false
-				targetResultStatus := fmt.Sprintf("%s Failed", deploymentType)
-				targetResultMessage := fmt.Sprintf("An error occurred in %s, err: %s", deploymentType, stepError.Error())
-				summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: targetResultStatus, Message: targetResultMessage, ComponentResults: componentResults}) // TODO: this keeps only the last error on the target
-				time.Sleep(5 * time.Second)                                                                                                                                   //TODO: make this configurable?
 			}
-			deployment.Instance.Spec.Scope = defaultScope
+			time.Sleep(5 * time.Second) //TODO: make this configurable?
 		}
-		if stepError != nil {
-			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to execute deployment step: %+v", stepError)
+		return result
+	}
 
-			successCount := 0
-			for _, v := range targetResult {
-				successCount += v
+	// onStepDone runs on the reconciling goroutine each time a step completes. Returning false
+	// stops scheduling further steps; steps that are already running are allowed to finish.
+	onStepDone := func(result stepResult) bool {
+		if result.filtered {
+			return true
+		}
+		step := wavePlan.Steps[result.index]
+		plannedCount++
+		if result.providerError != nil {
+			summary.SummaryMessage = "failed to create provider:" + result.providerError.Error()
+			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to create provider: %+v", result.providerError)
+			if fatalError == nil {
+				fatalError = result.providerError
 			}
+			return false
+		}
+		if result.skipped {
+			summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: result.componentResults})
+			targetResult[step.Target] = 1
+			planSuccessCount++
+			summary.CurrentDeployed += len(step.Components)
+			return true
+		}
+		someStepsRan = true
+		touchedTargets[step.Target] = true
+		if result.err != nil {
+			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to execute deployment step: %+v", result.err)
+			targetResult[step.Target] = 0
+			summary.AllAssignedDeployed = false
+			targetResultStatus := fmt.Sprintf("%s Failed", deploymentType)
+			targetResultMessage := fmt.Sprintf("An error occurred in %s, err: %s", deploymentType, result.err.Error())
+			summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: targetResultStatus, Message: targetResultMessage, ComponentResults: result.componentResults}) // TODO: this keeps only the last error on the target
 			deployedCount := 0
-			for _, ret := range componentResults {
+			for _, ret := range result.componentResults {
 				if (!remove && ret.Status == v1alpha2.Updated) || (remove && ret.Status == v1alpha2.Deleted) {
 					// TODO: need to ensure the status updated correctly on returning from target providers.
 					deployedCount += 1
 				}
 			}
 			summary.CurrentDeployed += deployedCount
-			if deployment.IsDryRun || deployment.IsInActive {
-				summary.SuccessCount = 0
-			} else {
-				summary.SuccessCount = successCount
+			if stepError == nil {
+				stepError = result.err
 			}
-			summary.AllAssignedDeployed = plannedCount == planSuccessCount
-			err = stepError
-			return summary, err
+			return false
 		}
+		targetResult[step.Target] = 1
 		planSuccessCount++
+		summary.AllAssignedDeployed = plannedCount == planSuccessCount
+		summary.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: result.componentResults})
 		summary.CurrentDeployed += len(step.Components)
+		saveErr := s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
+		if saveErr != nil {
+			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to save summary progress: %+v", saveErr)
+			if fatalError == nil {
+				fatalError = saveErr
+			}
+			return false
+		}
+		log.DebugfCtx(ctx, " M (SolutionVersion): reconcile save summary progress: current deployed %v out of total %v deployments", summary.CurrentDeployed, summary.PlannedDeployment)
+		return true
+	}
+
+	waves := [][]string{plan.GetTargets()}
+	strategy := deployment.Instance.Spec.RolloutStrategy
+	var pause time.Duration
+	if strategy != nil && !remove && targetName == "" && !s.IsTarget {
+		if strategy.PauseBetweenWaves != "" {
+			pause, err = time.ParseDuration(strategy.PauseBetweenWaves)
+			if err != nil {
+				summary.SummaryMessage = "invalid pauseBetweenWaves in rollout strategy: " + err.Error()
+				log.ErrorfCtx(ctx, " M (SolutionVersion): invalid pauseBetweenWaves in rollout strategy: %+v", err)
+				return summary, err
+			}
+		}
+		waves = strategy.SplitIntoWaves(plan.GetTargetGroups())
+		summary.Rollout = &model.RolloutResultSpec{
+			Waves: make([]model.RolloutWaveSpec, 0, len(waves)),
+		}
+		for _, wave := range waves {
+			summary.Rollout.Waves = append(summary.Rollout.Waves, model.RolloutWaveSpec{Targets: wave, Status: model.RolloutWavePending})
+		}
+	}
+	for w, wave := range waves {
+		if summary.Rollout != nil && pausedSummary != nil && w < len(pausedSummary.Rollout.Waves) &&
+			pausedSummary.Rollout.Waves[w].Status == model.RolloutWaveSucceeded && reflect.DeepEqual(pausedSummary.Rollout.Waves[w].Targets, wave) {
+			log.InfofCtx(ctx, " M (SolutionVersion): rollout wave %d of %d on targets %v already succeeded", w+1, len(waves), wave)
+			summary.Rollout.Waves[w] = pausedSummary.Rollout.Waves[w]
+			for _, step := range plan.ForTargets(wave).Steps {
+				summary.CurrentDeployed += len(step.Components)
+			}
+			for _, target := range wave {
+				targetResult[target] = 1
+				// the targets are rolled back too if a later wave fails
+				touchedTargets[target] = true
+				if result, ok := pausedSummary.TargetResults[target]; ok {
+					summary.UpdateTargetResult(target, result)
+				}
+			}
+			continue
+		}
+		if summary.Rollout != nil {
+			log.InfofCtx(ctx, " M (SolutionVersion): starting rollout wave %d of %d on targets %v", w+1, len(waves), wave)
+			wavePlan = plan.ForTargets(wave)
+			summary.Rollout.CurrentWave = w
+			summary.Rollout.Waves[w].Status = model.RolloutWaveRunning
+			err = s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
+			if err != nil {
+				log.ErrorfCtx(ctx, " M (SolutionVersion): failed to save summary progress: %+v", err)
+				return summary, err
+			}
+		}
+		runSteps(wavePlan, s.MaxConcurrentSteps, applyStep, onStepDone)
+		if summary.Rollout == nil || fatalError != nil {
+			break
+		}
+		if stepError != nil {
+			summary.Rollout.Waves[w].Status = model.RolloutWaveFailed
+			summary.Rollout.Waves[w].Message = "failed to deploy"
+			break
+		}
+		if !deployment.IsDryRun {
+			healthError := s.checkWaveHealth(ctx, dep, previousDesiredState, wavePlan, strategy.HealthCheck, namespace)
+			if healthError != nil {
+				log.ErrorfCtx(ctx, " M (SolutionVersion): rollout wave %d failed the health check: %+v", w+1, healthError)
+				summary.Rollout.Waves[w].Status = model.RolloutWaveFailed
+				summary.Rollout.Waves[w].Message = healthError.Error()
+				stepError = healthError
+				break
+			}
+		}
+		summary.Rollout.Waves[w].Status = model.RolloutWaveSucceeded
 		err = s.saveSummaryProgress(ctx, deployment.Instance.ObjectMeta.Name, summaryId, deployment.Generation, deployment.Hash, summary, namespace)
 		if err != nil {
 			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to save summary progress: %+v", err)
 			return summary, err
 		}
-		log.DebugfCtx(ctx, " M (SolutionVersion): reconcile save summary progress: current deployed %v out of total %v deployments", summary.CurrentDeployed, summary.PlannedDeployment)
+		if w < len(waves)-1 && pause > 0 && !deployment.IsDryRun {
+			// the deployment is queued again instead of waiting, so the instance isn't locked
+			// during the pause
+			resumeAt := time.Now().UTC().Add(pause)
+			summary.Rollout.ResumeAt = &resumeAt
+			summary.AllAssignedDeployed = false
+			log.InfofCtx(ctx, " M (SolutionVersion): pausing the rollout until %s before wave %d of %d", resumeAt.Format(time.RFC3339), w+2, len(waves))
+			paused = true
+			s.requeueDeployment(ctx, queuedDeployment, namespace, resumeAt)
+			return summary, nil
+		}
+	}
+
+	if fatalError != nil {
+		err = fatalError
+		return summary, err
+	}
+	if stepError != nil {
+		successCount := 0
+		for _, v := range targetResult {
+			successCount += v
+		}
+		if deployment.IsDryRun || deployment.IsInActive {
+			summary.SuccessCount = 0
+		} else {
+			summary.SuccessCount = successCount
+		}
+		summary.AllAssignedDeployed = false
+		if deployment.Instance.Spec.RollbackPolicy.IsEnabled() && !remove && !deployment.IsDryRun {
+			summary.Rollback = s.rollback(ctx, deployment, previousDesiredState, mergedState, touchedTargets)
+		}
+		err = stepError
+		return summary, err
 	}
 
 	mergedState.ClearAllRemoved()
@@ -504,6 +640,11 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 	// gofail: var beforeDeploymentError string
 
 	if !deployment.IsDryRun {
+		err = s.checkFence(ctx, deployment.Instance.ObjectMeta.Name, namespace)
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (SolutionVersion): not saving deployment state of %s: %+v", deployment.Instance.ObjectMeta.Name, err)
+			return summary, err
+		}
 		if len(mergedState.TargetComponent) == 0 && remove {
 			log.DebugfCtx(ctx, " M (SolutionVersion): no assigned components to manage, deleting state")
 			s.DeleteDeploymentState(ctx, deployment.Instance.ObjectMeta.Name, namespace)
@@ -531,6 +672,269 @@ func (s *SolutionVersionManager) Reconcile(ctx context.Context, deployment model
 	return summary, nil
 }
 
+// getPausedRollout returns the summary of the deployment if its rollout paused between waves
+func (s *SolutionVersionManager) getPausedRollout(ctx context.Context, summaryId string, deployment model.DeploymentSpec, namespace string) *model.SummarySpec {
+	previous, err := s.GetSummary(ctx, summaryId, deployment.Instance.ObjectMeta.Name, namespace)
+	if err != nil || previous.State != model.SummaryStateRunning || previous.Generation != deployment.Generation || previous.DeploymentHash != deployment.Hash {
+		return nil
+	}
+	if previous.Summary.Rollout == nil || previous.Summary.Rollout.ResumeAt == nil {
+		return nil
+	}
+	return &previous.Summary
+}
+
+// requeueDeployment schedules a job for the deployment at the given time, to continue a paused
+// rollout. The jobs manager keeps the job, so the rollout resumes after a restart, and it keeps
+// one job per instance, so a rollout that is triggered again during the pause resumes once.
+func (s *SolutionVersionManager) requeueDeployment(ctx context.Context, deployment model.DeploymentSpec, namespace string, resumeAt time.Time) {
+	data, err := json.Marshal(deployment)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (SolutionVersion): failed to queue the rollout of %s again: %+v", deployment.Instance.ObjectMeta.Name, err)
+		return
+	}
+	err = s.VendorContext.Publish("schedule", v1alpha2.Event{
+		Metadata: map[string]string{
+			"objectType": "deployment",
+			"namespace":  namespace,
+		},
+		Body: v1alpha2.ScheduledJobData{
+			Due: resumeAt,
+			Job: v1alpha2.JobData{
+				Id:     deployment.Instance.ObjectMeta.Name,
+				Scope:  namespace,
+				Action: v1alpha2.JobUpdate,
+				Data:   data,
+			},
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (SolutionVersion): failed to queue the rollout of %s again: %+v", deployment.Instance.ObjectMeta.Name, err)
+	}
+}
+
+// checkWaveHealth verifies that the targets of a rollout wave pass the health check of the rollout strategy
+func (s *SolutionVersionManager) checkWaveHealth(ctx context.Context, deployment model.DeploymentSpec, previousDesiredState *SolutionVersionManagerDeploymentState, wavePlan model.DeploymentPlan, healthCheck *model.RolloutHealthCheckSpec, namespace string) error {
+	if healthCheck == nil {
+		return nil
+	}
+	if healthCheck.ComponentsReady {
+		for _, step := range wavePlan.Steps {
+			updated := step.GetUpdatedComponentSteps()
+			if len(updated) == 0 {
+				continue
+			}
+			provider, err := s.getProviderForStep(step, s.getTargetStateForStep(step, deployment, previousDesiredState))
+			if err != nil {
+				return err
+			}
+			dep := deployment
+			dep.ActiveTarget = step.Target
+			components, err := provider.Get(ctx, dep, updated)
+			if err != nil {
+				return fmt.Errorf("failed to get components on target %s: %s", step.Target, err.Error())
+			}
+			for _, c := range updated {
+				found := false
+				for _, component := range components {
+					if component.Name == c.Component.Name {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return fmt.Errorf("component %s is not ready on target %s", c.Component.Name, step.Target)
+				}
+			}
+		}
+	}
+	if len(healthCheck.StatusProperties) > 0 {
+		if s.ApiClientHttp == nil {
+			return errors.New("target status can't be checked without an API client")
+		}
+		for _, target := range wavePlan.GetTargets() {
+			state, err := s.ApiClientHttp.GetTarget(ctx, target, namespace, s.Context.SiteInfo.CurrentSite.Username, s.Context.SiteInfo.CurrentSite.Password)
+			if err != nil {
+				return fmt.Errorf("failed to get status of target %s: %s", target, err.Error())
+			}
+			for k, v := range healthCheck.StatusProperties {
+				if state.Status.Properties[k] != v {
+					return fmt.Errorf("target %s reports status property %s='%s', expected '%s'", target, k, state.Status.Properties[k], v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type stepResult struct {
+	index            int
+	filtered         bool
+	skipped          bool
+	componentResults map[string]model.ComponentResultSpec
+	err              error
+	providerError    error
+}
+
+// runSteps executes the steps of a plan following their dependencies, with at most limit steps
+// in flight. apply is called on worker goroutines, while done is called on the calling goroutine
+// as each step completes. Once done returns false, no more steps are started and runSteps returns
+// after the running steps have completed. With a limit of 1, steps run in plan order.
+func runSteps(plan model.DeploymentPlan, limit int, apply func(index int) stepResult, done func(result stepResult) bool) {
+	if limit < 1 {
+		limit = 1
+	}
+	waiting := make([]int, len(plan.Steps))
+	dependents := make([][]int, len(plan.Steps))
+	ready := make([]int, 0)
+	for i, step := range plan.Steps {
+		waiting[i] = len(step.Dependencies)
+		for _, d := range step.Dependencies {
+			dependents[d] = append(dependents[d], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	results := make(chan stepResult)
+	running := 0
+	stopped := false
+	for {
+		for !stopped && running < limit && len(ready) > 0 {
+			index := ready[0]
+			ready = ready[1:]
+			running++
+			go func(index int) {
+				results <- apply(index)
+			}(index)
+		}
+		if running == 0 {
+			return
+		}
+		result := <-results
+		running--
+		if !done(result) {
+			stopped = true
+		}
+		if stopped {
+			continue
+		}
+		for _, d := range dependents[result.index] {
+			waiting[d]--
+			if waiting[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+		sort.Ints(ready)
+	}
+}
+
+// rollback replays the last successful deployment state on the targets touched by a failed reconcile.
+// Steps are applied in reverse order so that dependents are restored before their dependencies.
+func (s *SolutionVersionManager) rollback(ctx context.Context, deployment model.DeploymentSpec, previousDesiredState *SolutionVersionManagerDeploymentState, failedState model.DeploymentState, touchedTargets map[string]bool) *model.RollbackResultSpec {
+	ctx, span := observability.StartSpan("SolutionVersion Manager", ctx, &map[string]string{
+		"method": "rollback",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	result := &model.RollbackResultSpec{
+		TargetResults: make(map[string]model.TargetResultSpec),
+	}
+	if previousDesiredState == nil || previousDesiredState.Spec.SolutionVersion.Spec == nil || previousDesiredState.Spec.Instance.Spec == nil {
+		log.InfofCtx(ctx, " M (SolutionVersion): no previous deployment state of instance %s to roll back to", deployment.Instance.ObjectMeta.Name)
+		result.Status = model.RollbackSkipped
+		result.Message = "no previous successful deployment state to roll back to"
+		return result
+	}
+	log.InfofCtx(ctx, " M (SolutionVersion): rolling back instance %s to solution version %s", deployment.Instance.ObjectMeta.Name, previousDesiredState.Spec.SolutionVersionName)
+
+	// components introduced by the failed deployment are removed, all previous components are re-applied
+	restoreState := MergeDeploymentStates(&failedState, copyDeploymentState(previousDesiredState.State))
+	var plan model.DeploymentPlan
+	plan, err = PlanForDeployment(previousDesiredState.Spec, restoreState)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (SolutionVersion): failed to plan for rollback: %+v", err)
+		result.Status = model.RollbackFailed
+		result.Message = "failed to plan for rollback: " + err.Error()
+		return result
+	}
+
+	// the instance spec is shared with the saved state, so the rollback changes a copy
+	dep := previousDesiredState.Spec
+	instanceSpec := *dep.Instance.Spec
+	dep.Instance.Spec = &instanceSpec
+	col := api_utils.MergeCollection(dep.SolutionVersion.Spec.Metadata, dep.Instance.Spec.Metadata)
+	dep.Instance.Spec.Metadata = col
+	defaultScope := dep.Instance.Spec.Scope
+
+	failedTargets := make([]string, 0)
+	for i := len(plan.Steps) - 1; i >= 0; i-- {
+		step := plan.Steps[i]
+		if !touchedTargets[step.Target] {
+			continue
+		}
+		log.DebugfCtx(ctx, " M (SolutionVersion): rolling back step with Role %s on target %s", step.Role, step.Target)
+
+		dep.ActiveTarget = step.Target
+		agent := findAgentFromDeploymentState(restoreState, step.Target)
+		if agent != "" {
+			col[ENV_NAME] = agent
+		} else {
+			delete(col, ENV_NAME)
+		}
+		targetState, ok := dep.Targets[step.Target]
+		if !ok {
+			targetState = deployment.Targets[step.Target]
+		}
+
+		var componentResults map[string]model.ComponentResultSpec
+		provider, stepError := s.getProviderForStep(step, targetState)
+		if stepError == nil {
+			dep.Instance.Spec.Scope = getCurrentApplicationScope(ctx, dep.Instance, targetState)
+			componentResults, stepError = provider.Apply(ctx, dep, step, false)
+			dep.Instance.Spec.Scope = defaultScope
+		}
+		if stepError != nil {
+			log.ErrorfCtx(ctx, " M (SolutionVersion): failed to roll back step on target %s: %+v", step.Target, stepError)
+			if !api_utils.ContainsString(failedTargets, step.Target) {
+				failedTargets = append(failedTargets, step.Target)
+			}
+			result.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "Rollback Failed", Message: stepError.Error(), ComponentResults: componentResults})
+		} else {
+			result.UpdateTargetResult(step.Target, model.TargetResultSpec{Status: "OK", Message: "", ComponentResults: componentResults})
+		}
+	}
+
+	if len(failedTargets) > 0 {
+		result.Status = model.RollbackFailed
+		result.Message = fmt.Sprintf("failed to roll back targets: %s", strings.Join(failedTargets, ", "))
+		err = errors.New(result.Message)
+	} else {
+		result.Status = model.RollbackSucceeded
+	}
+	return result
+}
+
+// getProviderForStep returns the configured provider override for the step role, or creates a provider
+// from the target bindings.
+func (s *SolutionVersionManager) getProviderForStep(step model.DeploymentStep, targetState model.TargetState) (tgt.ITargetProvider, error) {
+	role := step.Role
+	if role == "container" {
+		role = "instance"
+	}
+	if v, ok := s.TargetProviders[role]; ok {
+		return v, nil
+	}
+	provider, err := sp.CreateProviderForTargetRole(s.Context, step.Role, targetState, nil)
+	if err != nil {
+		return nil, err
+	}
+	return provider.(tgt.ITargetProvider), nil
+}
+
 // The deployment spec may have changed, so the previous target is not in the new deployment anymore
 func (s *SolutionVersionManager) getTargetStateForStep(step model.DeploymentStep, deployment model.DeploymentSpec, previousDeploymentState *SolutionVersionManagerDeploymentState) model.TargetState {
 	//first find the target spec in the deployment
@@ -543,7 +947,65 @@ func (s *SolutionVersionManager) getTargetStateForStep(step model.DeploymentStep
 	return targetSpec
 }
 
+// checkFence makes sure the lock of the instance is still held, and that it hasn't been taken by
+// another reconcile since. The fencing token is recorded, so that a reconcile whose lease expired
+// can't overwrite the state saved by the next one. Key locks that aren't leases are always held.
+func (s *SolutionVersionManager) checkFence(ctx context.Context, instance string, namespace string) error {
+	fenced, ok := s.KeyLockProvider.(keylock.IFencedKeyLockProvider)
+	if !ok {
+		return nil
+	}
+	token, held := fenced.FencingToken(api_utils.GenerateKeyLockName(namespace, instance))
+	if !held {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("lock of instance %s in namespace %s is lost", instance, namespace), v1alpha2.Conflict)
+	}
+	metadata := map[string]interface{}{
+		"namespace": namespace,
+		"group":     model.SolutionVersionGroup,
+		"version":   "v1",
+		"resource":  FencingToken,
+	}
+	etag := ""
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID:       instance,
+		Metadata: metadata,
+	})
+	if err == nil {
+		var fence fencingState
+		jData, _ := json.Marshal(entry.Body)
+		err = json.Unmarshal(jData, &fence)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "fencing token is not valid", v1alpha2.InternalError)
+		}
+		if fence.Token > token {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("instance %s in namespace %s has been locked by another reconcile", instance, namespace), v1alpha2.Conflict)
+		}
+		if fence.Token == token {
+			return nil
+		}
+		etag = entry.ETag
+	} else if !v1alpha2.IsNotFound(err) {
+		return err
+	}
+	_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   instance,
+			Body: fencingState{Token: token},
+		},
+		ETag:     &etag,
+		Metadata: metadata,
+	})
+	if err != nil && v1alpha2.GetErrorState(err) == v1alpha2.Conflict {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("instance %s in namespace %s has been locked by another reconcile", instance, namespace), v1alpha2.Conflict)
+	}
+	return err
+}
+
 func (s *SolutionVersionManager) saveSummary(ctx context.Context, objectName string, summaryId string, generation string, hash string, summary model.SummarySpec, state model.SummaryState, namespace string) error {
+	if err := s.checkFence(ctx, objectName, namespace); err != nil {
+		log.ErrorfCtx(ctx, " M (SolutionVersion): not saving summary of %s: %+v", objectName, err)
+		return err
+	}
 	// TODO: delete this state when time expires. This should probably be invoked by the vendor (via GetSummary method, for instance)
 	log.DebugfCtx(ctx, " M (SolutionVersion): saving summary, objectName: %s, summaryId: %s, state: %v, namespace: %s, jobid: %s, hash %s, targetCount %d, successCount %d",
 		objectName, summaryId, state, namespace, summary.JobID, hash, summary.TargetCount, summary.SuccessCount)
@@ -8,11 +8,15 @@ package solutionversion
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/mock"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -970,3 +974,499 @@ func TestMockApplyWithError(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Equal(t, 0, summary.SuccessCount)
 }
+
+type rollbackTestTargetProvider struct {
+	Applied []model.DeploymentStep
+}
+
+func (r *rollbackTestTargetProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (r *rollbackTestTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{}
+}
+func (r *rollbackTestTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	return []model.ComponentSpec{}, nil
+}
+func (r *rollbackTestTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	r.Applied = append(r.Applied, step)
+	for _, c := range step.Components {
+		if c.Component.Properties["fail"] == true {
+			return nil, errors.New("apply failed")
+		}
+	}
+	return map[string]model.ComponentResultSpec{}, nil
+}
+
+func rollbackTestDeployment(version string, failOnB bool, rollback bool) model.DeploymentSpec {
+	deployment := model.DeploymentSpec{
+		SolutionVersionName: "app-" + version,
+		Instance: model.InstanceState{
+			ObjectMeta: model.ObjectMeta{
+				Name: "instance",
+			},
+			Spec: &model.InstanceSpec{
+				RollbackPolicy: &model.RollbackPolicySpec{
+					Enabled: rollback,
+				},
+			},
+		},
+		SolutionVersion: model.SolutionVersionState{
+			Spec: &model.SolutionVersionSpec{
+				Components: []model.ComponentSpec{
+					{
+						Name:       "a",
+						Type:       "mock",
+						Properties: map[string]interface{}{"version": version},
+					},
+					{
+						Name:       "b",
+						Type:       "mock",
+						Properties: map[string]interface{}{"version": version, "fail": failOnB},
+					},
+				},
+			},
+		},
+		Assignments: map[string]string{
+			"T1": "{a}",
+			"T2": "{b}",
+		},
+		Targets: map[string]model.TargetState{
+			"T1": {
+				Spec: &model.TargetSpec{},
+			},
+			"T2": {
+				Spec: &model.TargetSpec{},
+			},
+		},
+	}
+	deployment.Instance.ObjectMeta.SetGuid("rollback-test")
+	return deployment
+}
+
+func newRollbackTestManager(targetProvider target.ITargetProvider) SolutionVersionManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	keyLockProvider := &memorykeylock.MemoryKeyLockProvider{}
+	keyLockProvider.Init(memorykeylock.MemoryKeyLockProviderConfig{Mode: memorykeylock.Dedicated})
+	vendorContext := &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendorContext.Init(&pubSubProvider)
+	manager := SolutionVersionManager{
+		TargetProviders: map[string]target.ITargetProvider{
+			"mock": targetProvider,
+		},
+		SummaryManager: SummaryManager{
+			StateProvider: stateProvider,
+		},
+		KeyLockProvider: keyLockProvider,
+	}
+	manager.VendorContext = vendorContext
+	return manager
+}
+
+func TestReconcileRollbackOnFailure(t *testing.T) {
+	targetProvider := &rollbackTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	summary, err := manager.Reconcile(context.Background(), rollbackTestDeployment("1", false, true), false, "default", "")
+	assert.Nil(t, err)
+	assert.Nil(t, summary.Rollback)
+
+	targetProvider.Applied = nil
+	summary, err = manager.Reconcile(context.Background(), rollbackTestDeployment("2", true, true), false, "default", "")
+	assert.NotNil(t, err)
+	assert.NotNil(t, summary.Rollback)
+	assert.Equal(t, model.RollbackSucceeded, summary.Rollback.Status)
+	assert.Equal(t, "OK", summary.Rollback.TargetResults["T1"].Status)
+	assert.Equal(t, "OK", summary.Rollback.TargetResults["T2"].Status)
+
+	// two steps of the failed deployment, followed by the rollback steps in reverse order
+	assert.Equal(t, 4, len(targetProvider.Applied))
+	assert.Equal(t, "T2", targetProvider.Applied[2].Target)
+	assert.Equal(t, "1", targetProvider.Applied[2].Components[0].Component.Properties["version"])
+	assert.Equal(t, "T1", targetProvider.Applied[3].Target)
+	assert.Equal(t, "1", targetProvider.Applied[3].Components[0].Component.Properties["version"])
+}
+
+func TestRollbackDoesNotChangePreviousState(t *testing.T) {
+	targetProvider := &rollbackTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	previous := rollbackTestDeployment("1", false, true)
+	previous.Instance.Spec.Metadata = map[string]string{"instance": "1"}
+	previous.SolutionVersion.Spec.Metadata = map[string]string{"solution": "1"}
+	state, err := NewDeploymentState(previous)
+	assert.Nil(t, err)
+	previousDesiredState := &SolutionVersionManagerDeploymentState{Spec: previous, State: state}
+
+	result := manager.rollback(context.Background(), rollbackTestDeployment("2", true, true), previousDesiredState, model.DeploymentState{}, map[string]bool{"T1": true, "T2": true})
+	assert.Equal(t, model.RollbackSucceeded, result.Status)
+	assert.Equal(t, map[string]string{"instance": "1"}, previousDesiredState.Spec.Instance.Spec.Metadata)
+	assert.Equal(t, 2, len(targetProvider.Applied))
+	assert.Equal(t, "1", targetProvider.Applied[0].Components[0].Component.Properties["version"])
+}
+
+func TestReconcileRollbackWithoutPreviousState(t *testing.T) {
+	targetProvider := &rollbackTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	summary, err := manager.Reconcile(context.Background(), rollbackTestDeployment("1", true, true), false, "default", "")
+	assert.NotNil(t, err)
+	assert.NotNil(t, summary.Rollback)
+	assert.Equal(t, model.RollbackSkipped, summary.Rollback.Status)
+}
+
+func TestReconcileRollbackDisabled(t *testing.T) {
+	targetProvider := &rollbackTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	_, err := manager.Reconcile(context.Background(), rollbackTestDeployment("1", false, false), false, "default", "")
+	assert.Nil(t, err)
+
+	targetProvider.Applied = nil
+	summary, err := manager.Reconcile(context.Background(), rollbackTestDeployment("2", true, false), false, "default", "")
+	assert.NotNil(t, err)
+	assert.Nil(t, summary.Rollback)
+	assert.Equal(t, 2, len(targetProvider.Applied))
+}
+
+type concurrencyTestTargetProvider struct {
+	lock    sync.Mutex
+	running int
+	Max     int
+	Order   []string
+}
+
+func (c *concurrencyTestTargetProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (c *concurrencyTestTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{}
+}
+func (c *concurrencyTestTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	return []model.ComponentSpec{}, nil
+}
+func (c *concurrencyTestTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	c.lock.Lock()
+	c.running++
+	if c.running > c.Max {
+		c.Max = c.running
+	}
+	c.lock.Unlock()
+	time.Sleep(100 * time.Millisecond)
+	c.lock.Lock()
+	c.running--
+	c.Order = append(c.Order, step.Components[0].Component.Name)
+	c.lock.Unlock()
+	ret := make(map[string]model.ComponentResultSpec)
+	for _, component := range step.Components {
+		ret[component.Component.Name] = model.ComponentResultSpec{Status: v1alpha2.Updated}
+	}
+	return ret, nil
+}
+
+func concurrencyTestDeployment() model.DeploymentSpec {
+	deployment := model.DeploymentSpec{
+		SolutionVersionName: "app-v1",
+		Instance: model.InstanceState{
+			ObjectMeta: model.ObjectMeta{
+				Name: "instance",
+			},
+			Spec: &model.InstanceSpec{},
+		},
+		SolutionVersion: model.SolutionVersionState{
+			Spec: &model.SolutionVersionSpec{
+				Components: []model.ComponentSpec{
+					{Name: "a", Type: "mock"},
+					{Name: "b", Type: "mock"},
+					{Name: "c", Type: "mock"},
+					{Name: "d", Type: "mock", Dependencies: []string{"a"}},
+				},
+			},
+		},
+		Assignments: map[string]string{
+			"T1": "{a}",
+			"T2": "{b}",
+			"T3": "{c}",
+			"T4": "{d}",
+		},
+		Targets: map[string]model.TargetState{
+			"T1": {Spec: &model.TargetSpec{}},
+			"T2": {Spec: &model.TargetSpec{}},
+			"T3": {Spec: &model.TargetSpec{}},
+			"T4": {Spec: &model.TargetSpec{}},
+		},
+	}
+	deployment.Instance.ObjectMeta.SetGuid("concurrency-test")
+	return deployment
+}
+
+func TestReconcileConcurrentSteps(t *testing.T) {
+	targetProvider := &concurrencyTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+	manager.MaxConcurrentSteps = 2
+
+	summary, err := manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Nil(t, err)
+	assert.True(t, summary.AllAssignedDeployed)
+	assert.Equal(t, 4, summary.SuccessCount)
+	assert.Equal(t, 4, summary.CurrentDeployed)
+	assert.Equal(t, 2, targetProvider.Max)
+	assert.Equal(t, 4, len(targetProvider.Order))
+	// d depends on a, so it is applied after a
+	indexA, indexD := -1, -1
+	for i, name := range targetProvider.Order {
+		if name == "a" {
+			indexA = i
+		} else if name == "d" {
+			indexD = i
+		}
+	}
+	assert.True(t, indexA < indexD)
+}
+
+func TestReconcileSequentialStepsByDefault(t *testing.T) {
+	targetProvider := &concurrencyTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	summary, err := manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Nil(t, err)
+	assert.True(t, summary.AllAssignedDeployed)
+	assert.Equal(t, 1, targetProvider.Max)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, targetProvider.Order)
+}
+
+type rolloutTestTargetProvider struct {
+	lock      sync.Mutex
+	Applied   []string
+	Unhealthy map[string]bool
+}
+
+func (r *rolloutTestTargetProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (r *rolloutTestTargetProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{}
+}
+func (r *rolloutTestTargetProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ret := make([]model.ComponentSpec, 0)
+	if r.Unhealthy[deployment.ActiveTarget] {
+		return ret, nil
+	}
+	for _, reference := range references {
+		ret = append(ret, reference.Component)
+	}
+	return ret, nil
+}
+func (r *rolloutTestTargetProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Applied = append(r.Applied, step.Target)
+	return map[string]model.ComponentResultSpec{}, nil
+}
+
+func rolloutTestDeployment(strategy *model.RolloutStrategySpec) model.DeploymentSpec {
+	deployment := model.DeploymentSpec{
+		SolutionVersionName: "app-v1",
+		Instance: model.InstanceState{
+			ObjectMeta: model.ObjectMeta{
+				Name: "instance",
+			},
+			Spec: &model.InstanceSpec{
+				RolloutStrategy: strategy,
+			},
+		},
+		SolutionVersion: model.SolutionVersionState{
+			Spec: &model.SolutionVersionSpec{
+				Components: []model.ComponentSpec{
+					{Name: "a", Type: "mock"},
+				},
+			},
+		},
+		Assignments: map[string]string{
+			"T1": "{a}",
+			"T2": "{a}",
+			"T3": "{a}",
+		},
+		Targets: map[string]model.TargetState{
+			"T1": {Spec: &model.TargetSpec{}},
+			"T2": {Spec: &model.TargetSpec{}},
+			"T3": {Spec: &model.TargetSpec{}},
+		},
+	}
+	deployment.Instance.ObjectMeta.SetGuid("rollout-test")
+	return deployment
+}
+
+func TestReconcileRolloutWaves(t *testing.T) {
+	targetProvider := &rolloutTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+	jobs := make(chan v1alpha2.ScheduledJobData, 2)
+	manager.VendorContext.Subscribe("schedule", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			assert.Equal(t, "deployment", event.Metadata["objectType"])
+			jobs <- event.Body.(v1alpha2.ScheduledJobData)
+			return nil
+		},
+	})
+	deployment := rolloutTestDeployment(&model.RolloutStrategySpec{
+		BatchSize:         2,
+		PauseBetweenWaves: "100ms",
+		HealthCheck: &model.RolloutHealthCheckSpec{
+			ComponentsReady: true,
+		},
+	})
+
+	// the rollout pauses after the first wave, and the deployment is queued again
+	summary, err := manager.Reconcile(context.Background(), deployment, false, "default", "")
+	assert.Nil(t, err)
+	assert.False(t, summary.AllAssignedDeployed)
+	assert.Equal(t, []string{"T1", "T2"}, targetProvider.Applied)
+	assert.NotNil(t, summary.Rollout.ResumeAt)
+	assert.Equal(t, model.RolloutWaveSucceeded, summary.Rollout.Waves[0].Status)
+	assert.Equal(t, model.RolloutWavePending, summary.Rollout.Waves[1].Status)
+	result, err := manager.GetSummary(context.Background(), deployment.Instance.ObjectMeta.GetSummaryId(), "instance", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, model.SummaryStateRunning, result.State)
+
+	// reconciling during the pause doesn't continue the rollout
+	summary, err = manager.Reconcile(context.Background(), deployment, false, "default", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"T1", "T2"}, targetProvider.Applied)
+
+	// the deployment is scheduled for the end of the pause each time, which the jobs manager
+	// keeps as one job
+	var scheduled []v1alpha2.ScheduledJobData
+	for len(scheduled) < 2 {
+		select {
+		case job := <-jobs:
+			scheduled = append(scheduled, job)
+		case <-time.After(5 * time.Second):
+			assert.FailNow(t, "the deployment wasn't queued again")
+		}
+	}
+	assert.Equal(t, *summary.Rollout.ResumeAt, scheduled[0].Due)
+	assert.Equal(t, scheduled[0].Due, scheduled[1].Due)
+	job := scheduled[1].Job
+	assert.Equal(t, "instance", job.Id)
+	queued, err := model.ToDeployment(job.Data)
+	assert.Nil(t, err)
+	time.Sleep(time.Until(scheduled[1].Due))
+
+	summary, err = manager.Reconcile(context.Background(), *queued, false, "default", "")
+	assert.Nil(t, err)
+	assert.True(t, summary.AllAssignedDeployed)
+	assert.Equal(t, 3, summary.SuccessCount)
+	assert.Equal(t, []string{"T1", "T2", "T3"}, targetProvider.Applied)
+	assert.NotNil(t, summary.Rollout)
+	assert.Nil(t, summary.Rollout.ResumeAt)
+	assert.Equal(t, 1, summary.Rollout.CurrentWave)
+	assert.Equal(t, 2, len(summary.Rollout.Waves))
+	assert.Equal(t, []string{"T1", "T2"}, summary.Rollout.Waves[0].Targets)
+	assert.Equal(t, model.RolloutWaveSucceeded, summary.Rollout.Waves[0].Status)
+	assert.Equal(t, []string{"T3"}, summary.Rollout.Waves[1].Targets)
+	assert.Equal(t, model.RolloutWaveSucceeded, summary.Rollout.Waves[1].Status)
+	result, err = manager.GetSummary(context.Background(), deployment.Instance.ObjectMeta.GetSummaryId(), "instance", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, model.SummaryStateDone, result.State)
+}
+
+func TestReconcileRolloutStopsOnFailedHealthCheck(t *testing.T) {
+	targetProvider := &rolloutTestTargetProvider{
+		Unhealthy: map[string]bool{"T1": true},
+	}
+	manager := newRollbackTestManager(targetProvider)
+
+	summary, err := manager.Reconcile(context.Background(), rolloutTestDeployment(&model.RolloutStrategySpec{
+		BatchSize: 1,
+		HealthCheck: &model.RolloutHealthCheckSpec{
+			ComponentsReady: true,
+		},
+	}), false, "default", "")
+	assert.NotNil(t, err)
+	assert.False(t, summary.AllAssignedDeployed)
+	assert.Equal(t, []string{"T1"}, targetProvider.Applied)
+	assert.Equal(t, 0, summary.Rollout.CurrentWave)
+	assert.Equal(t, model.RolloutWaveFailed, summary.Rollout.Waves[0].Status)
+	assert.Equal(t, "component a is not ready on target T1", summary.Rollout.Waves[0].Message)
+	assert.Equal(t, model.RolloutWavePending, summary.Rollout.Waves[1].Status)
+	assert.Equal(t, model.RolloutWavePending, summary.Rollout.Waves[2].Status)
+	assert.Contains(t, summary.GenerateStatusMessage(), "Rollout stopped at wave 1 of 3")
+}
+
+func TestReconcileWithoutRolloutStrategy(t *testing.T) {
+	targetProvider := &rolloutTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+
+	summary, err := manager.Reconcile(context.Background(), rolloutTestDeployment(nil), false, "default", "")
+	assert.Nil(t, err)
+	assert.True(t, summary.AllAssignedDeployed)
+	assert.Nil(t, summary.Rollout)
+	assert.Equal(t, 3, len(targetProvider.Applied))
+}
+
+// fencedTestKeyLockProvider hands out the same fencing token for every lock
+type fencedTestKeyLockProvider struct {
+	*memorykeylock.MemoryKeyLockProvider
+	Token int64
+	Lost  bool
+}
+
+func (f *fencedTestKeyLockProvider) FencingToken(key string) (int64, bool) {
+	return f.Token, !f.Lost
+}
+
+func TestReconcileChecksFencingToken(t *testing.T) {
+	targetProvider := &concurrencyTestTargetProvider{}
+	manager := newRollbackTestManager(targetProvider)
+	keyLock := &fencedTestKeyLockProvider{
+		MemoryKeyLockProvider: manager.KeyLockProvider.(*memorykeylock.MemoryKeyLockProvider),
+		Token:                 2,
+	}
+	manager.KeyLockProvider = keyLock
+
+	_, err := manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Nil(t, err)
+
+	// a reconcile whose lease expired holds an older token than the one that saved the state
+	keyLock.Token = 1
+	_, err = manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+
+	keyLock.Token = 3
+	keyLock.Lost = true
+	_, err = manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+
+	keyLock.Lost = false
+	_, err = manager.Reconcile(context.Background(), concurrencyTestDeployment(), false, "default", "")
+	assert.Nil(t, err)
+}
+
+func TestSaveSummaryRejectsStaleFencingToken(t *testing.T) {
+	manager := newRollbackTestManager(&concurrencyTestTargetProvider{})
+	keyLock := &fencedTestKeyLockProvider{
+		MemoryKeyLockProvider: manager.KeyLockProvider.(*memorykeylock.MemoryKeyLockProvider),
+		Token:                 2,
+	}
+	manager.KeyLockProvider = keyLock
+	ctx := context.Background()
+
+	err := manager.concludeSummary(ctx, "instance1", "instance1", "1", "hash2", model.SummarySpec{SuccessCount: 2}, "default")
+	assert.Nil(t, err)
+
+	// a reconcile whose lease expired doesn't overwrite the summary of the next one
+	keyLock.Token = 1
+	err = manager.saveSummaryProgress(ctx, "instance1", "instance1", "1", "hash1", model.SummarySpec{SuccessCount: 1}, "default")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	err = manager.concludeSummary(ctx, "instance1", "instance1", "1", "hash1", model.SummarySpec{SuccessCount: 1}, "default")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+
+	result, err := manager.GetSummary(ctx, "instance1", "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "hash2", result.DeploymentHash)
+	assert.Equal(t, 2, result.Summary.SuccessCount)
+	assert.Equal(t, model.SummaryStateDone, result.State)
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/watch"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -139,6 +140,12 @@ func (t *SolutionVersionsManager) UpsertState(ctx context.Context, name string,
 }
 
 func (t *SolutionVersionsManager) ListState(ctx context.Context, namespace string) ([]model.SolutionVersionState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the solution versions, and returns the token of the next page
+func (t *SolutionVersionsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.SolutionVersionState, string, error) {
 	ctx, span := observability.StartSpan("SolutionVersions Manager", ctx, &map[string]string{
 		"method": "ListSpec",
 	})
@@ -154,23 +161,45 @@ func (t *SolutionVersionsManager) ListState(ctx context.Context, namespace strin
 			"namespace": namespace,
 			"kind":      "SolutionVersion",
 		},
+		ListOptions: options,
 	}
 	var solutionversions []states.StateEntry
-	solutionversions, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	solutionversions, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.SolutionVersionState, 0)
 	for _, t := range solutionversions {
 		var rt model.SolutionVersionState
 		rt, err = getSolutionVersionState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
+}
+
+// WatchState streams the solution versions and their changes
+func (t *SolutionVersionsManager) WatchState(ctx context.Context, namespace string) (<-chan model.WatchEvent, error) {
+	return watch.WatchStates(ctx, t.StateProvider, states.WatchRequest{
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.SolutionVersionGroup,
+			"resource":  "solutionversions",
+			"namespace": namespace,
+			"kind":      "SolutionVersion",
+		},
+	}, func(entry states.StateEntry) (interface{}, error) {
+		state, err := getSolutionVersionState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		state.ObjectMeta.UpdateEtag(entry.ETag)
+		return state, nil
+	})
 }
 
 func getSolutionVersionState(body interface{}) (model.SolutionVersionState, error) {
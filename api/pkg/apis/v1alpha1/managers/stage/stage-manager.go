@@ -9,6 +9,7 @@ package stage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -20,6 +21,8 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	symproviders "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/campaign"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/remote"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
@@ -38,6 +41,8 @@ type StageManager struct {
 	managers.Manager
 	StateProvider states.IStateProvider
 	apiClient     utils.ApiClient
+	// parentClient reads the activations of the parent site, and is nil without a parent site
+	parentClient utils.ApiClient
 }
 
 type StageResult struct {
@@ -190,6 +195,8 @@ type TaskProcessor interface {
 type GoRoutineTaskProcessor struct {
 	manager *StageManager
 	ctx     context.Context
+	// run holds back dispatching while the activation is paused; nil for stages run outside an activation
+	run *activationRun
 }
 
 func NewGoRoutineTaskProcessor(manager *StageManager, ctx context.Context) *GoRoutineTaskProcessor {
@@ -251,7 +258,7 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 		go func() {
 			defer taskWaitGroup.Done()
 			for task := range taskQueue {
-				outputs, err := handler.HandleTask(taskCtx, task, inputs, siteName)
+				outputs, err := p.handleTask(taskCtx, handler, task, inputs, siteName)
 				if err != nil {
 					outputs = carryOutPutsToErrorStatus(outputs, err, "")
 				}
@@ -277,6 +284,9 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 
 		// Initially dispatch up to `concurrency` tasks
 		for i := 0; i < concurrency && i < len(tasks); i++ {
+			if p.run.wait(taskCtx) != nil {
+				return
+			}
 			taskQueue <- tasks[taskIndex]
 			taskIndex++
 			pendingTasks++
@@ -301,6 +311,9 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 
 				// Dispatch next task if available
 				if taskIndex < len(tasks) {
+					if p.run.wait(taskCtx) != nil {
+						return
+					}
 					taskQueue <- tasks[taskIndex]
 					taskIndex++
 					pendingTasks++
@@ -324,6 +337,11 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 	// Close the results channel
 	close(taskResultsChan)
 
+	// The stage timed out or was cancelled before all tasks reported back
+	if ctx.Err() != nil {
+		return taskProcessor.TaskResults, ctx.Err()
+	}
+
 	// Final error decision
 	if taskProcessor.ErrorCount > 0 {
 		switch errorAction.Mode {
@@ -350,6 +368,21 @@ func (p *GoRoutineTaskProcessor) Process(ctx context.Context, tasks []model.Task
 	return taskProcessor.TaskResults, nil
 }
 
+// handleTask runs a single task, bounded by the task's timeout
+func (p *GoRoutineTaskProcessor) handleTask(ctx context.Context, handler TaskHandler, task model.TaskSpec, inputs map[string]interface{}, siteName string) (map[string]interface{}, error) {
+	timeout, err := task.TimeoutDuration()
+	if err != nil {
+		return nil, err
+	}
+	taskCtx, cancel, timedOut := withTimeout(ctx, timeout, fmt.Sprintf("task %s", task.Name))
+	defer cancel()
+	outputs, _, err := runUntilDone(taskCtx, func() (map[string]interface{}, bool, error) {
+		outputs, err := handler.HandleTask(taskCtx, task, inputs, siteName)
+		return outputs, false, err
+	})
+	return outputs, timedOut(err)
+}
+
 // processWithRetry executes fn and retries on error according to the stage's
 // retry spec. A nil spec or MaxRetries <= 0 means fn is called exactly once.
 // Interval (duration format, e.g. "10s") uses a fixed delay between attempts;
@@ -394,6 +427,195 @@ func processWithRetry(ctx context.Context, retry *model.RetrySpec, fn func() (ma
 	return outputs, pause, err
 }
 
+// withTimeout bounds ctx by timeout and returns a func that turns an error returned after the
+// deadline passed into a TimedOut error naming what timed out. A timeout <= 0 leaves ctx unbounded.
+func withTimeout(ctx context.Context, timeout time.Duration, what string) (context.Context, context.CancelFunc, func(error) error) {
+	if timeout <= 0 {
+		cCtx, cancel := context.WithCancel(ctx)
+		return cCtx, cancel, func(err error) error { return err }
+	}
+	tCtx, cancel := context.WithTimeout(ctx, timeout)
+	return tCtx, cancel, func(err error) error {
+		if err != nil && ctx.Err() == nil && errors.Is(tCtx.Err(), context.DeadlineExceeded) {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("%s timed out after %s", what, timeout), v1alpha2.TimedOut)
+		}
+		return err
+	}
+}
+
+// runUntilDone returns the result of fn, or ctx.Err() as soon as ctx is done. A provider that
+// ignores ctx keeps running in the background, but no longer holds up the stage.
+func runUntilDone(ctx context.Context, fn func() (map[string]interface{}, bool, error)) (map[string]interface{}, bool, error) {
+	type processResult struct {
+		outputs map[string]interface{}
+		pause   bool
+		err     error
+	}
+	done := make(chan processResult, 1)
+	go func() {
+		outputs, pause, err := fn()
+		done <- processResult{outputs: outputs, pause: pause, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.outputs, result.pause, result.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+func isTimeoutError(err error) bool {
+	cErr, ok := err.(v1alpha2.COAError)
+	return ok && cErr.State == v1alpha2.TimedOut
+}
+
+// activationRun is an activation with a stage being processed, which the activations API can
+// cancel or pause
+type activationRun struct {
+	cancel context.CancelCauseFunc
+	lock   sync.Mutex
+	// resumed is closed on resume, and nil while the run isn't paused
+	resumed chan struct{}
+	// control reads the cancel and pause requests persisted on the activation, so that requests
+	// handled by another replica reach the run
+	control func(ctx context.Context) (cancelled bool, paused bool, err error)
+}
+
+var errActivationCancelled = v1alpha2.NewCOAError(nil, "activation is cancelled", v1alpha2.Cancelled)
+
+// controlPollInterval is how often a run checks the persisted control state of its activation
+var controlPollInterval = 5 * time.Second
+
+var runLock sync.Mutex
+var runs = make(map[string]*activationRun)
+
+func (r *activationRun) pause() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.resumed == nil {
+		r.resumed = make(chan struct{})
+	}
+}
+
+func (r *activationRun) resume() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.resumed != nil {
+		close(r.resumed)
+		r.resumed = nil
+	}
+}
+
+// wait blocks while the run is paused. It returns ctx.Err() if ctx is done first.
+func (r *activationRun) wait(ctx context.Context) error {
+	if r == nil {
+		return ctx.Err()
+	}
+	r.lock.Lock()
+	resumed := r.resumed
+	r.lock.Unlock()
+	if resumed == nil {
+		return ctx.Err()
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sync applies the persisted control state of the activation to the run. The run is left as it is
+// if the state can't be read.
+func (r *activationRun) sync(ctx context.Context) {
+	cancelled, paused, err := r.control(ctx)
+	if err != nil {
+		log.DebugfCtx(ctx, " M (Stage): failed to read control state of activation: %v", err)
+		return
+	}
+	switch {
+	case cancelled:
+		r.cancel(errActivationCancelled)
+		r.resume()
+	case paused:
+		r.pause()
+	default:
+		r.resume()
+	}
+}
+
+// watch syncs the run with the persisted control state of the activation until ctx is done
+func (r *activationRun) watch(ctx context.Context) {
+	ticker := time.NewTicker(controlPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.sync(ctx)
+		}
+	}
+}
+
+func runKey(namespace string, activation string) string {
+	return fmt.Sprintf("%s/%s", namespace, activation)
+}
+
+// trackActivation registers the activation of a stage about to be processed, and returns a context
+// that's cancelled when the activation is cancelled. The returned func unregisters it.
+func (s *StageManager) trackActivation(ctx context.Context, triggerData v1alpha2.ActivationData) (context.Context, func()) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	run := &activationRun{cancel: cancel, control: s.activationControl(triggerData)}
+	key := runKey(triggerData.Namespace, triggerData.Activation)
+	runLock.Lock()
+	runs[key] = run
+	runLock.Unlock()
+	if run.control != nil {
+		go run.watch(runCtx)
+	}
+	return runCtx, func() {
+		runLock.Lock()
+		if runs[key] == run {
+			delete(runs, key)
+		}
+		runLock.Unlock()
+		cancel(nil)
+	}
+}
+
+// activationControl returns a func reading the cancel and pause requests persisted on the
+// activation. A stage run on behalf of the parent site reads them from the parent site.
+func (s *StageManager) activationControl(triggerData v1alpha2.ActivationData) func(ctx context.Context) (bool, bool, error) {
+	client, site := s.apiClient, s.VendorContext.SiteInfo.CurrentSite
+	if triggerData.NeedsReport {
+		client, site = s.parentClient, s.VendorContext.SiteInfo.ParentSite
+	}
+	if client == nil {
+		return nil
+	}
+	return func(ctx context.Context) (bool, bool, error) {
+		activation, err := client.GetActivation(ctx, triggerData.Activation, triggerData.Namespace, site.Username, site.Password)
+		if err != nil || activation.Status == nil {
+			return false, false, err
+		}
+		return activation.Status.Status == v1alpha2.Cancelled, activation.Status.PauseRequested, nil
+	}
+}
+
+func (s *StageManager) activationRun(namespace string, activation string) *activationRun {
+	runLock.Lock()
+	defer runLock.Unlock()
+	return runs[runKey(namespace, activation)]
+}
+
+// isCancelled returns true if ctx is done because its activation was cancelled
+func isCancelled(ctx context.Context) bool {
+	cause := context.Cause(ctx)
+	return cause != nil && v1alpha2.GetErrorState(cause) == v1alpha2.Cancelled
+}
+
 func (s *StageManager) processTasks(ctx context.Context, currentStage model.StageSpec, inputCopy map[string]interface{}, triggerData v1alpha2.ActivationData, triggers map[string]interface{}, siteName string) (map[string]interface{}, error) {
 	if len(currentStage.Tasks) == 0 {
 		return make(map[string]interface{}), nil
@@ -401,12 +623,54 @@ func (s *StageManager) processTasks(ctx context.Context, currentStage model.Stag
 
 	log.InfofCtx(ctx, " M (Stage): processing tasks for site %s", inputCopy["__site"])
 
+	tasks := currentStage.Tasks
+	if currentStage.ForEach != "" {
+		items, err := s.evaluateForEach(ctx, currentStage.ForEach, triggerData, inputCopy, triggers)
+		if err != nil {
+			return make(map[string]interface{}), err
+		}
+		log.InfofCtx(ctx, " M (Stage): expanding %d tasks over %d forEach items for site %s", len(currentStage.Tasks), len(items), siteName)
+		tasks = currentStage.ExpandTasks(items)
+	}
+
 	// Create task processor and handler
 	processor := NewGoRoutineTaskProcessor(s, ctx)
+	processor.run = s.activationRun(triggerData.Namespace, triggerData.Activation)
 	handler := NewCampaignVersionTaskHandler(s, triggerData, triggers)
 
 	// Process tasks using the processor
-	return processor.Process(ctx, currentStage.Tasks, inputCopy, handler, currentStage.TaskOption.ErrorAction, currentStage.TaskOption.Concurrency, siteName)
+	return processor.Process(ctx, tasks, inputCopy, handler, currentStage.TaskOption.ErrorAction, currentStage.TaskOption.Concurrency, siteName)
+}
+
+// evaluateForEach evaluates a forEach expression to the list of items its tasks run over
+func (s *StageManager) evaluateForEach(ctx context.Context, forEach string, triggerData v1alpha2.ActivationData, inputs map[string]interface{}, triggers map[string]interface{}) ([]interface{}, error) {
+	val, err := s.traceValue(ctx, forEach, triggerData.Namespace, inputs, triggers, triggerData.Outputs)
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Stage): failed to evaluate forEach expression %s: %v", forEach, err)
+		return nil, err
+	}
+	switch items := val.(type) {
+	case []interface{}:
+		return items, nil
+	case string:
+		// a list may come back in its JSON form, e.g. from a catalog property
+		var list []interface{}
+		if err := json.Unmarshal([]byte(items), &list); err == nil {
+			return list, nil
+		}
+	default:
+		// typed lists, such as the objects returned by the list stage provider
+		if reflect.ValueOf(val).Kind() == reflect.Slice {
+			var list []interface{}
+			data, err := json.Marshal(val)
+			if err == nil {
+				if err = json.Unmarshal(data, &list); err == nil {
+					return list, nil
+				}
+			}
+		}
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("forEach expression %s doesn't evaluate to a list: %v", forEach, val), v1alpha2.BadConfig)
 }
 
 func (s *StageManager) evaluateProxyConfig(ctx context.Context, triggerData v1alpha2.ActivationData, runtimeInputs map[string]interface{}, activationTriggers map[string]interface{}) (map[string]interface{}, error) {
@@ -475,8 +739,15 @@ func (t *StageResult) GetError() error {
 }
 
 type PendingTask struct {
+	Activation    string                            `json:"activation,omitempty"`
 	Sites         []string                          `json:"sites"`
 	OutputContext map[string]map[string]interface{} `json:"outputContext,omitempty"`
+	// Approval is set when the stage is waiting for a sign-off from the approval stage provider
+	Approval *model.ApprovalRequest `json:"approval,omitempty"`
+	// Child is set when the stage is waiting for a child activation started by the campaign stage provider
+	Child *model.ChildActivation `json:"child,omitempty"`
+	// Held is set when the stage hasn't started because the activation is paused
+	Held *v1alpha2.ActivationData `json:"held,omitempty"`
 }
 
 func (s *StageManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -495,13 +766,19 @@ func (s *StageManager) Init(context *contexts.VendorContext, config managers.Man
 	if err != nil {
 		return err
 	}
+	if s.VendorContext.SiteInfo.ParentSite.BaseUrl != "" {
+		s.parentClient, err = utils.GetParentApiClient(s.VendorContext.SiteInfo.ParentSite.BaseUrl)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (s *StageManager) Enabled() bool {
 	return s.Config.Properties["poll.enabled"] == "true"
 }
 func (s *StageManager) Poll() []error {
-	return nil
+	return s.pollApprovals(context.Background())
 }
 func (s *StageManager) Reconcil() []error {
 	return nil
@@ -630,6 +907,10 @@ func (s *StageManager) ResumeStage(ctx context.Context, status model.StageStatus
 
 				}
 				if nextStage != "" {
+					schedule, err := cam.Stages[nextStage].ResolveSchedule(time.Now())
+					if err != nil {
+						return nil, err
+					}
 					activationData := &v1alpha2.ActivationData{
 						CampaignVersion:             campaignversion,
 						Activation:           activation,
@@ -640,7 +921,7 @@ func (s *StageManager) ResumeStage(ctx context.Context, status model.StageStatus
 						Config:               cam.Stages[nextStage].Config,
 						Outputs:              outputs,
 						TriggeringStage:      stage,
-						Schedule:             cam.Stages[nextStage].Schedule,
+						Schedule:             schedule,
 						Namespace:            namespace,
 						Proxy:                cam.Stages[nextStage].Proxy,
 					}
@@ -675,6 +956,394 @@ func (s *StageManager) ResumeStage(ctx context.Context, status model.StageStatus
 
 	return nil, nil
 }
+
+// pollApprovals publishes the timeout decision of pending approvals whose deadline has passed
+func (s *StageManager) pollApprovals(ctx context.Context) []error {
+	entries, _, err := s.StateProvider.List(ctx, states.ListRequest{})
+	if err != nil {
+		return []error{err}
+	}
+	errs := make([]error, 0)
+	now := time.Now()
+	for _, entry := range entries {
+		p, err := toPendingTask(entry.Body)
+		if err != nil || p.Approval == nil || !p.Approval.IsExpired(now) {
+			continue
+		}
+		log.InfofCtx(ctx, " M (Stage): approval of stage %s in activation %s timed out, taking action '%s'", p.Approval.Stage, p.Approval.Activation, p.Approval.TimeoutAction)
+		err = s.Context.Publish("approval", v1alpha2.Event{
+			Body:    p.Approval.Timeout(),
+			Context: ctx,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// ResumeApproval completes a stage that is waiting for approval. An approved stage moves on to
+// the next stage like any other stage. A rejected or timed out stage fails, unless the stage
+// selector picks a next stage that handles errors.
+func (s *StageManager) ResumeApproval(ctx context.Context, decision model.ApprovalDecision, activation model.ActivationState, cam model.CampaignVersionSpec) (model.StageStatus, *v1alpha2.ActivationData, error) {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "ResumeApproval",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, " M (Stage): ResumeApproval: activation %s stage %s is %s", decision.Activation, decision.Stage, decision.Decision)
+
+	status := pausedStageStatus(activation, decision.Stage)
+	var pending *PendingTask
+	pending, err = s.takePendingTask(ctx, decision.Namespace, func(p PendingTask) bool {
+		return p.Approval != nil && p.Approval.Activation == decision.Activation && p.Approval.Stage == decision.Stage
+	})
+	if err != nil {
+		return status, nil, err
+	}
+	if pending == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is not waiting for approval of stage %s", decision.Activation, decision.Stage), v1alpha2.NotFound)
+		return status, nil, err
+	}
+
+	status.Outputs[model.ApprovalOutput] = decision.Decision
+	if decision.DecidedBy != "" {
+		status.Outputs["decidedBy"] = decision.DecidedBy
+	}
+	if decision.Comment != "" {
+		status.Outputs["comment"] = decision.Comment
+	}
+	if decision.TimedOut {
+		status.Outputs["timedOut"] = true
+	}
+
+	var failure error
+	if decision.TimedOut {
+		failure = v1alpha2.NewCOAError(nil, fmt.Sprintf("approval of stage %s timed out", decision.Stage), v1alpha2.TimedOut)
+	} else if decision.Decision != model.ApprovalApproved {
+		failure = v1alpha2.NewCOAError(nil, fmt.Sprintf("stage %s was rejected", decision.Stage), v1alpha2.ApprovalRejected)
+	}
+	return s.completePausedStage(ctx, v1alpha2.ActivationData{
+		CampaignVersion:      pending.Approval.CampaignVersion,
+		Activation:           decision.Activation,
+		ActivationGeneration: pending.Approval.ActivationGeneration,
+		Stage:                decision.Stage,
+		Namespace:            decision.Namespace,
+	}, status, pending.OutputContext, activation, cam, failure)
+}
+
+// ResumeChildActivation completes a stage that is waiting for a child activation started by the
+// campaign stage provider. The final outputs of the child become the outputs of the stage. The
+// stage fails if the child failed, unless the stage selector picks a next stage that handles errors.
+func (s *StageManager) ResumeChildActivation(ctx context.Context, result model.ChildActivationResult, activation model.ActivationState, cam model.CampaignVersionSpec) (model.StageStatus, *v1alpha2.ActivationData, error) {
+	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
+		"method": "ResumeChildActivation",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.InfofCtx(ctx, " M (Stage): ResumeChildActivation: child activation %s of activation %s finished as %s", result.Activation, result.Parent, result.Status.String())
+
+	var latest model.StageStatus
+	if activation.Status != nil && len(activation.Status.StageHistory) > 0 {
+		latest = activation.Status.StageHistory[len(activation.Status.StageHistory)-1]
+	}
+	if latest.Status != v1alpha2.Paused {
+		// the stage vendor checks the child again once the pause of the stage is reported
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s hasn't paused to wait for child activation %s", result.Parent, result.Activation), v1alpha2.NotFound)
+		return latest, nil, err
+	}
+	status := pausedStageStatus(activation, latest.Stage)
+	var pending *PendingTask
+	pending, err = s.takePendingTask(ctx, result.Namespace, func(p PendingTask) bool {
+		return p.Child != nil && p.Child.Activation == result.Parent && p.Child.Child == result.Activation
+	})
+	if err != nil {
+		return status, nil, err
+	}
+	if pending == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("activation %s is not waiting for child activation %s", result.Parent, result.Activation), v1alpha2.NotFound)
+		return status, nil, err
+	}
+
+	status.Outputs = map[string]interface{}{}
+	for k, v := range result.Outputs {
+		status.Outputs[k] = v
+	}
+	status.Outputs[model.ChildActivationOutput] = result.Activation
+
+	var failure error
+	if result.Status != v1alpha2.Done {
+		message := fmt.Sprintf("child activation %s finished as %s", result.Activation, result.Status.String())
+		if result.ErrorMessage != "" {
+			message = fmt.Sprintf("%s: %s", message, result.ErrorMessage)
+		}
+		failure = v1alpha2.NewCOAError(nil, message, result.Status)
+	}
+	return s.completePausedStage(ctx, v1alpha2.ActivationData{
+		CampaignVersion:      pending.Child.CampaignVersion,
+		Activation:           pending.Child.Activation,
+		ActivationGeneration: pending.Child.ActivationGeneration,
+		Stage:                pending.Child.Stage,
+		Namespace:            pending.Child.Namespace,
+	}, status, pending.OutputContext, activation, cam, failure)
+}
+
+// FinishedChildActivation returns the result of the child activation a paused stage is waiting
+// for if the child has already finished, which happens when it finishes before the pause is saved
+func (s *StageManager) FinishedChildActivation(ctx context.Context, triggerData v1alpha2.ActivationData) (*model.ChildActivationResult, error) {
+	entry, err := s.StateProvider.Get(ctx, states.GetRequest{
+		ID: fmt.Sprintf("%s-%s-%s", triggerData.CampaignVersion, triggerData.Activation, triggerData.ActivationGeneration),
+		Metadata: map[string]interface{}{
+			"namespace": triggerData.Namespace,
+		},
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	p, err := toPendingTask(entry.Body)
+	if err != nil || p.Child == nil {
+		return nil, err
+	}
+	child, err := s.apiClient.GetActivation(ctx, p.Child.Child, p.Child.Namespace, s.VendorContext.SiteInfo.CurrentSite.Username, s.VendorContext.SiteInfo.CurrentSite.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !child.IsFinished() {
+		return nil, nil
+	}
+	result := model.NewChildActivationResult(child)
+	return &result, nil
+}
+
+// HoldTrigger saves the trigger of a stage that can't start because its activation is paused, and
+// returns the status that shows the stage as paused. ResumeActivation gives the trigger back.
+func (s *StageManager) HoldTrigger(ctx context.Context, triggerData v1alpha2.ActivationData) (model.StageStatus, error) {
+	log.InfofCtx(ctx, " M (Stage): HoldTrigger: activation %s is paused before stage %s", triggerData.Activation, triggerData.Stage)
+	status := model.StageStatus{
+		Stage:         triggerData.Stage,
+		Outputs:       map[string]interface{}{},
+		Status:        v1alpha2.Paused,
+		StatusMessage: v1alpha2.Paused.String(),
+		IsActive:      false,
+	}
+	_, err := s.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: fmt.Sprintf("%s-%s-%s", triggerData.CampaignVersion, triggerData.Activation, triggerData.ActivationGeneration),
+			Body: PendingTask{
+				Activation: triggerData.Activation,
+				Held:       &triggerData,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": triggerData.Namespace,
+		},
+	})
+	return status, err
+}
+
+// PauseActivation holds back the tasks of the activation that haven't been dispatched yet
+func (s *StageManager) PauseActivation(ctx context.Context, activation string, namespace string) {
+	if run := s.activationRun(namespace, activation); run != nil {
+		log.InfofCtx(ctx, " M (Stage): PauseActivation: pausing tasks of activation %s", activation)
+		run.pause()
+	}
+}
+
+// ResumeActivation lets the tasks of a paused activation carry on, and returns the trigger of the
+// stage that was held while the activation was paused, if any
+func (s *StageManager) ResumeActivation(ctx context.Context, activation string, namespace string) (*v1alpha2.ActivationData, error) {
+	if run := s.activationRun(namespace, activation); run != nil {
+		log.InfofCtx(ctx, " M (Stage): ResumeActivation: resuming tasks of activation %s", activation)
+		run.resume()
+	}
+	p, err := s.takePendingTask(ctx, namespace, func(p PendingTask) bool {
+		return p.Held != nil && p.Activation == activation
+	})
+	if err != nil || p == nil {
+		return nil, err
+	}
+	log.InfofCtx(ctx, " M (Stage): ResumeActivation: releasing stage %s of activation %s", p.Held.Stage, activation)
+	return p.Held, nil
+}
+
+// CancelActivation cancels the stage being processed for the activation, and drops the pending
+// tasks of its paused stages so that they aren't resumed
+func (s *StageManager) CancelActivation(ctx context.Context, activation string, namespace string) error {
+	if run := s.activationRun(namespace, activation); run != nil {
+		log.InfofCtx(ctx, " M (Stage): CancelActivation: cancelling running stage of activation %s", activation)
+		run.cancel(errActivationCancelled)
+		run.resume()
+	}
+	for {
+		p, err := s.takePendingTask(ctx, namespace, func(p PendingTask) bool {
+			return p.Activation == activation
+		})
+		if err != nil && !v1alpha2.IsNotFound(err) {
+			return err
+		}
+		if p == nil && err == nil {
+			return nil
+		}
+	}
+}
+
+// pausedStageStatus returns the latest status of a paused stage from the activation history
+func pausedStageStatus(activation model.ActivationState, stage string) model.StageStatus {
+	status := model.StageStatus{
+		Stage:   stage,
+		Outputs: map[string]interface{}{},
+	}
+	if activation.Status != nil {
+		for _, history := range activation.Status.StageHistory {
+			if history.Stage == stage {
+				status = history
+			}
+		}
+	}
+	if status.Outputs == nil {
+		status.Outputs = map[string]interface{}{}
+	}
+	return status
+}
+
+// takePendingTask removes and returns the first pending task in the namespace that matches,
+// or nil if there is none
+func (s *StageManager) takePendingTask(ctx context.Context, namespace string, match func(PendingTask) bool) (*PendingTask, error) {
+	entries, _, err := s.StateProvider.List(ctx, states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": namespace,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		p, pErr := toPendingTask(entry.Body)
+		if pErr != nil || !match(p) {
+			continue
+		}
+		err = s.StateProvider.Delete(ctx, states.DeleteRequest{
+			ID: entry.ID,
+			Metadata: map[string]interface{}{
+				"namespace": namespace,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &p, nil
+	}
+	return nil, nil
+}
+
+// completePausedStage finishes a paused stage with the given status and evaluates its stage
+// selector. A non-nil failure fails the stage, unless the next stage handles errors.
+func (s *StageManager) completePausedStage(ctx context.Context, paused v1alpha2.ActivationData, status model.StageStatus, outputs map[string]map[string]interface{}, activation model.ActivationState, cam model.CampaignVersionSpec, failure error) (model.StageStatus, *v1alpha2.ActivationData, error) {
+	if outputs == nil {
+		outputs = make(map[string]map[string]interface{})
+	}
+	outputs[paused.Stage] = status.Outputs
+
+	failed := failure != nil
+	var failState v1alpha2.State
+	var failMessage string
+	if failed {
+		failState, failMessage = v1alpha2.GetErrorState(failure), failure.Error()
+		if cErr, ok := failure.(v1alpha2.COAError); ok {
+			failMessage = cErr.Message
+		}
+	}
+
+	currentStage, ok := cam.Stages[paused.Stage]
+	if !ok || !cam.SelfDriving {
+		if failed {
+			s.setStageStatus(&status, "", failState, failMessage)
+		} else {
+			s.setStageStatus(&status, "", v1alpha2.Done, "")
+		}
+		return status, nil, nil
+	}
+
+	parser := utils.NewParser(currentStage.StageSelector)
+	eCtx := s.VendorContext.EvaluationContext.Clone()
+	eCtx.Context = ctx
+	eCtx.Namespace = paused.Namespace
+	if activation.Spec != nil {
+		eCtx.Triggers = activation.Spec.Inputs
+	}
+	eCtx.Inputs = currentStage.Inputs
+	if eCtx.Inputs != nil {
+		if v, ok := eCtx.Inputs["context"]; ok {
+			eCtx.Value = v
+		}
+	}
+	eCtx.Outputs = outputs
+	val, err := parser.Eval(*eCtx)
+	if err != nil {
+		s.setStageStatus(&status, "", v1alpha2.InternalError, err.Error())
+		log.ErrorfCtx(ctx, " M (Stage): failed to evaluate stage selector: %v", err)
+		return status, nil, nil
+	}
+	nextStageName := ""
+	if val != nil {
+		nextStageName = utils.FormatAsString(val)
+	}
+	if nextStageName == "" {
+		if failed {
+			s.setStageStatus(&status, "", failState, failMessage)
+		} else {
+			s.setStageStatus(&status, "", v1alpha2.Done, "")
+		}
+		return status, nil, nil
+	}
+	nextStage, ok := cam.Stages[nextStageName]
+	if !ok {
+		s.setStageStatus(&status, "", v1alpha2.BadRequest, fmt.Sprintf("stage %s is not found", nextStageName))
+		return status, nil, nil
+	}
+	if failed && !nextStage.HandleErrors {
+		s.setStageStatus(&status, "", failState, failMessage)
+		return status, nil, nil
+	}
+	schedule, err := nextStage.ResolveSchedule(time.Now())
+	if err != nil {
+		s.setStageStatus(&status, "", v1alpha2.BadConfig, fmt.Sprintf("failed to resolve schedule of stage %s: %s", nextStageName, err.Error()))
+		return status, nil, nil
+	}
+	s.setStageStatus(&status, nextStageName, v1alpha2.Done, "")
+	return status, &v1alpha2.ActivationData{
+		CampaignVersion:      paused.CampaignVersion,
+		Activation:           paused.Activation,
+		ActivationGeneration: paused.ActivationGeneration,
+		Stage:                nextStageName,
+		Inputs:               eCtx.Triggers,
+		Outputs:              outputs,
+		Provider:             nextStage.Provider,
+		Config:               nextStage.Config,
+		TriggeringStage:      paused.Stage,
+		Schedule:             schedule,
+		Namespace:            paused.Namespace,
+		Proxy:                nextStage.Proxy,
+	}, nil
+}
+
+func toPendingTask(body interface{}) (PendingTask, error) {
+	var p PendingTask
+	jData, err := json.Marshal(body)
+	if err != nil {
+		return p, err
+	}
+	err = json.Unmarshal(jData, &p)
+	return p, err
+}
+
 func (s *StageManager) HandleDirectTriggerEvent(ctx context.Context, triggerData v1alpha2.ActivationData) model.StageStatus {
 	ctx, span := observability.StartSpan("Stage Manager", ctx, &map[string]string{
 		"method": "HandleDirectTriggerEvent",
@@ -684,6 +1353,12 @@ func (s *StageManager) HandleDirectTriggerEvent(ctx context.Context, triggerData
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
 	log.InfofCtx(ctx, " M (Stage): HandleDirectTriggerEvent for campaignversion %s, activation %s, stage %s", triggerData.CampaignVersion, triggerData.Activation, triggerData.Stage)
+	if triggerData.NeedsReport {
+		// a stage run on behalf of the parent site is cancelled along with its activation there
+		var untrack func()
+		ctx, untrack = s.trackActivation(ctx, triggerData)
+		defer untrack()
+	}
 
 	status := model.StageStatus{
 		Stage:     "",
@@ -841,6 +1516,8 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
 	log.InfofCtx(ctx, " M (Stage): HandleTriggerEvent for campaignversion %s, activation %s, stage %s, selfDriving %s", triggerData.CampaignVersion, triggerData.Activation, triggerData.Stage, campaignversion.SelfDriving)
+	ctx, untrack := s.trackActivation(ctx, triggerData)
+	defer untrack()
 	status := model.StageStatus{
 		Stage:         triggerData.Stage,
 		NextStage:     "",
@@ -1039,6 +1716,14 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 			}
 		}
 
+		var stageTimeout time.Duration
+		stageTimeout, err = currentStage.TimeoutDuration()
+		if err != nil {
+			s.setStageStatus(&status, "", v1alpha2.BadConfig, err.Error())
+			log.ErrorfCtx(ctx, " M (Stage): invalid timeout of stage %s: %v", triggerData.Stage, err)
+			return status, activationData
+		}
+
 		// 6. Iterate all sites, start multiple go routines
 		numTasks := len(sites)
 		waitGroup := sync.WaitGroup{}
@@ -1099,6 +1784,10 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 					return
 				}
 
+				// The stage timeout covers the provider, including retries, and the tasks
+				stageCtx, cancel, timedOut := withTimeout(ctx, stageTimeout, fmt.Sprintf("stage %s", triggerData.Stage))
+				defer cancel()
+
 				// 6.1. If triggerData.provider exists, follow current flow to process, collect the output.
 				if provider != nil {
 					var outputs map[string]interface{}
@@ -1145,14 +1834,19 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 						}
 						triggerDataForProxy.Inputs = proxyInputs
 
-						outputs, pause, iErr = processWithRetry(ctx, currentStage.Retry, func() (map[string]interface{}, bool, error) {
-							return proxyProvider.(stage.IProxyStageProvider).Process(ctx, *s.Manager.Context, triggerDataForProxy)
+						outputs, pause, iErr = runUntilDone(stageCtx, func() (map[string]interface{}, bool, error) {
+							return processWithRetry(stageCtx, currentStage.Retry, func() (map[string]interface{}, bool, error) {
+								return proxyProvider.(stage.IProxyStageProvider).Process(stageCtx, *s.Manager.Context, triggerDataForProxy)
+							})
 						})
 					} else {
-						outputs, pause, iErr = processWithRetry(ctx, currentStage.Retry, func() (map[string]interface{}, bool, error) {
-							return provider.(stage.IStageProvider).Process(ctx, *s.Manager.Context, inputCopy)
+						outputs, pause, iErr = runUntilDone(stageCtx, func() (map[string]interface{}, bool, error) {
+							return processWithRetry(stageCtx, currentStage.Retry, func() (map[string]interface{}, bool, error) {
+								return provider.(stage.IStageProvider).Process(stageCtx, *s.Manager.Context, inputCopy)
+							})
 						})
 					}
+					iErr = timedOut(iErr)
 					if iErr != nil {
 						log.ErrorfCtx(ctx, " M (Stage): failed to process stage %s for site %s: %v", triggerData.Stage, site, iErr)
 						results <- StageResult{
@@ -1181,7 +1875,8 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 						return
 					}
 
-					taskResults, err := s.processTasks(ctx, currentStage, inputCopy, triggerData, triggers, site)
+					taskResults, err := s.processTasks(stageCtx, currentStage, inputCopy, triggerData, triggers, site)
+					err = timedOut(err)
 					// Merge task results with allOutputs
 					allOutputs = utils.MergeCollection_StringAny(allOutputs, taskResults)
 
@@ -1209,8 +1904,15 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 		// DO NOT REMOVE THIS COMMENT
 		// gofail: var afterProvider string
 
+		if isCancelled(ctx) {
+			s.setStageStatus(&status, "", v1alpha2.Cancelled, fmt.Sprintf("stage %s is cancelled", triggerData.Stage))
+			log.InfofCtx(ctx, " M (Stage): stage %s in activation %s is cancelled", triggerData.Stage, triggerData.Activation)
+			return status, activationData
+		}
+
 		outputs := make(map[string]interface{})
 		hasStageError := false
+		stageTimedOut := false
 		for result := range results {
 			err = result.GetError()
 
@@ -1225,7 +1927,11 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 				status.Outputs = carryOutPutsToErrorStatus(result.Outputs, err, site)
 				result.Outputs = carryOutPutsToErrorStatus(result.Outputs, err, site)
 				status.Status = v1alpha2.InternalError
-				status.StatusMessage = v1alpha2.InternalError.String()
+				if isTimeoutError(err) {
+					status.Status = v1alpha2.TimedOut
+					stageTimedOut = true
+				}
+				status.StatusMessage = status.Status.String()
 				status.ErrorMessage = fmt.Sprintf("%s: %s", result.Site, err.Error())
 				status.IsActive = false
 				log.ErrorfCtx(ctx, " M (Stage): failed to process stage %s for site %s outputs: %v", triggerData.Stage, site, err)
@@ -1251,6 +1957,11 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 			}
 		}
 
+		failedState, failedMessage := v1alpha2.InternalError, fmt.Sprintf("stage %s failed", triggerData.Stage)
+		if stageTimedOut {
+			failedState, failedMessage = v1alpha2.TimedOut, fmt.Sprintf("stage %s timed out", triggerData.Stage)
+		}
+
 		for k, v := range outputs {
 			if !(strings.HasPrefix(k, "__") || strings.HasPrefix(k, "header.")) {
 				status.Outputs[k] = v
@@ -1264,9 +1975,32 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 		// If stage is paused, save the pending task and return paused status
 		if pauseRequested {
 			pendingTask := PendingTask{
+				Activation:    triggerData.Activation,
 				Sites:         sites,
 				OutputContext: triggerData.Outputs,
 			}
+			if _, ok := provider.(*approval.ApprovalStageProvider); ok {
+				if request, ok := model.ApprovalRequestFromOutputs(outputs); ok {
+					request.CampaignVersion = triggerData.CampaignVersion
+					request.Activation = triggerData.Activation
+					request.ActivationGeneration = triggerData.ActivationGeneration
+					request.Stage = triggerData.Stage
+					request.Namespace = triggerData.Namespace
+					pendingTask.Approval = &request
+				}
+			}
+			if _, ok := provider.(*campaign.CampaignStageProvider); ok {
+				if child, ok := outputs[model.ChildActivationOutput].(string); ok {
+					pendingTask.Child = &model.ChildActivation{
+						CampaignVersion:      triggerData.CampaignVersion,
+						Activation:           triggerData.Activation,
+						ActivationGeneration: triggerData.ActivationGeneration,
+						Stage:                triggerData.Stage,
+						Namespace:            triggerData.Namespace,
+						Child:                child,
+					}
+				}
+			}
 			_, err = s.StateProvider.Upsert(ctx, states.UpsertRequest{
 				Value: states.StateEntry{
 					ID:   fmt.Sprintf("%s-%s-%s", triggerData.CampaignVersion, triggerData.Activation, triggerData.ActivationGeneration),
@@ -1323,6 +2057,13 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 			if nextStageName != "" {
 				if nextStage, ok := campaignversion.Stages[nextStageName]; ok || hasStageError {
 					if !hasStageError || nextStage.HandleErrors {
+						var schedule string
+						schedule, err = nextStage.ResolveSchedule(time.Now())
+						if err != nil {
+							s.setStageStatus(&status, "", v1alpha2.BadConfig, fmt.Sprintf("failed to resolve schedule of stage %s: %s", nextStageName, err.Error()))
+							log.ErrorfCtx(ctx, " M (Stage): failed to resolve schedule of stage %s: %v", nextStageName, err)
+							return status, activationData
+						}
 						status.NextStage = nextStageName
 						activationData = &v1alpha2.ActivationData{
 							CampaignVersion:             triggerData.CampaignVersion,
@@ -1334,14 +2075,14 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 							Provider:             nextStage.Provider,
 							Config:               nextStage.Config,
 							TriggeringStage:      triggerData.Stage,
-							Schedule:             nextStage.Schedule,
+							Schedule:             schedule,
 							Namespace:            triggerData.Namespace,
 							Proxy:                nextStage.Proxy,
 						}
 						s.setStageStatus(&status, nextStageName, v1alpha2.Done, "")
 						return status, activationData
 					} else {
-						s.setStageStatus(&status, "", v1alpha2.InternalError, fmt.Sprintf("stage %s failed", triggerData.Stage))
+						s.setStageStatus(&status, "", failedState, failedMessage)
 						log.ErrorfCtx(ctx, " M (Stage): failed to process stage outputs: %v", status.ErrorMessage)
 						return status, activationData
 					}
@@ -1353,7 +2094,7 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 			}
 			// sVal is empty, no next stage
 			if hasStageError {
-				s.setStageStatus(&status, "", v1alpha2.InternalError, fmt.Sprintf("stage %s failed", triggerData.Stage))
+				s.setStageStatus(&status, "", failedState, failedMessage)
 				log.ErrorfCtx(ctx, " M (Stage): failed to process stage outputs: %v", status.ErrorMessage)
 				return status, activationData
 			}
@@ -1363,7 +2104,7 @@ func (s *StageManager) HandleTriggerEvent(ctx context.Context, campaignversion m
 		} else {
 			// Not self-driving, no next stage
 			if hasStageError {
-				s.setStageStatus(&status, "", v1alpha2.InternalError, fmt.Sprintf("stage %s failed", triggerData.Stage))
+				s.setStageStatus(&status, "", failedState, failedMessage)
 				log.ErrorfCtx(ctx, " M (Stage): failed to process stage outputs: %v", status.ErrorMessage)
 				return status, activationData
 			}
@@ -1457,6 +2198,10 @@ func (s *StageManager) HandleActivationEvent(ctx context.Context, actData v1alph
 		// 		stage)
 		// 	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("stage %s is not the next stage", stage), v1alpha2.BadRequest)
 		// }
+		schedule, err := stageSpec.ResolveSchedule(time.Now())
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to resolve schedule of stage %s", stage), v1alpha2.BadConfig)
+		}
 		return &v1alpha2.ActivationData{
 			CampaignVersion:             actData.CampaignVersion,
 			Activation:           actData.Activation,
@@ -1466,7 +2211,7 @@ func (s *StageManager) HandleActivationEvent(ctx context.Context, actData v1alph
 			Provider:             stageSpec.Provider,
 			Config:               stageSpec.Config,
 			TriggeringStage:      stage,
-			Schedule:             stageSpec.Schedule,
+			Schedule:             schedule,
 			Namespace:            actData.Namespace,
 			Proxy:                stageSpec.Proxy,
 		}, nil
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
@@ -1601,6 +1603,54 @@ func TestHandleActivationEvent(t *testing.T) {
 	assert.Equal(t, int(2), output.Inputs["bar"])
 	assert.Equal(t, "providers.stage.mock", output.Provider)
 }
+func TestHandleActivationEventWithCronScheduleAndMaintenanceWindow(t *testing.T) {
+	manager := StageManager{}
+	activationData := v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+	}
+	activationState := model.ActivationState{
+		Spec: &model.ActivationSpec{},
+	}
+
+	campaignversion := model.CampaignVersionSpec{
+		FirstStage: "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.mock",
+				Schedule: "*/10 * * * *",
+			},
+		},
+	}
+	output, err := manager.HandleActivationEvent(context.Background(), activationData, campaignversion, activationState)
+	assert.Nil(t, err)
+	fireTime, err := time.Parse(time.RFC3339, output.Schedule)
+	assert.Nil(t, err)
+	assert.True(t, fireTime.After(time.Now()))
+	assert.Equal(t, 0, fireTime.Minute()%10)
+
+	// a window that is always open lets the stage run right away
+	campaignversion.Stages["test"] = model.StageSpec{
+		Provider: "providers.stage.mock",
+		MaintenanceWindows: []model.MaintenanceWindowSpec{
+			{Schedule: "0 * * * *", Duration: "1h"},
+		},
+	}
+	output, err = manager.HandleActivationEvent(context.Background(), activationData, campaignversion, activationState)
+	assert.Nil(t, err)
+	assert.Equal(t, "", output.Schedule)
+
+	// a window that is never open is rejected
+	campaignversion.Stages["test"] = model.StageSpec{
+		Provider: "providers.stage.mock",
+		MaintenanceWindows: []model.MaintenanceWindowSpec{
+			{Schedule: "0 0 30 2 *", Duration: "1h"},
+		},
+	}
+	_, err = manager.HandleActivationEvent(context.Background(), activationData, campaignversion, activationState)
+	assert.NotNil(t, err)
+}
 func TestTriggerEventWithSchedule(t *testing.T) {
 	stateProvider := &memorystate.MemoryStateProvider{}
 	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
@@ -2208,3 +2258,908 @@ func TestStageWithoutRetryFailsImmediately(t *testing.T) {
 	assert.Equal(t, v1alpha2.InternalError, status.Status)
 	assert.Equal(t, 1, flaky.GetCallCount("retry-none"))
 }
+
+func createApprovalStageManager() StageManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := StageManager{
+		StateProvider: stateProvider,
+	}
+	manager.VendorContext = &contexts.VendorContext{
+		EvaluationContext: &coa_utils.EvaluationContext{},
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	manager.Context = &contexts.ManagerContext{
+		VencorContext: manager.VendorContext,
+		SiteInfo: v1alpha2.SiteInfo{
+			SiteId: "fake",
+		},
+	}
+	return manager
+}
+
+func approvalCampaignVersion() model.CampaignVersionSpec {
+	return model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "signoff",
+		Stages: map[string]model.StageSpec{
+			"signoff": {
+				Provider:      "providers.stage.approval",
+				StageSelector: "deploy",
+				Config: map[string]interface{}{
+					"approverRoles": []string{"release-manager"},
+					"timeout":       "1h",
+				},
+				Inputs: map[string]interface{}{
+					"message": "ship it?",
+				},
+			},
+			"deploy": {
+				Provider: "providers.stage.mock",
+			},
+			"cleanup": {
+				Provider:     "providers.stage.mock",
+				HandleErrors: true,
+			},
+		},
+	}
+}
+
+func pauseForApproval(t *testing.T, manager StageManager, campaignversion model.CampaignVersionSpec) model.StageStatus {
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaignversion, v1alpha2.ActivationData{
+		CampaignVersion:      "release-v-v1",
+		Activation:           "release-1",
+		ActivationGeneration: "1",
+		Stage:                "signoff",
+		Provider:             "providers.stage.approval",
+		Config:               campaignversion.Stages["signoff"].Config,
+		Namespace:            "default",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	return status
+}
+
+func TestApprovalStagePausesAndResumesOnApproval(t *testing.T) {
+	manager := createApprovalStageManager()
+	campaignversion := approvalCampaignVersion()
+	status := pauseForApproval(t, manager, campaignversion)
+
+	request, ok := model.ApprovalRequestFromOutputs(status.Outputs)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"release-manager"}, request.ApproverRoles)
+	assert.Equal(t, "ship it?", request.Message)
+	assert.NotEmpty(t, request.Deadline)
+
+	entry, err := manager.StateProvider.Get(context.Background(), states.GetRequest{
+		ID:       "release-v-v1-release-1-1",
+		Metadata: map[string]interface{}{"namespace": "default"},
+	})
+	assert.Nil(t, err)
+	pending, err := toPendingTask(entry.Body)
+	assert.Nil(t, err)
+	assert.NotNil(t, pending.Approval)
+	assert.Equal(t, "release-1", pending.Approval.Activation)
+	assert.Equal(t, "signoff", pending.Approval.Stage)
+
+	activationState := model.ActivationState{
+		Spec: &model.ActivationSpec{CampaignVersion: "release-v-v1"},
+		Status: &model.ActivationStatus{
+			StageHistory: []model.StageStatus{status},
+		},
+	}
+	decision := model.ApprovalDecision{
+		CampaignVersion: "release-v-v1",
+		Activation:      "release-1",
+		Stage:           "signoff",
+		Namespace:       "default",
+		Decision:        model.ApprovalApproved,
+		DecidedBy:       "alice",
+	}
+	status, next, err := manager.ResumeApproval(context.Background(), decision, activationState, campaignversion)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "deploy", status.NextStage)
+	assert.Equal(t, model.ApprovalApproved, status.Outputs[model.ApprovalOutput])
+	assert.Equal(t, "alice", status.Outputs["decidedBy"])
+	assert.NotNil(t, next)
+	assert.Equal(t, "deploy", next.Stage)
+	assert.Equal(t, "1", next.ActivationGeneration)
+	assert.Equal(t, model.ApprovalApproved, next.Outputs["signoff"][model.ApprovalOutput])
+
+	// the approval can only be decided once
+	_, _, err = manager.ResumeApproval(context.Background(), decision, activationState, campaignversion)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestApprovalStageRejected(t *testing.T) {
+	manager := createApprovalStageManager()
+	campaignversion := approvalCampaignVersion()
+	status := pauseForApproval(t, manager, campaignversion)
+
+	status, next, err := manager.ResumeApproval(context.Background(), model.ApprovalDecision{
+		CampaignVersion: "release-v-v1",
+		Activation:      "release-1",
+		Stage:           "signoff",
+		Namespace:       "default",
+		Decision:        model.ApprovalRejected,
+		Comment:         "not today",
+	}, model.ActivationState{
+		Spec:   &model.ActivationSpec{CampaignVersion: "release-v-v1"},
+		Status: &model.ActivationStatus{StageHistory: []model.StageStatus{status}},
+	}, campaignversion)
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, v1alpha2.ApprovalRejected, status.Status)
+	assert.Equal(t, "", status.NextStage)
+	assert.Equal(t, "not today", status.Outputs["comment"])
+}
+
+func TestApprovalStageRejectedWithErrorHandler(t *testing.T) {
+	manager := createApprovalStageManager()
+	campaignversion := approvalCampaignVersion()
+	signoff := campaignversion.Stages["signoff"]
+	signoff.StageSelector = "cleanup"
+	campaignversion.Stages["signoff"] = signoff
+	status := pauseForApproval(t, manager, campaignversion)
+
+	status, next, err := manager.ResumeApproval(context.Background(), model.ApprovalRequest{
+		CampaignVersion: "release-v-v1",
+		Activation:      "release-1",
+		Stage:           "signoff",
+		Namespace:       "default",
+	}.Timeout(), model.ActivationState{
+		Spec:   &model.ActivationSpec{CampaignVersion: "release-v-v1"},
+		Status: &model.ActivationStatus{StageHistory: []model.StageStatus{status}},
+	}, campaignversion)
+	assert.Nil(t, err)
+	assert.NotNil(t, next)
+	assert.Equal(t, "cleanup", next.Stage)
+	assert.Equal(t, true, status.Outputs["timedOut"])
+}
+
+func childCampaignVersion() model.CampaignVersionSpec {
+	return model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "drain",
+		Stages: map[string]model.StageSpec{
+			"drain": {
+				Provider:      "providers.stage.campaign",
+				StageSelector: "verify",
+				Config: map[string]interface{}{
+					"user":     "admin",
+					"password": "",
+				},
+				Inputs: map[string]interface{}{
+					"campaignVersion": "drain:v1",
+					"inputs": map[string]interface{}{
+						"node": "node-1",
+					},
+				},
+			},
+			"verify": {
+				Provider: "providers.stage.mock",
+			},
+			"cleanup": {
+				Provider:     "providers.stage.mock",
+				HandleErrors: true,
+			},
+		},
+	}
+}
+
+// initializeMockActivationsAPI serves the activations the campaign stage provider creates
+func initializeMockActivationsAPI(t *testing.T, children map[string]model.ActivationState) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/activations/registry/") {
+			name := strings.TrimPrefix(r.URL.Path, "/activations/registry/")
+			if r.Method == http.MethodPost {
+				var activation model.ActivationState
+				err := json.NewDecoder(r.Body).Decode(&activation)
+				assert.Nil(t, err)
+				children[name] = activation
+				return
+			}
+			json.NewEncoder(w).Encode(children[name])
+			return
+		}
+		json.NewEncoder(w).Encode(utils.AuthResponse{
+			AccessToken: "test-token",
+			TokenType:   "Bearer",
+			Username:    "test-user",
+			Roles:       []string{"role1", "role2"},
+		})
+	}))
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+	return ts
+}
+
+func pauseForChild(t *testing.T, manager StageManager, campaignversion model.CampaignVersionSpec) (model.StageStatus, string) {
+	status, activation := manager.HandleTriggerEvent(context.Background(), campaignversion, v1alpha2.ActivationData{
+		CampaignVersion:      "upgrade-v-v1",
+		Activation:           "upgrade-1",
+		ActivationGeneration: "1",
+		Stage:                "drain",
+		Provider:             "providers.stage.campaign",
+		Config:               campaignversion.Stages["drain"].Config,
+		Namespace:            "default",
+	})
+	assert.Nil(t, activation)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+	child, ok := status.Outputs[model.ChildActivationOutput].(string)
+	assert.True(t, ok)
+	return status, child
+}
+
+func TestCampaignStageResumesWithChildOutputs(t *testing.T) {
+	children := map[string]model.ActivationState{}
+	ts := initializeMockActivationsAPI(t, children)
+	defer ts.Close()
+	manager := createApprovalStageManager()
+	campaignversion := childCampaignVersion()
+	status, child := pauseForChild(t, manager, campaignversion)
+
+	assert.Equal(t, "drain:v1", children[child].Spec.CampaignVersion)
+	assert.Equal(t, "node-1", children[child].Spec.Inputs["node"])
+	assert.Equal(t, "upgrade-1", children[child].ObjectMeta.Labels[constants.ParentActivation])
+
+	entry, err := manager.StateProvider.Get(context.Background(), states.GetRequest{
+		ID:       "upgrade-v-v1-upgrade-1-1",
+		Metadata: map[string]interface{}{"namespace": "default"},
+	})
+	assert.Nil(t, err)
+	pending, err := toPendingTask(entry.Body)
+	assert.Nil(t, err)
+	assert.NotNil(t, pending.Child)
+	assert.Equal(t, child, pending.Child.Child)
+	assert.Equal(t, "drain", pending.Child.Stage)
+
+	activationState := model.ActivationState{
+		Spec: &model.ActivationSpec{CampaignVersion: "upgrade-v-v1"},
+		Status: &model.ActivationStatus{
+			StageHistory: []model.StageStatus{status},
+		},
+	}
+	result := model.ChildActivationResult{
+		Activation: child,
+		Namespace:  "default",
+		Parent:     "upgrade-1",
+		Status:     v1alpha2.Done,
+		Outputs: map[string]interface{}{
+			"drained": "3 pods",
+		},
+	}
+	status, next, err := manager.ResumeChildActivation(context.Background(), result, activationState, campaignversion)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, "verify", status.NextStage)
+	assert.Equal(t, "3 pods", status.Outputs["drained"])
+	assert.Equal(t, child, status.Outputs[model.ChildActivationOutput])
+	assert.NotNil(t, next)
+	assert.Equal(t, "verify", next.Stage)
+	assert.Equal(t, "3 pods", next.Outputs["drain"]["drained"])
+
+	// the result can only be handled once
+	_, _, err = manager.ResumeChildActivation(context.Background(), result, activationState, campaignversion)
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestCampaignStageChildFailed(t *testing.T) {
+	children := map[string]model.ActivationState{}
+	ts := initializeMockActivationsAPI(t, children)
+	defer ts.Close()
+	manager := createApprovalStageManager()
+	campaignversion := childCampaignVersion()
+	status, child := pauseForChild(t, manager, campaignversion)
+
+	status, next, err := manager.ResumeChildActivation(context.Background(), model.ChildActivationResult{
+		Activation:   child,
+		Namespace:    "default",
+		Parent:       "upgrade-1",
+		Status:       v1alpha2.InternalError,
+		ErrorMessage: "node-1 didn't drain",
+	}, model.ActivationState{
+		Spec:   &model.ActivationSpec{CampaignVersion: "upgrade-v-v1"},
+		Status: &model.ActivationStatus{StageHistory: []model.StageStatus{status}},
+	}, campaignversion)
+	assert.Nil(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Contains(t, status.ErrorMessage, "node-1 didn't drain")
+}
+
+func TestCampaignStageChildFailedWithErrorHandler(t *testing.T) {
+	children := map[string]model.ActivationState{}
+	ts := initializeMockActivationsAPI(t, children)
+	defer ts.Close()
+	manager := createApprovalStageManager()
+	campaignversion := childCampaignVersion()
+	drain := campaignversion.Stages["drain"]
+	drain.StageSelector = "cleanup"
+	campaignversion.Stages["drain"] = drain
+	status, child := pauseForChild(t, manager, campaignversion)
+
+	_, next, err := manager.ResumeChildActivation(context.Background(), model.ChildActivationResult{
+		Activation: child,
+		Namespace:  "default",
+		Parent:     "upgrade-1",
+		Status:     v1alpha2.TimedOut,
+	}, model.ActivationState{
+		Spec:   &model.ActivationSpec{CampaignVersion: "upgrade-v-v1"},
+		Status: &model.ActivationStatus{StageHistory: []model.StageStatus{status}},
+	}, campaignversion)
+	assert.Nil(t, err)
+	assert.NotNil(t, next)
+	assert.Equal(t, "cleanup", next.Stage)
+}
+
+func TestCampaignStageChildFinishedBeforePause(t *testing.T) {
+	children := map[string]model.ActivationState{}
+	ts := initializeMockActivationsAPI(t, children)
+	defer ts.Close()
+	manager := createApprovalStageManager()
+	var err error
+	manager.apiClient, err = utils.GetApiClient()
+	assert.Nil(t, err)
+	campaignversion := childCampaignVersion()
+	triggerData := v1alpha2.ActivationData{
+		CampaignVersion:      "upgrade-v-v1",
+		Activation:           "upgrade-1",
+		ActivationGeneration: "1",
+		Namespace:            "default",
+	}
+	status, child := pauseForChild(t, manager, campaignversion)
+
+	// the pause of the parent hasn't been reported yet
+	running := status
+	running.Status = v1alpha2.Running
+	result := model.ChildActivationResult{
+		Activation: child,
+		Namespace:  "default",
+		Parent:     "upgrade-1",
+		Status:     v1alpha2.Done,
+	}
+	_, _, err = manager.ResumeChildActivation(context.Background(), result, model.ActivationState{
+		Spec:   &model.ActivationSpec{CampaignVersion: "upgrade-v-v1"},
+		Status: &model.ActivationStatus{StageHistory: []model.StageStatus{running}},
+	}, campaignversion)
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	finished, err := manager.FinishedChildActivation(context.Background(), triggerData)
+	assert.Nil(t, err)
+	assert.Nil(t, finished)
+
+	childState := children[child]
+	childState.Status = &model.ActivationStatus{
+		Status: v1alpha2.Done,
+		StageHistory: []model.StageStatus{{
+			Stage:   "cordon",
+			Status:  v1alpha2.Done,
+			Outputs: map[string]interface{}{"cordoned": true, "__stage": "cordon"},
+		}},
+	}
+	children[child] = childState
+	finished, err = manager.FinishedChildActivation(context.Background(), triggerData)
+	assert.Nil(t, err)
+	assert.NotNil(t, finished)
+	assert.Equal(t, "upgrade-1", finished.Parent)
+	assert.Equal(t, map[string]interface{}{"cordoned": true}, finished.Outputs)
+}
+
+func TestPollPublishesExpiredApprovals(t *testing.T) {
+	manager := createApprovalStageManager()
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	manager.Context.Init(nil, &pubSubProvider)
+	decisions := make(chan model.ApprovalDecision, 2)
+	manager.Context.Subscribe("approval", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var decision model.ApprovalDecision
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &decision)
+			assert.Nil(t, err)
+			decisions <- decision
+			return nil
+		},
+	})
+
+	for name, deadline := range map[string]time.Time{
+		"expired": time.Now().Add(-time.Minute),
+		"waiting": time.Now().Add(time.Hour),
+	} {
+		_, err := manager.StateProvider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID: name,
+				Body: PendingTask{
+					Sites: []string{"fake"},
+					Approval: &model.ApprovalRequest{
+						CampaignVersion: "release-v-v1",
+						Activation:      name,
+						Stage:           "signoff",
+						Namespace:       "default",
+						Deadline:        deadline.UTC().Format(time.RFC3339),
+						TimeoutAction:   model.ApprovalTimeoutApprove,
+					},
+				},
+			},
+			Metadata: map[string]interface{}{"namespace": "default"},
+		})
+		assert.Nil(t, err)
+	}
+
+	errs := manager.Poll()
+	assert.Empty(t, errs)
+	select {
+	case decision := <-decisions:
+		assert.Equal(t, "expired", decision.Activation)
+		assert.Equal(t, model.ApprovalApproved, decision.Decision)
+		assert.True(t, decision.TimedOut)
+	case <-time.After(5 * time.Second):
+		t.Fatal("approval timeout was not published")
+	}
+	select {
+	case decision := <-decisions:
+		t.Fatalf("unexpected decision for %s", decision.Activation)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestStageTimeout(t *testing.T) {
+	manager := prepareManager()
+	activation := v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		Stage:                "test",
+		ActivationGeneration: "1",
+		Provider:             "providers.stage.delay",
+		Namespace:            "fakens",
+	}
+	timeStamp := time.Now()
+	status, next := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.delay",
+				Inputs: map[string]interface{}{
+					"delay": 5,
+				},
+				Timeout: "500ms",
+			},
+		},
+	}, activation)
+	assert.Nil(t, next)
+	assert.True(t, time.Since(timeStamp) < 5*time.Second)
+	assert.Equal(t, v1alpha2.TimedOut, status.Status)
+	assert.Equal(t, v1alpha2.TimedOut.String(), status.StatusMessage)
+	assert.Equal(t, "stage test timed out", status.ErrorMessage)
+	assert.Equal(t, v1alpha2.TimedOut, status.Outputs["status"])
+	assert.Contains(t, status.Outputs["error"], "timed out after 500ms")
+}
+
+func TestStageTimeoutWithErrorHandler(t *testing.T) {
+	manager := prepareManager()
+	campaignversion := model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider:      "providers.stage.delay",
+				StageSelector: "cleanup",
+				Inputs: map[string]interface{}{
+					"delay": 5,
+				},
+				Timeout: "500ms",
+			},
+			"cleanup": {
+				Provider:     "providers.stage.mock",
+				HandleErrors: true,
+			},
+		},
+	}
+	status, next := manager.HandleTriggerEvent(context.Background(), campaignversion, v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		Stage:                "test",
+		ActivationGeneration: "1",
+		Provider:             "providers.stage.delay",
+		Namespace:            "fakens",
+	})
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	assert.Equal(t, v1alpha2.TimedOut, status.Outputs["status"])
+	assert.NotNil(t, next)
+	assert.Equal(t, "cleanup", next.Stage)
+
+	status, next = manager.HandleTriggerEvent(context.Background(), campaignversion, *next)
+	assert.Nil(t, next)
+	assert.Equal(t, v1alpha2.Done, status.Status)
+}
+
+func TestTaskTimeout(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+	triggerData := v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		ActivationGeneration: "1",
+		Stage:                "test-stage",
+		Namespace:            "default",
+	}
+	tasks := []model.TaskSpec{
+		{
+			Name:     "slow",
+			Provider: "providers.stage.delay",
+			Config:   map[string]string{},
+			Inputs: map[string]interface{}{
+				"delay": 5,
+			},
+			Timeout: "500ms",
+		},
+		{
+			Name:     "fast",
+			Provider: "providers.stage.mock",
+			Config:   map[string]string{},
+		},
+	}
+	processor := NewGoRoutineTaskProcessor(manager, ctx)
+	handler := NewCampaignVersionTaskHandler(manager, triggerData, nil)
+
+	timeStamp := time.Now()
+	results, err := processor.Process(ctx, tasks, triggerData.Inputs, handler, model.ErrorAction{
+		Mode: model.ErrorActionMode_SilentlyContinue,
+	}, 2, "test-site")
+	assert.Nil(t, err)
+	assert.True(t, time.Since(timeStamp) < 5*time.Second)
+	assert.Equal(t, v1alpha2.TimedOut, results["slow"].(map[string]interface{})["status"])
+	assert.Contains(t, results["slow"].(map[string]interface{})["error"], "task slow timed out after 500ms")
+	assert.NotNil(t, results["fast"])
+
+	_, err = processor.Process(ctx, tasks, triggerData.Inputs, handler, model.ErrorAction{
+		Mode: model.ErrorActionMode_StopOnAnyFailure,
+	}, 2, "test-site")
+	assert.NotNil(t, err)
+}
+
+func TestStageTimeoutCoversTasks(t *testing.T) {
+	manager := prepareManager()
+	timeStamp := time.Now()
+	status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Tasks: []model.TaskSpec{
+					{
+						Name:     "slow",
+						Provider: "providers.stage.delay",
+						Config:   map[string]string{},
+						Inputs: map[string]interface{}{
+							"delay": 5,
+						},
+					},
+				},
+				TaskOption: model.TaskOption{
+					Concurrency: 1,
+				},
+				Timeout: "500ms",
+			},
+		},
+	}, v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		Stage:                "test",
+		ActivationGeneration: "1",
+		Namespace:            "fakens",
+	})
+	assert.True(t, time.Since(timeStamp) < 5*time.Second)
+	assert.Equal(t, v1alpha2.TimedOut, status.Status)
+	assert.Equal(t, "stage test timed out", status.ErrorMessage)
+}
+
+func TestForEachStage(t *testing.T) {
+	manager := prepareManager()
+	status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				ForEach: "${{$trigger(sites, '')}}",
+				Tasks: []model.TaskSpec{
+					{
+						Name:     "site",
+						Provider: "providers.stage.mock",
+						Config:   map[string]string{},
+						Inputs: map[string]interface{}{
+							"name":     "${{$item(name)}}",
+							"position": "${{$index()}}",
+						},
+					},
+				},
+				TaskOption: model.TaskOption{
+					Concurrency: 2,
+				},
+			},
+		},
+	}, v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		Stage:                "deploy",
+		ActivationGeneration: "1",
+		Namespace:            "fakens",
+		Inputs: map[string]interface{}{
+			"sites": []interface{}{
+				map[string]interface{}{"name": "munich"},
+				map[string]interface{}{"name": "tokyo"},
+				map[string]interface{}{"name": "new-york"},
+			},
+		},
+	})
+	assert.Equal(t, v1alpha2.Done, status.Status)
+	for i, name := range []string{"munich", "tokyo", "new-york"} {
+		outputs, ok := status.Outputs[fmt.Sprintf("site-%d", i)].(map[string]interface{})
+		assert.True(t, ok)
+		assert.Equal(t, name, outputs["name"])
+		assert.Equal(t, i, outputs["position"])
+	}
+}
+
+func TestForEachStageWithEmptyList(t *testing.T) {
+	manager := prepareManager()
+	results, err := manager.processTasks(context.Background(), model.StageSpec{
+		ForEach: "${{$trigger(sites, '[]')}}",
+		Tasks: []model.TaskSpec{
+			{
+				Name:     "site",
+				Provider: "providers.stage.mock",
+				Config:   map[string]string{},
+			},
+		},
+		TaskOption: model.TaskOption{
+			Concurrency: 1,
+		},
+	}, map[string]interface{}{}, v1alpha2.ActivationData{Namespace: "fakens"}, map[string]interface{}{}, "fake")
+	assert.Nil(t, err)
+	assert.Empty(t, results)
+}
+
+func TestForEachStageNotAList(t *testing.T) {
+	manager := prepareManager()
+	status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "deploy",
+		Stages: map[string]model.StageSpec{
+			"deploy": {
+				ForEach: "${{$trigger(sites, '')}}",
+				Tasks: []model.TaskSpec{
+					{
+						Name:     "site",
+						Provider: "providers.stage.mock",
+						Config:   map[string]string{},
+					},
+				},
+				TaskOption: model.TaskOption{
+					Concurrency: 1,
+				},
+			},
+		},
+	}, v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "test-activation",
+		Stage:                "deploy",
+		ActivationGeneration: "1",
+		Namespace:            "fakens",
+		Inputs: map[string]interface{}{
+			"sites": "munich",
+		},
+	})
+	assert.Equal(t, v1alpha2.InternalError, status.Status)
+	assert.Contains(t, status.Outputs["error"], "doesn't evaluate to a list")
+}
+
+// waitForRun waits until a stage of the activation is being processed
+func waitForRun(t *testing.T, manager *StageManager, namespace string, activation string) {
+	for i := 0; i < 100; i++ {
+		if manager.activationRun(namespace, activation) != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("activation %s didn't start", activation)
+}
+
+func TestCancelRunningStage(t *testing.T) {
+	manager := prepareManager()
+	results := make(chan model.StageStatus, 1)
+	timeStamp := time.Now()
+	go func() {
+		status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+			SelfDriving: true,
+			FirstStage:  "test",
+			Stages: map[string]model.StageSpec{
+				"test": {
+					Provider:      "providers.stage.delay",
+					StageSelector: "next",
+					Inputs: map[string]interface{}{
+						"delay": 5,
+					},
+				},
+				"next": {
+					Provider: "providers.stage.mock",
+				},
+			},
+		}, v1alpha2.ActivationData{
+			CampaignVersion:      "test-campaignversion",
+			Activation:           "cancelled-activation",
+			Stage:                "test",
+			ActivationGeneration: "1",
+			Provider:             "providers.stage.delay",
+			Namespace:            "fakens",
+		})
+		results <- status
+	}()
+	waitForRun(t, manager, "fakens", "cancelled-activation")
+	err := manager.CancelActivation(context.Background(), "cancelled-activation", "fakens")
+	assert.Nil(t, err)
+
+	select {
+	case status := <-results:
+		assert.True(t, time.Since(timeStamp) < 5*time.Second)
+		assert.Equal(t, v1alpha2.Cancelled, status.Status)
+		assert.Equal(t, "", status.NextStage)
+		assert.Equal(t, "stage test is cancelled", status.ErrorMessage)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stage wasn't cancelled")
+	}
+	assert.Nil(t, manager.activationRun("fakens", "cancelled-activation"))
+}
+
+func TestCancelPersistedByAnotherReplica(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		if strings.HasPrefix(r.URL.Path, "/activations/registry/") {
+			response = model.ActivationState{
+				Status: &model.ActivationStatus{
+					Status: v1alpha2.Cancelled,
+				},
+			}
+		} else {
+			response = utils.AuthResponse{
+				AccessToken: "test-token",
+				TokenType:   "Bearer",
+			}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer ts.Close()
+	interval := controlPollInterval
+	controlPollInterval = 10 * time.Millisecond
+	defer func() { controlPollInterval = interval }()
+
+	manager := prepareManager()
+	var err error
+	manager.apiClient, err = utils.GetParentApiClient(ts.URL)
+	assert.Nil(t, err)
+	timeStamp := time.Now()
+	// the cancel request was handled elsewhere, so only the persisted activation says it's cancelled
+	status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+		SelfDriving: true,
+		FirstStage:  "test",
+		Stages: map[string]model.StageSpec{
+			"test": {
+				Provider: "providers.stage.delay",
+				Inputs: map[string]interface{}{
+					"delay": 5,
+				},
+			},
+		},
+	}, v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "remotely-cancelled-activation",
+		Stage:                "test",
+		ActivationGeneration: "1",
+		Provider:             "providers.stage.delay",
+		Namespace:            "fakens",
+	})
+	assert.True(t, time.Since(timeStamp) < 5*time.Second)
+	assert.Equal(t, v1alpha2.Cancelled, status.Status)
+	assert.Nil(t, manager.activationRun("fakens", "remotely-cancelled-activation"))
+}
+
+func TestPauseBetweenTasks(t *testing.T) {
+	manager := prepareManager()
+	results := make(chan model.StageStatus, 1)
+	go func() {
+		status, _ := manager.HandleTriggerEvent(context.Background(), model.CampaignVersionSpec{
+			SelfDriving: true,
+			FirstStage:  "test",
+			Stages: map[string]model.StageSpec{
+				"test": {
+					Tasks: []model.TaskSpec{
+						{
+							Name:     "first",
+							Provider: "providers.stage.delay",
+							Config:   map[string]string{},
+							Inputs: map[string]interface{}{
+								"delay": 1,
+							},
+						},
+						{
+							Name:     "second",
+							Provider: "providers.stage.mock",
+							Config:   map[string]string{},
+						},
+					},
+					TaskOption: model.TaskOption{
+						Concurrency: 1,
+					},
+				},
+			},
+		}, v1alpha2.ActivationData{
+			CampaignVersion:      "test-campaignversion",
+			Activation:           "paused-activation",
+			Stage:                "test",
+			ActivationGeneration: "1",
+			Namespace:            "fakens",
+		})
+		results <- status
+	}()
+	waitForRun(t, manager, "fakens", "paused-activation")
+	manager.PauseActivation(context.Background(), "paused-activation", "fakens")
+
+	// tasks that haven't been dispatched are held back until the activation is resumed
+	select {
+	case <-results:
+		t.Fatal("stage finished while the activation was paused")
+	case <-time.After(2 * time.Second):
+	}
+	held, err := manager.ResumeActivation(context.Background(), "paused-activation", "fakens")
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+
+	select {
+	case status := <-results:
+		assert.Equal(t, v1alpha2.Done, status.Status)
+		assert.NotNil(t, status.Outputs["second"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("stage didn't finish after the activation was resumed")
+	}
+}
+
+func TestHoldTrigger(t *testing.T) {
+	manager := prepareManager()
+	ctx := context.Background()
+	triggerData := v1alpha2.ActivationData{
+		CampaignVersion:      "test-campaignversion",
+		Activation:           "held-activation",
+		Stage:                "deploy",
+		ActivationGeneration: "1",
+		Provider:             "providers.stage.mock",
+		Namespace:            "fakens",
+	}
+	status, err := manager.HoldTrigger(ctx, triggerData)
+	assert.Nil(t, err)
+	assert.Equal(t, "deploy", status.Stage)
+	assert.Equal(t, v1alpha2.Paused, status.Status)
+
+	held, err := manager.ResumeActivation(ctx, "held-activation", "fakens")
+	assert.Nil(t, err)
+	assert.NotNil(t, held)
+	assert.Equal(t, "deploy", held.Stage)
+	assert.Equal(t, "providers.stage.mock", held.Provider)
+
+	held, err = manager.ResumeActivation(ctx, "held-activation", "fakens")
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+
+	// a cancelled activation drops the stage it's held on
+	_, err = manager.HoldTrigger(ctx, triggerData)
+	assert.Nil(t, err)
+	err = manager.CancelActivation(ctx, "held-activation", "fakens")
+	assert.Nil(t, err)
+	held, err = manager.ResumeActivation(ctx, "held-activation", "fakens")
+	assert.Nil(t, err)
+	assert.Nil(t, held)
+}
@@ -167,7 +167,7 @@ func (s *StagingManager) GetABatchForSite(site string, count int) ([]v1alpha2.Jo
 		if err != nil {
 			return nil, err
 		}
-		if job, ok := queueElement.(v1alpha2.JobData); ok {
+		if job, ok := toJobData(queueElement); ok {
 			items = append(items, job)
 			itemCount++
 		} else {
@@ -179,3 +179,20 @@ func (s *StagingManager) GetABatchForSite(site string, count int) ([]v1alpha2.Jo
 	}
 	return items, nil
 }
+
+// toJobData reads a job from a queue element. Queue providers that serialize their
+// elements, such as the redis queue, return jobs as generic JSON objects.
+func toJobData(element interface{}) (v1alpha2.JobData, bool) {
+	switch e := element.(type) {
+	case v1alpha2.JobData:
+		return e, true
+	case map[string]interface{}:
+		var job v1alpha2.JobData
+		data, _ := json.Marshal(e)
+		if json.Unmarshal(data, &job) != nil || job.Id == "" {
+			return job, false
+		}
+		return job, true
+	}
+	return v1alpha2.JobData{}, false
+}
@@ -149,6 +149,26 @@ func TestGetABatchForSite(t *testing.T) {
 	assert.Equal(t, v1alpha2.JobUpdate, jobs[0].Action)
 }
 
+func TestGetABatchForSiteWithSerializedJobs(t *testing.T) {
+	queueProvider := &memoryqueue.MemoryQueueProvider{}
+	queueProvider.Init(memoryqueue.MemoryQueueProviderConfig{})
+
+	manager := StagingManager{
+		QueueProvider: queueProvider,
+	}
+
+	// jobs read back from a serializing queue, such as the redis queue, are generic JSON objects
+	queueProvider.Enqueue("fake", map[string]interface{}{
+		"id":     "catalogversion1",
+		"action": "UPDATE",
+	})
+	jobs, err := manager.GetABatchForSite("fake", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+	assert.Equal(t, "catalogversion1", jobs[0].Id)
+	assert.Equal(t, v1alpha2.JobUpdate, jobs[0].Action)
+}
+
 func InitializeMockSymphonyAPI() *httptest.Server {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var response interface{}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils/watch"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -203,6 +204,12 @@ func (t *TargetsManager) ReportState(ctx context.Context, current model.TargetSt
 	return targetState, nil
 }
 func (t *TargetsManager) ListState(ctx context.Context, namespace string) ([]model.TargetState, error) {
+	ret, _, err := t.ListStatePage(ctx, namespace, states.ListOptions{})
+	return ret, err
+}
+
+// ListStatePage lists a page of the targets, and returns the token of the next page
+func (t *TargetsManager) ListStatePage(ctx context.Context, namespace string, options states.ListOptions) ([]model.TargetState, string, error) {
 	ctx, span := observability.StartSpan("Targets Manager", ctx, &map[string]string{
 		"method": "ListSpec",
 	})
@@ -218,23 +225,45 @@ func (t *TargetsManager) ListState(ctx context.Context, namespace string) ([]mod
 			"namespace": namespace,
 			"kind":      "Target",
 		},
+		ListOptions: options,
 	}
 	var targets []states.StateEntry
-	targets, _, err = t.StateProvider.List(ctx, listRequest)
+	var next string
+	targets, next, err = t.StateProvider.List(ctx, listRequest)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	ret := make([]model.TargetState, 0)
 	for _, t := range targets {
 		var rt model.TargetState
 		rt, err = getTargetState(t.Body)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		rt.ObjectMeta.UpdateEtag(t.ETag)
 		ret = append(ret, rt)
 	}
-	return ret, nil
+	return ret, next, nil
+}
+
+// WatchState streams the targets and their changes
+func (t *TargetsManager) WatchState(ctx context.Context, namespace string) (<-chan model.WatchEvent, error) {
+	return watch.WatchStates(ctx, t.StateProvider, states.WatchRequest{
+		Metadata: map[string]interface{}{
+			"version":   "v1",
+			"group":     model.FabricGroup,
+			"resource":  "targets",
+			"namespace": namespace,
+			"kind":      "Target",
+		},
+	}, func(entry states.StateEntry) (interface{}, error) {
+		state, err := getTargetState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		state.ObjectMeta.UpdateEtag(entry.ETag)
+		return state, nil
+	})
 }
 
 func getTargetState(body interface{}) (model.TargetState, error) {
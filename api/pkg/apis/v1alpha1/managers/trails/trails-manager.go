@@ -25,6 +25,8 @@ var log = logger.NewLogger("coa.runtime")
 type TrailsManager struct {
 	managers.Manager
 	LedgerProviders []ledger.ILedgerProvider
+	// Verifiers are the ledger providers that can be verified, by provider name
+	Verifiers map[string]ledger.IVerifiableLedgerProvider
 }
 
 func (s *TrailsManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
@@ -33,10 +35,14 @@ func (s *TrailsManager) Init(context *contexts.VendorContext, config managers.Ma
 		return err
 	}
 	s.LedgerProviders = make([]ledger.ILedgerProvider, 0)
-	for _, provider := range providers {
+	s.Verifiers = make(map[string]ledger.IVerifiableLedgerProvider)
+	for name, provider := range providers {
 		if p, ok := provider.(ledger.ILedgerProvider); ok {
 			s.LedgerProviders = append(s.LedgerProviders, p)
 		}
+		if p, ok := provider.(ledger.IVerifiableLedgerProvider); ok {
+			s.Verifiers[name] = p
+		}
 	}
 	return nil
 }
@@ -65,3 +71,30 @@ func (s *TrailsManager) Append(ctx context.Context, trails []v1alpha2.Trail) err
 	log.DebugCtx(ctx, " M (Trails): append trails successfully")
 	return nil
 }
+
+// Verify checks the ledgers that can be verified, and returns their results by provider name.
+// A ledger that has been tampered with isn't an error, its result lists the problems.
+func (s *TrailsManager) Verify(ctx context.Context, anchor *ledger.Anchor) (map[string]ledger.VerifyResult, error) {
+	ctx, span := observability.StartSpan("Trails Manager", ctx, &map[string]string{
+		"method": "Verify",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	log.DebugfCtx(ctx, " M (Trails): verify ledgers, ledger count: %d", len(s.Verifiers))
+	ret := make(map[string]ledger.VerifyResult)
+	for name, p := range s.Verifiers {
+		var result ledger.VerifyResult
+		result, err = p.Verify(ctx, anchor)
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Trails): failed to verify ledger %s: %+v", name, err)
+			return nil, err
+		}
+		if !result.Valid {
+			log.WarnfCtx(ctx, " M (Trails): ledger %s has problems: %v", name, result.Problems)
+		}
+		ret[name] = result
+	}
+	return ret, nil
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	fileledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/file"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/stretchr/testify/assert"
 )
@@ -66,6 +67,31 @@ func TestAppendFail(t *testing.T) {
 	assert.Equal(t, assert.AnError.Error()+";", coaError.Message)
 }
 
+func TestVerify(t *testing.T) {
+	fileProvider := &fileledger.FileLedgerProvider{}
+	err := fileProvider.Init(fileledger.FileLedgerProviderConfig{Folder: t.TempDir()})
+	assert.Nil(t, err)
+	mockProvider := &mockledger.MockLedgerProvider{}
+	err = mockProvider.Init(mockledger.MockLedgerProviderConfig{})
+	assert.Nil(t, err)
+	providers := map[string]providers.IProvider{
+		"file": fileProvider,
+		"mock": mockProvider,
+	}
+	manager := TrailsManager{}
+	err = manager.Init(nil, managers.ManagerConfig{Properties: map[string]string{}}, providers)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(manager.LedgerProviders))
+	err = manager.Append(context.Background(), []v1alpha2.Trail{{Origin: "o1", Type: "t1"}})
+	assert.Nil(t, err)
+
+	results, err := manager.Verify(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(results))
+	assert.True(t, results["file"].Valid)
+	assert.Equal(t, uint64(1), results["file"].Entries)
+}
+
 type MockLedgerProviderFail struct {
 }
 
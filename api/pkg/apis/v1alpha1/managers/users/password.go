@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package users
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2Params are the parameters of argon2id hashes
+type argon2Params struct {
+	memory      uint32 // KiB
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// defaultArgon2Params follow the second recommended option of RFC 9106
+var defaultArgon2Params = argon2Params{
+	memory:      64 * 1024,
+	iterations:  3,
+	parallelism: 4,
+	saltLength:  16,
+	keyLength:   32,
+}
+
+// hashPassword hashes a password with argon2id, in the PHC string format:
+// "$argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>"
+func hashPassword(password string, params argon2Params) (string, error) {
+	salt := make([]byte, params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", v1alpha2.NewCOAError(err, "failed to generate salt", v1alpha2.InternalError)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, params.memory, params.iterations, params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// verifyPassword checks a password against a stored hash. upgrade tells if the hash should
+// be replaced with one of the current parameters, which is the case for the FNV hashes of
+// earlier versions.
+func verifyPassword(name string, password string, encoded string, params argon2Params) (match bool, upgrade bool) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return subtle.ConstantTimeCompare([]byte(legacyHash(name, password)), []byte(encoded)) == 1, true
+	}
+	var version int
+	var stored argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &stored.memory, &stored.iterations, &stored.parallelism); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+	stored.saltLength = uint32(len(salt))
+	stored.keyLength = uint32(len(key))
+	other := argon2.IDKey([]byte(password), salt, stored.iterations, stored.memory, stored.parallelism, stored.keyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false
+	}
+	return true, stored != params
+}
+
+// legacyHash is the salted 32-bit FNV hash that earlier versions stored. It's only used to
+// check passwords before they're upgraded.
+func legacyHash(name string, s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name + "." + s + ".salt"))
+	return fmt.Sprintf("H%d", h.Sum32())
+}
+
+// PasswordPolicy is the policy of passwords that are set over the API
+type PasswordPolicy struct {
+	MinLength int
+	// MinClasses is the number of character classes (lower case, upper case, digits and
+	// others) a password needs to have
+	MinClasses int
+}
+
+// Check tells why a password doesn't meet the policy
+func (p PasswordPolicy) Check(name string, password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("password must have at least %d characters", p.MinLength), v1alpha2.BadRequest)
+	}
+	if strings.EqualFold(password, name) {
+		return v1alpha2.NewCOAError(nil, "password must not be the user name", v1alpha2.BadRequest)
+	}
+	lower, upper, digit, other := 0, 0, 0, 0
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			other = 1
+		}
+	}
+	if lower+upper+digit+other < p.MinClasses {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("password must have at least %d of lower case letters, upper case letters, digits and other characters", p.MinClasses), v1alpha2.BadRequest)
+	}
+	return nil
+}
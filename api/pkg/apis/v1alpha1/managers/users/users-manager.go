@@ -10,8 +10,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
@@ -19,23 +24,69 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 var log = logger.NewLogger("coa.runtime")
 
+const (
+	defaultPasswordMinLength = 8
+	defaultMaxFailedLogins   = 5
+	defaultLockoutSeconds    = 900
+	// TrailType is the type of the trails of changes to users
+	TrailType = "users.symphony/v1"
+	// tokenIssuer is the issuer of the tokens that the users vendor issues at login
+	tokenIssuer = "symphony"
+)
+
 type UsersManager struct {
 	managers.Manager
-	StateProvider states.IStateProvider
+	StateProvider  states.IStateProvider
+	PasswordPolicy PasswordPolicy
+	// MaxFailedLogins is the number of failed logins in a row that lock a user out, or 0 to
+	// never lock users out
+	MaxFailedLogins int
+	LockoutDuration time.Duration
+	// lock serializes the updates of the failed logins of users
+	lock sync.Mutex
+	now  func() time.Time
 }
 
 type UserState struct {
-	Id           string   `json:"id"`
-	PasswordHash string   `json:"passwordHash,omitempty"`
-	Roles        []string `json:"roles,omitempty"`
+	Id            string     `json:"id"`
+	PasswordHash  string     `json:"passwordHash,omitempty"`
+	Roles         []string   `json:"roles,omitempty"`
+	Disabled      bool       `json:"disabled,omitempty"`
+	FailedLogins  int        `json:"failedLogins,omitempty"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+	PasswordSetAt *time.Time `json:"passwordSetAt,omitempty"`
+	LastLoginAt   *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// User is a user as it's returned by the API, without its password hash
+type User struct {
+	Id            string     `json:"id"`
+	Roles         []string   `json:"roles"`
+	Disabled      bool       `json:"disabled"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+	PasswordSetAt *time.Time `json:"passwordSetAt,omitempty"`
+	LastLoginAt   *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// UserUpdate has the changes to a user. Roles that are nil and Disabled that is nil are kept,
+// as is the password when it's empty.
+type UserUpdate struct {
+	Password string   `json:"password,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Disabled *bool    `json:"disabled,omitempty"`
 }
 
 func (s *UsersManager) Init(context *contexts.VendorContext, config managers.ManagerConfig, providers map[string]providers.IProvider) error {
-	stateprovider, err := managers.GetVolatileStateProvider(config, providers)
+	err := s.Manager.Init(context, config, providers)
+	if err != nil {
+		return err
+	}
+	stateprovider, err := managers.GetPersistentStateProvider(config, providers)
 	if err == nil {
 		s.StateProvider = stateprovider
 	} else {
@@ -43,8 +94,34 @@ func (s *UsersManager) Init(context *contexts.VendorContext, config managers.Man
 		return err
 	}
 
+	s.PasswordPolicy = PasswordPolicy{MinLength: defaultPasswordMinLength}
+	s.MaxFailedLogins = defaultMaxFailedLogins
+	lockoutSeconds := defaultLockoutSeconds
+	for key, target := range map[string]*int{
+		"passwordMinLength":  &s.PasswordPolicy.MinLength,
+		"passwordMinClasses": &s.PasswordPolicy.MinClasses,
+		"maxFailedLogins":    &s.MaxFailedLogins,
+		"lockoutSeconds":     &lockoutSeconds,
+	} {
+		if v, ok := config.Properties[key]; ok && strings.TrimSpace(v) != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil || n < 0 {
+				return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid %s '%s', expected a non-negative integer", key, v), v1alpha2.BadConfig)
+			}
+			*target = n
+		}
+	}
+	s.LockoutDuration = time.Duration(lockoutSeconds) * time.Second
 	return nil
 }
+
+func (t *UsersManager) currentTime() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now().UTC()
+}
+
 func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "DeleteUser",
@@ -64,12 +141,8 @@ func (t *UsersManager) DeleteUser(ctx context.Context, name string) error {
 	return nil
 }
 
-func hash(name string, s string) string {
-	h := fnv.New32a()
-	h.Write([]byte(name + "." + s + ".salt"))
-	return fmt.Sprintf("H%d", h.Sum32())
-}
-
+// UpsertUser sets the password and roles of a user, creating the user if it doesn't exist.
+// The password policy doesn't apply, so that built-in users can be seeded.
 func (t *UsersManager) UpsertUser(ctx context.Context, name string, password string, roles []string) error {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "UpsertUser",
@@ -79,23 +152,156 @@ func (t *UsersManager) UpsertUser(ctx context.Context, name string, password str
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 	log.InfofCtx(ctx, " M (Users): UpsertUser name %s", name)
 
-	upsertRequest := states.UpsertRequest{
-		Value: states.StateEntry{
-			ID: name,
-			Body: UserState{
-				Id:           name,
-				PasswordHash: hash(name, password),
-				Roles:        roles,
-			},
-		},
+	var passwordHash string
+	passwordHash, err = hashPassword(password, defaultArgon2Params)
+	if err != nil {
+		return err
 	}
-	_, err = t.StateProvider.Upsert(ctx, upsertRequest)
+	now := t.currentTime()
+	err = t.saveUser(ctx, UserState{
+		Id:            name,
+		PasswordHash:  passwordHash,
+		Roles:         roles,
+		PasswordSetAt: &now,
+	})
 	if err != nil {
 		log.DebugfCtx(ctx, " M (Users) : failed to upsert user %v", err)
 		return err
 	}
 	return nil
 }
+
+// CreateUser creates a user with a password that meets the password policy
+func (t *UsersManager) CreateUser(ctx context.Context, name string, update UserUpdate) (User, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "CreateUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Users): CreateUser name %s", name)
+
+	if strings.TrimSpace(name) == "" {
+		err = v1alpha2.NewCOAError(nil, "user name is required", v1alpha2.BadRequest)
+		return User{}, err
+	}
+	if update.Password == "" {
+		err = v1alpha2.NewCOAError(nil, "password is required to create a user", v1alpha2.BadRequest)
+		return User{}, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	_, err = t.getUserState(ctx, name)
+	if err == nil {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("user '%s' already exists", name), v1alpha2.Conflict)
+		return User{}, err
+	}
+	if !v1alpha2.IsNotFound(err) {
+		return User{}, err
+	}
+	state := UserState{Id: name, Roles: update.Roles}
+	if update.Disabled != nil {
+		state.Disabled = *update.Disabled
+	}
+	err = t.setPassword(&state, update.Password)
+	if err != nil {
+		return User{}, err
+	}
+	err = t.saveUser(ctx, state)
+	if err != nil {
+		return User{}, err
+	}
+	return toUser(state), nil
+}
+
+// UpdateUser changes the password, roles or status of a user. Setting the password also
+// lifts a lockout.
+func (t *UsersManager) UpdateUser(ctx context.Context, name string, update UserUpdate) (User, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "UpdateUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, " M (Users): UpdateUser name %s", name)
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var state UserState
+	state, err = t.getUserState(ctx, name)
+	if err != nil {
+		return User{}, err
+	}
+	if update.Password != "" {
+		err = t.setPassword(&state, update.Password)
+		if err != nil {
+			return User{}, err
+		}
+		state.FailedLogins = 0
+		state.LockedUntil = nil
+	}
+	if update.Roles != nil {
+		state.Roles = update.Roles
+	}
+	if update.Disabled != nil {
+		state.Disabled = *update.Disabled
+	}
+	err = t.saveUser(ctx, state)
+	if err != nil {
+		return User{}, err
+	}
+	return toUser(state), nil
+}
+
+func (t *UsersManager) GetUser(ctx context.Context, name string) (User, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "GetUser",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugfCtx(ctx, " M (Users): GetUser name %s", name)
+
+	var state UserState
+	state, err = t.getUserState(ctx, name)
+	if err != nil {
+		return User{}, err
+	}
+	return toUser(state), nil
+}
+
+// ListUsers returns the users sorted by name
+func (t *UsersManager) ListUsers(ctx context.Context) ([]User, error) {
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ListUsers",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.DebugCtx(ctx, " M (Users): ListUsers")
+
+	var entries []states.StateEntry
+	entries, _, err = t.StateProvider.List(ctx, states.ListRequest{})
+	if err != nil {
+		log.ErrorfCtx(ctx, " M (Users): failed to list users, err: %v", err)
+		return nil, err
+	}
+	ret := make([]User, 0, len(entries))
+	for _, entry := range entries {
+		var state UserState
+		state, err = getUserState(entry.Body)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, toUser(state))
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Id < ret[j].Id })
+	return ret, nil
+}
+
+// CheckUser checks the password of a user, and returns its roles if it's correct. Disabled
+// users and users that are locked out after too many failed logins can't log in. Passwords
+// that are stored with an outdated hash are hashed again.
 func (t *UsersManager) CheckUser(ctx context.Context, name string, password string) ([]string, bool) {
 	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
 		"method": "CheckUser",
@@ -105,27 +311,200 @@ func (t *UsersManager) CheckUser(ctx context.Context, name string, password stri
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 	log.InfofCtx(ctx, " M (Users): CheckUser name %s", name)
 
-	getRequest := states.GetRequest{
-		ID: name,
+	var userState UserState
+	userState, err = t.getUserState(ctx, name)
+	if err != nil {
+		log.DebugfCtx(ctx, " M (Users) : failed to get user %s states", err)
+		// hash anyway, so that unknown users can't be told apart by the time it takes
+		verifyPassword(name, password, getDummyHash(), defaultArgon2Params)
+		return nil, false
 	}
-	var user states.StateEntry
-	user, err = t.StateProvider.Get(ctx, getRequest)
+	if userState.Disabled {
+		log.InfofCtx(ctx, " M (Users) : user %s is disabled", name)
+		return nil, false
+	}
+	if userState.LockedUntil != nil && t.currentTime().Before(*userState.LockedUntil) {
+		log.InfofCtx(ctx, " M (Users) : user %s is locked out until %s", name, userState.LockedUntil.Format(time.RFC3339))
+		return nil, false
+	}
+	verifiedHash := userState.PasswordHash
+	match, upgrade := verifyPassword(name, password, verifiedHash, defaultArgon2Params)
+	passwordHash := ""
+	if match && upgrade {
+		log.InfofCtx(ctx, " M (Users) : upgrading password hash of user %s", name)
+		passwordHash, err = hashPassword(password, defaultArgon2Params)
+		if err != nil {
+			log.ErrorfCtx(ctx, " M (Users) : failed to upgrade password hash of user %s, err: %v", name, err)
+		}
+	}
+
+	// the user is read again, as it may have changed while the password was checked
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	userState, err = t.getUserState(ctx, name)
 	if err != nil {
 		log.DebugfCtx(ctx, " M (Users) : failed to get user %s states", err)
 		return nil, false
 	}
+	now := t.currentTime()
+	if !match {
+		log.DebugCtx(ctx, " M (Users) : authentication failed")
+		userState.FailedLogins++
+		if t.MaxFailedLogins > 0 && userState.FailedLogins >= t.MaxFailedLogins {
+			lockedUntil := now.Add(t.LockoutDuration)
+			userState.LockedUntil = &lockedUntil
+			userState.FailedLogins = 0
+			log.InfofCtx(ctx, " M (Users) : user %s is locked out until %s", name, lockedUntil.Format(time.RFC3339))
+			t.publishTrail(ctx, "lock", userState)
+		}
+		if err = t.saveUser(ctx, userState); err != nil {
+			log.ErrorfCtx(ctx, " M (Users) : failed to save failed login of user %s, err: %v", name, err)
+		}
+		return nil, false
+	}
+	if userState.PasswordHash != verifiedHash {
+		// the password was changed while the old one was checked, so neither the login nor the
+		// upgraded hash of the old password count
+		log.InfofCtx(ctx, " M (Users) : password of user %s changed while it was checked", name)
+		return nil, false
+	}
+	if passwordHash != "" {
+		userState.PasswordHash = passwordHash
+	}
+	userState.FailedLogins = 0
+	userState.LockedUntil = nil
+	userState.LastLoginAt = &now
+	if err = t.saveUser(ctx, userState); err != nil {
+		log.ErrorfCtx(ctx, " M (Users) : failed to save login of user %s, err: %v", name, err)
+	}
+	log.DebugCtx(ctx, " M (Users) : user authenticated")
+	return userState.Roles, true
+}
+
+// ValidateToken rejects the tokens of users that were disabled or deleted after they logged
+// in, which would otherwise be valid until they expire. It implements v1alpha2.ITokenValidator,
+// but it never accepts a token: the tokens of users that may log in, and tokens that aren't
+// issued at login, are left to the JWT middleware, which checks their signature.
+func (t *UsersManager) ValidateToken(ctx context.Context, token string) (v1alpha2.Principal, bool, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return v1alpha2.Principal{}, false, nil
+	}
+	name, _ := claims["user"].(string)
+	if name == "" || !claims.VerifyIssuer(tokenIssuer, true) {
+		return v1alpha2.Principal{}, false, nil
+	}
+	ctx, span := observability.StartSpan("Users Manager", ctx, &map[string]string{
+		"method": "ValidateToken",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+
 	var userState UserState
-	bytes, _ := json.Marshal(user.Body)
-	err = json.Unmarshal(bytes, &userState)
+	userState, err = t.getUserState(ctx, name)
 	if err != nil {
-		return nil, false
+		if !v1alpha2.IsNotFound(err) {
+			log.ErrorfCtx(ctx, " M (Users) : failed to get user %s, err: %v", name, err)
+			err = v1alpha2.NewCOAError(err, "failed to check user", v1alpha2.Unauthorized)
+			return v1alpha2.Principal{}, true, err
+		}
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("user '%s' doesn't exist", name), v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	if userState.Disabled {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("user '%s' is disabled", name), v1alpha2.Unauthorized)
+		return v1alpha2.Principal{}, true, err
+	}
+	return v1alpha2.Principal{}, false, nil
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// getDummyHash returns the hash that is checked for users that don't exist
+func getDummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = hashPassword("", defaultArgon2Params)
+	})
+	return dummyHash
+}
+
+func (t *UsersManager) setPassword(state *UserState, password string) error {
+	if err := t.PasswordPolicy.Check(state.Id, password); err != nil {
+		return err
+	}
+	passwordHash, err := hashPassword(password, defaultArgon2Params)
+	if err != nil {
+		return err
 	}
+	now := t.currentTime()
+	state.PasswordHash = passwordHash
+	state.PasswordSetAt = &now
+	return nil
+}
 
-	if hash(name, password) == userState.PasswordHash {
-		log.DebugCtx(ctx, " M (Users) : user authenticated")
-		return userState.Roles, true
+func (t *UsersManager) publishTrail(ctx context.Context, action string, state UserState) {
+	if t.Context == nil {
+		return
 	}
+	t.Context.Publish("trail", v1alpha2.Event{
+		Body: []v1alpha2.Trail{
+			{
+				Origin: t.Context.SiteInfo.SiteId,
+				Type:   TrailType,
+				Properties: map[string]interface{}{
+					"action": action,
+					"id":     state.Id,
+				},
+			},
+		},
+		Context: ctx,
+	})
+}
 
-	log.DebugCtx(ctx, " M (Users) : authentication failed")
-	return nil, false
+func (t *UsersManager) getUserState(ctx context.Context, name string) (UserState, error) {
+	entry, err := t.StateProvider.Get(ctx, states.GetRequest{
+		ID: name,
+	})
+	if err != nil {
+		return UserState{}, err
+	}
+	return getUserState(entry.Body)
+}
+
+func (t *UsersManager) saveUser(ctx context.Context, state UserState) error {
+	_, err := t.StateProvider.Upsert(ctx, states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   state.Id,
+			Body: state,
+		},
+	})
+	return err
+}
+
+func getUserState(body interface{}) (UserState, error) {
+	var userState UserState
+	bytes, _ := json.Marshal(body)
+	err := json.Unmarshal(bytes, &userState)
+	if err != nil {
+		return UserState{}, v1alpha2.NewCOAError(err, "failed to read user state", v1alpha2.InternalError)
+	}
+	return userState, nil
+}
+
+func toUser(state UserState) User {
+	roles := state.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return User{
+		Id:            state.Id,
+		Roles:         roles,
+		Disabled:      state.Disabled,
+		LockedUntil:   state.LockedUntil,
+		PasswordSetAt: state.PasswordSetAt,
+		LastLoginAt:   state.LastLoginAt,
+	}
 }
@@ -1,83 +1,324 @@
-/*
- * Copyright (c) Microsoft Corporation.
- * Licensed under the MIT license.
- * SPDX-License-Identifier: MIT
- */
-
-package users
-
-import (
-	"context"
-	"testing"
-
-	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
-	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
-	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
-	"github.com/stretchr/testify/assert"
-)
-
-func TestInit(t *testing.T) {
-	stateProvider := &memorystate.MemoryStateProvider{}
-	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
-	manager := UsersManager{
-		StateProvider: stateProvider,
-	}
-	config := managers.ManagerConfig{
-		Properties: map[string]string{
-			"providers.volatilestate": "StateProvider",
-		},
-	}
-	providers := make(map[string]providers.IProvider)
-	providers["StateProvider"] = stateProvider
-	err := manager.Init(nil, config, providers)
-	assert.Nil(t, err)
-}
-
-func TestUpsertAndDelete(t *testing.T) {
-	stateProvider := &memorystate.MemoryStateProvider{}
-	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
-	manager := UsersManager{
-		StateProvider: stateProvider,
-	}
-	config := managers.ManagerConfig{
-		Properties: map[string]string{
-			"providers.volatilestate": "StateProvider",
-		},
-	}
-	providers := make(map[string]providers.IProvider)
-	providers["StateProvider"] = stateProvider
-	err := manager.Init(nil, config, providers)
-	assert.Nil(t, err)
-	err = manager.UpsertUser(context.Background(), "test", "password", []string{"testrole"})
-	assert.Nil(t, err)
-	err = manager.DeleteUser(context.Background(), "test")
-	assert.Nil(t, err)
-}
-
-func TestUpsertAndCheck(t *testing.T) {
-	stateProvider := &memorystate.MemoryStateProvider{}
-	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
-	manager := UsersManager{
-		StateProvider: stateProvider,
-	}
-	config := managers.ManagerConfig{
-		Properties: map[string]string{
-			"providers.volatilestate": "StateProvider",
-		},
-	}
-	providers := make(map[string]providers.IProvider)
-	providers["StateProvider"] = stateProvider
-	err := manager.Init(nil, config, providers)
-	assert.Nil(t, err)
-	roles := []string{"testrole"}
-	err = manager.UpsertUser(context.Background(), "test", "password", roles)
-	assert.Nil(t, err)
-	rolescheck, res := manager.CheckUser(context.Background(), "test", "wrongpassword")
-	assert.False(t, res)
-	assert.Nil(t, rolescheck)
-	rolescheck, res = manager.CheckUser(context.Background(), "test", "password")
-	assert.Equal(t, roles, rolescheck)
-	assert.True(t, res)
-	err = manager.DeleteUser(context.Background(), "test")
-	assert.Nil(t, err)
-}
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := UsersManager{
+		StateProvider: stateProvider,
+	}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, config, providers)
+	assert.Nil(t, err)
+}
+
+func TestUpsertAndDelete(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := UsersManager{
+		StateProvider: stateProvider,
+	}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, config, providers)
+	assert.Nil(t, err)
+	err = manager.UpsertUser(context.Background(), "test", "password", []string{"testrole"})
+	assert.Nil(t, err)
+	err = manager.DeleteUser(context.Background(), "test")
+	assert.Nil(t, err)
+}
+
+func TestUpsertAndCheck(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := UsersManager{
+		StateProvider: stateProvider,
+	}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	providers := make(map[string]providers.IProvider)
+	providers["StateProvider"] = stateProvider
+	err := manager.Init(nil, config, providers)
+	assert.Nil(t, err)
+	roles := []string{"testrole"}
+	err = manager.UpsertUser(context.Background(), "test", "password", roles)
+	assert.Nil(t, err)
+	rolescheck, res := manager.CheckUser(context.Background(), "test", "wrongpassword")
+	assert.False(t, res)
+	assert.Nil(t, rolescheck)
+	rolescheck, res = manager.CheckUser(context.Background(), "test", "password")
+	assert.Equal(t, roles, rolescheck)
+	assert.True(t, res)
+	err = manager.DeleteUser(context.Background(), "test")
+	assert.Nil(t, err)
+}
+
+func newTestManager(t *testing.T, properties map[string]string) *UsersManager {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := &UsersManager{}
+	config := managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+		},
+	}
+	for k, v := range properties {
+		config.Properties[k] = v
+	}
+	err := manager.Init(nil, config, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.Nil(t, err)
+	return manager
+}
+
+func TestInitWithInvalidPolicy(t *testing.T) {
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	manager := UsersManager{}
+	err := manager.Init(nil, managers.ManagerConfig{
+		Properties: map[string]string{
+			"providers.persistentstate": "StateProvider",
+			"maxFailedLogins":           "many",
+		},
+	}, map[string]providers.IProvider{"StateProvider": stateProvider})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestPasswordsAreHashedWithArgon2id(t *testing.T) {
+	manager := newTestManager(t, nil)
+	err := manager.UpsertUser(context.Background(), "test", "password", nil)
+	assert.Nil(t, err)
+	state, err := manager.getUserState(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(state.PasswordHash, "$argon2id$v=19$m=65536,t=3,p=4$"))
+
+	// the same password is salted differently
+	err = manager.UpsertUser(context.Background(), "test2", "password", nil)
+	assert.Nil(t, err)
+	other, err := manager.getUserState(context.Background(), "test2")
+	assert.Nil(t, err)
+	assert.NotEqual(t, state.PasswordHash, other.PasswordHash)
+}
+
+func TestLegacyHashIsUpgradedAtLogin(t *testing.T) {
+	manager := newTestManager(t, nil)
+	err := manager.saveUser(context.Background(), UserState{Id: "test", PasswordHash: legacyHash("test", "password"), Roles: []string{"reader"}})
+	assert.Nil(t, err)
+
+	_, ok := manager.CheckUser(context.Background(), "test", "wrongpassword")
+	assert.False(t, ok)
+	state, _ := manager.getUserState(context.Background(), "test")
+	assert.Equal(t, legacyHash("test", "password"), state.PasswordHash)
+
+	roles, ok := manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"reader"}, roles)
+	state, _ = manager.getUserState(context.Background(), "test")
+	assert.True(t, strings.HasPrefix(state.PasswordHash, "$argon2id$"))
+	assert.NotNil(t, state.LastLoginAt)
+
+	_, ok = manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, ok)
+}
+
+func TestLockoutAfterFailedLogins(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"maxFailedLogins": "3", "lockoutSeconds": "60"})
+	now := time.Now().UTC()
+	manager.now = func() time.Time { return now }
+	err := manager.UpsertUser(context.Background(), "test", "password", nil)
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, ok := manager.CheckUser(context.Background(), "test", "wrongpassword")
+		assert.False(t, ok)
+	}
+	// a successful login resets the count
+	_, ok := manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, ok)
+	for i := 0; i < 3; i++ {
+		_, ok = manager.CheckUser(context.Background(), "test", "wrongpassword")
+		assert.False(t, ok)
+	}
+	_, ok = manager.CheckUser(context.Background(), "test", "password")
+	assert.False(t, ok)
+	user, err := manager.GetUser(context.Background(), "test")
+	assert.Nil(t, err)
+	assert.NotNil(t, user.LockedUntil)
+
+	now = now.Add(61 * time.Second)
+	_, ok = manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, ok)
+
+	// setting the password lifts a lockout
+	for i := 0; i < 3; i++ {
+		manager.CheckUser(context.Background(), "test", "wrongpassword")
+	}
+	_, err = manager.UpdateUser(context.Background(), "test", UserUpdate{Password: "newpassword"})
+	assert.Nil(t, err)
+	_, ok = manager.CheckUser(context.Background(), "test", "newpassword")
+	assert.True(t, ok)
+}
+
+func TestDisabledUserCantLogIn(t *testing.T) {
+	manager := newTestManager(t, nil)
+	disabled := true
+	_, err := manager.CreateUser(context.Background(), "test", UserUpdate{Password: "password", Disabled: &disabled})
+	assert.Nil(t, err)
+	_, ok := manager.CheckUser(context.Background(), "test", "password")
+	assert.False(t, ok)
+
+	disabled = false
+	_, err = manager.UpdateUser(context.Background(), "test", UserUpdate{Disabled: &disabled})
+	assert.Nil(t, err)
+	_, ok = manager.CheckUser(context.Background(), "test", "password")
+	assert.True(t, ok)
+}
+
+func TestTokensOfDisabledOrDeletedUsersAreRejected(t *testing.T) {
+	manager := newTestManager(t, nil)
+	_, err := manager.CreateUser(context.Background(), "test", UserUpdate{Password: "password"})
+	assert.Nil(t, err)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "test", "iss": "symphony"}).SignedString([]byte("key"))
+	assert.Nil(t, err)
+
+	// the tokens of users that may log in are left to the JWT middleware, as are other tokens
+	_, ok, err := manager.ValidateToken(context.Background(), token)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	_, ok, _ = manager.ValidateToken(context.Background(), "not a token")
+	assert.False(t, ok)
+
+	disabled := true
+	_, err = manager.UpdateUser(context.Background(), "test", UserUpdate{Disabled: &disabled})
+	assert.Nil(t, err)
+	_, ok, err = manager.ValidateToken(context.Background(), token)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Unauthorized, v1alpha2.GetErrorState(err))
+
+	err = manager.DeleteUser(context.Background(), "test")
+	assert.Nil(t, err)
+	_, ok, err = manager.ValidateToken(context.Background(), token)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.Unauthorized, v1alpha2.GetErrorState(err))
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	manager := newTestManager(t, map[string]string{"passwordMinLength": "10", "passwordMinClasses": "3"})
+	for _, password := range []string{"", "Short1!", "alllowercaseletters", "onlytwoclasses1", "TestUser01"} {
+		_, err := manager.CreateUser(context.Background(), "testuser01", UserUpdate{Password: password})
+		assert.NotNil(t, err, password)
+		assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State, password)
+	}
+	_, err := manager.CreateUser(context.Background(), "testuser01", UserUpdate{Password: "Three-Classes", Roles: []string{"reader"}})
+	assert.Nil(t, err)
+
+	_, err = manager.UpdateUser(context.Background(), "testuser01", UserUpdate{Password: "weak"})
+	assert.NotNil(t, err)
+	_, ok := manager.CheckUser(context.Background(), "testuser01", "Three-Classes")
+	assert.True(t, ok)
+}
+
+func TestUserCRUD(t *testing.T) {
+	manager := newTestManager(t, nil)
+	user, err := manager.CreateUser(context.Background(), "bob", UserUpdate{Password: "password1", Roles: []string{"reader"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", user.Id)
+	assert.NotNil(t, user.PasswordSetAt)
+
+	_, err = manager.CreateUser(context.Background(), "bob", UserUpdate{Password: "password1"})
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+	_, err = manager.UpdateUser(context.Background(), "alice", UserUpdate{})
+	assert.True(t, v1alpha2.IsNotFound(err))
+
+	_, err = manager.CreateUser(context.Background(), "alice", UserUpdate{Password: "password2"})
+	assert.Nil(t, err)
+	user, err = manager.UpdateUser(context.Background(), "alice", UserUpdate{Roles: []string{"administrator"}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, user.Roles)
+	roles, ok := manager.CheckUser(context.Background(), "alice", "password2")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"administrator"}, roles)
+
+	list, err := manager.ListUsers(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, "alice", list[0].Id)
+	assert.Equal(t, "bob", list[1].Id)
+	data, _ := json.Marshal(list)
+	assert.NotContains(t, string(data), "argon2")
+
+	err = manager.DeleteUser(context.Background(), "bob")
+	assert.Nil(t, err)
+	_, err = manager.GetUser(context.Background(), "bob")
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+// changingStateProvider changes the password of the user when it's read the second time, like
+// an update that lands while the password is checked
+type changingStateProvider struct {
+	*memorystate.MemoryStateProvider
+	manager *UsersManager
+	reads   int
+}
+
+func (s *changingStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
+	s.reads++
+	if s.reads == 2 {
+		entry, _ := s.MemoryStateProvider.Get(ctx, request)
+		state, _ := getUserState(entry.Body)
+		state.PasswordHash, _ = hashPassword("newpassword", defaultArgon2Params)
+		s.manager.saveUser(ctx, state)
+	}
+	return s.MemoryStateProvider.Get(ctx, request)
+}
+
+func TestPasswordChangedDuringLogin(t *testing.T) {
+	manager := newTestManager(t, nil)
+	err := manager.saveUser(context.Background(), UserState{Id: "test", PasswordHash: legacyHash("test", "password"), Roles: []string{"reader"}})
+	assert.Nil(t, err)
+	stateProvider := &changingStateProvider{MemoryStateProvider: manager.StateProvider.(*memorystate.MemoryStateProvider), manager: manager}
+	manager.StateProvider = stateProvider
+
+	_, ok := manager.CheckUser(context.Background(), "test", "password")
+	assert.False(t, ok)
+	// the hash of the old password doesn't replace the new one
+	state, _ := manager.getUserState(context.Background(), "test")
+	match, _ := verifyPassword("test", "newpassword", state.PasswordHash, defaultArgon2Params)
+	assert.True(t, match)
+	assert.Nil(t, state.LastLoginAt)
+}
@@ -0,0 +1,21 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+const (
+	ActivationCancel = "cancel"
+	ActivationPause  = "pause"
+	ActivationResume = "resume"
+)
+
+// ActivationControl is a cancel, pause or resume request on a started activation
+type ActivationControl struct {
+	Activation string `json:"activation"`
+	Namespace  string `json:"namespace,omitempty"`
+	Action     string `json:"action"`
+	User       string `json:"user,omitempty"`
+}
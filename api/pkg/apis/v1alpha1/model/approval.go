@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+
+	// ApprovalTimeoutFail fails the stage when nobody signs off before the deadline
+	ApprovalTimeoutFail = "fail"
+	// ApprovalTimeoutApprove approves the stage when nobody signs off before the deadline
+	ApprovalTimeoutApprove = "approve"
+
+	// ApprovalOutput is the stage output that carries the state of an approval
+	ApprovalOutput = "approval"
+
+	// ApproverAnyRole as an approver role lets anyone who can call the approval endpoints decide
+	ApproverAnyRole = "*"
+)
+
+type (
+	// ApprovalRequest is a sign-off an activation is waiting for before its stage can complete
+	ApprovalRequest struct {
+		CampaignVersion      string   `json:"campaignversion"`
+		Activation           string   `json:"activation"`
+		ActivationGeneration string   `json:"activationGeneration"`
+		Stage                string   `json:"stage"`
+		Namespace            string   `json:"namespace,omitempty"`
+		ApproverRoles        []string `json:"approverRoles,omitempty"`
+		Message              string   `json:"message,omitempty"`
+		// Deadline is the RFC 3339 time after which TimeoutAction is taken; empty means no timeout
+		Deadline      string `json:"deadline,omitempty"`
+		TimeoutAction string `json:"timeoutAction,omitempty"`
+	}
+
+	// ApprovalDecision approves or rejects a pending ApprovalRequest
+	ApprovalDecision struct {
+		CampaignVersion string `json:"campaignversion"`
+		Activation      string `json:"activation"`
+		Stage           string `json:"stage"`
+		Namespace       string `json:"namespace,omitempty"`
+		Decision        string `json:"decision"`
+		DecidedBy       string `json:"decidedBy,omitempty"`
+		Comment         string `json:"comment,omitempty"`
+		TimedOut        bool   `json:"timedOut,omitempty"`
+	}
+)
+
+// IsExpired returns true if the request has a deadline that has passed
+func (r ApprovalRequest) IsExpired(now time.Time) bool {
+	if r.Deadline == "" {
+		return false
+	}
+	deadline, err := time.Parse(time.RFC3339, r.Deadline)
+	if err != nil {
+		return false
+	}
+	return !now.Before(deadline)
+}
+
+// CanApprove returns true if any of the given roles is allowed to decide on the request.
+// A request without approver roles can't be decided by anyone, and one with the "*" role can be
+// decided by anyone.
+func (r ApprovalRequest) CanApprove(roles []string) bool {
+	for _, required := range r.ApproverRoles {
+		if required == ApproverAnyRole {
+			return true
+		}
+		for _, role := range roles {
+			if role == required {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Timeout returns the decision taken when the request expires
+func (r ApprovalRequest) Timeout() ApprovalDecision {
+	decision := ApprovalRejected
+	if r.TimeoutAction == ApprovalTimeoutApprove {
+		decision = ApprovalApproved
+	}
+	return ApprovalDecision{
+		CampaignVersion: r.CampaignVersion,
+		Activation:      r.Activation,
+		Stage:           r.Stage,
+		Namespace:       r.Namespace,
+		Decision:        decision,
+		TimedOut:        true,
+	}
+}
+
+// Outputs returns the stage outputs that record a pending request
+func (r ApprovalRequest) Outputs() map[string]interface{} {
+	outputs := map[string]interface{}{
+		ApprovalOutput:  ApprovalPending,
+		"approverRoles": r.ApproverRoles,
+	}
+	if r.Message != "" {
+		outputs["message"] = r.Message
+	}
+	if r.Deadline != "" {
+		outputs["deadline"] = r.Deadline
+		outputs["timeoutAction"] = r.TimeoutAction
+	}
+	return outputs
+}
+
+// ApprovalRequestFromOutputs reads a pending request back from stage outputs. It returns false
+// if the outputs don't record a pending approval.
+func ApprovalRequestFromOutputs(outputs map[string]interface{}) (ApprovalRequest, bool) {
+	ret := ApprovalRequest{}
+	if v, ok := outputs[ApprovalOutput]; !ok || fmt.Sprint(v) != ApprovalPending {
+		return ret, false
+	}
+	switch roles := outputs["approverRoles"].(type) {
+	case []string:
+		ret.ApproverRoles = roles
+	case []interface{}:
+		for _, role := range roles {
+			ret.ApproverRoles = append(ret.ApproverRoles, fmt.Sprint(role))
+		}
+	}
+	if v, ok := outputs["message"]; ok {
+		ret.Message = fmt.Sprint(v)
+	}
+	if v, ok := outputs["deadline"]; ok {
+		ret.Deadline = fmt.Sprint(v)
+	}
+	if v, ok := outputs["timeoutAction"]; ok {
+		ret.TimeoutAction = fmt.Sprint(v)
+	}
+	return ret, true
+}
@@ -65,12 +65,26 @@ type TaskOption struct {
 	ErrorAction ErrorAction `json:"errorAction,omitempty"`
 }
 
+const (
+	// ForEachItemInput is the task input that carries the item a forEach task was expanded for
+	ForEachItemInput = "__item"
+	// ForEachIndexInput is the task input that carries the index of that item
+	ForEachIndexInput = "__index"
+)
+
 type TaskSpec struct {
 	Name     string                 `json:"name,omitempty"`
 	Provider string                 `json:"provider,omitempty"`
 	Config   interface{}            `json:"config,omitempty"`
 	Inputs   map[string]interface{} `json:"inputs,omitempty"`
 	Target   string                 `json:"target,omitempty"`
+	// Timeout is how long the task may run in duration format (e.g. "5m"); empty means no limit
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// TimeoutDuration returns the task timeout, or 0 if the task has none
+func (t TaskSpec) TimeoutDuration() (time.Duration, error) {
+	return parseTimeout(t.Timeout)
 }
 
 // +kubebuilder:object:generate=true
@@ -82,6 +96,48 @@ type RetrySpec struct {
 	Interval string `json:"interval,omitempty"`
 }
 
+// +kubebuilder:object:generate=true
+type MaintenanceWindowSpec struct {
+	// Schedule is a cron expression for when the window opens (e.g. "0 22 * * sat")
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open in duration format (e.g. "4h")
+	Duration string `json:"duration"`
+	// TimeZone is the IANA time zone the schedule is evaluated in; empty means UTC
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Validate checks that the window has a valid cron schedule and a positive duration
+func (w MaintenanceWindowSpec) Validate() error {
+	if w.Schedule == "" || v1alpha2.IsTimestampSchedule(w.Schedule) {
+		return v1alpha2.NewCOAError(nil, "maintenance window schedule must be a cron expression", v1alpha2.BadConfig)
+	}
+	if err := v1alpha2.ValidateSchedule(w.Schedule, w.TimeZone); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil || duration <= 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid maintenance window duration '%s'", w.Duration), v1alpha2.BadConfig)
+	}
+	return nil
+}
+
+// NextOpen returns t if t falls inside the window, otherwise the time the window opens next
+func (w MaintenanceWindowSpec) NextOpen(t time.Time) (time.Time, error) {
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil || duration <= 0 {
+		return time.Time{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid maintenance window duration '%s'", w.Duration), v1alpha2.BadConfig)
+	}
+	// the first opening after t-duration is either inside [t-duration, t] or the next opening
+	opening, err := v1alpha2.NextScheduleTime(w.Schedule, w.TimeZone, t.Add(-duration))
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !opening.After(t) {
+		return t, nil
+	}
+	return opening, nil
+}
+
 type StageSpec struct {
 	Name          string                 `json:"name,omitempty"`
 	Contexts      string                 `json:"contexts,omitempty"`
@@ -96,6 +152,32 @@ type StageSpec struct {
 	Target        string                 `json:"target,omitempty"`
 	Tasks         []TaskSpec             `json:"tasks,omitempty"`
 	TaskOption    TaskOption             `json:"taskOption,omitempty"`
+
+	// TimeZone is the IANA time zone a cron Schedule is evaluated in; empty means UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// MaintenanceWindows delay the stage until one of the windows is open
+	MaintenanceWindows []MaintenanceWindowSpec `json:"maintenanceWindows,omitempty"`
+	// Timeout is how long the stage, including retries and tasks, may run on a site in duration
+	// format (e.g. "30m"); empty means no limit
+	Timeout string `json:"timeout,omitempty"`
+	// ForEach is an expression that evaluates to a list at runtime. Tasks are run once per item.
+	ForEach string `json:"forEach,omitempty"`
+}
+
+// TimeoutDuration returns the stage timeout, or 0 if the stage has none
+func (s StageSpec) TimeoutDuration() (time.Duration, error) {
+	return parseTimeout(s.Timeout)
+}
+
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(timeout)
+	if err != nil || duration <= 0 {
+		return 0, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid timeout '%s'", timeout), v1alpha2.BadConfig)
+	}
+	return duration, nil
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for StageSpec
@@ -109,22 +191,54 @@ func (s *StageSpec) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	// validate if Schedule meet RFC 3339
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid timestamp format: %v", err), v1alpha2.BadConfig)
+	// validate if Schedule is a RFC 3339 timestamp or a cron expression
+	if err := v1alpha2.ValidateSchedule(s.Schedule, s.TimeZone); err != nil {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid schedule format: %v", err), v1alpha2.BadConfig)
+	}
+	for _, window := range s.MaintenanceWindows {
+		if err := window.Validate(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.TimeoutDuration(); err != nil {
+		return err
+	}
+	for _, task := range s.Tasks {
+		if _, err := task.TimeoutDuration(); err != nil {
+			return err
 		}
 	}
+	if s.ForEach != "" && len(s.Tasks) == 0 {
+		return v1alpha2.NewCOAError(nil, "a forEach stage needs at least one task to run for each item", v1alpha2.BadConfig)
+	}
 	return nil
 }
 
+// ExpandTasks returns the stage's tasks expanded once per item of a forEach list. Each expanded
+// task is named "<task>-<index>" and gets the item and its index as the __item and __index inputs.
+func (s StageSpec) ExpandTasks(items []interface{}) []TaskSpec {
+	ret := make([]TaskSpec, 0, len(items)*len(s.Tasks))
+	for index, item := range items {
+		for _, task := range s.Tasks {
+			inputs := make(map[string]interface{}, len(task.Inputs)+2)
+			for k, v := range task.Inputs {
+				inputs[k] = v
+			}
+			inputs[ForEachItemInput] = item
+			inputs[ForEachIndexInput] = index
+			task.Name = fmt.Sprintf("%s-%d", task.Name, index)
+			task.Inputs = inputs
+			ret = append(ret, task)
+		}
+	}
+	return ret
+}
+
 // MarshalJSON customizes the JSON marshalling for StageSpec
 func (s StageSpec) MarshalJSON() ([]byte, error) {
 	type Alias StageSpec
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid timestamp format: %v", err), v1alpha2.BadConfig)
-		}
+	if err := v1alpha2.ValidateSchedule(s.Schedule, s.TimeZone); err != nil {
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid schedule format: %v", err), v1alpha2.BadConfig)
 	}
 	return json.Marshal(&struct {
 		*Alias
@@ -133,6 +247,47 @@ func (s StageSpec) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// ResolveSchedule returns the RFC 3339 time the stage should be triggered at, or an empty string
+// if it can run right away. A cron schedule resolves to its next activation after now, and
+// maintenance windows push the trigger time out until one of them is open.
+func (s StageSpec) ResolveSchedule(now time.Time) (string, error) {
+	if len(s.MaintenanceWindows) == 0 {
+		if s.Schedule == "" || v1alpha2.IsTimestampSchedule(s.Schedule) {
+			return s.Schedule, nil
+		}
+		next, err := v1alpha2.NextScheduleTime(s.Schedule, s.TimeZone, now)
+		if err != nil {
+			return "", err
+		}
+		return next.UTC().Format(time.RFC3339), nil
+	}
+
+	fireAt := now
+	if s.Schedule != "" {
+		next, err := v1alpha2.NextScheduleTime(s.Schedule, s.TimeZone, now)
+		if err != nil {
+			return "", err
+		}
+		if next.After(now) {
+			fireAt = next
+		}
+	}
+	var open time.Time
+	for _, window := range s.MaintenanceWindows {
+		windowOpen, err := window.NextOpen(fireAt)
+		if err != nil {
+			return "", err
+		}
+		if open.IsZero() || windowOpen.Before(open) {
+			open = windowOpen
+		}
+	}
+	if !open.After(now) {
+		return "", nil
+	}
+	return open.UTC().Format(time.RFC3339), nil
+}
+
 func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 	otherS, ok := other.(StageSpec)
 	if !ok {
@@ -162,6 +317,18 @@ func (s StageSpec) DeepEquals(other IDeepEquals) (bool, error) {
 	if !reflect.DeepEqual(s.Schedule, otherS.Schedule) {
 		return false, nil
 	}
+	if s.TimeZone != otherS.TimeZone {
+		return false, nil
+	}
+	if !reflect.DeepEqual(s.MaintenanceWindows, otherS.MaintenanceWindows) {
+		return false, nil
+	}
+	if s.Timeout != otherS.Timeout {
+		return false, nil
+	}
+	if s.ForEach != otherS.ForEach {
+		return false, nil
+	}
 	if s.Proxy == nil && otherS.Proxy != nil {
 		return false, nil
 	}
@@ -182,6 +349,8 @@ type ActivationStatus struct {
 	Status               v1alpha2.State `json:"status,omitempty"`
 	StatusMessage        string         `json:"statusMessage,omitempty"`
 	StageHistory         []StageStatus  `json:"stageHistory,omitempty"`
+	// PauseRequested is set by the pause API, and holds the activation at its next stage or task
+	PauseRequested bool `json:"pauseRequested,omitempty"`
 }
 type StageStatus struct {
 	Stage         string                 `json:"stage,omitempty"`
@@ -238,6 +407,18 @@ func (c ActivationState) DeepEquals(other IDeepEquals) (bool, error) {
 	return true, nil
 }
 
+// IsFinished returns true when the activation has started and is no longer running or paused on a stage
+func (c ActivationState) IsFinished() bool {
+	if c.Status == nil {
+		return false
+	}
+	switch c.Status.Status {
+	case v1alpha2.None, v1alpha2.Running, v1alpha2.Paused, v1alpha2.Untouched:
+		return false
+	}
+	return true
+}
+
 type CampaignVersionSpec struct {
 	FirstStage   string               `json:"firstStage,omitempty"`
 	Stages       map[string]StageSpec `json:"stages,omitempty"`
@@ -7,8 +7,11 @@
 package model
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -292,3 +295,153 @@ func TestActivationNotMatch(t *testing.T) {
 	assert.Equal(t, err.Error(), "inputs doesn't match")
 	assert.False(t, equal)
 }
+
+func TestStageResolveSchedule(t *testing.T) {
+	now := time.Date(2024, 3, 14, 10, 17, 0, 0, time.UTC) // Thursday
+
+	schedule, err := StageSpec{}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "", schedule)
+
+	// timestamps are passed through unchanged
+	schedule, err = StageSpec{Schedule: "2020-10-31T12:00:00-07:00"}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2020-10-31T12:00:00-07:00", schedule)
+
+	schedule, err = StageSpec{Schedule: "0 2 * * *"}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-15T02:00:00Z", schedule)
+
+	schedule, err = StageSpec{Schedule: "0 2 * * *", TimeZone: "America/New_York"}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-15T06:00:00Z", schedule)
+
+	_, err = StageSpec{Schedule: "0 2 * * *", TimeZone: "Not/AZone"}.ResolveSchedule(now)
+	assert.NotNil(t, err)
+}
+
+func TestStageResolveScheduleWithMaintenanceWindows(t *testing.T) {
+	now := time.Date(2024, 3, 14, 10, 17, 0, 0, time.UTC) // Thursday
+	weekend := MaintenanceWindowSpec{Schedule: "0 22 * * sat", Duration: "6h"}
+	morning := MaintenanceWindowSpec{Schedule: "0 9 * * *", Duration: "2h"}
+
+	// outside of the window the stage waits for the window to open
+	schedule, err := StageSpec{MaintenanceWindows: []MaintenanceWindowSpec{weekend}}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-16T22:00:00Z", schedule)
+
+	// inside the window the stage runs right away
+	schedule, err = StageSpec{MaintenanceWindows: []MaintenanceWindowSpec{weekend, morning}}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "", schedule)
+
+	// a cron schedule that fires outside of the window is pushed to the next opening
+	schedule, err = StageSpec{Schedule: "0 12 * * *", MaintenanceWindows: []MaintenanceWindowSpec{morning}}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-15T09:00:00Z", schedule)
+
+	// a cron schedule that fires inside the window is kept
+	schedule, err = StageSpec{Schedule: "30 9 * * *", MaintenanceWindows: []MaintenanceWindowSpec{morning}}.ResolveSchedule(now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-03-15T09:30:00Z", schedule)
+}
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	assert.Nil(t, MaintenanceWindowSpec{Schedule: "0 22 * * sat", Duration: "6h", TimeZone: "Europe/Berlin"}.Validate())
+	assert.NotNil(t, MaintenanceWindowSpec{Schedule: "2024-01-01T00:00:00Z", Duration: "6h"}.Validate())
+	assert.NotNil(t, MaintenanceWindowSpec{Schedule: "0 22 * * sat", Duration: "0s"}.Validate())
+	assert.NotNil(t, MaintenanceWindowSpec{Schedule: "0 22 * * sat"}.Validate())
+	assert.NotNil(t, MaintenanceWindowSpec{Schedule: "0 22 * * sat", Duration: "6h", TimeZone: "Not/AZone"}.Validate())
+}
+
+func TestStageSpecCronScheduleJSON(t *testing.T) {
+	var stage StageSpec
+	err := json.Unmarshal([]byte(`{"schedule": "0 2 * * 1-5", "timeZone": "Asia/Tokyo"}`), &stage)
+	assert.Nil(t, err)
+	assert.Equal(t, "0 2 * * 1-5", stage.Schedule)
+	assert.Equal(t, "Asia/Tokyo", stage.TimeZone)
+
+	err = json.Unmarshal([]byte(`{"schedule": "nightly"}`), &stage)
+	assert.NotNil(t, err)
+}
+
+func TestStageSpecInvalidMaintenanceWindowJSON(t *testing.T) {
+	var stage StageSpec
+	err := json.Unmarshal([]byte(`{"maintenanceWindows": [{"schedule": "0 22 * * sat", "duration": "forever"}]}`), &stage)
+	assert.NotNil(t, err)
+}
+
+func TestStageSpecTimeoutJSON(t *testing.T) {
+	var stage StageSpec
+	err := json.Unmarshal([]byte(`{"timeout": "30m", "tasks": [{"name": "t1", "timeout": "90s"}]}`), &stage)
+	assert.Nil(t, err)
+	timeout, err := stage.TimeoutDuration()
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Minute, timeout)
+	timeout, err = stage.Tasks[0].TimeoutDuration()
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+
+	timeout, err = StageSpec{}.TimeoutDuration()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), timeout)
+
+	err = json.Unmarshal([]byte(`{"timeout": "0s"}`), &stage)
+	assert.NotNil(t, err)
+	err = json.Unmarshal([]byte(`{"tasks": [{"name": "t1", "timeout": "soon"}]}`), &stage)
+	assert.NotNil(t, err)
+}
+
+func TestStageNotMatchTimeout(t *testing.T) {
+	equal, err := StageSpec{Name: "s", Timeout: "1m"}.DeepEquals(StageSpec{Name: "s", Timeout: "2m"})
+	assert.Nil(t, err)
+	assert.False(t, equal)
+}
+
+func TestStageSpecForEachJSON(t *testing.T) {
+	var stage StageSpec
+	err := json.Unmarshal([]byte(`{"forEach": "${{$output(list, items)}}", "tasks": [{"name": "deploy"}]}`), &stage)
+	assert.Nil(t, err)
+	assert.Equal(t, "${{$output(list, items)}}", stage.ForEach)
+
+	var noTasks StageSpec
+	err = json.Unmarshal([]byte(`{"forEach": "${{$output(list, items)}}"}`), &noTasks)
+	assert.NotNil(t, err)
+}
+
+func TestStageSpecExpandTasks(t *testing.T) {
+	stage := StageSpec{
+		Tasks: []TaskSpec{
+			{Name: "deploy", Inputs: map[string]interface{}{"foo": "bar"}},
+			{Name: "verify"},
+		},
+	}
+	tasks := stage.ExpandTasks([]interface{}{"munich", "tokyo"})
+	assert.Equal(t, 4, len(tasks))
+	assert.Equal(t, "deploy-0", tasks[0].Name)
+	assert.Equal(t, "verify-0", tasks[1].Name)
+	assert.Equal(t, "deploy-1", tasks[2].Name)
+	assert.Equal(t, "tokyo", tasks[2].Inputs[ForEachItemInput])
+	assert.Equal(t, 1, tasks[2].Inputs[ForEachIndexInput])
+	assert.Equal(t, "bar", tasks[2].Inputs["foo"])
+	// the template is left untouched
+	assert.Equal(t, "deploy", stage.Tasks[0].Name)
+	assert.Equal(t, 1, len(stage.Tasks[0].Inputs))
+
+	assert.Empty(t, stage.ExpandTasks([]interface{}{}))
+}
+
+func TestActivationStateIsFinished(t *testing.T) {
+	assert.False(t, ActivationState{}.IsFinished())
+	assert.False(t, ActivationState{Status: &ActivationStatus{}}.IsFinished())
+	for state, finished := range map[v1alpha2.State]bool{
+		v1alpha2.Running:       false,
+		v1alpha2.Paused:        false,
+		v1alpha2.Done:          true,
+		v1alpha2.InternalError: true,
+		v1alpha2.TimedOut:      true,
+		v1alpha2.Cancelled:     true,
+	} {
+		assert.Equal(t, finished, ActivationState{Status: &ActivationStatus{Status: state}}.IsFinished(), state.String())
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// ChildActivationOutput is the stage output that carries the name of the child activation a stage started
+const ChildActivationOutput = "activation"
+
+type (
+	// ChildActivation is a child activation a paused stage is waiting for
+	ChildActivation struct {
+		CampaignVersion      string `json:"campaignversion"`
+		Activation           string `json:"activation"`
+		ActivationGeneration string `json:"activationGeneration"`
+		Stage                string `json:"stage"`
+		Namespace            string `json:"namespace,omitempty"`
+		Child                string `json:"child"`
+	}
+
+	// ChildActivationResult is published when an activation started by another activation finishes
+	ChildActivationResult struct {
+		Activation   string                 `json:"activation"`
+		Namespace    string                 `json:"namespace,omitempty"`
+		Parent       string                 `json:"parent"`
+		Status       v1alpha2.State         `json:"status"`
+		ErrorMessage string                 `json:"errorMessage,omitempty"`
+		Outputs      map[string]interface{} `json:"outputs,omitempty"`
+	}
+)
+
+// NewChildActivationResult returns the result of a finished child activation, with the outputs
+// of its last stage minus the ones for internal tracking use
+func NewChildActivationResult(activation ActivationState) ChildActivationResult {
+	ret := ChildActivationResult{
+		Activation: activation.ObjectMeta.Name,
+		Namespace:  activation.ObjectMeta.Namespace,
+		Parent:     activation.ObjectMeta.Labels[constants.ParentActivation],
+		Outputs:    map[string]interface{}{},
+	}
+	if activation.Status == nil {
+		return ret
+	}
+	ret.Status = activation.Status.Status
+	if len(activation.Status.StageHistory) > 0 {
+		last := activation.Status.StageHistory[len(activation.Status.StageHistory)-1]
+		ret.ErrorMessage = last.ErrorMessage
+		for k, v := range last.Outputs {
+			if !strings.HasPrefix(k, "__") {
+				ret.Outputs[k] = v
+			}
+		}
+	}
+	return ret
+}
@@ -8,6 +8,7 @@ package model
 
 import (
 	"errors"
+	"reflect"
 )
 
 type (
@@ -35,6 +36,42 @@ type (
 		Pipelines   []PipelineSpec    `json:"pipelines,omitempty"`
 		IsDryRun    bool              `json:"isDryRun,omitempty"`
 		ActiveState ActiveState       `json:"activeState,omitempty"`
+
+		// Optional RollbackPolicy to specify whether a failed deployment should be rolled back
+		// to the last successfully deployed state.
+		RollbackPolicy *RollbackPolicySpec `json:"rollbackPolicy,omitempty"`
+
+		// Optional RolloutStrategy to update the selected targets in waves instead of
+		// all at once.
+		RolloutStrategy *RolloutStrategySpec `json:"rolloutStrategy,omitempty"`
+	}
+
+	// RollbackPolicySpec defines how a failed deployment of the instance is rolled back
+	// +kubebuilder:object:generate=true
+	RollbackPolicySpec struct {
+		Enabled bool `json:"enabled"`
+	}
+
+	// RolloutStrategySpec defines how the targets selected by the instance are updated in waves
+	// +kubebuilder:object:generate=true
+	RolloutStrategySpec struct {
+		// Number of targets updated in each wave
+		BatchSize int `json:"batchSize,omitempty"`
+		// Percentage of the selected targets updated in each wave, used when batchSize is not set
+		BatchPercentage int `json:"batchPercentage,omitempty"`
+		// Time to wait after a successful wave before starting the next one, such as "30s"
+		PauseBetweenWaves string `json:"pauseBetweenWaves,omitempty"`
+		// Optional health check the targets of a wave must pass before the next wave starts
+		HealthCheck *RolloutHealthCheckSpec `json:"healthCheck,omitempty"`
+	}
+
+	// RolloutHealthCheckSpec defines the gate between rollout waves
+	// +kubebuilder:object:generate=true
+	RolloutHealthCheckSpec struct {
+		// Requires the target providers to report all components of the wave as deployed
+		ComponentsReady bool `json:"componentsReady,omitempty"`
+		// Status properties that every target of the wave must report
+		StatusProperties map[string]string `json:"statusProperties,omitempty"`
 	}
 
 	// TargertRefSpec defines the target the instance will deploy to
@@ -166,9 +203,55 @@ func (c InstanceSpec) DeepEquals(other IDeepEquals) (bool, error) {
 		return false, nil
 	}
 
+	if c.RollbackPolicy.IsEnabled() != otherC.RollbackPolicy.IsEnabled() {
+		return false, nil
+	}
+
+	if !reflect.DeepEqual(c.RolloutStrategy, otherC.RolloutStrategy) {
+		return false, nil
+	}
+
 	return true, nil
 }
 
+func (c *RollbackPolicySpec) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+// SplitIntoWaves splits the groups of targets into the waves of the rollout. A group is never
+// split, so a wave can have more targets than the batch. Without a batch size or percentage, all
+// targets are updated in a single wave.
+func (c *RolloutStrategySpec) SplitIntoWaves(groups [][]string) [][]string {
+	count := 0
+	for _, group := range groups {
+		count += len(group)
+	}
+	if count == 0 {
+		return [][]string{}
+	}
+	size := count
+	if c != nil {
+		if c.BatchSize > 0 {
+			size = c.BatchSize
+		} else if c.BatchPercentage > 0 && c.BatchPercentage < 100 {
+			size = (count*c.BatchPercentage + 99) / 100
+		}
+	}
+	ret := make([][]string, 0)
+	wave := make([]string, 0)
+	for _, group := range groups {
+		wave = append(wave, group...)
+		if len(wave) >= size {
+			ret = append(ret, wave)
+			wave = make([]string, 0)
+		}
+	}
+	if len(wave) > 0 {
+		ret = append(ret, wave)
+	}
+	return ret
+}
+
 func (c InstanceState) DeepEquals(other IDeepEquals) (bool, error) {
 	otherC, ok := other.(InstanceState)
 	if !ok {
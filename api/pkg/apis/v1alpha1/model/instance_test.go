@@ -518,3 +518,38 @@ func TestTopologySpecDeepEqualsBindingsNotMatch(t *testing.T) {
 	assert.Nil(t, err)
 	assert.False(t, res)
 }
+
+func TestRolloutStrategySplitIntoWaves(t *testing.T) {
+	targets := []string{"t1", "t2", "t3", "t4", "t5"}
+	groups := [][]string{{"t1"}, {"t2"}, {"t3"}, {"t4"}, {"t5"}}
+
+	var strategy *RolloutStrategySpec
+	assert.Equal(t, [][]string{targets}, strategy.SplitIntoWaves(groups))
+
+	strategy = &RolloutStrategySpec{BatchSize: 2}
+	assert.Equal(t, [][]string{{"t1", "t2"}, {"t3", "t4"}, {"t5"}}, strategy.SplitIntoWaves(groups))
+
+	strategy = &RolloutStrategySpec{BatchPercentage: 50}
+	assert.Equal(t, [][]string{{"t1", "t2", "t3"}, {"t4", "t5"}}, strategy.SplitIntoWaves(groups))
+
+	strategy = &RolloutStrategySpec{BatchPercentage: 10}
+	assert.Equal(t, 5, len(strategy.SplitIntoWaves(groups)))
+
+	assert.Equal(t, 0, len(strategy.SplitIntoWaves([][]string{})))
+
+	// targets that have to be deployed together aren't split
+	strategy = &RolloutStrategySpec{BatchSize: 2}
+	assert.Equal(t, [][]string{{"t1", "t2", "t3"}, {"t4", "t5"}}, strategy.SplitIntoWaves([][]string{{"t1"}, {"t2", "t3"}, {"t4"}, {"t5"}}))
+}
+
+func TestInstanceSpecDeepEqualsRolloutStrategyNotMatch(t *testing.T) {
+	instance := InstanceSpec{
+		RolloutStrategy: &RolloutStrategySpec{BatchSize: 1},
+	}
+	other := InstanceSpec{
+		RolloutStrategy: &RolloutStrategySpec{BatchSize: 2},
+	}
+	res, err := instance.DeepEquals(other)
+	assert.Nil(t, err)
+	assert.False(t, res)
+}
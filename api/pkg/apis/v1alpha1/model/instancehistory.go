@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+type (
+	// InstanceHistoryState defines a revision of an instance that has been successfully deployed
+	InstanceHistoryState struct {
+		ObjectMeta ObjectMeta           `json:"metadata,omitempty"`
+		Spec       *InstanceHistorySpec `json:"spec,omitempty"`
+	}
+
+	// InstanceHistorySpec is a snapshot of the instance, solution version and targets of a deployment
+	InstanceHistorySpec struct {
+		RootResource    string               `json:"rootResource"`
+		Revision        int                  `json:"revision"`
+		Time            time.Time            `json:"time"`
+		Hash            string               `json:"hash,omitempty"`
+		Instance        InstanceSpec         `json:"instance"`
+		SolutionVersion SolutionVersionState `json:"solutionversion"`
+		Targets         []TargetState        `json:"targets,omitempty"`
+	}
+)
+
+// ComputeHash returns a hash of the snapshot content, so that identical deployments are
+// not recorded as separate revisions.
+func (c InstanceHistorySpec) ComputeHash() string {
+	data, _ := json.Marshal(struct {
+		Instance        InstanceSpec          `json:"instance"`
+		SolutionVersion *SolutionVersionSpec  `json:"solutionversion"`
+		Targets         map[string]TargetSpec `json:"targets"`
+	}{
+		Instance:        c.Instance,
+		SolutionVersion: c.SolutionVersion.Spec,
+		Targets:         c.targetSpecs(),
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c InstanceHistorySpec) targetSpecs() map[string]TargetSpec {
+	ret := make(map[string]TargetSpec)
+	for _, t := range c.Targets {
+		if t.Spec != nil {
+			ret[t.ObjectMeta.Name] = *t.Spec
+		} else {
+			ret[t.ObjectMeta.Name] = TargetSpec{}
+		}
+	}
+	return ret
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	go_slices "golang.org/x/exp/slices"
 )
 
 type DeploymentPlan struct {
@@ -21,6 +22,8 @@ type DeploymentStep struct {
 	Components []ComponentStep `json:"components"`
 	Role       string          `json:"role"`
 	IsFirst    bool            `json:"isFirst"`
+	// Dependencies are the indexes of the steps in the plan that must complete before this step
+	Dependencies []int `json:"dependencies,omitempty"`
 }
 
 type ComponentAction string
@@ -164,6 +167,142 @@ func (p DeploymentPlan) RevisedForDeletion() DeploymentPlan {
 	}
 	return ret
 }
+
+// WithDependencies returns the plan with the dependencies of each step populated, turning the
+// ordered list of steps into a dependency DAG. A step depends on the earlier steps on the same
+// target, and on the earlier steps that contain components it depends on or that depend on it.
+// Steps without a path between them can be executed concurrently.
+func (p DeploymentPlan) WithDependencies() DeploymentPlan {
+	ret := DeploymentPlan{
+		Steps: make([]DeploymentStep, len(p.Steps)),
+	}
+	for i, s := range p.Steps {
+		s.Dependencies = make([]int, 0)
+		for j := 0; j < i; j++ {
+			if s.dependsOn(p.Steps[j]) {
+				s.Dependencies = append(s.Dependencies, j)
+			}
+		}
+		ret.Steps[i] = s
+	}
+	return ret
+}
+
+// GetTargets returns the names of the targets the plan has steps on, in the order of the steps
+func (p DeploymentPlan) GetTargets() []string {
+	ret := make([]string, 0)
+	for _, s := range p.Steps {
+		if !go_slices.Contains(ret, s.Target) {
+			ret = append(ret, s.Target)
+		}
+	}
+	return ret
+}
+
+// GetTargetGroups returns the targets of the plan in an order they can be deployed in one after
+// another, in groups of targets that have to be deployed together. A target that has components
+// depending on components it doesn't host comes after the targets hosting them, and targets that
+// depend on each other are in the same group. Otherwise the targets keep the order of the steps.
+func (p DeploymentPlan) GetTargetGroups() [][]string {
+	targets := p.GetTargets()
+	hosts := make(map[string][]string)
+	for _, s := range p.Steps {
+		for _, c := range s.Components {
+			if !go_slices.Contains(hosts[c.Component.Name], s.Target) {
+				hosts[c.Component.Name] = append(hosts[c.Component.Name], s.Target)
+			}
+		}
+	}
+	dependencies := make(map[string][]string)
+	for _, s := range p.Steps {
+		for _, c := range s.Components {
+			if c.Action != ComponentUpdate {
+				continue
+			}
+			for _, d := range c.Component.Dependencies {
+				if go_slices.Contains(hosts[d], s.Target) {
+					continue
+				}
+				for _, host := range hosts[d] {
+					if !go_slices.Contains(dependencies[s.Target], host) {
+						dependencies[s.Target] = append(dependencies[s.Target], host)
+					}
+				}
+			}
+		}
+	}
+	// reach holds the targets each target depends on, directly or through other targets
+	reach := make(map[string]map[string]bool)
+	for _, t := range targets {
+		reach[t] = make(map[string]bool)
+		pending := append([]string{}, dependencies[t]...)
+		for len(pending) > 0 {
+			d := pending[0]
+			pending = pending[1:]
+			if reach[t][d] {
+				continue
+			}
+			reach[t][d] = true
+			pending = append(pending, dependencies[d]...)
+		}
+	}
+	ret := make([][]string, 0)
+	placed := make(map[string]bool)
+	for len(placed) < len(targets) {
+		for _, t := range targets {
+			if placed[t] {
+				continue
+			}
+			group := []string{}
+			for _, o := range targets {
+				if o == t || (reach[t][o] && reach[o][t]) {
+					group = append(group, o)
+				}
+			}
+			ready := true
+			for d := range reach[t] {
+				if !placed[d] && !go_slices.Contains(group, d) {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				for _, o := range group {
+					placed[o] = true
+				}
+				ret = append(ret, group)
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// ForTargets returns the steps of the plan on the given targets, with their dependencies recalculated
+func (p DeploymentPlan) ForTargets(targets []string) DeploymentPlan {
+	ret := DeploymentPlan{
+		Steps: make([]DeploymentStep, 0),
+	}
+	for _, s := range p.Steps {
+		if go_slices.Contains(targets, s.Target) {
+			ret.Steps = append(ret.Steps, s)
+		}
+	}
+	return ret.WithDependencies()
+}
+func (s DeploymentStep) dependsOn(other DeploymentStep) bool {
+	if s.Target == other.Target {
+		return true
+	}
+	for _, c := range s.Components {
+		for _, o := range other.Components {
+			if go_slices.Contains(c.Component.Dependencies, o.Component.Name) || go_slices.Contains(o.Component.Dependencies, c.Component.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
 func makeUpdateStep(step DeploymentStep) DeploymentStep {
 	ret := DeploymentStep{
 		Target:     step.Target,
@@ -379,3 +379,87 @@ func TestRevisedForDeletion(t *testing.T) {
 	assert.Equal(t, p.Steps[1].Components[1].Component.Type, "instance")
 	assert.Equal(t, p.Steps[1].Components[1].Component.Properties["file.content"], "hello world")
 }
+
+func TestWithDependencies(t *testing.T) {
+	p := DeploymentPlan{
+		Steps: []DeploymentStep{
+			{
+				Target: "T1",
+				Components: []ComponentStep{
+					{Action: ComponentUpdate, Component: ComponentSpec{Name: "a"}},
+				},
+			},
+			{
+				Target: "T2",
+				Components: []ComponentStep{
+					{Action: ComponentUpdate, Component: ComponentSpec{Name: "a"}},
+				},
+			},
+			{
+				Target: "T3",
+				Components: []ComponentStep{
+					{Action: ComponentUpdate, Component: ComponentSpec{Name: "b", Dependencies: []string{"a"}}},
+				},
+			},
+			{
+				Target: "T1",
+				Components: []ComponentStep{
+					{Action: ComponentUpdate, Component: ComponentSpec{Name: "c"}},
+				},
+			},
+		},
+	}
+	ret := p.WithDependencies()
+	assert.Equal(t, 4, len(ret.Steps))
+	assert.Equal(t, []int{}, ret.Steps[0].Dependencies)
+	assert.Equal(t, []int{}, ret.Steps[1].Dependencies)
+	assert.Equal(t, []int{0, 1}, ret.Steps[2].Dependencies)
+	assert.Equal(t, []int{0}, ret.Steps[3].Dependencies)
+	assert.Nil(t, p.Steps[2].Dependencies)
+}
+
+func TestForTargets(t *testing.T) {
+	p := DeploymentPlan{
+		Steps: []DeploymentStep{
+			{Target: "T2", Components: []ComponentStep{{Action: ComponentUpdate, Component: ComponentSpec{Name: "a"}}}},
+			{Target: "T1", Components: []ComponentStep{{Action: ComponentUpdate, Component: ComponentSpec{Name: "a"}}}},
+			{Target: "T3", Components: []ComponentStep{{Action: ComponentUpdate, Component: ComponentSpec{Name: "a"}}}},
+			{Target: "T1", Components: []ComponentStep{{Action: ComponentUpdate, Component: ComponentSpec{Name: "b", Dependencies: []string{"a"}}}}},
+		},
+	}
+	assert.Equal(t, []string{"T2", "T1", "T3"}, p.GetTargets())
+	ret := p.ForTargets([]string{"T1", "T3"})
+	assert.Equal(t, 3, len(ret.Steps))
+	assert.Equal(t, "T1", ret.Steps[0].Target)
+	assert.Equal(t, "T3", ret.Steps[1].Target)
+	assert.Equal(t, "T1", ret.Steps[2].Target)
+	assert.Equal(t, []int{0, 1}, ret.Steps[2].Dependencies)
+}
+
+func TestGetTargetGroups(t *testing.T) {
+	update := func(target string, name string, dependencies ...string) DeploymentStep {
+		return DeploymentStep{Target: target, Components: []ComponentStep{{Action: ComponentUpdate, Component: ComponentSpec{Name: name, Dependencies: dependencies}}}}
+	}
+	// the same components on every target don't make the targets depend on each other
+	p := DeploymentPlan{
+		Steps: []DeploymentStep{
+			update("T2", "a"),
+			update("T1", "a"),
+			update("T2", "b", "a"),
+			update("T1", "b", "a"),
+		},
+	}
+	assert.Equal(t, [][]string{{"T2"}, {"T1"}}, p.GetTargetGroups())
+
+	// T1 needs the database on T3, and T4 and T5 need each other
+	p = DeploymentPlan{
+		Steps: []DeploymentStep{
+			update("T1", "web", "db"),
+			update("T2", "web", "db"),
+			update("T3", "db"),
+			update("T4", "x", "y"),
+			update("T5", "y", "x"),
+		},
+	}
+	assert.Equal(t, [][]string{{"T3"}, {"T1"}, {"T2"}, {"T4", "T5"}}, p.GetTargetGroups())
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+type (
+	// RecurringActivationState defines a campaign activation that is created on a cron schedule
+	RecurringActivationState struct {
+		ObjectMeta ObjectMeta                 `json:"metadata,omitempty"`
+		Spec       *RecurringActivationSpec   `json:"spec,omitempty"`
+		Status     *RecurringActivationStatus `json:"status,omitempty"`
+	}
+
+	// RecurringActivationSpec is the activation template and the schedule it is created on
+	RecurringActivationSpec struct {
+		CampaignVersion string                 `json:"campaignversion,omitempty"`
+		Stage           string                 `json:"stage,omitempty"`
+		Inputs          map[string]interface{} `json:"inputs,omitempty"`
+		// Schedule is a cron expression, e.g. "0 2 * * *" for every night at 2am
+		Schedule string `json:"schedule"`
+		// TimeZone is the IANA time zone the schedule is evaluated in; empty means UTC
+		TimeZone string `json:"timeZone,omitempty"`
+		// Suspend stops new activations from being created without deleting the object
+		Suspend bool `json:"suspend,omitempty"`
+	}
+
+	// RecurringActivationStatus records the activations created so far
+	RecurringActivationStatus struct {
+		LastScheduleTime string `json:"lastScheduleTime,omitempty"`
+		NextScheduleTime string `json:"nextScheduleTime,omitempty"`
+		LastActivation   string `json:"lastActivation,omitempty"`
+		ActivationCount  int    `json:"activationCount,omitempty"`
+	}
+)
+
+// NextScheduleTime returns the first time the schedule fires after the given time
+func (c RecurringActivationSpec) NextScheduleTime(after time.Time) (time.Time, error) {
+	if c.Schedule == "" || v1alpha2.IsTimestampSchedule(c.Schedule) {
+		return time.Time{}, v1alpha2.NewCOAError(nil, "recurring activation schedule must be a cron expression", v1alpha2.BadConfig)
+	}
+	return v1alpha2.NextScheduleTime(c.Schedule, c.TimeZone, after)
+}
+
+func (c RecurringActivationSpec) DeepEquals(other IDeepEquals) (bool, error) {
+	otherC, ok := other.(RecurringActivationSpec)
+	if !ok {
+		return false, errors.New("parameter is not a RecurringActivationSpec type")
+	}
+	if c.CampaignVersion != otherC.CampaignVersion {
+		return false, nil
+	}
+	if c.Stage != otherC.Stage {
+		return false, nil
+	}
+	if !reflect.DeepEqual(c.Inputs, otherC.Inputs) {
+		return false, nil
+	}
+	if c.Schedule != otherC.Schedule || c.TimeZone != otherC.TimeZone {
+		return false, nil
+	}
+	if c.Suspend != otherC.Suspend {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c RecurringActivationState) DeepEquals(other IDeepEquals) (bool, error) {
+	otherC, ok := other.(RecurringActivationState)
+	if !ok {
+		return false, errors.New("parameter is not a RecurringActivationState type")
+	}
+
+	equal, err := c.ObjectMeta.DeepEquals(otherC.ObjectMeta)
+	if err != nil || !equal {
+		return equal, err
+	}
+
+	if c.Spec == nil || otherC.Spec == nil {
+		return c.Spec == otherC.Spec, nil
+	}
+	return c.Spec.DeepEquals(*otherC.Spec)
+}
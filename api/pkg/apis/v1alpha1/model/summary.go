@@ -38,6 +38,24 @@ type SummarySpec struct {
 	IsRemoval           bool                        `json:"isRemoval"`
 	AllAssignedDeployed bool                        `json:"allAssignedDeployed"`
 	Removed             bool                        `json:"removed"`
+	Rollback            *RollbackResultSpec         `json:"rollback,omitempty"`
+	Rollout             *RolloutResultSpec          `json:"rollout,omitempty"`
+}
+type RollbackResultSpec struct {
+	Status        RollbackStatus              `json:"status"`
+	Message       string                      `json:"message,omitempty"`
+	TargetResults map[string]TargetResultSpec `json:"targets,omitempty"`
+}
+type RolloutResultSpec struct {
+	CurrentWave int               `json:"currentWave"`
+	Waves       []RolloutWaveSpec `json:"waves"`
+	// ResumeAt is when the rollout continues with the next wave while it pauses between waves
+	ResumeAt *time.Time `json:"resumeAt,omitempty"`
+}
+type RolloutWaveSpec struct {
+	Targets []string          `json:"targets"`
+	Status  RolloutWaveStatus `json:"status"`
+	Message string            `json:"message,omitempty"`
 }
 type SummaryResult struct {
 	Summary        SummarySpec  `json:"summary"`
@@ -56,9 +74,34 @@ const (
 
 type SummaryState int
 
+type RollbackStatus string
+
+const (
+	RollbackSucceeded RollbackStatus = "Succeeded" // All touched targets were restored to the last successful deployment state
+	RollbackFailed    RollbackStatus = "Failed"    // At least one target failed to be restored
+	RollbackSkipped   RollbackStatus = "Skipped"   // There was no previous successful deployment state to restore
+)
+
+type RolloutWaveStatus string
+
+const (
+	RolloutWavePending   RolloutWaveStatus = "Pending"   // The wave hasn't started yet
+	RolloutWaveRunning   RolloutWaveStatus = "Running"   // The targets of the wave are being updated
+	RolloutWaveSucceeded RolloutWaveStatus = "Succeeded" // All targets of the wave were updated and passed the health check
+	RolloutWaveFailed    RolloutWaveStatus = "Failed"    // The wave failed to deploy or didn't pass the health check; the rollout stopped
+)
+
 func (s *SummarySpec) UpdateTargetResult(target string, spec TargetResultSpec) {
-	if v, ok := s.TargetResults[target]; !ok {
-		s.TargetResults[target] = spec
+	updateTargetResult(s.TargetResults, target, spec)
+}
+
+func (s *RollbackResultSpec) UpdateTargetResult(target string, spec TargetResultSpec) {
+	updateTargetResult(s.TargetResults, target, spec)
+}
+
+func updateTargetResult(results map[string]TargetResultSpec, target string, spec TargetResultSpec) {
+	if v, ok := results[target]; !ok {
+		results[target] = spec
 	} else {
 		status := v.Status
 		if spec.Status != "OK" {
@@ -74,7 +117,7 @@ func (s *SummarySpec) UpdateTargetResult(target string, spec TargetResultSpec) {
 		v.Status = status
 		v.Message = message
 		maps.Copy(v.ComponentResults, spec.ComponentResults)
-		s.TargetResults[target] = v
+		results[target] = v
 	}
 }
 
@@ -121,5 +164,19 @@ func (s *SummarySpec) GenerateStatusMessage() string {
 		targetErrors = append(targetErrors, targetError)
 	}
 
-	return errorMessage + fmt.Sprintf("Detailed status: %s", strings.Join(targetErrors, ", "))
+	errorMessage += fmt.Sprintf("Detailed status: %s", strings.Join(targetErrors, ", "))
+
+	if s.Rollout != nil && s.Rollout.CurrentWave < len(s.Rollout.Waves) && s.Rollout.Waves[s.Rollout.CurrentWave].Status == RolloutWaveFailed {
+		errorMessage += fmt.Sprintf(". Rollout stopped at wave %d of %d", s.Rollout.CurrentWave+1, len(s.Rollout.Waves))
+		if message := s.Rollout.Waves[s.Rollout.CurrentWave].Message; message != "" {
+			errorMessage += fmt.Sprintf(": %s", message)
+		}
+	}
+	if s.Rollback != nil {
+		errorMessage += fmt.Sprintf(". Rollback %s", strings.ToLower(string(s.Rollback.Status)))
+		if s.Rollback.Message != "" {
+			errorMessage += fmt.Sprintf(": %s", s.Rollback.Message)
+		}
+	}
+	return errorMessage
 }
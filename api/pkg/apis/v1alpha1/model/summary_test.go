@@ -174,6 +174,45 @@ func TestGenerateStatusMessage(t *testing.T) {
 			},
 			expected: `Failed to deploy. Detailed status: target1: "Success" (target1.comp1: OK), target2: "Failed" (target2.comp1: Component error)`,
 		},
+		{
+			name: "Rolled back deployment",
+			summary: SummarySpec{
+				AllAssignedDeployed: false,
+				SummaryMessage:      "",
+				TargetResults: map[string]TargetResultSpec{
+					"target1": {
+						Status:  v1alpha2.InternalError.String(),
+						Message: "Target failed",
+					},
+				},
+				Rollback: &RollbackResultSpec{
+					Status:  RollbackFailed,
+					Message: "failed to restore target target1",
+				},
+			},
+			expected: `Failed to deploy. Detailed status: target1: "Target failed". Rollback failed: failed to restore target target1`,
+		},
+		{
+			name: "Stopped rollout",
+			summary: SummarySpec{
+				AllAssignedDeployed: false,
+				SummaryMessage:      "",
+				TargetResults: map[string]TargetResultSpec{
+					"target1": {
+						Status:  v1alpha2.OK.String(),
+						Message: "",
+					},
+				},
+				Rollout: &RolloutResultSpec{
+					CurrentWave: 0,
+					Waves: []RolloutWaveSpec{
+						{Targets: []string{"target1"}, Status: RolloutWaveFailed, Message: "component comp1 is not ready on target target1"},
+						{Targets: []string{"target2"}, Status: RolloutWavePending},
+					},
+				},
+			},
+			expected: `Failed to deploy. Detailed status: target1: "". Rollout stopped at wave 1 of 2: component comp1 is not ready on target target1`,
+		},
 	}
 
 	for _, tt := range tests {
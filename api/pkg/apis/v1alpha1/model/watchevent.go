@@ -0,0 +1,15 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package model
+
+// WatchEvent is a change to an object, streamed by list endpoints called with watch=true.
+// Type is one of ADDED, MODIFIED and DELETED.
+type WatchEvent struct {
+	Type            string      `json:"type"`
+	Object          interface{} `json:"object"`
+	ResourceVersion string      `json:"resourceVersion,omitempty"`
+}
@@ -0,0 +1,543 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	defaultHealthCheckInterval = 10 // seconds
+	defaultStartTimeout        = 10 // seconds
+)
+
+type PluginProviderConfig struct {
+	Name string `json:"name"`
+	// Path of the plugin executable
+	Path string   `json:"path"`
+	Args []string `json:"args,omitempty"`
+	// Transport is "stdio" (the default) or "unix"
+	Transport           string `json:"transport,omitempty"`
+	HealthCheckInterval int    `json:"healthCheckInterval,omitempty"` // seconds
+	StartTimeout        int    `json:"startTimeout,omitempty"`        // seconds
+	// Config is passed to the Init of the plugin
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// PluginProviderConfigFromMap reads the settings of the plugin provider from the properties.
+// "args" is a JSON array, and the other properties are the config of the plugin.
+func PluginProviderConfigFromMap(properties map[string]string) (PluginProviderConfig, error) {
+	ret := PluginProviderConfig{Config: make(map[string]interface{})}
+	for k, v := range properties {
+		v = utils.ParseProperty(v)
+		switch k {
+		case "name":
+			ret.Name = v
+		case "path":
+			ret.Path = v
+		case "transport":
+			ret.Transport = v
+		case "args":
+			if err := json.Unmarshal([]byte(v), &ret.Args); err != nil {
+				return ret, v1alpha2.NewCOAError(err, "the 'args' setting of plugin provider is not a JSON array of strings", v1alpha2.BadConfig)
+			}
+		case "healthCheckInterval", "startTimeout":
+			iVal, err := strconv.Atoi(v)
+			if err != nil || iVal < 0 {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid int value in the '%s' setting of plugin provider", k), v1alpha2.BadConfig)
+			}
+			if k == "healthCheckInterval" {
+				ret.HealthCheckInterval = iVal
+			} else {
+				ret.StartTimeout = iVal
+			}
+		default:
+			ret.Config[k] = v
+		}
+	}
+	return ret, nil
+}
+
+func toPluginProviderConfig(config providers.IProviderConfig) (PluginProviderConfig, error) {
+	ret := PluginProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// validatePluginProviderConfig reads the config of the plugin provider and sets its defaults
+func validatePluginProviderConfig(config providers.IProviderConfig) (PluginProviderConfig, error) {
+	if properties, ok := config.(map[string]string); ok {
+		var err error
+		if config, err = PluginProviderConfigFromMap(properties); err != nil {
+			return PluginProviderConfig{}, err
+		}
+	}
+	pluginConfig, err := toPluginProviderConfig(config)
+	if err != nil {
+		return pluginConfig, v1alpha2.NewCOAError(err, "invalid plugin provider config", v1alpha2.BadConfig)
+	}
+	if pluginConfig.Path == "" {
+		return pluginConfig, v1alpha2.NewCOAError(nil, "plugin provider path is not set", v1alpha2.MissingConfig)
+	}
+	if pluginConfig.Transport == "" {
+		pluginConfig.Transport = TransportStdio
+	}
+	if pluginConfig.Transport != TransportStdio && pluginConfig.Transport != TransportUnix {
+		return pluginConfig, v1alpha2.NewCOAError(nil, fmt.Sprintf("plugin provider transport '%s' is not supported", pluginConfig.Transport), v1alpha2.BadConfig)
+	}
+	if pluginConfig.HealthCheckInterval == 0 {
+		pluginConfig.HealthCheckInterval = defaultHealthCheckInterval
+	}
+	if pluginConfig.StartTimeout == 0 {
+		pluginConfig.StartTimeout = defaultStartTimeout
+	}
+	return pluginConfig, nil
+}
+
+var (
+	sharedLock sync.Mutex
+	shared     = make(map[string]*PluginProvider)
+)
+
+// SharedPluginProvider returns the running plugin provider of the config, and starts it on the
+// first call. Target and stage providers are created for every step that runs, so they share one
+// plugin process per config instead of starting a new one each time.
+func SharedPluginProvider(config providers.IProviderConfig) (*PluginProvider, error) {
+	pluginConfig, err := validatePluginProviderConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(pluginConfig)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "invalid plugin provider config", v1alpha2.BadConfig)
+	}
+	key := string(data)
+	sharedLock.Lock()
+	defer sharedLock.Unlock()
+	if provider, ok := shared[key]; ok {
+		return provider, nil
+	}
+	provider := &PluginProvider{}
+	if err = provider.Init(pluginConfig); err != nil {
+		return nil, err
+	}
+	shared[key] = provider
+	return provider, nil
+}
+
+// process is a running plugin
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	client *rpc.Client
+	// closed when the process exits
+	exited chan struct{}
+	socket string
+}
+
+func (p *process) kill() {
+	if p.client != nil {
+		p.client.Close()
+	}
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+	<-p.exited
+	if p.socket != "" {
+		os.Remove(p.socket)
+	}
+}
+
+func (p *process) running() bool {
+	select {
+	case <-p.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// PluginProvider is a target and stage provider that runs in a plugin process, so that a provider
+// can be added without building it into Symphony, and a fault in it doesn't take Symphony down.
+// The plugin is started by Init. It's restarted and initialized again if it exits or fails a
+// health check, and when it's called while it isn't running.
+type PluginProvider struct {
+	Config   PluginProviderConfig
+	Context  *contexts.ManagerContext
+	Kinds    []string
+	Restarts int
+	lock     sync.Mutex
+	process  *process
+	cancel   context.CancelFunc
+}
+
+func (p *PluginProvider) SetContext(ctx *contexts.ManagerContext) {
+	p.Context = ctx
+}
+
+func (p *PluginProvider) InitWithMap(properties map[string]string) error {
+	config, err := PluginProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return p.Init(config)
+}
+
+func (p *PluginProvider) Init(config providers.IProviderConfig) error {
+	ctx, span := observability.StartSpan("Plugin Provider", context.TODO(), &map[string]string{
+		"method": "Init",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfoCtx(ctx, "  P (Plugin): Init()")
+
+	pluginConfig, err := validatePluginProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	p.Close()
+	p.Config = pluginConfig
+
+	p.lock.Lock()
+	_, err = p.ensureProcess(ctx)
+	p.lock.Unlock()
+	if err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): failed to start plugin %s: %+v", p.Config.Path, err)
+		return err
+	}
+	supervisorCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.supervise(supervisorCtx)
+	return nil
+}
+
+// Close stops the plugin
+func (p *PluginProvider) Close() {
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.process != nil {
+		p.process.kill()
+		p.process = nil
+	}
+}
+
+// supervise checks the health of the plugin at every interval, and restarts it if it isn't
+// running or isn't healthy
+func (p *PluginProvider) supervise(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(p.Config.HealthCheckInterval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reply := HealthReply{}
+		err := p.call(ctx, "Health", Empty{}, &reply, time.Duration(p.Config.HealthCheckInterval)*time.Second)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil && !reply.Healthy {
+			err = fmt.Errorf("plugin is unhealthy: %s", reply.Message)
+		}
+		if err != nil {
+			log.Errorf("  P (Plugin): health check of plugin %s failed, restarting it: %+v", p.Config.Path, err)
+			p.lock.Lock()
+			if p.process != nil {
+				// ensureProcess replaces it now that it isn't running
+				p.process.kill()
+			}
+			_, err = p.ensureProcess(ctx)
+			p.lock.Unlock()
+			if err != nil {
+				log.Errorf("  P (Plugin): failed to restart plugin %s: %+v", p.Config.Path, err)
+			}
+		}
+	}
+}
+
+// ensureProcess returns the running plugin, and starts it if there's none. The caller holds the
+// lock.
+func (p *PluginProvider) ensureProcess(ctx context.Context) (*process, error) {
+	if p.process != nil && p.process.running() {
+		return p.process, nil
+	}
+	if p.process != nil {
+		p.process.kill()
+		p.process = nil
+		p.Restarts++
+		log.InfofCtx(ctx, "  P (Plugin): restarting plugin %s, restarts: %d", p.Config.Path, p.Restarts)
+	}
+	proc, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.handshake(proc); err != nil {
+		proc.kill()
+		return nil, err
+	}
+	p.process = proc
+	return proc, nil
+}
+
+func (p *PluginProvider) start() (*process, error) {
+	cmd := exec.Command(p.Config.Path, p.Config.Args...)
+	cmd.Env = append(os.Environ(),
+		MagicCookieKey+"="+MagicCookieValue,
+		ProtocolVersionsKey+"="+strconv.Itoa(ProtocolVersion),
+		TransportKey+"="+p.Config.Transport)
+	proc := &process{cmd: cmd, exited: make(chan struct{})}
+	if p.Config.Transport == TransportUnix {
+		proc.socket = filepath.Join(os.TempDir(), "symphony-plugin-"+uuid.New().String()+".sock")
+		cmd.Env = append(cmd.Env, SocketKey+"="+proc.socket)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to start the plugin", v1alpha2.InitFailed)
+	}
+	proc.stdin = stdin
+	var stdout io.ReadCloser
+	if p.Config.Transport == TransportStdio {
+		if stdout, err = cmd.StdoutPipe(); err != nil {
+			return nil, v1alpha2.NewCOAError(err, "failed to start the plugin", v1alpha2.InitFailed)
+		}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to start the plugin", v1alpha2.InitFailed)
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to start plugin %s", p.Config.Path), v1alpha2.InitFailed)
+	}
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			log.Infof("  P (Plugin) %s: %s", filepath.Base(p.Config.Path), scanner.Text())
+		}
+		cmd.Wait()
+		close(proc.exited)
+	}()
+
+	var conn io.ReadWriteCloser
+	if p.Config.Transport == TransportStdio {
+		conn = pipeConn{ReadCloser: stdout, WriteCloser: stdin}
+	} else {
+		conn, err = p.dial(proc)
+		if err != nil {
+			proc.kill()
+			return nil, err
+		}
+	}
+	proc.client = jsonrpc.NewClient(conn)
+	return proc, nil
+}
+
+// dial connects to the socket of the plugin once it listens on it
+func (p *PluginProvider) dial(proc *process) (net.Conn, error) {
+	deadline := time.Now().Add(time.Duration(p.Config.StartTimeout) * time.Second)
+	for {
+		conn, err := net.Dial("unix", proc.socket)
+		if err == nil {
+			return conn, nil
+		}
+		if !proc.running() {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("plugin %s exited before it listened on its socket", p.Config.Path), v1alpha2.InitFailed)
+		}
+		if time.Now().After(deadline) {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("plugin %s didn't listen on its socket in time", p.Config.Path), v1alpha2.InitFailed)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// handshake agrees on the protocol version with a new plugin and initializes its providers
+func (p *PluginProvider) handshake(proc *process) error {
+	timeout := time.Duration(p.Config.StartTimeout) * time.Second
+	reply := HandshakeReply{}
+	if err := callProcess(context.Background(), proc, "Handshake", HandshakeArgs{ProtocolVersions: []int{ProtocolVersion}}, &reply, timeout); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("handshake with plugin %s failed", p.Config.Path), v1alpha2.InitFailed)
+	}
+	if reply.ProtocolVersion != ProtocolVersion {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("plugin %s speaks protocol version %d instead of %d", p.Config.Path, reply.ProtocolVersion, ProtocolVersion), v1alpha2.InitFailed)
+	}
+	p.Kinds = reply.Kinds
+	initReply := InitReply{}
+	if err := callProcess(context.Background(), proc, "Init", InitArgs{Config: p.Config.Config}, &initReply, timeout); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to initialize plugin %s", p.Config.Path), v1alpha2.InitFailed)
+	}
+	return initReply.Error.toCOAError()
+}
+
+// call calls the plugin, and starts it first if it isn't running. A timeout of 0 waits until ctx
+// is done.
+func (p *PluginProvider) call(ctx context.Context, method string, args interface{}, reply interface{}, timeout time.Duration) error {
+	p.lock.Lock()
+	proc, err := p.ensureProcess(ctx)
+	p.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return callProcess(ctx, proc, method, args, reply, timeout)
+}
+
+func callProcess(ctx context.Context, proc *process, method string, args interface{}, reply interface{}, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	call := proc.client.Go(serviceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error == nil {
+			return nil
+		}
+		if _, ok := call.Error.(rpc.ServerError); ok {
+			return v1alpha2.NewCOAError(nil, call.Error.Error(), v1alpha2.BadRequest)
+		}
+		// the connection is broken, so the plugin is started again on the next call
+		proc.kill()
+		return v1alpha2.NewCOAError(call.Error, fmt.Sprintf("plugin stopped during %s", method), v1alpha2.InternalError)
+	case <-proc.exited:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("plugin exited during %s", method), v1alpha2.InternalError)
+	case <-ctx.Done():
+		return v1alpha2.NewCOAError(ctx.Err(), fmt.Sprintf("plugin didn't complete %s in time", method), v1alpha2.InternalError)
+	}
+}
+
+func (p *PluginProvider) serves(kind string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, k := range p.Kinds {
+		if k == kind {
+			return nil
+		}
+	}
+	return v1alpha2.NewCOAError(nil, fmt.Sprintf("plugin %s doesn't serve a %s provider", p.Config.Path, kind), v1alpha2.BadConfig)
+}
+
+func (p *PluginProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	reply := ValidationRuleReply{}
+	if err := p.serves(KindTarget); err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): %+v", err)
+		return reply.Rule
+	}
+	if err := p.call(ctx, "GetValidationRule", Empty{}, &reply, 0); err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): failed to get the validation rule of plugin %s: %+v", p.Config.Path, err)
+	}
+	return reply.Rule
+}
+
+func (p *PluginProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ctx, span := observability.StartSpan("Plugin Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, "  P (Plugin): getting artifacts from plugin %s", p.Config.Path)
+
+	if err = p.serves(KindTarget); err != nil {
+		return nil, err
+	}
+	reply := GetReply{}
+	if err = p.call(ctx, "Get", GetArgs{Deployment: deployment, References: references}, &reply, 0); err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): failed to call Get of plugin %s: %+v", p.Config.Path, err)
+		return nil, err
+	}
+	err = reply.Error.toCOAError()
+	return reply.Components, err
+}
+
+func (p *PluginProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ctx, span := observability.StartSpan("Plugin Provider", ctx, &map[string]string{
+		"method": "Apply",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, "  P (Plugin): applying artifacts with plugin %s", p.Config.Path)
+
+	if err = p.serves(KindTarget); err != nil {
+		return nil, err
+	}
+	reply := ApplyReply{}
+	if err = p.call(ctx, "Apply", ApplyArgs{Deployment: deployment, Step: step, IsDryRun: isDryRun}, &reply, 0); err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): failed to call Apply of plugin %s: %+v", p.Config.Path, err)
+		return nil, err
+	}
+	err = reply.Error.toCOAError()
+	return reply.Results, err
+}
+
+// Process runs a stage in the plugin. Plugins get the inputs, but not the manager context.
+func (p *PluginProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("Plugin Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	log.InfofCtx(ctx, "  P (Plugin): processing stage with plugin %s", p.Config.Path)
+
+	if err = p.serves(KindStage); err != nil {
+		return nil, false, err
+	}
+	reply := ProcessReply{}
+	if err = p.call(ctx, "Process", ProcessArgs{Inputs: inputs}, &reply, 0); err != nil {
+		log.ErrorfCtx(ctx, "  P (Plugin): failed to call Process of plugin %s: %+v", p.Config.Path, err)
+		return nil, false, err
+	}
+	err = reply.Error.toCOAError()
+	return reply.Outputs, reply.Paused, err
+}
+
+// pipeConn is the connection to a plugin over its stdout and stdin
+type pipeConn struct {
+	io.ReadCloser
+	io.WriteCloser
+}
+
+func (c pipeConn) Close() error {
+	c.WriteCloser.Close()
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/conformance"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/stretchr/testify/assert"
+)
+
+// testPluginKey makes the test binary run as a plugin
+const testPluginKey = "SYMPHONY_TEST_PLUGIN"
+
+// testProvider is the target and stage provider of the test plugin. Components named "crash"
+// make the plugin exit, and components named "bad" fail with a bad request.
+type testProvider struct {
+	config map[string]interface{}
+}
+
+func (t *testProvider) Init(config providers.IProviderConfig) error {
+	data, _ := json.Marshal(config)
+	return json.Unmarshal(data, &t.config)
+}
+func (t *testProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{RequiredComponentType: "test", AllowSidecar: true}
+}
+func (t *testProvider) Get(ctx context.Context, deployment model.DeploymentSpec, references []model.ComponentStep) ([]model.ComponentSpec, error) {
+	ret := make([]model.ComponentSpec, 0)
+	for _, r := range references {
+		ret = append(ret, model.ComponentSpec{Name: r.Component.Name, Properties: map[string]interface{}{"greeting": t.config["greeting"]}})
+	}
+	return ret, nil
+}
+func (t *testProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	ret := make(map[string]model.ComponentResultSpec)
+	for _, c := range step.Components {
+		switch c.Component.Name {
+		case "crash":
+			os.Exit(1)
+		case "bad":
+			return nil, v1alpha2.NewCOAError(nil, "component is bad", v1alpha2.BadRequest)
+		}
+		ret[c.Component.Name] = model.ComponentResultSpec{Status: v1alpha2.Updated, Message: fmt.Sprintf("dry run: %t", isDryRun)}
+	}
+	return ret, nil
+}
+func (t *testProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	return map[string]interface{}{"echo": inputs["message"]}, true, nil
+}
+
+// conformanceProvider is the target provider of the test plugin that the conformance suite runs
+// against. It requires a property and a metadata of its components.
+type conformanceProvider struct {
+	testProvider
+}
+
+func (c *conformanceProvider) GetValidationRule(ctx context.Context) model.ValidationRule {
+	return model.ValidationRule{
+		ComponentValidationRule: model.ComponentValidationRule{
+			RequiredProperties: []string{"greeting", "audience"},
+			RequiredMetadata:   []string{"owner"},
+		},
+	}
+}
+func (c *conformanceProvider) Apply(ctx context.Context, deployment model.DeploymentSpec, step model.DeploymentStep, isDryRun bool) (map[string]model.ComponentResultSpec, error) {
+	if err := c.GetValidationRule(ctx).Validate(deployment.GetComponentSlice()); err != nil {
+		return nil, err
+	}
+	return c.testProvider.Apply(ctx, deployment, step, isDryRun)
+}
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testPluginKey) {
+	case "":
+		os.Exit(m.Run())
+	case "target":
+		err := Serve(ServeConfig{Name: "test", TargetProvider: &testProvider{}})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "both":
+		provider := &testProvider{}
+		err := Serve(ServeConfig{Name: "test", TargetProvider: provider, StageProvider: provider})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "conformance":
+		err := Serve(ServeConfig{Name: "test", TargetProvider: &conformanceProvider{}})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "future":
+		serve(ServeConfig{Name: "test", TargetProvider: &testProvider{}}, ProtocolVersion+1)
+	}
+	os.Exit(0)
+}
+
+// startPlugin runs the test binary as a plugin of the given kind
+func startPlugin(t *testing.T, kind string, transport string) *PluginProvider {
+	t.Setenv(testPluginKey, kind)
+	provider := &PluginProvider{}
+	err := provider.Init(PluginProviderConfig{
+		Path:      os.Args[0],
+		Transport: transport,
+		Config:    map[string]interface{}{"greeting": "hello"},
+	})
+	assert.Nil(t, err)
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+func testDeployment(names ...string) (model.DeploymentSpec, model.DeploymentStep) {
+	step := model.DeploymentStep{}
+	for _, name := range names {
+		step.Components = append(step.Components, model.ComponentStep{Action: model.ComponentUpdate, Component: model.ComponentSpec{Name: name}})
+	}
+	return model.DeploymentSpec{}, step
+}
+
+func TestPluginProviderConfigFromMap(t *testing.T) {
+	config, err := PluginProviderConfigFromMap(map[string]string{
+		"name":                "test",
+		"path":                "/plugins/test",
+		"args":                "[\"--verbose\"]",
+		"healthCheckInterval": "5",
+		"greeting":            "hello",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"--verbose"}, config.Args)
+	assert.Equal(t, 5, config.HealthCheckInterval)
+	assert.Equal(t, map[string]interface{}{"greeting": "hello"}, config.Config)
+
+	_, err = PluginProviderConfigFromMap(map[string]string{"args": "--verbose"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+
+	provider := PluginProvider{}
+	err = provider.InitWithMap(map[string]string{"name": "test"})
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+}
+
+func TestPluginTargetProvider(t *testing.T) {
+	for _, transport := range []string{TransportStdio, TransportUnix} {
+		t.Run(transport, func(t *testing.T) {
+			provider := startPlugin(t, "target", transport)
+			assert.Equal(t, []string{KindTarget}, provider.Kinds)
+
+			rule := provider.GetValidationRule(context.Background())
+			assert.Equal(t, "test", rule.RequiredComponentType)
+			assert.True(t, rule.AllowSidecar)
+
+			deployment, step := testDeployment("c1")
+			components, err := provider.Get(context.Background(), deployment, step.Components)
+			assert.Nil(t, err)
+			assert.Equal(t, "hello", components[0].Properties["greeting"])
+
+			results, err := provider.Apply(context.Background(), deployment, step, true)
+			assert.Nil(t, err)
+			assert.Equal(t, model.ComponentResultSpec{Status: v1alpha2.Updated, Message: "dry run: true"}, results["c1"])
+
+			deployment, step = testDeployment("bad")
+			_, err = provider.Apply(context.Background(), deployment, step, false)
+			assert.Equal(t, v1alpha2.BadRequest, err.(v1alpha2.COAError).State)
+			assert.Equal(t, "component is bad", err.(v1alpha2.COAError).Message)
+
+			// the plugin doesn't serve stages
+			_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, nil)
+			assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+		})
+	}
+}
+
+func TestPluginConformance(t *testing.T) {
+	for _, transport := range []string{TransportStdio, TransportUnix} {
+		t.Run(transport, func(t *testing.T) {
+			provider := startPlugin(t, "conformance", transport)
+			conformance.ConformanceSuite(t, provider)
+		})
+	}
+}
+
+func TestPluginStageProvider(t *testing.T) {
+	provider := startPlugin(t, "both", TransportStdio)
+	assert.Equal(t, []string{KindTarget, KindStage}, provider.Kinds)
+	outputs, paused, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{"message": "hi"})
+	assert.Nil(t, err)
+	assert.True(t, paused)
+	assert.Equal(t, "hi", outputs["echo"])
+}
+
+func TestPluginHandshakeVersion(t *testing.T) {
+	t.Setenv(testPluginKey, "future")
+	provider := &PluginProvider{}
+	err := provider.Init(PluginProviderConfig{Path: os.Args[0]})
+	assert.Equal(t, v1alpha2.InitFailed, err.(v1alpha2.COAError).State)
+}
+
+func TestPluginRestartsAfterCrash(t *testing.T) {
+	provider := startPlugin(t, "target", TransportStdio)
+
+	deployment, step := testDeployment("crash")
+	_, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Equal(t, v1alpha2.InternalError, err.(v1alpha2.COAError).State)
+
+	// the next call starts the plugin again
+	deployment, step = testDeployment("c1")
+	results, err := provider.Apply(context.Background(), deployment, step, false)
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Updated, results["c1"].Status)
+	assert.Equal(t, 1, provider.Restarts)
+}
+
+func TestPluginHealthCheckRestarts(t *testing.T) {
+	t.Setenv(testPluginKey, "target")
+	provider := &PluginProvider{}
+	err := provider.Init(PluginProviderConfig{Path: os.Args[0], HealthCheckInterval: 1})
+	assert.Nil(t, err)
+	defer provider.Close()
+
+	provider.lock.Lock()
+	provider.process.cmd.Process.Kill()
+	provider.lock.Unlock()
+	assert.Eventually(t, func() bool {
+		provider.lock.Lock()
+		defer provider.lock.Unlock()
+		return provider.Restarts == 1 && provider.process != nil && provider.process.running()
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestSharedPluginProvider(t *testing.T) {
+	t.Setenv(testPluginKey, "target")
+	t.Cleanup(func() {
+		sharedLock.Lock()
+		defer sharedLock.Unlock()
+		for key, provider := range shared {
+			provider.Close()
+			delete(shared, key)
+		}
+	})
+
+	config := map[string]string{"path": os.Args[0], "greeting": "hello"}
+	provider, err := SharedPluginProvider(config)
+	assert.Nil(t, err)
+	pid := provider.process.cmd.Process.Pid
+
+	// every step that runs gets the provider again, which doesn't start more plugins
+	deployment, step := testDeployment("c1")
+	for i := 0; i < 3; i++ {
+		again, err := SharedPluginProvider(config)
+		assert.Nil(t, err)
+		assert.Same(t, provider, again)
+		_, err = again.Apply(context.Background(), deployment, step, false)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, pid, provider.process.cmd.Process.Pid)
+	assert.Equal(t, 0, provider.Restarts)
+	assert.Equal(t, 1, len(shared))
+
+	other, err := SharedPluginProvider(map[string]string{"path": os.Args[0], "greeting": "hi"})
+	assert.Nil(t, err)
+	assert.NotSame(t, provider, other)
+	assert.Equal(t, 2, len(shared))
+
+	_, err = SharedPluginProvider(map[string]string{"greeting": "hello"})
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"errors"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// The plugin protocol is JSON-RPC 1.0, as implemented by net/rpc/jsonrpc, over the stdin and
+// stdout of the plugin process or over a unix socket that the plugin listens on. The methods
+// belong to the "Plugin" service. Provider errors are returned in the Error field of replies,
+// so they keep their state, and RPC errors only report failures of the protocol itself.
+const (
+	// ProtocolVersion is the version of the protocol in this package. It changes only when
+	// the methods or their messages change in a way older plugins or hosts can't handle.
+	ProtocolVersion = 1
+
+	// MagicCookieKey and MagicCookieValue are set in the environment of plugins, so that a
+	// plugin can tell that it's launched by Symphony and not run by hand
+	MagicCookieKey   = "SYMPHONY_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "b3e1c5a2-symphony-provider-plugin"
+	// ProtocolVersionsKey has the comma-separated protocol versions the host supports
+	ProtocolVersionsKey = "SYMPHONY_PLUGIN_PROTOCOL_VERSIONS"
+	// TransportKey is "stdio" or "unix"
+	TransportKey = "SYMPHONY_PLUGIN_TRANSPORT"
+	// SocketKey is the path of the unix socket that the plugin listens on
+	SocketKey = "SYMPHONY_PLUGIN_SOCKET"
+
+	TransportStdio = "stdio"
+	TransportUnix  = "unix"
+
+	// KindTarget and KindStage are the provider interfaces a plugin can serve
+	KindTarget = "target"
+	KindStage  = "stage"
+
+	serviceName = "Plugin"
+)
+
+// Error is a provider error. State is the v1alpha2.State of the error.
+type Error struct {
+	State   v1alpha2.State `json:"state"`
+	Message string         `json:"message"`
+}
+
+func toError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var coaErr v1alpha2.COAError
+	if errors.As(err, &coaErr) {
+		message := coaErr.Message
+		if message == "" && coaErr.InnerError != nil {
+			message = coaErr.InnerError.Error()
+		}
+		return &Error{State: coaErr.State, Message: message}
+	}
+	return &Error{State: v1alpha2.InternalError, Message: err.Error()}
+}
+
+func (e *Error) toCOAError() error {
+	if e == nil {
+		return nil
+	}
+	return v1alpha2.NewCOAError(nil, e.Message, e.State)
+}
+
+type Empty struct{}
+
+// HandshakeArgs has the protocol versions that the host supports
+type HandshakeArgs struct {
+	ProtocolVersions []int `json:"protocolVersions"`
+}
+
+// HandshakeReply has the protocol version that the plugin chose and the provider interfaces
+// that it serves
+type HandshakeReply struct {
+	ProtocolVersion int      `json:"protocolVersion"`
+	Name            string   `json:"name,omitempty"`
+	Kinds           []string `json:"kinds"`
+}
+
+type HealthReply struct {
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+// InitArgs has the "config" of the provider configuration, which is for the plugin to define
+type InitArgs struct {
+	Config map[string]interface{} `json:"config"`
+}
+
+type InitReply struct {
+	Error *Error `json:"error,omitempty"`
+}
+
+type ValidationRuleReply struct {
+	Rule model.ValidationRule `json:"rule"`
+}
+
+type GetArgs struct {
+	Deployment model.DeploymentSpec  `json:"deployment"`
+	References []model.ComponentStep `json:"references"`
+}
+
+type GetReply struct {
+	Components []model.ComponentSpec `json:"components"`
+	Error      *Error                `json:"error,omitempty"`
+}
+
+type ApplyArgs struct {
+	Deployment model.DeploymentSpec `json:"deployment"`
+	Step       model.DeploymentStep `json:"step"`
+	IsDryRun   bool                 `json:"isDryRun"`
+}
+
+type ApplyReply struct {
+	Results map[string]model.ComponentResultSpec `json:"results"`
+	Error   *Error                               `json:"error,omitempty"`
+}
+
+type ProcessArgs struct {
+	Inputs map[string]interface{} `json:"inputs"`
+}
+
+type ProcessReply struct {
+	Outputs map[string]interface{} `json:"outputs"`
+	Paused  bool                   `json:"paused"`
+	Error   *Error                 `json:"error,omitempty"`
+}
@@ -0,0 +1,193 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+)
+
+// ServeConfig has the providers that a plugin serves. At least one of them must be set.
+type ServeConfig struct {
+	// Name of the plugin, reported to the host in the handshake
+	Name           string
+	TargetProvider target.ITargetProvider
+	StageProvider  stage.IStageProvider
+}
+
+// Serve runs a plugin until the host closes its stdin. It's the main function of a plugin
+// executable. When the host talks to the plugin over stdio, what the plugin writes to stdout
+// goes to stderr instead, which the host logs. Init is called with the "config" of the
+// provider configuration of the host, and stage providers get an empty manager context.
+func Serve(config ServeConfig) error {
+	return serve(config, ProtocolVersion)
+}
+
+func serve(config ServeConfig, version int) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("this is a Symphony provider plugin, which is launched by Symphony")
+	}
+	if config.TargetProvider == nil && config.StageProvider == nil {
+		return errors.New("the plugin doesn't serve any provider")
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName(serviceName, &pluginServer{config: config, version: version}); err != nil {
+		return err
+	}
+	switch os.Getenv(TransportKey) {
+	case TransportStdio, "":
+		stdout, err := takeStdout()
+		if err != nil {
+			return err
+		}
+		server.ServeCodec(jsonrpc.NewServerCodec(stdio{stdout: stdout}))
+		return nil
+	case TransportUnix:
+		listener, err := net.Listen("unix", os.Getenv(SocketKey))
+		if err != nil {
+			return err
+		}
+		defer listener.Close()
+		// the plugin stops with its host, which holds its stdin
+		go func() {
+			io.Copy(io.Discard, os.Stdin)
+			listener.Close()
+		}()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return nil
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	default:
+		return fmt.Errorf("transport '%s' is not supported", os.Getenv(TransportKey))
+	}
+}
+
+// stdio is the connection to the host over stdin and the original stdout
+type stdio struct {
+	stdout *os.File
+}
+
+func (s stdio) Read(p []byte) (int, error) {
+	return os.Stdin.Read(p)
+}
+func (s stdio) Write(p []byte) (int, error) {
+	return s.stdout.Write(p)
+}
+func (s stdio) Close() error {
+	return s.stdout.Close()
+}
+
+// pluginServer is the "Plugin" service of a plugin
+type pluginServer struct {
+	config  ServeConfig
+	version int
+}
+
+func (s *pluginServer) Handshake(args HandshakeArgs, reply *HandshakeReply) error {
+	supported := false
+	for _, v := range args.ProtocolVersions {
+		if v == s.version {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("the plugin speaks protocol version %d, but the host only speaks %v", s.version, args.ProtocolVersions)
+	}
+	reply.ProtocolVersion = s.version
+	reply.Name = s.config.Name
+	reply.Kinds = make([]string, 0)
+	if s.config.TargetProvider != nil {
+		reply.Kinds = append(reply.Kinds, KindTarget)
+	}
+	if s.config.StageProvider != nil {
+		reply.Kinds = append(reply.Kinds, KindStage)
+	}
+	return nil
+}
+
+func (s *pluginServer) Health(args Empty, reply *HealthReply) error {
+	reply.Healthy = true
+	return nil
+}
+
+func (s *pluginServer) Init(args InitArgs, reply *InitReply) error {
+	if args.Config == nil {
+		args.Config = make(map[string]interface{})
+	}
+	if s.config.TargetProvider != nil {
+		reply.Error = toError(s.config.TargetProvider.Init(args.Config))
+	}
+	if reply.Error == nil && s.config.StageProvider != nil && !sameProvider(s.config) {
+		if p, ok := s.config.StageProvider.(interface {
+			Init(config providers.IProviderConfig) error
+		}); ok {
+			reply.Error = toError(p.Init(args.Config))
+		}
+	}
+	return nil
+}
+
+func (s *pluginServer) GetValidationRule(args Empty, reply *ValidationRuleReply) error {
+	if s.config.TargetProvider == nil {
+		return errors.New("the plugin doesn't serve a target provider")
+	}
+	reply.Rule = s.config.TargetProvider.GetValidationRule(context.Background())
+	return nil
+}
+
+func (s *pluginServer) Get(args GetArgs, reply *GetReply) error {
+	if s.config.TargetProvider == nil {
+		return errors.New("the plugin doesn't serve a target provider")
+	}
+	components, err := s.config.TargetProvider.Get(context.Background(), args.Deployment, args.References)
+	reply.Components = components
+	reply.Error = toError(err)
+	return nil
+}
+
+func (s *pluginServer) Apply(args ApplyArgs, reply *ApplyReply) error {
+	if s.config.TargetProvider == nil {
+		return errors.New("the plugin doesn't serve a target provider")
+	}
+	results, err := s.config.TargetProvider.Apply(context.Background(), args.Deployment, args.Step, args.IsDryRun)
+	reply.Results = results
+	reply.Error = toError(err)
+	return nil
+}
+
+func (s *pluginServer) Process(args ProcessArgs, reply *ProcessReply) error {
+	if s.config.StageProvider == nil {
+		return errors.New("the plugin doesn't serve a stage provider")
+	}
+	outputs, paused, err := s.config.StageProvider.Process(context.Background(), contexts.ManagerContext{}, args.Inputs)
+	reply.Outputs = outputs
+	reply.Paused = paused
+	reply.Error = toError(err)
+	return nil
+}
+
+// sameProvider tells if the target provider is also the stage provider, so it's initialized once
+func sameProvider(config ServeConfig) bool {
+	if t, ok := config.StageProvider.(target.ITargetProvider); ok {
+		return t == config.TargetProvider
+	}
+	return false
+}
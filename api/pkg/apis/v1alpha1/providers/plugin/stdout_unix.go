@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// takeStdout returns a file for the stdout of the process, and points the stdout file descriptor
+// at stderr, so that the output of loggers and of the providers doesn't get into the protocol
+func takeStdout() (*os.File, error) {
+	fd, err := unix.Dup(int(os.Stdout.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	if err = unix.Dup2(int(os.Stderr.Fd()), int(os.Stdout.Fd())); err != nil {
+		unix.Close(fd)
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), "plugin-stdout"), nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package plugin
+
+import (
+	"os"
+)
+
+// takeStdout returns the stdout of the process. The stdout handle can't be pointed at stderr
+// for files that are already open on it, so plugins on Windows must not write to stdout when
+// they're served over stdio.
+func takeStdout() (*os.File, error) {
+	return os.Stdout, nil
+}
@@ -8,11 +8,14 @@ package providers
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	catalogversionconfig "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/config/catalogversion"
 	memorygraph "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/graph/memory"
+	approvalstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/approval"
+	campaignstage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/campaign"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/counter"
 	symphonystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/create"
 	delaystage "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage/delay"
@@ -40,10 +43,13 @@ import (
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/staging"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/win10/sideload"
 	mockconfig "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/mock"
+	rediskeylock "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/keylock/redis"
+	fileledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/file"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/probe/rtsp"
 	mempubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	memoryqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/memory"
+	redisqueue "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/queue/redis"
 	cvref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/customvision"
 	httpref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/http"
 	k8sref "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reference/k8s"
@@ -51,6 +57,7 @@ import (
 	k8sreporter "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/reporter/k8s"
 	mocksecret "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/secret/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/httpstate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/embeddedstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/redisstate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/uploader/azure/blob"
@@ -66,6 +73,10 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*memorystate.MemoryStateProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.state.embedded", embeddedstate.EmbeddedStateProviderConfig{Path: filepath.Join(t.TempDir(), "state.db")})
+	assert.Nil(t, err)
+	assert.NotNil(t, *provider.(*embeddedstate.EmbeddedStateProvider))
+
 	if getTestMiniKubeEnabled == "" {
 		t.Log("Skipping providers.state.k8s test as TEST_MINIKUBE_ENABLED is not set")
 	} else {
@@ -80,6 +91,14 @@ func TestCreateProvider(t *testing.T) {
 		provider, err = providerfactory.CreateProvider("providers.state.redis", redisstate.RedisStateProviderConfig{Host: "localhost:6379"})
 		assert.Nil(t, err)
 		assert.NotNil(t, *provider.(*redisstate.RedisStateProvider))
+
+		provider, err = providerfactory.CreateProvider("providers.keylock.redis", rediskeylock.RedisKeyLockProviderConfig{Host: "localhost:6379"})
+		assert.Nil(t, err)
+		assert.NotNil(t, provider.(*rediskeylock.RedisKeyLockProvider))
+
+		provider, err = providerfactory.CreateProvider("providers.queue.redis", redisqueue.RedisQueueProviderConfig{Host: "localhost:6379"})
+		assert.Nil(t, err)
+		assert.NotNil(t, provider.(*redisqueue.RedisQueueProvider))
 	}
 
 	if getTestMiniKubeEnabled == "" {
@@ -134,6 +153,10 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*mockledger.MockLedgerProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.ledger.file", fileledger.FileLedgerProviderConfig{Folder: t.TempDir()})
+	assert.Nil(t, err)
+	assert.NotNil(t, provider.(*fileledger.FileLedgerProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.stage.counter", counter.CounterStageProviderConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*counter.CounterStageProvider))
@@ -224,7 +247,7 @@ func TestCreateProvider(t *testing.T) {
 
 	provider, err = providerfactory.CreateProvider("providers.pubsub.memory", mempubsub.InMemoryPubSubConfig{})
 	assert.Nil(t, err)
-	assert.NotNil(t, *provider.(*mempubsub.InMemoryPubSubProvider))
+	assert.NotNil(t, provider.(*mempubsub.InMemoryPubSubProvider))
 
 	provider, err = providerfactory.CreateProvider("providers.stage.mock", mockstage.MockStageProviderConfig{})
 	assert.Nil(t, err)
@@ -262,6 +285,14 @@ func TestCreateProvider(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*delaystage.DelayStageProvider))
 
+	provider, err = providerfactory.CreateProvider("providers.stage.approval", approvalstage.ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, *provider.(*approvalstage.ApprovalStageProvider))
+
+	provider, err = providerfactory.CreateProvider("providers.stage.campaign", campaignstage.CampaignStageProviderConfig{})
+	assert.Nil(t, err)
+	assert.NotNil(t, *provider.(*campaignstage.CampaignStageProvider))
+
 	provider, err = providerfactory.CreateProvider("providers.stage.materialize", materialize.MaterializeStageProviderConfig{})
 	assert.Nil(t, err)
 	assert.NotNil(t, *provider.(*materialize.MaterializeStageProvider))
@@ -756,7 +787,7 @@ func TestCreateProviderForTargetRole(t *testing.T) {
 
 	provider, err = CreateProviderForTargetRole(nil, "mempubsub", targetState, nil)
 	assert.Nil(t, err)
-	assert.NotNil(t, *provider.(*mempubsub.InMemoryPubSubProvider))
+	assert.NotNil(t, provider.(*mempubsub.InMemoryPubSubProvider))
 
 	provider, err = CreateProviderForTargetRole(nil, "httpreporter", targetState, nil)
 	assert.Nil(t, err)
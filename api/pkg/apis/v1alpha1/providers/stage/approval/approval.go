@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var msLock sync.Mutex
+var mLog = logger.NewLogger("coa.runtime")
+
+type ApprovalStageProviderConfig struct {
+	ID string `json:"id"`
+	// ApproverRoles are the roles, as mapped by the JWT middleware, that may approve or reject.
+	// They're required; "*" lets anyone who can reach the approval endpoints decide.
+	ApproverRoles []string `json:"approverRoles,omitempty"`
+	// Timeout is how long to wait for a decision, e.g. "24h"; empty waits forever
+	Timeout string `json:"timeout,omitempty"`
+	// TimeoutAction is "fail" (default) or "approve"
+	TimeoutAction string `json:"timeoutAction,omitempty"`
+}
+
+type ApprovalStageProvider struct {
+	Config  ApprovalStageProviderConfig
+	Context *contexts.ManagerContext
+}
+
+func (m *ApprovalStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+
+	approvalConfig, err := toApprovalStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	if err = validateTimeout(approvalConfig.Timeout, approvalConfig.TimeoutAction); err != nil {
+		return err
+	}
+	m.Config = approvalConfig
+	return nil
+}
+func (s *ApprovalStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toApprovalStageProviderConfig(config providers.IProviderConfig) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils2.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *ApprovalStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := ApprovalStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func ApprovalStageProviderConfigFromMap(properties map[string]string) (ApprovalStageProviderConfig, error) {
+	ret := ApprovalStageProviderConfig{}
+	ret.ID = properties["id"]
+	if v, ok := properties["approverRoles"]; ok && v != "" {
+		ret.ApproverRoles = splitRoles(v)
+	}
+	ret.Timeout = properties["timeout"]
+	ret.TimeoutAction = properties["timeoutAction"]
+	return ret, nil
+}
+
+// Process records a pending approval in the stage outputs and pauses the stage. The activation
+// resumes when the approval is decided through the activations API or the timeout expires.
+// The "approverRoles", "timeout", "timeoutAction" and "message" inputs override the config.
+func (i *ApprovalStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Approval Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	mLog.InfoCtx(ctx, "  P (Approval Stage): Process")
+
+	request := model.ApprovalRequest{
+		ApproverRoles: i.Config.ApproverRoles,
+		TimeoutAction: i.Config.TimeoutAction,
+	}
+	timeout := i.Config.Timeout
+	if v, ok := inputs["approverRoles"]; ok {
+		switch roles := v.(type) {
+		case []interface{}:
+			request.ApproverRoles = make([]string, 0, len(roles))
+			for _, role := range roles {
+				request.ApproverRoles = append(request.ApproverRoles, utils.FormatAsString(role))
+			}
+		case []string:
+			request.ApproverRoles = roles
+		default:
+			request.ApproverRoles = splitRoles(utils.FormatAsString(v))
+		}
+	}
+	if v, ok := inputs["timeout"]; ok {
+		timeout = utils.FormatAsString(v)
+	}
+	if v, ok := inputs["timeoutAction"]; ok {
+		request.TimeoutAction = utils.FormatAsString(v)
+	}
+	if v, ok := inputs["message"]; ok {
+		request.Message = utils.FormatAsString(v)
+	}
+
+	if len(request.ApproverRoles) == 0 {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("approval requires approverRoles, use '%s' to let anyone who can call the approval endpoints decide", model.ApproverAnyRole), v1alpha2.BadConfig)
+		mLog.ErrorfCtx(ctx, "  P (Approval Stage): %v", err)
+		return nil, false, err
+	}
+	err = validateTimeout(timeout, request.TimeoutAction)
+	if err != nil {
+		mLog.ErrorfCtx(ctx, "  P (Approval Stage): %v", err)
+		return nil, false, err
+	}
+	if timeout != "" {
+		duration, _ := time.ParseDuration(timeout)
+		request.Deadline = time.Now().UTC().Add(duration).Format(time.RFC3339)
+		if request.TimeoutAction == "" {
+			request.TimeoutAction = model.ApprovalTimeoutFail
+		}
+	}
+
+	mLog.InfofCtx(ctx, "  P (Approval Stage): waiting for approval from roles %v, deadline '%s'", request.ApproverRoles, request.Deadline)
+	return request.Outputs(), true, nil
+}
+
+func validateTimeout(timeout string, timeoutAction string) error {
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil || duration <= 0 {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid approval timeout '%s'", timeout), v1alpha2.BadConfig)
+		}
+	}
+	switch timeoutAction {
+	case "", model.ApprovalTimeoutFail, model.ApprovalTimeoutApprove:
+		return nil
+	default:
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid approval timeout action '%s', expected '%s' or '%s'", timeoutAction, model.ApprovalTimeoutFail, model.ApprovalTimeoutApprove), v1alpha2.BadConfig)
+	}
+}
+
+func splitRoles(roles string) []string {
+	ret := make([]string, 0)
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			ret = append(ret, role)
+		}
+	}
+	return ret
+}
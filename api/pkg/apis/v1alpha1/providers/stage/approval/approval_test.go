@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package approval
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalInitFromVendorMap(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"id":            "test",
+		"approverRoles": "administrator, release-manager",
+		"timeout":       "2h",
+		"timeoutAction": "approve",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "test", provider.Config.ID)
+	assert.Equal(t, []string{"administrator", "release-manager"}, provider.Config.ApproverRoles)
+	assert.Equal(t, "2h", provider.Config.Timeout)
+	assert.Equal(t, "approve", provider.Config.TimeoutAction)
+}
+
+func TestApprovalInitWithInvalidTimeout(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"timeout": "tomorrow",
+	})
+	assert.NotNil(t, err)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+
+	err = provider.InitWithMap(map[string]string{
+		"timeout":       "1h",
+		"timeoutAction": "ignore",
+	})
+	assert.NotNil(t, err)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+}
+
+func TestApprovalProcess(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{
+		ApproverRoles: []string{"administrator"},
+	})
+	assert.Nil(t, err)
+
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"message": "promote build 42 to production?",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	request, ok := model.ApprovalRequestFromOutputs(outputs)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"administrator"}, request.ApproverRoles)
+	assert.Equal(t, "promote build 42 to production?", request.Message)
+	assert.Empty(t, request.Deadline)
+	assert.False(t, request.IsExpired(time.Now().Add(24*time.Hour)))
+}
+
+func TestApprovalProcessWithTimeout(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approverRoles": []interface{}{"operator", "administrator"},
+		"timeout":       "30m",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	request, ok := model.ApprovalRequestFromOutputs(outputs)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"operator", "administrator"}, request.ApproverRoles)
+	assert.Equal(t, model.ApprovalTimeoutFail, request.TimeoutAction)
+	assert.False(t, request.IsExpired(time.Now()))
+	assert.True(t, request.IsExpired(time.Now().Add(31*time.Minute)))
+	assert.True(t, request.CanApprove([]string{"reader", "operator"}))
+	assert.False(t, request.CanApprove([]string{"reader"}))
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approverRoles": "operator",
+		"timeout":       "-5m",
+	})
+	assert.NotNil(t, err)
+}
+
+func TestApprovalProcessRequiresApproverRoles(t *testing.T) {
+	provider := ApprovalStageProvider{}
+	err := provider.Init(ApprovalStageProviderConfig{})
+	assert.Nil(t, err)
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{})
+	assert.NotNil(t, err)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"approverRoles": "*",
+	})
+	assert.Nil(t, err)
+	request, ok := model.ApprovalRequestFromOutputs(outputs)
+	assert.True(t, ok)
+	assert.True(t, request.CanApprove(nil))
+	assert.False(t, model.ApprovalRequest{}.CanApprove([]string{"administrator"}))
+}
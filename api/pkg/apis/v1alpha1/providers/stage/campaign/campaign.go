@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/stage"
+	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	loggerName = "providers.stage.campaign"
+	// maxPrefixLength keeps child activation names within the 63 characters allowed for Kubernetes labels
+	maxPrefixLength = 54
+	// defaultMaxDepth is how deep child activations can be nested when maxDepth isn't configured
+	defaultMaxDepth = 5
+)
+
+var msLock sync.Mutex
+var mLog = logger.NewLogger(loggerName)
+
+type CampaignStageProviderConfig struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+	MaxDepth int    `json:"maxDepth,omitempty"`
+}
+
+// CampaignStageProvider starts an activation of another campaign version and pauses the stage
+// until the child activation finishes
+type CampaignStageProvider struct {
+	Config    CampaignStageProviderConfig
+	Context   *contexts.ManagerContext
+	ApiClient api_utils.ApiClient
+}
+
+func (s *CampaignStageProvider) Init(config providers.IProviderConfig) error {
+	msLock.Lock()
+	defer msLock.Unlock()
+
+	campaignConfig, err := toCampaignStageProviderConfig(config)
+	if err != nil {
+		return err
+	}
+	s.Config = campaignConfig
+	s.ApiClient, err = api_utils.GetApiClient()
+	return err
+}
+func (s *CampaignStageProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+func toCampaignStageProviderConfig(config providers.IProviderConfig) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = utils.UnmarshalJson(data, &ret)
+	return ret, err
+}
+func (i *CampaignStageProvider) InitWithMap(properties map[string]string) error {
+	config, err := CampaignStageProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+func CampaignStageProviderConfigFromMap(properties map[string]string) (CampaignStageProviderConfig, error) {
+	ret := CampaignStageProviderConfig{}
+	if api_utils.ShouldUseUserCreds() {
+		user, err := api_utils.GetString(properties, "user")
+		if err != nil {
+			return ret, err
+		}
+		ret.User = user
+		if ret.User == "" && !api_utils.ShouldUseSATokens() {
+			return ret, v1alpha2.NewCOAError(nil, "user is required", v1alpha2.BadConfig)
+		}
+		password, err := api_utils.GetString(properties, "password")
+		if err != nil {
+			return ret, err
+		}
+		ret.Password = password
+	}
+	if v, ok := properties["maxDepth"]; ok && v != "" {
+		maxDepth, err := strconv.Atoi(v)
+		if err != nil || maxDepth < 1 {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("maxDepth must be a positive integer, got %s", v), v1alpha2.BadConfig)
+		}
+		ret.MaxDepth = maxDepth
+	}
+	return ret, nil
+}
+
+// Process creates a child activation of the "campaignVersion" input with the "inputs" input as its
+// inputs, starting from the optional "stage" input. The stage is paused until the child finishes,
+// and the stage manager then replaces the outputs with the final outputs of the child.
+func (i *CampaignStageProvider) Process(ctx context.Context, mgrContext contexts.ManagerContext, inputs map[string]interface{}) (map[string]interface{}, bool, error) {
+	ctx, span := observability.StartSpan("[Stage] Campaign Provider", ctx, &map[string]string{
+		"method": "Process",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	mLog.InfoCtx(ctx, "  P (Campaign Stage): Process")
+
+	campaignVersion := stage.ReadInputString(inputs, "campaignVersion")
+	if campaignVersion == "" {
+		err = v1alpha2.NewCOAError(nil, "campaignVersion input is required", v1alpha2.BadConfig)
+		mLog.ErrorfCtx(ctx, "  P (Campaign Stage): %v", err)
+		return nil, false, err
+	}
+	childInputs := map[string]interface{}{}
+	if v, ok := inputs["inputs"]; ok && v != nil {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("inputs input must be an object, got %T", v), v1alpha2.BadConfig)
+			mLog.ErrorfCtx(ctx, "  P (Campaign Stage): %v", err)
+			return nil, false, err
+		}
+		childInputs = m
+	}
+	namespace := stage.ReadInputString(inputs, "__namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+	parent := stage.ReadInputString(inputs, "__activation")
+
+	// campaigns running each other through expressions aren't caught when they're validated, so
+	// the nesting of child activations is limited
+	depth := 1
+	if parent != "" {
+		var parentActivation model.ActivationState
+		parentActivation, err = i.ApiClient.GetActivation(ctx, parent, namespace, i.Config.User, i.Config.Password)
+		if err != nil {
+			mLog.ErrorfCtx(ctx, "  P (Campaign Stage): failed to get parent activation %s: %v", parent, err)
+			return nil, false, err
+		}
+		if d, pErr := strconv.Atoi(parentActivation.ObjectMeta.Labels[constants.ActivationDepth]); pErr == nil {
+			depth = d + 1
+		}
+	}
+	maxDepth := i.Config.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	if depth > maxDepth {
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("child activation of %s would be nested %d levels deep, more than the limit of %d", parent, depth, maxDepth), v1alpha2.BadConfig)
+		mLog.ErrorfCtx(ctx, "  P (Campaign Stage): %v", err)
+		return nil, false, err
+	}
+
+	name := childActivationName(parent, campaignVersion)
+	activation := model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: &model.ActivationSpec{
+			CampaignVersion: campaignVersion,
+			Stage:           stage.ReadInputString(inputs, "stage"),
+			Inputs:          childInputs,
+		},
+	}
+	activation.ObjectMeta.Labels = map[string]string{
+		constants.ActivationDepth: strconv.Itoa(depth),
+	}
+	if parent != "" {
+		activation.ObjectMeta.Labels[constants.ParentActivation] = parent
+	}
+	payload, _ := json.Marshal(activation)
+
+	observ_utils.EmitUserAuditsLogs(ctx, "  P (Campaign Stage): Start to create child activation %s of campaignversion %s in namespace %s", name, campaignVersion, namespace)
+	err = i.ApiClient.CreateActivation(ctx, name, payload, namespace, i.Config.User, i.Config.Password)
+	if err != nil {
+		mLog.ErrorfCtx(ctx, "  P (Campaign Stage): failed to create child activation %s: %v", name, err)
+		return nil, false, err
+	}
+
+	mLog.InfofCtx(ctx, "  P (Campaign Stage): waiting for child activation %s", name)
+	return map[string]interface{}{
+		model.ChildActivationOutput: name,
+		"campaignVersion":           campaignVersion,
+	}, true, nil
+}
+
+func childActivationName(parent string, campaignVersion string) string {
+	prefix := parent
+	if prefix == "" {
+		prefix = api_utils.ConvertReferenceToObjectName(campaignVersion)
+	}
+	if len(prefix) > maxPrefixLength {
+		prefix = prefix[:maxPrefixLength]
+	}
+	return fmt.Sprintf("%s-%s", prefix, uuid.New().String()[:8])
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockActivationsAPI struct {
+	lock        sync.Mutex
+	activations map[string]model.ActivationState
+}
+
+func (m *mockActivationsAPI) handler(w http.ResponseWriter, r *http.Request) {
+	var response interface{}
+	if strings.HasPrefix(r.URL.Path, "/activations/registry/") && r.Method == http.MethodPost {
+		body, _ := io.ReadAll(r.Body)
+		var activation model.ActivationState
+		json.Unmarshal(body, &activation)
+		m.lock.Lock()
+		m.activations[strings.TrimPrefix(r.URL.Path, "/activations/registry/")] = activation
+		m.lock.Unlock()
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if strings.HasPrefix(r.URL.Path, "/activations/registry/") && r.Method == http.MethodGet {
+		m.lock.Lock()
+		response = m.activations[strings.TrimPrefix(r.URL.Path, "/activations/registry/")]
+		m.lock.Unlock()
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	response = utils.AuthResponse{
+		AccessToken: "test-token",
+		TokenType:   "Bearer",
+		Username:    "test-user",
+		Roles:       []string{"role1", "role2"},
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func TestCampaignInitFromVendorMapForNonServiceAccount(t *testing.T) {
+	UseServiceAccountTokenEnvName := os.Getenv(constants.UseServiceAccountTokenEnvName)
+	if UseServiceAccountTokenEnvName != "false" {
+		t.Skip("Skipping becasue UseServiceAccountTokenEnvName is not false")
+	}
+	config, err := CampaignStageProviderConfigFromMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", config.User)
+
+	_, err = CampaignStageProviderConfigFromMap(map[string]string{})
+	assert.NotNil(t, err)
+}
+
+func TestCampaignProcess(t *testing.T) {
+	api := &mockActivationsAPI{activations: map[string]model.ActivationState{}}
+	ts := httptest.NewServer(http.HandlerFunc(api.handler))
+	defer ts.Close()
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+
+	provider := CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+	})
+	assert.Nil(t, err)
+
+	outputs, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaignVersion": "drain:v1",
+		"stage":           "cordon",
+		"inputs": map[string]interface{}{
+			"node": "node-1",
+		},
+		"__activation": "upgrade",
+		"__namespace":  "edge",
+	})
+	assert.Nil(t, err)
+	assert.True(t, pause)
+	assert.Equal(t, "drain:v1", outputs["campaignVersion"])
+	name, ok := outputs[model.ChildActivationOutput].(string)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(name, "upgrade-"))
+
+	api.lock.Lock()
+	defer api.lock.Unlock()
+	child, ok := api.activations[name]
+	assert.True(t, ok)
+	assert.Equal(t, "edge", child.ObjectMeta.Namespace)
+	assert.Equal(t, "upgrade", child.ObjectMeta.Labels[constants.ParentActivation])
+	assert.Equal(t, "1", child.ObjectMeta.Labels[constants.ActivationDepth])
+	assert.Equal(t, "drain:v1", child.Spec.CampaignVersion)
+	assert.Equal(t, "cordon", child.Spec.Stage)
+	assert.Equal(t, "node-1", child.Spec.Inputs["node"])
+}
+
+func TestCampaignProcessNestedTooDeep(t *testing.T) {
+	api := &mockActivationsAPI{activations: map[string]model.ActivationState{
+		"upgrade": {
+			ObjectMeta: model.ObjectMeta{
+				Name: "upgrade",
+				Labels: map[string]string{
+					constants.ActivationDepth: "2",
+				},
+			},
+		},
+	}}
+	ts := httptest.NewServer(http.HandlerFunc(api.handler))
+	defer ts.Close()
+	os.Setenv(constants.SymphonyAPIUrlEnvName, ts.URL+"/")
+	os.Setenv(constants.UseServiceAccountTokenEnvName, "false")
+
+	provider := CampaignStageProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"user":     "admin",
+		"password": "",
+		"maxDepth": "3",
+	})
+	assert.Nil(t, err)
+	inputs := map[string]interface{}{
+		"campaignVersion": "drain:v1",
+		"__activation":    "upgrade",
+		"__namespace":     "edge",
+	}
+	outputs, _, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.Nil(t, err)
+	name := outputs[model.ChildActivationOutput].(string)
+	api.lock.Lock()
+	assert.Equal(t, "3", api.activations[name].ObjectMeta.Labels[constants.ActivationDepth])
+	api.lock.Unlock()
+
+	// the child runs the campaign version again, one level past the limit
+	inputs["__activation"] = name
+	_, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, inputs)
+	assert.NotNil(t, err)
+	assert.False(t, pause)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+}
+
+func TestCampaignProcessMissingCampaignVersion(t *testing.T) {
+	provider := CampaignStageProvider{}
+	_, pause, err := provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"__activation": "upgrade",
+	})
+	assert.NotNil(t, err)
+	assert.False(t, pause)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+
+	_, _, err = provider.Process(context.Background(), contexts.ManagerContext{}, map[string]interface{}{
+		"campaignVersion": "drain:v1",
+		"inputs":          "node-1",
+	})
+	assert.NotNil(t, err)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+}
+
+func TestChildActivationName(t *testing.T) {
+	name := childActivationName(strings.Repeat("a", 80), "drain:v1")
+	assert.Equal(t, 63, len(name))
+	name = childActivationName("", "drain:v1")
+	assert.True(t, strings.HasPrefix(name, "drain-v-v1-"))
+}
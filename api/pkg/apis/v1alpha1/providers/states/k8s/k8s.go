@@ -29,6 +29,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -203,6 +204,22 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 	j, _ := json.Marshal(entry.Value.Body)
 	var item *unstructured.Unstructured
 	item, err = s.DynamicClient.Resource(resourceId).Namespace(namespace).Get(ctx, entry.Value.ID, metav1.GetOptions{})
+	if entry.ETag != nil {
+		// the entry is only written if its resource version is still the expected one, which is
+		// empty for objects that don't exist yet
+		current := ""
+		if err == nil {
+			current = item.GetResourceVersion()
+		} else if !k8s_errors.IsNotFound(err) {
+			sLog.ErrorfCtx(ctx, "  P (K8s State): failed to get object: %v", err)
+			return "", err
+		}
+		if (err == nil && *entry.ETag == "") || current != *entry.ETag {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", entry.Value.ID, *entry.ETag, current), v1alpha2.Conflict)
+			sLog.ErrorfCtx(ctx, "  P (K8s State): failed to upsert state: %v", err)
+			return "", err
+		}
+	}
 	if err != nil {
 		template := fmt.Sprintf(`{"apiVersion":"%s/v1", "kind": "%s", "metadata": {}}`, group, kind)
 		var unc *unstructured.Unstructured
@@ -234,6 +251,9 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 		_, err = s.DynamicClient.Resource(resourceId).Namespace(namespace).Create(ctx, unc, metav1.CreateOptions{})
 		if err != nil {
 			sLog.ErrorfCtx(ctx, "  P (K8s State): failed to create object: %v", err)
+			if entry.ETag != nil && k8s_errors.IsAlreadyExists(err) {
+				return "", v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' has been created by another caller", entry.Value.ID), v1alpha2.Conflict)
+			}
 			if statusError, ok := err.(*apierrors.StatusError); ok {
 				sLog.InfofCtx(ctx, "  P (K8s State): This is an webhook error with status: %d, message: %v", statusError.Status().Code, statusError)
 				state := v1alpha2.State(int(statusError.Status().Code))
@@ -276,12 +296,17 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 
 			// If we update the labels, annotations or spec, we should respect the resource version provided by client.
 			// If client does not provide a ETag, we treat it as concurrency not required and ignore the ETag.
-			if entry.Value.ETag != "" {
+			if entry.ETag != nil {
+				item.SetResourceVersion(*entry.ETag)
+			} else if entry.Value.ETag != "" {
 				item.SetResourceVersion(entry.Value.ETag)
 			}
 			_, err = s.DynamicClient.Resource(resourceId).Namespace(namespace).Update(ctx, item, metav1.UpdateOptions{})
 			if err != nil {
 				sLog.ErrorfCtx(ctx, "  P (K8s State): failed to update object: %v", err)
+				if k8s_errors.IsConflict(err) {
+					return "", v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' has been modified", entry.Value.ID), v1alpha2.Conflict)
+				}
 				return "", err
 			}
 			getResourceVersion = true
@@ -332,6 +357,12 @@ func (s *K8sStateProvider) Upsert(ctx context.Context, entry states.UpsertReques
 	return entry.Value.ID, nil
 }
 
+// SupportsConditionalUpsert is true, as upserts with an ETag are conditional on the resource
+// version of the object
+func (s *K8sStateProvider) SupportsConditionalUpsert() bool {
+	return true
+}
+
 func (s *K8sStateProvider) ListAllNamespaces(ctx context.Context, version string) ([]string, error) {
 	namespaceResource := schema.GroupVersionResource{Group: "", Version: version, Resource: "namespaces"}
 	namespaces, err := s.DynamicClient.Resource(namespaceResource).List(ctx, metav1.ListOptions{})
@@ -373,6 +404,20 @@ func (s *K8sStateProvider) List(ctx context.Context, request states.ListRequest)
 	} else {
 		namespaces = []string{namespace}
 	}
+	// Objects of a namespace are listed by name, so k8s can page them. Otherwise, the
+	// objects of all namespaces are sorted and paged here.
+	sortBy := states.NormalizeSortBy(request.SortBy)
+	nativePaging := namespace != "" && sortBy == states.SortByName
+	cursor := ""
+	if nativePaging && request.Continue != "" {
+		token, err := states.DecodeContinueToken(request.Continue, request.SortBy)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = token.Cursor
+	}
+	var items []states.ListItem
+	next := ""
 	for _, namespace := range namespaces {
 		resourceId := schema.GroupVersionResource{
 			Group:    group,
@@ -402,12 +447,22 @@ func (s *K8sStateProvider) List(ctx context.Context, request states.ListRequest)
 			sLog.ErrorfCtx(ctx, "  P (K8s State): invalid filter type: %s", request.FilterType)
 			return nil, "", v1alpha2.NewCOAError(nil, "invalid filter type", v1alpha2.BadRequest)
 		}
-		items, err := s.DynamicClient.Resource(resourceId).Namespace(namespace).List(ctx, options)
+		if nativePaging {
+			options.Limit = int64(request.Limit)
+			options.Continue = cursor
+		}
+		list, err := s.DynamicClient.Resource(resourceId).Namespace(namespace).List(ctx, options)
 		if err != nil {
 			sLog.ErrorfCtx(ctx, "  P (K8s State): failed to list objects in namespace %s: %v ", namespace, err)
 			return nil, "", err
 		}
-		for _, v := range items.Items {
+		if nativePaging && list.GetContinue() != "" {
+			next = states.EncodeContinueToken(states.ContinueToken{
+				SortBy: sortBy,
+				Cursor: list.GetContinue(),
+			})
+		}
+		for _, v := range list.Items {
 
 			if filterValue != "" {
 				switch request.FilterType {
@@ -438,27 +493,114 @@ func (s *K8sStateProvider) List(ctx context.Context, request states.ListRequest)
 				}
 			}
 
-			metadata := model.ObjectMeta{
-				Name:          v.GetName(),
-				Namespace:     v.GetNamespace(),
-				Labels:        v.GetLabels(),
-				ETag:          v.GetResourceVersion(),
-				Annotations:   v.GetAnnotations(),
-				ObjGeneration: v.GetGeneration(),
-			}
-			entry := states.StateEntry{
-				ETag: v.GetResourceVersion(),
-				ID:   v.GetName(),
-				Body: map[string]interface{}{
-					"spec":     v.Object["spec"],
-					"status":   v.Object["status"],
-					"metadata": metadata,
-				},
+			items = append(items, states.ListItem{Key: v.GetNamespace() + "/" + v.GetName(), Entry: toStateEntry(v)})
+		}
+	}
+	if nativePaging {
+		for _, item := range items {
+			entities = append(entities, item.Entry)
+		}
+		return entities, next, nil
+	}
+	return states.PageList(items, request)
+}
+
+func (s *K8sStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	namespace := model.ReadPropertyCompat(request.Metadata, "namespace", nil)
+	group := model.ReadPropertyCompat(request.Metadata, "group", nil)
+	version := model.ReadPropertyCompat(request.Metadata, "version", nil)
+	resource := model.ReadPropertyCompat(request.Metadata, "resource", nil)
+
+	sLog.InfofCtx(ctx, "  P (K8s State): watch state for %s.%s in namespace %s", resource, group, namespace)
+
+	resourceId := schema.GroupVersionResource{
+		Group:    group,
+		Version:  version,
+		Resource: resource,
+	}
+	options := metav1.ListOptions{}
+	switch request.FilterType {
+	case "label":
+		options.LabelSelector = request.FilterValue
+	case "field":
+		options.FieldSelector = request.FilterValue
+	case "spec", "status", "":
+		// spec and status filters are applied to the events
+	default:
+		sLog.ErrorfCtx(ctx, "  P (K8s State): invalid filter type: %s", request.FilterType)
+		return nil, v1alpha2.NewCOAError(nil, "invalid filter type", v1alpha2.BadRequest)
+	}
+	// An empty namespace watches all namespaces
+	watcher, err := s.DynamicClient.Resource(resourceId).Namespace(namespace).Watch(ctx, options)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (K8s State): failed to watch objects in namespace %s: %v ", namespace, err)
+		return nil, err
+	}
+	ret := make(chan states.WatchEvent)
+	go func() {
+		defer close(ret)
+		defer watcher.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+				var eventType string
+				switch event.Type {
+				case watch.Added:
+					eventType = states.WatchAdded
+				case watch.Modified:
+					eventType = states.WatchModified
+				case watch.Deleted:
+					eventType = states.WatchDeleted
+				case watch.Error:
+					sLog.ErrorfCtx(ctx, "  P (K8s State): watch of %s.%s failed: %v", resource, group, apierrors.FromObject(event.Object))
+					return
+				default:
+					continue
+				}
+				item, ok := event.Object.(*unstructured.Unstructured)
+				if !ok {
+					continue
+				}
+				entry := toStateEntry(*item)
+				if (request.FilterType == "spec" || request.FilterType == "status") && request.FilterValue != "" {
+					if match, err := states.MatchFilter(entry, request.FilterType, request.FilterValue); err != nil || !match {
+						continue
+					}
+				}
+				select {
+				case ret <- states.WatchEvent{Type: eventType, Entry: entry, ResourceVersion: entry.ETag}:
+				case <-ctx.Done():
+					return
+				}
 			}
-			entities = append(entities, entry)
 		}
+	}()
+	return ret, nil
+}
+
+func toStateEntry(v unstructured.Unstructured) states.StateEntry {
+	metadata := model.ObjectMeta{
+		Name:          v.GetName(),
+		Namespace:     v.GetNamespace(),
+		Labels:        v.GetLabels(),
+		ETag:          v.GetResourceVersion(),
+		Annotations:   v.GetAnnotations(),
+		ObjGeneration: v.GetGeneration(),
+	}
+	return states.StateEntry{
+		ETag: v.GetResourceVersion(),
+		ID:   v.GetName(),
+		Body: map[string]interface{}{
+			"spec":     v.Object["spec"],
+			"status":   v.Object["status"],
+			"metadata": metadata,
+		},
 	}
-	return entities, "", nil
 }
 
 func (s *K8sStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
@@ -16,8 +16,14 @@ import (
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
 )
 
 func TestK8sStateProviderConfigFromMapNil(t *testing.T) {
@@ -1074,3 +1080,158 @@ func TestTargetUpSertWithStateOnly(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+func TestActivationWatch(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.WorkflowGroup, Version: "v1", Resource: "activations"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "ActivationList",
+	})
+	provider := K8sStateProvider{DynamicClient: client}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := provider.Watch(ctx, states.WatchRequest{
+		FilterType:  "label",
+		FilterValue: "tier=edge",
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+			"group":     model.WorkflowGroup,
+			"version":   "v1",
+			"resource":  "activations",
+		},
+	})
+	assert.Nil(t, err)
+
+	item := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": model.WorkflowGroup + "/v1",
+			"kind":       "Activation",
+			"metadata": map[string]interface{}{
+				"name":            "a1",
+				"namespace":       "default",
+				"resourceVersion": "7",
+				"labels": map[string]interface{}{
+					"tier": "edge",
+				},
+			},
+			"spec": map[string]interface{}{
+				"campaignversion": "c1",
+			},
+		},
+	}
+	_, err = client.Resource(gvr).Namespace("default").Create(context.Background(), item, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	err = client.Resource(gvr).Namespace("default").Delete(context.Background(), "a1", metav1.DeleteOptions{})
+	assert.Nil(t, err)
+
+	event := <-events
+	assert.Equal(t, states.WatchAdded, event.Type)
+	assert.Equal(t, "a1", event.Entry.ID)
+	assert.Equal(t, "7", event.ResourceVersion)
+	assert.Equal(t, "c1", event.Entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["campaignversion"])
+	event = <-events
+	assert.Equal(t, states.WatchDeleted, event.Type)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestListPagingBySpecField(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.WorkflowGroup, Version: "v1", Resource: "activations"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "ActivationList",
+	})
+	provider := K8sStateProvider{DynamicClient: client}
+	for i, name := range []string{"a1", "a2", "a3"} {
+		item := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": model.WorkflowGroup + "/v1",
+				"kind":       "Activation",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": "default",
+				},
+				"spec": map[string]interface{}{
+					"stage": []interface{}{"s2", "s3", "s1"}[i],
+				},
+			},
+		}
+		_, err := client.Resource(gvr).Namespace("default").Create(context.Background(), item, metav1.CreateOptions{})
+		assert.Nil(t, err)
+	}
+	metadata := map[string]interface{}{
+		"namespace": "default",
+		"group":     model.WorkflowGroup,
+		"version":   "v1",
+		"resource":  "activations",
+	}
+
+	entries, next, err := provider.List(context.Background(), states.ListRequest{
+		Metadata:    metadata,
+		ListOptions: states.ListOptions{Limit: 2, SortBy: "spec.stage"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "a3", entries[0].ID)
+	assert.Equal(t, "a1", entries[1].ID)
+	entries, next, err = provider.List(context.Background(), states.ListRequest{
+		Metadata:    metadata,
+		ListOptions: states.ListOptions{Limit: 2, SortBy: "spec.stage", Continue: next},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "a2", entries[0].ID)
+	assert.Equal(t, "", next)
+}
+
+func TestUpsertWithETag(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: model.FabricGroup, Version: "v1", Resource: "targets"}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gvr: "TargetList",
+	})
+	provider := K8sStateProvider{DynamicClient: client}
+	assert.True(t, states.SupportsConditionalUpsert(&provider))
+	metadata := map[string]interface{}{
+		"namespace": "default",
+		"group":     model.FabricGroup,
+		"version":   "v1",
+		"resource":  "targets",
+		"kind":      "Target",
+	}
+	upsert := func(etag string, value string) error {
+		_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID: "t1",
+				Body: map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "t1", "namespace": "default"},
+					"spec":     map[string]interface{}{"displayName": value},
+				},
+			},
+			ETag:     &etag,
+			Metadata: metadata,
+		})
+		return err
+	}
+
+	// a stale ETag doesn't create a missing object
+	err := upsert("3", "a")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	// the empty ETag only creates
+	err = upsert("", "a")
+	assert.Nil(t, err)
+	item, err := client.Resource(gvr).Namespace("default").Get(context.Background(), "t1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	item.SetResourceVersion("5")
+	_, err = client.Resource(gvr).Namespace("default").Update(context.Background(), item, metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	err = upsert("", "b")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	err = upsert("4", "b")
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+
+	err = upsert("5", "b")
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "t1", Metadata: metadata})
+	assert.Nil(t, err)
+	assert.Equal(t, "b", entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["displayName"])
+}
@@ -7,15 +7,40 @@
 package conformance
 
 import (
+	"fmt"
+	"os"
 	"testing"
 
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/plugin"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/providers/target/azure/adu"
 	"github.com/stretchr/testify/assert"
 )
 
+// servePluginKey makes the test binary serve the ADU provider as a plugin
+const servePluginKey = "SYMPHONY_CONFORMANCE_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(servePluginKey) == "" {
+		os.Exit(m.Run())
+	}
+	if err := plugin.Serve(plugin.ServeConfig{Name: "adu", TargetProvider: &adu.ADUTargetProvider{}}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
 func TestConformanceSuite(t *testing.T) {
 	provider := &adu.ADUTargetProvider{}
 	err := provider.Init(adu.ADUTargetProviderConfig{})
 	assert.Nil(t, err)
 	ConformanceSuite(t, provider)
 }
+
+func TestConformanceSuiteOverPlugin(t *testing.T) {
+	t.Setenv(servePluginKey, "true")
+	provider := &plugin.PluginProvider{}
+	err := provider.Init(plugin.PluginProviderConfig{Path: os.Args[0]})
+	assert.Nil(t, err)
+	defer provider.Close()
+	ConformanceSuite(t, provider)
+}
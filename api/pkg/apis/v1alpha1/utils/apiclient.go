@@ -76,6 +76,7 @@ type (
 		CatalogVersionHook(ctx context.Context, payload []byte, user string, password string) error
 		PublishActivationEvent(ctx context.Context, event v1alpha2.ActivationData, user string, password string) error
 		GetActivation(ctx context.Context, activation string, namespace string, user string, password string) (model.ActivationState, error)
+		CreateActivation(ctx context.Context, activation string, payload []byte, namespace string, user string, password string) error
 		GetCatalogVersion(ctx context.Context, catalogversion string, namespace string, user string, password string) (model.CatalogVersionState, error)
 		UpsertCatalogVersion(ctx context.Context, catalogversion string, payload []byte, user string, password string) error
 		DeleteCatalogVersion(ctx context.Context, catalogversion string, user string, password string) error
@@ -645,6 +646,20 @@ func (a *apiClient) GetActivation(ctx context.Context, activation string, namesp
 	return ret, nil
 }
 
+func (a *apiClient) CreateActivation(ctx context.Context, activation string, payload []byte, namespace string, user string, password string) error {
+	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.callRestAPI(ctx, "activations/registry/"+url.QueryEscape(activation)+"?namespace="+url.QueryEscape(namespace), "POST", payload, token)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (a *apiClient) GetCatalogVersion(ctx context.Context, catalogversion string, namespace string, user string, password string) (model.CatalogVersionState, error) {
 	ret := model.CatalogVersionState{}
 	token, err := a.tokenProvider(ctx, a.baseUrl, a.client, user, password)
@@ -939,26 +939,34 @@ func (n *FunctionNode) Eval(context utils.EvaluationContext) (interface{}, error
 			if err != nil {
 				return nil, err
 			}
-			path := FormatAsString(obj)
-			if strings.HasPrefix(path, "$") || strings.HasPrefix(path, "{$") {
-				result, err := JsonPathQuery(context.Value, FormatAsString(obj))
-				if err != nil {
-					return nil, err
-				}
-				return result, nil
-			} else {
-				if mobj, ok := context.Value.(map[string]interface{}); ok {
-					if v, ok := mobj[path]; ok {
-						return v, nil
-					} else {
-						return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("key %s is not found in context value", path), v1alpha2.BadConfig)
-					}
-				} else {
-					return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("context value '%v' is not a map", context.Value), v1alpha2.BadConfig)
-				}
-			}
+			return queryValue(context.Value, FormatAsString(obj), "context value")
 		}
 		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("$val() or $context() expects 0 or 1 argument, found %d", len(n.Args)), v1alpha2.BadConfig)
+	case "item":
+		item, ok := context.Inputs[model.ForEachItemInput]
+		if !ok {
+			return nil, v1alpha2.NewCOAError(nil, "$item() can only be used in the tasks of a forEach stage", v1alpha2.BadConfig)
+		}
+		if len(n.Args) == 0 {
+			return item, nil
+		}
+		if len(n.Args) == 1 {
+			obj, err := n.Args[0].Eval(context)
+			if err != nil {
+				return nil, err
+			}
+			return queryValue(item, FormatAsString(obj), "item")
+		}
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("$item() expects 0 or 1 argument, found %d", len(n.Args)), v1alpha2.BadConfig)
+	case "index":
+		if len(n.Args) == 0 {
+			index, ok := context.Inputs[model.ForEachIndexInput]
+			if !ok {
+				return nil, v1alpha2.NewCOAError(nil, "$index() can only be used in the tasks of a forEach stage", v1alpha2.BadConfig)
+			}
+			return index, nil
+		}
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("$index() expects 0 arguments, found %d", len(n.Args)), v1alpha2.BadConfig)
 	case "base64decode":
 		if len(n.Args) == 1 {
 			val, err := n.Args[0].Eval(context)
@@ -1037,6 +1045,21 @@ func (n *FunctionNode) Eval(context utils.EvaluationContext) (interface{}, error
 	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("invalid function name: '%s'", n.Name), v1alpha2.BadConfig)
 }
 
+// queryValue reads path from value. A path starting with "$" is a JSONPath query, anything
+// else is a key of a map value. name describes value in errors.
+func queryValue(value interface{}, path string, name string) (interface{}, error) {
+	if strings.HasPrefix(path, "$") || strings.HasPrefix(path, "{$") {
+		return JsonPathQuery(value, path)
+	}
+	if mobj, ok := value.(map[string]interface{}); ok {
+		if v, ok := mobj[path]; ok {
+			return v, nil
+		}
+		return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("key %s is not found in %s", path, name), v1alpha2.BadConfig)
+	}
+	return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s '%v' is not a map", name, value), v1alpha2.BadConfig)
+}
+
 type Parser struct {
 	Segments     []string
 	OriginalText string
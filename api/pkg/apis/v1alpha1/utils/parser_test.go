@@ -3207,3 +3207,29 @@ func TestCircularErrorIsBadConfig(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, v1alpha2.BadConfig, cErr.State)
 }
+func TestItemAndIndex(t *testing.T) {
+	context := utils.EvaluationContext{
+		Context: ctx,
+		Inputs: map[string]interface{}{
+			model.ForEachItemInput:  map[string]interface{}{"name": "tokyo"},
+			model.ForEachIndexInput: 1,
+		},
+	}
+	val, err := NewParser("${{$item(name)}}-${{$index()}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, "tokyo-1", val)
+
+	val, err = NewParser("${{$item('$.name')}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, "tokyo", val)
+
+	val, err = NewParser("${{$item()}}").Eval(context)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "tokyo"}, val)
+}
+func TestItemOutsideForEach(t *testing.T) {
+	_, err := NewParser("${{$item()}}").Eval(utils.EvaluationContext{Context: ctx})
+	assert.NotNil(t, err)
+	_, err = NewParser("${{$index()}}").Eval(utils.EvaluationContext{Context: ctx})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package watch
+
+import (
+	"context"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+// WatchStates sends the entries that currently match the request as ADDED events, followed by
+// the changes reported by the state provider. convert turns an entry into the object sent to
+// the client. The channel is closed when ctx is done or when the provider stops the watch.
+func WatchStates(ctx context.Context, provider states.IStateProvider, request states.WatchRequest, convert func(states.StateEntry) (interface{}, error)) (<-chan model.WatchEvent, error) {
+	watchable, ok := provider.(states.IWatchableStateProvider)
+	if !ok {
+		return nil, v1alpha2.NewCOAError(nil, "watch is not supported by the state provider", v1alpha2.BadRequest)
+	}
+	// Watch before listing, so that no change is missed between the two
+	events, err := watchable.Watch(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	entries, _, err := provider.List(ctx, states.ListRequest{
+		FilterType:  request.FilterType,
+		FilterValue: request.FilterValue,
+		Metadata:    request.Metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret := make(chan model.WatchEvent)
+	send := func(eventType string, entry states.StateEntry) bool {
+		object, err := convert(entry)
+		if err != nil {
+			log.ErrorfCtx(ctx, "failed to convert watched entry %s: %+v", entry.ID, err)
+			return true
+		}
+		select {
+		case ret <- model.WatchEvent{Type: eventType, Object: object, ResourceVersion: entry.ETag}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(ret)
+		for _, entry := range entries {
+			if !send(states.WatchAdded, entry) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok || !send(event.Type, event.Entry) {
+					return
+				}
+			}
+		}
+	}()
+	return ret, nil
+}
@@ -8,6 +8,7 @@ package validation
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -19,18 +20,24 @@ var (
 	campaignversionMinNameLength = 1
 )
 
+const campaignStageProvider = "providers.stage.campaign"
+
 type CampaignVersionValidator struct {
 	// Check CampaignVersion Container existence
 	CampaignLookupFunc ObjectLookupFunc
 
 	// Check Activations associated with the CampaignVersion
 	CampaignVersionActivationsLookupFunc LinkedObjectLookupFunc
+
+	// Check CampaignVersions invoked by campaign stages for cycles
+	CampaignVersionLookupFunc ObjectLookupFunc
 }
 
-func NewCampaignVersionValidator(campaignversionContainerLookupFunc ObjectLookupFunc, campaignversionActivationsLookupFunc LinkedObjectLookupFunc) CampaignVersionValidator {
+func NewCampaignVersionValidator(campaignversionContainerLookupFunc ObjectLookupFunc, campaignversionActivationsLookupFunc LinkedObjectLookupFunc, campaignversionLookupFunc ObjectLookupFunc) CampaignVersionValidator {
 	return CampaignVersionValidator{
 		CampaignLookupFunc:   campaignversionContainerLookupFunc,
 		CampaignVersionActivationsLookupFunc: campaignversionActivationsLookupFunc,
+		CampaignVersionLookupFunc:            campaignversionLookupFunc,
 	}
 }
 
@@ -39,6 +46,7 @@ func NewCampaignVersionValidator(campaignversionContainerLookupFunc ObjectLookup
 // 2. Stages in the list are
 // 3. campaignversion name and rootResource is valid. And rootResource is immutable
 // 4. Update is not allow when there are running activations
+// 5. Campaign stages don't invoke the campaignversion recursively
 func (c *CampaignVersionValidator) ValidateCreateOrUpdate(ctx context.Context, newRef interface{}, oldRef interface{}) []ErrorField {
 	new := c.ConvertInterfaceToCampaignVersion(newRef)
 	old := c.ConvertInterfaceToCampaignVersion(oldRef)
@@ -54,6 +62,10 @@ func (c *CampaignVersionValidator) ValidateCreateOrUpdate(ctx context.Context, n
 	if err := c.ValidateStages(new); err != nil {
 		errorFields = append(errorFields, *err)
 	}
+	// validate campaign stages
+	if err := c.ValidateCampaignStages(ctx, new); err != nil {
+		errorFields = append(errorFields, *err)
+	}
 	if oldRef == nil {
 		// validate create specific fields
 		if err := ValidateObjectName(new.ObjectMeta.Name, new.Spec.RootResource, campaignversionMinNameLength, campaignversionMaxNameLength); err != nil {
@@ -137,6 +149,72 @@ func (c *CampaignVersionValidator) ValidateStages(campaignversion model.Campaign
 	return nil
 }
 
+// Validate campaign stages don't invoke the campaignversion, either directly or through the campaignversions they invoke
+// CampaignVersionLookupFunc will look up the invoked campaignversions. Only campaignVersion inputs without expressions are followed
+func (c *CampaignVersionValidator) ValidateCampaignStages(ctx context.Context, campaignversion model.CampaignVersionState) *ErrorField {
+	for _, stage := range campaignversion.Spec.Stages {
+		invoked := invokedCampaignVersion(stage)
+		if invoked == "" {
+			continue
+		}
+		if invoked == campaignversion.ObjectMeta.Name || c.invokesCampaignVersion(ctx, invoked, campaignversion.ObjectMeta.Namespace, campaignversion.ObjectMeta.Name, map[string]struct{}{}) {
+			return &ErrorField{
+				FieldPath:       fmt.Sprintf("spec.stages.%s.inputs.campaignVersion", stage.Name),
+				Value:           stage.Inputs["campaignVersion"],
+				DetailedMessage: "campaign stage invokes the campaignversion recursively",
+			}
+		}
+	}
+	return nil
+}
+
+func (c *CampaignVersionValidator) invokesCampaignVersion(ctx context.Context, name string, namespace string, campaignversionName string, visited map[string]struct{}) bool {
+	if c.CampaignVersionLookupFunc == nil {
+		return false
+	}
+	if _, ok := visited[name]; ok {
+		return false
+	}
+	visited[name] = struct{}{}
+
+	lookupRes, err := c.CampaignVersionLookupFunc(ctx, name, namespace)
+	if err != nil {
+		return false
+	}
+	jsonData, err := json.Marshal(lookupRes)
+	if err != nil {
+		return false
+	}
+	var campaignversion model.CampaignVersionState
+	err = json.Unmarshal(jsonData, &campaignversion)
+	if err != nil || campaignversion.Spec == nil {
+		return false
+	}
+	for _, stage := range campaignversion.Spec.Stages {
+		invoked := invokedCampaignVersion(stage)
+		if invoked == "" {
+			continue
+		}
+		if invoked == campaignversionName || c.invokesCampaignVersion(ctx, invoked, namespace, campaignversionName, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// invokedCampaignVersion returns the object name of the campaignversion a campaign stage invokes,
+// or an empty string if the stage isn't a campaign stage or the campaignversion is an expression
+func invokedCampaignVersion(stage model.StageSpec) string {
+	if stage.Provider != campaignStageProvider {
+		return ""
+	}
+	invoked, ok := stage.Inputs["campaignVersion"].(string)
+	if !ok || invoked == "" || strings.Contains(invoked, "$") {
+		return ""
+	}
+	return ConvertReferenceToObjectName(invoked)
+}
+
 // Validate NO running activations
 // CampaignVersionActivationsLookupFunc will look up activations with label {"campaignversion" : c.ObjectMeta.Name}
 func (c *CampaignVersionValidator) ValidateRunningActivation(ctx context.Context, campaignversion model.CampaignVersionState) *ErrorField {
@@ -9,6 +9,7 @@ package validation
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	api_constants "github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -39,6 +40,7 @@ func NewInstanceValidator(uniqueNameInstanceLookupFunc ObjectLookupFunc, solutio
 // 2. SolutionVersion exists
 // 3. Target exists if provided by name rather than selector
 // 4. Target is valid, i.e. either name or selector is provided
+// 5. RolloutStrategy is valid if provided
 func (i *InstanceValidator) ValidateCreateOrUpdate(ctx context.Context, newRef interface{}, oldRef interface{}) []ErrorField {
 	new := i.ConvertInterfaceToInstance(newRef)
 	old := i.ConvertInterfaceToInstance(oldRef)
@@ -83,6 +85,9 @@ func (i *InstanceValidator) ValidateCreateOrUpdate(ctx context.Context, newRef i
 	if err := i.ValidateTargetValid(new); err != nil {
 		errorFields = append(errorFields, *err)
 	}
+	if err := i.ValidateRolloutStrategy(new); err != nil {
+		errorFields = append(errorFields, *err)
+	}
 	return errorFields
 }
 
@@ -204,6 +209,38 @@ func (i *InstanceValidator) ValidateTargetValid(c model.InstanceState) *ErrorFie
 	return nil
 }
 
+// Validate RolloutStrategy has a valid batch size, batch percentage and pause between waves
+func (i *InstanceValidator) ValidateRolloutStrategy(c model.InstanceState) *ErrorField {
+	strategy := c.Spec.RolloutStrategy
+	if strategy == nil {
+		return nil
+	}
+	if strategy.BatchSize < 0 {
+		return &ErrorField{
+			FieldPath:       "spec.rolloutStrategy.batchSize",
+			Value:           strategy.BatchSize,
+			DetailedMessage: "batchSize must not be negative",
+		}
+	}
+	if strategy.BatchPercentage < 0 || strategy.BatchPercentage > 100 {
+		return &ErrorField{
+			FieldPath:       "spec.rolloutStrategy.batchPercentage",
+			Value:           strategy.BatchPercentage,
+			DetailedMessage: "batchPercentage must be between 0 and 100",
+		}
+	}
+	if strategy.PauseBetweenWaves != "" {
+		if d, err := time.ParseDuration(strategy.PauseBetweenWaves); err != nil || d < 0 {
+			return &ErrorField{
+				FieldPath:       "spec.rolloutStrategy.pauseBetweenWaves",
+				Value:           strategy.PauseBetweenWaves,
+				DetailedMessage: "pauseBetweenWaves must be a valid non-negative duration, such as 30s",
+			}
+		}
+	}
+	return nil
+}
+
 func (i *InstanceValidator) ConvertInterfaceToInstance(ref interface{}) model.InstanceState {
 	if ref == nil {
 		return model.InstanceState{
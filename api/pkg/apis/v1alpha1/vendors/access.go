@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+)
+
+// storedLabels reads the labels of the stored objects of a registry with the GetState of its
+// manager, for the label selectors of the RBAC policy
+func storedLabels[T any](getState func(ctx context.Context, name string, namespace string) (T, error)) v1alpha2.ObjectLabelsFunc {
+	return func(ctx context.Context, namespace string, name string) (map[string]string, bool, error) {
+		if namespace == "" {
+			namespace = constants.DefaultScope
+		}
+		state, err := getState(ctx, name, namespace)
+		if err != nil {
+			if utils.IsNotFound(err) {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		data, _ := json.Marshal(state)
+		return coa_http.ObjectLabels(data), true, nil
+	}
+}
+
+// hasAnyRole tells if the caller of a request has any of the roles
+func hasAnyRole(request v1alpha2.COARequest, roles []string) bool {
+	for _, role := range splitList(request.Metadata[v1alpha2.COARolesKey]) {
+		for _, allowed := range roles {
+			if role == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requireAnyRole tells if the caller of a request has any of the roles, and returns the
+// response to send if it doesn't. The operation names what the roles guard in that response.
+func requireAnyRole(ctx context.Context, request v1alpha2.COARequest, roles []string, operation string) (v1alpha2.COAResponse, bool) {
+	if hasAnyRole(request, roles) {
+		return v1alpha2.COAResponse{}, true
+	}
+	log.ErrorfCtx(ctx, "V: user '%s' doesn't have any of the roles %v for %s", request.Metadata[v1alpha2.COAUserKey], roles, operation)
+	return v1alpha2.COAResponse{
+		State: v1alpha2.Forbidden,
+		Body:  []byte(fmt.Sprintf("%s requires one of the roles %v", operation, roles)),
+	}, false
+}
+
+// callerTrail adds the name of the object of a request and the user making it to the
+// properties of a trail
+func callerTrail(request v1alpha2.COARequest, properties map[string]interface{}) map[string]interface{} {
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	properties["id"] = request.Parameters["__name"]
+	properties["user"] = request.Metadata[v1alpha2.COAUserKey]
+	return properties
+}
+
+// publishTrail records an action of a vendor as a trail of the type. Callers keep secrets and
+// their hashes out of the properties.
+func publishTrail(ctx context.Context, vendorContext *contexts.VendorContext, trailType string, action string, properties map[string]interface{}) {
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	properties["action"] = action
+	vendorContext.Publish("trail", v1alpha2.Event{
+		Body: []v1alpha2.Trail{
+			{
+				Origin:     vendorContext.SiteInfo.SiteId,
+				Type:       trailType,
+				Properties: properties,
+			},
+		},
+		Context: ctx,
+	})
+}
@@ -7,6 +7,11 @@
 package vendors
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -17,6 +22,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -27,7 +33,8 @@ var vLog = logger.NewLogger("coa.runtime")
 
 type ActivationsVendor struct {
 	vendors.Vendor
-	ActivationsManager *activations.ActivationsManager
+	ActivationsManager          *activations.ActivationsManager
+	RecurringActivationsManager *activations.RecurringActivationsManager
 }
 
 func (o *ActivationsVendor) GetInfo() vendors.VendorInfo {
@@ -46,11 +53,16 @@ func (e *ActivationsVendor) Init(config vendors.VendorConfig, factories []manage
 	for _, m := range e.Managers {
 		if c, ok := m.(*activations.ActivationsManager); ok {
 			e.ActivationsManager = c
+		} else if c, ok := m.(*activations.RecurringActivationsManager); ok {
+			e.RecurringActivationsManager = c
 		}
 	}
 	if e.ActivationsManager == nil {
 		return v1alpha2.NewCOAError(nil, "activations manager is not supplied", v1alpha2.MissingConfig)
 	}
+	if e.RecurringActivationsManager != nil {
+		e.RecurringActivationsManager.ActivationsManager = e.ActivationsManager
+	}
 	return nil
 }
 
@@ -59,13 +71,15 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	if o.Route != "" {
 		route = o.Route
 	}
-	return []v1alpha2.Endpoint{
+	endpoints := []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route + "/registry",
-			Version:    o.Version,
-			Handler:    o.onActivations,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route + "/registry",
+			Version:      o.Version,
+			Handler:      o.onActivations,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.ActivationsManager.GetState),
+			Docs:         registryDocs("activation", model.ActivationState{}, []model.ActivationState{}),
 		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
@@ -74,7 +88,47 @@ func (o *ActivationsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Handler:    o.onStatus,
 			Parameters: []string{"name?"},
 		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/{name}/approve",
+			Version: o.Version,
+			Handler: o.onApprove,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/{name}/reject",
+			Version: o.Version,
+			Handler: o.onReject,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/registry/{name}/cancel",
+			Version: o.Version,
+			Handler: o.onCancel,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/registry/{name}/pause",
+			Version: o.Version,
+			Handler: o.onPause,
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/registry/{name}/resume",
+			Version: o.Version,
+			Handler: o.onResume,
+		},
 	}
+	if o.RecurringActivationsManager != nil {
+		endpoints = append(endpoints, v1alpha2.Endpoint{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route + "/recurring",
+			Version:    o.Version,
+			Handler:    o.onRecurringActivations,
+			Parameters: []string{"name?"},
+		})
+	}
+	return endpoints
 }
 
 func (c *ActivationsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
@@ -143,12 +197,22 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 		id := request.Parameters["__name"]
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			if !namespaceSupplied {
 				namespace = ""
 			}
-			state, err = c.ActivationsManager.ListState(ctx, namespace)
+			if isWatch(request) {
+				return watchResponse(ctx, span, func(ctx context.Context) (<-chan model.WatchEvent, error) {
+					return c.ActivationsManager.WatchState(ctx, namespace)
+				})
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.ActivationsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.ActivationsManager.GetState(ctx, id, namespace)
@@ -163,6 +227,7 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -250,3 +315,260 @@ func (c *ActivationsVendor) onActivations(request v1alpha2.COARequest) v1alpha2.
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *ActivationsVendor) onCancel(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, model.ActivationCancel)
+}
+
+func (c *ActivationsVendor) onPause(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, model.ActivationPause)
+}
+
+func (c *ActivationsVendor) onResume(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onControl(request, model.ActivationResume)
+}
+
+// onControl cancels, pauses or resumes an activation. The request is recorded on the activation
+// right away, and the stage vendor acts on the stage being processed.
+func (c *ActivationsVendor) onControl(request v1alpha2.COARequest, action string) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onControl",
+	})
+	defer span.End()
+
+	vLog.InfofCtx(ctx, "V (Activations Vendor): onControl, method: %s, action: %s", string(request.Method), action)
+
+	namespace, namespaceSupplied := request.Parameters["namespace"]
+	if !namespaceSupplied {
+		namespace = "default"
+	}
+	control := model.ActivationControl{
+		Activation: request.Parameters["__name"],
+		Namespace:  namespace,
+		Action:     action,
+		User:       request.Metadata[v1alpha2.COAUserKey],
+	}
+	err := c.ActivationsManager.ControlActivation(ctx, control)
+	if err != nil {
+		vLog.ErrorfCtx(ctx, "V (Activations Vendor): onControl failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	jData, _ := json.Marshal(control)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.Accepted,
+		Body:        jData,
+		ContentType: "application/json",
+	})
+}
+
+func (c *ActivationsVendor) onApprove(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onApproval(request, model.ApprovalApproved)
+}
+
+func (c *ActivationsVendor) onReject(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	return c.onApproval(request, model.ApprovalRejected)
+}
+
+// onApproval decides on the approval the latest stage of an activation is waiting for. The caller
+// must hold one of the approver roles of the stage. The stage vendor resumes the activation.
+func (c *ActivationsVendor) onApproval(request v1alpha2.COARequest, decision string) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onApproval",
+	})
+	defer span.End()
+
+	vLog.InfofCtx(ctx, "V (Activations Vendor): onApproval, method: %s, decision: %s", string(request.Method), decision)
+
+	namespace, namespaceSupplied := request.Parameters["namespace"]
+	if !namespaceSupplied {
+		namespace = "default"
+	}
+	id := request.Parameters["__name"]
+
+	var body struct {
+		Comment string `json:"comment,omitempty"`
+	}
+	if len(request.Body) > 0 {
+		err := utils2.UnmarshalJson(request.Body, &body)
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): onApproval failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+	}
+
+	activation, err := c.ActivationsManager.GetState(ctx, id, namespace)
+	if err != nil {
+		vLog.ErrorfCtx(ctx, "V (Activations Vendor): onApproval failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	var approvalRequest model.ApprovalRequest
+	var stage string
+	pending := false
+	if activation.Spec != nil && activation.Status != nil && len(activation.Status.StageHistory) > 0 {
+		latest := activation.Status.StageHistory[len(activation.Status.StageHistory)-1]
+		if latest.Status == v1alpha2.Paused {
+			approvalRequest, pending = model.ApprovalRequestFromOutputs(latest.Outputs)
+			stage = latest.Stage
+		}
+	}
+	if !pending {
+		vLog.ErrorfCtx(ctx, "V (Activations Vendor): onApproval failed - activation %s is not waiting for approval", id)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Conflict,
+			Body:  []byte(fmt.Sprintf("activation %s is not waiting for approval", id)),
+		})
+	}
+
+	user := request.Metadata[v1alpha2.COAUserKey]
+	roles := make([]string, 0)
+	if request.Metadata[v1alpha2.COARolesKey] != "" {
+		roles = strings.Split(request.Metadata[v1alpha2.COARolesKey], ",")
+	}
+	if !approvalRequest.CanApprove(roles) {
+		vLog.ErrorfCtx(ctx, "V (Activations Vendor): onApproval failed - user '%s' with roles %v is not an approver of activation %s", user, roles, id)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.Forbidden,
+			Body:  []byte(fmt.Sprintf("approval requires one of the roles %v", approvalRequest.ApproverRoles)),
+		})
+	}
+
+	approvalDecision := model.ApprovalDecision{
+		CampaignVersion: activation.Spec.CampaignVersion,
+		Activation:      id,
+		Stage:           stage,
+		Namespace:       namespace,
+		Decision:        decision,
+		DecidedBy:       user,
+		Comment:         body.Comment,
+	}
+	err = c.Context.Publish("approval", v1alpha2.Event{
+		Body:    approvalDecision,
+		Context: ctx,
+	})
+	if err != nil {
+		vLog.ErrorfCtx(ctx, "V (Activations Vendor): onApproval failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(err.Error()),
+		})
+	}
+	jData, _ := json.Marshal(approvalDecision)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.Accepted,
+		Body:        jData,
+		ContentType: "application/json",
+	})
+}
+
+func (c *ActivationsVendor) onRecurringActivations(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Activations Vendor", request.Context, &map[string]string{
+		"method": "onRecurringActivations",
+	})
+	defer span.End()
+
+	vLog.InfofCtx(pCtx, "V (Activations Vendor): onRecurringActivations, method: %s", string(request.Method))
+
+	namespace, namespaceSupplied := request.Parameters["namespace"]
+	if !namespaceSupplied {
+		namespace = "default"
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onRecurringActivations-GET", pCtx, nil)
+		id := request.Parameters["__name"]
+		var err error
+		var state interface{}
+		var next string
+		isArray := false
+		if id == "" {
+			if !namespaceSupplied {
+				namespace = ""
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.RecurringActivationsManager.ListStatePage(ctx, namespace, options)
+			}
+			isArray = true
+		} else {
+			state, err = c.RecurringActivationsManager.GetState(ctx, id, namespace)
+		}
+		if err != nil {
+			vLog.InfofCtx(ctx, "V (Activations Vendor): onRecurringActivations failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
+		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
+			Body:        jData,
+			ContentType: "application/json",
+		})
+		if request.Parameters["doc-type"] == "yaml" {
+			resp.ContentType = "text/plain"
+		}
+		return resp
+	case fasthttp.MethodPost:
+		ctx, span := observability.StartSpan("onRecurringActivations-POST", pCtx, nil)
+		id := request.Parameters["__name"]
+
+		var recurring model.RecurringActivationState
+		err := utils2.UnmarshalJson(request.Body, &recurring)
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): onRecurringActivations failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		if recurring.ObjectMeta.Namespace == "" {
+			recurring.ObjectMeta.Namespace = namespace
+		}
+		err = c.RecurringActivationsManager.UpsertState(ctx, id, recurring)
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): onRecurringActivations failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	case fasthttp.MethodDelete:
+		ctx, span := observability.StartSpan("onRecurringActivations-DELETE", pCtx, nil)
+		id := request.Parameters["__name"]
+		err := c.RecurringActivationsManager.DeleteState(ctx, id, namespace)
+		if err != nil {
+			vLog.ErrorfCtx(ctx, "V (Activations Vendor): onRecurringActivations failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	vLog.InfoCtx(pCtx, "V (Activations Vendor): onRecurringActivations failed - 405 method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
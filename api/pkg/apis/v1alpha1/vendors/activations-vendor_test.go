@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/activations"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -39,7 +40,93 @@ func TestActivationsEndpoints(t *testing.T) {
 	vendor := createActivationsVendor()
 	vendor.Route = "activations"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 2, len(endpoints))
+	assert.Equal(t, 7, len(endpoints))
+	assert.Equal(t, "activations/{name}/approve", endpoints[2].Route)
+	assert.Equal(t, "activations/{name}/reject", endpoints[3].Route)
+	assert.Equal(t, "activations/registry/{name}/cancel", endpoints[4].Route)
+	assert.Equal(t, "activations/registry/{name}/pause", endpoints[5].Route)
+	assert.Equal(t, "activations/registry/{name}/resume", endpoints[6].Route)
+}
+func createActivationsVendorWithRecurring() ActivationsVendor {
+	vendor := createActivationsVendor()
+	stateProvider := &memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	vendor.RecurringActivationsManager = &activations.RecurringActivationsManager{
+		StateProvider:      stateProvider,
+		ActivationsManager: vendor.ActivationsManager,
+	}
+	return vendor
+}
+func TestRecurringActivationsEndpoints(t *testing.T) {
+	vendor := createActivationsVendorWithRecurring()
+	vendor.Route = "activations"
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 8, len(endpoints))
+	assert.Equal(t, "activations/recurring", endpoints[7].Route)
+}
+func TestActivationsOnRecurringActivations(t *testing.T) {
+	vendor := createActivationsVendorWithRecurring()
+	recurring := model.RecurringActivationState{
+		Spec: &model.RecurringActivationSpec{
+			CampaignVersion: "campaignversion1",
+			Schedule:        "0 2 * * *",
+		},
+	}
+	data, _ := json.Marshal(recurring)
+	resp := vendor.onRecurringActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "nightly",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	resp = vendor.onRecurringActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "nightly",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var state model.RecurringActivationState
+	err := json.Unmarshal(resp.Body, &state)
+	assert.Nil(t, err)
+	assert.Equal(t, "campaignversion1", state.Spec.CampaignVersion)
+	assert.NotEmpty(t, state.Status.NextScheduleTime)
+
+	recurring.Spec.Schedule = "nightly"
+	data, _ = json.Marshal(recurring)
+	resp = vendor.onRecurringActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   data,
+		Parameters: map[string]string{
+			"__name": "invalid",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+
+	resp = vendor.onRecurringActivations(v1alpha2.COARequest{
+		Method: fasthttp.MethodDelete,
+		Parameters: map[string]string{
+			"__name": "nightly",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	resp = vendor.onRecurringActivations(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var list []model.RecurringActivationState
+	err = json.Unmarshal(resp.Body, &list)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list))
 }
 func TestActivationsInfo(t *testing.T) {
 	vendor := createActivationsVendor()
@@ -195,3 +282,159 @@ func TestActivationsWrongMethod(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
 }
+
+func TestActivationsOnApproval(t *testing.T) {
+	vendor := createActivationsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	decisions := make(chan model.ApprovalDecision, 1)
+	vendor.Context.Subscribe("approval", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var decision model.ApprovalDecision
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &decision)
+			assert.Nil(t, err)
+			decisions <- decision
+			return nil
+		},
+	})
+
+	err := vendor.ActivationsManager.UpsertState(context.Background(), "activation1", model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name: "activation1",
+		},
+		Spec: &model.ActivationSpec{
+			CampaignVersion: "campaignversion1",
+		},
+	})
+	assert.Nil(t, err)
+
+	// nothing to approve before the stage pauses
+	resp := vendor.onApprove(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "activation1"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Conflict, resp.State)
+
+	err = vendor.ActivationsManager.ReportStageStatus(context.Background(), "activation1", "default", model.StageStatus{
+		Stage:         "signoff",
+		Status:        v1alpha2.Paused,
+		StatusMessage: v1alpha2.Paused.String(),
+		Outputs: model.ApprovalRequest{
+			ApproverRoles: []string{"release-manager"},
+		}.Outputs(),
+	})
+	assert.Nil(t, err)
+
+	resp = vendor.onApprove(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "activation1"},
+		Metadata: map[string]string{
+			v1alpha2.COAUserKey:  "developer",
+			v1alpha2.COARolesKey: "reader,solutionversion-creator",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+
+	resp = vendor.onReject(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "activation1"},
+		Body:       []byte(`{"comment":"not during the freeze"}`),
+		Metadata: map[string]string{
+			v1alpha2.COAUserKey:  "alice",
+			v1alpha2.COARolesKey: "reader,release-manager",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.Accepted, resp.State)
+
+	select {
+	case decision := <-decisions:
+		assert.Equal(t, "campaignversion1", decision.CampaignVersion)
+		assert.Equal(t, "activation1", decision.Activation)
+		assert.Equal(t, "signoff", decision.Stage)
+		assert.Equal(t, model.ApprovalRejected, decision.Decision)
+		assert.Equal(t, "alice", decision.DecidedBy)
+		assert.Equal(t, "not during the freeze", decision.Comment)
+	case <-time.After(5 * time.Second):
+		t.Fatal("approval event was not published")
+	}
+}
+
+func TestActivationsOnControl(t *testing.T) {
+	vendor := createActivationsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	vendor.ActivationsManager.Context = &contexts.ManagerContext{}
+	vendor.ActivationsManager.Context.Init(vendor.Context, &pubSubProvider)
+	controls := make(chan model.ActivationControl, 3)
+	vendor.Context.Subscribe("activation-control", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var control model.ActivationControl
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &control)
+			assert.Nil(t, err)
+			controls <- control
+			return nil
+		},
+	})
+
+	err := vendor.ActivationsManager.UpsertState(context.Background(), "activation1", model.ActivationState{
+		ObjectMeta: model.ObjectMeta{
+			Name: "activation1",
+		},
+		Spec: &model.ActivationSpec{
+			CampaignVersion: "campaignversion1",
+		},
+	})
+	assert.Nil(t, err)
+	err = vendor.ActivationsManager.ReportStageStatus(context.Background(), "activation1", "default", model.StageStatus{
+		Stage:         "deploy",
+		Status:        v1alpha2.Running,
+		StatusMessage: v1alpha2.Running.String(),
+	})
+	assert.Nil(t, err)
+
+	request := v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "activation1"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "alice"},
+		Context:    context.Background(),
+	}
+	resp := vendor.onResume(request)
+	assert.Equal(t, v1alpha2.Conflict, resp.State)
+	resp = vendor.onPause(request)
+	assert.Equal(t, v1alpha2.Accepted, resp.State)
+	resp = vendor.onPause(request)
+	assert.Equal(t, v1alpha2.Conflict, resp.State)
+	resp = vendor.onCancel(request)
+	assert.Equal(t, v1alpha2.Accepted, resp.State)
+	resp = vendor.onCancel(request)
+	assert.Equal(t, v1alpha2.Conflict, resp.State)
+
+	actions := make([]string, 0)
+	for len(actions) < 2 {
+		select {
+		case control := <-controls:
+			assert.Equal(t, "activation1", control.Activation)
+			assert.Equal(t, "default", control.Namespace)
+			assert.Equal(t, "alice", control.User)
+			actions = append(actions, control.Action)
+		case <-time.After(5 * time.Second):
+			t.Fatal("activation-control event was not published")
+		}
+	}
+	assert.ElementsMatch(t, []string{model.ActivationPause, model.ActivationCancel}, actions)
+
+	state, err := vendor.ActivationsManager.GetState(context.Background(), "activation1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.Status)
+	assert.False(t, state.Status.PauseRequested)
+	assert.Equal(t, v1alpha2.Cancelled, state.Status.StageHistory[0].Status)
+}
@@ -0,0 +1,300 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/apikeys"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/valyala/fasthttp"
+)
+
+// ApiKeysVendor manages API keys, which CI pipelines and agents use as bearer tokens instead
+// of the password of a user. The host passes the keys manager to the JWT middleware, which
+// accepts the keys alongside the tokens of users.
+type ApiKeysVendor struct {
+	vendors.Vendor
+	ApiKeysManager *apikeys.ApiKeysManager
+	// roles that can manage API keys
+	roles []string
+}
+
+func (o *ApiKeysVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "ApiKeys",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *ApiKeysVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	err := e.Vendor.Init(config, factories, providers, pubsubProvider)
+	if err != nil {
+		return err
+	}
+	for _, m := range e.Managers {
+		if c, ok := m.(*apikeys.ApiKeysManager); ok {
+			e.ApiKeysManager = c
+		}
+	}
+	if e.ApiKeysManager == nil {
+		return v1alpha2.NewCOAError(nil, "API keys manager is not supplied", v1alpha2.MissingConfig)
+	}
+	e.roles = []string{"administrator"}
+	if config.Properties != nil && strings.TrimSpace(config.Properties["roles"]) != "" {
+		e.roles = splitList(utils2.ParseProperty(config.Properties["roles"]))
+	}
+	return nil
+}
+
+// GetTokenValidator returns the validator of API keys for the JWT middleware
+func (e *ApiKeysVendor) GetTokenValidator() v1alpha2.ITokenValidator {
+	return e.ApiKeysManager
+}
+
+func (o *ApiKeysVendor) GetEndpoints() []v1alpha2.Endpoint {
+	route := "apikeys"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route,
+			Version:    o.Version,
+			Handler:    o.onApiKeys,
+			Parameters: []string{"name?"},
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Manage API keys",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodGet: {
+						Summary:      "Get an API key, or list the API keys",
+						Response:     apikeys.ApiKey{},
+						ListResponse: []apikeys.ApiKey{},
+					},
+					fasthttp.MethodPost: {
+						Summary:  "Create an API key. The key is only returned once.",
+						Request:  apikeys.ApiKeySpec{},
+						Response: apikeys.IssuedApiKey{},
+					},
+					fasthttp.MethodDelete: {
+						Summary: "Delete an API key",
+					},
+				},
+			},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/{name}/revoke",
+			Version: o.Version,
+			Handler: o.onRevoke,
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Revoke an API key",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodPost: {
+						Summary:  "Revoke an API key",
+						Response: apikeys.ApiKey{},
+					},
+				},
+			},
+		},
+		{
+			Methods: []string{fasthttp.MethodPost},
+			Route:   route + "/{name}/rotate",
+			Version: o.Version,
+			Handler: o.onRotate,
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Rotate an API key",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodPost: {
+						Summary:  "Replace the key of an API key, keeping the old key valid for a grace period",
+						Request:  apikeys.RotateRequest{},
+						Response: apikeys.IssuedApiKey{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *ApiKeysVendor) onApiKeys(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("ApiKeys Vendor", request.Context, &map[string]string{
+		"method": "onApiKeys",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	log.InfofCtx(ctx, "V (ApiKeys): onApiKeys, method: %s, name: %s", request.Method, name)
+
+	if resp, ok := requireAnyRole(ctx, request, c.roles, "managing API keys"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var result interface{}
+		var err error
+		if name == "" {
+			result, err = c.ApiKeysManager.ListApiKeys(ctx)
+		} else {
+			result, err = c.ApiKeysManager.GetApiKey(ctx, name)
+		}
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (ApiKeys): onApiKeys failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		data, _ := json.Marshal(result)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("API key name is required"),
+			})
+		}
+		var spec apikeys.ApiKeySpec
+		err := utils2.UnmarshalJson(request.Body, &spec)
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (ApiKeys): onApiKeys failed to unmarshall request body, error: %+v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		key, err := c.ApiKeysManager.CreateApiKey(ctx, name, spec, request.Metadata[v1alpha2.COAUserKey])
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (ApiKeys): onApiKeys failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, apikeys.TrailType, "create", callerTrail(request, map[string]interface{}{
+			"roles":      key.Roles,
+			"namespaces": key.Namespaces,
+			"expiresAt":  key.ExpiresAt,
+		}))
+		data, _ := json.Marshal(key)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("API key name is required"),
+			})
+		}
+		err := c.ApiKeysManager.DeleteApiKey(ctx, name)
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (ApiKeys): onApiKeys failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, apikeys.TrailType, "delete", callerTrail(request, nil))
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	log.ErrorfCtx(ctx, "V (ApiKeys): onApiKeys failed - method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *ApiKeysVendor) onRevoke(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("ApiKeys Vendor", request.Context, &map[string]string{
+		"method": "onRevoke",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	log.InfofCtx(ctx, "V (ApiKeys): onRevoke, name: %s", name)
+
+	if resp, ok := requireAnyRole(ctx, request, c.roles, "managing API keys"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	key, err := c.ApiKeysManager.RevokeApiKey(ctx, name)
+	if err != nil {
+		log.ErrorfCtx(ctx, "V (ApiKeys): onRevoke failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	publishTrail(ctx, c.Vendor.Context, apikeys.TrailType, "revoke", callerTrail(request, nil))
+	data, _ := json.Marshal(key)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
+
+func (c *ApiKeysVendor) onRotate(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("ApiKeys Vendor", request.Context, &map[string]string{
+		"method": "onRotate",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	log.InfofCtx(ctx, "V (ApiKeys): onRotate, name: %s", name)
+
+	if resp, ok := requireAnyRole(ctx, request, c.roles, "managing API keys"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	var rotate apikeys.RotateRequest
+	if len(request.Body) > 0 {
+		err := utils2.UnmarshalJson(request.Body, &rotate)
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (ApiKeys): onRotate failed to unmarshall request body, error: %+v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+	}
+	key, err := c.ApiKeysManager.RotateApiKey(ctx, name, time.Duration(rotate.GracePeriodSeconds)*time.Second)
+	if err != nil {
+		log.ErrorfCtx(ctx, "V (ApiKeys): onRotate failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	publishTrail(ctx, c.Vendor.Context, apikeys.TrailType, "rotate", callerTrail(request, map[string]interface{}{
+		"gracePeriodSeconds": rotate.GracePeriodSeconds,
+	}))
+	data, _ := json.Marshal(key)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/apikeys"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func initApiKeysVendor(t *testing.T) ApiKeysVendor {
+	p := memorystate.MemoryStateProvider{}
+	p.Init(memorystate.MemoryStateProviderConfig{})
+	vendor := ApiKeysVendor{}
+	err := vendor.Init(vendors.VendorConfig{
+		Managers: []managers.ManagerConfig{
+			{
+				Name: "apikeys-manager",
+				Type: "managers.symphony.apikeys",
+				Properties: map[string]string{
+					"providers.persistentstate": "mem-state",
+				},
+				Providers: map[string]managers.ProviderConfig{
+					"mem-state": {
+						Type:   "providers.state.memory",
+						Config: memorystate.MemoryStateProviderConfig{},
+					},
+				},
+			},
+		},
+	}, []managers.IManagerFactroy{
+		&sym_mgr.SymphonyManagerFactory{},
+	}, map[string]map[string]providers.IProvider{
+		"apikeys-manager": map[string]providers.IProvider{
+			"mem-state": &p,
+		},
+	}, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+func TestApiKeysEndpoints(t *testing.T) {
+	vendor := initApiKeysVendor(t)
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 3, len(endpoints))
+	assert.Equal(t, "apikeys", endpoints[0].Route)
+	assert.Equal(t, "apikeys/{name}/revoke", endpoints[1].Route)
+	assert.Equal(t, "apikeys/{name}/rotate", endpoints[2].Route)
+	assert.Equal(t, vendor.ApiKeysManager, vendor.GetTokenValidator())
+}
+
+func TestApiKeysRequireAdministrator(t *testing.T) {
+	vendor := initApiKeysVendor(t)
+	request := v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "ci"},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "operator"},
+	}
+	assert.Equal(t, v1alpha2.Forbidden, vendor.onApiKeys(request).State)
+	assert.Equal(t, v1alpha2.Forbidden, vendor.onRevoke(request).State)
+	assert.Equal(t, v1alpha2.Forbidden, vendor.onRotate(request).State)
+}
+
+func TestApiKeysLifecycle(t *testing.T) {
+	vendor := initApiKeysVendor(t)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	trails := make(chan v1alpha2.Trail, 10)
+	vendor.Context.Subscribe("trail", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			for _, trail := range event.Body.([]v1alpha2.Trail) {
+				trails <- trail
+			}
+			return nil
+		},
+	})
+
+	data, _ := json.Marshal(apikeys.ApiKeySpec{Roles: []string{"operator"}, Namespaces: []string{"ci"}})
+	response := vendor.onApiKeys(adminRequest(fasthttp.MethodPost, "ci", data))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var key apikeys.IssuedApiKey
+	assert.Nil(t, json.Unmarshal(response.Body, &key))
+	assert.Equal(t, "admin", key.CreatedBy)
+	trail := <-trails
+	assert.Equal(t, apikeys.TrailType, trail.Type)
+	assert.Equal(t, "create", trail.Properties["action"])
+	assert.Equal(t, "ci", trail.Properties["id"])
+	assert.Equal(t, "admin", trail.Properties["user"])
+	assert.NotContains(t, trail.Properties, "key")
+
+	principal, ok, err := vendor.GetTokenValidator().ValidateToken(context.Background(), key.Key)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "apikey:ci", principal.User)
+
+	response = vendor.onApiKeys(adminRequest(fasthttp.MethodPost, "ci", data))
+	assert.Equal(t, v1alpha2.Conflict, response.State)
+
+	// the key is never returned again
+	response = vendor.onApiKeys(adminRequest(fasthttp.MethodGet, "", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.NotContains(t, string(response.Body), key.Key)
+	var list []apikeys.ApiKey
+	assert.Nil(t, json.Unmarshal(response.Body, &list))
+	assert.Equal(t, 1, len(list))
+
+	data, _ = json.Marshal(apikeys.RotateRequest{GracePeriodSeconds: 60})
+	response = vendor.onRotate(adminRequest(fasthttp.MethodPost, "ci", data))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var rotated apikeys.IssuedApiKey
+	assert.Nil(t, json.Unmarshal(response.Body, &rotated))
+	assert.NotEqual(t, key.Key, rotated.Key)
+	trail = <-trails
+	assert.Equal(t, "rotate", trail.Properties["action"])
+
+	response = vendor.onRevoke(adminRequest(fasthttp.MethodPost, "ci", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	trail = <-trails
+	assert.Equal(t, "revoke", trail.Properties["action"])
+	_, _, err = vendor.GetTokenValidator().ValidateToken(context.Background(), rotated.Key)
+	assert.NotNil(t, err)
+
+	response = vendor.onApiKeys(adminRequest(fasthttp.MethodDelete, "ci", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	trail = <-trails
+	assert.Equal(t, "delete", trail.Properties["action"])
+	response = vendor.onApiKeys(adminRequest(fasthttp.MethodGet, "ci", nil))
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"encoding/json"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/valyala/fasthttp"
+)
+
+// AuthVendor lets callers review their permissions under the RBAC policy of the binding, without
+// doing the operations. It only answers for the caller itself.
+type AuthVendor struct {
+	vendors.Vendor
+}
+
+func (o *AuthVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "Auth",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *AuthVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	return e.Vendor.Init(config, factories, providers, pubsubProvider)
+}
+
+func (o *AuthVendor) GetEndpoints() []v1alpha2.Endpoint {
+	route := "auth"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   route + "/can-i",
+			Version: o.Version,
+			Handler: o.onCanI,
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Review the permissions of the caller",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodGet: {
+						Summary:  "Check if the caller can do a verb (query parameter 'verb') on a resource ('resource', 'namespace', 'name'), or list the rules that apply to the caller",
+						Response: v1alpha2.AccessReview{},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *AuthVendor) onCanI(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Auth Vendor", request.Context, &map[string]string{
+		"method": "onCanI",
+	})
+	defer span.End()
+	verb := request.Parameters["verb"]
+	resource := request.Parameters["resource"]
+	log.InfofCtx(ctx, "V (Auth): onCanI, verb: %s, resource: %s", verb, resource)
+
+	if (verb == "") != (resource == "") {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("'verb' and 'resource' must be given together"),
+		})
+	}
+
+	review := v1alpha2.AccessReview{
+		User:  request.Metadata[v1alpha2.COAUserKey],
+		Roles: splitList(request.Metadata[v1alpha2.COARolesKey]),
+	}
+	policy := v1alpha2.AccessPolicyFromContext(request.Context)
+	review.Enforced = policy != nil
+	switch {
+	case policy == nil && verb != "":
+		review.Decision = &v1alpha2.AccessDecision{Allowed: true, Reason: "no RBAC policy is enforced"}
+	case verb != "":
+		decision := policy.Check(v1alpha2.AccessRequest{
+			User:      review.User,
+			Roles:     review.Roles,
+			Verb:      verb,
+			Resource:  resource,
+			Namespace: request.Parameters["namespace"],
+			Name:      request.Parameters["name"],
+		})
+		review.Decision = &decision
+	case policy != nil:
+		review.Rules = policy.RulesFor(review.User, review.Roles)
+	}
+
+	data, _ := json.Marshal(review)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
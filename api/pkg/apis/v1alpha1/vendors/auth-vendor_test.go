@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func initAuthVendor(t *testing.T) AuthVendor {
+	vendor := AuthVendor{}
+	err := vendor.Init(vendors.VendorConfig{}, nil, nil, nil)
+	assert.Nil(t, err)
+	return vendor
+}
+
+func canIRequest(policy *v1alpha2.RBACPolicy, parameters map[string]string) v1alpha2.COARequest {
+	ctx := context.Background()
+	if policy != nil {
+		ctx = context.WithValue(ctx, v1alpha2.COAAccessPolicyKey, policy)
+	}
+	return v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    ctx,
+		Parameters: parameters,
+		Metadata: map[string]string{
+			v1alpha2.COAUserKey:  "alice",
+			v1alpha2.COARolesKey: "operator",
+		},
+	}
+}
+
+func TestAuthVendorEndpoints(t *testing.T) {
+	vendor := initAuthVendor(t)
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, "auth/can-i", endpoints[0].Route)
+	assert.Equal(t, "Auth", vendor.GetInfo().Name)
+}
+
+func TestAuthVendorCanI(t *testing.T) {
+	vendor := initAuthVendor(t)
+	policy := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:   []v1alpha2.RBACSubject{{Role: "operator"}},
+				Verbs:      []string{v1alpha2.VerbGet, v1alpha2.VerbList},
+				Resources:  []string{"instances"},
+				Namespaces: []string{"line-3"},
+			},
+			{
+				Subjects:  []v1alpha2.RBACSubject{{Role: "administrator"}},
+				Verbs:     []string{"*"},
+				Resources: []string{"*"},
+			},
+		},
+	}
+
+	resp := vendor.onCanI(canIRequest(policy, map[string]string{"verb": "get", "resource": "instances", "namespace": "line-3", "name": "i1"}))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var review v1alpha2.AccessReview
+	assert.Nil(t, json.Unmarshal(resp.Body, &review))
+	assert.Equal(t, "alice", review.User)
+	assert.Equal(t, []string{"operator"}, review.Roles)
+	assert.True(t, review.Enforced)
+	assert.True(t, review.Decision.Allowed)
+
+	resp = vendor.onCanI(canIRequest(policy, map[string]string{"verb": "delete", "resource": "instances", "namespace": "line-3"}))
+	review = v1alpha2.AccessReview{}
+	assert.Nil(t, json.Unmarshal(resp.Body, &review))
+	assert.False(t, review.Decision.Allowed)
+	assert.Contains(t, review.Decision.Reason, "no rule allows")
+
+	resp = vendor.onCanI(canIRequest(policy, map[string]string{}))
+	review = v1alpha2.AccessReview{}
+	assert.Nil(t, json.Unmarshal(resp.Body, &review))
+	assert.Nil(t, review.Decision)
+	assert.Equal(t, 1, len(review.Rules))
+	assert.Equal(t, []string{"instances"}, review.Rules[0].Resources)
+
+	resp = vendor.onCanI(canIRequest(policy, map[string]string{"verb": "get"}))
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+
+func TestAuthVendorCanIWithoutPolicy(t *testing.T) {
+	vendor := initAuthVendor(t)
+	resp := vendor.onCanI(canIRequest(nil, map[string]string{"verb": "delete", "resource": "targets"}))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var review v1alpha2.AccessReview
+	assert.Nil(t, json.Unmarshal(resp.Body, &review))
+	assert.False(t, review.Enforced)
+	assert.True(t, review.Decision.Allowed)
+}
@@ -17,6 +17,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/valyala/fasthttp"
@@ -60,11 +61,12 @@ func (o *CampaignsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route,
-			Version:    o.Version,
-			Handler:    o.onCampaigns,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route,
+			Version:      o.Version,
+			Handler:      o.onCampaigns,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.CampaignsManager.GetState),
 		},
 	}
 }
@@ -88,13 +90,18 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 		ctx, span := observability.StartSpan("onCampaigns-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			// Change partition back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				namespace = ""
 			}
-			state, err = c.CampaignsManager.ListState(ctx, namespace)
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.CampaignsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.CampaignsManager.GetState(ctx, id, namespace)
@@ -109,6 +116,7 @@ func (c *CampaignsVendor) onCampaigns(request v1alpha2.COARequest) v1alpha2.COAR
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -16,6 +16,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -60,11 +61,12 @@ func (o *CampaignVersionsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route,
-			Version:    o.Version,
-			Handler:    o.onCampaignVersions,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route,
+			Version:      o.Version,
+			Handler:      o.onCampaignVersions,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.CampaignVersionsManager.GetState),
 		},
 	}
 }
@@ -87,12 +89,17 @@ func (c *CampaignVersionsVendor) onCampaignVersions(request v1alpha2.COARequest)
 		ctx, span := observability.StartSpan("onCampaignVersions-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			if !namespaceSupplied {
 				namespace = ""
 			}
-			state, err = c.CampaignVersionsManager.ListState(ctx, namespace)
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.CampaignVersionsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.CampaignVersionsManager.GetState(ctx, id, namespace)
@@ -107,6 +114,7 @@ func (c *CampaignVersionsVendor) onCampaignVersions(request v1alpha2.COARequest)
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -53,7 +53,7 @@ func createCampaignVersionsVendor() CampaignVersionsVendor {
 			"mem-state": &stateProvider,
 		},
 	}, nil)
-	vendor.CampaignVersionsManager.CampaignVersionValidator = validation.NewCampaignVersionValidator(nil, nil)
+	vendor.CampaignVersionsManager.CampaignVersionValidator = validation.NewCampaignVersionValidator(nil, nil, nil)
 	return vendor
 }
 func TestCampaignVersionsEndpoints(t *testing.T) {
@@ -17,6 +17,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -61,11 +62,12 @@ func (o *CatalogsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route,
-			Version:    o.Version,
-			Handler:    o.onCatalogs,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route,
+			Version:      o.Version,
+			Handler:      o.onCatalogs,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.CatalogsManager.GetState),
 		},
 	}
 }
@@ -89,13 +91,18 @@ func (c *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 		ctx, span := observability.StartSpan("onCatalogs-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			// Change partition back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				namespace = ""
 			}
-			state, err = c.CatalogsManager.ListState(ctx, namespace)
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.CatalogsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.CatalogsManager.GetState(ctx, id, namespace)
@@ -110,6 +117,7 @@ func (c *CatalogsVendor) onCatalogs(request v1alpha2.COARequest) v1alpha2.COARes
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -21,6 +21,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -99,11 +100,13 @@ func (e *CatalogVersionsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route + "/registry",
-			Version:    e.Version,
-			Handler:    e.onCatalogVersions,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route + "/registry",
+			Version:      e.Version,
+			Handler:      e.onCatalogVersions,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(e.CatalogVersionsManager.GetState),
+			Docs:         registryDocs("catalog version", model.CatalogVersionState{}, []model.CatalogVersionState{}, "filterType", "filterValue"),
 		},
 		{
 			Methods: []string{fasthttp.MethodGet},
@@ -307,12 +310,22 @@ func (e *CatalogVersionsVendor) onCatalogVersions(request v1alpha2.COARequest) v
 		ctx, span := observability.StartSpan("onCatalogVersions-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			if !namesapceSupplied {
 				namespace = ""
 			}
-			state, err = e.CatalogVersionsManager.ListState(ctx, namespace, request.Parameters["filterType"], request.Parameters["filterValue"])
+			if isWatch(request) {
+				return watchResponse(ctx, span, func(ctx context.Context) (<-chan model.WatchEvent, error) {
+					return e.CatalogVersionsManager.WatchState(ctx, namespace, request.Parameters["filterType"], request.Parameters["filterValue"])
+				})
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = e.CatalogVersionsManager.ListStatePage(ctx, namespace, request.Parameters["filterType"], request.Parameters["filterValue"], options)
+			}
 			isArray = true
 		} else {
 			state, err = e.CatalogVersionsManager.GetState(ctx, id, namespace)
@@ -334,6 +347,7 @@ func (e *CatalogVersionsVendor) onCatalogVersions(request v1alpha2.COARequest) v
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
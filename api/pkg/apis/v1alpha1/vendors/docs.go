@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+)
+
+// The query parameters read by the object registries, on top of their own
+var registryQuery = []string{"namespace", "doc-type", "path", "watch", "limit", "continue", "sortBy"}
+
+// registryDocs describes an object registry endpoint in the OpenAPI document. Registries get
+// an object by name or list them, and create, update and delete objects by name.
+func registryDocs(kind string, object interface{}, list interface{}, query ...string) *v1alpha2.EndpointDocs {
+	return &v1alpha2.EndpointDocs{
+		Summary: fmt.Sprintf("Manage %ss", kind),
+		Query:   append(append([]string{}, registryQuery...), query...),
+		Operations: map[string]v1alpha2.OperationDocs{
+			fasthttp.MethodGet: {
+				Summary:      fmt.Sprintf("Get a %s, or list the %ss", kind, kind),
+				Response:     object,
+				ListResponse: list,
+			},
+			fasthttp.MethodPost: {
+				Summary: fmt.Sprintf("Create or update a %s", kind),
+				Request: object,
+			},
+			fasthttp.MethodDelete: {
+				Summary: fmt.Sprintf("Delete a %s", kind),
+			},
+		},
+	}
+}
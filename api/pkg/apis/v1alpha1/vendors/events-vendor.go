@@ -0,0 +1,197 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/valyala/fasthttp"
+)
+
+var evLog = logger.NewLogger("coa.runtime")
+
+// events that can't be written to a slow client right away are dropped
+const eventStreamBuffer = 64
+
+// relayedEvent is the data of a server-sent event relayed by the events vendor
+type relayedEvent struct {
+	Topic    string            `json:"topic"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Body     interface{}       `json:"body"`
+}
+
+// EventsVendor relays the events published to selected pub-sub topics to clients as server-sent
+// events, without taking part in their delivery to subscribers.
+//
+// Configuration (vendor properties):
+//   - "topics": comma-separated topics that may be relayed. Other topics are refused.
+//   - "roles": comma-separated roles that callers need one of, "administrator" by default.
+//
+// Callers that are limited to namespaces only get the events of those namespaces, which is the
+// "namespace" metadata of an event, or "default" if the event doesn't have one.
+type EventsVendor struct {
+	vendors.Vendor
+	topics map[string]bool
+	roles  []string
+}
+
+func (o *EventsVendor) GetInfo() vendors.VendorInfo {
+	return vendors.VendorInfo{
+		Version:  o.Vendor.Version,
+		Name:     "Events",
+		Producer: "Microsoft",
+	}
+}
+
+func (e *EventsVendor) Init(config vendors.VendorConfig, factories []managers.IManagerFactroy, providers map[string]map[string]providers.IProvider, pubsubProvider pubsub.IPubSubProvider) error {
+	err := e.Vendor.Init(config, factories, providers, pubsubProvider)
+	if err != nil {
+		return err
+	}
+	e.topics = make(map[string]bool)
+	e.roles = []string{"administrator"}
+	if config.Properties != nil {
+		for _, topic := range splitList(coa_utils.ParseProperty(config.Properties["topics"])) {
+			e.topics[topic] = true
+		}
+		if roles := splitList(coa_utils.ParseProperty(config.Properties["roles"])); len(roles) > 0 {
+			e.roles = roles
+		}
+	}
+	if len(e.topics) == 0 {
+		return v1alpha2.NewCOAError(nil, "events vendor doesn't have any topics in its 'topics' property", v1alpha2.MissingConfig)
+	}
+	return nil
+}
+
+func (o *EventsVendor) GetEndpoints() []v1alpha2.Endpoint {
+	route := "events"
+	if o.Route != "" {
+		route = o.Route
+	}
+	return []v1alpha2.Endpoint{
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   route + "/stream",
+			Version: o.Version,
+			Handler: o.onStream,
+		},
+	}
+}
+
+func (c *EventsVendor) onStream(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Events Vendor", request.Context, &map[string]string{
+		"method": "onStream",
+	})
+	defer span.End()
+	evLog.InfofCtx(pCtx, "V (Events): onStream, topic: %s", request.Parameters["topic"])
+
+	if resp, ok := requireAnyRole(pCtx, request, c.roles, "streaming events"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	namespaces := splitList(request.Metadata[v1alpha2.COANamespacesKey])
+	topics := splitList(request.Parameters["topic"])
+	if len(topics) == 0 {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("the 'topic' parameter is required"),
+		})
+	}
+	for _, topic := range topics {
+		if !c.topics[topic] {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.Forbidden,
+				Body:  []byte(fmt.Sprintf("topic '%s' can't be streamed", topic)),
+			})
+		}
+	}
+	observable, ok := c.Context.PubsubProvider.(pubsub.IObservablePubSubProvider)
+	if !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("the pub-sub provider doesn't support event streams"),
+		})
+	}
+
+	// The stream is written after the handler returns, so the observers outlive the request context
+	streamCtx, cancel := context.WithCancel(context.WithoutCancel(pCtx))
+	events := make(chan relayedEvent, eventStreamBuffer)
+	for _, topic := range topics {
+		topic := topic
+		err := observable.Observe(streamCtx, topic, func(event v1alpha2.Event) {
+			if !v1alpha2.NamespaceAllowed(namespaces, event.Metadata["namespace"]) {
+				return
+			}
+			select {
+			case events <- relayedEvent{Topic: topic, Metadata: event.Metadata, Body: event.Body}:
+			default:
+				evLog.Warnf("V (Events): dropped an event of topic %s to a slow client", topic)
+			}
+		})
+		if err != nil {
+			cancel()
+			evLog.ErrorfCtx(pCtx, "V (Events): onStream failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		ContentType: v1alpha2.SSEContentType,
+		Stream: func(w v1alpha2.StreamWriter) {
+			defer cancel()
+			if v1alpha2.WriteSSEComment(w, "stream opened") != nil {
+				return
+			}
+			ticker := time.NewTicker(watchKeepAlive)
+			defer ticker.Stop()
+			for {
+				select {
+				case event := <-events:
+					data, err := json.Marshal(event)
+					if err != nil {
+						evLog.Errorf("V (Events): failed to serialize an event of topic %s: %+v", event.Topic, err)
+						continue
+					}
+					if v1alpha2.WriteSSEEvent(w, v1alpha2.SSEEvent{Event: event.Topic, Data: data}) != nil {
+						return
+					}
+				case <-ticker.C:
+					if v1alpha2.WriteSSEComment(w, "keep-alive") != nil {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// splitList reads a comma-separated list, skipping empty items
+func splitList(value string) []string {
+	ret := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
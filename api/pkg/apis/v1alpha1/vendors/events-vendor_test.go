@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	mempubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func createEventsVendor(t *testing.T, properties map[string]string) (*EventsVendor, *mempubsub.InMemoryPubSubProvider) {
+	pubSubProvider := &mempubsub.InMemoryPubSubProvider{}
+	err := pubSubProvider.Init(mempubsub.InMemoryPubSubConfig{})
+	assert.Nil(t, err)
+	vendor := &EventsVendor{}
+	err = vendor.Init(vendors.VendorConfig{
+		Type:       "vendors.events",
+		Route:      "events",
+		Properties: properties,
+	}, nil, nil, pubSubProvider)
+	assert.Nil(t, err)
+	return vendor, pubSubProvider
+}
+
+func TestEventsVendorRequiresTopics(t *testing.T) {
+	vendor := &EventsVendor{}
+	err := vendor.Init(vendors.VendorConfig{Type: "vendors.events"}, nil, nil, nil)
+	assert.Equal(t, v1alpha2.MissingConfig, v1alpha2.GetErrorState(err))
+}
+
+func TestEventsVendorGetEndpoints(t *testing.T) {
+	vendor, _ := createEventsVendor(t, map[string]string{"topics": "trail"})
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, "events/stream", endpoints[0].Route)
+	// topics are passed in the query string
+	assert.Equal(t, 0, len(endpoints[0].Parameters))
+}
+
+func TestEventsVendorRefusedTopic(t *testing.T) {
+	vendor, _ := createEventsVendor(t, map[string]string{"topics": "trail, job-report"})
+	resp := vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail,job"},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "administrator"},
+	})
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+
+	resp = vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "administrator"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+
+func TestEventsVendorRequiresRole(t *testing.T) {
+	vendor, _ := createEventsVendor(t, map[string]string{"topics": "trail", "roles": "administrator,operator"})
+	resp := vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "reader", v1alpha2.COARolesKey: "reader"},
+	})
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+
+	resp = vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "admin", v1alpha2.COARolesKey: "reader,operator"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, v1alpha2.SSEContentType, resp.ContentType)
+}
+
+func TestEventsVendorRequiresRoleByDefault(t *testing.T) {
+	vendor, _ := createEventsVendor(t, map[string]string{"topics": "trail"})
+	resp := vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail"},
+	})
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+}
+
+func TestEventsVendorStreamFiltersNamespaces(t *testing.T) {
+	vendor, pubSubProvider := createEventsVendor(t, map[string]string{"topics": "trail"})
+	resp := vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail"},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "administrator", v1alpha2.COANamespacesKey: "ns1"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	recorder := &streamRecorder{}
+	go resp.Stream(recorder)
+	defer recorder.close()
+
+	pubSubProvider.Publish("trail", v1alpha2.Event{Metadata: map[string]string{"namespace": "ns2"}, Body: "ns2-event"})
+	pubSubProvider.Publish("trail", v1alpha2.Event{Body: "default-event"})
+	pubSubProvider.Publish("trail", v1alpha2.Event{Metadata: map[string]string{"namespace": "ns1"}, Body: "ns1-event"})
+	assert.Eventually(t, func() bool {
+		return strings.Contains(recorder.String(), "ns1-event")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, recorder.String(), "ns2-event")
+	assert.NotContains(t, recorder.String(), "default-event")
+}
+
+func TestEventsVendorStream(t *testing.T) {
+	vendor, pubSubProvider := createEventsVendor(t, map[string]string{"topics": "trail,job-report"})
+	resp := vendor.onStream(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"topic": "trail,job-report"},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "administrator"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	recorder := &streamRecorder{}
+	done := make(chan struct{})
+	go func() {
+		resp.Stream(recorder)
+		close(done)
+	}()
+
+	pubSubProvider.Publish("trail", v1alpha2.Event{Body: "trail-event"})
+	pubSubProvider.Publish("job", v1alpha2.Event{Body: "job-event"})
+	pubSubProvider.Publish("job-report", v1alpha2.Event{Body: "report-event"})
+	assert.Eventually(t, func() bool {
+		return strings.Contains(recorder.String(), "report-event")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, recorder.String(), "event: trail\ndata: {\"topic\":\"trail\",\"body\":\"trail-event\"}\n\n")
+	assert.NotContains(t, recorder.String(), "job-event")
+
+	// the observers are removed once the client is gone
+	recorder.close()
+	pubSubProvider.Publish("trail", v1alpha2.Event{Body: "trail-event"})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stream didn't end")
+	}
+}
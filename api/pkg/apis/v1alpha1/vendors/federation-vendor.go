@@ -25,6 +25,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -118,6 +119,50 @@ func (f *FederationVendor) Init(config vendors.VendorConfig, factories []manager
 			return nil
 		},
 	})
+	f.Vendor.Context.Subscribe("activation-control", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var control model.ActivationControl
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &control)
+			if err != nil {
+				return v1alpha2.NewCOAError(nil, "event body is not an activation control", v1alpha2.BadRequest)
+			}
+			// stages of the activation may run on any site, so the request goes to all of them
+			sites, err := f.SitesManager.ListState(ctx)
+			if err != nil {
+				return err
+			}
+			for _, site := range sites {
+				if site.Spec.Name == f.Vendor.Context.SiteInfo.SiteId {
+					continue
+				}
+				fLog.InfofCtx(ctx, "V (Federation): forwarding %s of activation %s to site %s", control.Action, control.Activation, site.Spec.Name)
+				err = f.StagingManager.HandleJobEvent(ctx, v1alpha2.Event{
+					Metadata: map[string]string{
+						"site":       site.Spec.Name,
+						"objectType": "activation-control",
+					},
+					Body: v1alpha2.JobData{
+						Id:     control.Activation,
+						Scope:  control.Namespace,
+						Action: v1alpha2.JobControl,
+						Body:   control,
+					},
+					Context: ctx,
+				})
+				if err != nil {
+					fLog.ErrorfCtx(ctx, "V (Federation): failed to forward %s of activation %s to site %s: %v", control.Action, control.Activation, site.Spec.Name, err)
+					return err
+				}
+			}
+			return nil
+		},
+		Group: "federation",
+	})
 	f.Vendor.Context.Subscribe("report", v1alpha2.EventHandler{
 		Handler: func(topic string, event v1alpha2.Event) error {
 			ctx := context.TODO()
@@ -269,9 +314,14 @@ func (f *FederationVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAR
 		id := request.Parameters["__name"]
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
-			state, err = f.SitesManager.ListState(ctx)
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = f.SitesManager.ListStatePage(ctx, options)
+			}
 			isArray = true
 		} else {
 			state, err = f.SitesManager.GetState(ctx, id)
@@ -293,6 +343,7 @@ func (f *FederationVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAR
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -411,7 +462,7 @@ func (f *FederationVendor) onSync(request v1alpha2.COARequest) v1alpha2.COARespo
 		catalogversions := make([]model.CatalogVersionState, 0)
 		jobs := make([]v1alpha2.JobData, 0)
 		for _, c := range batch {
-			if c.Action == v1alpha2.JobRun { //TODO: I don't really like this
+			if c.Action == v1alpha2.JobRun || c.Action == v1alpha2.JobControl { //TODO: I don't really like this
 				jobs = append(jobs, c)
 			} else {
 				catalogversion, err := f.CatalogVersionsManager.GetState(ctx, c.Id, namespace)
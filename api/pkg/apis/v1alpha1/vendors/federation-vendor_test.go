@@ -431,6 +431,54 @@ func TestFederationOnSyncGet(t *testing.T) {
 
 }
 
+func TestFederationForwardsActivationControl(t *testing.T) {
+	vendor := federationVendorInit()
+	SiteSpec.Name = "test1"
+	b, err := json.Marshal(model.SiteState{
+		Spec: &SiteSpec,
+	})
+	assert.Nil(t, err)
+	response := vendor.onRegistry(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Context: context.Background(),
+		Parameters: map[string]string{
+			"__name": SiteSpec.Name,
+		},
+		Body: b,
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	vendor.Context.PubsubProvider.Publish("activation-control", v1alpha2.Event{
+		Body: model.ActivationControl{
+			Activation: "activation1",
+			Namespace:  "default",
+			Action:     model.ActivationCancel,
+		},
+	})
+	for i := 0; i < 30; i++ {
+		response = vendor.onSync(v1alpha2.COARequest{
+			Method:  fasthttp.MethodGet,
+			Context: context.Background(),
+			Parameters: map[string]string{
+				"__site": SiteSpec.Name,
+				"count":  "1",
+			},
+		})
+		assert.Equal(t, v1alpha2.OK, response.State)
+		var summary model.SyncPackage
+		err = json.Unmarshal(response.Body, &summary)
+		assert.Nil(t, err)
+		if len(summary.Jobs) == 1 {
+			assert.Equal(t, "activation1", summary.Jobs[0].Id)
+			assert.Equal(t, "default", summary.Jobs[0].Scope)
+			assert.Equal(t, v1alpha2.JobControl, summary.Jobs[0].Action)
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	t.Fatal("activation control wasn't forwarded to the site")
+}
+
 // Commented due to race
 // func TestFederationOnTrail(t *testing.T) {
 // 	vendor := federationVendorInit()
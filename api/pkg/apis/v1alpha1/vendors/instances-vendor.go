@@ -7,9 +7,13 @@
 package vendors
 
 import (
+	"context"
+	"encoding/json"
+	"strconv"
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instancehistory"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/instances"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -19,6 +23,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -29,7 +34,8 @@ var iLog = logger.NewLogger("coa.runtime")
 
 type InstancesVendor struct {
 	vendors.Vendor
-	InstancesManager *instances.InstancesManager
+	InstancesManager       *instances.InstancesManager
+	InstanceHistoryManager *instancehistory.InstanceHistoryManager
 }
 
 func (o *InstancesVendor) GetInfo() vendors.VendorInfo {
@@ -49,10 +55,36 @@ func (e *InstancesVendor) Init(config vendors.VendorConfig, factories []managers
 		if c, ok := m.(*instances.InstancesManager); ok {
 			e.InstancesManager = c
 		}
+		if c, ok := m.(*instancehistory.InstanceHistoryManager); ok {
+			e.InstanceHistoryManager = c
+		}
 	}
 	if e.InstancesManager == nil {
 		return v1alpha2.NewCOAError(nil, "instances manager is not supplied", v1alpha2.MissingConfig)
 	}
+	if e.InstanceHistoryManager != nil {
+		// revisions are recorded by the jobs manager once a deployment succeeds, and can't be posted by clients
+		e.Vendor.Context.Subscribe("instance-history", v1alpha2.EventHandler{
+			Handler: func(topic string, event v1alpha2.Event) error {
+				ctx := context.TODO()
+				if event.Context != nil {
+					ctx = event.Context
+				}
+				var spec model.InstanceHistorySpec
+				jData, _ := json.Marshal(event.Body)
+				err := utils2.UnmarshalJson(jData, &spec)
+				if err != nil {
+					iLog.ErrorCtx(ctx, "V (Instances): event body of instance-history event is not an InstanceHistorySpec")
+					return v1alpha2.NewCOAError(nil, "event body is not an instance history", v1alpha2.BadRequest)
+				}
+				_, err = e.InstanceHistoryManager.SaveHistory(ctx, event.Metadata["instance"], event.Metadata["namespace"], spec)
+				if err != nil {
+					iLog.ErrorfCtx(ctx, "V (Instances): failed to save history of instance %s: %s", event.Metadata["instance"], err.Error())
+				}
+				return err
+			},
+		})
+	}
 	return nil
 }
 
@@ -61,15 +93,35 @@ func (o *InstancesVendor) GetEndpoints() []v1alpha2.Endpoint {
 	if o.Route != "" {
 		route = o.Route
 	}
-	return []v1alpha2.Endpoint{
+	endpoints := []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route,
-			Version:    o.Version,
-			Handler:    o.onInstances,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route,
+			Version:      o.Version,
+			Handler:      o.onInstances,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.InstancesManager.GetState),
+			Docs:         registryDocs("instance", model.InstanceState{}, []model.InstanceState{}, "target", "target-selector", "solutionversion", "direct"),
 		},
 	}
+	if o.InstanceHistoryManager != nil {
+		endpoints = append(endpoints,
+			v1alpha2.Endpoint{
+				Methods:    []string{fasthttp.MethodGet},
+				Route:      route + "/{name}/history",
+				Version:    o.Version,
+				Handler:    o.onInstanceHistory,
+				Parameters: []string{"revision?"},
+			},
+			v1alpha2.Endpoint{
+				Methods: []string{fasthttp.MethodPost},
+				Route:   route + "/{name}/history/{revision}/revert",
+				Version: o.Version,
+				Handler: o.onInstanceHistoryRevert,
+			},
+		)
+	}
+	return endpoints
 }
 
 func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAResponse {
@@ -91,13 +143,23 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 		ctx, span := observability.StartSpan("onInstances-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			// Change partition back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				namespace = ""
 			}
-			state, err = c.InstancesManager.ListState(ctx, namespace)
+			if isWatch(request) {
+				return watchResponse(ctx, span, func(ctx context.Context) (<-chan model.WatchEvent, error) {
+					return c.InstancesManager.WatchState(ctx, namespace)
+				})
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.InstancesManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.InstancesManager.GetState(ctx, id, namespace)
@@ -112,6 +174,7 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -223,6 +286,12 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 					Body:  []byte(err.Error()),
 				})
 			}
+			if c.InstanceHistoryManager != nil {
+				err = c.InstanceHistoryManager.DeleteHistory(ctx, id, namespace)
+				if err != nil {
+					iLog.WarnfCtx(ctx, "V (Instances): onInstances failed to delete history of instance %s - %s", id, err.Error())
+				}
+			}
 		}
 		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State: v1alpha2.OK,
@@ -237,3 +306,156 @@ func (c *InstancesVendor) onInstances(request v1alpha2.COARequest) v1alpha2.COAR
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *InstancesVendor) onInstanceHistory(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Instances Vendor", request.Context, &map[string]string{
+		"method": "onInstanceHistory",
+	})
+	defer span.End()
+
+	id := request.Parameters["__name"]
+	namespace, exist := request.Parameters["namespace"]
+	if !exist {
+		namespace = constants.DefaultScope
+	}
+	iLog.InfofCtx(pCtx, "V (Instances): onInstanceHistory, method: %s, instance: %s", request.Method, id)
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		ctx, span := observability.StartSpan("onInstanceHistory-GET", pCtx, nil)
+		var err error
+		var state interface{}
+		isArray := false
+		if request.Parameters["__revision"] == "" {
+			state, err = c.InstanceHistoryManager.ListHistory(ctx, id, namespace)
+			isArray = true
+		} else {
+			var revision int
+			revision, err = strconv.Atoi(request.Parameters["__revision"])
+			if err != nil {
+				err = v1alpha2.NewCOAError(err, "revision must be an integer", v1alpha2.BadRequest)
+			} else {
+				state, err = c.InstanceHistoryManager.GetHistory(ctx, id, revision, namespace)
+			}
+		}
+		if err != nil {
+			iLog.ErrorfCtx(ctx, "V (Instances): onInstanceHistory failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
+		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        jData,
+			ContentType: "application/json",
+		})
+		if request.Parameters["doc-type"] == "yaml" {
+			resp.ContentType = "text/plain"
+		}
+		return resp
+	}
+	iLog.InfoCtx(pCtx, "V (Instances): onInstanceHistory failed - 405 method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *InstancesVendor) onInstanceHistoryRevert(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Instances Vendor", request.Context, &map[string]string{
+		"method": "onInstanceHistoryRevert",
+	})
+	defer span.End()
+
+	id := request.Parameters["__name"]
+	namespace, exist := request.Parameters["namespace"]
+	if !exist {
+		namespace = constants.DefaultScope
+	}
+	iLog.InfofCtx(pCtx, "V (Instances): onInstanceHistoryRevert, method: %s, instance: %s, revision: %s", request.Method, id, request.Parameters["__revision"])
+
+	if request.Method != fasthttp.MethodPost {
+		iLog.InfoCtx(pCtx, "V (Instances): onInstanceHistoryRevert failed - 405 method not allowed")
+		resp := v1alpha2.COAResponse{
+			State:       v1alpha2.MethodNotAllowed,
+			Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+			ContentType: "application/json",
+		}
+		observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+		return resp
+	}
+
+	ctx, span := observability.StartSpan("onInstanceHistoryRevert-POST", pCtx, nil)
+	revision, err := strconv.Atoi(request.Parameters["__revision"])
+	if err != nil {
+		iLog.ErrorfCtx(ctx, "V (Instances): onInstanceHistoryRevert failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("revision must be an integer"),
+		})
+	}
+	deployment, err := c.revertInstance(ctx, id, revision, namespace)
+	if err != nil {
+		iLog.ErrorfCtx(ctx, "V (Instances): onInstanceHistoryRevert failed - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	data, _ := json.Marshal(deployment)
+	err = c.Context.Publish("job", v1alpha2.Event{
+		Metadata: map[string]string{
+			"objectType": "deployment",
+			"namespace":  namespace,
+		},
+		Body: v1alpha2.JobData{
+			Id:     id,
+			Scope:  namespace,
+			Action: v1alpha2.JobUpdate,
+			Data:   data,
+		},
+		Context: ctx,
+	})
+	if err != nil {
+		iLog.ErrorfCtx(ctx, "V (Instances): onInstanceHistoryRevert failed to publish job - %s", err.Error())
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.InternalError,
+			Body:        []byte("{\"result\":\"500 - failed to publish job pubsub event\"}"),
+			ContentType: "application/json",
+		})
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        []byte("{\"result\":\"200 - instance revert job accepted\"}"),
+		ContentType: "application/json",
+	})
+}
+
+// revertInstance restores the instance spec saved in the given revision and returns the deployment
+// that re-applies the solution version and targets captured in that revision.
+func (c *InstancesVendor) revertInstance(ctx context.Context, id string, revision int, namespace string) (model.DeploymentSpec, error) {
+	history, err := c.InstanceHistoryManager.GetHistory(ctx, id, revision, namespace)
+	if err != nil {
+		return model.DeploymentSpec{}, err
+	}
+	instance, err := c.InstancesManager.GetState(ctx, id, namespace)
+	if err != nil {
+		return model.DeploymentSpec{}, err
+	}
+	spec := history.Spec.Instance
+	instance.Spec = &spec
+	err = c.InstancesManager.UpsertState(ctx, id, instance)
+	if err != nil {
+		return model.DeploymentSpec{}, err
+	}
+	instance, err = c.InstancesManager.GetState(ctx, id, namespace)
+	if err != nil {
+		return model.DeploymentSpec{}, err
+	}
+	return utils.CreateSymphonyDeployment(ctx, instance, history.Spec.SolutionVersion, history.Spec.Targets, nil, namespace)
+}
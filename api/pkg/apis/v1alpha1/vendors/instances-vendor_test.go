@@ -7,8 +7,10 @@
 package vendors
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"io"
 	"testing"
 	"time"
 
@@ -208,6 +210,131 @@ func TestInstancesTargetSelector(t *testing.T) {
 	assert.Equal(t, "value1", instance.Spec.Target.Selector["property1"])
 }
 
+type pipeStreamWriter struct {
+	*io.PipeWriter
+}
+
+func (p pipeStreamWriter) Flush() error {
+	return nil
+}
+
+func TestInstancesListPaging(t *testing.T) {
+	vendor := createInstancesVendor()
+	for _, name := range []string{"instance3", "instance1", "instance2"} {
+		err := vendor.InstancesManager.UpsertState(context.Background(), name, model.InstanceState{
+			ObjectMeta: model.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: &model.InstanceSpec{
+				SolutionVersion: "solution1:v1",
+				Target: model.TargetSelector{
+					Name: "target1-v1",
+				},
+			},
+		})
+		assert.Nil(t, err)
+	}
+
+	names := []string{}
+	parameters := map[string]string{
+		"limit": "2",
+	}
+	for {
+		resp := vendor.onInstances(v1alpha2.COARequest{
+			Method:     fasthttp.MethodGet,
+			Parameters: parameters,
+			Context:    context.Background(),
+		})
+		assert.Equal(t, v1alpha2.OK, resp.State)
+		var instances []model.InstanceState
+		err := json.Unmarshal(resp.Body, &instances)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(instances), 2)
+		for _, instance := range instances {
+			names = append(names, instance.ObjectMeta.Name)
+		}
+		next, ok := resp.Metadata["continue"]
+		if !ok {
+			break
+		}
+		parameters["continue"] = next
+	}
+	assert.Equal(t, []string{"instance1", "instance2", "instance3"}, names)
+
+	resp := vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"limit": "-1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+
+func TestInstancesWatch(t *testing.T) {
+	vendor := createInstancesVendor()
+	instance := model.InstanceState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "instance1-v1",
+			Namespace: "default",
+		},
+		Spec: &model.InstanceSpec{
+			SolutionVersion: "solution1:v1",
+			Target: model.TargetSelector{
+				Name: "target1-v1",
+			},
+		},
+	}
+	err := vendor.InstancesManager.UpsertState(context.Background(), "instance1-v1", instance)
+	assert.Nil(t, err)
+
+	resp := vendor.onInstances(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"watch": "true",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.NotNil(t, resp.Stream)
+
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		resp.Stream(pipeStreamWriter{writer})
+		close(done)
+	}()
+	scanner := bufio.NewScanner(reader)
+
+	var event model.WatchEvent
+	assert.True(t, scanner.Scan())
+	err = json.Unmarshal(scanner.Bytes(), &event)
+	assert.Nil(t, err)
+	assert.Equal(t, "ADDED", event.Type)
+	assert.Equal(t, "1", event.ResourceVersion)
+	assert.Equal(t, "instance1-v1", event.Object.(map[string]interface{})["metadata"].(map[string]interface{})["name"])
+
+	instance.Spec.SolutionVersion = "solution1:v2"
+	err = vendor.InstancesManager.UpsertState(context.Background(), "instance1-v1", instance)
+	assert.Nil(t, err)
+	assert.True(t, scanner.Scan())
+	err = json.Unmarshal(scanner.Bytes(), &event)
+	assert.Nil(t, err)
+	assert.Equal(t, "MODIFIED", event.Type)
+	assert.Equal(t, "solution1:v2", event.Object.(map[string]interface{})["spec"].(map[string]interface{})["solutionversion"])
+
+	// the stream ends once the client is gone
+	reader.Close()
+	err = vendor.InstancesManager.DeleteState(context.Background(), "instance1-v1", "default")
+	assert.Nil(t, err)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stream didn't stop")
+	}
+}
+
 func TestInstancesWrongMethod(t *testing.T) {
 	vendor := createInstancesVendor()
 	resp := vendor.onInstances(v1alpha2.COARequest{
@@ -216,3 +343,228 @@ func TestInstancesWrongMethod(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
 }
+
+func createInstancesVendorWithHistory() InstancesVendor {
+	stateProvider := memorystate.MemoryStateProvider{}
+	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	historyStateProvider := memorystate.MemoryStateProvider{}
+	historyStateProvider.Init(memorystate.MemoryStateProviderConfig{})
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor := InstancesVendor{}
+	vendor.Init(vendors.VendorConfig{
+		Properties: map[string]string{
+			"test": "true",
+		},
+		Managers: []managers.ManagerConfig{
+			{
+				Name: "instances-manager",
+				Type: "managers.symphony.instances",
+				Properties: map[string]string{
+					"providers.persistentstate": "mem-state",
+				},
+				Providers: map[string]managers.ProviderConfig{
+					"mem-state": {
+						Type:   "providers.state.memory",
+						Config: memorystate.MemoryStateProviderConfig{},
+					},
+				},
+			},
+			{
+				Name: "instance-history-manager",
+				Type: "managers.symphony.instancehistory",
+				Properties: map[string]string{
+					"providers.persistentstate": "mem-state",
+				},
+				Providers: map[string]managers.ProviderConfig{
+					"mem-state": {
+						Type:   "providers.state.memory",
+						Config: memorystate.MemoryStateProviderConfig{},
+					},
+				},
+			},
+		},
+	}, []managers.IManagerFactroy{
+		&sym_mgr.SymphonyManagerFactory{},
+	}, map[string]map[string]providers.IProvider{
+		"instances-manager": {
+			"mem-state": &stateProvider,
+		},
+		"instance-history-manager": {
+			"mem-state": &historyStateProvider,
+		},
+	}, &pubSubProvider)
+	vendor.InstancesManager.InstanceValidator = validation.NewInstanceValidator(nil, nil, nil)
+	return vendor
+}
+
+func TestInstancesHistoryEndpoints(t *testing.T) {
+	vendor := createInstancesVendorWithHistory()
+	vendor.Route = "instances"
+	endpoints := vendor.GetEndpoints()
+	assert.Equal(t, 3, len(endpoints))
+	assert.Equal(t, "instances/{name}/history", endpoints[1].Route)
+	assert.Equal(t, "instances/{name}/history/{revision}/revert", endpoints[2].Route)
+}
+
+func TestInstancesOnInstanceHistory(t *testing.T) {
+	vendor := createInstancesVendorWithHistory()
+
+	for _, solutionVersion := range []string{"solution1-v-v1", "solution1-v-v2"} {
+		err := vendor.Context.Publish("instance-history", v1alpha2.Event{
+			Metadata: map[string]string{
+				"instance":  "instance1",
+				"namespace": "default",
+			},
+			Body: model.InstanceHistorySpec{
+				Instance: model.InstanceSpec{
+					SolutionVersion: solutionVersion,
+				},
+				SolutionVersion: model.SolutionVersionState{
+					ObjectMeta: model.ObjectMeta{
+						Name: solutionVersion,
+					},
+					Spec: &model.SolutionVersionSpec{},
+				},
+			},
+			Context: context.Background(),
+		})
+		assert.Nil(t, err)
+		// wait for the revision to be saved, so that revisions are numbered in order
+		for i := 0; i < 50; i++ {
+			history, _ := vendor.InstanceHistoryManager.ListHistory(context.Background(), "instance1", "default")
+			if len(history) > 0 && history[len(history)-1].Spec.Instance.SolutionVersion == solutionVersion {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	resp := vendor.onInstanceHistory(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name": "instance1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
+
+	resp = vendor.onInstanceHistory(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name": "instance1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var histories []model.InstanceHistoryState
+	err := json.Unmarshal(resp.Body, &histories)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(histories))
+
+	resp = vendor.onInstanceHistory(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name":     "instance1",
+			"__revision": "1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var history model.InstanceHistoryState
+	err = json.Unmarshal(resp.Body, &history)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, history.Spec.Revision)
+	assert.Equal(t, "solution1-v-v1", history.Spec.Instance.SolutionVersion)
+
+	resp = vendor.onInstanceHistory(v1alpha2.COARequest{
+		Method: fasthttp.MethodGet,
+		Parameters: map[string]string{
+			"__name":     "instance1",
+			"__revision": "abc",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+}
+
+func TestInstancesOnInstanceHistoryRevert(t *testing.T) {
+	vendor := createInstancesVendorWithHistory()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+
+	sig := make(chan v1alpha2.JobData)
+	vendor.Context.Subscribe("job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			assert.Equal(t, "deployment", event.Metadata["objectType"])
+			var job v1alpha2.JobData
+			jData, _ := json.Marshal(event.Body)
+			err := json.Unmarshal(jData, &job)
+			assert.Nil(t, err)
+			sig <- job
+			return nil
+		},
+	})
+
+	err := vendor.InstancesManager.UpsertState(context.Background(), "instance1", model.InstanceState{
+		ObjectMeta: model.ObjectMeta{
+			Name:      "instance1",
+			Namespace: "default",
+		},
+		Spec: &model.InstanceSpec{
+			SolutionVersion: "solution1:v2",
+			Target: model.TargetSelector{
+				Name: "target1",
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = vendor.InstanceHistoryManager.SaveHistory(context.Background(), "instance1", "default", model.InstanceHistorySpec{
+		Instance: model.InstanceSpec{
+			SolutionVersion: "solution1:v1",
+			Target: model.TargetSelector{
+				Name: "target1",
+			},
+		},
+		SolutionVersion: model.SolutionVersionState{
+			ObjectMeta: model.ObjectMeta{
+				Name: "solution1-v-v1",
+			},
+			Spec: &model.SolutionVersionSpec{},
+		},
+	})
+	assert.Nil(t, err)
+
+	resp := vendor.onInstanceHistoryRevert(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name":     "instance1",
+			"__revision": "1",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	job := <-sig
+	assert.Equal(t, "instance1", job.Id)
+	assert.Equal(t, v1alpha2.JobUpdate, job.Action)
+	var deployment model.DeploymentSpec
+	err = json.Unmarshal(job.Data, &deployment)
+	assert.Nil(t, err)
+	assert.Equal(t, "solution1-v-v1", deployment.SolutionVersionName)
+
+	instance, err := vendor.InstancesManager.GetState(context.Background(), "instance1", "default")
+	assert.Nil(t, err)
+	assert.Equal(t, "solution1:v1", instance.Spec.SolutionVersion)
+
+	resp = vendor.onInstanceHistoryRevert(v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Parameters: map[string]string{
+			"__name":     "instance1",
+			"__revision": "5",
+		},
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+}
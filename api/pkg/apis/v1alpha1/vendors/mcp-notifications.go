@@ -0,0 +1,448 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+const (
+	mcpResourceScheme = "symphony://"
+	// notifications that can't be delivered to a slow stream right away are dropped
+	mcpStreamBuffer = 64
+)
+
+var (
+	// An instance deployment started by create_object with 'wait' is polled this often
+	mcpDeploymentPollInterval = 2 * time.Second
+	// and given up on after this long
+	mcpDeploymentTimeout = 10 * time.Minute
+)
+
+// watchableObjectTypes are the object types whose list endpoints support watch=true, so their
+// resources can be subscribed to
+var watchableObjectTypes = map[string]bool{
+	"targets":          true,
+	"solutionversions": true,
+	"instances":        true,
+	"catalogversions":  true,
+	"activations":      true,
+}
+
+type jsonRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// mcpNotificationHub routes server-initiated notifications to the SSE streams a caller has open,
+// and keeps the resource subscriptions of each caller. Subscriptions end when the caller's last
+// stream closes.
+type mcpNotificationHub struct {
+	lock          sync.Mutex
+	streams       map[string]map[int]chan []byte
+	nextStreamID  int
+	subscriptions map[string]map[string]context.CancelFunc
+}
+
+func newMCPNotificationHub() *mcpNotificationHub {
+	return &mcpNotificationHub{
+		streams:       make(map[string]map[int]chan []byte),
+		subscriptions: make(map[string]map[string]context.CancelFunc),
+	}
+}
+
+func (h *mcpNotificationHub) open(caller string) (int, <-chan []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.streams[caller]; !ok {
+		h.streams[caller] = make(map[int]chan []byte)
+	}
+	id := h.nextStreamID
+	h.nextStreamID++
+	ch := make(chan []byte, mcpStreamBuffer)
+	h.streams[caller][id] = ch
+	return id, ch
+}
+
+func (h *mcpNotificationHub) close(caller string, id int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.streams[caller], id)
+	if len(h.streams[caller]) > 0 {
+		return
+	}
+	delete(h.streams, caller)
+	for _, cancel := range h.subscriptions[caller] {
+		cancel()
+	}
+	delete(h.subscriptions, caller)
+}
+
+func (h *mcpNotificationHub) notify(caller string, method string, params interface{}) {
+	data, err := json.Marshal(jsonRPCNotification{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, ch := range h.streams[caller] {
+		select {
+		case ch <- data:
+		default:
+			mcpLog.Warnf("V (MCP): dropped notification %s to a slow stream", method)
+		}
+	}
+}
+
+// subscribe records a subscription and returns false if the caller is already subscribed to the uri
+func (h *mcpNotificationHub) subscribe(caller string, uri string, cancel context.CancelFunc) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.subscriptions[caller]; !ok {
+		h.subscriptions[caller] = make(map[string]context.CancelFunc)
+	}
+	if _, ok := h.subscriptions[caller][uri]; ok {
+		return false
+	}
+	h.subscriptions[caller][uri] = cancel
+	return true
+}
+
+func (h *mcpNotificationHub) unsubscribe(caller string, uri string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if cancel, ok := h.subscriptions[caller][uri]; ok {
+		cancel()
+		delete(h.subscriptions[caller], uri)
+	}
+}
+
+func (h *mcpNotificationHub) hasStream(caller string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.streams[caller]) > 0
+}
+
+// mcpCaller identifies the caller whose streams receive notifications: the bearer token of the
+// request, narrowed to the MCP session if the client names one. Sessions are scoped to the token, so
+// a session id alone never reaches the streams of another credential, and clients sharing a user but
+// not a token or session don't receive each other's notifications.
+func mcpCaller(request v1alpha2.COARequest) string {
+	if request.Metadata == nil {
+		return ""
+	}
+	token := request.Metadata["Authorization"]
+	if token == "" {
+		return ""
+	}
+	if session := request.Metadata[v1alpha2.MCPSessionHeader]; session != "" {
+		return "token:" + token + "|session:" + session
+	}
+	return "token:" + token
+}
+
+// openStream streams the notifications of the caller as SSE events until the client disconnects
+func (c *MCPVendor) openStream(caller string) v1alpha2.COAResponse {
+	return v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		ContentType: v1alpha2.SSEContentType,
+		Stream: func(w v1alpha2.StreamWriter) {
+			id, notifications := c.notifications.open(caller)
+			defer c.notifications.close(caller, id)
+			if v1alpha2.WriteSSEComment(w, "stream opened") != nil {
+				return
+			}
+			ticker := time.NewTicker(watchKeepAlive)
+			defer ticker.Stop()
+			for {
+				select {
+				case data := <-notifications:
+					if v1alpha2.WriteSSEEvent(w, v1alpha2.SSEEvent{Event: "message", Data: data}) != nil {
+						return
+					}
+				case <-ticker.C:
+					if v1alpha2.WriteSSEComment(w, "keep-alive") != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+}
+
+// ---- resources ----
+
+// parseResourceURI reads a symphony://{objectType}/{namespace}/{name} resource URI
+func parseResourceURI(uri string) (string, string, string, error) {
+	if !strings.HasPrefix(uri, mcpResourceScheme) {
+		return "", "", "", fmt.Errorf("resource URI '%s' doesn't start with %s", uri, mcpResourceScheme)
+	}
+	parts := strings.Split(strings.TrimPrefix(uri, mcpResourceScheme), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("resource URI '%s' isn't of the form %s{objectType}/{namespace}/{name}", uri, mcpResourceScheme)
+	}
+	if _, ok := objectRoutes[parts[0]]; !ok {
+		return "", "", "", fmt.Errorf("unsupported object type '%s'", parts[0])
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+func resourceParams(rpcReq jsonRPCRequest) (string, error) {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(rpcReq.Params, &params); err != nil {
+		return "", err
+	}
+	if params.URI == "" {
+		return "", fmt.Errorf("'uri' is required")
+	}
+	return params.URI, nil
+}
+
+func resourceTemplates() []map[string]interface{} {
+	return []map[string]interface{}{
+		{
+			"uriTemplate": mcpResourceScheme + "{objectType}/{namespace}/{name}",
+			"name":        "Symphony object",
+			"description": "A Symphony object of a given type. Targets, solution versions, instances, catalog versions and activations can be subscribed to.",
+			"mimeType":    "application/json",
+		},
+	}
+}
+
+func (c *MCPVendor) handleResourceRead(ctx context.Context, rpcReq jsonRPCRequest, authToken string) v1alpha2.COAResponse {
+	uri, err := resourceParams(rpcReq)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, "invalid resource parameters", err.Error())
+	}
+	objectType, namespace, name, err := parseResourceURI(uri)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, err.Error(), nil)
+	}
+	if err := checkAccess(ctx, v1alpha2.VerbGet, objectType, namespace, name, nil); err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidRequest, err.Error(), nil)
+	}
+	body, err := c.callAPI(ctx, http.MethodGet, objectRoutes[objectType]+"/"+name, map[string]string{"namespace": namespace}, nil, authToken)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInternalError, "failed to read resource", err.Error())
+	}
+	return rpcResultResponse(rpcReq.ID, map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{"uri": uri, "mimeType": "application/json", "text": string(body)},
+		},
+	})
+}
+
+// handleResourceSubscribe watches the object type in the namespace of the resource on behalf of the
+// caller and sends notifications/resources/updated to the caller's streams when the resource changes
+func (c *MCPVendor) handleResourceSubscribe(ctx context.Context, rpcReq jsonRPCRequest, caller string, authToken string) v1alpha2.COAResponse {
+	uri, err := resourceParams(rpcReq)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, "invalid resource parameters", err.Error())
+	}
+	objectType, namespace, name, err := parseResourceURI(uri)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, err.Error(), nil)
+	}
+	if err := checkAccess(ctx, v1alpha2.VerbGet, objectType, namespace, name, nil); err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidRequest, err.Error(), nil)
+	}
+	if !watchableObjectTypes[objectType] {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, fmt.Sprintf("resources of type '%s' can't be subscribed to", objectType), nil)
+	}
+	if !c.notifications.hasStream(caller) {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidRequest, "open a notification stream with GET before subscribing to resources", nil)
+	}
+	// The watch outlives the request, so it's bound to the subscription instead
+	watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	resp, err := c.openWatch(watchCtx, objectRoutes[objectType], namespace, authToken)
+	if err != nil {
+		cancel()
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInternalError, "failed to watch resource", err.Error())
+	}
+	if !c.notifications.subscribe(caller, uri, cancel) {
+		cancel()
+		resp.Body.Close()
+		return rpcResultResponse(rpcReq.ID, map[string]interface{}{})
+	}
+	go func() {
+		defer resp.Body.Close()
+		defer c.notifications.unsubscribe(caller, uri)
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var event struct {
+				Object struct {
+					ObjectMeta model.ObjectMeta `json:"metadata"`
+				} `json:"object"`
+			}
+			if err := json.Unmarshal(line, &event); err != nil {
+				continue
+			}
+			if event.Object.ObjectMeta.Name == name {
+				c.notifications.notify(caller, "notifications/resources/updated", map[string]interface{}{"uri": uri})
+			}
+		}
+		if watchCtx.Err() == nil {
+			mcpLog.Infof("V (MCP): watch of resource %s ended", uri)
+		}
+	}()
+	return rpcResultResponse(rpcReq.ID, map[string]interface{}{})
+}
+
+func (c *MCPVendor) handleResourceUnsubscribe(rpcReq jsonRPCRequest, caller string) v1alpha2.COAResponse {
+	uri, err := resourceParams(rpcReq)
+	if err != nil {
+		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, "invalid resource parameters", err.Error())
+	}
+	c.notifications.unsubscribe(caller, uri)
+	return rpcResultResponse(rpcReq.ID, map[string]interface{}{})
+}
+
+// openWatch calls a list endpoint with watch=true and returns the response whose body streams the changes
+func (c *MCPVendor) openWatch(ctx context.Context, route string, namespace string, authToken string) (*http.Response, error) {
+	if c.apiBaseUrl == "" {
+		return nil, fmt.Errorf("the MCP vendor is not configured with a Symphony API base URL")
+	}
+	if authToken == "" {
+		return nil, fmt.Errorf("resource subscriptions require an authenticated caller")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(c.apiBaseUrl, "/")+route, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", authToken)
+	query := req.URL.Query()
+	query.Add("watch", "true")
+	query.Add("namespace", namespace)
+	req.URL.RawQuery = query.Encode()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Symphony API returned [%d]", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// ---- progress ----
+
+// progressFunc reports the progress of a long-running tool call
+type progressFunc func(progress int, total int, message string)
+
+// waitsForDeployment tells whether a tool call creates an instance and asks to wait for its deployment
+func waitsForDeployment(name string, args map[string]interface{}) bool {
+	wait, _ := args["wait"].(bool)
+	return wait && name == "create_object" && argString(args, "objectType") == "instances"
+}
+
+// waitForDeployment polls the deployment summary of an instance until the current generation of the
+// instance is reconciled, reporting the deployed targets as progress
+func (c *MCPVendor) waitForDeployment(ctx context.Context, args map[string]interface{}, authToken string, progress progressFunc) (string, error) {
+	name := argString(args, "name")
+	namespace := argString(args, "namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+	body, err := c.callAPI(ctx, http.MethodGet, objectRoutes["instances"]+"/"+name, map[string]string{"namespace": namespace}, nil, authToken)
+	if err != nil {
+		return "", err
+	}
+	var instance model.InstanceState
+	if err := json.Unmarshal(body, &instance); err != nil {
+		return "", fmt.Errorf("failed to read instance '%s': %v", name, err)
+	}
+	params := map[string]string{
+		"instance":  instance.ObjectMeta.GetSummaryId(),
+		"name":      name,
+		"namespace": namespace,
+	}
+	deadline := time.Now().Add(mcpDeploymentTimeout)
+	lastMessage := ""
+	for {
+		var summary model.SummaryResult
+		// the summary isn't there until the first reconcile starts
+		if body, err := c.callAPI(ctx, http.MethodGet, "/solutionversion/queue", params, nil, authToken); err == nil && json.Unmarshal(body, &summary) == nil {
+			if summary.Generation == instance.ObjectMeta.ETag {
+				if summary.State == model.SummaryStateDone {
+					if !summary.Summary.AllAssignedDeployed {
+						return "", fmt.Errorf("deployment of instance '%s' failed: %s", name, summary.Summary.GenerateStatusMessage())
+					}
+					progress(summary.Summary.TargetCount, summary.Summary.TargetCount, "deployed")
+					return fmt.Sprintf("Created/updated '%s' and deployed it to %d targets", name, summary.Summary.TargetCount), nil
+				}
+				message := fmt.Sprintf("deployed %d of %d components", summary.Summary.CurrentDeployed, summary.Summary.PlannedDeployment)
+				if message != lastMessage {
+					progress(summary.Summary.CurrentDeployed, summary.Summary.PlannedDeployment, message)
+					lastMessage = message
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("instance '%s' was created/updated but its deployment didn't finish in %s", name, mcpDeploymentTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(mcpDeploymentPollInterval):
+		}
+	}
+}
+
+// streamToolCall runs a tool call whose request carries a progress token. The response is an SSE
+// stream of notifications/progress messages that ends with the response to the call.
+func (c *MCPVendor) streamToolCall(ctx context.Context, rpcReq jsonRPCRequest, name string, args map[string]interface{}, authToken string, progressToken json.RawMessage) v1alpha2.COAResponse {
+	// The stream is written after the handler returns, so the call outlives the request context
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		ContentType: v1alpha2.SSEContentType,
+		Stream: func(w v1alpha2.StreamWriter) {
+			defer cancel()
+			progress := func(progress int, total int, message string) {
+				data, _ := json.Marshal(jsonRPCNotification{
+					JSONRPC: jsonRPCVersion,
+					Method:  "notifications/progress",
+					Params: map[string]interface{}{
+						"progressToken": progressToken,
+						"progress":      progress,
+						"total":         total,
+						"message":       message,
+					},
+				})
+				if v1alpha2.WriteSSEEvent(w, v1alpha2.SSEEvent{Event: "message", Data: data}) != nil {
+					// the client is gone
+					cancel()
+				}
+			}
+			resp := c.callTool(callCtx, rpcReq, name, args, authToken, progress)
+			v1alpha2.WriteSSEEvent(w, v1alpha2.SSEEvent{Event: "message", Data: resp.Body})
+		},
+	}
+}
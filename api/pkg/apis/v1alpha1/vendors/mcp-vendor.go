@@ -16,6 +16,7 @@ import (
 	"strings"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
@@ -24,6 +25,7 @@ import (
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
@@ -60,17 +62,28 @@ var objectRoutes = map[string]string{
 // transport is a single JSON-RPC endpoint (POST /<version>/mcp). Tools are
 // backed by calls to the Symphony REST API.
 //
+// GET /<version>/mcp opens an SSE stream of server notifications for the caller:
+// notifications/resources/updated for the resources it subscribed to. Streams
+// and subscriptions belong to the bearer token of the caller and, if the client
+// sends the Mcp-Session-Id that initialize returns, to that session. Tool
+// calls that carry a progress token are answered with an SSE stream of
+// notifications/progress messages that ends with the result.
+//
 // Tool calls are executed on behalf of the authenticated caller: the vendor
 // forwards the caller's bearer token to the underlying REST API so every tool
 // operation is subject to the same authentication and RBAC as a direct API
-// call. The vendor never uses ambient/stored credentials.
+// call. The vendor never uses ambient/stored credentials. When the binding
+// enforces an RBAC policy, tool calls and resource reads are also checked
+// against it before the API is called, so denied operations fail with the
+// reason of the policy.
 //
 // Configuration (vendor properties, optional):
 //   - "baseUrl": base URL of the Symphony API the tools call. Defaults to the
 //     site's current base URL.
 type MCPVendor struct {
 	vendors.Vendor
-	apiBaseUrl string
+	apiBaseUrl    string
+	notifications *mcpNotificationHub
 }
 
 func (o *MCPVendor) GetInfo() vendors.VendorInfo {
@@ -88,6 +101,7 @@ func (e *MCPVendor) Init(config vendors.VendorConfig, factories []managers.IMana
 	}
 
 	e.apiBaseUrl = e.Context.SiteInfo.CurrentSite.BaseUrl
+	e.notifications = newMCPNotificationHub()
 	if config.Properties != nil {
 		if v, ok := config.Properties["baseUrl"]; ok && v != "" {
 			e.apiBaseUrl = coa_utils.ParseProperty(v)
@@ -140,14 +154,21 @@ func (c *MCPVendor) onMCP(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	defer span.End()
 	mcpLog.InfofCtx(pCtx, "V (MCP): onMCP, method: %s", request.Method)
 
+	// Notifications are only sent to the caller they concern, so streams and
+	// subscriptions are bound to the credential and session of the caller.
+	caller := mcpCaller(request)
+
 	// The Streamable HTTP transport uses GET to open a server-to-client SSE
-	// stream. This server does not push server-initiated messages, so decline.
+	// stream.
 	if request.Method == fasthttp.MethodGet {
-		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-			State:       v1alpha2.MethodNotAllowed,
-			Body:        []byte("SSE stream is not supported by this server"),
-			ContentType: "text/plain",
-		})
+		if caller == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State:       v1alpha2.Unauthorized,
+				Body:        []byte("notification streams require an authenticated caller"),
+				ContentType: "text/plain",
+			})
+		}
+		return observ_utils.CloseSpanWithCOAResponse(span, c.openStream(caller))
 	}
 
 	var rpcReq jsonRPCRequest
@@ -160,6 +181,11 @@ func (c *MCPVendor) onMCP(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	authToken := ""
 	if request.Metadata != nil {
 		authToken = request.Metadata["Authorization"]
+		pCtx = context.WithValue(pCtx, mcpIdentityKey{}, mcpIdentity{
+			user:       request.Metadata[v1alpha2.COAUserKey],
+			roles:      splitList(request.Metadata[v1alpha2.COARolesKey]),
+			namespaces: splitList(request.Metadata[v1alpha2.COANamespacesKey]),
+		})
 	}
 
 	// Notifications (no id) require no response body.
@@ -179,6 +205,17 @@ func (c *MCPVendor) onMCP(request v1alpha2.COARequest) v1alpha2.COAResponse {
 		return observ_utils.CloseSpanWithCOAResponse(span, rpcResultResponse(rpcReq.ID, map[string]interface{}{"tools": toolDefinitions()}))
 	case "tools/call":
 		return observ_utils.CloseSpanWithCOAResponse(span, c.handleToolCall(pCtx, rpcReq, authToken))
+	case "resources/list":
+		// objects are reached through the resource template rather than listed
+		return observ_utils.CloseSpanWithCOAResponse(span, rpcResultResponse(rpcReq.ID, map[string]interface{}{"resources": []interface{}{}}))
+	case "resources/templates/list":
+		return observ_utils.CloseSpanWithCOAResponse(span, rpcResultResponse(rpcReq.ID, map[string]interface{}{"resourceTemplates": resourceTemplates()}))
+	case "resources/read":
+		return observ_utils.CloseSpanWithCOAResponse(span, c.handleResourceRead(pCtx, rpcReq, authToken))
+	case "resources/subscribe":
+		return observ_utils.CloseSpanWithCOAResponse(span, c.handleResourceSubscribe(pCtx, rpcReq, caller, authToken))
+	case "resources/unsubscribe":
+		return observ_utils.CloseSpanWithCOAResponse(span, c.handleResourceUnsubscribe(rpcReq, caller))
 	default:
 		return observ_utils.CloseSpanWithCOAResponse(span, rpcErrorResponse(rpcReq.ID, jsonRPCMethodNotFound, fmt.Sprintf("method '%s' is not supported", rpcReq.Method), nil))
 	}
@@ -192,22 +229,32 @@ func (c *MCPVendor) handleInitialize(rpcReq jsonRPCRequest) v1alpha2.COAResponse
 	if err := json.Unmarshal(rpcReq.Params, &params); err == nil && params.ProtocolVersion != "" {
 		protocolVersion = params.ProtocolVersion
 	}
-	return rpcResultResponse(rpcReq.ID, map[string]interface{}{
+	resp := rpcResultResponse(rpcReq.ID, map[string]interface{}{
 		"protocolVersion": protocolVersion,
 		"capabilities": map[string]interface{}{
 			"tools": map[string]interface{}{},
+			"resources": map[string]interface{}{
+				"subscribe": true,
+			},
 		},
 		"serverInfo": map[string]interface{}{
 			"name":    "symphony-mcp",
 			"version": c.Version,
 		},
 	})
+	// the client names the session in later requests, which keeps its notifications apart from
+	// other clients with the same token
+	resp.Metadata = map[string]string{v1alpha2.MCPSessionHeader: uuid.New().String()}
+	return resp
 }
 
 func (c *MCPVendor) handleToolCall(ctx context.Context, rpcReq jsonRPCRequest, authToken string) v1alpha2.COAResponse {
 	var params struct {
 		Name      string                 `json:"name"`
 		Arguments map[string]interface{} `json:"arguments"`
+		Meta      struct {
+			ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+		} `json:"_meta"`
 	}
 	if err := json.Unmarshal(rpcReq.Params, &params); err != nil {
 		return rpcErrorResponse(rpcReq.ID, jsonRPCInvalidParams, "invalid tool call parameters", err.Error())
@@ -215,8 +262,17 @@ func (c *MCPVendor) handleToolCall(ctx context.Context, rpcReq jsonRPCRequest, a
 	if params.Arguments == nil {
 		params.Arguments = map[string]interface{}{}
 	}
+	if len(params.Meta.ProgressToken) != 0 && waitsForDeployment(params.Name, params.Arguments) {
+		return c.streamToolCall(ctx, rpcReq, params.Name, params.Arguments, authToken, params.Meta.ProgressToken)
+	}
+	return c.callTool(ctx, rpcReq, params.Name, params.Arguments, authToken, func(int, int, string) {})
+}
 
-	text, err := c.dispatchTool(ctx, params.Name, params.Arguments, authToken)
+func (c *MCPVendor) callTool(ctx context.Context, rpcReq jsonRPCRequest, name string, args map[string]interface{}, authToken string, progress progressFunc) v1alpha2.COAResponse {
+	text, err := c.dispatchTool(ctx, name, args, authToken)
+	if err == nil && waitsForDeployment(name, args) {
+		text, err = c.waitForDeployment(ctx, args, authToken, progress)
+	}
 	if err != nil {
 		// Tool execution errors are reported inside the result with isError,
 		// per the MCP spec, so the model can react to them.
@@ -232,6 +288,9 @@ func (c *MCPVendor) dispatchTool(ctx context.Context, name string, args map[stri
 		if err != nil {
 			return "", err
 		}
+		if err := checkAccess(ctx, v1alpha2.VerbList, objectType, argString(args, "namespace"), "", nil); err != nil {
+			return "", err
+		}
 		body, err := c.callAPI(ctx, http.MethodGet, route, queryParams(args), nil, authToken)
 		if err != nil {
 			return "", err
@@ -246,13 +305,16 @@ func (c *MCPVendor) dispatchTool(ctx context.Context, name string, args map[stri
 		if objName == "" {
 			return "", fmt.Errorf("'name' is required")
 		}
+		if err := checkAccess(ctx, v1alpha2.VerbGet, objectType, argString(args, "namespace"), objName, nil); err != nil {
+			return "", err
+		}
 		body, err := c.callAPI(ctx, http.MethodGet, route+"/"+objName, queryParams(args), nil, authToken)
 		if err != nil {
 			return "", err
 		}
 		return fmt.Sprintf("%s '%s':\n%s", objectType, objName, string(body)), nil
 	case "create_object":
-		_, route, err := resolveObjectRoute(args)
+		objectType, route, err := resolveObjectRoute(args)
 		if err != nil {
 			return "", err
 		}
@@ -268,12 +330,15 @@ func (c *MCPVendor) dispatchTool(ctx context.Context, name string, args map[stri
 		if err != nil {
 			return "", fmt.Errorf("failed to serialize 'body': %v", err)
 		}
+		if err := checkAccess(ctx, v1alpha2.VerbUpdate, objectType, argString(args, "namespace"), objName, coa_http.ObjectLabels(payload)); err != nil {
+			return "", err
+		}
 		if _, err := c.callAPI(ctx, http.MethodPost, route+"/"+objName, queryParams(args), payload, authToken); err != nil {
 			return "", err
 		}
 		return fmt.Sprintf("Created/updated '%s'", objName), nil
 	case "delete_object":
-		_, route, err := resolveObjectRoute(args)
+		objectType, route, err := resolveObjectRoute(args)
 		if err != nil {
 			return "", err
 		}
@@ -281,6 +346,9 @@ func (c *MCPVendor) dispatchTool(ctx context.Context, name string, args map[stri
 		if objName == "" {
 			return "", fmt.Errorf("'name' is required")
 		}
+		if err := checkAccess(ctx, v1alpha2.VerbDelete, objectType, argString(args, "namespace"), objName, nil); err != nil {
+			return "", err
+		}
 		if _, err := c.callAPI(ctx, http.MethodDelete, route+"/"+objName, queryParams(args), nil, authToken); err != nil {
 			return "", err
 		}
@@ -292,6 +360,49 @@ func (c *MCPVendor) dispatchTool(ctx context.Context, name string, args map[stri
 
 // ---- Symphony API access ----
 
+type mcpIdentityKey struct{}
+
+// mcpIdentity is the authenticated caller that tool calls are checked for
+type mcpIdentity struct {
+	user  string
+	roles []string
+	// namespaces limits the caller to these namespaces, if it's not empty
+	namespaces []string
+}
+
+// checkAccess checks an operation on an object type against the namespaces the caller is
+// limited to and the RBAC policy the request was checked with. The object types are named like
+// the resources of their API routes.
+func checkAccess(ctx context.Context, verb string, objectType string, namespace string, name string, labels map[string]string) error {
+	identity, _ := ctx.Value(mcpIdentityKey{}).(mcpIdentity)
+	if !v1alpha2.NamespaceAllowed(identity.namespaces, namespace) {
+		return fmt.Errorf("access denied: '%s' is limited to the namespaces %s", identity.user, strings.Join(identity.namespaces, ","))
+	}
+	policy := v1alpha2.AccessPolicyFromContext(ctx)
+	if policy == nil {
+		return nil
+	}
+	request := v1alpha2.AccessRequest{
+		User:      identity.user,
+		Roles:     identity.roles,
+		Verb:      verb,
+		Resource:  objectType,
+		Namespace: namespace,
+		Name:      name,
+		Labels:    labels,
+	}
+	if labels != nil {
+		// only the written labels are checked here. The tool call writes through the API with
+		// the token of the caller, whose binding checks the labels of the stored object too.
+		request.Stored = &v1alpha2.StoredObject{}
+	}
+	decision := policy.Check(request)
+	if !decision.Allowed {
+		return fmt.Errorf("access denied: %s", decision.Reason)
+	}
+	return nil
+}
+
 func (c *MCPVendor) callAPI(ctx context.Context, method string, route string, params map[string]string, payload []byte, authToken string) ([]byte, error) {
 	if c.apiBaseUrl == "" {
 		return nil, fmt.Errorf("the MCP vendor is not configured with a Symphony API base URL")
@@ -467,6 +578,10 @@ func toolDefinitions() []map[string]interface{} {
 						"type":        "object",
 						"description": "The full object definition to create or update.",
 					},
+					"wait": map[string]interface{}{
+						"type":        "boolean",
+						"description": "For instances, wait until the instance is deployed. Progress is reported to callers that send a progress token.",
+					},
 				},
 				"required": []string{"objectType", "name", "body"},
 			},
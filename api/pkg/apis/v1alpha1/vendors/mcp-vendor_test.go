@@ -7,11 +7,16 @@
 package vendors
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -92,6 +97,39 @@ func TestMCPInitialize(t *testing.T) {
 	assert.NotNil(t, result["serverInfo"])
 }
 
+func TestMCPInitializeIssuesSession(t *testing.T) {
+	vendor := createMCPVendor("http://localhost")
+	request := v1alpha2.COARequest{
+		Context:  context.Background(),
+		Method:   fasthttp.MethodPost,
+		Body:     []byte(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`),
+		Metadata: map[string]string{"Authorization": "Bearer tok"},
+	}
+	first := vendor.onMCP(request)
+	second := vendor.onMCP(request)
+	assert.Equal(t, v1alpha2.OK, first.State)
+	assert.NotEmpty(t, first.Metadata[v1alpha2.MCPSessionHeader])
+	assert.NotEqual(t, first.Metadata[v1alpha2.MCPSessionHeader], second.Metadata[v1alpha2.MCPSessionHeader])
+}
+
+func TestMCPCallerKeysByCredentialAndSession(t *testing.T) {
+	caller := func(metadata map[string]string) string {
+		return mcpCaller(v1alpha2.COARequest{Metadata: metadata})
+	}
+	// the user name alone doesn't identify the streams of a caller
+	assert.Equal(t, "", caller(map[string]string{v1alpha2.COAUserKey: "admin"}))
+	assert.NotEqual(t,
+		caller(map[string]string{v1alpha2.COAUserKey: "admin", "Authorization": "Bearer a"}),
+		caller(map[string]string{v1alpha2.COAUserKey: "admin", "Authorization": "Bearer b"}))
+	assert.NotEqual(t,
+		caller(map[string]string{"Authorization": "Bearer a", v1alpha2.MCPSessionHeader: "s1"}),
+		caller(map[string]string{"Authorization": "Bearer a", v1alpha2.MCPSessionHeader: "s2"}))
+	// a session id doesn't reach the streams of another token
+	assert.NotEqual(t,
+		caller(map[string]string{"Authorization": "Bearer a", v1alpha2.MCPSessionHeader: "s1"}),
+		caller(map[string]string{"Authorization": "Bearer b", v1alpha2.MCPSessionHeader: "s1"}))
+}
+
 func TestMCPPing(t *testing.T) {
 	vendor := createMCPVendor("http://localhost")
 	resp := rpcCall(t, vendor, "ping", "1", nil)
@@ -151,13 +189,218 @@ func TestMCPNotificationNoBody(t *testing.T) {
 	assert.Empty(t, resp.Body)
 }
 
-func TestMCPGetNotAllowed(t *testing.T) {
+func TestMCPGetRequiresCaller(t *testing.T) {
 	vendor := createMCPVendor("http://localhost")
 	resp := vendor.onMCP(v1alpha2.COARequest{
 		Context: context.Background(),
 		Method:  fasthttp.MethodGet,
 	})
-	assert.Equal(t, v1alpha2.MethodNotAllowed, resp.State)
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+}
+
+// streamRecorder collects a streamed response. Once closed, writes fail like they do when the
+// client is gone.
+type streamRecorder struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+	closed bool
+}
+
+func (s *streamRecorder) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return 0, errors.New("client is gone")
+	}
+	return s.buffer.Write(p)
+}
+
+func (s *streamRecorder) Flush() error {
+	return nil
+}
+
+func (s *streamRecorder) String() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.buffer.String()
+}
+
+func (s *streamRecorder) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+}
+
+// openMCPStream opens the notification stream of the caller with the "Bearer tok" token
+func openMCPStream(t *testing.T, vendor *MCPVendor) (*streamRecorder, chan struct{}) {
+	resp := vendor.onMCP(v1alpha2.COARequest{
+		Context:  context.Background(),
+		Method:   fasthttp.MethodGet,
+		Metadata: map[string]string{"Authorization": "Bearer tok"},
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	assert.Equal(t, v1alpha2.SSEContentType, resp.ContentType)
+	recorder := &streamRecorder{}
+	done := make(chan struct{})
+	go func() {
+		resp.Stream(recorder)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool {
+		return vendor.notifications.hasStream("token:Bearer tok")
+	}, 5*time.Second, 10*time.Millisecond)
+	return recorder, done
+}
+
+func TestMCPNotificationStream(t *testing.T) {
+	vendor := createMCPVendor("http://localhost")
+	recorder, done := openMCPStream(t, vendor)
+
+	vendor.notifications.notify("token:Bearer tok", "notifications/resources/updated", map[string]interface{}{"uri": "symphony://instances/default/i1"})
+	vendor.notifications.notify("token:Bearer other", "notifications/resources/updated", map[string]interface{}{"uri": "symphony://instances/default/i2"})
+	assert.Eventually(t, func() bool {
+		return strings.Contains(recorder.String(), "symphony://instances/default/i1")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, recorder.String(), "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/resources/updated\"")
+	assert.NotContains(t, recorder.String(), "i2")
+
+	// the stream ends at the first write after the client is gone
+	recorder.close()
+	vendor.notifications.notify("token:Bearer tok", "notifications/resources/updated", map[string]interface{}{"uri": "symphony://instances/default/i1"})
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "stream didn't end")
+	}
+	assert.False(t, vendor.notifications.hasStream("token:Bearer tok"))
+}
+
+func TestMCPResourceTemplatesList(t *testing.T) {
+	vendor := createMCPVendor("http://localhost")
+	resp := rpcCall(t, vendor, "resources/templates/list", "1", nil)
+	assert.Nil(t, resp.Error)
+	templates := resp.Result.(map[string]interface{})["resourceTemplates"].([]interface{})
+	assert.Equal(t, "symphony://{objectType}/{namespace}/{name}", templates[0].(map[string]interface{})["uriTemplate"])
+}
+
+func TestMCPResourceRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/instances/i1", r.URL.Path)
+		assert.Equal(t, "ns1", r.URL.Query().Get("namespace"))
+		_, _ = w.Write([]byte(`{"metadata":{"name":"i1"}}`))
+	}))
+	defer server.Close()
+
+	vendor := createMCPVendor(server.URL)
+	resp := rpcCall(t, vendor, "resources/read", "1", map[string]interface{}{"uri": "symphony://instances/ns1/i1"})
+	assert.Nil(t, resp.Error)
+	contents := resp.Result.(map[string]interface{})["contents"].([]interface{})
+	assert.Equal(t, `{"metadata":{"name":"i1"}}`, contents[0].(map[string]interface{})["text"])
+
+	resp = rpcCall(t, vendor, "resources/read", "2", map[string]interface{}{"uri": "symphony://instances/i1"})
+	assert.NotNil(t, resp.Error)
+	assert.Equal(t, jsonRPCInvalidParams, resp.Error.Code)
+}
+
+func TestMCPResourceSubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/instances", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("watch"))
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"type":"MODIFIED","object":{"metadata":{"name":"other"}}}` + "\n"))
+		w.Write([]byte(`{"type":"MODIFIED","object":{"metadata":{"name":"i1"}}}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	vendor := createMCPVendor(server.URL)
+	// subscriptions deliver to a notification stream, so one must be open
+	resp := rpcCall(t, vendor, "resources/subscribe", "1", map[string]interface{}{"uri": "symphony://instances/default/i1"})
+	assert.NotNil(t, resp.Error)
+
+	recorder, done := openMCPStream(t, vendor)
+	resp = rpcCall(t, vendor, "resources/subscribe", "2", map[string]interface{}{"uri": "symphony://solutions/default/s1"})
+	assert.NotNil(t, resp.Error)
+	resp = rpcCall(t, vendor, "resources/subscribe", "3", map[string]interface{}{"uri": "symphony://instances/default/i1"})
+	assert.Nil(t, resp.Error)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(recorder.String(), "symphony://instances/default/i1")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.NotContains(t, recorder.String(), "other")
+
+	resp = rpcCall(t, vendor, "resources/unsubscribe", "4", map[string]interface{}{"uri": "symphony://instances/default/i1"})
+	assert.Nil(t, resp.Error)
+	recorder.close()
+	vendor.notifications.notify("token:Bearer tok", "ping", nil)
+	<-done
+}
+
+func TestMCPToolCallWaitWithProgress(t *testing.T) {
+	pollInterval := mcpDeploymentPollInterval
+	mcpDeploymentPollInterval = 10 * time.Millisecond
+	defer func() { mcpDeploymentPollInterval = pollInterval }()
+
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/instances/i1" && r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/instances/i1":
+			_, _ = w.Write([]byte(`{"metadata":{"name":"i1","etag":"2"}}`))
+		case r.URL.Path == "/solutionversion/queue":
+			assert.Equal(t, "i1", r.URL.Query().Get("instance"))
+			polls++
+			switch polls {
+			case 1:
+				// the summary of the previous generation
+				_, _ = w.Write([]byte(`{"generation":"1","state":2,"summary":{"allAssignedDeployed":true}}`))
+			case 2:
+				_, _ = w.Write([]byte(`{"generation":"2","state":1,"summary":{"plannedDeployment":2,"currentDeployed":1}}`))
+			default:
+				_, _ = w.Write([]byte(`{"generation":"2","state":2,"summary":{"targetCount":1,"successCount":1,"plannedDeployment":2,"currentDeployed":2,"allAssignedDeployed":true}}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	vendor := createMCPVendor(server.URL)
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": jsonRPCVersion,
+		"id":      1,
+		"method":  "tools/call",
+		"params": map[string]interface{}{
+			"name": "create_object",
+			"arguments": map[string]interface{}{
+				"objectType": "instances",
+				"name":       "i1",
+				"body":       map[string]interface{}{},
+				"wait":       true,
+			},
+			"_meta": map[string]interface{}{"progressToken": "p1"},
+		},
+	})
+	resp := vendor.onMCP(v1alpha2.COARequest{
+		Context:  context.Background(),
+		Method:   fasthttp.MethodPost,
+		Body:     body,
+		Metadata: map[string]string{"Authorization": "Bearer tok"},
+	})
+	assert.Equal(t, v1alpha2.SSEContentType, resp.ContentType)
+	recorder := &streamRecorder{}
+	resp.Stream(recorder)
+
+	events := strings.Split(strings.TrimSpace(recorder.String()), "\n\n")
+	assert.Equal(t, 3, len(events))
+	assert.Contains(t, events[0], `"method":"notifications/progress","params":{"message":"deployed 1 of 2 components","progress":1,"progressToken":"p1","total":2}`)
+	assert.Contains(t, events[1], `"progress":1,"progressToken":"p1","total":1`)
+	var rpcResp jsonRPCResponse
+	err := json.Unmarshal([]byte(strings.TrimPrefix(events[2], "event: message\ndata: ")), &rpcResp)
+	assert.Nil(t, err)
+	result := rpcResp.Result.(map[string]interface{})
+	assert.Equal(t, false, result["isError"])
 }
 
 func TestMCPToolCallListObjects(t *testing.T) {
@@ -265,3 +508,113 @@ func TestMCPToolCallRequiresCallerToken(t *testing.T) {
 	text := content[0].(map[string]interface{})["text"].(string)
 	assert.Contains(t, text, "authenticated caller")
 }
+
+func TestMCPToolCallChecksRBACPolicy(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		_, _ = w.Write([]byte(`{"metadata":{"name":"s1"}}`))
+	}))
+	defer server.Close()
+
+	policy := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:   []v1alpha2.RBACSubject{{Role: "operator"}},
+				Verbs:      []string{v1alpha2.VerbGet, v1alpha2.VerbList},
+				Resources:  []string{"solutions"},
+				Namespaces: []string{"line-3"},
+			},
+			{
+				Subjects:      []v1alpha2.RBACSubject{{User: "ci"}},
+				Verbs:         []string{v1alpha2.VerbUpdate},
+				Resources:     []string{"solutions"},
+				LabelSelector: map[string]string{"owner": "ci"},
+			},
+		},
+	}
+	vendor := createMCPVendor(server.URL)
+	call := func(user string, roles string, method string, params interface{}) jsonRPCResponse {
+		rawParams, err := json.Marshal(params)
+		assert.Nil(t, err)
+		body, err := json.Marshal(jsonRPCRequest{JSONRPC: jsonRPCVersion, ID: json.RawMessage("1"), Method: method, Params: rawParams})
+		assert.Nil(t, err)
+		resp := vendor.onMCP(v1alpha2.COARequest{
+			Context: context.WithValue(context.Background(), v1alpha2.COAAccessPolicyKey, policy),
+			Method:  fasthttp.MethodPost,
+			Body:    body,
+			Metadata: map[string]string{
+				"Authorization":      "Bearer tok",
+				v1alpha2.COAUserKey:  user,
+				v1alpha2.COARolesKey: roles,
+			},
+		})
+		var rpcResp jsonRPCResponse
+		assert.Nil(t, json.Unmarshal(resp.Body, &rpcResp))
+		return rpcResp
+	}
+	toolCall := func(user string, roles string, name string, args map[string]interface{}) (bool, string) {
+		resp := call(user, roles, "tools/call", map[string]interface{}{"name": name, "arguments": args})
+		assert.Nil(t, resp.Error)
+		result := resp.Result.(map[string]interface{})
+		text := result["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+		return result["isError"].(bool), text
+	}
+
+	isError, _ := toolCall("alice", "operator", "get_object", map[string]interface{}{"objectType": "solutions", "name": "s1", "namespace": "line-3"})
+	assert.False(t, isError)
+	isError, text := toolCall("alice", "operator", "get_object", map[string]interface{}{"objectType": "solutions", "name": "s1"})
+	assert.True(t, isError)
+	assert.Contains(t, text, "access denied")
+	isError, _ = toolCall("alice", "operator", "delete_object", map[string]interface{}{"objectType": "solutions", "name": "s1", "namespace": "line-3"})
+	assert.True(t, isError)
+
+	labeled := map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"owner": "ci"}}}
+	isError, _ = toolCall("ci", "", "create_object", map[string]interface{}{"objectType": "solutions", "name": "s2", "body": labeled})
+	assert.False(t, isError)
+	isError, _ = toolCall("ci", "", "create_object", map[string]interface{}{"objectType": "solutions", "name": "s3", "body": map[string]interface{}{}})
+	assert.True(t, isError)
+
+	resp := call("alice", "operator", "resources/read", map[string]interface{}{"uri": "symphony://solutions/line-4/s1"})
+	assert.NotNil(t, resp.Error)
+	assert.Contains(t, resp.Error.Message, "access denied")
+
+	// only the allowed operations reached the API
+	assert.Equal(t, []string{"GET /solutions/s1", "POST /solutions/s2"}, calls)
+}
+
+func TestMCPToolCallChecksNamespaces(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	vendor := createMCPVendor(server.URL)
+	toolCall := func(args map[string]interface{}) bool {
+		rawParams, err := json.Marshal(map[string]interface{}{"name": "list_objects", "arguments": args})
+		assert.Nil(t, err)
+		body, err := json.Marshal(jsonRPCRequest{JSONRPC: jsonRPCVersion, ID: json.RawMessage("1"), Method: "tools/call", Params: rawParams})
+		assert.Nil(t, err)
+		resp := vendor.onMCP(v1alpha2.COARequest{
+			Context: context.Background(),
+			Method:  fasthttp.MethodPost,
+			Body:    body,
+			Metadata: map[string]string{
+				"Authorization":           "Bearer tok",
+				v1alpha2.COAUserKey:       "apikey:ci",
+				v1alpha2.COANamespacesKey: "ci",
+			},
+		})
+		var rpcResp jsonRPCResponse
+		assert.Nil(t, json.Unmarshal(resp.Body, &rpcResp))
+		return rpcResp.Result.(map[string]interface{})["isError"].(bool)
+	}
+
+	// the caller is limited to its namespaces even without an RBAC policy
+	assert.False(t, toolCall(map[string]interface{}{"objectType": "solutions", "namespace": "ci"}))
+	assert.True(t, toolCall(map[string]interface{}{"objectType": "solutions", "namespace": "prod"}))
+	assert.True(t, toolCall(map[string]interface{}{"objectType": "solutions"}))
+	assert.Equal(t, 1, len(calls))
+}
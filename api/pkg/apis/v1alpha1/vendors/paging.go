@@ -0,0 +1,43 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"strconv"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+)
+
+// The token of the next page of a list is returned in this response metadata key
+const continueMetadataKey = "continue"
+
+// readListOptions reads the limit, continue and sortBy parameters of a list request
+func readListOptions(request v1alpha2.COARequest) (states.ListOptions, error) {
+	ret := states.ListOptions{
+		Continue: request.Parameters["continue"],
+		SortBy:   request.Parameters["sortBy"],
+	}
+	if v := request.Parameters["limit"]; v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return ret, v1alpha2.NewCOAError(err, "limit must be a non-negative integer", v1alpha2.BadRequest)
+		}
+		ret.Limit = limit
+	}
+	return ret, nil
+}
+
+// pageMetadata returns the response metadata of a list page
+func pageMetadata(next string) map[string]string {
+	if next == "" {
+		return nil
+	}
+	return map[string]string{
+		continueMetadataKey: next,
+	}
+}
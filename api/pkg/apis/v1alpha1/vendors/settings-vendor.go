@@ -8,6 +8,7 @@ package vendors
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	api_utils "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -30,6 +31,8 @@ var csLog = logger.NewLogger("coa.runtime")
 type SettingsVendor struct {
 	vendors.Vendor
 	EvaluationContext *utils.EvaluationContext
+	// roles that can manage dead letters
+	roles []string
 }
 
 func (e *SettingsVendor) GetInfo() vendors.VendorInfo {
@@ -60,6 +63,10 @@ func (e *SettingsVendor) Init(cfg vendors.VendorConfig, factories []managers.IMa
 		ConfigProvider: configProvider,
 		SecretProvider: secretProvider,
 	}
+	e.roles = []string{"administrator"}
+	if cfg.Properties != nil && strings.TrimSpace(cfg.Properties["roles"]) != "" {
+		e.roles = splitList(utils.ParseProperty(cfg.Properties["roles"]))
+	}
 	return nil
 }
 func (e *SettingsVendor) GetEvaluationContext() *utils.EvaluationContext {
@@ -71,6 +78,13 @@ func (o *SettingsVendor) GetEndpoints() []v1alpha2.Endpoint {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route + "/deadletters",
+			Version:    o.Version,
+			Handler:    o.onDeadLetters,
+			Parameters: []string{"topic", "id?"},
+		},
 		{
 			Methods:    []string{fasthttp.MethodGet},
 			Route:      route + "/config",
@@ -151,3 +165,88 @@ func (c *SettingsVendor) onConfig(request v1alpha2.COARequest) v1alpha2.COARespo
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// onDeadLetters lists, inspects, replays or purges the events that the pub-sub provider
+// dead-lettered for a topic. POST replays an event, and DELETE without an id purges the topic.
+// Dead letters carry the bodies of any events, so they're only for callers with the roles.
+func (c *SettingsVendor) onDeadLetters(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Settings Vendor", request.Context, &map[string]string{
+		"method": "onDeadLetters",
+	})
+	defer span.End()
+	csLog.InfofCtx(ctx, "V (Settings): onDeadLetters method: %s", request.Method)
+
+	if resp, ok := requireAnyRole(ctx, request, c.roles, "managing dead letters"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+
+	var provider pubsub.IDeadLetterProvider
+	if c.Context != nil {
+		provider, _ = c.Context.PubsubProvider.(pubsub.IDeadLetterProvider)
+	}
+	if provider == nil {
+		log.ErrorCtx(ctx, "V (Settings): onDeadLetters pub-sub provider doesn't keep dead letters")
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.BadRequest,
+			Body:  []byte("pub-sub provider doesn't keep dead letters"),
+		})
+	}
+	topic := request.Parameters["__topic"]
+	id := request.Parameters["__id"]
+
+	var body interface{}
+	var err error
+	switch request.Method {
+	case fasthttp.MethodGet:
+		if id == "" {
+			body, err = provider.ListDeadLetters(topic)
+		} else {
+			body, err = provider.GetDeadLetter(topic, id)
+		}
+	case fasthttp.MethodPost:
+		if id == "" {
+			err = v1alpha2.NewCOAError(nil, "id of the dead letter to replay is not set", v1alpha2.BadRequest)
+		} else {
+			err = provider.ReplayDeadLetter(topic, id)
+		}
+	case fasthttp.MethodDelete:
+		if id == "" {
+			var count int
+			count, err = provider.PurgeDeadLetters(topic)
+			body = map[string]int{"purged": count}
+		} else {
+			err = provider.DeleteDeadLetter(topic, id)
+		}
+	default:
+		log.ErrorCtx(ctx, "V (Settings): onDeadLetters returned MethodNotAllowed")
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.MethodNotAllowed,
+			Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+			ContentType: "application/json",
+		})
+	}
+	if err != nil {
+		log.ErrorfCtx(ctx, "V (Settings): onDeadLetters failed to %s dead letters of topic %s: %v", strings.ToLower(request.Method), topic, err)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	if body == nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.InternalError,
+			Body:  []byte(fmt.Sprintf("failed to serialize dead letters: %s", err.Error())),
+		})
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        data,
+		ContentType: "application/json",
+	})
+}
@@ -8,15 +8,20 @@ package vendors
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/configs"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config"
 	memory "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/config/memoryconfig"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	mempubsub "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	coa_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -38,6 +43,7 @@ func createSettingsVendor() SettingsVendor {
 		EvaluationContext: &coa_utils.EvaluationContext{
 			ConfigProvider: &manager,
 		},
+		roles: []string{"administrator"},
 	}
 	return vendor
 }
@@ -73,6 +79,7 @@ func TestSettingsVendorInit(t *testing.T) {
 		},
 	}, nil)
 	assert.Nil(t, err)
+	assert.Equal(t, []string{"administrator"}, vendor.roles)
 }
 
 func TestSettingsEndpoints(t *testing.T) {
@@ -150,3 +157,95 @@ func TestConfigGetField(t *testing.T) {
 	res = vendor.onConfig(*request)
 	assert.Equal(t, v1alpha2.NotFound, res.State)
 }
+
+func TestDeadLetters(t *testing.T) {
+	vendor := createSettingsVendor()
+	pubSubProvider := &mempubsub.InMemoryPubSubProvider{}
+	pubSubProvider.Init(mempubsub.InMemoryPubSubConfig{
+		DeadLetterPolicies: map[string]pubsub.DeadLetterPolicy{
+			"job": {MaxDeliveryAttempts: 1},
+		},
+	})
+	vendor.Context = &contexts.VendorContext{PubsubProvider: pubSubProvider}
+	delivered := make(chan struct{}, 2)
+	pubSubProvider.Subscribe("job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			delivered <- struct{}{}
+			return v1alpha2.NewCOAError(nil, "insert internal error", v1alpha2.InternalError)
+		},
+	})
+	pubSubProvider.Publish("job", v1alpha2.Event{Body: "bad job"})
+	<-delivered
+
+	request := v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__topic": "job"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "admin", v1alpha2.COARolesKey: "administrator"},
+	}
+	var deadLetters []pubsub.DeadLetter
+	assert.Eventually(t, func() bool {
+		res := vendor.onDeadLetters(request)
+		json.Unmarshal(res.Body, &deadLetters)
+		return res.State == v1alpha2.OK && len(deadLetters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	request.Parameters["__id"] = deadLetters[0].ID
+	res := vendor.onDeadLetters(request)
+	assert.Equal(t, v1alpha2.OK, res.State)
+	var deadLetter pubsub.DeadLetter
+	json.Unmarshal(res.Body, &deadLetter)
+	assert.Equal(t, "bad job", deadLetter.Event.Body)
+
+	request.Method = fasthttp.MethodPost
+	res = vendor.onDeadLetters(request)
+	assert.Equal(t, v1alpha2.OK, res.State)
+	<-delivered
+
+	request.Method = fasthttp.MethodGet
+	res = vendor.onDeadLetters(request)
+	assert.Equal(t, v1alpha2.NotFound, res.State)
+
+	request.Method = fasthttp.MethodDelete
+	delete(request.Parameters, "__id")
+	assert.Eventually(t, func() bool {
+		res = vendor.onDeadLetters(request)
+		return string(res.Body) == `{"purged":1}`
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDeadLettersWithoutProvider(t *testing.T) {
+	vendor := createSettingsVendor()
+	res := vendor.onDeadLetters(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__topic": "job"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "admin", v1alpha2.COARolesKey: "administrator"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, res.State)
+}
+
+func TestDeadLettersRequireRoles(t *testing.T) {
+	vendor := createSettingsVendor()
+	pubSubProvider := &mempubsub.InMemoryPubSubProvider{}
+	pubSubProvider.Init(mempubsub.InMemoryPubSubConfig{})
+	vendor.Context = &contexts.VendorContext{PubsubProvider: pubSubProvider}
+	for _, method := range []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete} {
+		res := vendor.onDeadLetters(v1alpha2.COARequest{
+			Method:     method,
+			Context:    context.Background(),
+			Parameters: map[string]string{"__topic": "job", "__id": "1"},
+			Metadata:   map[string]string{v1alpha2.COAUserKey: "bob", v1alpha2.COARolesKey: "reader"},
+		})
+		assert.Equal(t, v1alpha2.Forbidden, res.State)
+	}
+
+	vendor.roles = []string{"operator"}
+	res := vendor.onDeadLetters(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"__topic": "job"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "alice", v1alpha2.COARolesKey: "reader,operator"},
+	})
+	assert.Equal(t, v1alpha2.OK, res.State)
+}
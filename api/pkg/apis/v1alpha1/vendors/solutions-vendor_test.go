@@ -14,6 +14,7 @@ import (
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -140,3 +141,45 @@ func TestOnSolutions(t *testing.T) {
 	})
 	assert.Equal(t, v1alpha2.OK, resp.State)
 }
+
+func TestSolutionsLabelSelectorChecksStoredLabels(t *testing.T) {
+	vendor := createSolutionsVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	write := func(name string, env string) v1alpha2.COARequest {
+		data, _ := json.Marshal(model.SolutionState{
+			Spec: &model.SolutionSpec{},
+			ObjectMeta: model.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{"env": env},
+			},
+		})
+		return v1alpha2.COARequest{
+			Method:     fasthttp.MethodPost,
+			Body:       data,
+			Parameters: map[string]string{"__name": name},
+			Metadata:   map[string]string{v1alpha2.COAUserKey: "dev", v1alpha2.COARolesKey: "developer"},
+			Context:    context.Background(),
+		}
+	}
+	assert.Equal(t, v1alpha2.OK, vendor.onSolutions(write("prod-app", "prod")).State)
+
+	policy := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:      []v1alpha2.RBACSubject{{Role: "developer"}},
+				Verbs:         []string{v1alpha2.VerbUpdate},
+				Resources:     []string{"solutions"},
+				LabelSelector: map[string]string{"env": "dev"},
+			},
+		},
+	}
+	endpoint := vendor.GetEndpoints()[0]
+	_, decision := coa_http.CheckAccess(endpoint, write("dev-app", "dev"), policy, nil)
+	assert.True(t, decision.Allowed, decision.Reason)
+	// a prod solution can't be taken over by relabeling it
+	_, decision = coa_http.CheckAccess(endpoint, write("prod-app", "dev"), policy, nil)
+	assert.False(t, decision.Allowed)
+}
@@ -160,6 +160,16 @@ func (c *SolutionVersionVendor) onQueue(request v1alpha2.COARequest) v1alpha2.CO
 					Body:        []byte(fmt.Sprintf(`{"result":"%s"}`, err.Error())),
 				})
 			}
+			// the instance parameter is what callers are authorized for, such as enrolled targets
+			// that can only queue their own instance, so the deployment must be for it
+			if instance != "" && instance != deployment.Instance.ObjectMeta.Name {
+				sLog.ErrorfCtx(ctx, "V (SolutionVersion): onQueue failed - 403 deployment of instance %s doesn't match instance parameter %s", deployment.Instance.ObjectMeta.Name, instance)
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State:       v1alpha2.Forbidden,
+					Body:        []byte(fmt.Sprintf("{\"result\":\"403 - deployment is for instance '%s', not '%s'\"}", deployment.Instance.ObjectMeta.Name, instance)),
+					ContentType: "application/json",
+				})
+			}
 			instance = deployment.Instance.ObjectMeta.Name
 		}
 
@@ -10,12 +10,15 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	coa_http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
@@ -482,3 +485,62 @@ func TestSolutionVersionQueueTargetUpdate(t *testing.T) {
 	time.Sleep(time.Second)
 	assert.Equal(t, 1, succeededCount)
 }
+
+func TestSolutionVersionQueueDeploymentOfOtherInstance(t *testing.T) {
+	vendor := createSolutionVersionVendor()
+	vendor.Context = &contexts.VendorContext{}
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	queued := make(chan string, 1)
+	vendor.Context.Subscribe("job", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			var job v1alpha2.JobData
+			jData, _ := json.Marshal(event.Body)
+			json.Unmarshal(jData, &job)
+			queued <- job.Id
+			return nil
+		},
+	})
+
+	// an enrolled target may queue its own instance
+	targets := newTargetsVendor(true)
+	data, _ := json.Marshal(enrollment.JoinTokenSpec{Target: "target1"})
+	resp := targets.onJoinTokens(adminRequest(fasthttp.MethodPost, "target1-join", data))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var joinToken enrollment.IssuedJoinToken
+	json.Unmarshal(resp.Body, &joinToken)
+	credential, err := targets.EnrollmentManager.Enroll(context.Background(), enrollment.EnrollRequest{JoinToken: joinToken.Token}, nil)
+	assert.Nil(t, err)
+	principal, _, err := targets.EnrollmentManager.ValidateToken(context.Background(), credential.AccessToken)
+	assert.Nil(t, err)
+
+	deployment := createDeployment2Mocks1Target("instance2")
+	deployment.Instance.ObjectMeta.Name = "instance2"
+	body, _ := json.Marshal(deployment)
+	request := v1alpha2.COARequest{
+		Method: fasthttp.MethodPost,
+		Body:   body,
+		Parameters: map[string]string{
+			"instance":   "target1",
+			"objectType": "deployment",
+		},
+		Metadata: map[string]string{
+			v1alpha2.COAUserKey:  principal.User,
+			v1alpha2.COARolesKey: strings.Join(principal.Roles, ","),
+		},
+		Context: context.Background(),
+	}
+	endpoint := vendor.GetEndpoints()[2]
+	_, decision := coa_http.CheckAccess(endpoint, request, nil, principal.Policy)
+	assert.True(t, decision.Allowed, decision.Reason)
+
+	// but not a deployment of another instance under its own instance parameter
+	resp = vendor.onQueue(request)
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+	select {
+	case id := <-queued:
+		assert.Fail(t, "deployment was queued", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
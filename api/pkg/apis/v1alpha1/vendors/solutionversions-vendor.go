@@ -7,6 +7,8 @@
 package vendors
 
 import (
+	"context"
+
 	"github.com/eclipse-symphony/symphony/api/constants"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/solutionversions"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
@@ -17,6 +19,7 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
@@ -61,11 +64,13 @@ func (o *SolutionVersionsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route,
-			Version:    o.Version,
-			Handler:    o.onSolutionVersions,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route,
+			Version:      o.Version,
+			Handler:      o.onSolutionVersions,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.SolutionVersionsManager.GetState),
+			Docs:         registryDocs("solution version", model.SolutionVersionState{}, []model.SolutionVersionState{}, "embed-type", "embed-component", "embed-property"),
 		},
 	}
 }
@@ -87,13 +92,23 @@ func (c *SolutionVersionsVendor) onSolutionVersions(request v1alpha2.COARequest)
 		ctx, span := observability.StartSpan("onSolutionVersions-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			// Change namespace back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				namespace = ""
 			}
-			state, err = c.SolutionVersionsManager.ListState(ctx, namespace)
+			if isWatch(request) {
+				return watchResponse(ctx, span, func(ctx context.Context) (<-chan model.WatchEvent, error) {
+					return c.SolutionVersionsManager.WatchState(ctx, namespace)
+				})
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.SolutionVersionsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.SolutionVersionsManager.GetState(ctx, id, namespace)
@@ -108,6 +123,7 @@ func (c *SolutionVersionsVendor) onSolutionVersions(request v1alpha2.COARequest)
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -178,6 +194,7 @@ func (c *SolutionVersionsVendor) onSolutionVersions(request v1alpha2.COARequest)
 					Type:    "solutionversions.solution.symphony/v1",
 					Properties: map[string]interface{}{
 						"spec": solutionversion,
+						"user": request.Metadata[v1alpha2.COAUserKey],
 					},
 				},
 			},
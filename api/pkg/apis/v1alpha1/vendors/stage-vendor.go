@@ -157,11 +157,20 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 				triggerData.Activation, triggerData.Stage, triggerData.Namespace)
 
 			status.Outputs["__namespace"] = triggerData.Namespace
-			_, err = s.ActivationsManager.GetState(ctx, triggerData.Activation, triggerData.Namespace)
+			activationState, err := s.ActivationsManager.GetState(ctx, triggerData.Activation, triggerData.Namespace)
 			if err != nil {
 				sLog.ErrorfCtx(ctx, "V (Stage): unable to find activation: %+v", err)
 				return nil
 			}
+			if !triggerData.NeedsReport {
+				if activationState.Status.Status == v1alpha2.Cancelled {
+					sLog.InfofCtx(ctx, "V (Stage): activation %s is cancelled, skipping stage %s", triggerData.Activation, triggerData.Stage)
+					return nil
+				}
+				if activationState.Status.PauseRequested {
+					return s.holdTrigger(ctx, triggerData)
+				}
+			}
 			campaignversionName := api_utils.ConvertReferenceToObjectName(triggerData.CampaignVersion)
 			campaignversion, err := s.CampaignVersionsManager.GetState(ctx, campaignversionName, triggerData.Namespace)
 			if err != nil {
@@ -210,11 +219,146 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 						Context: ctx,
 					})
 				}
+				if status.Status == v1alpha2.Paused {
+					// a child activation may have finished before the pause was reported
+					result, err := s.StageManager.FinishedChildActivation(ctx, triggerData)
+					if err != nil {
+						sLog.ErrorfCtx(ctx, "V (Stage): failed to check child activation: %v", err)
+					} else if result != nil {
+						s.Vendor.Context.Publish("child-activation", v1alpha2.Event{
+							Body:    *result,
+							Context: ctx,
+						})
+					}
+				}
 			}
 			log.InfoCtx(ctx, "V (Stage): Finished handling trigger event")
 			return nil
 		},
 	})
+	s.Vendor.Context.Subscribe("approval", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var decision model.ApprovalDecision
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &decision)
+			if err != nil {
+				sLog.ErrorCtx(ctx, "V (Stage): event body of approval event is not an ApprovalDecision")
+				return v1alpha2.NewCOAError(nil, "event body is not an approval decision", v1alpha2.BadRequest)
+			}
+			sLog.InfofCtx(ctx, "V (Stage): handling approval event for activation %s stage %s in namespace %s: %s", decision.Activation, decision.Stage, decision.Namespace, decision.Decision)
+			activation, err := s.ActivationsManager.GetState(ctx, decision.Activation, decision.Namespace)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): unable to find activation: %+v", err)
+				return nil
+			}
+			campaignversionName := api_utils.ConvertReferenceToObjectName(decision.CampaignVersion)
+			campaignversion, err := s.CampaignVersionsManager.GetState(ctx, campaignversionName, decision.Namespace)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to get campaignversion spec: %v", err)
+				return s.reportActivationStatusWithBadRequest(decision.Activation, decision.Namespace, err)
+			}
+			status, next, err := s.StageManager.ResumeApproval(ctx, decision, activation, *campaignversion.Spec)
+			if err != nil {
+				// the approval has already been decided, e.g. by a timeout racing a user
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to resume approval: %v", err)
+				return nil
+			}
+			err = s.ActivationsManager.ReportStageStatus(ctx, decision.Activation, decision.Namespace, status)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
+				return err
+			}
+			if next != nil {
+				s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+					Body:    *next,
+					Context: ctx,
+				})
+			}
+			return nil
+		},
+	})
+	s.Vendor.Context.Subscribe("child-activation", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var result model.ChildActivationResult
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &result)
+			if err != nil {
+				sLog.ErrorCtx(ctx, "V (Stage): event body of child-activation event is not a ChildActivationResult")
+				return v1alpha2.NewCOAError(nil, "event body is not a child activation result", v1alpha2.BadRequest)
+			}
+			sLog.InfofCtx(ctx, "V (Stage): handling child-activation event for activation %s child %s in namespace %s: %s", result.Parent, result.Activation, result.Namespace, result.Status.String())
+			activation, err := s.ActivationsManager.GetState(ctx, result.Parent, result.Namespace)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): unable to find parent activation: %+v", err)
+				return nil
+			}
+			if activation.Spec == nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): parent activation %s has no spec", result.Parent)
+				return nil
+			}
+			campaignversionName := api_utils.ConvertReferenceToObjectName(activation.Spec.CampaignVersion)
+			campaignversion, err := s.CampaignVersionsManager.GetState(ctx, campaignversionName, result.Namespace)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to get campaignversion spec: %v", err)
+				return s.reportActivationStatusWithBadRequest(result.Parent, result.Namespace, err)
+			}
+			status, next, err := s.StageManager.ResumeChildActivation(ctx, result, activation, *campaignversion.Spec)
+			if err != nil {
+				// the result has already been handled, or the pause hasn't been reported yet
+				sLog.InfofCtx(ctx, "V (Stage): not resuming activation %s: %v", result.Parent, err)
+				return nil
+			}
+			err = s.ActivationsManager.ReportStageStatus(ctx, result.Parent, result.Namespace, status)
+			if err != nil {
+				sLog.ErrorfCtx(ctx, "V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
+				return err
+			}
+			if next != nil {
+				s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+					Body:    *next,
+					Context: ctx,
+				})
+			}
+			return nil
+		},
+	})
+	s.Vendor.Context.Subscribe("activation-control", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			ctx := context.TODO()
+			if event.Context != nil {
+				ctx = event.Context
+			}
+			var control model.ActivationControl
+			jData, _ := json.Marshal(event.Body)
+			err := utils2.UnmarshalJson(jData, &control)
+			if err != nil {
+				sLog.ErrorCtx(ctx, "V (Stage): event body of activation-control event is not an ActivationControl")
+				return v1alpha2.NewCOAError(nil, "event body is not an activation control", v1alpha2.BadRequest)
+			}
+			sLog.InfofCtx(ctx, "V (Stage): handling activation-control event for activation %s in namespace %s: %s", control.Activation, control.Namespace, control.Action)
+			switch control.Action {
+			case model.ActivationCancel:
+				err = s.StageManager.CancelActivation(ctx, control.Activation, control.Namespace)
+				if err != nil {
+					sLog.ErrorfCtx(ctx, "V (Stage): failed to cancel activation %s: %v", control.Activation, err)
+				}
+				return err
+			case model.ActivationPause:
+				s.StageManager.PauseActivation(ctx, control.Activation, control.Namespace)
+			case model.ActivationResume:
+				return s.releaseTrigger(ctx, control.Activation, control.Namespace)
+			}
+			return nil
+		},
+	})
 	s.Vendor.Context.Subscribe("job-report", v1alpha2.EventHandler{
 		Handler: func(topic string, event v1alpha2.Event) error {
 			ctx := context.TODO()
@@ -286,6 +430,9 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 			var job v1alpha2.JobData
 			utils2.UnmarshalJson(jData, &job)
 			jData, _ = json.Marshal(job.Body)
+			if job.Action == v1alpha2.JobControl {
+				return s.controlRemoteActivation(ctx, jData)
+			}
 			var dataPackage v1alpha2.InputOutputData
 			err := utils2.UnmarshalJson(jData, &dataPackage)
 			if err != nil {
@@ -349,6 +496,64 @@ func (s *StageVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	return nil
 }
 
+// controlRemoteActivation applies a cancel, pause or resume request forwarded by the parent site to the
+// stages this site runs for the activation
+func (s *StageVendor) controlRemoteActivation(ctx context.Context, data []byte) error {
+	var control model.ActivationControl
+	err := utils2.UnmarshalJson(data, &control)
+	if err != nil {
+		sLog.ErrorCtx(ctx, "V (Stage): remote job body is not an ActivationControl")
+		return v1alpha2.NewCOAError(nil, "job body is not an activation control", v1alpha2.BadRequest)
+	}
+	sLog.InfofCtx(ctx, "V (Stage): handling remote %s of activation %s in namespace %s", control.Action, control.Activation, control.Namespace)
+	switch control.Action {
+	case model.ActivationCancel:
+		return s.StageManager.CancelActivation(ctx, control.Activation, control.Namespace)
+	case model.ActivationPause:
+		s.StageManager.PauseActivation(ctx, control.Activation, control.Namespace)
+	case model.ActivationResume:
+		_, err = s.StageManager.ResumeActivation(ctx, control.Activation, control.Namespace)
+		return err
+	}
+	return nil
+}
+
+// holdTrigger keeps the trigger of a stage of a paused activation until the activation is resumed
+func (s *StageVendor) holdTrigger(ctx context.Context, triggerData v1alpha2.ActivationData) error {
+	status, err := s.StageManager.HoldTrigger(ctx, triggerData)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "V (Stage): failed to hold stage %s of activation %s: %v", triggerData.Stage, triggerData.Activation, err)
+		return err
+	}
+	err = s.ActivationsManager.ReportStageStatus(ctx, triggerData.Activation, triggerData.Namespace, status)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "V (Stage): failed to report status: %v (%v)", status.ErrorMessage, err)
+		return err
+	}
+	// the activation may have been resumed before the trigger was saved
+	activation, err := s.ActivationsManager.GetState(ctx, triggerData.Activation, triggerData.Namespace)
+	if err != nil || activation.Status.PauseRequested {
+		return nil
+	}
+	return s.releaseTrigger(ctx, triggerData.Activation, triggerData.Namespace)
+}
+
+// releaseTrigger resumes the tasks of an activation and starts the stage that was held while it was paused
+func (s *StageVendor) releaseTrigger(ctx context.Context, activation string, namespace string) error {
+	next, err := s.StageManager.ResumeActivation(ctx, activation, namespace)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "V (Stage): failed to resume activation %s: %v", activation, err)
+		return err
+	}
+	if next != nil {
+		s.Vendor.Context.Publish("trigger", v1alpha2.Event{
+			Body:    *next,
+			Context: ctx,
+		})
+	}
+	return nil
+}
+
 func (s *StageVendor) reportActivationStatusWithBadRequest(activation string, namespace string, err error) error {
 	status := model.StageStatus{
 		Stage:         "",
@@ -0,0 +1,293 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// onBootstrap enrolls the agent of a target. The agent trades a one-time join token, which it
+// calls the endpoint with before it has a token of its own, for the token of its target.
+func (c *TargetsVendor) onBootstrap(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
+		"method": "onBootstrap",
+	})
+	defer span.End()
+	tLog.InfofCtx(ctx, "V (Targets) : onBootstrap, method: %s", request.Method)
+	switch request.Method {
+	case fasthttp.MethodPost:
+		if resp, ok := c.checkEnrollment(ctx); !ok {
+			return observ_utils.CloseSpanWithCOAResponse(span, resp)
+		}
+		var enrollRequest enrollment.EnrollRequest
+		err := utils2.UnmarshalJson(request.Body, &enrollRequest)
+		if err != nil || enrollRequest.JoinToken == "" {
+			if err == nil {
+				err = v1alpha2.NewCOAError(nil, "joinToken is required", v1alpha2.BadRequest)
+			}
+			tLog.ErrorfCtx(ctx, "V (Targets) : onBootstrap failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		credential, err := c.EnrollmentManager.Enroll(ctx, enrollRequest, c.targetLabels)
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onBootstrap failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, enrollment.TrailType, "enroll", map[string]interface{}{
+			"target":    credential.Target,
+			"namespace": credential.Namespace,
+			"expiresAt": credential.ExpiresAt,
+		})
+		data, _ := json.Marshal(credential)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	}
+	tLog.ErrorCtx(ctx, "V (Targets) : onBootstrap failed - method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+func (c *TargetsVendor) onJoinTokens(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
+		"method": "onJoinTokens",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	tLog.InfofCtx(ctx, "V (Targets) : onJoinTokens, method: %s, name: %s", request.Method, name)
+
+	if resp, ok := c.checkEnrollment(ctx); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	if resp, ok := requireAnyRole(ctx, request, c.enrollmentRoles, "managing the enrollment of targets"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var result interface{}
+		var err error
+		if name == "" {
+			result, err = c.EnrollmentManager.ListJoinTokens(ctx)
+		} else {
+			result, err = c.EnrollmentManager.GetJoinToken(ctx, name)
+		}
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onJoinTokens failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		data, _ := json.Marshal(result)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		var spec enrollment.JoinTokenSpec
+		err := utils2.UnmarshalJson(request.Body, &spec)
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onJoinTokens failed to unmarshall request body, error: %+v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		if namespace, ok := request.Parameters["namespace"]; ok && spec.Namespace == "" {
+			spec.Namespace = namespace
+		}
+		token, err := c.EnrollmentManager.CreateJoinToken(ctx, name, spec, request.Metadata[v1alpha2.COAUserKey])
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onJoinTokens failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, enrollment.TrailType, "create", map[string]interface{}{
+			"id":        token.Name,
+			"user":      request.Metadata[v1alpha2.COAUserKey],
+			"namespace": token.Namespace,
+			"target":    token.Target,
+			"labels":    token.Labels,
+			"expiresAt": token.ExpiresAt,
+		})
+		data, _ := json.Marshal(token)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("join token name is required"),
+			})
+		}
+		err := c.EnrollmentManager.DeleteJoinToken(ctx, name)
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onJoinTokens failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, enrollment.TrailType, "delete", map[string]interface{}{
+			"id":   name,
+			"user": request.Metadata[v1alpha2.COAUserKey],
+		})
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	tLog.ErrorCtx(ctx, "V (Targets) : onJoinTokens failed - method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// onCredentials lists the credentials of enrolled targets, or revokes the credential of the
+// target with the name
+func (c *TargetsVendor) onCredentials(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
+		"method": "onCredentials",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	tLog.InfofCtx(ctx, "V (Targets) : onCredentials, method: %s, name: %s", request.Method, name)
+
+	if resp, ok := c.checkEnrollment(ctx); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	if resp, ok := requireAnyRole(ctx, request, c.enrollmentRoles, "managing the enrollment of targets"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+	namespace, exist := request.Parameters["namespace"]
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		credentials, err := c.EnrollmentManager.ListCredentials(ctx, namespace)
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onCredentials failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		var result interface{} = credentials
+		if name != "" {
+			result = nil
+			for _, credential := range credentials {
+				if credential.Target == name && (exist || credential.Namespace == constants.DefaultScope) {
+					result = credential
+				}
+			}
+			if result == nil {
+				return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+					State: v1alpha2.NotFound,
+					Body:  []byte(fmt.Sprintf("target '%s' isn't enrolled", name)),
+				})
+			}
+		}
+		data, _ := json.Marshal(result)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("target name is required"),
+			})
+		}
+		if !exist {
+			namespace = constants.DefaultScope
+		}
+		err := c.EnrollmentManager.RevokeCredential(ctx, namespace, name)
+		if err != nil {
+			tLog.ErrorfCtx(ctx, "V (Targets) : onCredentials failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, enrollment.TrailType, "revoke", map[string]interface{}{
+			"target":    name,
+			"namespace": namespace,
+			"user":      request.Metadata[v1alpha2.COAUserKey],
+		})
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	tLog.ErrorCtx(ctx, "V (Targets) : onCredentials failed - method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
+
+// targetLabels reads the labels of a registered target for the join tokens that are bound to
+// labels
+func (c *TargetsVendor) targetLabels(ctx context.Context, namespace string, target string) (map[string]string, error) {
+	state, err := c.TargetsManager.GetState(ctx, target, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if state.ObjectMeta.Labels == nil {
+		return map[string]string{}, nil
+	}
+	return state.ObjectMeta.Labels, nil
+}
+
+// checkEnrollment tells if enrollment is configured, and returns the response to send if it
+// isn't
+func (c *TargetsVendor) checkEnrollment(ctx context.Context) (v1alpha2.COAResponse, bool) {
+	if c.EnrollmentManager != nil {
+		return v1alpha2.COAResponse{}, true
+	}
+	tLog.ErrorCtx(ctx, "V (Targets) : enrollment manager is not configured")
+	return v1alpha2.COAResponse{
+		State: v1alpha2.MissingConfig,
+		Body:  []byte("target enrollment is not configured, add a managers.symphony.enrollment manager to the targets vendor"),
+	}, false
+}
@@ -7,11 +7,13 @@
 package vendors
 
 import (
+	"context"
 	"encoding/json"
 	"strings"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/constants"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/targets"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
@@ -21,11 +23,11 @@ import (
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/valyala/fasthttp"
 )
 
@@ -34,6 +36,11 @@ var tLog = logger.NewLogger("coa.runtime")
 type TargetsVendor struct {
 	vendors.Vendor
 	TargetsManager *targets.TargetsManager
+	// EnrollmentManager enrolls the agents of targets with join tokens. Targets can't bootstrap
+	// if it isn't configured.
+	EnrollmentManager *enrollment.EnrollmentManager
+	// roles that can manage join tokens and the credentials of targets
+	enrollmentRoles []string
 }
 
 func (o *TargetsVendor) GetInfo() vendors.VendorInfo {
@@ -53,13 +60,29 @@ func (e *TargetsVendor) Init(config vendors.VendorConfig, factories []managers.I
 		if c, ok := m.(*targets.TargetsManager); ok {
 			e.TargetsManager = c
 		}
+		if c, ok := m.(*enrollment.EnrollmentManager); ok {
+			e.EnrollmentManager = c
+		}
 	}
 	if e.TargetsManager == nil {
 		return v1alpha2.NewCOAError(nil, "targets manager is not supplied", v1alpha2.MissingConfig)
 	}
+	e.enrollmentRoles = []string{"administrator"}
+	if config.Properties != nil && strings.TrimSpace(config.Properties["enrollmentRoles"]) != "" {
+		e.enrollmentRoles = splitList(utils2.ParseProperty(config.Properties["enrollmentRoles"]))
+	}
 	return nil
 }
 
+// GetTokenValidator returns the validator of the tokens of enrolled targets for the JWT
+// middleware, or nil if enrollment isn't configured
+func (e *TargetsVendor) GetTokenValidator() v1alpha2.ITokenValidator {
+	if e.EnrollmentManager == nil {
+		return nil
+	}
+	return e.EnrollmentManager
+}
+
 func (o *TargetsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	route := "targets"
 	if o.Route != "" {
@@ -67,17 +90,73 @@ func (o *TargetsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 	return []v1alpha2.Endpoint{
 		{
-			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
-			Route:      route + "/registry",
-			Version:    o.Version,
-			Handler:    o.onRegistry,
-			Parameters: []string{"name?"},
+			Methods:      []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:        route + "/registry",
+			Version:      o.Version,
+			Handler:      o.onRegistry,
+			Parameters:   []string{"name?"},
+			ObjectLabels: storedLabels(o.TargetsManager.GetState),
+			Docs:         registryDocs("target", model.TargetState{}, []model.TargetState{}, "with-binding", "direct"),
 		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/bootstrap",
 			Version: o.Version,
 			Handler: o.onBootstrap,
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Enroll the agent of a target",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodPost: {
+						Summary:  "Trade a join token for the token of a target. The token is only returned once.",
+						Request:  enrollment.EnrollRequest{},
+						Response: enrollment.IssuedCredential{},
+					},
+				},
+			},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route + "/jointokens",
+			Version:    o.Version,
+			Handler:    o.onJoinTokens,
+			Parameters: []string{"name?"},
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Manage the join tokens of targets",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodGet: {
+						Summary:      "Get a join token, or list the join tokens",
+						Response:     enrollment.JoinToken{},
+						ListResponse: []enrollment.JoinToken{},
+					},
+					fasthttp.MethodPost: {
+						Summary:  "Create a join token. The token is only returned once.",
+						Request:  enrollment.JoinTokenSpec{},
+						Response: enrollment.IssuedJoinToken{},
+					},
+					fasthttp.MethodDelete: {
+						Summary: "Delete a join token",
+					},
+				},
+			},
+		},
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodDelete},
+			Route:      route + "/credentials",
+			Version:    o.Version,
+			Handler:    o.onCredentials,
+			Parameters: []string{"name?"},
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Manage the credentials of enrolled targets",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodGet: {
+						Summary:      "List the credentials of enrolled targets",
+						ListResponse: []enrollment.Credential{},
+					},
+					fasthttp.MethodDelete: {
+						Summary: "Revoke the credential of a target",
+					},
+				},
+			},
 		},
 		{
 			Methods:    []string{fasthttp.MethodPost},
@@ -103,11 +182,6 @@ func (o *TargetsVendor) GetEndpoints() []v1alpha2.Endpoint {
 	}
 }
 
-type MyCustomClaims struct {
-	User string `json:"user"`
-	jwt.RegisteredClaims
-}
-
 func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
 		"method": "onRegistry",
@@ -126,13 +200,23 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		ctx, span := observability.StartSpan("onRegistry-GET", pCtx, nil)
 		var err error
 		var state interface{}
+		var next string
 		isArray := false
 		if id == "" {
 			// Change namespace back to empty to indicate ListSpec need to query all namespaces
 			if !exist {
 				namespace = ""
 			}
-			state, err = c.TargetsManager.ListState(ctx, namespace)
+			if isWatch(request) {
+				return watchResponse(ctx, span, func(ctx context.Context) (<-chan model.WatchEvent, error) {
+					return c.TargetsManager.WatchState(ctx, namespace)
+				})
+			}
+			var options states.ListOptions
+			options, err = readListOptions(request)
+			if err == nil {
+				state, next, err = c.TargetsManager.ListStatePage(ctx, namespace, options)
+			}
 			isArray = true
 		} else {
 			state, err = c.TargetsManager.GetState(ctx, id, namespace)
@@ -147,6 +231,7 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 		jData, _ := utils.FormatObject(state, isArray, request.Parameters["path"], request.Parameters["doc-type"])
 		resp := observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
 			State:       v1alpha2.OK,
+			Metadata:    pageMetadata(next),
 			Body:        jData,
 			ContentType: "application/json",
 		})
@@ -280,60 +365,6 @@ func (c *TargetsVendor) onRegistry(request v1alpha2.COARequest) v1alpha2.COAResp
 	return resp
 }
 
-func (c *TargetsVendor) onBootstrap(request v1alpha2.COARequest) v1alpha2.COAResponse {
-	ctx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
-		"method": "onBootstrap",
-	})
-	defer span.End()
-	tLog.InfofCtx(ctx, "V (Targets) : onBootstrap, method: %s", request.Method)
-	switch request.Method {
-	case fasthttp.MethodPost:
-		var authRequest utils.AuthRequest
-		err := utils2.UnmarshalJson(request.Body, &authRequest)
-		if err != nil || authRequest.UserName != "symphony-test" {
-			tLog.ErrorfCtx(ctx, "V (Targets) : onBootstrap failed - %s", err.Error())
-			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
-				State: v1alpha2.Unauthorized,
-				Body:  []byte(err.Error()),
-			})
-		}
-		mySigningKey := []byte("SymphonyKey")
-		claims := MyCustomClaims{
-			authRequest.UserName,
-			jwt.RegisteredClaims{
-				// A usual scenario is to set the expiration time relative to the current time
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-				IssuedAt:  jwt.NewNumericDate(time.Now()),
-				NotBefore: jwt.NewNumericDate(time.Now()),
-				Issuer:    "symphony",
-				Subject:   "symphony",
-				ID:        "1",
-				Audience:  []string{"*"},
-			},
-		}
-
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		ss, _ := token.SignedString(mySigningKey)
-
-		resp := v1alpha2.COAResponse{
-			State:       v1alpha2.OK,
-			Body:        []byte(`{"accessToken":"` + ss + `", "tokenType": "Bearer"}`),
-			ContentType: "application/json",
-		}
-
-		observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
-		return resp
-	}
-	tLog.ErrorCtx(ctx, "V (Targets) : onRegistry failed - method not allowed")
-	resp := v1alpha2.COAResponse{
-		State:       v1alpha2.MethodNotAllowed,
-		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
-		ContentType: "application/json",
-	}
-	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
-	return resp
-}
-
 func (c *TargetsVendor) onStatus(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	pCtx, span := observability.StartSpan("Targets Vendor", request.Context, &map[string]string{
 		"method": "onStatus",
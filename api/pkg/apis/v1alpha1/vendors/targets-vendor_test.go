@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/enrollment"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/validation"
@@ -30,7 +31,8 @@ func TestTargetsEndpoints(t *testing.T) {
 	vendor := createTargetsVendor()
 	vendor.Route = "targets"
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 5, len(endpoints))
+	assert.Equal(t, 7, len(endpoints))
+	assert.Nil(t, vendor.GetTokenValidator())
 }
 
 func TestTargetsInfo(t *testing.T) {
@@ -41,12 +43,16 @@ func TestTargetsInfo(t *testing.T) {
 	assert.Equal(t, "1.0", info.Version)
 }
 func createTargetsVendor() TargetsVendor {
+	return newTargetsVendor(false)
+}
+
+// newTargetsVendor creates a targets vendor, which enrolls targets if enroll is set
+func newTargetsVendor(enroll bool) TargetsVendor {
 	stateProvider := memorystate.MemoryStateProvider{}
 	stateProvider.Init(memorystate.MemoryStateProviderConfig{})
 	pubSubProvider := memory.InMemoryPubSubProvider{}
 	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
-	vendor := TargetsVendor{}
-	vendor.Init(vendors.VendorConfig{
+	config := vendors.VendorConfig{
 		Properties: map[string]string{
 			"test": "true",
 		},
@@ -65,17 +71,35 @@ func createTargetsVendor() TargetsVendor {
 				},
 			},
 		},
-	}, []managers.IManagerFactroy{
-		&sym_mgr.SymphonyManagerFactory{},
-	}, map[string]map[string]providers.IProvider{
+	}
+	vendorProviders := map[string]map[string]providers.IProvider{
 		"targets-manager": {
 			"mem-state": &stateProvider,
 		},
-	}, &pubSubProvider)
+	}
+	if enroll {
+		enrollmentState := memorystate.MemoryStateProvider{}
+		enrollmentState.Init(memorystate.MemoryStateProviderConfig{})
+		config.Managers = append(config.Managers, managers.ManagerConfig{
+			Name: "enrollment-manager",
+			Type: "managers.symphony.enrollment",
+			Properties: map[string]string{
+				"providers.persistentstate": "mem-state",
+			},
+		})
+		vendorProviders["enrollment-manager"] = map[string]providers.IProvider{
+			"mem-state": &enrollmentState,
+		}
+	}
+	vendor := TargetsVendor{}
+	vendor.Init(config, []managers.IManagerFactroy{
+		&sym_mgr.SymphonyManagerFactory{},
+	}, vendorProviders, &pubSubProvider)
 	vendor.Config.Properties["useJobManager"] = "true"
 	vendor.TargetsManager.TargetValidator = validation.NewTargetValidator(nil, nil)
 	return vendor
 }
+
 func TestTargetsOnRegistry(t *testing.T) {
 	vendor := createTargetsVendor()
 	target := model.TargetState{
@@ -140,22 +164,135 @@ func TestTargetsOnRegistry(t *testing.T) {
 	assert.Equal(t, v1alpha2.OK, resp.State)
 }
 func TestTargetsOnBootstrap(t *testing.T) {
+	// targets can't bootstrap without enrollment
 	vendor := createTargetsVendor()
-	authRequest := utils.AuthRequest{
-		UserName: "symphony-test",
-		Password: "",
-	}
-	data, _ := json.Marshal(authRequest)
+	data, _ := json.Marshal(enrollment.EnrollRequest{JoinToken: "symj_t1.secret"})
 	resp := vendor.onBootstrap(v1alpha2.COARequest{
 		Method:  fasthttp.MethodPost,
 		Body:    data,
 		Context: context.Background(),
 	})
+	assert.Equal(t, v1alpha2.MissingConfig, resp.State)
+
+	vendor = newTargetsVendor(true)
+	assert.Equal(t, vendor.EnrollmentManager, vendor.GetTokenValidator())
+	trails := make(chan v1alpha2.Trail, 10)
+	vendor.Context.Subscribe("trail", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			for _, trail := range event.Body.([]v1alpha2.Trail) {
+				trails <- trail
+			}
+			return nil
+		},
+	})
+
+	data, _ = json.Marshal(enrollment.JoinTokenSpec{Target: "target1"})
+	resp = vendor.onJoinTokens(adminRequest(fasthttp.MethodPost, "target1-join", data))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var joinToken enrollment.IssuedJoinToken
+	assert.Nil(t, json.Unmarshal(resp.Body, &joinToken))
+	trail := <-trails
+	assert.Equal(t, enrollment.TrailType, trail.Type)
+	assert.Equal(t, "create", trail.Properties["action"])
+	assert.NotContains(t, trail.Properties, "token")
+
+	bootstrap := func(request enrollment.EnrollRequest) v1alpha2.COAResponse {
+		data, _ := json.Marshal(request)
+		return vendor.onBootstrap(v1alpha2.COARequest{
+			Method:  fasthttp.MethodPost,
+			Body:    data,
+			Context: context.Background(),
+		})
+	}
+	// the old test user is not accepted anymore
+	data, _ = json.Marshal(utils.AuthRequest{UserName: "symphony-test"})
+	resp = vendor.onBootstrap(v1alpha2.COARequest{
+		Method:  fasthttp.MethodPost,
+		Body:    data,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.BadRequest, resp.State)
+	resp = bootstrap(enrollment.EnrollRequest{JoinToken: joinToken.Token, Target: "target2"})
+	assert.Equal(t, v1alpha2.Forbidden, resp.State)
+
+	resp = bootstrap(enrollment.EnrollRequest{JoinToken: joinToken.Token})
 	assert.Equal(t, v1alpha2.OK, resp.State)
-	var authResponse utils.AuthResponse
-	json.Unmarshal(resp.Body, &authResponse)
-	assert.NotNil(t, authResponse.AccessToken)
-	assert.Equal(t, "Bearer", authResponse.TokenType)
+	var credential enrollment.IssuedCredential
+	assert.Nil(t, json.Unmarshal(resp.Body, &credential))
+	assert.Equal(t, "Bearer", credential.TokenType)
+	assert.Equal(t, "target1", credential.Target)
+	trail = <-trails
+	assert.Equal(t, "enroll", trail.Properties["action"])
+
+	// join tokens can only be used once
+	resp = bootstrap(enrollment.EnrollRequest{JoinToken: joinToken.Token})
+	assert.Equal(t, v1alpha2.Unauthorized, resp.State)
+
+	principal, ok, err := vendor.GetTokenValidator().ValidateToken(context.Background(), credential.AccessToken)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "target:default/target1", principal.User)
+
+	resp = vendor.onCredentials(adminRequest(fasthttp.MethodGet, "target1", nil))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	resp = vendor.onCredentials(adminRequest(fasthttp.MethodDelete, "target1", nil))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	trail = <-trails
+	assert.Equal(t, "revoke", trail.Properties["action"])
+	_, _, err = vendor.GetTokenValidator().ValidateToken(context.Background(), credential.AccessToken)
+	assert.NotNil(t, err)
+	resp = vendor.onCredentials(adminRequest(fasthttp.MethodGet, "target1", nil))
+	assert.Equal(t, v1alpha2.NotFound, resp.State)
+}
+
+func TestTargetsBootstrapWithLabels(t *testing.T) {
+	vendor := newTargetsVendor(true)
+	target := model.TargetState{
+		ObjectMeta: model.ObjectMeta{
+			Name:   "target1-v1",
+			Labels: map[string]string{"line": "3"},
+		},
+		Spec: &model.TargetSpec{
+			DisplayName: "target1-v1",
+		},
+	}
+	data, _ := json.Marshal(target)
+	resp := vendor.onRegistry(v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Body:       data,
+		Parameters: map[string]string{"__name": "target1-v1"},
+		Context:    context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, resp.State)
+
+	data, _ = json.Marshal(enrollment.JoinTokenSpec{Labels: map[string]string{"line": "3"}})
+	resp = vendor.onJoinTokens(adminRequest(fasthttp.MethodPost, "", data))
+	assert.Equal(t, v1alpha2.OK, resp.State)
+	var joinToken enrollment.IssuedJoinToken
+	assert.Nil(t, json.Unmarshal(resp.Body, &joinToken))
+
+	bootstrap := func(name string) v1alpha2.COAResponse {
+		data, _ := json.Marshal(enrollment.EnrollRequest{JoinToken: joinToken.Token, Target: name})
+		return vendor.onBootstrap(v1alpha2.COARequest{
+			Method:  fasthttp.MethodPost,
+			Body:    data,
+			Context: context.Background(),
+		})
+	}
+	assert.Equal(t, v1alpha2.Forbidden, bootstrap("target2").State)
+	assert.Equal(t, v1alpha2.OK, bootstrap("target1-v1").State)
+}
+
+func TestTargetsEnrollmentRequiresAdministrator(t *testing.T) {
+	vendor := newTargetsVendor(true)
+	request := v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{},
+		Metadata:   map[string]string{v1alpha2.COARolesKey: "target"},
+	}
+	assert.Equal(t, v1alpha2.Forbidden, vendor.onJoinTokens(request).State)
+	assert.Equal(t, v1alpha2.Forbidden, vendor.onCredentials(request).State)
 }
 
 func TestTargetsOnStatus(t *testing.T) {
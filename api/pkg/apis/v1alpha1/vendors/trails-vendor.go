@@ -7,12 +7,16 @@
 package vendors
 
 import (
+	"encoding/json"
+	"strconv"
+
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/trails"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
 	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	utils2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
@@ -63,6 +67,12 @@ func (o *TrailsVendor) GetEndpoints() []v1alpha2.Endpoint {
 			Version: o.Version,
 			Handler: o.onTrails,
 		},
+		{
+			Methods: []string{fasthttp.MethodGet},
+			Route:   route + "/verify",
+			Version: o.Version,
+			Handler: o.onVerify,
+		},
 	}
 }
 
@@ -106,3 +116,42 @@ func (c *TrailsVendor) onTrails(request v1alpha2.COARequest) v1alpha2.COARespons
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+// onVerify verifies the ledgers of the trails manager. With the "seq" and "hash" of an entry that
+// an auditor wrote down earlier, it also checks that the ledgers still have that entry.
+func (c *TrailsVendor) onVerify(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	pCtx, span := observability.StartSpan("Trails Vendor", request.Context, &map[string]string{
+		"method": "onVerify",
+	})
+	defer span.End()
+	tLog.InfofCtx(pCtx, "V (Trails) : onVerify %s", request.Method)
+
+	var anchor *ledger.Anchor
+	seq, hasSeq := request.Parameters["seq"]
+	hash, hasHash := request.Parameters["hash"]
+	if hasSeq || hasHash {
+		sequence, err := strconv.ParseUint(seq, 10, 64)
+		if err != nil || hash == "" {
+			tLog.ErrorfCtx(pCtx, "V (Trails): onVerify failed to parse the anchor, seq: %s, hash: %s", seq, hash)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("an anchor needs both a numeric 'seq' and a 'hash'"),
+			})
+		}
+		anchor = &ledger.Anchor{Sequence: sequence, Hash: hash}
+	}
+	results, err := c.TrailsManager.Verify(pCtx, anchor)
+	if err != nil {
+		tLog.ErrorfCtx(pCtx, "V (Trails): onVerify failed to Verify, error: %v", err)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	jData, _ := json.Marshal(results)
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		Body:        jData,
+		ContentType: "application/json",
+	})
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
+	fileledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/file"
 	mockledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/mock"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
 	"github.com/stretchr/testify/assert"
@@ -125,12 +127,13 @@ func TestTrailsVendorGetInfo(t *testing.T) {
 func TestTrailsVendorEndopints(t *testing.T) {
 	vendor := createTrailsVendor("")
 	endpoints := vendor.GetEndpoints()
-	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, 2, len(endpoints))
 
 	vendor = createTrailsVendor("trails")
 	endpoints = vendor.GetEndpoints()
-	assert.Equal(t, 1, len(endpoints))
+	assert.Equal(t, 2, len(endpoints))
 	assert.Equal(t, "trails", endpoints[0].Route)
+	assert.Equal(t, "trails/verify", endpoints[1].Route)
 }
 
 func TestTrailsVendorOnTrails_PostEmptyArrayAsBody(t *testing.T) {
@@ -206,3 +209,60 @@ func TestTrailsVendorOnTrails_Get(t *testing.T) {
 	response := vendor.onTrails(*request)
 	assert.Equal(t, v1alpha2.MethodNotAllowed, response.State)
 }
+
+func TestTrailsVendorOnVerify(t *testing.T) {
+	fileProvider := &fileledger.FileLedgerProvider{}
+	err := fileProvider.Init(fileledger.FileLedgerProviderConfig{Folder: t.TempDir()})
+	assert.Nil(t, err)
+	vendor := TrailsVendor{}
+	err = vendor.Init(vendors.VendorConfig{
+		Type: "vendors.trails",
+		Managers: []managers.ManagerConfig{
+			{
+				Name:       "trails-manager",
+				Type:       "managers.symphony.trails",
+				Properties: map[string]string{},
+				Providers: map[string]managers.ProviderConfig{
+					"file": {
+						Type:   "providers.ledger.file",
+						Config: fileledger.FileLedgerProviderConfig{},
+					},
+				},
+			},
+		},
+	}, []managers.IManagerFactroy{
+		&sym_mgr.SymphonyManagerFactory{},
+	}, map[string]map[string]providers.IProvider{
+		"trails-manager": {
+			"file": fileProvider,
+		},
+	}, nil)
+	assert.Nil(t, err)
+	err = vendor.TrailsManager.Append(context.Background(), []v1alpha2.Trail{{Origin: "site1", Type: "t1"}})
+	assert.Nil(t, err)
+
+	response := vendor.onVerify(v1alpha2.COARequest{
+		Method:  fasthttp.MethodGet,
+		Context: context.Background(),
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var results map[string]ledger.VerifyResult
+	assert.Nil(t, json.Unmarshal(response.Body, &results))
+	assert.True(t, results["file"].Valid)
+
+	response = vendor.onVerify(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"seq": "2", "hash": "abc"},
+	})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Nil(t, json.Unmarshal(response.Body, &results))
+	assert.False(t, results["file"].Valid)
+
+	response = vendor.onVerify(v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Context:    context.Background(),
+		Parameters: map[string]string{"seq": "abc"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/users"
@@ -32,6 +33,16 @@ var rLog = logger.NewLogger("coa.runtime")
 type UsersVendor struct {
 	vendors.Vendor
 	UsersManager *users.UsersManager
+	// roles that can manage users
+	roles []string
+}
+
+// UserClaims are the claims of the tokens of users, with the roles that are assigned to them,
+// so that the JWT middleware can map the "roles" claim to roles
+type UserClaims struct {
+	User  string   `json:"user"`
+	Roles []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
 }
 
 func (o *UsersVendor) GetInfo() vendors.VendorInfo {
@@ -55,6 +66,10 @@ func (e *UsersVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	if e.UsersManager == nil {
 		return v1alpha2.NewCOAError(nil, "users manager is not supplied", v1alpha2.MissingConfig)
 	}
+	e.roles = []string{"administrator"}
+	if config.Properties != nil && strings.TrimSpace(config.Properties["roles"]) != "" {
+		e.roles = splitList(utils2.ParseProperty(config.Properties["roles"]))
+	}
 	if config.Properties != nil && config.Properties["test-users"] == "true" {
 		e.UsersManager.UpsertUser(context.Background(), "admin", "", nil)
 		e.UsersManager.UpsertUser(context.Background(), "reader", "", nil)
@@ -66,12 +81,42 @@ func (e *UsersVendor) Init(config vendors.VendorConfig, factories []managers.IMa
 	return nil
 }
 
+// GetTokenValidator returns the validator that rejects the tokens of disabled or deleted users
+func (e *UsersVendor) GetTokenValidator() v1alpha2.ITokenValidator {
+	return e.UsersManager
+}
+
 func (o *UsersVendor) GetEndpoints() []v1alpha2.Endpoint {
 	route := "users"
 	if o.Route != "" {
 		route = o.Route
 	}
 	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete},
+			Route:      route,
+			Version:    o.Version,
+			Handler:    o.onUsers,
+			Parameters: []string{"name?"},
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Manage users",
+				Operations: map[string]v1alpha2.OperationDocs{
+					fasthttp.MethodGet: {
+						Summary:      "Get a user, or list the users",
+						Response:     users.User{},
+						ListResponse: []users.User{},
+					},
+					fasthttp.MethodPost: {
+						Summary:  "Create or update a user",
+						Request:  users.UserUpdate{},
+						Response: users.User{},
+					},
+					fasthttp.MethodDelete: {
+						Summary: "Delete a user",
+					},
+				},
+			},
+		},
 		{
 			Methods: []string{fasthttp.MethodPost},
 			Route:   route + "/auth",
@@ -106,8 +151,9 @@ func (c *UsersVendor) onAuth(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	}
 
 	mySigningKey := []byte("SymphonyKey")
-	claims := MyCustomClaims{
+	claims := UserClaims{
 		authRequest.UserName,
+		roles,
 		jwt.RegisteredClaims{
 			// A usual scenario is to set the expiration time relative to the current time
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
@@ -133,3 +179,107 @@ func (c *UsersVendor) onAuth(request v1alpha2.COARequest) v1alpha2.COAResponse {
 	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
 	return resp
 }
+
+func (c *UsersVendor) onUsers(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	ctx, span := observability.StartSpan("Users Vendor", request.Context, &map[string]string{
+		"method": "onUsers",
+	})
+	defer span.End()
+	name := request.Parameters["__name"]
+	log.InfofCtx(ctx, "V (Users): onUsers, method: %s, name: %s", request.Method, name)
+
+	if resp, ok := requireAnyRole(ctx, request, c.roles, "managing users"); !ok {
+		return observ_utils.CloseSpanWithCOAResponse(span, resp)
+	}
+
+	switch request.Method {
+	case fasthttp.MethodGet:
+		var result interface{}
+		var err error
+		if name == "" {
+			result, err = c.UsersManager.ListUsers(ctx)
+		} else {
+			result, err = c.UsersManager.GetUser(ctx, name)
+		}
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (Users): onUsers failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		data, _ := json.Marshal(result)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodPost:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("user name is required"),
+			})
+		}
+		var update users.UserUpdate
+		err := utils2.UnmarshalJson(request.Body, &update)
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (Users): onUsers failed to unmarshall request body, error: %+v", err)
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte(err.Error()),
+			})
+		}
+		action := "update"
+		user, err := c.UsersManager.UpdateUser(ctx, name, update)
+		if v1alpha2.IsNotFound(err) {
+			action = "create"
+			user, err = c.UsersManager.CreateUser(ctx, name, update)
+		}
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (Users): onUsers failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, users.TrailType, action, callerTrail(request, map[string]interface{}{
+			"roles":           user.Roles,
+			"disabled":        user.Disabled,
+			"passwordChanged": update.Password != "",
+		}))
+		data, _ := json.Marshal(user)
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		})
+	case fasthttp.MethodDelete:
+		if name == "" {
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.BadRequest,
+				Body:  []byte("user name is required"),
+			})
+		}
+		err := c.UsersManager.DeleteUser(ctx, name)
+		if err != nil {
+			log.ErrorfCtx(ctx, "V (Users): onUsers failed - %s", err.Error())
+			return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+				State: v1alpha2.GetErrorState(err),
+				Body:  []byte(err.Error()),
+			})
+		}
+		publishTrail(ctx, c.Vendor.Context, users.TrailType, "delete", callerTrail(request, nil))
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.OK,
+		})
+	}
+	log.ErrorfCtx(ctx, "V (Users): onUsers failed - method not allowed")
+	resp := v1alpha2.COAResponse{
+		State:       v1alpha2.MethodNotAllowed,
+		Body:        []byte("{\"result\":\"405 - method not allowed\"}"),
+		ContentType: "application/json",
+	}
+	observ_utils.UpdateSpanStatusFromCOAResponse(span, resp)
+	return resp
+}
@@ -12,13 +12,17 @@ import (
 	"testing"
 
 	sym_mgr "github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers"
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/managers/users"
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub/memory"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states/memorystate"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/vendors"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func initVendor(t *testing.T) UsersVendor {
@@ -34,7 +38,7 @@ func initVendor(t *testing.T) UsersVendor {
 				Name: "users-manager",
 				Type: "managers.symphony.users",
 				Properties: map[string]string{
-					"providers.volatilestate": "mem-state",
+					"providers.persistentstate": "mem-state",
 				},
 				Providers: map[string]managers.ProviderConfig{
 					"mem-state": {
@@ -96,3 +100,107 @@ func TestUsersEndpoints(t *testing.T) {
 	assert.NotNil(t, endpoints)
 	assert.Equal(t, "user/auth", endpoints[len(endpoints)-1].Route)
 }
+
+func adminRequest(method string, name string, body []byte) v1alpha2.COARequest {
+	return v1alpha2.COARequest{
+		Context:    context.Background(),
+		Method:     method,
+		Body:       body,
+		Parameters: map[string]string{"__name": name},
+		Metadata: map[string]string{
+			v1alpha2.COAUserKey:  "admin",
+			v1alpha2.COARolesKey: "reader,administrator",
+		},
+	}
+}
+
+func TestUsersRequireAdministrator(t *testing.T) {
+	vendor := initVendor(t)
+	response := vendor.onUsers(v1alpha2.COARequest{
+		Context: context.Background(),
+		Method:  fasthttp.MethodGet,
+	})
+	assert.Equal(t, v1alpha2.Forbidden, response.State)
+	response = vendor.onUsers(v1alpha2.COARequest{
+		Context:  context.Background(),
+		Method:   fasthttp.MethodGet,
+		Metadata: map[string]string{v1alpha2.COARolesKey: "reader"},
+	})
+	assert.Equal(t, v1alpha2.Forbidden, response.State)
+	response = vendor.onUsers(adminRequest(fasthttp.MethodGet, "", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var list []users.User
+	assert.Nil(t, json.Unmarshal(response.Body, &list))
+	assert.Equal(t, 5, len(list))
+}
+
+func TestUsersCRUD(t *testing.T) {
+	vendor := initVendor(t)
+	pubSubProvider := memory.InMemoryPubSubProvider{}
+	pubSubProvider.Init(memory.InMemoryPubSubConfig{Name: "test"})
+	vendor.Context.Init(&pubSubProvider)
+	trails := make(chan v1alpha2.Trail, 10)
+	vendor.Context.Subscribe("trail", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			for _, trail := range event.Body.([]v1alpha2.Trail) {
+				trails <- trail
+			}
+			return nil
+		},
+	})
+
+	// a new user needs a password that meets the policy
+	data, _ := json.Marshal(users.UserUpdate{Roles: []string{"operator"}})
+	response := vendor.onUsers(adminRequest(fasthttp.MethodPost, "alice", data))
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+	data, _ = json.Marshal(users.UserUpdate{Password: "short", Roles: []string{"operator"}})
+	response = vendor.onUsers(adminRequest(fasthttp.MethodPost, "alice", data))
+	assert.Equal(t, v1alpha2.BadRequest, response.State)
+
+	data, _ = json.Marshal(users.UserUpdate{Password: "alice-password", Roles: []string{"operator"}})
+	response = vendor.onUsers(adminRequest(fasthttp.MethodPost, "alice", data))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.NotContains(t, string(response.Body), "alice-password")
+	trail := <-trails
+	assert.Equal(t, users.TrailType, trail.Type)
+	assert.Equal(t, "create", trail.Properties["action"])
+	assert.Equal(t, "alice", trail.Properties["id"])
+	assert.Equal(t, "admin", trail.Properties["user"])
+	assert.Equal(t, true, trail.Properties["passwordChanged"])
+
+	// the token of the user has its roles
+	authData, _ := json.Marshal(utils.AuthRequest{UserName: "alice", Password: "alice-password"})
+	response = vendor.onAuth(v1alpha2.COARequest{Context: context.Background(), Method: fasthttp.MethodPost, Body: authData})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	var auth map[string]interface{}
+	assert.Nil(t, json.Unmarshal(response.Body, &auth))
+	claims := jwt.MapClaims{}
+	_, _, err := new(jwt.Parser).ParseUnverified(auth["accessToken"].(string), claims)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"operator"}, claims["roles"])
+
+	disabled := true
+	data, _ = json.Marshal(users.UserUpdate{Disabled: &disabled})
+	response = vendor.onUsers(adminRequest(fasthttp.MethodPost, "alice", data))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	trail = <-trails
+	assert.Equal(t, "update", trail.Properties["action"])
+	assert.Equal(t, true, trail.Properties["disabled"])
+	assert.Equal(t, false, trail.Properties["passwordChanged"])
+	response = vendor.onAuth(v1alpha2.COARequest{Context: context.Background(), Method: fasthttp.MethodPost, Body: authData})
+	assert.Equal(t, v1alpha2.Unauthorized, response.State)
+
+	response = vendor.onUsers(adminRequest(fasthttp.MethodGet, "alice", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	user := users.User{}
+	assert.Nil(t, json.Unmarshal(response.Body, &user))
+	assert.True(t, user.Disabled)
+	assert.Equal(t, []string{"operator"}, user.Roles)
+
+	response = vendor.onUsers(adminRequest(fasthttp.MethodDelete, "alice", nil))
+	assert.Equal(t, v1alpha2.OK, response.State)
+	trail = <-trails
+	assert.Equal(t, "delete", trail.Properties["action"])
+	response = vendor.onUsers(adminRequest(fasthttp.MethodGet, "alice", nil))
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+}
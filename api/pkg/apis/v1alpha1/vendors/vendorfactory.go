@@ -43,6 +43,10 @@ func (c SymphonyVendorFactory) CreateVendor(config vendors.VendorConfig) (vendor
 		return &ActivationsVendor{}, nil
 	case "vendors.users":
 		return &UsersVendor{}, nil
+	case "vendors.apikeys":
+		return &ApiKeysVendor{}, nil
+	case "vendors.auth":
+		return &AuthVendor{}, nil
 	case "vendors.jobs":
 		return &JobVendor{}, nil
 	case "vendors.stage":
@@ -57,6 +61,8 @@ func (c SymphonyVendorFactory) CreateVendor(config vendors.VendorConfig) (vendor
 		return &ModelRouterVendor{}, nil
 	case "vendors.mcp":
 		return &MCPVendor{}, nil
+	case "vendors.events":
+		return &EventsVendor{}, nil
 	case "vendors.skills":
 		return &SkillsVendor{}, nil
 	case "vendors.settings":
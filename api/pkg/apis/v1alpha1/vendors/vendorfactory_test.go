@@ -56,6 +56,11 @@ func TestCreateVendor(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, vendor.(*CampaignVersionsVendor))
 
+	config.Type = "vendors.auth"
+	vendor, err = factory.CreateVendor(config)
+	assert.Nil(t, err)
+	assert.NotNil(t, vendor.(*AuthVendor))
+
 	config.Type = "vendors.catalogversions"
 	vendor, err = factory.CreateVendor(config)
 	assert.Nil(t, err)
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package vendors
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// An idle watch writes an empty line this often to find out whether the client is gone
+var watchKeepAlive = 30 * time.Second
+
+func isWatch(request v1alpha2.COARequest) bool {
+	return request.Parameters["watch"] == "true"
+}
+
+// watchResponse streams watch events as JSON objects, one per line, until the client
+// disconnects or the watch ends
+func watchResponse(ctx context.Context, span trace.Span, watch func(context.Context) (<-chan model.WatchEvent, error)) v1alpha2.COAResponse {
+	// The stream is written after the handler returns, so the watch outlives the request context
+	watchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	events, err := watch(watchCtx)
+	if err != nil {
+		cancel()
+		return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+			State: v1alpha2.GetErrorState(err),
+			Body:  []byte(err.Error()),
+		})
+	}
+	return observ_utils.CloseSpanWithCOAResponse(span, v1alpha2.COAResponse{
+		State:       v1alpha2.OK,
+		ContentType: "application/json",
+		Stream: func(w v1alpha2.StreamWriter) {
+			defer cancel()
+			ticker := time.NewTicker(watchKeepAlive)
+			defer ticker.Stop()
+			encoder := json.NewEncoder(w)
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					if encoder.Encode(event) != nil || w.Flush() != nil {
+						return
+					}
+				case <-ticker.C:
+					if _, err := w.Write([]byte("\n")); err != nil || w.Flush() != nil {
+						return
+					}
+				}
+			}
+		},
+	})
+}
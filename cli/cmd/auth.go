@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	canINamespace string
+	canIList      bool
+)
+
+var AuthCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect the authorization of the current user",
+}
+
+var AuthCanICmd = &cobra.Command{
+	Use:   "can-i [verb] [resource] [name]",
+	Short: "Check if the current user can do a verb on a resource",
+	Long: `Check if the user of the current context can do a verb (get, list, update or delete) on a
+resource, such as instances or activations/approve, under the RBAC policy of the Symphony API.
+Pass --list instead of a verb and resource to show the rules that apply to the user.`,
+	Args: cobra.MaximumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		if canIList != (len(args) == 0) || (!canIList && len(args) < 2) {
+			fmt.Printf("\n%s  Give a verb and a resource, or --list%s\n\n", utils.ColorRed(), utils.ColorReset())
+			os.Exit(1)
+		}
+		c := config.GetMaestroConfig(configFile)
+		ctx := c.DefaultContext
+		if configContext != "" {
+			ctx = configContext
+		}
+		if ctx == "" {
+			ctx = "default"
+		}
+		verb, resource, name := "", "", ""
+		if !canIList {
+			verb, resource = args[0], args[1]
+			if len(args) > 2 {
+				name = args[2]
+			}
+		}
+		review, err := utils.CanI(c.Contexts[ctx].Url, c.Contexts[ctx].User, c.Contexts[ctx].Secret, verb, resource, canINamespace, name)
+		if err != nil {
+			fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			os.Exit(1)
+		}
+		fmt.Println()
+		if !review.Enforced {
+			fmt.Printf("%s  No RBAC policy is enforced, the roles of the user decide%s\n\n", utils.ColorYellow(), utils.ColorReset())
+			return
+		}
+		if review.Decision != nil {
+			if review.Decision.Allowed {
+				fmt.Printf("%s  yes%s (%s)\n\n", utils.ColorGreen(), utils.ColorReset(), review.Decision.Reason)
+				return
+			}
+			fmt.Printf("%s  no%s (%s)\n\n", utils.ColorRed(), utils.ColorReset(), review.Decision.Reason)
+			os.Exit(1)
+		}
+		fmt.Printf("  user: %s, roles: %s\n\n", review.User, strings.Join(review.Roles, ", "))
+		if len(review.Rules) == 0 {
+			fmt.Printf("%s  No rule applies to the user%s\n\n", utils.ColorYellow(), utils.ColorReset())
+			return
+		}
+		for _, rule := range review.Rules {
+			fmt.Printf("  %s %s", strings.Join(rule.Verbs, ","), strings.Join(rule.Resources, ","))
+			if len(rule.Namespaces) > 0 {
+				fmt.Printf(" in %s", strings.Join(rule.Namespaces, ","))
+			}
+			if len(rule.Names) > 0 {
+				fmt.Printf(" named %s", strings.Join(rule.Names, ","))
+			}
+			labels := make([]string, 0, len(rule.LabelSelector))
+			for k, v := range rule.LabelSelector {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+			if len(labels) > 0 {
+				fmt.Printf(" labeled %s", strings.Join(labels, ","))
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	},
+}
+
+func init() {
+	AuthCanICmd.Flags().StringVarP(&canINamespace, "namespace", "n", "", "Namespace of the resource")
+	AuthCanICmd.Flags().BoolVar(&canIList, "list", false, "List the rules that apply to the current user")
+	AuthCanICmd.Flags().StringVarP(&configFile, "config", "c", "", "Maestro CLI config file")
+	AuthCanICmd.Flags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
+	AuthCmd.AddCommand(AuthCanICmd)
+	RootCmd.AddCommand(AuthCmd)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/eclipse-symphony/symphony/cli/config"
+	"github.com/eclipse-symphony/symphony/cli/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
+	fileledger "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger/file"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ledgerFolder string
+	ledgerKey    string
+	anchorSeq    uint64
+	anchorHash   string
+)
+
+var TrailsCmd = &cobra.Command{
+	Use:   "trails",
+	Short: "Audit the trails of Symphony objects",
+}
+
+var TrailsVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify that trail ledgers haven't been truncated or edited",
+	Long: `Verify the trail ledgers of the Symphony API in the current context, or the file ledger in
+the folder given with --folder. Pass the seq and hash of the head of an earlier verification
+with --seq and --hash to check that the ledgers still have it.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (anchorSeq == 0) != (anchorHash == "") {
+			fmt.Printf("\n%s  --seq and --hash must be given together%s\n\n", utils.ColorRed(), utils.ColorReset())
+			os.Exit(1)
+		}
+		var anchor *ledger.Anchor
+		if anchorSeq > 0 {
+			anchor = &ledger.Anchor{Sequence: anchorSeq, Hash: anchorHash}
+		}
+		results := make(map[string]ledger.VerifyResult)
+		if ledgerFolder != "" {
+			result, err := fileledger.VerifyFolder(ledgerFolder, ledgerKey, anchor)
+			if err != nil {
+				fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+				os.Exit(1)
+			}
+			results[ledgerFolder] = result
+		} else {
+			c := config.GetMaestroConfig(configFile)
+			ctx := c.DefaultContext
+			if configContext != "" {
+				ctx = configContext
+			}
+			if ctx == "" {
+				ctx = "default"
+			}
+			var err error
+			results, err = utils.VerifyTrails(c.Contexts[ctx].Url, c.Contexts[ctx].User, c.Contexts[ctx].Secret, anchorSeq, anchorHash)
+			if err != nil {
+				fmt.Printf("\n%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+				os.Exit(1)
+			}
+		}
+		if len(results) == 0 {
+			fmt.Printf("\n%s  No trail ledger can be verified%s\n\n", utils.ColorYellow(), utils.ColorReset())
+			return
+		}
+		names := make([]string, 0, len(results))
+		for name := range results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		valid := true
+		fmt.Println()
+		for _, name := range names {
+			result := results[name]
+			if result.Valid {
+				fmt.Printf("%s  %s: OK%s\n", utils.ColorGreen(), name, utils.ColorReset())
+			} else {
+				valid = false
+				fmt.Printf("%s  %s: TAMPERED%s\n", utils.ColorRed(), name, utils.ColorReset())
+				for _, problem := range result.Problems {
+					fmt.Printf("    %s\n", problem)
+				}
+			}
+			fmt.Printf("    %d entries in %d segments, head: --seq %d --hash %s\n", result.Entries, result.Segments, result.Head.Sequence, result.Head.Hash)
+		}
+		if anchor == nil {
+			fmt.Printf("\n%s  No anchor was given with --seq and --hash, so a ledger that was truncated along with its head isn't detected%s\n", utils.ColorYellow(), utils.ColorReset())
+		}
+		fmt.Println()
+		if !valid {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	TrailsVerifyCmd.Flags().StringVar(&ledgerFolder, "folder", "", "Folder of a file ledger to verify instead of the ledgers of the Symphony API")
+	TrailsVerifyCmd.Flags().StringVar(&ledgerKey, "key", "", "Key of the file ledger in --folder, if it has one")
+	TrailsVerifyCmd.Flags().Uint64Var(&anchorSeq, "seq", 0, "Sequence number of an entry the ledgers must have")
+	TrailsVerifyCmd.Flags().StringVar(&anchorHash, "hash", "", "Hash of the entry given with --seq")
+	TrailsVerifyCmd.Flags().StringVarP(&configFile, "config", "c", "", "Maestro CLI config file")
+	TrailsVerifyCmd.Flags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
+	TrailsCmd.AddCommand(TrailsVerifyCmd)
+	RootCmd.AddCommand(TrailsCmd)
+}
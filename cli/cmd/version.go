@@ -9,19 +9,50 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/eclipse-symphony/symphony/cli/config"
 	"github.com/eclipse-symphony/symphony/cli/utils"
 	"github.com/spf13/cobra"
 )
 
+var checkServer bool
+
 var VersionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Get CLI version",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("\n%s  Maestro Version: %s%s\n\n", utils.ColorPurple(), SymphonyAPIVersion, utils.ColorReset())
-
+		if !checkServer {
+			return
+		}
+		c := config.GetMaestroConfig(configFile)
+		ctx := c.DefaultContext
+		if configContext != "" {
+			ctx = configContext
+		}
+		if ctx == "" {
+			ctx = "default"
+		}
+		version, missing, err := utils.CheckServer(c.Contexts[ctx].Url, c.Contexts[ctx].User, c.Contexts[ctx].Secret)
+		if err != nil {
+			fmt.Printf("%s  %s%s\n\n", utils.ColorRed(), err.Error(), utils.ColorReset())
+			return
+		}
+		fmt.Printf("%s  Symphony API Version: %s%s\n\n", utils.ColorPurple(), version, utils.ColorReset())
+		if len(missing) == 0 {
+			fmt.Printf("%s  Symphony API serves all calls of maestro%s\n\n", utils.ColorGreen(), utils.ColorReset())
+			return
+		}
+		fmt.Printf("%s  Symphony API doesn't serve these calls of maestro:%s\n", utils.ColorYellow(), utils.ColorReset())
+		for _, call := range missing {
+			fmt.Printf("    %s\n", call)
+		}
+		fmt.Println()
 	},
 }
 
 func init() {
+	VersionCmd.Flags().BoolVar(&checkServer, "server", false, "Check the version and the calls of the Symphony API")
+	VersionCmd.Flags().StringVarP(&configFile, "config", "c", "", "Maestro CLI config file")
+	VersionCmd.Flags().StringVarP(&configContext, "context", "", "", "Maestro CLI configuration context")
 	RootCmd.AddCommand(VersionCmd)
 }
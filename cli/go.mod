@@ -12,7 +12,7 @@ require github.com/spf13/cobra v1.9.1
 
 require (
 	github.com/andybalholm/brotli v1.0.5 // indirect
-	github.com/eclipse-symphony/symphony/coa v0.0.0 // indirect
+	github.com/eclipse-symphony/symphony/coa v0.0.0
 	github.com/fatih/color v1.13.0 // indirect
 	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
 	github.com/go-logr/logr v1.4.2 // indirect
@@ -49,6 +49,17 @@ require (
 	github.com/princjef/mageutil v1.0.0
 )
 
+require (
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/contrib/bridges/otellogrus v0.3.0 // indirect
+	go.opentelemetry.io/otel v1.35.0 // indirect
+	go.opentelemetry.io/otel/log v0.8.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+	go.opentelemetry.io/otel/trace v1.35.0 // indirect
+	k8s.io/client-go v0.33.0 // indirect
+)
+
 require (
 	github.com/eclipse-symphony/symphony/api v0.0.0
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
@@ -13,7 +13,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
 	"sigs.k8s.io/yaml"
 )
 
@@ -274,3 +278,144 @@ func callRestAPI(url string, route string, method string, payload []byte, token
 	}
 	return bodyBytes, nil
 }
+
+// apiCall is a call maestro makes, with the path relative to the context url. Segments in
+// braces match any path parameter.
+type apiCall struct {
+	Method string
+	Path   string
+}
+
+// objectRoutes are the routes of the objects maestro gets, applies and removes
+var objectRoutes = []string{
+	"/activations/registry",
+	"/targets/registry",
+	"/devices",
+	"/solutions",
+	"/campaigns",
+	"/solutionversions",
+	"/instances",
+	"/catalogs",
+	"/catalogversions/registry",
+	"/campaignversions",
+}
+
+func maestroCalls() []apiCall {
+	ret := []apiCall{{Method: "POST", Path: "/users/auth"}}
+	for _, route := range objectRoutes {
+		ret = append(ret,
+			apiCall{Method: "GET", Path: route},
+			apiCall{Method: "GET", Path: route + "/{name}"},
+			apiCall{Method: "POST", Path: route + "/{name}"},
+			apiCall{Method: "DELETE", Path: route + "/{name}"})
+	}
+	return ret
+}
+
+type openAPIDocument struct {
+	Info struct {
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// CheckServer reads the OpenAPI document of the Symphony API and returns the version of the
+// server and the calls of maestro that the server doesn't have.
+func CheckServer(url string, username string, password string) (string, []string, error) {
+	token, err := Login(url, username, password)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := callRestAPI(url, "/openapi.json", "GET", nil, token, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if resp == nil {
+		return "", nil, errors.New("Symphony API doesn't serve an OpenAPI document")
+	}
+	var doc openAPIDocument
+	if err = json.Unmarshal(resp, &doc); err != nil {
+		return "", nil, err
+	}
+	return doc.Info.Version, missingCalls(doc, maestroCalls()), nil
+}
+
+// missingCalls returns the calls that are not in the document. Document paths start with
+// the API version, which is part of the context url of maestro.
+func missingCalls(doc openAPIDocument, calls []apiCall) []string {
+	served := make(map[string]bool)
+	for path, item := range doc.Paths {
+		segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+		for method := range item {
+			served[strings.ToUpper(method)+" "+pathPattern(segments[1:])] = true
+		}
+	}
+	ret := make([]string, 0)
+	for _, call := range calls {
+		if !served[call.Method+" "+pathPattern(strings.Split(strings.TrimPrefix(call.Path, "/"), "/"))] {
+			ret = append(ret, call.Method+" "+call.Path)
+		}
+	}
+	return ret
+}
+
+// pathPattern joins path segments with the path parameters replaced by "*"
+func pathPattern(segments []string) string {
+	ret := make([]string, len(segments))
+	for i, s := range segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			s = "*"
+		}
+		ret[i] = s
+	}
+	return "/" + strings.Join(ret, "/")
+}
+
+// VerifyTrails asks the Symphony API to verify its trail ledgers. seq and hash are an entry an
+// auditor wrote down earlier, which the ledgers must still have. They're skipped if seq is 0.
+func VerifyTrails(url string, username string, password string, seq uint64, hash string) (map[string]ledger.VerifyResult, error) {
+	token, err := Login(url, username, password)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string)
+	if seq > 0 {
+		params["seq"] = strconv.FormatUint(seq, 10)
+		params["hash"] = hash
+	}
+	resp, err := callRestAPI(url, "/trails/verify", "GET", nil, token, params)
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("Symphony API doesn't serve trails")
+	}
+	var ret map[string]ledger.VerifyResult
+	err = json.Unmarshal(resp, &ret)
+	return ret, err
+}
+
+// CanI asks the Symphony API if the user can do a verb on a resource, or which rules of the RBAC
+// policy apply to the user if verb is empty. The namespace and name are skipped if they're empty.
+func CanI(url string, username string, password string, verb string, resource string, namespace string, name string) (v1alpha2.AccessReview, error) {
+	var ret v1alpha2.AccessReview
+	token, err := Login(url, username, password)
+	if err != nil {
+		return ret, err
+	}
+	params := make(map[string]string)
+	for k, v := range map[string]string{"verb": verb, "resource": resource, "namespace": namespace, "name": name} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	resp, err := callRestAPI(url, "/auth/can-i", "GET", nil, token, params)
+	if err != nil {
+		return ret, err
+	}
+	if resp == nil {
+		return ret, errors.New("Symphony API doesn't serve auth")
+	}
+	err = json.Unmarshal(resp, &ret)
+	return ret, err
+}
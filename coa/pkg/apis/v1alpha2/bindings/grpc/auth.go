@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authorizer applies the checks of the "middleware.http.jwt" middleware to gRPC calls, reading
+// the token from the call metadata and checking the RBAC policy against the route and method
+// of the request.
+type authorizer struct {
+	jwt *http.JWT
+}
+
+func buildAuthorizer(pipeline []http.MiddlewareConfig, tokenValidator v1alpha2.ITokenValidator) (*authorizer, error) {
+	ret := &authorizer{}
+	for _, c := range pipeline {
+		if c.Type != "middleware.http.jwt" {
+			log.Debugf("G (GrpcBinding): middleware '%s' doesn't apply to gRPC calls", c.Type)
+			continue
+		}
+		jwts := http.JWT{}
+		jData, _ := json.Marshal(c.Properties)
+		err := json.Unmarshal(jData, &jwts)
+		if err != nil {
+			return nil, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
+		}
+		jwts.TokenValidator = tokenValidator
+		if err := jwts.Init(); err != nil {
+			return nil, err
+		}
+		ret.jwt = &jwts
+	}
+	return ret, nil
+}
+
+// authorize checks the caller of a request, and passes the Authorization header and the
+// caller's user and roles to the handler in the request metadata. It returns the RBAC policy
+// and the policy of the caller that the request is checked with once its endpoint is known, if
+// there are any.
+func (a *authorizer) authorize(ctx context.Context, request *v1alpha2.COARequest) (*v1alpha2.RBACPolicy, *v1alpha2.RBACPolicy, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if auth := firstValue(md, "authorization"); auth != "" {
+		if request.Metadata == nil {
+			request.Metadata = make(map[string]string)
+		}
+		request.Metadata["Authorization"] = auth
+	}
+	if a.jwt == nil || a.jwt.IsIgnoredPath(request.Route) {
+		return nil, nil, nil
+	}
+	tokenStr := ""
+	if token := strings.Split(firstValue(md, strings.ToLower(a.jwt.AuthHeader)), "Bearer "); len(token) == 2 {
+		tokenStr = strings.TrimSpace(token[1])
+	}
+	principal, err := a.jwt.AuthorizePrincipal(ctx, tokenStr, request.Route, request.Method, request.Parameters["namespace"])
+	if err != nil {
+		return nil, nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if request.Metadata == nil {
+		request.Metadata = make(map[string]string)
+	}
+	if principal.User != "" {
+		request.Metadata[v1alpha2.COAUserKey] = principal.User
+	}
+	if principal.Roles != nil {
+		request.Metadata[v1alpha2.COARolesKey] = strings.Join(principal.Roles, ",")
+	}
+	if len(principal.Namespaces) > 0 {
+		request.Metadata[v1alpha2.COANamespacesKey] = strings.Join(principal.Namespaces, ",")
+	}
+	return a.jwt.RBAC, principal.Policy, nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs"
+	autogen "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	localfile "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/localfile"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger/contexts"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+var log = logger.NewLogger("coa.runtime")
+
+const (
+	// GenericServiceName is the name of the service that exposes every endpoint
+	GenericServiceName = "coa.v1alpha2.COA"
+	// CodecName is the content subtype of the messages, which are JSON documents
+	CodecName = "json"
+)
+
+// GrpcBindingConfig configures a GrpcBinding.
+type GrpcBindingConfig struct {
+	Port         int                     `json:"port"`
+	TLS          bool                    `json:"tls"`
+	CertProvider http.CertProviderConfig `json:"certProvider"`
+	// Pipeline takes the same middleware as the HTTP binding. Only "middleware.http.jwt" applies
+	// to gRPC calls; other middleware is ignored.
+	Pipeline []http.MiddlewareConfig `json:"pipeline"`
+	// Version is the API version of the routes the object services call, "v1alpha2" by default.
+	Version string `json:"version,omitempty"`
+	// TokenValidator is passed to the JWT middleware, to validate tokens that aren't JWTs
+	TokenValidator v1alpha2.ITokenValidator `json:"-"`
+	// Objects are the object services to serve. The core objects are served by default.
+	Objects []ObjectServiceConfig `json:"objects,omitempty"`
+	// SummaryPollSeconds is how often summary streams check for changes, 2 seconds by default.
+	SummaryPollSeconds int `json:"summaryPollSeconds,omitempty"`
+}
+
+// GrpcBinding provides service endpoints as a gRPC server. A generic service takes
+// v1alpha2.COARequest messages for any endpoint, and object services offer typed calls for the
+// core objects on top of the same endpoints. Messages are encoded as JSON (see Codec).
+type GrpcBinding struct {
+	CertProvider certs.ICertProvider
+	server       *gogrpc.Server
+	router       *router
+	auth         *authorizer
+}
+
+// Codec encodes gRPC messages as JSON. Clients need to use it too, for example with
+// grpc.WithDefaultCallOptions(grpc.ForceCodec(Codec{})).
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (Codec) Name() string {
+	return CodecName
+}
+
+// Launch gRPC server
+func (g *GrpcBinding) Launch(config GrpcBindingConfig, endpoints []v1alpha2.Endpoint) error {
+	err := g.init(config, endpoints)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to listen on port %d", config.Port), v1alpha2.InternalError)
+	}
+	go func() {
+		if err := g.server.Serve(listener); err != nil {
+			log.Errorf("G (GrpcBinding): Server error: %s", err.Error())
+		}
+	}()
+	log.Debugf("G (GrpcBinding): Server started on port: %d", config.Port)
+	return nil
+}
+
+// init creates the server with its services
+func (g *GrpcBinding) init(config GrpcBindingConfig, endpoints []v1alpha2.Endpoint) error {
+	var err error
+	g.auth, err = buildAuthorizer(config.Pipeline, config.TokenValidator)
+	if err != nil {
+		return err
+	}
+	g.router = newRouter(endpoints)
+
+	options := []gogrpc.ServerOption{gogrpc.ForceServerCodec(Codec{})}
+	if config.TLS {
+		switch config.CertProvider.Type {
+		case "certs.autogen":
+			g.CertProvider = &autogen.AutoGenCertProvider{}
+		case "certs.localfile":
+			g.CertProvider = &localfile.LocalCertFileProvider{}
+		default:
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("cert provider type '%s' is not recognized", config.CertProvider.Type), v1alpha2.BadConfig)
+		}
+		err = g.CertProvider.Init(config.CertProvider.Config)
+		if err != nil {
+			return err
+		}
+		cert, key, err := g.CertProvider.GetCert("localhost") //TODO: user proper host/DNS name
+		if err != nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("error getting TLS certificates: %s", err.Error()), v1alpha2.BadConfig)
+		}
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "error reading TLS certificates", v1alpha2.BadConfig)
+		}
+		options = append(options, gogrpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{pair},
+			MinVersion:   tls.VersionTLS12,
+		})))
+	}
+
+	g.server = gogrpc.NewServer(options...)
+	g.server.RegisterService(&genericServiceDesc, g)
+	objects := config.Objects
+	if len(objects) == 0 {
+		objects = defaultObjectServices
+	}
+	version := config.Version
+	if version == "" {
+		version = "v1alpha2"
+	}
+	pollInterval := time.Duration(config.SummaryPollSeconds) * time.Second
+	if pollInterval <= 0 {
+		pollInterval = defaultSummaryPollInterval
+	}
+	for _, o := range objects {
+		service := &objectService{binding: g, config: o, version: version, pollInterval: pollInterval}
+		g.server.RegisterService(service.desc(), service)
+	}
+	return nil
+}
+
+// Shutdown gRPC server. Calls that are still running when the context ends are cancelled.
+func (g *GrpcBinding) Shutdown(ctx context.Context) error {
+	if g.server == nil {
+		return nil
+	}
+	stopped := make(chan struct{})
+	go func() {
+		g.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		g.server.Stop()
+	}
+	return nil
+}
+
+// genericService is implemented by GrpcBinding
+type genericService interface {
+	invoke(ctx context.Context, request *v1alpha2.COARequest) (*v1alpha2.COAResponse, error)
+	invokeStream(request *v1alpha2.COARequest, stream gogrpc.ServerStream) error
+}
+
+var genericServiceDesc = gogrpc.ServiceDesc{
+	ServiceName: GenericServiceName,
+	HandlerType: (*genericService)(nil),
+	Methods: []gogrpc.MethodDesc{
+		{
+			MethodName: "Invoke",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+				request := &v1alpha2.COARequest{}
+				if err := dec(request); err != nil {
+					return nil, err
+				}
+				return srv.(genericService).invoke(ctx, request)
+			},
+		},
+	},
+	Streams: []gogrpc.StreamDesc{
+		{
+			StreamName:    "InvokeStream",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream gogrpc.ServerStream) error {
+				request := &v1alpha2.COARequest{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				return srv.(genericService).invokeStream(request, stream)
+			},
+		},
+	},
+}
+
+// invoke calls the endpoint of a request. The state of the response tells how the call went,
+// as the status code does over HTTP. Responses that are streamed need InvokeStream.
+func (g *GrpcBinding) invoke(ctx context.Context, request *v1alpha2.COARequest) (*v1alpha2.COAResponse, error) {
+	response, err := g.dispatch(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if response.Stream != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "the response of %s %s is streamed, call InvokeStream instead", request.Method, request.Route)
+	}
+	return &response, nil
+}
+
+// invokeStream calls the endpoint of a request and sends its response as a stream of
+// v1alpha2.COAResponse messages. The first one has the state, content type and metadata of
+// the response, and each one after it has a part of the body. Responses that aren't streamed
+// are sent as a single message.
+func (g *GrpcBinding) invokeStream(request *v1alpha2.COARequest, stream gogrpc.ServerStream) error {
+	response, err := g.dispatch(stream.Context(), request)
+	if err != nil {
+		return err
+	}
+	if response.Stream == nil {
+		return stream.SendMsg(&response)
+	}
+	if err := stream.SendMsg(&v1alpha2.COAResponse{
+		State:       response.State,
+		ContentType: response.ContentType,
+		Metadata:    response.Metadata,
+	}); err != nil {
+		return err
+	}
+	return runStream(stream.Context(), response.Stream, func(data []byte) error {
+		return stream.SendMsg(&v1alpha2.COAResponse{Body: data})
+	})
+}
+
+// dispatch authorizes a request and passes it to the handler of its endpoint. Errors are only
+// returned for calls that are refused; handler failures are in the state of the response.
+func (g *GrpcBinding) dispatch(ctx context.Context, request *v1alpha2.COARequest) (v1alpha2.COAResponse, error) {
+	// the route is checked by the JWT policy as it's matched by the router
+	request.Route = "/" + strings.Join(splitPath(strings.SplitN(request.Route, "?", 2)[0]), "/")
+	// values that are set by the binding can't be smuggled in by the client
+	delete(request.Metadata, "Authorization")
+	delete(request.Metadata, v1alpha2.COAUserKey)
+	delete(request.Metadata, v1alpha2.COARolesKey)
+	delete(request.Metadata, v1alpha2.COANamespacesKey)
+	for k := range request.Parameters {
+		if strings.HasPrefix(k, "__") {
+			delete(request.Parameters, k)
+		}
+	}
+	policy, callerPolicy, err := g.auth.authorize(ctx, request)
+	if err != nil {
+		return v1alpha2.COAResponse{}, err
+	}
+
+	endpoint, parameters, state := g.router.match(request.Method, request.Route)
+	if state != v1alpha2.OK {
+		return v1alpha2.COAResponse{
+			State:       state,
+			ContentType: "text/plain",
+			Body:        []byte(fmt.Sprintf("no endpoint for %s %s", request.Method, request.Route)),
+		}, nil
+	}
+	if request.Parameters == nil {
+		request.Parameters = make(map[string]string)
+	}
+	for k, v := range parameters {
+		request.Parameters[k] = v
+	}
+	request.Context = composeCOARequestContext(ctx, request.Metadata)
+	if policy != nil || callerPolicy != nil || request.Metadata[v1alpha2.COANamespacesKey] != "" {
+		access, decision := http.CheckAccess(endpoint, *request, policy, callerPolicy)
+		if !decision.Allowed {
+			log.Infof("G (GrpcBinding): '%s' with roles %v is denied: %s", access.User, access.Roles, decision.Reason)
+			return v1alpha2.COAResponse{}, status.Error(codes.PermissionDenied, decision.Reason)
+		}
+	}
+	if policy != nil {
+		request.Context = context.WithValue(request.Context, v1alpha2.COAAccessPolicyKey, policy)
+	}
+	return endpoint.Handler(*request), nil
+}
+
+// composeCOARequestContext keeps the values of the call context, such as its metadata, but not
+// its cancellation, since handlers may carry on with the request context once they return, as
+// they do with the HTTP binding.
+func composeCOARequestContext(ctx context.Context, metadata map[string]string) context.Context {
+	retCtx := context.WithoutCancel(ctx)
+	if actCtx := contexts.ParseActivityLogContextFromMetadata(metadata); actCtx != nil {
+		retCtx = context.WithValue(retCtx, contexts.ActivityLogContextKey, actCtx)
+	}
+	if diagCtx := contexts.ParseDiagnosticLogContextFromMetadata(metadata); diagCtx != nil {
+		retCtx = context.WithValue(retCtx, contexts.DiagnosticLogContextKey, diagCtx)
+	}
+	// patch correlation id if missing
+	return contexts.GenerateCorrelationIdToParentContextIfMissing(retCtx)
+}
@@ -0,0 +1,341 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
+	autogen "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/certs/autogen"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startBinding serves a binding over an in-memory connection and returns a client connection
+func startBinding(t *testing.T, config GrpcBindingConfig, endpoints []v1alpha2.Endpoint) *gogrpc.ClientConn {
+	binding := &GrpcBinding{}
+	err := binding.init(config, endpoints)
+	assert.Nil(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	go binding.server.Serve(listener)
+	t.Cleanup(func() {
+		binding.Shutdown(context.Background())
+	})
+	conn, err := gogrpc.NewClient("passthrough:///bufnet",
+		gogrpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		gogrpc.WithTransportCredentials(insecure.NewCredentials()),
+		gogrpc.WithDefaultCallOptions(gogrpc.ForceCodec(Codec{})))
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+// echoHandler returns the method, parameters and metadata of the request
+func echoHandler(request v1alpha2.COARequest) v1alpha2.COAResponse {
+	data, _ := json.Marshal(map[string]interface{}{
+		"method":     request.Method,
+		"parameters": request.Parameters,
+		"metadata":   request.Metadata,
+		"body":       string(request.Body),
+	})
+	return v1alpha2.COAResponse{State: v1alpha2.OK, ContentType: "application/json", Body: data}
+}
+
+type echo struct {
+	Method     string            `json:"method"`
+	Parameters map[string]string `json:"parameters"`
+	Metadata   map[string]string `json:"metadata"`
+	Body       string            `json:"body"`
+}
+
+func invoke(t *testing.T, ctx context.Context, conn *gogrpc.ClientConn, request v1alpha2.COARequest) (v1alpha2.COAResponse, echo, error) {
+	response := v1alpha2.COAResponse{}
+	err := conn.Invoke(ctx, "/"+GenericServiceName+"/Invoke", &request, &response)
+	ret := echo{}
+	if err == nil && response.State == v1alpha2.OK {
+		assert.Nil(t, json.Unmarshal(response.Body, &ret))
+	}
+	return response, ret, err
+}
+
+func routingEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{"GET", "POST"},
+			Route:      "instances",
+			Version:    "v1alpha2",
+			Parameters: []string{"name?"},
+			Handler:    echoHandler,
+		},
+		{
+			Methods:    []string{"GET"},
+			Route:      "instances/{name}/history",
+			Version:    "v1alpha2",
+			Parameters: []string{"revision?"},
+			Handler:    echoHandler,
+		},
+		{
+			Methods: []string{"GET"},
+			Route:   "instances/status",
+			Version: "v1alpha2",
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte("{\"method\":\"status\"}")}
+			},
+		},
+	}
+}
+
+func TestInvokeRoutesToEndpoints(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{}, routingEndpoints())
+	ctx := context.Background()
+
+	response, ret, err := invoke(t, ctx, conn, v1alpha2.COARequest{
+		Method:     "POST",
+		Route:      "/v1alpha2/instances/instance1",
+		Body:       []byte("{}"),
+		Parameters: map[string]string{"namespace": "ns1", "__name": "smuggled"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, "POST", ret.Method)
+	assert.Equal(t, "{}", ret.Body)
+	assert.Equal(t, map[string]string{"namespace": "ns1", "__name": "instance1"}, ret.Parameters)
+
+	_, ret, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"__name": ""}, ret.Parameters)
+
+	_, ret, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances/instance1/history/3"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"__name": "instance1", "__revision": "3"}, ret.Parameters)
+
+	// literal segments win over parameters
+	_, ret, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances/status"})
+	assert.Nil(t, err)
+	assert.Equal(t, "status", ret.Method)
+
+	response, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "DELETE", Route: "/v1alpha2/instances/instance1"})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.MethodNotAllowed, response.State)
+
+	response, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/solutions/solution1"})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.NotFound, response.State)
+}
+
+func TestInvokeStream(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{}, []v1alpha2.Endpoint{
+		{
+			Methods: []string{"GET"},
+			Route:   "events",
+			Version: "v1alpha2",
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					State:       v1alpha2.OK,
+					ContentType: v1alpha2.SSEContentType,
+					Stream: func(w v1alpha2.StreamWriter) {
+						for i := 0; i < 3; i++ {
+							fmt.Fprintf(w, "event %d\n", i)
+							if w.Flush() != nil {
+								return
+							}
+						}
+					},
+				}
+			},
+		},
+	})
+	ctx := context.Background()
+	request := &v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/events"}
+
+	_, _, err := invoke(t, ctx, conn, *request)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	stream, err := conn.NewStream(ctx, &gogrpc.StreamDesc{ServerStreams: true}, "/"+GenericServiceName+"/InvokeStream")
+	assert.Nil(t, err)
+	assert.Nil(t, stream.SendMsg(request))
+	assert.Nil(t, stream.CloseSend())
+	responses := make([]v1alpha2.COAResponse, 0)
+	for {
+		response := v1alpha2.COAResponse{}
+		err := stream.RecvMsg(&response)
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		responses = append(responses, response)
+	}
+	assert.Equal(t, 4, len(responses))
+	assert.Equal(t, v1alpha2.OK, responses[0].State)
+	assert.Equal(t, v1alpha2.SSEContentType, responses[0].ContentType)
+	assert.Equal(t, "event 2\n", string(responses[3].Body))
+}
+
+func generateToken(t *testing.T, user string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":  http.SymphonyIssuer,
+		"user": user,
+		"exp":  time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("test"))
+	assert.Nil(t, err)
+	return token
+}
+
+func TestInvokeChecksJWT(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{
+		Pipeline: []http.MiddlewareConfig{
+			{
+				Type: "middleware.http.jwt",
+				Properties: map[string]interface{}{
+					"verifyKey":   "test",
+					"enableRBAC":  true,
+					"ignorePaths": []string{"/v1alpha2/instances/status"},
+					"roles": []map[string]string{
+						{"role": "reader", "claim": "user", "value": "*"},
+					},
+					"policy": map[string]interface{}{
+						"reader": map[string]interface{}{
+							"items": map[string]string{"/v1alpha2/instances": "GET"},
+						},
+					},
+				},
+			},
+			{Type: "middleware.http.cors"},
+		},
+	}, routingEndpoints())
+
+	// no token
+	_, _, err := invoke(t, context.Background(), conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances/instance1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// ignored paths don't need a token
+	response, _, err := invoke(t, context.Background(), conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances/status"})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+generateToken(t, "user1"))
+	response, ret, err := invoke(t, ctx, conn, v1alpha2.COARequest{
+		Method:   "GET",
+		Route:    "/v1alpha2/instances/instance1",
+		Metadata: map[string]string{v1alpha2.COAUserKey: "admin", v1alpha2.COARolesKey: "administrator"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, "user1", ret.Metadata[v1alpha2.COAUserKey])
+	assert.Equal(t, "reader", ret.Metadata[v1alpha2.COARolesKey])
+	assert.Contains(t, ret.Metadata["Authorization"], "Bearer ")
+
+	// the policy is checked against the method and route of the request
+	_, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "POST", Route: "/v1alpha2/instances/instance1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "//v1alpha2//instances/instance1"})
+	assert.Nil(t, err)
+}
+
+func TestInvokeChecksRBACPolicy(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{
+		Pipeline: []http.MiddlewareConfig{
+			{
+				Type: "middleware.http.jwt",
+				Properties: map[string]interface{}{
+					"verifyKey": "test",
+					"roles": []map[string]string{
+						{"role": "operator", "claim": "user", "value": "*"},
+					},
+					"rbac": map[string]interface{}{
+						"rules": []map[string]interface{}{
+							{
+								"subjects":   []map[string]string{{"role": "operator"}},
+								"verbs":      []string{"get", "list", "update"},
+								"resources":  []string{"instances"},
+								"namespaces": []string{"line-3"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, routingEndpoints())
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+generateToken(t, "user1"))
+	response, _, err := invoke(t, ctx, conn, v1alpha2.COARequest{
+		Method:     "POST",
+		Route:      "/v1alpha2/instances/instance1",
+		Parameters: map[string]string{"namespace": "line-3"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, response.State)
+
+	_, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{Method: "POST", Route: "/v1alpha2/instances/instance1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// the history of instances is another resource
+	_, _, err = invoke(t, ctx, conn, v1alpha2.COARequest{
+		Method:     "GET",
+		Route:      "/v1alpha2/instances/instance1/history",
+		Parameters: map[string]string{"namespace": "line-3"},
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestLaunchWithTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	assert.Nil(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	binding := &GrpcBinding{}
+	err = binding.Launch(GrpcBindingConfig{
+		Port: port,
+		TLS:  true,
+		CertProvider: http.CertProviderConfig{
+			Type:   "certs.autogen",
+			Config: autogen.AutoGenCertProviderConfig{Name: "test"},
+		},
+	}, routingEndpoints())
+	assert.Nil(t, err)
+	defer binding.Shutdown(context.Background())
+
+	conn, err := gogrpc.NewClient(fmt.Sprintf("localhost:%d", port),
+		gogrpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})),
+		gogrpc.WithDefaultCallOptions(gogrpc.ForceCodec(Codec{})))
+	assert.Nil(t, err)
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	response, _, err := invoke(t, ctx, conn, v1alpha2.COARequest{Method: "GET", Route: "/v1alpha2/instances/instance1"})
+	assert.Nil(t, err)
+	assert.Equal(t, v1alpha2.OK, response.State)
+}
+
+func TestLaunchWithUnknownCertProvider(t *testing.T) {
+	binding := &GrpcBinding{}
+	err := binding.Launch(GrpcBindingConfig{
+		TLS:          true,
+		CertProvider: http.CertProviderConfig{Type: "certs.unknown"},
+	}, nil)
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+}
@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultSummaryPollInterval = 2 * time.Second
+	// the annotation that makes the summary id of an object unique across re-creations
+	guidAnnotation = "Guid"
+	// the summary state of a finished reconcile
+	summaryStateDone = 2
+)
+
+// ObjectServiceConfig configures a service with typed calls for a kind of objects, on top of
+// the endpoints of their route.
+type ObjectServiceConfig struct {
+	// Service is the full name of the gRPC service, such as "symphony.v1alpha2.Instances".
+	Service string `json:"service"`
+	// Route is the route of the objects, such as "instances", which takes the object name as
+	// its parameter.
+	Route string `json:"route"`
+	// SummaryRoute, when set, is the route of the deployment summaries of the objects, which
+	// the WatchSummary call polls.
+	SummaryRoute string `json:"summaryRoute,omitempty"`
+}
+
+var defaultObjectServices = []ObjectServiceConfig{
+	{Service: "symphony.v1alpha2.Targets", Route: "targets/registry"},
+	{Service: "symphony.v1alpha2.SolutionVersions", Route: "solutionversions"},
+	{Service: "symphony.v1alpha2.Instances", Route: "instances", SummaryRoute: "solutionversion/queue"},
+	{Service: "symphony.v1alpha2.Activations", Route: "activations/registry"},
+	{Service: "symphony.v1alpha2.CatalogVersions", Route: "catalogversions/registry"},
+}
+
+// ObjectRequest is the request of the calls of object services.
+type ObjectRequest struct {
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Object is the object to apply.
+	Object json.RawMessage `json:"object,omitempty"`
+	// Parameters are passed on to the endpoint, such as "filterType" and "filterValue" of lists.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// ObjectResponse has what an endpoint returned: an object, a list of objects or a summary.
+// Responses that aren't JSON are in Message.
+type ObjectResponse struct {
+	Object  json.RawMessage `json:"object,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// WatchEvent is a change of an object sent by Watch calls.
+type WatchEvent struct {
+	Type            string          `json:"type"`
+	Object          json.RawMessage `json:"object"`
+	ResourceVersion string          `json:"resourceVersion,omitempty"`
+}
+
+// objectMeta has the object metadata the object services need
+type objectMeta struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		ETag        string            `json:"etag,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+}
+
+type summaryState struct {
+	Generation string `json:"generation"`
+	State      int    `json:"state"`
+}
+
+// objectService serves Get, List, Apply, Delete and Watch calls for a kind of objects, and
+// WatchSummary calls if they have summaries. The calls are made to the endpoints of the
+// objects, so they're authorized as the equivalent REST calls are.
+type objectService struct {
+	binding      *GrpcBinding
+	config       ObjectServiceConfig
+	version      string
+	pollInterval time.Duration
+}
+
+func (s *objectService) desc() *gogrpc.ServiceDesc {
+	desc := &gogrpc.ServiceDesc{
+		ServiceName: s.config.Service,
+		HandlerType: (*interface{})(nil),
+		Methods: []gogrpc.MethodDesc{
+			s.unary("Get", fasthttp.MethodGet, true),
+			s.unary("List", fasthttp.MethodGet, false),
+			s.unary("Apply", fasthttp.MethodPost, true),
+			s.unary("Delete", fasthttp.MethodDelete, true),
+		},
+		Streams: []gogrpc.StreamDesc{
+			{
+				StreamName:    "Watch",
+				ServerStreams: true,
+				Handler: func(srv interface{}, stream gogrpc.ServerStream) error {
+					request := &ObjectRequest{}
+					if err := stream.RecvMsg(request); err != nil {
+						return err
+					}
+					return s.watch(request, stream)
+				},
+			},
+		},
+	}
+	if s.config.SummaryRoute != "" {
+		desc.Streams = append(desc.Streams, gogrpc.StreamDesc{
+			StreamName:    "WatchSummary",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream gogrpc.ServerStream) error {
+				request := &ObjectRequest{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				return s.watchSummary(request, stream)
+			},
+		})
+	}
+	return desc
+}
+
+func (s *objectService) unary(name string, method string, withName bool) gogrpc.MethodDesc {
+	return gogrpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor gogrpc.UnaryServerInterceptor) (interface{}, error) {
+			request := &ObjectRequest{}
+			if err := dec(request); err != nil {
+				return nil, err
+			}
+			if withName && request.Name == "" {
+				return nil, status.Errorf(codes.InvalidArgument, "%s needs the name of the object", name)
+			}
+			objectName := request.Name
+			if !withName {
+				objectName = ""
+			}
+			response, err := s.call(ctx, method, s.config.Route, objectName, request)
+			if err != nil {
+				return nil, err
+			}
+			if response.Stream != nil {
+				return nil, status.Errorf(codes.FailedPrecondition, "the response of %s is streamed", name)
+			}
+			return toObjectResponse(response.Body), nil
+		},
+	}
+}
+
+// call makes a request to the endpoint of route, and turns failed responses into errors
+func (s *objectService) call(ctx context.Context, method string, route string, name string, request *ObjectRequest) (v1alpha2.COAResponse, error) {
+	path := fmt.Sprintf("/%s/%s", s.version, route)
+	if name != "" {
+		path += "/" + name
+	}
+	parameters := make(map[string]string)
+	for k, v := range request.Parameters {
+		parameters[k] = v
+	}
+	if request.Namespace != "" {
+		parameters["namespace"] = request.Namespace
+	}
+	coaRequest := &v1alpha2.COARequest{
+		Method:      method,
+		Route:       path,
+		ContentType: "application/json",
+		Body:        request.Object,
+		Parameters:  parameters,
+	}
+	response, err := s.binding.dispatch(ctx, coaRequest)
+	if err != nil {
+		return response, err
+	}
+	if response.State != v1alpha2.OK && response.State != v1alpha2.Accepted {
+		return response, status.Error(toCode(response.State), string(response.Body))
+	}
+	return response, nil
+}
+
+// watch streams the changes of the objects, or of the object with the request name. Watching
+// an activation follows its progress through the stages of its campaign.
+func (s *objectService) watch(request *ObjectRequest, stream gogrpc.ServerStream) error {
+	watchRequest := *request
+	watchRequest.Parameters = make(map[string]string)
+	for k, v := range request.Parameters {
+		watchRequest.Parameters[k] = v
+	}
+	watchRequest.Parameters["watch"] = "true"
+	response, err := s.call(stream.Context(), fasthttp.MethodGet, s.config.Route, "", &watchRequest)
+	if err != nil {
+		return err
+	}
+	if response.Stream == nil {
+		return status.Errorf(codes.Unimplemented, "%s don't support watches", s.config.Route)
+	}
+	return runStream(stream.Context(), response.Stream, lineSender(func(line []byte) error {
+		event := WatchEvent{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			log.Errorf("G (GrpcBinding): failed to read a watch event of %s: %+v", s.config.Route, err)
+			return nil
+		}
+		if request.Name != "" {
+			meta := objectMeta{}
+			if json.Unmarshal(event.Object, &meta) != nil || meta.Metadata.Name != request.Name {
+				return nil
+			}
+		}
+		return stream.SendMsg(&event)
+	}))
+}
+
+// watchSummary streams the deployment summary of an object each time it changes, until the
+// current generation of the object is deployed.
+func (s *objectService) watchSummary(request *ObjectRequest, stream gogrpc.ServerStream) error {
+	ctx := stream.Context()
+	if request.Name == "" {
+		return status.Error(codes.InvalidArgument, "WatchSummary needs the name of the object")
+	}
+	response, err := s.call(ctx, fasthttp.MethodGet, s.config.Route, request.Name, &ObjectRequest{Namespace: request.Namespace})
+	if err != nil {
+		return err
+	}
+	meta := objectMeta{}
+	if err := json.Unmarshal(response.Body, &meta); err != nil {
+		return status.Errorf(codes.Internal, "failed to read object '%s': %v", request.Name, err)
+	}
+	summaryId := request.Name
+	if guid := meta.Metadata.Annotations[guidAnnotation]; guid != "" {
+		summaryId = fmt.Sprintf("%s-%s", request.Name, guid)
+	}
+	summaryRequest := &ObjectRequest{
+		Namespace:  request.Namespace,
+		Parameters: map[string]string{"instance": summaryId, "name": request.Name},
+	}
+	var last []byte
+	for {
+		response, err := s.call(ctx, fasthttp.MethodGet, s.config.SummaryRoute, "", summaryRequest)
+		// the summary isn't there until the first reconcile starts
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil && !bytes.Equal(response.Body, last) {
+			if err := stream.SendMsg(toObjectResponse(response.Body)); err != nil {
+				return err
+			}
+			last = response.Body
+			summary := summaryState{}
+			if json.Unmarshal(response.Body, &summary) == nil && summary.Generation == meta.Metadata.ETag && summary.State == summaryStateDone {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+func toObjectResponse(body []byte) *ObjectResponse {
+	if len(body) == 0 {
+		return &ObjectResponse{}
+	}
+	if json.Valid(body) {
+		return &ObjectResponse{Object: body}
+	}
+	return &ObjectResponse{Message: string(body)}
+}
+
+func toCode(state v1alpha2.State) codes.Code {
+	switch state {
+	case v1alpha2.OK, v1alpha2.Accepted:
+		return codes.OK
+	case v1alpha2.BadRequest, v1alpha2.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case v1alpha2.Unauthorized:
+		return codes.Unauthenticated
+	case v1alpha2.Forbidden:
+		return codes.PermissionDenied
+	case v1alpha2.NotFound:
+		return codes.NotFound
+	case v1alpha2.MethodNotAllowed:
+		return codes.Unimplemented
+	case v1alpha2.Conflict:
+		return codes.Aborted
+	case v1alpha2.InternalError:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
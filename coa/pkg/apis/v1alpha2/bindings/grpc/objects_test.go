@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	gogrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const instancesService = "/symphony.v1alpha2.Instances/"
+
+// instanceEndpoints serve instance "instance1" with a summary that is missing on the first
+// call, running on the second and done after that
+func instanceEndpoints() []v1alpha2.Endpoint {
+	var lock sync.Mutex
+	summaryCalls := 0
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{"GET", "POST", "DELETE"},
+			Route:      "instances",
+			Version:    "v1alpha2",
+			Parameters: []string{"name?"},
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				name := request.Parameters["__name"]
+				switch {
+				case request.Parameters["watch"] == "true":
+					return v1alpha2.COAResponse{
+						State:       v1alpha2.OK,
+						ContentType: "application/json",
+						Stream: func(w v1alpha2.StreamWriter) {
+							for _, n := range []string{"instance2", "instance1"} {
+								fmt.Fprintf(w, "{\"type\":\"MODIFIED\",\"object\":{\"metadata\":{\"name\":\"%s\"}}}\n\n", n)
+								if w.Flush() != nil {
+									return
+								}
+							}
+						},
+					}
+				case request.Method == "POST":
+					return v1alpha2.COAResponse{State: v1alpha2.OK, Body: request.Body}
+				case name == "":
+					return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte(fmt.Sprintf("[{\"metadata\":{\"name\":\"instance1\",\"namespace\":\"%s\"}}]", request.Parameters["namespace"]))}
+				case name != "instance1":
+					return v1alpha2.COAResponse{State: v1alpha2.NotFound, Body: []byte("instance is not found")}
+				case request.Method == "DELETE":
+					return v1alpha2.COAResponse{State: v1alpha2.OK}
+				}
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte("{\"metadata\":{\"name\":\"instance1\",\"etag\":\"2\",\"annotations\":{\"Guid\":\"abc\"}}}")}
+			},
+		},
+		{
+			Methods: []string{"GET"},
+			Route:   "solutionversion/queue",
+			Version: "v1alpha2",
+			Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+				if request.Parameters["instance"] != "instance1-abc" {
+					return v1alpha2.COAResponse{State: v1alpha2.BadRequest}
+				}
+				lock.Lock()
+				defer lock.Unlock()
+				summaryCalls++
+				switch summaryCalls {
+				case 1:
+					return v1alpha2.COAResponse{State: v1alpha2.NotFound}
+				case 2:
+					return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte("{\"generation\":\"2\",\"state\":1}")}
+				}
+				return v1alpha2.COAResponse{State: v1alpha2.OK, Body: []byte("{\"generation\":\"2\",\"state\":2}")}
+			},
+		},
+	}
+}
+
+func receiveAll[T any](t *testing.T, conn *gogrpc.ClientConn, method string, request *ObjectRequest) ([]T, error) {
+	stream, err := conn.NewStream(context.Background(), &gogrpc.StreamDesc{ServerStreams: true}, method)
+	assert.Nil(t, err)
+	assert.Nil(t, stream.SendMsg(request))
+	assert.Nil(t, stream.CloseSend())
+	ret := make([]T, 0)
+	for {
+		var message T
+		err := stream.RecvMsg(&message)
+		if err == io.EOF {
+			return ret, nil
+		}
+		if err != nil {
+			return ret, err
+		}
+		ret = append(ret, message)
+	}
+}
+
+func TestObjectServiceCalls(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{}, instanceEndpoints())
+	ctx := context.Background()
+
+	response := ObjectResponse{}
+	err := conn.Invoke(ctx, instancesService+"Get", &ObjectRequest{Name: "instance1"}, &response)
+	assert.Nil(t, err)
+	meta := objectMeta{}
+	assert.Nil(t, json.Unmarshal(response.Object, &meta))
+	assert.Equal(t, "instance1", meta.Metadata.Name)
+
+	err = conn.Invoke(ctx, instancesService+"Get", &ObjectRequest{Name: "instance2"}, &response)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	err = conn.Invoke(ctx, instancesService+"Get", &ObjectRequest{}, &response)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	response = ObjectResponse{}
+	err = conn.Invoke(ctx, instancesService+"List", &ObjectRequest{Namespace: "ns1"}, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "[{\"metadata\":{\"name\":\"instance1\",\"namespace\":\"ns1\"}}]", string(response.Object))
+
+	response = ObjectResponse{}
+	err = conn.Invoke(ctx, instancesService+"Apply", &ObjectRequest{Name: "instance1", Object: json.RawMessage("{\"spec\":{}}")}, &response)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"spec\":{}}", string(response.Object))
+
+	response = ObjectResponse{}
+	err = conn.Invoke(ctx, instancesService+"Delete", &ObjectRequest{Name: "instance1"}, &response)
+	assert.Nil(t, err)
+	assert.Empty(t, response.Object)
+}
+
+func TestObjectServiceWatch(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{}, instanceEndpoints())
+
+	events, err := receiveAll[WatchEvent](t, conn, instancesService+"Watch", &ObjectRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(events))
+
+	events, err = receiveAll[WatchEvent](t, conn, instancesService+"Watch", &ObjectRequest{Name: "instance1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "MODIFIED", events[0].Type)
+}
+
+func TestObjectServiceWatchSummary(t *testing.T) {
+	conn := startBinding(t, GrpcBindingConfig{SummaryPollSeconds: 1}, instanceEndpoints())
+
+	start := time.Now()
+	summaries, err := receiveAll[ObjectResponse](t, conn, instancesService+"WatchSummary", &ObjectRequest{Name: "instance1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(summaries))
+	assert.Equal(t, "{\"generation\":\"2\",\"state\":2}", string(summaries[1].Object))
+	assert.GreaterOrEqual(t, time.Since(start), 2*time.Second)
+
+	_, err = receiveAll[ObjectResponse](t, conn, instancesService+"WatchSummary", &ObjectRequest{Name: "instance2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// only objects with summaries have the call
+	_, err = receiveAll[ObjectResponse](t, conn, "/symphony.v1alpha2.Targets/WatchSummary", &ObjectRequest{Name: "target1"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// router finds the endpoint of a route the way the HTTP binding does: routes are
+// "/{version}/{route}" followed by a segment per endpoint parameter, where parameters ending
+// with "?" are optional, and "{name}" segments of the route are parameters as well.
+type router struct {
+	routes []route
+}
+
+type route struct {
+	endpoint v1alpha2.Endpoint
+	segments []string
+}
+
+func newRouter(endpoints []v1alpha2.Endpoint) *router {
+	r := &router{routes: make([]route, 0, len(endpoints))}
+	for _, e := range endpoints {
+		segments := splitPath(e.Version + "/" + e.Route)
+		for _, p := range e.Parameters {
+			segments = append(segments, "{"+p+"}")
+		}
+		r.routes = append(r.routes, route{endpoint: e, segments: segments})
+	}
+	return r
+}
+
+// match returns the endpoint of a method and route with its path parameters, named "__" plus
+// the parameter name. The state is NotFound if no endpoint has the route, and MethodNotAllowed
+// if none of those that have it takes the method.
+func (r *router) match(method string, path string) (v1alpha2.Endpoint, map[string]string, v1alpha2.State) {
+	segments := splitPath(strings.SplitN(path, "?", 2)[0])
+	state := v1alpha2.NotFound
+	best := -1
+	var endpoint v1alpha2.Endpoint
+	var parameters map[string]string
+	for _, rt := range r.routes {
+		params, score, ok := rt.match(segments)
+		if !ok {
+			continue
+		}
+		if !hasMethod(rt.endpoint, method) {
+			state = v1alpha2.MethodNotAllowed
+			continue
+		}
+		// literal segments win over parameters, as with the HTTP router
+		if score > best {
+			best = score
+			endpoint = rt.endpoint
+			parameters = params
+		}
+	}
+	if best < 0 {
+		return endpoint, nil, state
+	}
+	return endpoint, parameters, v1alpha2.OK
+}
+
+// match returns the parameters of the path segments and the number of literal segments they
+// match
+func (rt route) match(segments []string) (map[string]string, int, bool) {
+	params := make(map[string]string)
+	score := 0
+	for i, s := range rt.segments {
+		name, isParam, optional := parseSegment(s)
+		if i >= len(segments) {
+			if !optional {
+				return nil, 0, false
+			}
+			params["__"+name] = ""
+			continue
+		}
+		if isParam {
+			params["__"+name] = segments[i]
+		} else if s == segments[i] {
+			score++
+		} else {
+			return nil, 0, false
+		}
+	}
+	if len(segments) > len(rt.segments) {
+		return nil, 0, false
+	}
+	return params, score, true
+}
+
+func parseSegment(segment string) (string, bool, bool) {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+		return segment, false, false
+	}
+	name := segment[1 : len(segment)-1]
+	if strings.HasSuffix(name, "?") {
+		return name[:len(name)-1], true, true
+	}
+	return name, true, false
+}
+
+func hasMethod(endpoint v1alpha2.Endpoint, method string) bool {
+	for _, m := range endpoint.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(path string) []string {
+	ret := make([]string, 0)
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
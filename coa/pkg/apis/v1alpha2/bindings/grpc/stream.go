@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// streamWriter passes what a streamed response has written so far to send on each Flush.
+// Sending fails once the call has ended.
+type streamWriter struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+	send   func([]byte) error
+	closed bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return 0, status.Error(codes.Canceled, "the call has ended")
+	}
+	return w.buffer.Write(p)
+}
+
+func (w *streamWriter) Flush() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return status.Error(codes.Canceled, "the call has ended")
+	}
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	data := make([]byte, w.buffer.Len())
+	copy(data, w.buffer.Bytes())
+	w.buffer.Reset()
+	return w.send(data)
+}
+
+func (w *streamWriter) close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.closed = true
+}
+
+// runStream writes a streamed response until it ends or the call is cancelled. Streams only
+// return once a write fails, so the call doesn't wait for that after it's cancelled.
+func runStream(ctx context.Context, stream func(v1alpha2.StreamWriter), send func([]byte) error) error {
+	w := &streamWriter{send: send}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stream(w)
+		w.Flush()
+	}()
+	select {
+	case <-done:
+		w.close()
+		return nil
+	case <-ctx.Done():
+		w.close()
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// lineSender calls send with each complete, non-empty line of the data it's given
+func lineSender(send func([]byte) error) func([]byte) error {
+	var partial []byte
+	return func(data []byte) error {
+		partial = append(partial, data...)
+		for {
+			i := bytes.IndexByte(partial, '\n')
+			if i < 0 {
+				return nil
+			}
+			line := bytes.TrimSpace(partial[:i])
+			partial = partial[i+1:]
+			if len(line) == 0 {
+				continue
+			}
+			if err := send(line); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+)
+
+// AccessRequestFor describes a request to an endpoint as an access request of the RBAC policy.
+// The resource is the route of the endpoint without its parameters and "registry" segments, so
+// "targets/registry/{name}" is the "targets" resource and "activations/{name}/approve" is the
+// "activations/approve" resource. The caller is read from the request metadata, so the request
+// must have passed the JWT middleware.
+func AccessRequestFor(endpoint v1alpha2.Endpoint, request v1alpha2.COARequest) v1alpha2.AccessRequest {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(endpoint.Route, "/") {
+		if segment == "" || segment == "registry" || (strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")) {
+			continue
+		}
+		segments = append(segments, segment)
+	}
+	ret := v1alpha2.AccessRequest{
+		User:      request.Metadata[v1alpha2.COAUserKey],
+		Resource:  strings.Join(segments, "/"),
+		Namespace: request.Parameters["namespace"],
+		Name:      request.Parameters["__name"],
+	}
+	if roles := request.Metadata[v1alpha2.COARolesKey]; roles != "" {
+		ret.Roles = strings.Split(roles, ",")
+	}
+	for k, v := range request.Parameters {
+		if !strings.HasPrefix(k, "__") {
+			if ret.Parameters == nil {
+				ret.Parameters = make(map[string]string)
+			}
+			ret.Parameters[k] = v
+		}
+	}
+	switch request.Method {
+	case fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch:
+		ret.Verb = v1alpha2.VerbUpdate
+		ret.Labels = ObjectLabels(request.Body)
+	case fasthttp.MethodDelete:
+		ret.Verb = v1alpha2.VerbDelete
+	default:
+		ret.Verb = v1alpha2.VerbGet
+		if ret.Name == "" && hasNameParameter(endpoint) {
+			ret.Verb = v1alpha2.VerbList
+		}
+	}
+	return ret
+}
+
+// CheckAccess checks a request to an endpoint against the RBAC policy of the binding and the
+// policy of the caller. Either can be nil, but the request must be allowed by those that aren't.
+// A caller that is limited to namespaces is also checked against the namespaces of the request.
+// The labels of the object that a write replaces are read with the ObjectLabels of the endpoint
+// when a policy has label selectors; writes aren't granted by label selectors without them.
+func CheckAccess(endpoint v1alpha2.Endpoint, request v1alpha2.COARequest, policy *v1alpha2.RBACPolicy, callerPolicy *v1alpha2.RBACPolicy) (v1alpha2.AccessRequest, v1alpha2.AccessDecision) {
+	access := AccessRequestFor(endpoint, request)
+	if access.Verb == v1alpha2.VerbUpdate && access.Name != "" && endpoint.ObjectLabels != nil &&
+		(policy.HasLabelSelectors() || callerPolicy.HasLabelSelectors()) {
+		ctx := request.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		labels, exists, err := endpoint.ObjectLabels(ctx, access.Namespace, access.Name)
+		if err == nil {
+			access.Stored = &v1alpha2.StoredObject{Exists: exists, Labels: labels}
+		}
+	}
+	if namespaces := request.Metadata[v1alpha2.COANamespacesKey]; namespaces != "" {
+		for _, namespace := range requestNamespaces(access, request) {
+			if !v1alpha2.NamespaceAllowed(strings.Split(namespaces, ","), namespace) {
+				return access, v1alpha2.AccessDecision{Reason: fmt.Sprintf("'%s' is limited to the namespaces %s, not '%s'", access.User, namespaces, namespace)}
+			}
+		}
+	}
+	decision := v1alpha2.AccessDecision{Allowed: true}
+	if policy != nil {
+		if decision = policy.Check(access); !decision.Allowed {
+			return access, decision
+		}
+	}
+	if callerPolicy != nil {
+		if decision = callerPolicy.Check(access); !decision.Allowed {
+			decision.Reason = fmt.Sprintf("'%s' is limited to its own requests: %s", access.User, decision.Reason)
+		}
+	}
+	return access, decision
+}
+
+// ObjectLabels returns the labels in the metadata of an object that is written. It never
+// returns nil, so that objects without labels don't match label selectors.
+func ObjectLabels(body []byte) map[string]string {
+	var object struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal(body, &object) != nil || object.Metadata.Labels == nil {
+		return map[string]string{}
+	}
+	return object.Metadata.Labels
+}
+
+// requestNamespaces returns the namespaces a request is to: the namespace parameter, and the
+// namespace of a written object or request, which handlers may use instead. Lists without a
+// namespace are of all namespaces.
+func requestNamespaces(access v1alpha2.AccessRequest, request v1alpha2.COARequest) []string {
+	if access.Verb == v1alpha2.VerbList && access.Namespace == "" {
+		return []string{v1alpha2.AllNamespaces}
+	}
+	ret := []string{access.Namespace}
+	if access.Verb != v1alpha2.VerbUpdate {
+		return ret
+	}
+	var object struct {
+		Namespace string `json:"namespace"`
+		Metadata  struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal(request.Body, &object) == nil {
+		for _, namespace := range []string{object.Namespace, object.Metadata.Namespace} {
+			if namespace != "" {
+				ret = append(ret, namespace)
+			}
+		}
+	}
+	return ret
+}
+
+func hasNameParameter(endpoint v1alpha2.Endpoint) bool {
+	for _, p := range endpoint.Parameters {
+		if strings.TrimSuffix(p, "?") == "name" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"testing"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestAccessRequestFor(t *testing.T) {
+	registry := v1alpha2.Endpoint{Route: "targets/registry", Parameters: []string{"name?"}}
+	request := v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{"__name": "", "namespace": "line-3"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "alice", v1alpha2.COARolesKey: "operator,reader"},
+	}
+	access := AccessRequestFor(registry, request)
+	assert.Equal(t, v1alpha2.AccessRequest{
+		User:      "alice",
+		Roles:     []string{"operator", "reader"},
+		Verb:      v1alpha2.VerbList,
+		Resource:  "targets",
+		Namespace: "line-3",
+		// route parameters are skipped, as they're the name
+		Parameters: map[string]string{"namespace": "line-3"},
+	}, access)
+
+	request.Parameters["__name"] = "target1"
+	access = AccessRequestFor(registry, request)
+	assert.Equal(t, v1alpha2.VerbGet, access.Verb)
+	assert.Equal(t, "target1", access.Name)
+
+	request.Method = fasthttp.MethodDelete
+	assert.Equal(t, v1alpha2.VerbDelete, AccessRequestFor(registry, request).Verb)
+
+	request.Method = fasthttp.MethodPost
+	request.Body = []byte(`{"metadata":{"name":"target1","labels":{"line":"3"}}}`)
+	access = AccessRequestFor(registry, request)
+	assert.Equal(t, v1alpha2.VerbUpdate, access.Verb)
+	assert.Equal(t, map[string]string{"line": "3"}, access.Labels)
+
+	request.Body = nil
+	access = AccessRequestFor(registry, request)
+	assert.NotNil(t, access.Labels)
+	assert.Empty(t, access.Labels)
+
+	action := v1alpha2.Endpoint{Route: "activations/{name}/approve"}
+	request.Parameters["__name"] = "activation1"
+	access = AccessRequestFor(action, request)
+	assert.Equal(t, "activations/approve", access.Resource)
+	assert.Equal(t, "activation1", access.Name)
+
+	// endpoints without a name are read with "get"
+	status := v1alpha2.Endpoint{Route: "trails/verify"}
+	request.Method = fasthttp.MethodGet
+	request.Parameters = map[string]string{}
+	access = AccessRequestFor(status, request)
+	assert.Equal(t, v1alpha2.VerbGet, access.Verb)
+	assert.Equal(t, "trails/verify", access.Resource)
+}
+
+func TestHandlerChecksRBACPolicy(t *testing.T) {
+	called := false
+	endpoint := v1alpha2.Endpoint{
+		Route:      "instances",
+		Parameters: []string{"name?"},
+		Handler: func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+			called = true
+			assert.NotNil(t, v1alpha2.AccessPolicyFromContext(request.Context))
+			return v1alpha2.COAResponse{State: v1alpha2.OK}
+		},
+	}
+	policy := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:   []v1alpha2.RBACSubject{{Role: "operator"}},
+				Verbs:      []string{"*"},
+				Resources:  []string{"instances"},
+				Namespaces: []string{"line-3"},
+			},
+		},
+	}
+	handler := wrapAsHTTPHandler(endpoint, endpoint.Handler)
+	newRequest := func(namespace string) *fasthttp.RequestCtx {
+		reqCtx := &fasthttp.RequestCtx{}
+		reqCtx.Request.Header.SetMethod(fasthttp.MethodPost)
+		reqCtx.Request.SetRequestURI("/v1alpha2/instances/instance1?namespace=" + namespace)
+		reqCtx.SetUserValue("name", "instance1")
+		reqCtx.SetUserValue(v1alpha2.COAUserKey, "alice")
+		reqCtx.SetUserValue(v1alpha2.COARolesKey, []string{"operator"})
+		reqCtx.SetUserValue(string(v1alpha2.COAAccessPolicyKey), policy)
+		return reqCtx
+	}
+
+	reqCtx := newRequest("line-4")
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusForbidden, reqCtx.Response.StatusCode())
+	assert.False(t, called)
+
+	reqCtx = newRequest("line-3")
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusOK, reqCtx.Response.StatusCode())
+	assert.True(t, called)
+}
+
+func TestJWTPassesRBACPolicy(t *testing.T) {
+	j := JWT{
+		AuthHeader:     "Authorization",
+		TokenValidator: testTokenValidator{},
+		RBAC:           &v1alpha2.RBACPolicy{},
+	}
+	assert.Nil(t, j.Init())
+	var policy interface{}
+	handler := j.JWT(func(ctx *fasthttp.RequestCtx) {
+		policy = ctx.UserValue(string(v1alpha2.COAAccessPolicyKey))
+	})
+	reqCtx := &fasthttp.RequestCtx{}
+	reqCtx.Request.SetRequestURI("/v1alpha2/instances?namespace=ci")
+	reqCtx.Request.Header.Set("Authorization", "Bearer key-ci")
+	handler(reqCtx)
+	assert.Equal(t, j.RBAC, policy)
+
+	// the policy of a caller is passed on separately
+	reqCtx = &fasthttp.RequestCtx{}
+	reqCtx.Request.SetRequestURI("/v1alpha2/targets/ping/t1")
+	reqCtx.Request.Header.Set("Authorization", "Bearer target-t1")
+	var callerPolicy interface{}
+	j.JWT(func(ctx *fasthttp.RequestCtx) {
+		callerPolicy = ctx.UserValue(string(v1alpha2.COACallerPolicyKey))
+	})(reqCtx)
+	assert.NotNil(t, callerPolicy)
+
+	j.RBAC = &v1alpha2.RBACPolicy{Rules: []v1alpha2.RBACRule{{Subjects: []v1alpha2.RBACSubject{{Role: "operator"}}, Verbs: []string{"read"}, Resources: []string{"*"}}}}
+	assert.NotNil(t, j.Init())
+}
+
+func TestCheckAccessWithStoredLabels(t *testing.T) {
+	stored := map[string]map[string]string{
+		"dev-app":  {"env": "dev"},
+		"prod-app": {"env": "prod"},
+	}
+	endpoint := v1alpha2.Endpoint{
+		Route:      "solutions",
+		Parameters: []string{"name?"},
+		ObjectLabels: func(ctx context.Context, namespace string, name string) (map[string]string, bool, error) {
+			labels, ok := stored[name]
+			return labels, ok, nil
+		},
+	}
+	policy := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:      []v1alpha2.RBACSubject{{Role: "developer"}},
+				Verbs:         []string{v1alpha2.VerbUpdate},
+				Resources:     []string{"solutions"},
+				LabelSelector: map[string]string{"env": "dev"},
+			},
+		},
+	}
+	write := func(name string) v1alpha2.COARequest {
+		return v1alpha2.COARequest{
+			Method:     fasthttp.MethodPost,
+			Body:       []byte(`{"metadata":{"labels":{"env":"dev"}}}`),
+			Parameters: map[string]string{"__name": name},
+			Metadata:   map[string]string{v1alpha2.COAUserKey: "dev", v1alpha2.COARolesKey: "developer"},
+		}
+	}
+	_, decision := CheckAccess(endpoint, write("dev-app"), policy, nil)
+	assert.True(t, decision.Allowed, decision.Reason)
+	_, decision = CheckAccess(endpoint, write("new-app"), policy, nil)
+	assert.True(t, decision.Allowed, decision.Reason)
+	// relabeling an object doesn't take it over
+	_, decision = CheckAccess(endpoint, write("prod-app"), policy, nil)
+	assert.False(t, decision.Allowed)
+
+	// the stored labels of endpoints that can't read them are unknown
+	endpoint.ObjectLabels = nil
+	_, decision = CheckAccess(endpoint, write("dev-app"), policy, nil)
+	assert.False(t, decision.Allowed)
+}
+
+func TestCheckAccessWithCallerPolicy(t *testing.T) {
+	ping := v1alpha2.Endpoint{Route: "targets/ping", Parameters: []string{"name"}}
+	queue := v1alpha2.Endpoint{Route: "solutionversion/queue"}
+	request := func(method string, parameters map[string]string) v1alpha2.COARequest {
+		return v1alpha2.COARequest{
+			Method:     method,
+			Parameters: parameters,
+			Metadata:   map[string]string{v1alpha2.COAUserKey: "target:default/t1", v1alpha2.COARolesKey: "target"},
+		}
+	}
+	caller := targetPolicy("t1")
+
+	_, decision := CheckAccess(ping, request(fasthttp.MethodPost, map[string]string{"__name": "t1"}), nil, caller)
+	assert.True(t, decision.Allowed)
+	_, decision = CheckAccess(ping, request(fasthttp.MethodPost, map[string]string{"__name": "t2"}), nil, caller)
+	assert.False(t, decision.Allowed)
+	assert.Contains(t, decision.Reason, "limited to its own requests")
+	_, decision = CheckAccess(queue, request(fasthttp.MethodGet, map[string]string{"instance": "t1"}), nil, caller)
+	assert.True(t, decision.Allowed)
+	_, decision = CheckAccess(queue, request(fasthttp.MethodGet, map[string]string{"instance": "t2"}), nil, caller)
+	assert.False(t, decision.Allowed)
+	_, decision = CheckAccess(v1alpha2.Endpoint{Route: "instances", Parameters: []string{"name?"}}, request(fasthttp.MethodGet, map[string]string{}), nil, caller)
+	assert.False(t, decision.Allowed)
+
+	// the policy of the binding must allow the request too
+	binding := &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:  []v1alpha2.RBACSubject{{Role: "target"}},
+				Verbs:     []string{"*"},
+				Resources: []string{"targets/*"},
+			},
+		},
+	}
+	_, decision = CheckAccess(ping, request(fasthttp.MethodPost, map[string]string{"__name": "t1"}), binding, caller)
+	assert.True(t, decision.Allowed)
+	_, decision = CheckAccess(queue, request(fasthttp.MethodGet, map[string]string{"instance": "t1"}), binding, caller)
+	assert.False(t, decision.Allowed)
+	_, decision = CheckAccess(queue, request(fasthttp.MethodGet, map[string]string{"instance": "t1"}), nil, nil)
+	assert.True(t, decision.Allowed)
+}
+
+func TestCheckAccessWithNamespaces(t *testing.T) {
+	endpoint := v1alpha2.Endpoint{Route: "instances", Parameters: []string{"name?"}}
+	request := v1alpha2.COARequest{
+		Method:     fasthttp.MethodPost,
+		Parameters: map[string]string{"__name": "instance1", "namespace": "ci"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "apikey:ci", v1alpha2.COANamespacesKey: "ci"},
+		Body:       []byte(`{"metadata":{"name":"instance1","namespace":"ci"}}`),
+	}
+	_, decision := CheckAccess(endpoint, request, nil, nil)
+	assert.True(t, decision.Allowed)
+
+	// the namespace of the written object counts, not only the namespace parameter
+	request.Body = []byte(`{"metadata":{"name":"instance1","namespace":"prod"}}`)
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.False(t, decision.Allowed)
+	request.Body = []byte(`{"namespace":"prod","target":"t1"}`)
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.False(t, decision.Allowed)
+
+	request.Method = fasthttp.MethodGet
+	request.Body = nil
+	request.Parameters["namespace"] = ""
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.False(t, decision.Allowed)
+	request.Metadata[v1alpha2.COANamespacesKey] = "ci,default"
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.True(t, decision.Allowed)
+}
+
+func TestCheckAccessListWithoutNamespace(t *testing.T) {
+	endpoint := v1alpha2.Endpoint{Route: "targets/registry", Parameters: []string{"name?"}}
+	request := v1alpha2.COARequest{
+		Method:     fasthttp.MethodGet,
+		Parameters: map[string]string{"namespace": "default"},
+		Metadata:   map[string]string{v1alpha2.COAUserKey: "apikey:ci", v1alpha2.COANamespacesKey: "default"},
+	}
+	_, decision := CheckAccess(endpoint, request, nil, nil)
+	assert.True(t, decision.Allowed)
+
+	// handlers list all namespaces when the namespace is left out
+	delete(request.Parameters, "namespace")
+	access, decision := CheckAccess(endpoint, request, nil, nil)
+	assert.Equal(t, v1alpha2.VerbList, access.Verb)
+	assert.False(t, decision.Allowed)
+	request.Metadata[v1alpha2.COANamespacesKey] = "*"
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.True(t, decision.Allowed)
+
+	// callers that aren't limited to namespaces can list all of them
+	delete(request.Metadata, v1alpha2.COANamespacesKey)
+	_, decision = CheckAccess(endpoint, request, nil, nil)
+	assert.True(t, decision.Allowed)
+}
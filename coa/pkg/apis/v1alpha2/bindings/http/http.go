@@ -7,6 +7,7 @@
 package http
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -42,6 +43,8 @@ type HttpBindingConfig struct {
 	Pipeline     []MiddlewareConfig `json:"pipeline"`
 	TLS          bool               `json:"tls"`
 	CertProvider CertProviderConfig `json:"certProvider"`
+	// TokenValidator is passed to the JWT middleware, to validate tokens that aren't JWTs
+	TokenValidator v1alpha2.ITokenValidator `json:"-"`
 }
 
 // HttpBinding provides service endpoints as a fasthttp web server
@@ -183,9 +186,44 @@ func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler)
 			}
 			req.Metadata["Authorization"] = string(auth)
 		}
+		// The MCP session of the caller is taken from the real request header as well
+		if req.Metadata != nil {
+			delete(req.Metadata, v1alpha2.MCPSessionHeader)
+		}
+		if session := reqCtx.Request.Header.Peek(v1alpha2.MCPSessionHeader); len(session) > 0 {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]string)
+			}
+			req.Metadata[v1alpha2.MCPSessionHeader] = string(session)
+		}
+		// The caller identity set by the JWT middleware is passed on the same way, and is
+		// likewise never taken from the COA metadata header.
+		if req.Metadata != nil {
+			delete(req.Metadata, v1alpha2.COAUserKey)
+			delete(req.Metadata, v1alpha2.COARolesKey)
+			delete(req.Metadata, v1alpha2.COANamespacesKey)
+		}
+		if user, ok := reqCtx.UserValue(v1alpha2.COAUserKey).(string); ok {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]string)
+			}
+			req.Metadata[v1alpha2.COAUserKey] = user
+		}
+		if roles, ok := reqCtx.UserValue(v1alpha2.COARolesKey).([]string); ok {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]string)
+			}
+			req.Metadata[v1alpha2.COARolesKey] = strings.Join(roles, ",")
+		}
+		if namespaces, ok := reqCtx.UserValue(v1alpha2.COANamespacesKey).([]string); ok {
+			if req.Metadata == nil {
+				req.Metadata = make(map[string]string)
+			}
+			req.Metadata[v1alpha2.COANamespacesKey] = strings.Join(namespaces, ",")
+		}
 		req.Parameters = make(map[string]string)
 
-		for _, p := range endpoint.Parameters {
+		for _, p := range append(getRouteParameters(endpoint.Route), endpoint.Parameters...) {
 			k := p
 			if strings.HasSuffix(p, "?") {
 				k = k[:len(p)-1]
@@ -203,6 +241,23 @@ func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler)
 			req.Parameters[string(key)] = string(value)
 		})
 
+		policy, _ := reqCtx.UserValue(string(v1alpha2.COAAccessPolicyKey)).(*v1alpha2.RBACPolicy)
+		callerPolicy, _ := reqCtx.UserValue(string(v1alpha2.COACallerPolicyKey)).(*v1alpha2.RBACPolicy)
+		if policy != nil || callerPolicy != nil || req.Metadata[v1alpha2.COANamespacesKey] != "" {
+			access, decision := CheckAccess(endpoint, req, policy, callerPolicy)
+			if !decision.Allowed {
+				httpLogger.InfofCtx(ctx, "H (HttpBinding): '%s' with roles %v is denied: %s", access.User, access.Roles, decision.Reason)
+				reqCtx.SetStatusCode(fasthttp.StatusForbidden)
+				reqCtx.SetContentType("text/plain")
+				reqCtx.SetBodyString(decision.Reason)
+				return
+			}
+		}
+		if policy != nil {
+			// handlers can check the operations they do on behalf of the caller
+			req.Context = context.WithValue(req.Context, v1alpha2.COAAccessPolicyKey, policy)
+		}
+
 		resp := handler(req)
 
 		if resp.State == v1alpha2.APIRedirect {
@@ -211,14 +266,41 @@ func wrapAsHTTPHandler(endpoint v1alpha2.Endpoint, handler v1alpha2.COAHandler)
 			if len(resp.Metadata) != 0 {
 				data, _ := json.Marshal(resp.Metadata)
 				reqCtx.Response.Header.Set(v1alpha2.COAMetaHeader, string(data))
+				if session := resp.Metadata[v1alpha2.MCPSessionHeader]; session != "" {
+					reqCtx.Response.Header.Set(v1alpha2.MCPSessionHeader, session)
+				}
 			}
 			reqCtx.SetContentType(resp.ContentType)
-			reqCtx.SetBody(resp.Body)
+			if resp.Stream != nil {
+				stream := resp.Stream
+				if resp.ContentType == v1alpha2.SSEContentType {
+					// events must reach the client as they're written
+					reqCtx.Response.Header.Set("Cache-Control", "no-cache")
+					reqCtx.Response.Header.Set("X-Accel-Buffering", "no")
+				}
+				reqCtx.SetBodyStreamWriter(func(w *bufio.Writer) {
+					stream(w)
+				})
+			} else {
+				reqCtx.SetBody(resp.Body)
+			}
 			reqCtx.SetStatusCode(toHttpState(resp.State))
 		}
 	}
 }
 
+// getRouteParameters returns the names of the parameters embedded in a route, such as
+// "name" in "instances/{name}/history".
+func getRouteParameters(route string) []string {
+	ret := make([]string, 0)
+	for _, segment := range strings.Split(route, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			ret = append(ret, segment[1:len(segment)-1])
+		}
+	}
+	return ret
+}
+
 func toHttpState(state v1alpha2.State) int {
 	switch state {
 	case v1alpha2.OK:
@@ -65,8 +65,9 @@ func BuildPipeline(config HttpBindingConfig, pubsubProvider pubsub.IPubSubProvid
 			if err != nil {
 				return ret, v1alpha2.NewCOAError(nil, "incorrect jwt pipeline configuration format", v1alpha2.BadConfig)
 			}
-			if jwts.AuthHeader == "" {
-				jwts.AuthHeader = "Authorization"
+			jwts.TokenValidator = config.TokenValidator
+			if err := jwts.Init(); err != nil {
+				return ret, err
 			}
 			ret.Handlers = append(ret.Handlers, jwts.JWT)
 		case "middleware.http.tracing":
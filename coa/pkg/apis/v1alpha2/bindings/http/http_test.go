@@ -121,6 +121,50 @@ func TestHTTPEcho(t *testing.T) {
 				}
 			},
 		},
+		{
+			Methods:    []string{"GET"},
+			Route:      "greetings4/{name}/times",
+			Version:    "v1",
+			Parameters: []string{"count?"},
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					Body:  []byte("Hi " + c.Parameters["__name"] + " x" + c.Parameters["__count"]),
+					State: v1alpha2.OK,
+				}
+			},
+		},
+		{
+			Methods: []string{"GET"},
+			Route:   "greetingsStream",
+			Version: "v1",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					State: v1alpha2.OK,
+					Stream: func(w v1alpha2.StreamWriter) {
+						for _, part := range []string{"Hi ", "there", "!!"} {
+							w.Write([]byte(part))
+							if w.Flush() != nil {
+								return
+							}
+						}
+					},
+				}
+			},
+		},
+		{
+			Methods: []string{"GET"},
+			Route:   "greetingsEvents",
+			Version: "v1",
+			Handler: func(c v1alpha2.COARequest) v1alpha2.COAResponse {
+				return v1alpha2.COAResponse{
+					State:       v1alpha2.OK,
+					ContentType: v1alpha2.SSEContentType,
+					Stream: func(w v1alpha2.StreamWriter) {
+						v1alpha2.WriteSSEEvent(w, v1alpha2.SSEEvent{Event: "greeting", Data: []byte("Hi")})
+					},
+				}
+			},
+		},
 		{
 			Methods: []string{"POST"},
 			Route:   "greetingsWithMetadata",
@@ -152,6 +196,16 @@ func TestHTTPEcho(t *testing.T) {
 	// path parameters
 	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetings3/John", nil, 200, "Hi John!!!")
 
+	// path parameters embedded in route
+	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetings4/John/times/3", nil, 200, "Hi John x3")
+
+	// streamed body
+	testHttpRequestHelper(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetingsStream", nil, 200, "Hi there!!")
+
+	// server-sent events
+	testHttpRequestHelperWithHeaders(context.Background(), t, fasthttp.MethodGet, "http://localhost:8080/v1/greetingsEvents", nil, nil, 200,
+		"event: greeting\ndata: Hi\n\n", map[string]string{"Cache-Control": "no-cache"})
+
 	// req metadata and resp metadata
 	req4Metadata := map[string]string{
 		"key": "Alice",
@@ -7,6 +7,7 @@
 package http
 
 import (
+	"context"
 	"crypto/rsa"
 	"errors"
 	"fmt"
@@ -34,6 +35,13 @@ type JWT struct {
 	EnableRBAC       bool              `json:"enableRBAC,omitempty"`
 	Policy           map[string]Policy `json:"policy,omitempty"`
 	DisableUserCreds bool              `json:"disableUserCreds,omitempty"`
+	OIDC             *OIDC             `json:"oidc,omitempty"`
+	// RBAC is the policy that requests are checked with once their endpoint is known. It's
+	// independent of the path-based Policy of EnableRBAC.
+	RBAC *v1alpha2.RBACPolicy `json:"rbac,omitempty"`
+	// TokenValidator validates tokens that aren't JWTs, such as API keys, if it's set
+	TokenValidator v1alpha2.ITokenValidator `json:"-"`
+	oidc           *oidcVerifier
 }
 
 // enum string for AuthServer
@@ -42,7 +50,9 @@ type AuthServer string
 const (
 	// AuthServerKuberenetes means we are using kubernetes api server as auth server
 	AuthServerKuberenetes AuthServer = "kubernetes"
-	SymphonyIssuer        string     = "symphony"
+	// AuthServerOIDC means we are validating tokens of an OpenID Connect issuer
+	AuthServerOIDC AuthServer = "oidc"
+	SymphonyIssuer string     = "symphony"
 )
 
 var (
@@ -75,83 +85,168 @@ type Policy struct {
 	Items map[string]string `json:"items"`
 }
 
+// Init checks the configuration of the middleware and prepares the validation of tokens of
+// the auth server. It's called once the configuration is read, before the middleware is used.
+func (j *JWT) Init() error {
+	if j.AuthHeader == "" {
+		j.AuthHeader = "Authorization"
+	}
+	if j.RBAC != nil {
+		if err := j.RBAC.Validate(); err != nil {
+			return err
+		}
+	}
+	if j.AuthServer == AuthServerOIDC {
+		verifier, err := newOIDCVerifier(j.OIDC)
+		if err != nil {
+			return err
+		}
+		j.oidc = verifier
+	}
+	return nil
+}
+
 func (j JWT) JWT(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
-		if j.IgnorePaths != nil {
-			for _, p := range j.IgnorePaths {
-				if p == string(ctx.Path()) {
-					next(ctx)
-					return
-				}
-			}
+		if j.IsIgnoredPath(string(ctx.Path())) {
+			next(ctx)
+			return
 		}
 		if ctx.IsOptions() {
 			next(ctx)
 			return
 		}
-		tokenStr := j.readAuthHeader(ctx)
-		if tokenStr == "" {
-			log.Errorf("JWT: Token is empty.\n")
+		principal, err := j.AuthorizePrincipal(ctx, j.readAuthHeader(ctx), string(ctx.Path()), string(ctx.Method()), string(ctx.QueryArgs().Peek("namespace")))
+		if err != nil {
 			ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-		} else {
-			issuer, err := decodeJWTTokenForIssuer(tokenStr)
+			return
+		}
+		if principal.User != "" {
+			ctx.SetUserValue(v1alpha2.COAUserKey, principal.User)
+		}
+		if principal.Roles != nil {
+			ctx.SetUserValue(v1alpha2.COARolesKey, principal.Roles)
+		}
+		if len(principal.Namespaces) > 0 {
+			ctx.SetUserValue(v1alpha2.COANamespacesKey, principal.Namespaces)
+		}
+		// the binding checks the policies once the endpoint of the request is known
+		if j.RBAC != nil {
+			ctx.SetUserValue(string(v1alpha2.COAAccessPolicyKey), j.RBAC)
+		}
+		if principal.Policy != nil {
+			ctx.SetUserValue(string(v1alpha2.COACallerPolicyKey), principal.Policy)
+		}
+		next(ctx)
+	}
+}
+
+// IsIgnoredPath tells if a path is served without a token.
+func (j JWT) IsIgnoredPath(path string) bool {
+	for _, p := range j.IgnorePaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize validates a bearer token (without the "Bearer " prefix) for a request to the given
+// path, method and namespace, and returns the user and roles of the caller.
+func (j *JWT) Authorize(ctx context.Context, tokenStr string, path string, method string, namespace string) (string, []string, error) {
+	principal, err := j.AuthorizePrincipal(ctx, tokenStr, path, method, namespace)
+	return principal.User, principal.Roles, err
+}
+
+// AuthorizePrincipal validates a bearer token like Authorize, and returns the caller. Tokens of
+// the token validator, such as API keys, are checked first. Tokens issued by Symphony are checked
+// against the configured key, claims and RBAC policy; other tokens are reviewed by the configured
+// auth server. It's shared by the bindings so they apply the same checks.
+func (j *JWT) AuthorizePrincipal(ctx context.Context, tokenStr string, path string, method string, namespace string) (v1alpha2.Principal, error) {
+	if tokenStr == "" {
+		log.Errorf("JWT: Token is empty.\n")
+		return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, "token is empty", v1alpha2.Unauthorized)
+	}
+	if j.TokenValidator != nil {
+		principal, ok, err := j.TokenValidator.ValidateToken(ctx, tokenStr)
+		if ok {
 			if err != nil {
-				log.Errorf("JWT: Could not decode issuer from token. %s\n", err.Error())
-				ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-				return
+				log.Errorf("JWT: Validate token with token validator failed. %s\n", err.Error())
+				return v1alpha2.Principal{}, v1alpha2.NewCOAError(err, "validate token failed", v1alpha2.Unauthorized)
 			}
-			if issuer == SymphonyIssuer {
-				if j.DisableUserCreds == true {
-					log.Infof("JWT: Token with username plus pwd is not allowed.")
-					ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-					return
-				}
-				log.Debugf("JWT: Validating token with username plus pwd.")
-				_, roles, err := j.validateToken(tokenStr)
-				if err != nil {
-					log.Error("JWT: Validate token with user creds failed. %s\n", err.Error())
-					ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-					return
-				} else {
-					if j.EnableRBAC {
-						path := string(ctx.Path())
-						method := string(ctx.Method())
-						for _, role := range roles {
-							if v, ok := j.Policy[role]; ok {
-								for key, val := range v.Items {
-									if key == "*" || strings.HasPrefix(path, key) {
-										if val == "*" || strings.Contains(val, method) {
-											next(ctx)
-											return
-										}
-									}
-								}
-							}
-						}
-						ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-						return
-					}
-					next(ctx)
-				}
-			} else {
-				if j.AuthServer == AuthServerKuberenetes {
-					log.Debugf("JWT: Validating token with k8s.")
-					err := j.validateServiceAccountToken(ctx, tokenStr)
-					if err != nil {
-						log.Errorf("JWT: Validate token with k8s failed. %s\n", err.Error())
-						ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-						return
+			// the namespaces of the request that are only known once it's routed, such as the
+			// namespace of a written object, are checked by the binding
+			if !v1alpha2.NamespaceAllowed(principal.Namespaces, namespace) {
+				return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("namespace '%s' is not allowed for '%s'", namespace, principal.User), v1alpha2.Unauthorized)
+			}
+			if j.EnableRBAC && !j.isAllowed(principal.Roles, path, method) {
+				return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s %s is not allowed for the roles %v", method, path, principal.Roles), v1alpha2.Unauthorized)
+			}
+			return principal, nil
+		}
+	}
+	issuer, err := decodeJWTTokenForIssuer(tokenStr)
+	if err != nil {
+		log.Errorf("JWT: Could not decode issuer from token. %s\n", err.Error())
+		return v1alpha2.Principal{}, v1alpha2.NewCOAError(err, "could not decode issuer from token", v1alpha2.Unauthorized)
+	}
+	if issuer == SymphonyIssuer {
+		if j.DisableUserCreds == true {
+			log.Infof("JWT: Token with username plus pwd is not allowed.")
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, "token with username plus pwd is not allowed", v1alpha2.Unauthorized)
+		}
+		log.Debugf("JWT: Validating token with username plus pwd.")
+		claims, roles, err := j.validateToken(tokenStr)
+		if err != nil {
+			log.Error("JWT: Validate token with user creds failed. %s\n", err.Error())
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(err, "validate token with user creds failed", v1alpha2.Unauthorized)
+		}
+		user, _ := claims["user"].(string)
+		if j.EnableRBAC && !j.isAllowed(roles, path, method) {
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s %s is not allowed for the roles %v", method, path, roles), v1alpha2.Unauthorized)
+		}
+		return v1alpha2.Principal{User: user, Roles: roles}, nil
+	}
+	if j.AuthServer == AuthServerOIDC {
+		log.Debugf("JWT: Validating token with OIDC issuer.")
+		user, roles, err := j.validateOIDCToken(tokenStr)
+		if err != nil {
+			log.Errorf("JWT: Validate token with OIDC issuer failed. %s\n", err.Error())
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(err, "validate token with OIDC issuer failed", v1alpha2.Unauthorized)
+		}
+		if j.EnableRBAC && !j.isAllowed(roles, path, method) {
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("%s %s is not allowed for the roles %v", method, path, roles), v1alpha2.Unauthorized)
+		}
+		return v1alpha2.Principal{User: user, Roles: roles}, nil
+	}
+	if j.AuthServer == AuthServerKuberenetes {
+		log.Debugf("JWT: Validating token with k8s.")
+		user, err := j.validateServiceAccountToken(ctx, tokenStr)
+		if err != nil {
+			log.Errorf("JWT: Validate token with k8s failed. %s\n", err.Error())
+			return v1alpha2.Principal{}, v1alpha2.NewCOAError(err, "validate token with k8s failed", v1alpha2.Unauthorized)
+		}
+		return v1alpha2.Principal{User: user}, nil
+	}
+	log.Errorf("JWT: Not supported auth server, %s.\n", j.AuthServer)
+	return v1alpha2.Principal{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("auth server '%s' is not supported", j.AuthServer), v1alpha2.Unauthorized)
+}
+
+func (j *JWT) isAllowed(roles []string, path string, method string) bool {
+	for _, role := range roles {
+		if v, ok := j.Policy[role]; ok {
+			for key, val := range v.Items {
+				if key == "*" || strings.HasPrefix(path, key) {
+					if val == "*" || strings.Contains(val, method) {
+						return true
 					}
-					next(ctx)
-				} else {
-					log.Errorf("JWT: Not supported auth server, %s.\n", j.AuthServer)
-					ctx.Response.SetStatusCode(fasthttp.StatusUnauthorized)
-					return
 				}
 			}
 		}
 	}
+	return false
 }
+
 func (j JWT) readAuthHeader(ctx *fasthttp.RequestCtx) string {
 	v := ctx.Request.Header.Peek(j.AuthHeader)
 	if v != nil {
@@ -197,10 +292,33 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 	for k, v := range claims {
 		ret[k] = v
 	}
+	roles, err := j.checkClaims(ret)
+	return ret, roles, err
+}
+
+// validateOIDCToken validates a token of the OIDC issuer, and returns the user and roles of
+// the caller
+func (j *JWT) validateOIDCToken(tokenStr string) (string, []string, error) {
+	if j.oidc == nil {
+		return "", nil, v1alpha2.NewCOAError(nil, "jwt middleware is not initialized for OIDC", v1alpha2.BadConfig)
+	}
+	claims, err := j.oidc.validate(tokenStr)
+	if err != nil {
+		return "", nil, err
+	}
+	roles, err := j.checkClaims(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return j.oidc.user(claims), roles, nil
+}
+
+// checkClaims checks the required claims of a validated token, and maps its claims to roles
+func (j *JWT) checkClaims(ret map[string]interface{}) ([]string, error) {
 	if j.MustHave != nil && len(j.MustHave) > 0 {
 		for _, k := range j.MustHave {
 			if _, ok := ret[k]; !ok {
-				return ret, nil, fmt.Errorf("required claim '%s' is not found", k)
+				return nil, fmt.Errorf("required claim '%s' is not found", k)
 			}
 		}
 	}
@@ -208,26 +326,37 @@ func (j *JWT) validateToken(tokenStr string) (map[string]interface{}, []string,
 		for k, v := range j.MustMatch {
 			if hv, ok := ret[k]; ok {
 				if hv != v {
-					return ret, nil, fmt.Errorf("claim '%s' doesn't have required value", k)
+					return nil, fmt.Errorf("claim '%s' doesn't have required value", k)
 				}
 			} else {
-				return ret, nil, fmt.Errorf("required claim '%s' is not found", k)
+				return nil, fmt.Errorf("required claim '%s' is not found", k)
 			}
 		}
 	}
-	var roles []string
-	if j.EnableRBAC {
-		roles = make([]string, 0)
-		for _, m := range j.Roles {
-			if v, ok := ret[m.Claim]; ok {
-				if m.Value == "*" || v == m.Value {
-					roles = append(roles, m.Role)
-				}
+	// roles are mapped even without RBAC so handlers can make their own decisions
+	roles := make([]string, 0)
+	for _, m := range j.Roles {
+		if v, ok := ret[m.Claim]; ok {
+			if m.Value == "*" || claimHasValue(v, m.Value) {
+				roles = append(roles, m.Role)
 			}
 		}
+	}
+	return roles, nil
+}
 
+// claimHasValue tells if a claim is the value, or has it when the claim is a list such as the
+// "groups" or "roles" of OIDC tokens
+func claimHasValue(claim interface{}, value string) bool {
+	if list, ok := claim.([]interface{}); ok {
+		for _, v := range list {
+			if v == value {
+				return true
+			}
+		}
+		return false
 	}
-	return ret, roles, nil
+	return claim == value
 }
 
 func decodeJWTTokenForIssuer(tokenString string) (string, error) {
@@ -250,11 +379,13 @@ func decodeJWTTokenForIssuer(tokenString string) (string, error) {
 	}
 }
 
-func (j *JWT) validateServiceAccountToken(ctx *fasthttp.RequestCtx, tokenStr string) error {
+// validateServiceAccountToken reviews a Kubernetes service account token, and returns the
+// name of the service account if it's the one of the API or of the controller
+func (j *JWT) validateServiceAccountToken(ctx context.Context, tokenStr string) (string, error) {
 	clientset, err := getKubernetesClient()
 	if err != nil {
 		log.Errorf("JWT: Could not initialize Kubernetes client.\n")
-		return v1alpha2.NewCOAError(err, "Could not initialize Kubernetes client", v1alpha2.InternalError)
+		return "", v1alpha2.NewCOAError(err, "Could not initialize Kubernetes client", v1alpha2.InternalError)
 	}
 	tokenReview := &v1.TokenReview{
 		Spec: v1.TokenReviewSpec{
@@ -268,27 +399,26 @@ func (j *JWT) validateServiceAccountToken(ctx *fasthttp.RequestCtx, tokenStr str
 	result, err := clientset.AuthenticationV1().TokenReviews().Create(ctx, tokenReview, metav1.CreateOptions{})
 	if err != nil {
 		log.Errorf("JWT: Token review using kubernetes api server failed. %s\n", err.Error())
-		return v1alpha2.NewCOAError(err, "Token review using kubernetes api server failed.", v1alpha2.InternalError)
+		return "", v1alpha2.NewCOAError(err, "Token review using kubernetes api server failed.", v1alpha2.InternalError)
 	}
 	if !result.Status.Authenticated {
 		log.Errorf("JWT: Validate token with k8s failed. K8s returned not authenticated.\n")
-		return v1alpha2.NewCOAError(nil, "Authentication failed.", v1alpha2.Unauthorized)
+		return "", v1alpha2.NewCOAError(nil, "Authentication failed.", v1alpha2.Unauthorized)
 	} else {
 		apiUsername, err := getApiServiceAccountUsername()
 		if err != nil {
-			return err
+			return "", err
 		}
 		controllerUsername, err := getControllerServiceAccountUsername()
 		if err != nil {
-			return err
+			return "", err
 		}
 		if result.Status.User.Username != apiUsername && result.Status.User.Username != controllerUsername {
 			log.Errorf("JWT: Validate token with k8s failed. K8s returned invalid username, %s\n", result.Status.User.Username)
-			return v1alpha2.NewCOAError(nil, "Authentication failed.", v1alpha2.Unauthorized)
+			return "", v1alpha2.NewCOAError(nil, "Authentication failed.", v1alpha2.Unauthorized)
 		}
+		return result.Status.User.Username, nil
 	}
-	return nil
-
 }
 func getKubernetesClient() (*kubernetes.Clientset, error) {
 	config, err := rest.InClusterConfig()
@@ -7,15 +7,19 @@
 package http
 
 import (
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	jwt "github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
 )
 
 func generateJWTToken(signingKey interface{}, method jwt.SigningMethod, userName string, expiresAt time.Time, issuedAt time.Time, notAfter time.Time, issuer string, subject string, audiences []string) (string, error) {
@@ -142,3 +146,134 @@ func TestValidateWithTokenWithCert(t *testing.T) {
 	_, _, err = j.validateToken(token)
 	assert.Nil(t, err)
 }
+
+func TestValidateTokenMapsRolesWithoutRBAC(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "test",
+		Roles: []ClaimRoleMap{
+			{Role: "approver", Claim: "user", Value: "test"},
+			{Role: "administrator", Claim: "user", Value: "admin"},
+		},
+	}
+
+	token, err := generateJWTToken([]byte("test"), jwt.SigningMethodHS256, "test", time.Now().Add(time.Hour), time.Now(), time.Now(), "test", "test", []string{"test"})
+	assert.Nil(t, err)
+	_, roles, err := j.validateToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"approver"}, roles)
+}
+
+func TestJWTPassesCallerIdentityToHandler(t *testing.T) {
+	j := JWT{
+		AuthHeader: "Authorization",
+		VerifyKey:  "test",
+		Roles: []ClaimRoleMap{
+			{Role: "approver", Claim: "user", Value: "test"},
+		},
+	}
+	var metadata map[string]string
+	handler := j.JWT(wrapAsHTTPHandler(v1alpha2.Endpoint{Route: "greetings"}, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		metadata = request.Metadata
+		return v1alpha2.COAResponse{State: v1alpha2.OK}
+	}))
+
+	token, err := generateJWTToken([]byte("test"), jwt.SigningMethodHS256, "test", time.Now().Add(time.Hour), time.Now(), time.Now(), SymphonyIssuer, "test", []string{"test"})
+	assert.Nil(t, err)
+	reqCtx := &fasthttp.RequestCtx{}
+	reqCtx.Request.Header.Set("Authorization", "Bearer "+token)
+	// identity smuggled through the COA metadata header is ignored
+	reqCtx.Request.Header.Set(v1alpha2.COAMetaHeader, `{"__user":"admin","__roles":"administrator"}`)
+	handler(reqCtx)
+
+	assert.Equal(t, fasthttp.StatusOK, reqCtx.Response.StatusCode())
+	assert.Equal(t, "test", metadata[v1alpha2.COAUserKey])
+	assert.Equal(t, "approver", metadata[v1alpha2.COARolesKey])
+}
+
+type testTokenValidator struct{}
+
+func (v testTokenValidator) ValidateToken(ctx context.Context, token string) (v1alpha2.Principal, bool, error) {
+	switch token {
+	case "key-ci":
+		return v1alpha2.Principal{User: "apikey:ci", Roles: []string{"operator"}, Namespaces: []string{"ci"}}, true, nil
+	case "key-revoked":
+		return v1alpha2.Principal{}, true, errors.New("key is revoked")
+	case "target-t1":
+		return v1alpha2.Principal{User: "target:default/t1", Roles: []string{"target"}, Policy: targetPolicy("t1")}, true, nil
+	}
+	return v1alpha2.Principal{}, false, nil
+}
+
+// targetPolicy limits a target to its heartbeats and its queue
+func targetPolicy(name string) *v1alpha2.RBACPolicy {
+	return &v1alpha2.RBACPolicy{
+		Rules: []v1alpha2.RBACRule{
+			{
+				Subjects:  []v1alpha2.RBACSubject{{User: "*"}},
+				Verbs:     []string{v1alpha2.VerbUpdate},
+				Resources: []string{"targets/ping"},
+				Names:     []string{name},
+			},
+			{
+				Subjects:   []v1alpha2.RBACSubject{{User: "*"}},
+				Verbs:      []string{v1alpha2.VerbGet},
+				Resources:  []string{"solutionversion/queue"},
+				Parameters: map[string]string{"instance": name},
+			},
+		},
+	}
+}
+
+func TestAuthorizeWithTokenValidator(t *testing.T) {
+	j := JWT{
+		AuthHeader:     "Authorization",
+		VerifyKey:      "test",
+		TokenValidator: testTokenValidator{},
+		EnableRBAC:     true,
+		Policy: map[string]Policy{
+			"operator": {Items: map[string]string{"/v1alpha2/instances": "*"}},
+		},
+	}
+	user, roles, err := j.Authorize(context.Background(), "key-ci", "/v1alpha2/instances", "POST", "ci")
+	assert.Nil(t, err)
+	assert.Equal(t, "apikey:ci", user)
+	assert.Equal(t, []string{"operator"}, roles)
+
+	// namespaces and the RBAC policy limit the caller
+	_, _, err = j.Authorize(context.Background(), "key-ci", "/v1alpha2/instances", "POST", "")
+	assert.NotNil(t, err)
+	_, _, err = j.Authorize(context.Background(), "key-ci", "/v1alpha2/targets", "POST", "ci")
+	assert.NotNil(t, err)
+
+	_, _, err = j.Authorize(context.Background(), "key-revoked", "/v1alpha2/instances", "POST", "ci")
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State)
+
+	// other tokens are still read as JWTs
+	token, err := generateJWTToken([]byte("test"), jwt.SigningMethodHS256, "test", time.Now().Add(time.Hour), time.Now(), time.Now(), SymphonyIssuer, "test", []string{"test"})
+	assert.Nil(t, err)
+	j.EnableRBAC = false
+	user, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "POST", "ci")
+	assert.Nil(t, err)
+	assert.Equal(t, "test", user)
+}
+
+func TestJWTPassesNamespaceToTokenValidator(t *testing.T) {
+	j := JWT{AuthHeader: "Authorization", TokenValidator: testTokenValidator{}}
+	handler := j.JWT(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+	reqCtx := &fasthttp.RequestCtx{}
+	reqCtx.Request.SetRequestURI("/v1alpha2/instances?namespace=ci")
+	reqCtx.Request.Header.Set("Authorization", "Bearer key-ci")
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusOK, reqCtx.Response.StatusCode())
+	assert.Equal(t, "apikey:ci", reqCtx.UserValue(v1alpha2.COAUserKey))
+
+	reqCtx = &fasthttp.RequestCtx{}
+	reqCtx.Request.SetRequestURI("/v1alpha2/instances?namespace=prod")
+	reqCtx.Request.Header.Set("Authorization", "Bearer key-ci")
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, reqCtx.Response.StatusCode())
+}
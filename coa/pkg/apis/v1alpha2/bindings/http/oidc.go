@@ -0,0 +1,315 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	defaultOIDCClockSkew    = 60   // seconds
+	defaultOIDCCacheSeconds = 3600 // seconds
+	defaultOIDCUserClaim    = "sub"
+	// keys are fetched again for an unknown key id at most this often, so that tokens with
+	// made-up key ids can't make Symphony hammer the issuer
+	oidcMinRefreshInterval = 30 * time.Second
+	oidcRequestTimeout     = 10 * time.Second
+)
+
+// oidcMethods are the signing methods that are accepted in tokens of OIDC issuers. Symmetric
+// methods are left out, as the keys of the issuer are public.
+var oidcMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// OIDC configures the validation of tokens issued by an OpenID Connect provider, when the auth
+// server is "oidc"
+type OIDC struct {
+	// Issuer is the "iss" of the tokens. Its discovery document is at
+	// "{issuer}/.well-known/openid-configuration".
+	Issuer string `json:"issuer"`
+	// Audiences are the accepted values of the "aud" claim. A token must have one of them.
+	Audiences []string `json:"audiences"`
+	// UserClaim is the claim that names the caller, "sub" by default
+	UserClaim string `json:"userClaim,omitempty"`
+	// ClockSkew is the tolerance in seconds when checking "exp", "nbf" and "iat", 60 by default
+	ClockSkew int `json:"clockSkew,omitempty"`
+	// CacheSeconds is how long the keys of the issuer are cached, 3600 by default. Keys are
+	// fetched again earlier when a token is signed by a key that isn't known yet.
+	CacheSeconds int `json:"cacheSeconds,omitempty"`
+}
+
+type oidcDiscovery struct {
+	Issuer  string `json:"issuer"`
+	JwksURI string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type oidcKey struct {
+	key interface{}
+	alg string
+}
+
+// oidcVerifier validates tokens of an OIDC issuer. It fetches the discovery document and the
+// keys of the issuer when the first token is validated, and caches them.
+type oidcVerifier struct {
+	config  OIDC
+	client  *http.Client
+	now     func() time.Time
+	lock    sync.Mutex
+	jwksURI string
+	keys    map[string]oidcKey
+	fetched time.Time
+}
+
+func newOIDCVerifier(config *OIDC) (*oidcVerifier, error) {
+	if config == nil || config.Issuer == "" {
+		return nil, v1alpha2.NewCOAError(nil, "the 'oidc.issuer' setting of jwt middleware is not set", v1alpha2.MissingConfig)
+	}
+	if len(config.Audiences) == 0 {
+		return nil, v1alpha2.NewCOAError(nil, "the 'oidc.audiences' setting of jwt middleware is not set", v1alpha2.MissingConfig)
+	}
+	ret := &oidcVerifier{
+		config: *config,
+		client: &http.Client{Timeout: oidcRequestTimeout},
+		now:    time.Now,
+	}
+	if ret.config.UserClaim == "" {
+		ret.config.UserClaim = defaultOIDCUserClaim
+	}
+	if ret.config.ClockSkew == 0 {
+		ret.config.ClockSkew = defaultOIDCClockSkew
+	}
+	if ret.config.CacheSeconds == 0 {
+		ret.config.CacheSeconds = defaultOIDCCacheSeconds
+	}
+	return ret, nil
+}
+
+// validate checks the signature and the registered claims of a token, and returns its claims
+func (v *oidcVerifier) validate(tokenStr string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	// the claims are checked below, with the clock skew
+	parser := jwt.NewParser(jwt.WithValidMethods(oidcMethods), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(tokenStr, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.key(kid, token.Method.Alg())
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyIssuer(v.config.Issuer, true) {
+		return nil, fmt.Errorf("token is not issued by '%s'", v.config.Issuer)
+	}
+	now := v.now()
+	skew := time.Duration(v.config.ClockSkew) * time.Second
+	if !claims.VerifyExpiresAt(now.Add(-skew).Unix(), true) {
+		return nil, fmt.Errorf("token is expired or has no expiry")
+	}
+	if !claims.VerifyNotBefore(now.Add(skew).Unix(), false) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+	if !claims.VerifyIssuedAt(now.Add(skew).Unix(), false) {
+		return nil, fmt.Errorf("token is issued in the future")
+	}
+	audience := false
+	for _, a := range v.config.Audiences {
+		if claims.VerifyAudience(a, true) {
+			audience = true
+			break
+		}
+	}
+	if !audience {
+		return nil, fmt.Errorf("token is not for any of the audiences %v", v.config.Audiences)
+	}
+	return claims, nil
+}
+
+// user returns the caller named by the claims
+func (v *oidcVerifier) user(claims jwt.MapClaims) string {
+	user, _ := claims[v.config.UserClaim].(string)
+	return user
+}
+
+// key returns the key of the issuer with the given key id, fetching the keys if they're not
+// cached, have expired, or don't have the key id
+func (v *oidcVerifier) key(kid string, alg string) (interface{}, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	now := v.now()
+	if v.keys == nil || now.Sub(v.fetched) > time.Duration(v.config.CacheSeconds)*time.Second {
+		if err := v.refresh(now); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := v.findKey(kid)
+	if !ok && now.Sub(v.fetched) > oidcMinRefreshInterval {
+		// the issuer may have rotated its keys
+		if err := v.refresh(now); err != nil {
+			return nil, err
+		}
+		key, ok = v.findKey(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("key '%s' is not found in the keys of '%s'", kid, v.config.Issuer)
+	}
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key '%s' is not for %s", kid, alg)
+	}
+	return key.key, nil
+}
+
+// findKey finds a key by its id. Tokens without a key id can only be checked when the issuer
+// has a single key.
+func (v *oidcVerifier) findKey(kid string) (oidcKey, bool) {
+	if kid == "" {
+		if len(v.keys) == 1 {
+			for _, key := range v.keys {
+				return key, true
+			}
+		}
+		return oidcKey{}, false
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *oidcVerifier) refresh(now time.Time) error {
+	if v.jwksURI == "" {
+		discovery := oidcDiscovery{}
+		if err := v.get(strings.TrimSuffix(v.config.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return err
+		}
+		if discovery.Issuer != v.config.Issuer {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("discovery document of '%s' is for issuer '%s'", v.config.Issuer, discovery.Issuer), v1alpha2.BadConfig)
+		}
+		if discovery.JwksURI == "" {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("discovery document of '%s' has no jwks_uri", v.config.Issuer), v1alpha2.BadConfig)
+		}
+		v.jwksURI = discovery.JwksURI
+	}
+	set := jsonWebKeySet{}
+	if err := v.get(v.jwksURI, &set); err != nil {
+		return err
+	}
+	keys := make(map[string]oidcKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			log.Infof("JWT: Skipping key '%s' of '%s'. %s", k.Kid, v.config.Issuer, err.Error())
+			continue
+		}
+		keys[k.Kid] = oidcKey{key: key, alg: k.Alg}
+	}
+	v.keys = keys
+	v.fetched = now
+	log.Debugf("JWT: Fetched %d keys of '%s'", len(keys), v.config.Issuer)
+	return nil
+}
+
+func (v *oidcVerifier) get(url string, result interface{}) error {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to get '%s'", url), v1alpha2.InternalError)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read '%s'", url), v1alpha2.InternalError)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("getting '%s' returned status %d", url, resp.StatusCode), v1alpha2.InternalError)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("'%s' is not valid JSON", url), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > int64(^uint32(0)>>1) {
+			return nil, fmt.Errorf("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve '%s' is not supported", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve '%s'", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("key type '%s' is not supported", k.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("key parameter is empty")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// testIssuer is a stand-in OIDC issuer that serves a discovery document and its keys
+type testIssuer struct {
+	server   *httptest.Server
+	lock     sync.Mutex
+	keys     []jsonWebKey
+	jwksGets int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{}
+	issuer.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer.lock.Lock()
+		defer issuer.lock.Unlock()
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(oidcDiscovery{Issuer: issuer.server.URL, JwksURI: issuer.server.URL + "/keys"})
+		case "/keys":
+			issuer.jwksGets++
+			json.NewEncoder(w).Encode(jsonWebKeySet{Keys: issuer.keys})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) setKeys(keys ...jsonWebKey) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.keys = keys
+}
+
+func (i *testIssuer) gets() int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	return i.jwksGets
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJWK(t *testing.T, kid string) (*rsa.PrivateKey, jsonWebKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	return key, jsonWebKey{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256", N: encodeBigInt(key.N), E: encodeBigInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jsonWebKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	return key, jsonWebKey{Kty: "EC", Kid: kid, Crv: "P-256", X: encodeBigInt(key.X), Y: encodeBigInt(key.Y)}
+}
+
+func signOIDCToken(t *testing.T, key interface{}, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	ret, err := token.SignedString(key)
+	assert.Nil(t, err)
+	return ret
+}
+
+func oidcClaims(issuer string, audience string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":    issuer,
+		"aud":    audience,
+		"sub":    "alice",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"iat":    time.Now().Unix(),
+		"groups": []string{"symphony-operators", "symphony-admins"},
+	}
+}
+
+func newOIDCJWT(t *testing.T, issuer string) JWT {
+	j := JWT{
+		AuthServer: AuthServerOIDC,
+		OIDC:       &OIDC{Issuer: issuer, Audiences: []string{"symphony-api"}},
+		Roles: []ClaimRoleMap{
+			{Role: "administrator", Claim: "groups", Value: "symphony-admins"},
+			{Role: "reader", Claim: "groups", Value: "symphony-readers"},
+		},
+	}
+	assert.Nil(t, j.Init())
+	return j
+}
+
+func TestOIDCInitRequiresIssuerAndAudiences(t *testing.T) {
+	j := JWT{AuthServer: AuthServerOIDC}
+	err := j.Init()
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+
+	j = JWT{AuthServer: AuthServerOIDC, OIDC: &OIDC{Issuer: "https://idp"}}
+	err = j.Init()
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+
+	j = JWT{AuthServer: AuthServerOIDC, OIDC: &OIDC{Issuer: "https://idp", Audiences: []string{"symphony-api"}}}
+	assert.Nil(t, j.Init())
+	assert.Equal(t, "Authorization", j.AuthHeader)
+	assert.Equal(t, "sub", j.oidc.config.UserClaim)
+}
+
+func TestOIDCAuthorize(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, rsaPublic := rsaJWK(t, "rsa1")
+	ecKey, ecPublic := ecJWK(t, "ec1")
+	issuer.setKeys(rsaPublic, ecPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+
+	token := signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", oidcClaims(issuer.server.URL, "symphony-api"))
+	user, roles, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, []string{"administrator"}, roles)
+
+	token = signOIDCToken(t, ecKey, jwt.SigningMethodES256, "ec1", oidcClaims(issuer.server.URL, "symphony-api"))
+	user, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", user)
+
+	// keys are cached
+	assert.Equal(t, 1, issuer.gets())
+}
+
+func TestOIDCRejectsInvalidTokens(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, rsaPublic := rsaJWK(t, "rsa1")
+	otherKey, _ := rsaJWK(t, "rsa1")
+	issuer.setKeys(rsaPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+
+	cases := map[string]jwt.MapClaims{
+		"wrong audience": oidcClaims(issuer.server.URL, "other-api"),
+		"wrong issuer":   oidcClaims("https://other-issuer", "symphony-api"),
+	}
+	expired := oidcClaims(issuer.server.URL, "symphony-api")
+	expired["exp"] = time.Now().Add(-2 * time.Minute).Unix()
+	cases["expired"] = expired
+	noExpiry := oidcClaims(issuer.server.URL, "symphony-api")
+	delete(noExpiry, "exp")
+	cases["no expiry"] = noExpiry
+	notYet := oidcClaims(issuer.server.URL, "symphony-api")
+	notYet["nbf"] = time.Now().Add(2 * time.Minute).Unix()
+	cases["not valid yet"] = notYet
+	for name, claims := range cases {
+		token := signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", claims)
+		_, _, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+		assert.NotNil(t, err, name)
+		assert.Equal(t, v1alpha2.Unauthorized, err.(v1alpha2.COAError).State, name)
+	}
+
+	// signed by another key with the same key id
+	token := signOIDCToken(t, otherKey, jwt.SigningMethodRS256, "rsa1", oidcClaims(issuer.server.URL, "symphony-api"))
+	_, _, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.NotNil(t, err)
+
+	// symmetric tokens can't be signed with the public key of the issuer
+	token = signOIDCToken(t, []byte("secret"), jwt.SigningMethodHS256, "rsa1", oidcClaims(issuer.server.URL, "symphony-api"))
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.NotNil(t, err)
+}
+
+func TestOIDCClockSkew(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, rsaPublic := rsaJWK(t, "rsa1")
+	issuer.setKeys(rsaPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+
+	// within the default skew of 60 seconds
+	claims := oidcClaims(issuer.server.URL, "symphony-api")
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	claims["nbf"] = time.Now().Add(30 * time.Second).Unix()
+	token := signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", claims)
+	_, _, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+
+	j.oidc.config.ClockSkew = 10
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.NotNil(t, err)
+}
+
+func TestOIDCFetchesRotatedKeys(t *testing.T) {
+	issuer := newTestIssuer(t)
+	oldKey, oldPublic := rsaJWK(t, "old")
+	newKey, newPublic := rsaJWK(t, "new")
+	issuer.setKeys(oldPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+	now := time.Now()
+	j.oidc.now = func() time.Time { return now }
+
+	token := signOIDCToken(t, oldKey, jwt.SigningMethodRS256, "old", oidcClaims(issuer.server.URL, "symphony-api"))
+	_, _, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+
+	issuer.setKeys(newPublic)
+	token = signOIDCToken(t, newKey, jwt.SigningMethodRS256, "new", oidcClaims(issuer.server.URL, "symphony-api"))
+	// keys were just fetched, so an unknown key doesn't fetch them again
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, issuer.gets())
+
+	now = now.Add(time.Minute)
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, issuer.gets())
+
+	// cached keys expire
+	now = now.Add(2 * time.Hour)
+	j.oidc.now = func() time.Time { return now }
+	token = signOIDCToken(t, newKey, jwt.SigningMethodRS256, "new", jwt.MapClaims{
+		"iss": issuer.server.URL,
+		"aud": []string{"other-api", "symphony-api"},
+		"sub": "alice",
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "GET", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, issuer.gets())
+}
+
+func TestOIDCWithRBAC(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, rsaPublic := rsaJWK(t, "rsa1")
+	issuer.setKeys(rsaPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+	j.EnableRBAC = true
+	j.Policy = map[string]Policy{
+		"administrator": {Items: map[string]string{"*": "*"}},
+	}
+
+	token := signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", oidcClaims(issuer.server.URL, "symphony-api"))
+	_, _, err := j.Authorize(context.Background(), token, "/v1alpha2/instances", "POST", "")
+	assert.Nil(t, err)
+
+	claims := oidcClaims(issuer.server.URL, "symphony-api")
+	claims["groups"] = []string{"symphony-readers"}
+	token = signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", claims)
+	_, _, err = j.Authorize(context.Background(), token, "/v1alpha2/instances", "POST", "")
+	assert.NotNil(t, err)
+}
+
+func TestOIDCMiddleware(t *testing.T) {
+	issuer := newTestIssuer(t)
+	rsaKey, rsaPublic := rsaJWK(t, "rsa1")
+	issuer.setKeys(rsaPublic)
+	j := newOIDCJWT(t, issuer.server.URL)
+	j.OIDC.UserClaim = "email"
+	assert.Nil(t, j.Init())
+
+	var metadata map[string]string
+	handler := j.JWT(wrapAsHTTPHandler(v1alpha2.Endpoint{Route: "greetings"}, func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		metadata = request.Metadata
+		return v1alpha2.COAResponse{State: v1alpha2.OK}
+	}))
+
+	claims := oidcClaims(issuer.server.URL, "symphony-api")
+	claims["email"] = "alice@contoso.com"
+	reqCtx := &fasthttp.RequestCtx{}
+	reqCtx.Request.Header.Set("Authorization", "Bearer "+signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", claims))
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusOK, reqCtx.Response.StatusCode())
+	assert.Equal(t, "alice@contoso.com", metadata[v1alpha2.COAUserKey])
+	assert.Equal(t, "administrator", metadata[v1alpha2.COARolesKey])
+
+	reqCtx = &fasthttp.RequestCtx{}
+	reqCtx.Request.Header.Set("Authorization", "Bearer "+signOIDCToken(t, rsaKey, jwt.SigningMethodRS256, "rsa1", oidcClaims(issuer.server.URL, "other-api")))
+	handler(reqCtx)
+	assert.Equal(t, fasthttp.StatusUnauthorized, reqCtx.Response.StatusCode())
+}
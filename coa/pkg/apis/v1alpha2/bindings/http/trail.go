@@ -7,17 +7,45 @@
 package http
 
 import (
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/valyala/fasthttp"
 )
 
+// TrailType is the type of the trails of HTTP requests
+const TrailType = "http.request"
+
 type Trail struct {
 	PubSubProvider pubsub.IPubSubProvider
 }
 
+// Trail publishes a trail to the "trail" topic for every request that can change an object, so
+// that the trails manager can append it to the ledgers. The trail has the caller, the method,
+// the path and the status of the response, including for requests that were refused.
 func (j Trail) Trail(next fasthttp.RequestHandler) fasthttp.RequestHandler {
 	return func(ctx *fasthttp.RequestCtx) {
 		next(ctx)
+		method := string(ctx.Method())
+		if j.PubSubProvider == nil || method == fasthttp.MethodGet || method == fasthttp.MethodHead || method == fasthttp.MethodOptions {
+			return
+		}
+		user, _ := ctx.UserValue(v1alpha2.COAUserKey).(string)
+		err := j.PubSubProvider.Publish("trail", v1alpha2.Event{
+			Body: []v1alpha2.Trail{
+				{
+					Type: TrailType,
+					Properties: map[string]interface{}{
+						"user":   user,
+						"method": method,
+						"path":   string(ctx.Path()),
+						"status": ctx.Response.StatusCode(),
+					},
+				},
+			},
+		})
+		if err != nil {
+			log.Errorf("failed to publish the trail of %s %s: %s", method, string(ctx.Path()), err.Error())
+		}
 	}
 }
 func (j *Trail) SetPubSubProvider(provider pubsub.IPubSubProvider) {
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package http
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+type recordingPubSubProvider struct {
+	events []v1alpha2.Event
+}
+
+func (r *recordingPubSubProvider) Init(config providers.IProviderConfig) error {
+	return nil
+}
+func (r *recordingPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
+	if topic == "trail" {
+		r.events = append(r.events, event)
+	}
+	return nil
+}
+func (r *recordingPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
+	return nil
+}
+func (r *recordingPubSubProvider) Cancel() context.CancelFunc {
+	return nil
+}
+
+func TestTrailPublishesChanges(t *testing.T) {
+	pubsub := &recordingPubSubProvider{}
+	trail := Trail{}
+	trail.SetPubSubProvider(pubsub)
+	handler := trail.Trail(func(ctx *fasthttp.RequestCtx) {
+		ctx.SetUserValue(v1alpha2.COAUserKey, "admin")
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	})
+
+	for _, method := range []string{fasthttp.MethodGet, fasthttp.MethodPost, fasthttp.MethodDelete} {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(method)
+		ctx.Request.SetRequestURI("/v1alpha2/instances/instance1")
+		handler(ctx)
+	}
+
+	assert.Equal(t, 2, len(pubsub.events))
+	trails := pubsub.events[1].Body.([]v1alpha2.Trail)
+	assert.Equal(t, TrailType, trails[0].Type)
+	assert.Equal(t, map[string]interface{}{
+		"user":   "admin",
+		"method": fasthttp.MethodDelete,
+		"path":   "/v1alpha2/instances/instance1",
+		"status": fasthttp.StatusOK,
+	}, trails[0].Properties)
+}
@@ -208,6 +208,8 @@ const (
 	JobUpdate JobAction = "UPDATE"
 	JobDelete JobAction = "DELETE"
 	JobRun    JobAction = "RUN"
+	// JobControl carries a cancel, pause or resume request of an activation to a site
+	JobControl JobAction = "CONTROL"
 )
 
 type JobData struct {
@@ -217,6 +219,16 @@ type JobData struct {
 	Body   interface{} `json:"body,omitempty"`
 	Data   []byte      `json:"data"`
 }
+
+// ScheduledJobData is a job that is queued once it's due, such as the deployment of a rollout
+// that is paused between waves
+type ScheduledJobData struct {
+	ObjectType string    `json:"objectType"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Due        time.Time `json:"due"`
+	Job        JobData   `json:"job"`
+}
+
 type ActivationData struct {
 	CampaignVersion             string                            `json:"campaignversion"`
 	Namespace            string                            `json:"namespace,omitempty"`
@@ -244,11 +256,9 @@ func (s *ActivationData) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	// validate if Schedule meet RFC 3339
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return fmt.Errorf("invalid timestamp format: %v", err)
-		}
+	// validate if Schedule is a RFC 3339 timestamp or a cron expression
+	if err := ValidateSchedule(s.Schedule, ""); err != nil {
+		return fmt.Errorf("invalid schedule format: %v", err)
 	}
 	return nil
 }
@@ -256,10 +266,8 @@ func (s *ActivationData) UnmarshalJSON(data []byte) error {
 // MarshalJSON customizes the JSON marshalling for ActivationData
 func (s ActivationData) MarshalJSON() ([]byte, error) {
 	type Alias ActivationData
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return nil, fmt.Errorf("invalid timestamp format: %v", err)
-		}
+	if err := ValidateSchedule(s.Schedule, ""); err != nil {
+		return nil, fmt.Errorf("invalid schedule format: %v", err)
 	}
 	return json.Marshal(&struct {
 		*Alias
@@ -298,6 +306,19 @@ func (s ActivationData) ShouldFireNow() (bool, error) {
 	return dtUTC.Before(dtNow), nil
 }
 
+// ResolveSchedule replaces a cron schedule with the RFC 3339 timestamp of its next activation after the given time
+func (s *ActivationData) ResolveSchedule(after time.Time) error {
+	if s.Schedule == "" || IsTimestampSchedule(s.Schedule) {
+		return nil
+	}
+	next, err := NextScheduleTime(s.Schedule, "", after)
+	if err != nil {
+		return err
+	}
+	s.Schedule = next.UTC().Format(time.RFC3339)
+	return nil
+}
+
 type InputOutputData struct {
 	Inputs  map[string]interface{}            `json:"inputs,omitempty"`
 	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
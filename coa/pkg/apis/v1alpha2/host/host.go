@@ -19,10 +19,12 @@ import (
 
 	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	bindings "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings"
+	grpc "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/grpc"
 	http "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/http"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/bindings/mqtt"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	mf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/managers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/openapi"
 	pf "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providerfactory"
 	pv "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
@@ -79,6 +81,14 @@ type APIConfig struct {
 	Vendors []vendors.VendorConfig `json:"vendors"`
 	PubSub  PubSubConfig           `json:"pubsub,omitempty"`
 	KeyLock KeyLockConfig          `json:"keylock,omitempty"`
+	OpenAPI OpenAPIConfig          `json:"openAPI,omitempty"`
+}
+
+// OpenAPIConfig configures the OpenAPI document the host serves at /v1alpha2/openapi.json.
+type OpenAPIConfig struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Version  string `json:"version,omitempty"`
 }
 
 type BindingConfig struct {
@@ -96,6 +106,8 @@ type APIHost struct {
 	SharedPubSubProvider  pv.IProvider
 	SharedKeyLockProvider pv.IProvider
 	ShutdownGracePeriod   time.Duration
+	// TokenValidator validates tokens that aren't JWTs for the bindings, if a vendor issues them
+	TokenValidator v1alpha2.ITokenValidator
 }
 
 func overrideWithEnvVariable(value string, env string) string {
@@ -250,6 +262,21 @@ func (h *APIHost) Launch(config HostConfig,
 		}
 	}
 
+	validators := make(v1alpha2.TokenValidators, 0)
+	for _, v := range h.Vendors {
+		if tv, ok := v.Vendor.(vendors.ITokenValidatorVendor); ok {
+			if validator := tv.GetTokenValidator(); validator != nil {
+				log.Infof("--- token validator established by vendor: %s ---", v.Vendor.GetInfo().Name)
+				validators = append(validators, validator)
+			}
+		}
+	}
+	if len(validators) == 1 {
+		h.TokenValidator = validators[0]
+	} else if len(validators) > 1 {
+		h.TokenValidator = validators
+	}
+
 	var wg sync.WaitGroup
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -273,6 +300,9 @@ func (h *APIHost) Launch(config HostConfig,
 		for _, v := range h.Vendors {
 			endpoints = append(endpoints, v.Vendor.GetEndpoints()...)
 		}
+		if !config.API.OpenAPI.Disabled {
+			endpoints = append(endpoints, openapi.NewEndpoint(openAPIInfo(config.API.OpenAPI), endpoints))
+		}
 
 		for _, b := range config.Bindings {
 			switch b.Type {
@@ -305,6 +335,12 @@ func (h *APIHost) Launch(config HostConfig,
 					return err
 				}
 				h.Bindings = append(h.Bindings, binding)
+			case "bindings.grpc":
+				binding, err := h.launchGRPC(b.Config, endpoints)
+				if err != nil {
+					return err
+				}
+				h.Bindings = append(h.Bindings, binding)
 			default:
 				return v1alpha2.NewCOAError(nil, fmt.Sprintf("binding type '%s' is not recognized", b.Type), v1alpha2.BadConfig)
 			}
@@ -363,6 +399,7 @@ func (h *APIHost) launchHTTP(config interface{}, endpoints []v1alpha2.Endpoint,
 	if err != nil {
 		return nil, err
 	}
+	httpConfig.TokenValidator = h.TokenValidator
 	binding := &http.HttpBinding{}
 	return binding, binding.Launch(httpConfig, endpoints, pubsubProvider)
 }
@@ -380,3 +417,29 @@ func (h *APIHost) launchMQTT(config interface{}, endpoints []v1alpha2.Endpoint)
 	binding := &mqtt.MQTTBinding{}
 	return binding, binding.Launch(mqttConfig, endpoints)
 }
+
+func (h *APIHost) launchGRPC(config interface{}, endpoints []v1alpha2.Endpoint) (bindings.IBinding, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	grpcConfig := grpc.GrpcBindingConfig{}
+	err = json.Unmarshal(data, &grpcConfig)
+	if err != nil {
+		return nil, err
+	}
+	grpcConfig.TokenValidator = h.TokenValidator
+	binding := &grpc.GrpcBinding{}
+	return binding, binding.Launch(grpcConfig, endpoints)
+}
+
+func openAPIInfo(config OpenAPIConfig) openapi.Info {
+	info := openapi.Info{Title: config.Title, Version: config.Version}
+	if info.Title == "" {
+		info.Title = "Symphony API"
+	}
+	if info.Version == "" {
+		info.Version = "v1alpha2"
+	}
+	return info
+}
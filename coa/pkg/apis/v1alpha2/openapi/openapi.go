@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// Version is the OpenAPI version of the documents
+	Version = "3.0.3"
+	// Route is the route of the document endpoint, under the "v1alpha2" version
+	Route = "openapi.json"
+)
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem has the operations of a path by lower-case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Content map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Build describes endpoints with an OpenAPI document. Paths are the routes of the HTTP
+// binding: "/{version}/{route}" followed by a path parameter for each endpoint parameter.
+// Optional parameters make a path for each number of them that are given, as OpenAPI path
+// parameters are required. Bodies are only described if the endpoints have docs for them.
+// Bodies described by ListResponse go to the path without the optional parameters.
+func Build(info Info, endpoints []v1alpha2.Endpoint) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+	schemas := newSchemaBuilder()
+	for _, e := range endpoints {
+		docs := v1alpha2.EndpointDocs{}
+		if e.Docs != nil {
+			docs = *e.Docs
+		}
+		tags := docs.Tags
+		if len(tags) == 0 {
+			tags = []string{strings.Split(e.Route, "/")[0]}
+		}
+		paths := endpointPaths(e)
+		for i, path := range paths {
+			item, ok := doc.Paths[path]
+			if !ok {
+				item = make(PathItem)
+				doc.Paths[path] = item
+			}
+			for _, method := range e.Methods {
+				opDocs := docs.Operations[method]
+				op := &Operation{
+					OperationID: operationID(method, path),
+					Summary:     opDocs.Summary,
+					Tags:        tags,
+					Responses: map[string]Response{
+						"200": {Description: "OK"},
+					},
+				}
+				if op.Summary == "" {
+					op.Summary = docs.Summary
+				}
+				for _, name := range pathParameters(path) {
+					op.Parameters = append(op.Parameters, Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+				}
+				for _, name := range docs.Query {
+					op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Schema: &Schema{Type: "string"}})
+				}
+				if opDocs.Request != nil {
+					op.RequestBody = &RequestBody{Content: map[string]MediaType{
+						"application/json": {Schema: schemas.schemaOf(opDocs.Request)},
+					}}
+				}
+				response := opDocs.Response
+				if i == 0 && len(paths) > 1 && opDocs.ListResponse != nil {
+					response = opDocs.ListResponse
+				}
+				if response != nil {
+					op.Responses["200"] = Response{Description: "OK", Content: map[string]MediaType{
+						"application/json": {Schema: schemas.schemaOf(response)},
+					}}
+				}
+				item[strings.ToLower(method)] = op
+			}
+		}
+	}
+	doc.Components.Schemas = schemas.components
+	return doc
+}
+
+// NewEndpoint returns the endpoint that serves the document of the given endpoints and of
+// itself at "/v1alpha2/openapi.json".
+func NewEndpoint(info Info, endpoints []v1alpha2.Endpoint) v1alpha2.Endpoint {
+	endpoint := v1alpha2.Endpoint{
+		Methods: []string{fasthttp.MethodGet},
+		Route:   Route,
+		Version: "v1alpha2",
+		Docs: &v1alpha2.EndpointDocs{
+			Summary: "Get the OpenAPI document of the API",
+			Tags:    []string{"openapi"},
+		},
+	}
+	all := append(append(make([]v1alpha2.Endpoint, 0, len(endpoints)+1), endpoints...), endpoint)
+	data, err := json.Marshal(Build(info, all))
+	endpoint.Handler = func(request v1alpha2.COARequest) v1alpha2.COAResponse {
+		if err != nil {
+			return v1alpha2.COAResponse{
+				State: v1alpha2.InternalError,
+				Body:  []byte(err.Error()),
+			}
+		}
+		return v1alpha2.COAResponse{
+			State:       v1alpha2.OK,
+			Body:        data,
+			ContentType: "application/json",
+		}
+	}
+	return endpoint
+}
+
+// endpointPaths returns the paths of an endpoint, one more for each optional parameter
+func endpointPaths(e v1alpha2.Endpoint) []string {
+	path := "/" + e.Version + "/" + e.Route
+	ret := make([]string, 0)
+	optional := false
+	for _, p := range e.Parameters {
+		if strings.HasSuffix(p, "?") {
+			if !optional {
+				ret = append(ret, path)
+			}
+			optional = true
+			p = p[:len(p)-1]
+		}
+		path += "/{" + p + "}"
+		if optional {
+			ret = append(ret, path)
+		}
+	}
+	if !optional {
+		ret = append(ret, path)
+	}
+	return ret
+}
+
+func pathParameters(path string) []string {
+	ret := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			ret = append(ret, segment[1:len(segment)-1])
+		}
+	}
+	return ret
+}
+
+// operationID names an operation after its method and path, such as
+// "getV1alpha2InstancesByName" for GET /v1alpha2/instances/{name}
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") {
+			b.WriteString("By")
+		}
+		upper := true
+		for _, r := range segment {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
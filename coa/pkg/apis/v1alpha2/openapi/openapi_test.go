@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMeta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type testNode struct {
+	Name     string     `json:"name"`
+	Children []testNode `json:"children,omitempty"`
+}
+
+type testObject struct {
+	Metadata testMeta        `json:"metadata"`
+	Spec     *testNode       `json:"spec,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+	Created  time.Time       `json:"created"`
+	Count    int64           `json:"count"`
+	Secret   string          `json:"-"`
+	internal string
+	testInline
+}
+
+type testInline struct {
+	Inlined bool `json:"inlined"`
+}
+
+func testEndpoints() []v1alpha2.Endpoint {
+	return []v1alpha2.Endpoint{
+		{
+			Methods:    []string{"GET", "POST", "DELETE"},
+			Route:      "instances",
+			Version:    "v1alpha2",
+			Parameters: []string{"name?"},
+			Docs: &v1alpha2.EndpointDocs{
+				Summary: "Manage instances",
+				Query:   []string{"namespace"},
+				Operations: map[string]v1alpha2.OperationDocs{
+					"GET":  {Response: testObject{}, ListResponse: []testObject{}},
+					"POST": {Summary: "Create or update an instance", Request: testObject{}},
+				},
+			},
+		},
+		{
+			Methods:    []string{"GET"},
+			Route:      "instances/{name}/history",
+			Version:    "v1alpha2",
+			Parameters: []string{"revision?"},
+		},
+		{
+			Methods:    []string{"GET"},
+			Route:      "targets/download",
+			Version:    "v1alpha2",
+			Parameters: []string{"doc-type", "name"},
+		},
+	}
+}
+
+func TestBuildPaths(t *testing.T) {
+	doc := Build(Info{Title: "test", Version: "1.0"}, testEndpoints())
+	assert.Equal(t, Version, doc.OpenAPI)
+	paths := make([]string, 0)
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	assert.ElementsMatch(t, []string{
+		"/v1alpha2/instances",
+		"/v1alpha2/instances/{name}",
+		"/v1alpha2/instances/{name}/history",
+		"/v1alpha2/instances/{name}/history/{revision}",
+		"/v1alpha2/targets/download/{doc-type}/{name}",
+	}, paths)
+
+	op := doc.Paths["/v1alpha2/targets/download/{doc-type}/{name}"]["get"]
+	assert.Equal(t, "getV1alpha2TargetsDownloadByDocTypeByName", op.OperationID)
+	assert.Equal(t, []string{"targets"}, op.Tags)
+	assert.Equal(t, 2, len(op.Parameters))
+	assert.Equal(t, "doc-type", op.Parameters[0].Name)
+	assert.True(t, op.Parameters[0].Required)
+
+	ids := make(map[string]bool)
+	for _, item := range doc.Paths {
+		for _, op := range item {
+			assert.False(t, ids[op.OperationID], op.OperationID)
+			ids[op.OperationID] = true
+		}
+	}
+}
+
+func TestBuildWithDocs(t *testing.T) {
+	doc := Build(Info{Title: "test", Version: "1.0"}, testEndpoints())
+
+	list := doc.Paths["/v1alpha2/instances"]
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, "Manage instances", list["delete"].Summary)
+	assert.Equal(t, "Create or update an instance", list["post"].Summary)
+	assert.Equal(t, []Parameter{{Name: "namespace", In: "query", Schema: &Schema{Type: "string"}}}, list["get"].Parameters)
+	assert.Equal(t, "#/components/schemas/testObject", list["post"].RequestBody.Content["application/json"].Schema.Ref)
+	assert.Nil(t, list["delete"].RequestBody)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/testObject"}}, list["get"].Responses["200"].Content["application/json"].Schema)
+	assert.Equal(t, "#/components/schemas/testObject", doc.Paths["/v1alpha2/instances/{name}"]["get"].Responses["200"].Content["application/json"].Schema.Ref)
+
+	object := doc.Components.Schemas["testObject"]
+	assert.NotNil(t, object)
+	names := make([]string, 0)
+	for name := range object.Properties {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"metadata", "spec", "data", "created", "count", "inlined"}, names)
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, object.Properties["created"])
+	assert.Equal(t, &Schema{}, object.Properties["data"])
+	assert.Equal(t, &Schema{Type: "integer", Format: "int64"}, object.Properties["count"])
+
+	meta := doc.Components.Schemas["testMeta"]
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "string"}}, meta.Properties["labels"])
+
+	// recursive types refer to their component
+	node := doc.Components.Schemas["testNode"]
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/testNode"}}, node.Properties["children"])
+}
+
+func TestNewEndpoint(t *testing.T) {
+	endpoint := NewEndpoint(Info{Title: "test", Version: "1.0"}, testEndpoints())
+	assert.Equal(t, "v1alpha2", endpoint.Version)
+	assert.Equal(t, Route, endpoint.Route)
+
+	response := endpoint.Handler(v1alpha2.COARequest{Method: "GET"})
+	assert.Equal(t, v1alpha2.OK, response.State)
+	assert.Equal(t, "application/json", response.ContentType)
+	doc := Document{}
+	assert.Nil(t, json.Unmarshal(response.Body, &doc))
+	assert.Equal(t, "1.0", doc.Info.Version)
+	assert.NotNil(t, doc.Paths["/v1alpha2/openapi.json"]["get"])
+	assert.NotNil(t, doc.Paths["/v1alpha2/instances/{name}"]["post"])
+}
@@ -19,3 +19,12 @@ type IKeyLockProvider interface {
 	TryLock(string) bool
 	TryLockWithTimeout(string, time.Duration) bool
 }
+
+// IFencedKeyLockProvider is implemented by key lock providers whose locks are leases that can be lost,
+// such as locks shared by several processes. Every time a key is locked it gets a larger fencing token,
+// so a store that records the last token it has seen can reject writes from a holder whose lease expired.
+type IFencedKeyLockProvider interface {
+	IKeyLockProvider
+	// FencingToken returns the token of the lease this provider holds on the key, if any
+	FencingToken(string) (int64, bool)
+}
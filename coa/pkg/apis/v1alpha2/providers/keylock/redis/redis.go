@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package rediskeylock
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var rLog = logger.NewLogger("coa.runtime")
+
+const (
+	separator = "*"
+	// the fencing counter of a key outlives its leases so that tokens keep growing
+	fenceSuffix = "-fence"
+
+	defaultKeyPrefix     = "symphony-lock"
+	defaultLeaseDuration = 30  // seconds
+	defaultRetryInterval = 100 // milliseconds
+)
+
+// acquireScript takes the lease if nobody holds it and returns the new fencing token, or 0
+var acquireScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local fence = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], ARGV[1] .. ":" .. fence, "PX", ARGV[2])
+return fence
+`)
+
+// renewScript extends the lease if it's still held by the caller
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes the lease if it's still held by the caller
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+type RedisKeyLockProviderConfig struct {
+	Name          string `json:"name"`
+	Host          string `json:"host"`
+	Password      string `json:"password,omitempty"`
+	RequiresTLS   bool   `json:"requiresTLS,omitempty"`
+	KeyPrefix     string `json:"keyPrefix,omitempty"`
+	LeaseDuration int    `json:"leaseDuration,omitempty"` // seconds
+	RetryInterval int    `json:"retryInterval,omitempty"` // milliseconds
+}
+
+func RedisKeyLockProviderConfigFromMap(properties map[string]string) (RedisKeyLockProviderConfig, error) {
+	ret := RedisKeyLockProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["host"]; ok {
+		ret.Host = utils.ParseProperty(v)
+	} else {
+		return ret, v1alpha2.NewCOAError(nil, "Redis key lock provider host name is not set", v1alpha2.BadConfig)
+	}
+	if v, ok := properties["password"]; ok {
+		ret.Password = utils.ParseProperty(v)
+	}
+	if v, ok := properties["requiresTLS"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(utils.ParseProperty(v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'requiresTLS' setting of Redis key lock provider", v1alpha2.BadConfig)
+		}
+		ret.RequiresTLS = bVal
+	}
+	if v, ok := properties["keyPrefix"]; ok {
+		ret.KeyPrefix = utils.ParseProperty(v)
+	}
+	for name, target := range map[string]*int{"leaseDuration": &ret.LeaseDuration, "retryInterval": &ret.RetryInterval} {
+		if v, ok := properties[name]; ok && v != "" {
+			iVal, err := strconv.Atoi(utils.ParseProperty(v))
+			if err != nil || iVal < 0 {
+				return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid int value in the '%s' setting of Redis key lock provider", name), v1alpha2.BadConfig)
+			}
+			*target = iVal
+		}
+	}
+	return ret, nil
+}
+
+func toRedisKeyLockProviderConfig(config providers.IProviderConfig) (RedisKeyLockProviderConfig, error) {
+	ret := RedisKeyLockProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// lease is a lock held by this provider. It's renewed in the background until it's unlocked.
+type lease struct {
+	value  string
+	fence  int64
+	cancel context.CancelFunc
+}
+
+// RedisKeyLockProvider locks keys across processes that share a redis server. A lock is a lease
+// that expires after LeaseDuration unless the holder keeps renewing it, so a crashed holder can't
+// block a key forever.
+type RedisKeyLockProvider struct {
+	Config  RedisKeyLockProviderConfig
+	Context *contexts.ManagerContext
+	Client  *redis.Client
+	Ctx     context.Context
+	Cancel  context.CancelFunc
+	owner   string
+	leases  map[string]*lease
+	lock    sync.Mutex
+}
+
+func (r *RedisKeyLockProvider) ID() string {
+	return r.Config.Name
+}
+
+func (r *RedisKeyLockProvider) SetContext(ctx *contexts.ManagerContext) {
+	r.Context = ctx
+}
+
+func (r *RedisKeyLockProvider) InitWithMap(properties map[string]string) error {
+	config, err := RedisKeyLockProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return r.Init(config)
+}
+
+func (r *RedisKeyLockProvider) Init(config providers.IProviderConfig) error {
+	vConfig, err := toRedisKeyLockProviderConfig(config)
+	if err != nil {
+		rLog.Errorf("  P (Redis KeyLock): failed to parse provider config %+v", err)
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid redis key lock provider config", v1alpha2.BadConfig)
+	}
+	if vConfig.Host == "" {
+		return v1alpha2.NewCOAError(nil, "Redis host is not supplied", v1alpha2.MissingConfig)
+	}
+	if vConfig.KeyPrefix == "" {
+		vConfig.KeyPrefix = defaultKeyPrefix
+	}
+	if vConfig.LeaseDuration <= 0 {
+		vConfig.LeaseDuration = defaultLeaseDuration
+	}
+	if vConfig.RetryInterval <= 0 {
+		vConfig.RetryInterval = defaultRetryInterval
+	}
+	r.Config = vConfig
+	r.Ctx, r.Cancel = context.WithCancel(context.Background())
+	options := &redis.Options{
+		Addr:            r.Config.Host,
+		Password:        r.Config.Password,
+		DB:              0,
+		MaxRetries:      3,
+		MaxRetryBackoff: time.Second * 2,
+	}
+	if r.Config.RequiresTLS {
+		options.TLSConfig = &tls.Config{
+			InsecureSkipVerify: !r.Config.RequiresTLS,
+		}
+	}
+	client := redis.NewClient(options)
+	if _, err := client.Ping(r.Ctx).Result(); err != nil {
+		rLog.Errorf("  P (Redis KeyLock): failed to connect to redis %+v", err)
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("redis key lock: error connecting to redis at %s", r.Config.Host), v1alpha2.InternalError)
+	}
+	r.Client = client
+	r.owner = uuid.New().String()
+	r.leases = make(map[string]*lease)
+	return nil
+}
+
+func (r *RedisKeyLockProvider) Lock(key string) {
+	for !r.TryLock(key) {
+		time.Sleep(r.retryInterval())
+	}
+}
+
+func (r *RedisKeyLockProvider) UnLock(key string) {
+	r.lock.Lock()
+	l, ok := r.leases[key]
+	delete(r.leases, key)
+	r.lock.Unlock()
+	if !ok {
+		rLog.Warnf("  P (Redis KeyLock): unlocking key %s that isn't locked by this provider", key)
+		return
+	}
+	l.cancel()
+	released, err := releaseScript.Run(r.Ctx, r.Client, []string{r.leaseKey(key)}, l.value).Int64()
+	if err != nil {
+		rLog.Errorf("  P (Redis KeyLock): failed to release lock %s: %+v", key, err)
+	} else if released == 0 {
+		rLog.Warnf("  P (Redis KeyLock): lease of lock %s expired before it was released", key)
+	}
+}
+
+func (r *RedisKeyLockProvider) TryLock(key string) bool {
+	fence, err := acquireScript.Run(r.Ctx, r.Client, []string{r.leaseKey(key), r.fenceKey(key)},
+		r.owner, r.leaseDuration().Milliseconds()).Int64()
+	if err != nil {
+		rLog.Errorf("  P (Redis KeyLock): failed to acquire lock %s: %+v", key, err)
+		return false
+	}
+	if fence == 0 {
+		return false
+	}
+	ctx, cancel := context.WithCancel(r.Ctx)
+	l := &lease{
+		value:  fmt.Sprintf("%s:%d", r.owner, fence),
+		fence:  fence,
+		cancel: cancel,
+	}
+	r.lock.Lock()
+	r.leases[key] = l
+	r.lock.Unlock()
+	go r.renew(ctx, key, l)
+	return true
+}
+
+func (r *RedisKeyLockProvider) TryLockWithTimeout(key string, duration time.Duration) bool {
+	start := time.Now()
+	for start.Add(duration).After(time.Now()) {
+		if r.TryLock(key) {
+			return true
+		}
+		time.Sleep(r.retryInterval())
+	}
+	return false
+}
+
+func (r *RedisKeyLockProvider) FencingToken(key string) (int64, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if l, ok := r.leases[key]; ok {
+		return l.fence, true
+	}
+	return 0, false
+}
+
+// renew extends the lease every third of its duration until it's released. If the lease is
+// found to be taken over by someone else, it's dropped.
+func (r *RedisKeyLockProvider) renew(ctx context.Context, key string, l *lease) {
+	ticker := time.NewTicker(r.leaseDuration() / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := renewScript.Run(ctx, r.Client, []string{r.leaseKey(key)}, l.value, r.leaseDuration().Milliseconds()).Int64()
+			if err != nil {
+				if ctx.Err() == nil {
+					rLog.Errorf("  P (Redis KeyLock): failed to renew lock %s: %+v", key, err)
+				}
+				continue
+			}
+			if renewed == 0 {
+				rLog.Errorf("  P (Redis KeyLock): lease of lock %s (fencing token %d) was lost", key, l.fence)
+				r.lock.Lock()
+				if r.leases[key] == l {
+					delete(r.leases, key)
+				}
+				r.lock.Unlock()
+				return
+			}
+		}
+	}
+}
+
+func (r *RedisKeyLockProvider) leaseKey(key string) string {
+	return fmt.Sprintf("%s%s%s", r.Config.KeyPrefix, separator, key)
+}
+
+func (r *RedisKeyLockProvider) fenceKey(key string) string {
+	return fmt.Sprintf("%s%s%s%s", r.Config.KeyPrefix, fenceSuffix, separator, key)
+}
+
+func (r *RedisKeyLockProvider) leaseDuration() time.Duration {
+	return time.Duration(r.Config.LeaseDuration) * time.Second
+}
+
+func (r *RedisKeyLockProvider) retryInterval() time.Duration {
+	return time.Duration(r.Config.RetryInterval) * time.Millisecond
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package rediskeylock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func initializeProvider(t *testing.T, server *miniredis.Miniredis, leaseDuration int) *RedisKeyLockProvider {
+	provider := &RedisKeyLockProvider{}
+	err := provider.Init(RedisKeyLockProviderConfig{
+		Host:          server.Addr(),
+		LeaseDuration: leaseDuration,
+		RetryInterval: 10,
+	})
+	assert.Nil(t, err)
+	t.Cleanup(provider.Cancel)
+	return provider
+}
+
+func TestInitWithMap(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := &RedisKeyLockProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":          "lock",
+		"host":          server.Addr(),
+		"leaseDuration": "10",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "lock", provider.ID())
+	assert.Equal(t, 10, provider.Config.LeaseDuration)
+	assert.Equal(t, defaultKeyPrefix, provider.Config.KeyPrefix)
+	provider.Cancel()
+
+	err = provider.InitWithMap(map[string]string{})
+	assert.NotNil(t, err)
+	err = provider.InitWithMap(map[string]string{"host": server.Addr(), "leaseDuration": "abc"})
+	assert.NotNil(t, err)
+}
+
+func TestLockIsExclusiveAcrossProviders(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider1 := initializeProvider(t, server, 30)
+	provider2 := initializeProvider(t, server, 30)
+
+	assert.True(t, provider1.TryLock("instance1"))
+	assert.False(t, provider2.TryLock("instance1"))
+	assert.False(t, provider1.TryLock("instance1"))
+	assert.True(t, provider2.TryLock("instance2"))
+	fence1, ok := provider1.FencingToken("instance1")
+	assert.True(t, ok)
+
+	provider1.UnLock("instance1")
+	_, ok = provider1.FencingToken("instance1")
+	assert.False(t, ok)
+	assert.True(t, provider2.TryLockWithTimeout("instance1", time.Second))
+	fence2, ok := provider2.FencingToken("instance1")
+	assert.True(t, ok)
+	assert.Greater(t, fence2, fence1)
+}
+
+func TestLockWaitsForUnLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider1 := initializeProvider(t, server, 30)
+	provider2 := initializeProvider(t, server, 30)
+
+	provider1.Lock("instance1")
+	assert.False(t, provider2.TryLockWithTimeout("instance1", 50*time.Millisecond))
+	locked := make(chan struct{})
+	go func() {
+		provider2.Lock("instance1")
+		close(locked)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	provider1.UnLock("instance1")
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "lock wasn't acquired after it was released")
+	}
+}
+
+func TestExpiredLeaseIsTakenOver(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider1 := initializeProvider(t, server, 30)
+	provider2 := initializeProvider(t, server, 30)
+
+	assert.True(t, provider1.TryLock("instance1"))
+	server.FastForward(31 * time.Second)
+	assert.True(t, provider2.TryLock("instance1"))
+
+	// the stale holder must not release the new lease
+	provider1.UnLock("instance1")
+	assert.False(t, provider1.TryLock("instance1"))
+	fence, ok := provider2.FencingToken("instance1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), fence)
+}
+
+func TestLeaseIsRenewed(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := initializeProvider(t, server, 1)
+
+	assert.True(t, provider.TryLock("instance1"))
+	server.FastForward(900 * time.Millisecond)
+	time.Sleep(500 * time.Millisecond)
+	assert.Greater(t, server.TTL(provider.leaseKey("instance1")), 500*time.Millisecond)
+
+	provider.UnLock("instance1")
+	assert.False(t, server.Exists(provider.leaseKey("instance1")))
+}
@@ -0,0 +1,513 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package fileledger
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+)
+
+var fLog = logger.NewLogger("coa.runtime")
+
+const (
+	headFile      = "head.json"
+	segmentPrefix = "segment-"
+	segmentSuffix = ".jsonl"
+	// partialSuffix is added to a segment for the file with the entry that was cut short at its end
+	partialSuffix = ".partial"
+
+	defaultMaxSegmentSize = 10 * 1024 * 1024 // bytes
+	// verification stops listing problems after this many
+	maxProblems = 100
+)
+
+type FileLedgerProviderConfig struct {
+	Name           string `json:"name"`
+	Folder         string `json:"folder"`
+	MaxSegmentSize int64  `json:"maxSegmentSize,omitempty"` // bytes
+	Key            string `json:"key,omitempty"`
+}
+
+func FileLedgerProviderConfigFromMap(properties map[string]string) (FileLedgerProviderConfig, error) {
+	ret := FileLedgerProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["folder"]; ok {
+		ret.Folder = utils.ParseProperty(v)
+	}
+	if v, ok := properties["maxSegmentSize"]; ok && v != "" {
+		iVal, err := strconv.ParseInt(utils.ParseProperty(v), 10, 64)
+		if err != nil || iVal < 0 {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'maxSegmentSize' setting of file ledger provider", v1alpha2.BadConfig)
+		}
+		ret.MaxSegmentSize = iVal
+	}
+	if v, ok := properties["key"]; ok {
+		ret.Key = utils.ParseProperty(v)
+	}
+	return ret, nil
+}
+
+func toFileLedgerProviderConfig(config providers.IProviderConfig) (FileLedgerProviderConfig, error) {
+	ret := FileLedgerProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// Entry is a line of a ledger segment. Its hash covers the hash of the entry before it, so
+// changing, removing or reordering entries breaks the chain from there on. The trail is kept
+// as it was serialized when it was appended, so it hashes the same when it's read back.
+type Entry struct {
+	Sequence uint64          `json:"seq"`
+	Time     time.Time       `json:"time"`
+	Trail    json.RawMessage `json:"trail"`
+	Previous string          `json:"prev"`
+	Hash     string          `json:"hash"`
+}
+
+// computeHash hashes an entry with SHA-256, or with HMAC-SHA256 if the ledger has a key. Without
+// the key, entries can't be rewritten with a valid chain.
+func (e Entry) computeHash(key string) string {
+	var h hash.Hash
+	if key != "" {
+		h = hmac.New(sha256.New, []byte(key))
+	} else {
+		h = sha256.New()
+	}
+	fmt.Fprintf(h, "%s\n%d\n%s\n", e.Previous, e.Sequence, e.Time.UTC().Format(time.RFC3339Nano))
+	h.Write(e.Trail)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// headState is the content of the head file. With a key, its MAC covers the head, so that the
+// head can't be moved back to match a truncated ledger without the key.
+type headState struct {
+	ledger.Anchor
+	MAC string `json:"mac,omitempty"`
+}
+
+func headMAC(key string, head ledger.Anchor) string {
+	h := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(h, "%d\n%s", head.Sequence, head.Hash)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// signedWith tells if the head was written with the key. Heads of ledgers without a key, and
+// the empty head of a new ledger, aren't signed.
+func (h headState) signedWith(key string) bool {
+	if key == "" || h.Anchor == (ledger.Anchor{}) {
+		return true
+	}
+	return hmac.Equal([]byte(h.MAC), []byte(headMAC(key, h.Anchor)))
+}
+
+// FileLedgerProvider appends trails to hash-chained segment files in a folder. A segment is
+// closed for good once it reaches MaxSegmentSize, and entries go to a new one. The head file
+// has the last entry, so removing entries from the end of the ledger is detected too.
+type FileLedgerProvider struct {
+	Config  FileLedgerProviderConfig
+	Context *contexts.ManagerContext
+	lock    sync.Mutex
+	file    *os.File
+	segment int
+	size    int64
+	head    ledger.Anchor
+}
+
+func (f *FileLedgerProvider) Init(config providers.IProviderConfig) error {
+	fileConfig, err := toFileLedgerProviderConfig(config)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "invalid file ledger provider config", v1alpha2.BadConfig)
+	}
+	if fileConfig.Folder == "" {
+		return v1alpha2.NewCOAError(nil, "file ledger provider folder is not set", v1alpha2.MissingConfig)
+	}
+	if fileConfig.MaxSegmentSize == 0 {
+		fileConfig.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if fileConfig.Key == "" {
+		fLog.Warnf("  P (File Ledger): WARNING: the file ledger in %s has no key, so anyone who can write to the folder can rewrite it with a valid chain. Set 'key' to sign it.", fileConfig.Folder)
+	}
+	f.Config = fileConfig
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.open()
+}
+
+func (f *FileLedgerProvider) ID() string {
+	return f.Config.Name
+}
+
+func (f *FileLedgerProvider) SetContext(context *contexts.ManagerContext) {
+	f.Context = context
+}
+
+func (f *FileLedgerProvider) InitWithMap(properties map[string]string) error {
+	config, err := FileLedgerProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return f.Init(config)
+}
+
+// open finds the last entry of the ledger and opens its last segment for appending. It fails if
+// the ledger ends before its head, or if the head isn't signed with the key, so that new entries
+// don't cover up a truncation. Entries past the head were appended by a process that stopped
+// before it could update the head, and the end of the last segment is recovered, see
+// recoverTail.
+func (f *FileLedgerProvider) open() error {
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	if err := os.MkdirAll(f.Config.Folder, 0700); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to create the file ledger folder", v1alpha2.InternalError)
+	}
+	segments, err := listSegments(f.Config.Folder)
+	if err != nil {
+		return err
+	}
+	head, err := readHead(f.Config.Folder)
+	if err != nil {
+		return err
+	}
+	if !head.signedWith(f.Config.Key) {
+		return v1alpha2.NewCOAError(nil, "file ledger head isn't signed with the key of the ledger", v1alpha2.InternalError)
+	}
+	last := ledger.Anchor{}
+	f.segment = 1
+	if len(segments) > 0 {
+		f.segment = segments[len(segments)-1]
+		path := segmentPath(f.Config.Folder, f.segment)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to read the last file ledger segment", v1alpha2.InternalError)
+		}
+		if end := bytes.LastIndexByte(data, '\n') + 1; end < len(data) {
+			if err = f.recoverTail(path, data, end); err != nil {
+				return err
+			}
+		}
+		// the last segment is empty if the process stopped right after it rotated
+		for i := len(segments) - 1; i >= 0; i-- {
+			entries, err := readEntries(segmentPath(f.Config.Folder, segments[i]))
+			if err != nil {
+				return err
+			}
+			if len(entries) > 0 {
+				last = ledger.Anchor{Sequence: entries[len(entries)-1].Sequence, Hash: entries[len(entries)-1].Hash}
+				break
+			}
+		}
+	}
+	if last.Sequence < head.Sequence || (last.Sequence == head.Sequence && last.Hash != head.Hash) {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("file ledger ends at entry %d, which doesn't match its head entry %d", last.Sequence, head.Sequence), v1alpha2.InternalError)
+	}
+	f.head = last
+	if last != head.Anchor {
+		if err = writeHead(f.Config.Folder, f.Config.Key, last); err != nil {
+			return err
+		}
+	}
+	return f.openSegment()
+}
+
+// recoverTail handles the bytes after the last line of a segment, which start at end. An entry
+// that was written without its newline is completed. Anything else is an entry that was cut
+// short while it was appended: it's moved to the partial file of the segment, so that it's kept
+// for an audit, and verification reports it until the partial file is removed.
+func (f *FileLedgerProvider) recoverTail(path string, data []byte, end int) error {
+	tail := data[end:]
+	entry := Entry{}
+	if json.Unmarshal(tail, &entry) == nil && entry.Hash != "" && entry.computeHash(f.Config.Key) == entry.Hash {
+		fLog.Warnf("  P (File Ledger): completing entry %d at the end of %s, which was written without its newline", entry.Sequence, path)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to complete a file ledger entry", v1alpha2.InternalError)
+		}
+		defer file.Close()
+		if _, err = file.Write([]byte("\n")); err != nil {
+			return v1alpha2.NewCOAError(err, "failed to complete a file ledger entry", v1alpha2.InternalError)
+		}
+		return nil
+	}
+	fLog.Errorf("  P (File Ledger): moving an incomplete entry at the end of %s to %s", path, path+partialSuffix)
+	partial, err := os.OpenFile(path+partialSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to keep an incomplete file ledger entry", v1alpha2.InternalError)
+	}
+	_, err = partial.Write(append(append([]byte{}, tail...), '\n'))
+	if err == nil {
+		err = partial.Sync()
+	}
+	partial.Close()
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to keep an incomplete file ledger entry", v1alpha2.InternalError)
+	}
+	if err = os.Truncate(path, int64(end)); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to remove an incomplete file ledger entry", v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (f *FileLedgerProvider) openSegment() error {
+	file, err := os.OpenFile(segmentPath(f.Config.Folder, f.segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "failed to open the file ledger segment", v1alpha2.InternalError)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return v1alpha2.NewCOAError(err, "failed to open the file ledger segment", v1alpha2.InternalError)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *FileLedgerProvider) rotate() error {
+	if err := f.file.Sync(); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write the file ledger segment", v1alpha2.InternalError)
+	}
+	f.file.Close()
+	f.file = nil
+	f.segment++
+	return f.openSegment()
+}
+
+func (f *FileLedgerProvider) Append(ctx context.Context, trails []v1alpha2.Trail) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return v1alpha2.NewCOAError(nil, "file ledger provider is not initialized", v1alpha2.InternalError)
+	}
+	for _, trail := range trails {
+		data, err := json.Marshal(trail)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to serialize the trail", v1alpha2.BadRequest)
+		}
+		entry := Entry{
+			Sequence: f.head.Sequence + 1,
+			Time:     time.Now().UTC(),
+			Trail:    data,
+			Previous: f.head.Hash,
+		}
+		entry.Hash = entry.computeHash(f.Config.Key)
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, "failed to serialize the file ledger entry", v1alpha2.InternalError)
+		}
+		line = append(line, '\n')
+		if f.size > 0 && f.size+int64(len(line)) > f.Config.MaxSegmentSize {
+			if err = f.rotate(); err != nil {
+				return err
+			}
+		}
+		if _, err = f.file.Write(line); err != nil {
+			// don't leave a partial entry for the next one to be appended to
+			f.file.Truncate(f.size)
+			return v1alpha2.NewCOAError(err, "failed to write the file ledger entry", v1alpha2.InternalError)
+		}
+		f.size += int64(len(line))
+		f.head = ledger.Anchor{Sequence: entry.Sequence, Hash: entry.Hash}
+	}
+	if err := f.file.Sync(); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write the file ledger segment", v1alpha2.InternalError)
+	}
+	return writeHead(f.Config.Folder, f.Config.Key, f.head)
+}
+
+func (f *FileLedgerProvider) Verify(ctx context.Context, anchor *ledger.Anchor) (ledger.VerifyResult, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return VerifyFolder(f.Config.Folder, f.Config.Key, anchor)
+}
+
+// VerifyFolder checks the ledger in a folder without a provider, so that it can be checked
+// where the API doesn't run. It recomputes the chain of every segment, and compares its end
+// with the head file and with the anchor, if there is one. The result lists what's wrong.
+func VerifyFolder(folder string, key string, anchor *ledger.Anchor) (ledger.VerifyResult, error) {
+	ret := ledger.VerifyResult{}
+	problem := func(format string, args ...interface{}) {
+		if len(ret.Problems) < maxProblems {
+			ret.Problems = append(ret.Problems, fmt.Sprintf(format, args...))
+		}
+	}
+	segments, err := listSegments(folder)
+	if err != nil {
+		return ret, err
+	}
+	ret.Segments = len(segments)
+	anchorFound := false
+	for i, segment := range segments {
+		expected := 1
+		if i > 0 {
+			expected = segments[i-1] + 1
+		}
+		if segment == expected+1 {
+			problem("segment %d is missing", expected)
+		} else if segment > expected {
+			problem("segments %d to %d are missing", expected, segment-1)
+		}
+		name := filepath.Base(segmentPath(folder, segment))
+		data, err := os.ReadFile(segmentPath(folder, segment))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read file ledger segment %s", name), v1alpha2.InternalError)
+		}
+		lines := bytes.Split(data, []byte("\n"))
+		if len(lines[len(lines)-1]) > 0 {
+			problem("%s ends with an incomplete entry", name)
+		}
+		if _, err := os.Stat(segmentPath(folder, segment) + partialSuffix); err == nil {
+			problem("%s had an incomplete entry at its end, which was moved to %s", name, name+partialSuffix)
+		}
+		for l, line := range lines[:len(lines)-1] {
+			entry := Entry{}
+			if err := json.Unmarshal(line, &entry); err != nil {
+				problem("line %d of %s isn't a ledger entry", l+1, name)
+				continue
+			}
+			if entry.Sequence != ret.Head.Sequence+1 {
+				problem("entry %d follows entry %d in %s", entry.Sequence, ret.Head.Sequence, name)
+			} else if entry.Previous != ret.Head.Hash {
+				problem("entry %d in %s isn't chained to the entry before it", entry.Sequence, name)
+			}
+			if entry.computeHash(key) != entry.Hash {
+				problem("entry %d in %s has been changed", entry.Sequence, name)
+			}
+			if anchor != nil && entry.Sequence == anchor.Sequence {
+				anchorFound = true
+				if entry.Hash != anchor.Hash {
+					problem("entry %d doesn't match the anchor", entry.Sequence)
+				}
+			}
+			ret.Entries++
+			ret.Head = ledger.Anchor{Sequence: entry.Sequence, Hash: entry.Hash}
+		}
+	}
+	if anchor != nil && !anchorFound {
+		problem("the ledger doesn't have entry %d of the anchor", anchor.Sequence)
+	}
+	head, err := readHead(folder)
+	if err != nil {
+		return ret, err
+	}
+	if !head.signedWith(key) {
+		problem("the head isn't signed with the key")
+	}
+	// entries past the head are fine as long as they're chained, see open
+	if head.Sequence > ret.Head.Sequence {
+		problem("the ledger ends at entry %d, but its head is entry %d", ret.Head.Sequence, head.Sequence)
+	} else if head.Sequence == ret.Head.Sequence && head.Hash != ret.Head.Hash {
+		problem("the last entry doesn't match the head")
+	}
+	ret.Valid = len(ret.Problems) == 0
+	return ret, nil
+}
+
+func segmentPath(folder string, segment int) string {
+	return filepath.Join(folder, fmt.Sprintf("%s%06d%s", segmentPrefix, segment, segmentSuffix))
+}
+
+// listSegments returns the numbers of the segments in a folder in order
+func listSegments(folder string) ([]int, error) {
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to read the file ledger folder", v1alpha2.InternalError)
+	}
+	ret := make([]int, 0)
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		segment, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+		if err != nil || segment <= 0 {
+			continue
+		}
+		ret = append(ret, segment)
+	}
+	sort.Ints(ret)
+	return ret, nil
+}
+
+// readEntries reads the complete entries of a segment
+func readEntries(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, "failed to read the file ledger segment", v1alpha2.InternalError)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	ret := make([]Entry, 0, len(lines)-1)
+	for _, line := range lines[:len(lines)-1] {
+		entry := Entry{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("file ledger segment %s has an invalid entry", filepath.Base(path)), v1alpha2.InternalError)
+		}
+		ret = append(ret, entry)
+	}
+	return ret, nil
+}
+
+// readHead returns the head of the ledger, which is empty for a new ledger
+func readHead(folder string) (headState, error) {
+	ret := headState{}
+	data, err := os.ReadFile(filepath.Join(folder, headFile))
+	if os.IsNotExist(err) {
+		return ret, nil
+	}
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "failed to read the file ledger head", v1alpha2.InternalError)
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, "file ledger head is invalid", v1alpha2.InternalError)
+	}
+	return ret, nil
+}
+
+// writeHead replaces the head file, so that it's never seen half-written. The head is signed if
+// the ledger has a key.
+func writeHead(folder string, key string, head ledger.Anchor) error {
+	state := headState{Anchor: head}
+	if key != "" {
+		state.MAC = headMAC(key, head)
+	}
+	data, _ := json.Marshal(state)
+	tmp := filepath.Join(folder, headFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write the file ledger head", v1alpha2.InternalError)
+	}
+	if err := os.Rename(tmp, filepath.Join(folder, headFile)); err != nil {
+		return v1alpha2.NewCOAError(err, "failed to write the file ledger head", v1alpha2.InternalError)
+	}
+	return nil
+}
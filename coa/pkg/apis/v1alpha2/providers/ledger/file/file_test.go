@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package fileledger
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/ledger"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProvider(t *testing.T, folder string, properties map[string]string) *FileLedgerProvider {
+	provider := &FileLedgerProvider{}
+	props := map[string]string{"name": "test", "folder": folder}
+	for k, v := range properties {
+		props[k] = v
+	}
+	assert.Nil(t, provider.InitWithMap(props))
+	return provider
+}
+
+func appendTrails(t *testing.T, provider *FileLedgerProvider, count int) {
+	for i := 0; i < count; i++ {
+		err := provider.Append(context.Background(), []v1alpha2.Trail{
+			{
+				Origin:         "site1",
+				CatalogVersion: "config-v1",
+				Type:           "solutionversions.solution.symphony/v1",
+				Properties:     map[string]interface{}{"user": "admin", "index": i, "html": "<b>&</b>"},
+			},
+		})
+		assert.Nil(t, err)
+	}
+}
+
+func verify(t *testing.T, folder string, key string, anchor *ledger.Anchor) ledger.VerifyResult {
+	result, err := VerifyFolder(folder, key, anchor)
+	assert.Nil(t, err)
+	return result
+}
+
+func TestFileLedgerProviderInit(t *testing.T) {
+	provider := FileLedgerProvider{}
+	err := provider.InitWithMap(map[string]string{"name": "test"})
+	assert.Equal(t, v1alpha2.MissingConfig, err.(v1alpha2.COAError).State)
+	err = provider.InitWithMap(map[string]string{"name": "test", "folder": t.TempDir(), "maxSegmentSize": "abc"})
+	assert.Equal(t, v1alpha2.BadConfig, err.(v1alpha2.COAError).State)
+
+	p := newProvider(t, t.TempDir(), nil)
+	assert.Equal(t, "test", p.ID())
+	assert.Equal(t, int64(defaultMaxSegmentSize), p.Config.MaxSegmentSize)
+}
+
+func TestFileLedgerProviderAppendAndVerify(t *testing.T) {
+	folder := t.TempDir()
+	provider := newProvider(t, folder, map[string]string{"maxSegmentSize": "1000"})
+	appendTrails(t, provider, 20)
+
+	result, err := provider.Verify(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.True(t, result.Valid, result.Problems)
+	assert.Equal(t, uint64(20), result.Entries)
+	assert.Equal(t, uint64(20), result.Head.Sequence)
+	assert.Greater(t, result.Segments, 1)
+
+	// a new provider continues the chain
+	provider = newProvider(t, folder, map[string]string{"maxSegmentSize": "1000"})
+	appendTrails(t, provider, 2)
+	result = verify(t, folder, "", &ledger.Anchor{Sequence: 20, Hash: result.Head.Hash})
+	assert.True(t, result.Valid, result.Problems)
+	assert.Equal(t, uint64(22), result.Entries)
+}
+
+func TestFileLedgerDetectsEdits(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, nil), 3)
+
+	path := segmentPath(folder, 1)
+	data, _ := os.ReadFile(path)
+	assert.Nil(t, os.WriteFile(path, bytes.Replace(data, []byte("admin"), []byte("guest"), 1), 0600))
+	result := verify(t, folder, "", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"entry 1 in segment-000001.jsonl has been changed"}, result.Problems)
+}
+
+func TestFileLedgerDetectsRemovedEntries(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, nil), 3)
+
+	path := segmentPath(folder, 1)
+	data, _ := os.ReadFile(path)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	assert.Nil(t, os.WriteFile(path, append(append([]byte{}, lines[0]...), lines[2]...), 0600))
+	result := verify(t, folder, "", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"entry 3 follows entry 1 in segment-000001.jsonl"}, result.Problems)
+}
+
+func TestFileLedgerDetectsTruncation(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, nil), 3)
+	anchor := verify(t, folder, "", nil).Head
+
+	path := segmentPath(folder, 1)
+	data, _ := os.ReadFile(path)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	assert.Nil(t, os.WriteFile(path, bytes.Join(lines[:2], nil), 0600))
+	result := verify(t, folder, "", &anchor)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{
+		"the ledger doesn't have entry 3 of the anchor",
+		"the ledger ends at entry 2, but its head is entry 3",
+	}, result.Problems)
+
+	// entries are not appended after a truncation
+	provider := FileLedgerProvider{}
+	assert.NotNil(t, provider.InitWithMap(map[string]string{"folder": folder}))
+}
+
+func TestFileLedgerDetectsMissingSegments(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, map[string]string{"maxSegmentSize": "1"}), 3)
+
+	assert.Nil(t, os.Remove(segmentPath(folder, 2)))
+	result := verify(t, folder, "", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{
+		"segment 2 is missing",
+		"entry 3 follows entry 1 in segment-000003.jsonl",
+	}, result.Problems)
+}
+
+func TestFileLedgerWithKey(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, map[string]string{"key": "secret"}), 2)
+
+	assert.True(t, verify(t, folder, "secret", nil).Valid)
+	result := verify(t, folder, "", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, 2, len(result.Problems))
+}
+
+func TestFileLedgerRecoversIncompleteEntry(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, nil), 2)
+
+	file, _ := os.OpenFile(segmentPath(folder, 1), os.O_WRONLY|os.O_APPEND, 0600)
+	file.Write([]byte("{\"seq\":3,\"ti"))
+	file.Close()
+	assert.False(t, verify(t, folder, "", nil).Valid)
+
+	// the incomplete entry is kept in the partial file, and reported until it's removed
+	appendTrails(t, newProvider(t, folder, nil), 1)
+	result := verify(t, folder, "", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{
+		"segment-000001.jsonl had an incomplete entry at its end, which was moved to segment-000001.jsonl.partial",
+	}, result.Problems)
+	assert.Equal(t, uint64(3), result.Entries)
+	partial, err := os.ReadFile(segmentPath(folder, 1) + partialSuffix)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"seq\":3,\"ti\n", string(partial))
+
+	assert.Nil(t, os.Remove(segmentPath(folder, 1)+partialSuffix))
+	assert.True(t, verify(t, folder, "", nil).Valid)
+}
+
+func TestFileLedgerCompletesEntryWithoutNewline(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, map[string]string{"key": "secret"}), 2)
+
+	path := segmentPath(folder, 1)
+	data, _ := os.ReadFile(path)
+	assert.Nil(t, os.WriteFile(path, data[:len(data)-1], 0600))
+
+	appendTrails(t, newProvider(t, folder, map[string]string{"key": "secret"}), 1)
+	result := verify(t, folder, "secret", nil)
+	assert.True(t, result.Valid, result.Problems)
+	assert.Equal(t, uint64(3), result.Entries)
+	_, err := os.Stat(path + partialSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileLedgerDetectsUnsignedHead(t *testing.T) {
+	folder := t.TempDir()
+	appendTrails(t, newProvider(t, folder, map[string]string{"key": "secret"}), 3)
+
+	// a truncation that moves the head back can't sign it without the key
+	path := segmentPath(folder, 1)
+	data, _ := os.ReadFile(path)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	assert.Nil(t, os.WriteFile(path, bytes.Join(lines[:2], nil), 0600))
+	head, _ := readHead(folder)
+	head.Sequence = 2
+	head.Hash = verify(t, folder, "secret", nil).Head.Hash
+	assert.Nil(t, writeHead(folder, "", head.Anchor))
+
+	result := verify(t, folder, "secret", nil)
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"the head isn't signed with the key"}, result.Problems)
+
+	provider := FileLedgerProvider{}
+	assert.NotNil(t, provider.InitWithMap(map[string]string{"folder": folder, "key": "secret"}))
+}
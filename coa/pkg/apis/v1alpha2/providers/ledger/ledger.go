@@ -15,3 +15,26 @@ import (
 type ILedgerProvider interface {
 	Append(ctx context.Context, entries []v1alpha2.Trail) error
 }
+
+// IVerifiableLedgerProvider is implemented by ledger providers that can prove that their entries
+// haven't been changed, reordered or removed since they were appended.
+type IVerifiableLedgerProvider interface {
+	Verify(ctx context.Context, anchor *Anchor) (VerifyResult, error)
+}
+
+// Anchor is an entry that a ledger had at some point, such as the head an auditor wrote down
+// earlier. A ledger that no longer has the entry, or has a different one, has been truncated
+// or rewritten since.
+type Anchor struct {
+	Sequence uint64 `json:"seq"`
+	Hash     string `json:"hash"`
+}
+
+// VerifyResult is the outcome of a ledger verification. Head is the last entry of the ledger.
+type VerifyResult struct {
+	Valid    bool     `json:"valid"`
+	Entries  uint64   `json:"entries"`
+	Segments int      `json:"segments"`
+	Head     Anchor   `json:"head"`
+	Problems []string `json:"problems,omitempty"`
+}
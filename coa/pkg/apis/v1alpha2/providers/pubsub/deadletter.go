@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package pubsub
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	v1alpha2 "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+const (
+	// DefaultDeadLetterPolicyKey is the key of the policy that applies to topics without a policy of their own
+	DefaultDeadLetterPolicyKey = "*"
+	// DeadLetterTopicSuffix is appended to a topic to name its dead-letter topic, unless the policy names one
+	DeadLetterTopicSuffix = "-deadletter"
+)
+
+// DeadLetterPolicy bounds the delivery of the events of a topic. An event whose handler keeps failing
+// with a retriable error is retried after a backoff that doubles with every attempt, and is moved to
+// the dead-letter topic after MaxDeliveryAttempts deliveries. An event that can't be read at all is
+// moved there right away.
+type DeadLetterPolicy struct {
+	MaxDeliveryAttempts int    `json:"maxDeliveryAttempts,omitempty"`
+	BackoffSeconds      int    `json:"backoffSeconds,omitempty"`
+	MaxBackoffSeconds   int    `json:"maxBackoffSeconds,omitempty"`
+	DeadLetterTopic     string `json:"deadLetterTopic,omitempty"`
+}
+
+// Backoff returns how long to wait after the given failed attempt, starting at 1
+func (p DeadLetterPolicy) Backoff(attempt int) time.Duration {
+	if p.BackoffSeconds <= 0 || attempt <= 0 {
+		return 0
+	}
+	backoff := time.Duration(p.BackoffSeconds) * time.Second
+	max := time.Duration(p.MaxBackoffSeconds) * time.Second
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if max > 0 && backoff >= max {
+			break
+		}
+	}
+	if max > 0 && backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// Topic returns the dead-letter topic of the given topic
+func (p DeadLetterPolicy) Topic(topic string) string {
+	if p.DeadLetterTopic != "" {
+		return p.DeadLetterTopic
+	}
+	return topic + DeadLetterTopicSuffix
+}
+
+// FindDeadLetterPolicy returns the policy of the topic, or the default policy if the topic doesn't have one
+func FindDeadLetterPolicy(policies map[string]DeadLetterPolicy, topic string) (DeadLetterPolicy, bool) {
+	if policy, ok := policies[topic]; ok {
+		return policy, true
+	}
+	policy, ok := policies[DefaultDeadLetterPolicyKey]
+	return policy, ok
+}
+
+// DeadLetterPoliciesFromString reads the 'deadLetterPolicies' setting of a pub-sub provider, which is
+// a JSON object of policies by topic
+func DeadLetterPoliciesFromString(value string) (map[string]DeadLetterPolicy, error) {
+	if value == "" || value == "null" {
+		return nil, nil
+	}
+	var ret map[string]DeadLetterPolicy
+	if err := json.Unmarshal([]byte(value), &ret); err != nil {
+		return nil, v1alpha2.NewCOAError(err, "invalid value in the 'deadLetterPolicies' setting of pub-sub provider", v1alpha2.BadConfig)
+	}
+	for topic, policy := range ret {
+		if policy.MaxDeliveryAttempts < 0 || policy.BackoffSeconds < 0 || policy.MaxBackoffSeconds < 0 {
+			return nil, v1alpha2.NewCOAError(nil, fmt.Sprintf("negative value in the dead-letter policy of topic '%s'", topic), v1alpha2.BadConfig)
+		}
+	}
+	return ret, nil
+}
+
+// DeadLetter is an event that was moved to a dead-letter topic. Event is nil if the payload
+// couldn't be read as an event, in which case the raw payload is kept in Payload.
+type DeadLetter struct {
+	ID               string          `json:"id"`
+	Topic            string          `json:"topic"`
+	Group            string          `json:"group,omitempty"`
+	Event            *v1alpha2.Event `json:"event,omitempty"`
+	Payload          string          `json:"payload,omitempty"`
+	Attempts         int             `json:"attempts"`
+	Error            string          `json:"error,omitempty"`
+	DeadLetteredTime time.Time       `json:"deadLetteredTime"`
+}
+
+// IDeadLetterProvider is implemented by pub-sub providers that keep dead-lettered events. Topics are the
+// original topics of the events, not the dead-letter topics.
+type IDeadLetterProvider interface {
+	ListDeadLetters(topic string) ([]DeadLetter, error)
+	GetDeadLetter(topic string, id string) (DeadLetter, error)
+	// ReplayDeadLetter publishes the event to its topic again and removes it from the dead-letter topic
+	ReplayDeadLetter(topic string, id string) error
+	DeleteDeadLetter(topic string, id string) error
+	// PurgeDeadLetters removes all dead-lettered events of the topic and returns how many were removed
+	PurgeDeadLetters(topic string) (int, error)
+}
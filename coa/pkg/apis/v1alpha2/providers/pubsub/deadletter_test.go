@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	policy := DeadLetterPolicy{BackoffSeconds: 1, MaxBackoffSeconds: 5}
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, 2*time.Second, policy.Backoff(2))
+	assert.Equal(t, 4*time.Second, policy.Backoff(3))
+	assert.Equal(t, 5*time.Second, policy.Backoff(4))
+	assert.Equal(t, 5*time.Second, policy.Backoff(100))
+	assert.Equal(t, time.Duration(0), DeadLetterPolicy{}.Backoff(3))
+}
+
+func TestFindDeadLetterPolicy(t *testing.T) {
+	policies := map[string]DeadLetterPolicy{
+		"job":                      {MaxDeliveryAttempts: 3, DeadLetterTopic: "dead-jobs"},
+		DefaultDeadLetterPolicyKey: {MaxDeliveryAttempts: 5},
+	}
+	policy, ok := FindDeadLetterPolicy(policies, "job")
+	assert.True(t, ok)
+	assert.Equal(t, "dead-jobs", policy.Topic("job"))
+	policy, ok = FindDeadLetterPolicy(policies, "trail")
+	assert.True(t, ok)
+	assert.Equal(t, 5, policy.MaxDeliveryAttempts)
+	assert.Equal(t, "trail-deadletter", policy.Topic("trail"))
+	_, ok = FindDeadLetterPolicy(nil, "trail")
+	assert.False(t, ok)
+}
@@ -9,14 +9,18 @@ package memory
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
 )
 
 var log = logger.NewLogger("coa.runtime")
@@ -30,12 +34,20 @@ type InMemoryPubSubProvider struct {
 	Config      InMemoryPubSubConfig               `json:"config"`
 	Subscribers map[string][]v1alpha2.EventHandler `json:"subscribers"`
 	Context     *contexts.ManagerContext
+	// dead-lettered events by dead-letter topic
+	deadLetters    map[string][]pubsub.DeadLetter
+	deadLetterLock sync.Mutex
+	// observers by topic and by an id that lets Observe remove them
+	observers      map[string]map[int]func(v1alpha2.Event)
+	nextObserverID int
+	observerLock   sync.Mutex
 }
 
 type InMemoryPubSubConfig struct {
-	Name                      string `json:"name"`
-	SubscriberRetryCount      int    `json:"subscriberRetryCount"`
-	SubscriberRetryWaitSecond int    `json:"subscriberRetryWaitSecond"`
+	Name                      string                             `json:"name"`
+	SubscriberRetryCount      int                                `json:"subscriberRetryCount"`
+	SubscriberRetryWaitSecond int                                `json:"subscriberRetryWaitSecond"`
+	DeadLetterPolicies        map[string]pubsub.DeadLetterPolicy `json:"deadLetterPolicies,omitempty"`
 }
 
 func InMemoryPubSubConfigFromMap(properties map[string]string) (InMemoryPubSubConfig, error) {
@@ -71,6 +83,13 @@ func InMemoryPubSubConfigFromMap(properties map[string]string) (InMemoryPubSubCo
 	if ret.SubscriberRetryWaitSecond == 0 {
 		ret.SubscriberRetryWaitSecond = DefaultRetryWaitSecond
 	}
+	if v, ok := properties["deadLetterPolicies"]; ok {
+		policies, err := pubsub.DeadLetterPoliciesFromString(v)
+		if err != nil {
+			return ret, err
+		}
+		ret.DeadLetterPolicies = policies
+	}
 	return ret, nil
 }
 
@@ -99,27 +118,159 @@ func (i *InMemoryPubSubProvider) Init(config providers.IProviderConfig) error {
 	}
 	i.Config = vConfig
 	i.Subscribers = make(map[string][]v1alpha2.EventHandler)
+	i.deadLetters = make(map[string][]pubsub.DeadLetter)
+	i.observers = make(map[string]map[int]func(v1alpha2.Event))
 	return nil
 }
 func (i *InMemoryPubSubProvider) Publish(topic string, event v1alpha2.Event) error {
 	arr, ok := i.Subscribers[topic]
 	if ok && arr != nil {
 		for _, s := range arr {
-			go func(handler v1alpha2.EventHandler, topic string, event v1alpha2.Event) {
-				shouldRetry := true
-				count := 0
-				for shouldRetry && count <= i.Config.SubscriberRetryCount {
-					shouldRetry = v1alpha2.EventShouldRetryWrapper(handler, topic, event)
-					if shouldRetry {
-						count++
-						time.Sleep(time.Duration(i.Config.SubscriberRetryWaitSecond) * time.Second)
-					}
-				}
-			}(s, topic, event)
+			go i.deliver(s, topic, event)
 		}
 	}
+	i.observerLock.Lock()
+	for _, observer := range i.observers[topic] {
+		observer(event)
+	}
+	i.observerLock.Unlock()
+	return nil
+}
+
+func (i *InMemoryPubSubProvider) Observe(ctx context.Context, topic string, observer func(v1alpha2.Event)) error {
+	i.observerLock.Lock()
+	defer i.observerLock.Unlock()
+	if _, ok := i.observers[topic]; !ok {
+		i.observers[topic] = make(map[int]func(v1alpha2.Event))
+	}
+	id := i.nextObserverID
+	i.nextObserverID++
+	i.observers[topic][id] = observer
+	go func() {
+		<-ctx.Done()
+		i.observerLock.Lock()
+		defer i.observerLock.Unlock()
+		delete(i.observers[topic], id)
+		if len(i.observers[topic]) == 0 {
+			delete(i.observers, topic)
+		}
+	}()
 	return nil
 }
+
+// deliver retries the handler while it fails with a retriable error. Without a dead-letter policy,
+// the event is dropped after SubscriberRetryCount retries.
+func (i *InMemoryPubSubProvider) deliver(handler v1alpha2.EventHandler, topic string, event v1alpha2.Event) {
+	policy, hasPolicy := pubsub.FindDeadLetterPolicy(i.Config.DeadLetterPolicies, topic)
+	maxAttempts := i.Config.SubscriberRetryCount + 1
+	if hasPolicy && policy.MaxDeliveryAttempts > 0 {
+		maxAttempts = policy.MaxDeliveryAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		err := handler.Handler(topic, event)
+		if err == nil || !v1alpha2.IsRetriableErr(err) {
+			return
+		}
+		if attempt >= maxAttempts {
+			if hasPolicy {
+				i.deadLetter(policy.Topic(topic), pubsub.DeadLetter{
+					Topic:    topic,
+					Group:    handler.Group,
+					Event:    &event,
+					Attempts: attempt,
+					Error:    err.Error(),
+				})
+			} else {
+				log.Errorf("  P (Memory PubSub): dropping event of topic %s after %d attempts: %s", topic, attempt, err.Error())
+			}
+			return
+		}
+		if hasPolicy {
+			time.Sleep(policy.Backoff(attempt))
+		} else {
+			time.Sleep(time.Duration(i.Config.SubscriberRetryWaitSecond) * time.Second)
+		}
+	}
+}
+
+func (i *InMemoryPubSubProvider) deadLetter(deadLetterTopic string, deadLetter pubsub.DeadLetter) {
+	i.deadLetterLock.Lock()
+	defer i.deadLetterLock.Unlock()
+	deadLetter.ID = uuid.New().String()
+	deadLetter.DeadLetteredTime = time.Now().UTC()
+	i.deadLetters[deadLetterTopic] = append(i.deadLetters[deadLetterTopic], deadLetter)
+	log.Errorf("  P (Memory PubSub): event of topic %s is moved to dead-letter topic %s as %s after %d attempts: %s", deadLetter.Topic, deadLetterTopic, deadLetter.ID, deadLetter.Attempts, deadLetter.Error)
+}
+
+func (i *InMemoryPubSubProvider) deadLetterTopic(topic string) string {
+	policy, _ := pubsub.FindDeadLetterPolicy(i.Config.DeadLetterPolicies, topic)
+	return policy.Topic(topic)
+}
+
+func (i *InMemoryPubSubProvider) ListDeadLetters(topic string) ([]pubsub.DeadLetter, error) {
+	i.deadLetterLock.Lock()
+	defer i.deadLetterLock.Unlock()
+	ret := make([]pubsub.DeadLetter, 0)
+	for _, deadLetter := range i.deadLetters[i.deadLetterTopic(topic)] {
+		if deadLetter.Topic == topic {
+			ret = append(ret, deadLetter)
+		}
+	}
+	return ret, nil
+}
+
+func (i *InMemoryPubSubProvider) GetDeadLetter(topic string, id string) (pubsub.DeadLetter, error) {
+	i.deadLetterLock.Lock()
+	defer i.deadLetterLock.Unlock()
+	for _, deadLetter := range i.deadLetters[i.deadLetterTopic(topic)] {
+		if deadLetter.Topic == topic && deadLetter.ID == id {
+			return deadLetter, nil
+		}
+	}
+	return pubsub.DeadLetter{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("dead letter '%s' of topic '%s' is not found", id, topic), v1alpha2.NotFound)
+}
+
+func (i *InMemoryPubSubProvider) ReplayDeadLetter(topic string, id string) error {
+	deadLetter, err := i.GetDeadLetter(topic, id)
+	if err != nil {
+		return err
+	}
+	if deadLetter.Event == nil {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("dead letter '%s' of topic '%s' isn't a valid event and can't be replayed", id, topic), v1alpha2.BadRequest)
+	}
+	if err = i.DeleteDeadLetter(topic, id); err != nil {
+		return err
+	}
+	return i.Publish(topic, *deadLetter.Event)
+}
+
+func (i *InMemoryPubSubProvider) DeleteDeadLetter(topic string, id string) error {
+	i.deadLetterLock.Lock()
+	defer i.deadLetterLock.Unlock()
+	deadLetterTopic := i.deadLetterTopic(topic)
+	for index, deadLetter := range i.deadLetters[deadLetterTopic] {
+		if deadLetter.Topic == topic && deadLetter.ID == id {
+			i.deadLetters[deadLetterTopic] = append(i.deadLetters[deadLetterTopic][:index], i.deadLetters[deadLetterTopic][index+1:]...)
+			return nil
+		}
+	}
+	return v1alpha2.NewCOAError(nil, fmt.Sprintf("dead letter '%s' of topic '%s' is not found", id, topic), v1alpha2.NotFound)
+}
+
+func (i *InMemoryPubSubProvider) PurgeDeadLetters(topic string) (int, error) {
+	i.deadLetterLock.Lock()
+	defer i.deadLetterLock.Unlock()
+	deadLetterTopic := i.deadLetterTopic(topic)
+	kept := make([]pubsub.DeadLetter, 0)
+	for _, deadLetter := range i.deadLetters[deadLetterTopic] {
+		if deadLetter.Topic != topic {
+			kept = append(kept, deadLetter)
+		}
+	}
+	count := len(i.deadLetters[deadLetterTopic]) - len(kept)
+	i.deadLetters[deadLetterTopic] = kept
+	return count, nil
+}
 func (i *InMemoryPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHandler) error {
 	arr, ok := i.Subscribers[topic]
 	if !ok || arr == nil {
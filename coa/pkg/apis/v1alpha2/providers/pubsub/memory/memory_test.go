@@ -7,10 +7,12 @@
 package memory
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -223,3 +225,88 @@ func TestMemoryPubsubProviderInternalError(t *testing.T) {
 	time.Sleep(2 * time.Second) // Wait to ensure no further calls are made
 	assert.Equal(t, 5, count)
 }
+
+func TestMemoryPubsubProviderDeadLetter(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	provider.Init(InMemoryPubSubConfig{
+		Name: "test",
+		DeadLetterPolicies: map[string]pubsub.DeadLetterPolicy{
+			pubsub.DefaultDeadLetterPolicyKey: {MaxDeliveryAttempts: 3},
+		},
+	})
+
+	ch := make(chan struct{}, 10)
+	count := 0
+	err := provider.Subscribe("test", v1alpha2.EventHandler{
+		Handler: func(topic string, event v1alpha2.Event) error {
+			count += 1
+			ch <- struct{}{}
+			if count > 3 {
+				return nil
+			}
+			return v1alpha2.NewCOAError(nil, "insert internal error", v1alpha2.InternalError)
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Publish("test", v1alpha2.Event{
+		Body: "test",
+	})
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Function was not called within the timeout period")
+		}
+	}
+
+	var deadLetters []pubsub.DeadLetter
+	assert.Eventually(t, func() bool {
+		deadLetters, _ = provider.ListDeadLetters("test")
+		return len(deadLetters) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 3, deadLetters[0].Attempts)
+	assert.Equal(t, "test", deadLetters[0].Event.Body)
+
+	err = provider.ReplayDeadLetter("test", deadLetters[0].ID)
+	assert.Nil(t, err)
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Replayed event was not delivered within the timeout period")
+	}
+	deadLetters, err = provider.ListDeadLetters("test")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deadLetters))
+	err = provider.DeleteDeadLetter("test", "missing")
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+}
+
+func TestMemoryPubsubProviderObserve(t *testing.T) {
+	provider := InMemoryPubSubProvider{}
+	err := provider.Init(InMemoryPubSubConfig{Name: "test"})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	observed := make([]v1alpha2.Event, 0)
+	err = provider.Observe(ctx, "test", func(event v1alpha2.Event) {
+		observed = append(observed, event)
+	})
+	assert.Nil(t, err)
+
+	err = provider.Publish("test", v1alpha2.Event{Body: "first"})
+	assert.Nil(t, err)
+	err = provider.Publish("other", v1alpha2.Event{Body: "other"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(observed))
+	assert.Equal(t, "first", observed[0].Body)
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		provider.observerLock.Lock()
+		defer provider.observerLock.Unlock()
+		return len(provider.observers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	err = provider.Publish("test", v1alpha2.Event{Body: "second"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(observed))
+}
@@ -19,3 +19,10 @@ type IPubSubProvider interface {
 	Subscribe(topic string, handler v1alpha2.EventHandler) error
 	Cancel() context.CancelFunc
 }
+
+// IObservablePubSubProvider is implemented by pub-sub providers that can show the events published to a
+// topic to a caller without taking part in their delivery, so subscribers still get every event. The
+// observer is called for every event published after Observe returns until ctx is done. It must not block.
+type IObservablePubSubProvider interface {
+	Observe(ctx context.Context, topic string, observer func(v1alpha2.Event)) error
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/host"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
 	"github.com/redis/go-redis/v9"
@@ -55,6 +56,9 @@ type RedisPubSubProviderConfig struct {
 	RequiresTLS     bool   `json:"requiresTLS,omitempty"`
 	NumberOfWorkers int    `json:"numberOfWorkers,omitempty"`
 	ConsumerID      string `json:"consumerID"`
+	// DeadLetterPolicies bound the deliveries of the events of each topic. Without a policy, a message
+	// whose handler keeps failing is claimed again until it expires.
+	DeadLetterPolicies map[string]pubsub.DeadLetterPolicy `json:"deadLetterPolicies,omitempty"`
 }
 
 const (
@@ -64,10 +68,17 @@ const (
 	ClaimMessageInterval = 10 * time.Second
 	// ClaimPendingMessageIdleTime
 	ClaimMessageIdleTime = 30 * time.Second
+	// ObserveBlockTime is how long an observer waits for new messages before it checks whether it's done
+	ObserveBlockTime = 1 * time.Second
 
 	DefaultNumberOfWorkers = 20
 
 	MessageExpireDuration = 30 * time.Minute
+
+	// deliveriesKeyPrefix names the hash that counts the failed deliveries of the pending messages of
+	// a topic and group, and records when a message may be delivered again
+	deliveriesKeyPrefix = "symphony-deliveries"
+	nextAttemptSuffix   = ":next"
 )
 
 func RedisPubSubProviderConfigFromMap(properties map[string]string) (RedisPubSubProviderConfig, error) {
@@ -111,6 +122,13 @@ func RedisPubSubProviderConfigFromMap(properties map[string]string) (RedisPubSub
 		ret.ConsumerID = ""
 	}
 	ret.ConsumerID = ret.ConsumerID + generateConsumerIDSuffix()
+	if v, ok := properties["deadLetterPolicies"]; ok {
+		policies, err := pubsub.DeadLetterPoliciesFromString(v)
+		if err != nil {
+			return ret, err
+		}
+		ret.DeadLetterPolicies = policies
+	}
 
 	if ret.NumberOfWorkers <= 0 {
 		ret.NumberOfWorkers = DefaultNumberOfWorkers
@@ -211,6 +229,43 @@ func (i *RedisPubSubProvider) Subscribe(topic string, handler v1alpha2.EventHand
 	return nil
 }
 
+// Observe reads the stream of the topic without a consumer group, so it doesn't claim or acknowledge
+// messages. Messages that are handled and deleted before they're read aren't observed.
+func (i *RedisPubSubProvider) Observe(ctx context.Context, topic string, observer func(v1alpha2.Event)) error {
+	go func() {
+		lastID := "$"
+		for {
+			if ctx.Err() != nil || i.Ctx.Err() != nil {
+				return
+			}
+			streams, err := i.Client.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{topic, lastID},
+				Block:   ObserveBlockTime,
+			}).Result()
+			if err != nil && errors.Is(err, redis.Nil) {
+				continue
+			} else if err != nil {
+				if ctx.Err() == nil {
+					mLog.ErrorfCtx(ctx, "  P (Redis PubSub) : failed to observe topic %s: %v", topic, err)
+					time.Sleep(ClaimMessageInterval)
+				}
+				continue
+			}
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					lastID = msg.ID
+					var evt v1alpha2.Event
+					if err := json.Unmarshal([]byte(utils.FormatAsString(msg.Values["data"])), &evt); err != nil {
+						continue
+					}
+					observer(evt)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 func (i *RedisPubSubProvider) pollNewMessagesLoop(topic string, handler v1alpha2.EventHandler) {
 	for {
 		// DO NOT REMOVE THIS COMMENT
@@ -295,6 +350,11 @@ func (i *RedisPubSubProvider) ClaimMessageLoop(topic string, handler v1alpha2.Ev
 			i.AcknowledgeAndDeleteMessage(i.Ctx, topic, handler.Group, pendingResult[0].ID)
 			continue
 		}
+		if i.IsBackingOff(topic, handler.Group, pendingResult[0].ID) {
+			// skip the message until its backoff has passed
+			startMessageId = "(" + pendingResult[0].ID
+			continue
+		}
 		if claimWorker := i.WaitForIdleWorkers(pendingResult[0].ID, time.Second); !claimWorker {
 			mLog.InfofCtx(i.Ctx, "  P (Redis PubSub) : unable to claim idle workers in %s for topic %s, group %s, message %s", time.Second, topic, handler.Group, pendingResult[0].ID)
 			time.Sleep(ClaimMessageInterval)
@@ -327,18 +387,186 @@ func (i *RedisPubSubProvider) processMessage(topic string, handler v1alpha2.Even
 	var evt v1alpha2.Event
 	err := json.Unmarshal([]byte(utils.FormatAsString(data)), &evt)
 	if err != nil {
+		// a message that can't be read will never be handled, so it isn't retried
 		mLog.ErrorfCtx(i.Ctx, "  P (Redis PubSub) : failed to unmarshal event for message %s and topic %s, group %s: %v", msg.ID, topic, handler.Group, err.Error())
+		if policy, ok := pubsub.FindDeadLetterPolicy(i.Config.DeadLetterPolicies, topic); ok {
+			i.DeadLetterMessage(i.Ctx, policy, pubsub.DeadLetter{
+				Topic:    topic,
+				Group:    handler.Group,
+				Payload:  utils.FormatAsString(data),
+				Attempts: 1,
+				Error:    err.Error(),
+			}, msg.ID)
+		} else {
+			i.AcknowledgeAndDeleteMessage(i.Ctx, topic, handler.Group, msg.ID)
+		}
 		return v1alpha2.NewCOAError(err, "failed to unmarshal event", v1alpha2.InternalError)
 	}
-	shouldRetry := v1alpha2.EventShouldRetryWrapper(handler, topic, evt)
-	if shouldRetry {
+	err = handler.Handler(topic, evt)
+	if err != nil && v1alpha2.IsRetriableErr(err) {
 		mLog.ErrorfCtx(evt.Context, "  P (Redis PubSub) : processing failed with retriable error for message %s for topic %s, group %s", msg.ID, topic, handler.Group)
+		i.RecordFailedDelivery(evt, topic, handler.Group, msg.ID, err)
 		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to handle message %s", msg.ID), v1alpha2.InternalError)
 	}
 	i.AcknowledgeAndDeleteMessage(evt.Context, topic, handler.Group, msg.ID)
+	i.clearDeliveries(topic, handler.Group, msg.ID)
 	return nil
 }
 
+// RecordFailedDelivery counts a failed delivery of a message. The message is dead-lettered once the
+// policy of the topic allows no more attempts, or else it's held back for the backoff of the policy.
+func (i *RedisPubSubProvider) RecordFailedDelivery(evt v1alpha2.Event, topic string, group string, msgID string, cause error) {
+	policy, ok := pubsub.FindDeadLetterPolicy(i.Config.DeadLetterPolicies, topic)
+	if !ok {
+		return
+	}
+	key := deliveriesKey(topic, group)
+	attempts, err := i.Client.HIncrBy(i.Ctx, key, msgID, 1).Result()
+	if err != nil {
+		mLog.ErrorfCtx(evt.Context, "  P (Redis PubSub) : failed to count deliveries of message %s for topic %s, group %s: %v", msgID, topic, group, err)
+		return
+	}
+	if policy.MaxDeliveryAttempts > 0 && int(attempts) >= policy.MaxDeliveryAttempts {
+		i.DeadLetterMessage(evt.Context, policy, pubsub.DeadLetter{
+			Topic:    topic,
+			Group:    group,
+			Event:    &evt,
+			Attempts: int(attempts),
+			Error:    cause.Error(),
+		}, msgID)
+		return
+	}
+	if backoff := policy.Backoff(int(attempts)); backoff > 0 {
+		next := time.Now().Add(backoff).UnixMilli()
+		if err := i.Client.HSet(i.Ctx, key, msgID+nextAttemptSuffix, next).Err(); err != nil {
+			mLog.ErrorfCtx(evt.Context, "  P (Redis PubSub) : failed to record backoff of message %s for topic %s, group %s: %v", msgID, topic, group, err)
+		}
+	}
+}
+
+// IsBackingOff tells whether a pending message must not be delivered again yet
+func (i *RedisPubSubProvider) IsBackingOff(topic string, group string, msgID string) bool {
+	next, err := i.Client.HGet(i.Ctx, deliveriesKey(topic, group), msgID+nextAttemptSuffix).Int64()
+	if err != nil {
+		return false
+	}
+	return time.Now().UnixMilli() < next
+}
+
+// DeadLetterMessage moves a message to the dead-letter topic of its policy
+func (i *RedisPubSubProvider) DeadLetterMessage(ctx context.Context, policy pubsub.DeadLetterPolicy, deadLetter pubsub.DeadLetter, msgID string) {
+	deadLetter.DeadLetteredTime = time.Now().UTC()
+	data, _ := json.Marshal(deadLetter)
+	deadLetterTopic := policy.Topic(deadLetter.Topic)
+	id, err := i.Client.XAdd(i.Ctx, &redis.XAddArgs{
+		Stream: deadLetterTopic,
+		Values: map[string]interface{}{"data": string(data)},
+	}).Result()
+	if err != nil {
+		// leave the message pending so that it's dead-lettered on its next failure
+		mLog.ErrorfCtx(ctx, "  P (Redis PubSub) : failed to dead-letter message %s for topic %s, group %s: %v", msgID, deadLetter.Topic, deadLetter.Group, err)
+		return
+	}
+	mLog.ErrorfCtx(ctx, "  P (Redis PubSub) : message %s for topic %s, group %s is moved to dead-letter topic %s as %s after %d attempts: %s", msgID, deadLetter.Topic, deadLetter.Group, deadLetterTopic, id, deadLetter.Attempts, deadLetter.Error)
+	i.AcknowledgeAndDeleteMessage(ctx, deadLetter.Topic, deadLetter.Group, msgID)
+	i.clearDeliveries(deadLetter.Topic, deadLetter.Group, msgID)
+}
+
+func (i *RedisPubSubProvider) clearDeliveries(topic string, group string, msgID string) {
+	if len(i.Config.DeadLetterPolicies) == 0 {
+		return
+	}
+	i.Client.HDel(i.Ctx, deliveriesKey(topic, group), msgID, msgID+nextAttemptSuffix)
+}
+
+func deliveriesKey(topic string, group string) string {
+	return fmt.Sprintf("%s*%s*%s", deliveriesKeyPrefix, topic, group)
+}
+
+func (i *RedisPubSubProvider) deadLetterTopic(topic string) string {
+	policy, _ := pubsub.FindDeadLetterPolicy(i.Config.DeadLetterPolicies, topic)
+	return policy.Topic(topic)
+}
+
+func (i *RedisPubSubProvider) readDeadLetters(topic string, start string, end string) ([]pubsub.DeadLetter, error) {
+	messages, err := i.Client.XRange(i.Ctx, i.deadLetterTopic(topic), start, end).Result()
+	if err != nil {
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to read dead letters of topic '%s'", topic), v1alpha2.InternalError)
+	}
+	ret := make([]pubsub.DeadLetter, 0, len(messages))
+	for _, message := range messages {
+		var deadLetter pubsub.DeadLetter
+		if err := json.Unmarshal([]byte(utils.FormatAsString(message.Values["data"])), &deadLetter); err != nil {
+			mLog.Errorf("  P (Redis PubSub) : failed to read dead letter %s of topic %s: %v", message.ID, topic, err)
+			continue
+		}
+		if deadLetter.Topic != topic {
+			continue
+		}
+		deadLetter.ID = message.ID
+		ret = append(ret, deadLetter)
+	}
+	return ret, nil
+}
+
+func (i *RedisPubSubProvider) ListDeadLetters(topic string) ([]pubsub.DeadLetter, error) {
+	return i.readDeadLetters(topic, "-", "+")
+}
+
+func (i *RedisPubSubProvider) GetDeadLetter(topic string, id string) (pubsub.DeadLetter, error) {
+	if _, err := redisIDToTime(id); err != nil {
+		return pubsub.DeadLetter{}, v1alpha2.NewCOAError(err, fmt.Sprintf("invalid dead letter id '%s'", id), v1alpha2.BadRequest)
+	}
+	deadLetters, err := i.readDeadLetters(topic, id, id)
+	if err != nil {
+		return pubsub.DeadLetter{}, err
+	}
+	if len(deadLetters) == 0 {
+		return pubsub.DeadLetter{}, v1alpha2.NewCOAError(nil, fmt.Sprintf("dead letter '%s' of topic '%s' is not found", id, topic), v1alpha2.NotFound)
+	}
+	return deadLetters[0], nil
+}
+
+func (i *RedisPubSubProvider) ReplayDeadLetter(topic string, id string) error {
+	deadLetter, err := i.GetDeadLetter(topic, id)
+	if err != nil {
+		return err
+	}
+	if deadLetter.Event == nil {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("dead letter '%s' of topic '%s' isn't a valid event and can't be replayed", id, topic), v1alpha2.BadRequest)
+	}
+	if err = i.Publish(topic, *deadLetter.Event); err != nil {
+		return err
+	}
+	return i.DeleteDeadLetter(topic, id)
+}
+
+func (i *RedisPubSubProvider) DeleteDeadLetter(topic string, id string) error {
+	if _, err := i.GetDeadLetter(topic, id); err != nil {
+		return err
+	}
+	if err := i.Client.XDel(i.Ctx, i.deadLetterTopic(topic), id).Err(); err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to delete dead letter '%s' of topic '%s'", id, topic), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (i *RedisPubSubProvider) PurgeDeadLetters(topic string) (int, error) {
+	deadLetters, err := i.ListDeadLetters(topic)
+	if err != nil || len(deadLetters) == 0 {
+		return 0, err
+	}
+	ids := make([]string, len(deadLetters))
+	for index, deadLetter := range deadLetters {
+		ids[index] = deadLetter.ID
+	}
+	count, err := i.Client.XDel(i.Ctx, i.deadLetterTopic(topic), ids...).Result()
+	if err != nil {
+		return 0, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to purge dead letters of topic '%s'", topic), v1alpha2.InternalError)
+	}
+	return int(count), nil
+}
+
 func (i *RedisPubSubProvider) ClaimMessage(topic string, group string, minIdle time.Duration, msgID string) (*redis.XMessage, bool) {
 	claimResult, err := i.Client.XClaim(i.Ctx, &redis.XClaimArgs{
 		Stream:   topic,
@@ -15,8 +15,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/host"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/pubsub"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 )
@@ -417,3 +419,177 @@ func TestRedisStreamBasic(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(claimResult))
 }
+
+func initializeDeadLetterProvider(t *testing.T, policy pubsub.DeadLetterPolicy) *RedisPubSubProvider {
+	server := miniredis.RunT(t)
+	provider := &RedisPubSubProvider{}
+	err := provider.Init(RedisPubSubProviderConfig{
+		Name:               "redis",
+		Host:               server.Addr(),
+		NumberOfWorkers:    1,
+		DeadLetterPolicies: map[string]pubsub.DeadLetterPolicy{"job": policy},
+	})
+	assert.Nil(t, err)
+	t.Cleanup(provider.ContextCancel)
+	// subscribe without starting the polling loops, so that the test delivers the messages itself
+	err = provider.Client.XGroupCreateMkStream(provider.Ctx, "job", "group", "0").Err()
+	assert.Nil(t, err)
+	return provider
+}
+
+func readMessage(t *testing.T, provider *RedisPubSubProvider) *redis.XMessage {
+	streams, err := provider.Client.XReadGroup(provider.Ctx, &redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: provider.Config.ConsumerID,
+		Streams:  []string{"job", ">"},
+		Count:    1,
+	}).Result()
+	assert.Nil(t, err)
+	return &streams[0].Messages[0]
+}
+
+func TestDeadLetterAfterMaxDeliveryAttempts(t *testing.T) {
+	provider := initializeDeadLetterProvider(t, pubsub.DeadLetterPolicy{MaxDeliveryAttempts: 2})
+	count := 0
+	handler := v1alpha2.EventHandler{
+		Group: "group",
+		Handler: func(topic string, message v1alpha2.Event) error {
+			count++
+			return v1alpha2.NewCOAError(nil, "insert internal error", v1alpha2.InternalError)
+		},
+	}
+	err := provider.Publish("job", v1alpha2.Event{Body: "bad job"})
+	assert.Nil(t, err)
+	msg := readMessage(t, provider)
+
+	provider.IdleWorkers--
+	assert.NotNil(t, provider.processMessage("job", handler, msg))
+	deadLetters, err := provider.ListDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deadLetters))
+
+	provider.IdleWorkers--
+	assert.NotNil(t, provider.processMessage("job", handler, msg))
+	assert.Equal(t, 2, count)
+	deadLetters, err = provider.ListDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deadLetters))
+	assert.Equal(t, "job", deadLetters[0].Topic)
+	assert.Equal(t, "group", deadLetters[0].Group)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+	assert.Equal(t, "bad job", deadLetters[0].Event.Body)
+	assert.Contains(t, deadLetters[0].Error, "insert internal error")
+	pending, err := provider.Client.XPending(provider.Ctx, "job", "group").Result()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+	assert.Equal(t, int64(0), provider.Client.XLen(provider.Ctx, "job").Val())
+	assert.Equal(t, int64(1), provider.Client.XLen(provider.Ctx, "job"+pubsub.DeadLetterTopicSuffix).Val())
+
+	deadLetter, err := provider.GetDeadLetter("job", deadLetters[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, deadLetters[0].ID, deadLetter.ID)
+
+	err = provider.ReplayDeadLetter("job", deadLetter.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), provider.Client.XLen(provider.Ctx, "job").Val())
+	deadLetters, err = provider.ListDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deadLetters))
+	_, err = provider.GetDeadLetter("job", deadLetter.ID)
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+}
+
+func TestPoisonMessageIsDeadLetteredRightAway(t *testing.T) {
+	provider := initializeDeadLetterProvider(t, pubsub.DeadLetterPolicy{MaxDeliveryAttempts: 5})
+	called := false
+	handler := v1alpha2.EventHandler{
+		Group: "group",
+		Handler: func(topic string, message v1alpha2.Event) error {
+			called = true
+			return nil
+		},
+	}
+	err := provider.Client.XAdd(provider.Ctx, &redis.XAddArgs{
+		Stream: "job",
+		Values: map[string]interface{}{"data": "not an event"},
+	}).Err()
+	assert.Nil(t, err)
+
+	provider.IdleWorkers--
+	assert.NotNil(t, provider.processMessage("job", handler, readMessage(t, provider)))
+	assert.False(t, called)
+	deadLetters, err := provider.ListDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(deadLetters))
+	assert.Nil(t, deadLetters[0].Event)
+	assert.Equal(t, "not an event", deadLetters[0].Payload)
+
+	err = provider.ReplayDeadLetter("job", deadLetters[0].ID)
+	assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err))
+	purged, err := provider.PurgeDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, purged)
+	deadLetters, err = provider.ListDeadLetters("job")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deadLetters))
+}
+
+func TestFailedDeliveryBacksOff(t *testing.T) {
+	provider := initializeDeadLetterProvider(t, pubsub.DeadLetterPolicy{MaxDeliveryAttempts: 3, BackoffSeconds: 60})
+	handler := v1alpha2.EventHandler{
+		Group: "group",
+		Handler: func(topic string, message v1alpha2.Event) error {
+			return v1alpha2.NewCOAError(nil, "insert internal error", v1alpha2.InternalError)
+		},
+	}
+	provider.Publish("job", v1alpha2.Event{Body: "job"})
+	msg := readMessage(t, provider)
+	assert.False(t, provider.IsBackingOff("job", "group", msg.ID))
+
+	provider.IdleWorkers--
+	provider.processMessage("job", handler, msg)
+	assert.True(t, provider.IsBackingOff("job", "group", msg.ID))
+}
+
+func TestDeadLetterPoliciesFromMap(t *testing.T) {
+	config, err := RedisPubSubProviderConfigFromMap(map[string]string{
+		"host":               "localhost:6379",
+		"deadLetterPolicies": `{"job": {"maxDeliveryAttempts": 3, "deadLetterTopic": "dead-jobs"}}`,
+	})
+	assert.Nil(t, err)
+	policy, ok := pubsub.FindDeadLetterPolicy(config.DeadLetterPolicies, "job")
+	assert.True(t, ok)
+	assert.Equal(t, 3, policy.MaxDeliveryAttempts)
+	assert.Equal(t, "dead-jobs", policy.Topic("job"))
+
+	_, err = RedisPubSubProviderConfigFromMap(map[string]string{
+		"host":               "localhost:6379",
+		"deadLetterPolicies": `{"job": {"maxDeliveryAttempts": -1}}`,
+	})
+	assert.Equal(t, v1alpha2.BadConfig, v1alpha2.GetErrorState(err))
+}
+
+func TestObserveDoesNotConsumeMessages(t *testing.T) {
+	provider := initializeDeadLetterProvider(t, pubsub.DeadLetterPolicy{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observed := make(chan v1alpha2.Event, 1)
+	err := provider.Observe(ctx, "job", func(event v1alpha2.Event) {
+		observed <- event
+	})
+	assert.Nil(t, err)
+	// give the observer time to start reading from the end of the stream
+	time.Sleep(200 * time.Millisecond)
+
+	err = provider.Publish("job", v1alpha2.Event{Body: "observed"})
+	assert.Nil(t, err)
+	select {
+	case event := <-observed:
+		assert.Equal(t, "observed", event.Body)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "event was not observed")
+	}
+	// the message is still delivered to the consumer group
+	msg := readMessage(t, provider)
+	assert.NotNil(t, msg)
+}
@@ -12,3 +12,12 @@ type IQueueProvider interface {
 	Peek(queue string) (interface{}, error)
 	Size(queue string) int
 }
+
+// IAckQueueProvider is implemented by queue providers that can hand out an element without removing it.
+// The element is hidden for a visibility timeout and is removed when it's acknowledged with the returned
+// receipt. If it isn't acknowledged in time, it goes back to the head of the queue.
+type IAckQueueProvider interface {
+	IQueueProvider
+	DequeueWithAck(queue string) (interface{}, string, error)
+	Ack(queue string, receipt string) error
+}
@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package redisqueue
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+var rLog = logger.NewLogger("coa.runtime")
+
+const (
+	separator = "*"
+	// elements handed out by DequeueWithAck are kept in a sorted set of receipts by deadline
+	// and in a hash of receipts to elements until they're acknowledged
+	inflightSuffix = "-inflight"
+	messagesSuffix = "-messages"
+
+	defaultKeyPrefix         = "symphony-queue"
+	defaultVisibilityTimeout = 30 // seconds
+)
+
+// restoreScript moves the elements whose visibility timeout has passed back to the head of the queue,
+// keeping their order
+var restoreScript = redis.NewScript(`
+local ids = redis.call("ZRANGEBYSCORE", KEYS[2], "-inf", ARGV[1])
+for i = #ids, 1, -1 do
+	local message = redis.call("HGET", KEYS[3], ids[i])
+	if message then
+		redis.call("LPUSH", KEYS[1], message)
+	end
+	redis.call("HDEL", KEYS[3], ids[i])
+	redis.call("ZREM", KEYS[2], ids[i])
+end
+return #ids
+`)
+
+// reserveScript pops the head of the queue and hides it until the deadline
+var reserveScript = redis.NewScript(`
+local message = redis.call("LPOP", KEYS[1])
+if not message then
+	return false
+end
+local id = cjson.decode(message).id
+redis.call("ZADD", KEYS[2], ARGV[1], id)
+redis.call("HSET", KEYS[3], id, message)
+return message
+`)
+
+// ackScript removes an element that was handed out, unless it has been restored already
+var ackScript = redis.NewScript(`
+if redis.call("ZREM", KEYS[2], ARGV[1]) == 1 then
+	redis.call("HDEL", KEYS[3], ARGV[1])
+	return 1
+end
+return 0
+`)
+
+type queueMessage struct {
+	ID   string      `json:"id"`
+	Data interface{} `json:"data"`
+}
+
+type RedisQueueProviderConfig struct {
+	Name              string `json:"name"`
+	Host              string `json:"host"`
+	Password          string `json:"password,omitempty"`
+	RequiresTLS       bool   `json:"requiresTLS,omitempty"`
+	KeyPrefix         string `json:"keyPrefix,omitempty"`
+	VisibilityTimeout int    `json:"visibilityTimeout,omitempty"` // seconds
+}
+
+func RedisQueueProviderConfigFromMap(properties map[string]string) (RedisQueueProviderConfig, error) {
+	ret := RedisQueueProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["host"]; ok {
+		ret.Host = utils.ParseProperty(v)
+	} else {
+		return ret, v1alpha2.NewCOAError(nil, "Redis queue provider host name is not set", v1alpha2.BadConfig)
+	}
+	if v, ok := properties["password"]; ok {
+		ret.Password = utils.ParseProperty(v)
+	}
+	if v, ok := properties["requiresTLS"]; ok && v != "" {
+		bVal, err := strconv.ParseBool(utils.ParseProperty(v))
+		if err != nil {
+			return ret, v1alpha2.NewCOAError(err, "invalid bool value in the 'requiresTLS' setting of Redis queue provider", v1alpha2.BadConfig)
+		}
+		ret.RequiresTLS = bVal
+	}
+	if v, ok := properties["keyPrefix"]; ok {
+		ret.KeyPrefix = utils.ParseProperty(v)
+	}
+	if v, ok := properties["visibilityTimeout"]; ok && v != "" {
+		iVal, err := strconv.Atoi(utils.ParseProperty(v))
+		if err != nil || iVal < 0 {
+			return ret, v1alpha2.NewCOAError(err, "invalid int value in the 'visibilityTimeout' setting of Redis queue provider", v1alpha2.BadConfig)
+		}
+		ret.VisibilityTimeout = iVal
+	}
+	return ret, nil
+}
+
+func toRedisQueueProviderConfig(config providers.IProviderConfig) (RedisQueueProviderConfig, error) {
+	ret := RedisQueueProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+// RedisQueueProvider keeps FIFO queues in redis lists so that they're shared by all processes using
+// the same redis server. Elements are stored as JSON, so they come back as generic JSON values.
+type RedisQueueProvider struct {
+	Config  RedisQueueProviderConfig
+	Context *contexts.ManagerContext
+	Client  *redis.Client
+	Ctx     context.Context
+	Cancel  context.CancelFunc
+	now     func() time.Time
+}
+
+func (r *RedisQueueProvider) ID() string {
+	return r.Config.Name
+}
+
+func (r *RedisQueueProvider) SetContext(ctx *contexts.ManagerContext) {
+	r.Context = ctx
+}
+
+func (r *RedisQueueProvider) InitWithMap(properties map[string]string) error {
+	config, err := RedisQueueProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return r.Init(config)
+}
+
+func (r *RedisQueueProvider) Init(config providers.IProviderConfig) error {
+	vConfig, err := toRedisQueueProviderConfig(config)
+	if err != nil {
+		rLog.Errorf("  P (Redis Queue): failed to parse provider config %+v", err)
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid redis queue provider config", v1alpha2.BadConfig)
+	}
+	if vConfig.Host == "" {
+		return v1alpha2.NewCOAError(nil, "Redis host is not supplied", v1alpha2.MissingConfig)
+	}
+	if vConfig.KeyPrefix == "" {
+		vConfig.KeyPrefix = defaultKeyPrefix
+	}
+	if vConfig.VisibilityTimeout <= 0 {
+		vConfig.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	r.Config = vConfig
+	r.Ctx, r.Cancel = context.WithCancel(context.Background())
+	options := &redis.Options{
+		Addr:            r.Config.Host,
+		Password:        r.Config.Password,
+		DB:              0,
+		MaxRetries:      3,
+		MaxRetryBackoff: time.Second * 2,
+	}
+	if r.Config.RequiresTLS {
+		options.TLSConfig = &tls.Config{
+			InsecureSkipVerify: !r.Config.RequiresTLS,
+		}
+	}
+	client := redis.NewClient(options)
+	if _, err := client.Ping(r.Ctx).Result(); err != nil {
+		rLog.Errorf("  P (Redis Queue): failed to connect to redis %+v", err)
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("redis queue: error connecting to redis at %s", r.Config.Host), v1alpha2.InternalError)
+	}
+	r.Client = client
+	if r.now == nil {
+		r.now = time.Now
+	}
+	return nil
+}
+
+func (r *RedisQueueProvider) Enqueue(queue string, element interface{}) error {
+	message, err := json.Marshal(queueMessage{
+		ID:   uuid.New().String(),
+		Data: element,
+	})
+	if err != nil {
+		return v1alpha2.NewCOAError(err, "queue element can't be serialized", v1alpha2.BadRequest)
+	}
+	return r.Client.RPush(r.Ctx, r.queueKey(queue), string(message)).Err()
+}
+
+func (r *RedisQueueProvider) Dequeue(queue string) (interface{}, error) {
+	if err := r.restore(queue); err != nil {
+		return nil, err
+	}
+	message, err := r.Client.LPop(r.Ctx, r.queueKey(queue)).Result()
+	if err == redis.Nil {
+		return nil, v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	ret, err := decodeMessage(message)
+	return ret.Data, err
+}
+
+func (r *RedisQueueProvider) Peek(queue string) (interface{}, error) {
+	if err := r.restore(queue); err != nil {
+		return nil, err
+	}
+	message, err := r.Client.LIndex(r.Ctx, r.queueKey(queue), 0).Result()
+	if err == redis.Nil {
+		return nil, v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+	} else if err != nil {
+		return nil, err
+	}
+	ret, err := decodeMessage(message)
+	return ret.Data, err
+}
+
+func (r *RedisQueueProvider) Size(queue string) int {
+	if err := r.restore(queue); err != nil {
+		rLog.Errorf("  P (Redis Queue): failed to restore expired elements of queue %s: %+v", queue, err)
+	}
+	size, err := r.Client.LLen(r.Ctx, r.queueKey(queue)).Result()
+	if err != nil {
+		rLog.Errorf("  P (Redis Queue): failed to get size of queue %s: %+v", queue, err)
+		return 0
+	}
+	return int(size)
+}
+
+// DequeueWithAck hands out the head of the queue for VisibilityTimeout seconds. The returned receipt
+// must be passed to Ack within that time, or the element is delivered again.
+func (r *RedisQueueProvider) DequeueWithAck(queue string) (interface{}, string, error) {
+	if err := r.restore(queue); err != nil {
+		return nil, "", err
+	}
+	deadline := r.now().Add(time.Duration(r.Config.VisibilityTimeout) * time.Second).UnixMilli()
+	message, err := reserveScript.Run(r.Ctx, r.Client, r.keys(queue), deadline).Text()
+	if err == redis.Nil {
+		return nil, "", v1alpha2.NewCOAError(nil, "queue is empty", v1alpha2.NotFound)
+	} else if err != nil {
+		return nil, "", err
+	}
+	ret, err := decodeMessage(message)
+	return ret.Data, ret.ID, err
+}
+
+func (r *RedisQueueProvider) Ack(queue string, receipt string) error {
+	acked, err := ackScript.Run(r.Ctx, r.Client, r.keys(queue), receipt).Int64()
+	if err != nil {
+		return err
+	}
+	if acked == 0 {
+		return v1alpha2.NewCOAError(nil, fmt.Sprintf("receipt '%s' is not found in queue '%s', its visibility timeout may have passed", receipt, queue), v1alpha2.NotFound)
+	}
+	return nil
+}
+
+func (r *RedisQueueProvider) restore(queue string) error {
+	restored, err := restoreScript.Run(r.Ctx, r.Client, r.keys(queue), r.now().UnixMilli()).Int64()
+	if err != nil {
+		return err
+	}
+	if restored > 0 {
+		rLog.Infof("  P (Redis Queue): %d unacknowledged elements are back in queue %s", restored, queue)
+	}
+	return nil
+}
+
+func (r *RedisQueueProvider) keys(queue string) []string {
+	return []string{
+		r.queueKey(queue),
+		fmt.Sprintf("%s%s%s%s", r.Config.KeyPrefix, inflightSuffix, separator, queue),
+		fmt.Sprintf("%s%s%s%s", r.Config.KeyPrefix, messagesSuffix, separator, queue),
+	}
+}
+
+func (r *RedisQueueProvider) queueKey(queue string) string {
+	return fmt.Sprintf("%s%s%s", r.Config.KeyPrefix, separator, queue)
+}
+
+func decodeMessage(message string) (queueMessage, error) {
+	var ret queueMessage
+	if err := json.Unmarshal([]byte(message), &ret); err != nil {
+		return ret, v1alpha2.NewCOAError(err, "queue element can't be deserialized", v1alpha2.InternalError)
+	}
+	return ret, nil
+}
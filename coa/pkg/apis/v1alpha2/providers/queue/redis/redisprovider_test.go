@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package redisqueue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	"github.com/stretchr/testify/assert"
+)
+
+func initializeProvider(t *testing.T, server *miniredis.Miniredis) *RedisQueueProvider {
+	provider := &RedisQueueProvider{}
+	err := provider.Init(RedisQueueProviderConfig{
+		Host:              server.Addr(),
+		VisibilityTimeout: 10,
+	})
+	assert.Nil(t, err)
+	t.Cleanup(provider.Cancel)
+	return provider
+}
+
+func TestInitWithMap(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := &RedisQueueProvider{}
+	err := provider.InitWithMap(map[string]string{
+		"name":              "queue",
+		"host":              server.Addr(),
+		"visibilityTimeout": "5",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "queue", provider.ID())
+	assert.Equal(t, 5, provider.Config.VisibilityTimeout)
+	provider.Cancel()
+
+	err = provider.InitWithMap(map[string]string{})
+	assert.NotNil(t, err)
+}
+
+func TestFIFO(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := initializeProvider(t, server)
+	// another replica sees the same queue
+	other := initializeProvider(t, server)
+
+	assert.Nil(t, provider.Enqueue("queue1", "a"))
+	assert.Nil(t, provider.Enqueue("queue1", "b"))
+	assert.Nil(t, other.Enqueue("queue1", map[string]interface{}{"id": "c"}))
+	assert.Equal(t, 3, other.Size("queue1"))
+
+	element, err := other.Peek("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	element, err = other.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	element, err = provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+	element, err = provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "c"}, element)
+
+	_, err = provider.Dequeue("queue1")
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+	_, err = provider.Peek("queue1")
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+	assert.Equal(t, 0, provider.Size("queue1"))
+}
+
+func TestDequeueWithAck(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := initializeProvider(t, server)
+
+	provider.Enqueue("queue1", "a")
+	provider.Enqueue("queue1", "b")
+	element, receipt, err := provider.DequeueWithAck("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	assert.Equal(t, 1, provider.Size("queue1"))
+
+	assert.Nil(t, provider.Ack("queue1", receipt))
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(provider.Ack("queue1", receipt)))
+	assert.Equal(t, 1, provider.Size("queue1"))
+}
+
+func TestUnackedElementIsDeliveredAgain(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := initializeProvider(t, server)
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	provider.Enqueue("queue1", "a")
+	provider.Enqueue("queue1", "b")
+	provider.Enqueue("queue1", "c")
+	_, receiptA, err := provider.DequeueWithAck("queue1")
+	assert.Nil(t, err)
+	now = now.Add(time.Second)
+	element, _, err := provider.DequeueWithAck("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+	assert.Equal(t, 1, provider.Size("queue1"))
+
+	// both visibility timeouts pass, and the elements come back in their original order
+	now = now.Add(11 * time.Second)
+	assert.Equal(t, 3, provider.Size("queue1"))
+	element, err = provider.Dequeue("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", element)
+	element, err = provider.Peek("queue1")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", element)
+	assert.NotNil(t, provider.Ack("queue1", receiptA))
+}
@@ -0,0 +1,456 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package embeddedstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability"
+	observ_utils "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/observability/utils"
+	providers "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/utils"
+	"github.com/eclipse-symphony/symphony/coa/pkg/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+var sLog = logger.NewLogger("coa.runtime")
+
+const (
+	defaultTimeout = "5s"
+	// objects without a resource type are kept in this bucket
+	defaultObjectType = "_"
+	keyPrefix         = "/"
+)
+
+// A database file can only be opened once per process, so provider instances
+// (including clones) configured with the same path share one handle.
+var (
+	dbLock sync.Mutex
+	dbs    = map[string]*bolt.DB{}
+)
+
+type EmbeddedStateProviderConfig struct {
+	Name string `json:"name"`
+	// Path of the database file. It's created if it doesn't exist.
+	Path string `json:"path"`
+	// Timeout is how long to wait for the file lock held by another process
+	Timeout string `json:"timeout,omitempty"`
+}
+
+func EmbeddedStateProviderConfigFromMap(properties map[string]string) (EmbeddedStateProviderConfig, error) {
+	ret := EmbeddedStateProviderConfig{}
+	if v, ok := properties["name"]; ok {
+		ret.Name = utils.ParseProperty(v)
+	}
+	if v, ok := properties["path"]; ok {
+		ret.Path = utils.ParseProperty(v)
+	}
+	if ret.Path == "" {
+		return ret, v1alpha2.NewCOAError(nil, "embedded state provider path is not set", v1alpha2.MissingConfig)
+	}
+	if v, ok := properties["timeout"]; ok {
+		ret.Timeout = utils.ParseProperty(v)
+	}
+	return ret, nil
+}
+
+// EmbeddedStateProvider keeps states in a local bbolt database file. Every write is
+// committed in its own transaction and synced to disk, so the file stays consistent
+// if the process crashes. Entries are stored as JSON in a bucket per namespace, with
+// a nested bucket per object type.
+type EmbeddedStateProvider struct {
+	Config  EmbeddedStateProviderConfig
+	Context *contexts.ManagerContext
+	DB      *bolt.DB
+}
+
+func (s *EmbeddedStateProvider) ID() string {
+	return s.Config.Name
+}
+
+func (s *EmbeddedStateProvider) SetContext(ctx *contexts.ManagerContext) {
+	s.Context = ctx
+}
+
+func (i *EmbeddedStateProvider) InitWithMap(properties map[string]string) error {
+	config, err := EmbeddedStateProviderConfigFromMap(properties)
+	if err != nil {
+		return err
+	}
+	return i.Init(config)
+}
+
+func (s *EmbeddedStateProvider) Init(config providers.IProviderConfig) error {
+	stateConfig, err := toEmbeddedStateProviderConfig(config)
+	if err != nil {
+		sLog.Errorf("  P (Embedded State): failed to parse provider config %+v", err)
+		return v1alpha2.NewCOAError(nil, "provided config is not a valid embedded state provider config", v1alpha2.BadConfig)
+	}
+	if stateConfig.Path == "" {
+		return v1alpha2.NewCOAError(nil, "embedded state provider path is not set", v1alpha2.MissingConfig)
+	}
+	if stateConfig.Timeout == "" {
+		stateConfig.Timeout = defaultTimeout
+	}
+	timeout, err := time.ParseDuration(stateConfig.Timeout)
+	if err != nil {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("invalid timeout '%s' in embedded state provider config", stateConfig.Timeout), v1alpha2.BadConfig)
+	}
+	s.Config = stateConfig
+	s.DB, err = openDB(s.Config.Path, timeout)
+	if err != nil {
+		sLog.Errorf("  P (Embedded State): failed to open database %s: %+v", s.Config.Path, err)
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to open embedded state database %s", s.Config.Path), v1alpha2.InternalError)
+	}
+	return nil
+}
+
+func (s *EmbeddedStateProvider) Upsert(ctx context.Context, entry states.UpsertRequest) (string, error) {
+	ctx, span := observability.StartSpan("Embedded State Provider", ctx, &map[string]string{
+		"method": "Upsert",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	namespace := getNamespace(entry.Metadata, "default")
+	sLog.DebugfCtx(ctx, "  P (Embedded State): upsert states %s in namespace %s", entry.Value.ID, namespace)
+
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		nBucket, err := tx.CreateBucketIfNotExists([]byte(namespace))
+		if err != nil {
+			return err
+		}
+		bucket, err := nBucket.CreateBucketIfNotExists([]byte(getObjectType(entry.Metadata)))
+		if err != nil {
+			return err
+		}
+
+		var existing *states.StateEntry
+		if data := bucket.Get(entryKey(entry.Value.ID)); data != nil {
+			existing = &states.StateEntry{}
+			if err := json.Unmarshal(data, existing); err != nil {
+				return v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' is not a valid state entry", entry.Value.ID), v1alpha2.InternalError)
+			}
+		}
+
+		if entry.ETag != nil {
+			// the entry is only written if its ETag is still the expected one, which is empty for
+			// entries that don't exist yet
+			current := ""
+			if existing != nil {
+				current = existing.ETag
+			}
+			if current != *entry.ETag {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", entry.Value.ID, *entry.ETag, current), v1alpha2.Conflict)
+			}
+		}
+
+		value := entry.Value
+		value.ETag = "1"
+		if existing != nil {
+			value.ETag = nextETag(existing.ETag)
+		}
+		if entry.Options.UpdateStatusOnly {
+			if existing == nil {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found in namespace %s", entry.Value.ID, namespace), v1alpha2.NotFound)
+			}
+			body, err := mergeStatus(existing.Body, entry.Value.Body)
+			if err != nil {
+				return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to update status of entry '%s'", entry.Value.ID), v1alpha2.InternalError)
+			}
+			value.Body = body
+		} else if existing != nil && entry.ETag == nil {
+			// If client does not provide an ETag, concurrency is not required and the entry is overwritten.
+			if entry.Value.ETag != "" && entry.Value.ETag != existing.ETag {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", entry.Value.ID, entry.Value.ETag, existing.ETag), v1alpha2.Conflict)
+			}
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("failed to marshal entry '%s'", entry.Value.ID), v1alpha2.SerializationError)
+		}
+		return bucket.Put(entryKey(entry.Value.ID), data)
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Embedded State): failed to upsert %s state: %+v", entry.Value.ID, err)
+		return "", err
+	}
+	return entry.Value.ID, nil
+}
+
+func (s *EmbeddedStateProvider) List(ctx context.Context, request states.ListRequest) ([]states.StateEntry, string, error) {
+	ctx, span := observability.StartSpan("Embedded State Provider", ctx, &map[string]string{
+		"method": "List",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	var items []states.ListItem
+	// If namespace is not specified, get entries for all namespaces
+	namespace := getNamespace(request.Metadata, "")
+	objectType := []byte(getObjectType(request.Metadata))
+	sLog.DebugfCtx(ctx, "  P (Embedded State): list states in namespace %s", namespace)
+
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(nKey []byte, nBucket *bolt.Bucket) error {
+			if namespace != "" && namespace != string(nKey) {
+				return nil
+			}
+			bucket := nBucket.Bucket(objectType)
+			if bucket == nil {
+				return nil
+			}
+			return bucket.ForEach(func(k, v []byte) error {
+				var entry states.StateEntry
+				if err := json.Unmarshal(v, &entry); err != nil {
+					return v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' is not a valid state entry", strings.TrimPrefix(string(k), keyPrefix)), v1alpha2.InternalError)
+				}
+				if request.FilterType != "" && request.FilterValue != "" {
+					match, err := states.MatchFilter(entry, request.FilterType, request.FilterValue)
+					if err != nil {
+						return err
+					} else if !match {
+						return nil
+					}
+				}
+				items = append(items, states.ListItem{Key: string(nKey) + "/" + entry.ID, Entry: entry})
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Embedded State): failed to list states: %+v", err)
+		return nil, "", err
+	}
+	var entities []states.StateEntry
+	var next string
+	entities, next, err = states.PageList(items, request)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Embedded State): failed to list states: %+v", err)
+		return nil, "", err
+	}
+	return entities, next, nil
+}
+
+func (s *EmbeddedStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
+	ctx, span := observability.StartSpan("Embedded State Provider", ctx, &map[string]string{
+		"method": "Delete",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	namespace := getNamespace(request.Metadata, "default")
+	sLog.DebugfCtx(ctx, "  P (Embedded State): delete state %s in namespace %s", request.ID, namespace)
+
+	err = s.DB.Update(func(tx *bolt.Tx) error {
+		bucket := getBucket(tx, namespace, request.Metadata)
+		if bucket == nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found in namespace %s", request.ID, namespace), v1alpha2.NotFound)
+		}
+		data := bucket.Get(entryKey(request.ID))
+		if data == nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found in namespace %s", request.ID, namespace), v1alpha2.NotFound)
+		}
+		if request.ETag != nil && *request.ETag != "" {
+			var existing states.StateEntry
+			if err := json.Unmarshal(data, &existing); err != nil {
+				return v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' is not a valid state entry", request.ID), v1alpha2.InternalError)
+			}
+			if *request.ETag != existing.ETag {
+				return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", request.ID, *request.ETag, existing.ETag), v1alpha2.Conflict)
+			}
+		}
+		return bucket.Delete(entryKey(request.ID))
+	})
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Embedded State): failed to delete %s: %+v", request.ID, err)
+	}
+	return err
+}
+
+// SupportsConditionalUpsert is true, as upserts with an ETag are conditional
+func (s *EmbeddedStateProvider) SupportsConditionalUpsert() bool {
+	return true
+}
+
+func (s *EmbeddedStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
+	ctx, span := observability.StartSpan("Embedded State Provider", ctx, &map[string]string{
+		"method": "Get",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+
+	namespace := getNamespace(request.Metadata, "default")
+	sLog.DebugfCtx(ctx, "  P (Embedded State): get state %s in namespace %s", request.ID, namespace)
+
+	var entry states.StateEntry
+	err = s.DB.View(func(tx *bolt.Tx) error {
+		var data []byte
+		if bucket := getBucket(tx, namespace, request.Metadata); bucket != nil {
+			data = bucket.Get(entryKey(request.ID))
+		}
+		if data == nil {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found in namespace %s", request.ID, namespace), v1alpha2.NotFound)
+		}
+		if err := json.Unmarshal(data, &entry); err != nil {
+			return v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' is not a valid state entry", request.ID), v1alpha2.InternalError)
+		}
+		return nil
+	})
+	if err != nil {
+		if v1alpha2.IsNotFound(err) {
+			// Log entry not found as Info instead of Error to avoid flooding the logs
+			sLog.InfofCtx(ctx, "  P (Embedded State): failed to get %s state: %+v", request.ID, err)
+		} else {
+			sLog.ErrorfCtx(ctx, "  P (Embedded State): failed to get %s state: %+v", request.ID, err)
+		}
+		return states.StateEntry{}, err
+	}
+	return entry, nil
+}
+
+func (a *EmbeddedStateProvider) Clone(config providers.IProviderConfig) (providers.IProvider, error) {
+	ret := &EmbeddedStateProvider{}
+	if config == nil {
+		err := ret.Init(a.Config)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := ret.Init(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if a.Context != nil {
+		ret.Context = a.Context
+	}
+	return ret, nil
+}
+
+func toEmbeddedStateProviderConfig(config providers.IProviderConfig) (EmbeddedStateProviderConfig, error) {
+	ret := EmbeddedStateProviderConfig{}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ret, err
+	}
+	err = json.Unmarshal(data, &ret)
+	return ret, err
+}
+
+func openDB(path string, timeout time.Duration) (*bolt.DB, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	dbLock.Lock()
+	defer dbLock.Unlock()
+	if db, ok := dbs[abs]; ok {
+		return db, nil
+	}
+	if err = os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(abs, 0600, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	dbs[abs] = db
+	return db, nil
+}
+
+func getBucket(tx *bolt.Tx, namespace string, metadata map[string]interface{}) *bolt.Bucket {
+	nBucket := tx.Bucket([]byte(namespace))
+	if nBucket == nil {
+		return nil
+	}
+	return nBucket.Bucket([]byte(getObjectType(metadata)))
+}
+
+func getNamespace(metadata map[string]interface{}, defaultNamespace string) string {
+	if n, ok := metadata["namespace"]; ok {
+		if nstring, ok := n.(string); ok && nstring != "" {
+			return nstring
+		}
+	}
+	return defaultNamespace
+}
+
+func getObjectType(metadata map[string]interface{}) string {
+	objectType := ""
+	if resource, ok := metadata["resource"]; ok {
+		if rstring, ok := resource.(string); ok && rstring != "" {
+			objectType = rstring
+		}
+	}
+	if group, ok := metadata["group"]; ok {
+		if gstring, ok := group.(string); ok && gstring != "" {
+			objectType = objectType + "." + gstring
+		}
+	}
+	if objectType == "" {
+		return defaultObjectType
+	}
+	return objectType
+}
+
+// bbolt doesn't accept empty keys, so keys are prefixed to allow an empty ID
+func entryKey(id string) []byte {
+	return []byte(keyPrefix + id)
+}
+
+func nextETag(etag string) string {
+	if v, err := strconv.ParseInt(etag, 10, 64); err == nil {
+		return strconv.FormatInt(v+1, 10)
+	}
+	return "1"
+}
+
+func mergeStatus(existing interface{}, update interface{}) (map[string]interface{}, error) {
+	var body map[string]interface{}
+	jBody, _ := json.Marshal(existing)
+	if err := json.Unmarshal(jBody, &body); err != nil {
+		return nil, err
+	}
+	if body == nil {
+		body = make(map[string]interface{})
+	}
+	var newBody map[string]interface{}
+	jBody, _ = json.Marshal(update)
+	if err := json.Unmarshal(jBody, &newBody); err != nil {
+		return nil, err
+	}
+	statusMap, ok := newBody["status"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("new body doesn't have a valid status")
+	}
+	oldStatus, ok := body["status"].(map[string]interface{})
+	if !ok {
+		oldStatus = make(map[string]interface{})
+	}
+	for k, v := range statusMap {
+		oldStatus[k] = v
+	}
+	body["status"] = oldStatus
+	return body, nil
+}
@@ -0,0 +1,1061 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package embeddedstate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
+	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
+	"github.com/stretchr/testify/assert"
+)
+
+type TestPayload struct {
+	Name  string
+	Value int
+}
+
+func testConfig(t *testing.T) EmbeddedStateProviderConfig {
+	return EmbeddedStateProviderConfig{
+		Path: filepath.Join(t.TempDir(), "state.db"),
+	}
+}
+
+func TestInitWithEmptyConfig(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(EmbeddedStateProviderConfig{})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.MissingConfig, v1alpha2.GetErrorState(err))
+}
+
+func TestInitWithBadTimeout(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	config := testConfig(t)
+	config.Timeout = "soon"
+	err := provider.Init(config)
+	assert.NotNil(t, err)
+	assert.True(t, v1alpha2.IsBadConfig(err))
+}
+
+func TestInitWithMap(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.InitWithMap(
+		map[string]string{
+			"name": "name1",
+			"path": filepath.Join(t.TempDir(), "nested", "state.db"),
+		},
+	)
+	assert.Nil(t, err)
+}
+
+func TestID(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	config := testConfig(t)
+	config.Name = "name"
+	provider.Init(config)
+
+	assert.Equal(t, "name", provider.ID())
+}
+
+func TestSetContext(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	provider.Init(testConfig(t))
+	provider.SetContext(&contexts.ManagerContext{})
+	assert.NotNil(t, provider.Context)
+}
+
+func TestUpSert(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	id, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "123", id)
+}
+
+func TestUpSertWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	id, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "123", id)
+}
+
+func TestList(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "123", entries[0].ID)
+}
+
+func TestListWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "234",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "123", entries[0].ID)
+	entries, _, err = provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "234", entries[0].ID)
+	entries, _, err = provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.True(t, (entries[0].ID == "123" && entries[1].ID == "234") || (entries[1].ID == "123" && entries[0].ID == "234"))
+}
+
+func TestListWithNamespaceTwoObjectWithSameName(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "default",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "123", entries[0].ID)
+	entries, _, err = provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "123", entries[0].ID)
+	entries, _, err = provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "123", entries[0].ID)
+	assert.Equal(t, "123", entries[1].ID)
+}
+
+func TestDelete(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID: "123",
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestDeleteWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID: "123",
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestEmbeddedStateProviderConfigFromMapNil(t *testing.T) {
+	_, err := EmbeddedStateProviderConfigFromMap(nil)
+	assert.NotNil(t, err)
+}
+
+func TestEmbeddedStateProviderConfigFromMapEmpty(t *testing.T) {
+	_, err := EmbeddedStateProviderConfigFromMap(map[string]string{})
+	assert.NotNil(t, err)
+}
+func TestEmbeddedStateProviderConfigFromMap(t *testing.T) {
+	config, err := EmbeddedStateProviderConfigFromMap(map[string]string{
+		"name":    "my-name",
+		"path":    "/var/lib/symphony/state.db",
+		"timeout": "10s",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "my-name", config.Name)
+	assert.Equal(t, "/var/lib/symphony/state.db", config.Path)
+	assert.Equal(t, "10s", config.Timeout)
+}
+func TestEmbeddedStateProviderConfigFromMapEnvOverride(t *testing.T) {
+	os.Setenv("my-name", "real-name")
+	config, err := EmbeddedStateProviderConfigFromMap(map[string]string{
+		"name": "$env:my-name",
+		"path": "state.db",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "real-name", config.Name)
+}
+func TestGet(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, err := provider.Get(context.Background(), states.GetRequest{
+		ID: "123",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, entity)
+	assert.Equal(t, "123", entity.ID)
+
+	payload := TestPayload{}
+	data, err := json.Marshal(entity.Body)
+	assert.Nil(t, err)
+	err = json.Unmarshal(data, &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "Random name", payload.Name)
+	assert.Equal(t, 12345, payload.Value)
+	entity, err = provider.Get(context.Background(), states.GetRequest{
+		ID: "890",
+	})
+	sczErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.NotFound, sczErr.State)
+}
+
+func TestGetWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "123",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entity, err := provider.Get(context.Background(), states.GetRequest{
+		ID: "123",
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, entity)
+	assert.Equal(t, "123", entity.ID)
+
+	payload := TestPayload{}
+	data, err := json.Marshal(entity.Body)
+	assert.Nil(t, err)
+	err = json.Unmarshal(data, &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "Random name", payload.Name)
+	assert.Equal(t, 12345, payload.Value)
+	entity, err = provider.Get(context.Background(), states.GetRequest{
+		ID: "890",
+	})
+	sczErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.NotFound, sczErr.State)
+}
+
+func TestUpSertEmptyID(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	id, err := provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "", id)
+}
+
+func TestListEmptyID(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "", entries[0].ID)
+}
+
+func TestDeleteEmptyID(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID: "",
+	})
+	assert.Nil(t, err)
+	entries, _, err := provider.List(context.Background(), states.ListRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestGetEmptyID(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, err := provider.Get(context.Background(), states.GetRequest{
+		ID: "",
+	})
+	assert.Nil(t, err)
+	assert.NotNil(t, entity)
+	assert.Equal(t, "", entity.ID)
+
+	payload := TestPayload{}
+	data, err := json.Marshal(entity.Body)
+	assert.Nil(t, err)
+	err = json.Unmarshal(data, &payload)
+	assert.Nil(t, err)
+	assert.Equal(t, "Random name", payload.Name)
+	assert.Equal(t, 12345, payload.Value)
+	entity, err = provider.Get(context.Background(), states.GetRequest{
+		ID: "890",
+	})
+	sczErr, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.NotFound, sczErr.State)
+}
+
+func TestClone(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+
+	p, err := provider.Clone(testConfig(t))
+	assert.NotNil(t, p)
+	assert.Nil(t, err)
+
+	p, err = provider.Clone(nil)
+	assert.NotNil(t, p)
+	assert.Nil(t, err)
+}
+
+func TestLabelFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: "app=test",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+
+func TestLabelFilterWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: "app=test",
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+
+func TestLabelFilterNotEqual(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: "app!=test2",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestLabelFilterNotEqualWithNamespace(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: "app!=test2",
+		Metadata: map[string]interface{}{
+			"namespace": "nondefault",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestLabelFilterBadFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: "xxxxx",
+	})
+	assert.NotNil(t, err)
+	e, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, e.State)
+}
+func TestFieldFilterMetadata(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+					"name": "c1",
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "field",
+		FilterValue: "metadata.name=c1",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestFieldFilterDeepMetadataNotEqual(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+					"name": "c1",
+				},
+				"spec": map[string]interface{}{},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "field",
+		FilterValue: "metadata.labels.app!=xxx",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestFieldFilterStatus(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+					"name": "c1",
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"phase": "Running",
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "field",
+		FilterValue: "status.phase=Running",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestFieldFilterStatusBadFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+					"name": "c1",
+				},
+				"spec": map[string]interface{}{},
+				"status": map[string]interface{}{
+					"phase": "Running",
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.List(context.Background(), states.ListRequest{
+		FilterType:  "field",
+		FilterValue: "status.phase",
+	})
+	assert.NotNil(t, err)
+	e, ok := err.(v1alpha2.COAError)
+	assert.True(t, ok)
+	assert.Equal(t, v1alpha2.BadRequest, e.State)
+}
+func TestSpecFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"spec": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"foo": "bar",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "spec",
+		FilterValue: `[?(@.properties.foo=="bar")]`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+func TestStatusFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app": "test",
+					},
+				},
+				"status": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"foo": "bar",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "status",
+		FilterValue: `[?(@.properties.foo=="bar")]`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+
+func TestMultipleLabelsFilter(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "",
+			Body: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{
+						"app":  "test",
+						"app2": "test2",
+					},
+				},
+				"status": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"foo": "bar",
+					},
+				},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	entity, _, err := provider.List(context.Background(), states.ListRequest{
+		FilterType:  "label",
+		FilterValue: `app==test,app2=test2`,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entity))
+}
+
+func TestUpsertCreateOnly(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	create := ""
+	request := states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+		},
+		ETag: &create,
+	}
+	_, err = provider.Upsert(context.Background(), request)
+	assert.Nil(t, err)
+
+	// an empty ETag only creates, so the second caller loses
+	request.Value.Body = map[string]interface{}{"spec": map[string]interface{}{"a": "2"}}
+	_, err = provider.Upsert(context.Background(), request)
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["a"])
+
+	current := "1"
+	request.ETag = &current
+	_, err = provider.Upsert(context.Background(), request)
+	assert.Nil(t, err)
+	entry, err = provider.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", entry.ETag)
+}
+
+func TestUpsertStaleETagOfMissingEntry(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	stale := "3"
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "gone",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+		},
+		ETag: &stale,
+	})
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	_, err = provider.Get(context.Background(), states.GetRequest{ID: "gone"})
+	assert.True(t, v1alpha2.IsNotFound(err))
+}
+
+func TestUpsertETag(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			ETag: "42",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "1"}},
+		},
+	})
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.ETag)
+
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			ETag: "1",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "2"}},
+		},
+	})
+	assert.Nil(t, err)
+
+	// a stale ETag is rejected
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			ETag: "1",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "3"}},
+		},
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+
+	// no ETag means last write wins
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			Body: map[string]interface{}{"spec": map[string]interface{}{"a": "4"}},
+		},
+	})
+	assert.Nil(t, err)
+	entry, err = provider.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "3", entry.ETag)
+	assert.Equal(t, "4", entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["a"])
+
+	stale := "2"
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "a", ETag: &stale})
+	assert.Equal(t, v1alpha2.Conflict, v1alpha2.GetErrorState(err))
+	current := "3"
+	err = provider.Delete(context.Background(), states.DeleteRequest{ID: "a", ETag: &current})
+	assert.Nil(t, err)
+}
+
+func TestUpdateStatusOnly(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "a",
+			Body: map[string]interface{}{"status": map[string]interface{}{"a": "1"}},
+		},
+		Options: states.UpsertOption{UpdateStatusOnly: true},
+	})
+	assert.Equal(t, v1alpha2.NotFound, v1alpha2.GetErrorState(err))
+
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "a",
+			Body: map[string]interface{}{
+				"spec":   map[string]interface{}{"a": "1"},
+				"status": map[string]interface{}{"a": "1", "b": "1"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "a",
+			Body: map[string]interface{}{
+				"spec":   map[string]interface{}{"a": "2"},
+				"status": map[string]interface{}{"b": "2"},
+			},
+		},
+		Options: states.UpsertOption{UpdateStatusOnly: true},
+	})
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	body := entry.Body.(map[string]interface{})
+	assert.Equal(t, "1", body["spec"].(map[string]interface{})["a"])
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "2"}, body["status"])
+	assert.Equal(t, "2", entry.ETag)
+}
+
+func TestObjectTypes(t *testing.T) {
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(testConfig(t))
+	assert.Nil(t, err)
+	for _, resource := range []string{"solutions", "targets"} {
+		_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   "a",
+				Body: map[string]interface{}{"spec": map[string]interface{}{"resource": resource}},
+			},
+			Metadata: map[string]interface{}{
+				"namespace": "edge",
+				"group":     "solution.symphony",
+				"resource":  resource,
+			},
+		})
+		assert.Nil(t, err)
+	}
+	entries, _, err := provider.List(context.Background(), states.ListRequest{
+		Metadata: map[string]interface{}{
+			"group":    "solution.symphony",
+			"resource": "targets",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "targets", entries[0].Body.(map[string]interface{})["spec"].(map[string]interface{})["resource"])
+}
+
+func TestReopen(t *testing.T) {
+	config := testConfig(t)
+	provider := EmbeddedStateProvider{}
+	err := provider.Init(config)
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID: "a",
+			Body: TestPayload{
+				Name:  "Random name",
+				Value: 12345,
+			},
+		},
+	})
+	assert.Nil(t, err)
+
+	// instances with the same path share the database
+	other := EmbeddedStateProvider{}
+	err = other.Init(config)
+	assert.Nil(t, err)
+	_, err = other.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+
+	abs, _ := filepath.Abs(config.Path)
+	dbLock.Lock()
+	delete(dbs, abs)
+	dbLock.Unlock()
+	assert.Nil(t, provider.DB.Close())
+
+	reopened := EmbeddedStateProvider{}
+	err = reopened.Init(config)
+	assert.Nil(t, err)
+	entry, err := reopened.Get(context.Background(), states.GetRequest{ID: "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.ETag)
+	assert.Equal(t, "Random name", entry.Body.(map[string]interface{})["Name"])
+}
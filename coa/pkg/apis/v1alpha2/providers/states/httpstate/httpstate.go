@@ -16,6 +16,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	contexts "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/contexts"
@@ -37,6 +38,31 @@ type HttpStateProviderConfig struct {
 	PostBodyKeyName   string `json:"postBodyKeyName,omitempty"`
 	PostBodyValueName string `json:"postBodyValueName,omitempty"`
 	NotFoundAs204     bool   `json:"notFoundAs204,omitempty"`
+	QueryUrl          string `json:"queryUrl,omitempty"`
+}
+
+// queryRequest and queryResponse follow the state query API of Dapr
+type queryRequest struct {
+	Filter map[string]interface{} `json:"filter"`
+	Sort   []querySort            `json:"sort,omitempty"`
+	Page   queryPage              `json:"page"`
+}
+type querySort struct {
+	Key   string `json:"key"`
+	Order string `json:"order,omitempty"`
+}
+type queryPage struct {
+	Limit int    `json:"limit,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+type queryResponse struct {
+	Results []queryResult `json:"results"`
+	Token   string        `json:"token,omitempty"`
+}
+type queryResult struct {
+	Key  string      `json:"key"`
+	Data interface{} `json:"data"`
+	ETag string      `json:"etag,omitempty"`
 }
 
 type HttpStateProvider struct {
@@ -88,6 +114,9 @@ func HttpStateProviderConfigFromMap(properties map[string]string) (HttpStateProv
 			ret.NotFoundAs204 = bVal
 		}
 	}
+	if v, ok := properties["queryUrl"]; ok {
+		ret.QueryUrl = utils.ParseProperty(v)
+	}
 	if v, ok := properties["url"]; ok {
 		ret.Url = utils.ParseProperty(v)
 	} else {
@@ -144,6 +173,12 @@ func (s *HttpStateProvider) Upsert(ctx context.Context, entry states.UpsertReque
 		sLog.ErrorfCtx(ctx, " P (Http State): failed to upsert state: %+v", err)
 		return "", err
 	}
+	if entry.ETag != nil {
+		// the HTTP state store can't tell if the entry changed, so it can't honour the ETag
+		err = v1alpha2.NewCOAError(nil, "Http state store doesn't support conditional upserts", v1alpha2.NotImplemented)
+		sLog.ErrorfCtx(ctx, " P (Http State): failed to upsert state: %+v", err)
+		return "", err
+	}
 	if s.Config.PostNameInPath {
 		rUrl, err = url.JoinPath(s.Config.Url, entry.Value.ID)
 	}
@@ -180,7 +215,101 @@ func (s *HttpStateProvider) Upsert(ctx context.Context, entry states.UpsertReque
 }
 
 func (s *HttpStateProvider) List(ctx context.Context, request states.ListRequest) ([]states.StateEntry, string, error) {
-	return nil, "", v1alpha2.NewCOAError(nil, "Http state store list is not implemented", v1alpha2.NotImplemented)
+	if s.Config.QueryUrl == "" {
+		return nil, "", v1alpha2.NewCOAError(nil, "Http state store list is not implemented", v1alpha2.NotImplemented)
+	}
+	ctx, span := observability.StartSpan("Http State Provider", ctx, &map[string]string{
+		"method": "List",
+	})
+	var err error = nil
+	defer observ_utils.CloseSpanWithError(span, &err)
+	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
+	sLog.InfoCtx(ctx, "  P (Http State): list states")
+
+	query := queryRequest{
+		Filter: map[string]interface{}{},
+		Page: queryPage{
+			Limit: request.Limit,
+		},
+	}
+	sortBy := states.NormalizeSortBy(request.SortBy)
+	sortKey, order := strings.TrimPrefix(sortBy, "-"), "ASC"
+	if strings.HasPrefix(sortBy, "-") {
+		order = "DESC"
+	}
+	if sortKey == states.SortByName {
+		// Symphony objects keep their names in the metadata of their bodies
+		sortKey = "metadata.name"
+	}
+	query.Sort = []querySort{{Key: sortKey, Order: order}}
+	if request.Continue != "" {
+		var token states.ContinueToken
+		token, err = states.DecodeContinueToken(request.Continue, request.SortBy)
+		if err != nil {
+			sLog.ErrorfCtx(ctx, "  P (Http State): failed to list states: %+v", err)
+			return nil, "", err
+		}
+		query.Page.Token = token.Cursor
+	}
+
+	jData, _ := json.Marshal(query)
+	req, err := http.NewRequest("POST", s.Config.QueryUrl, bytes.NewBuffer(jData))
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Http State): failed to create a query request: %+v", err)
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Http State): failed to get response from querying states: %+v", err)
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		sLog.ErrorfCtx(ctx, "  P (Http State): failed to get correct state code from querying states, status code %d", resp.StatusCode)
+		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to query HTTP state store: [%d]", resp.StatusCode), v1alpha2.InternalError)
+		return nil, "", err
+	}
+	bodyBytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Http State): failed to read query response body: %+v", err)
+		return nil, "", err
+	}
+	var result queryResponse
+	err = json.Unmarshal(bodyBytes, &result)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Http State): failed to unmarshall query response body: %+v", err)
+		return nil, "", err
+	}
+
+	var entities []states.StateEntry
+	for _, r := range result.Results {
+		entry := states.StateEntry{
+			ID:   r.Key,
+			Body: r.Data,
+			ETag: r.ETag,
+		}
+		// The query API has no filters matching the ones of Symphony, so the page is filtered here
+		if request.FilterType != "" && request.FilterValue != "" {
+			var match bool
+			match, err = states.MatchFilter(entry, request.FilterType, request.FilterValue)
+			if err != nil {
+				return nil, "", err
+			} else if !match {
+				continue
+			}
+		}
+		entities = append(entities, entry)
+	}
+	next := ""
+	if result.Token != "" && request.Limit > 0 && len(result.Results) == request.Limit {
+		next = states.EncodeContinueToken(states.ContinueToken{
+			SortBy: sortBy,
+			Cursor: result.Token,
+		})
+	}
+	return entities, next, nil
 }
 
 func (s *HttpStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
@@ -357,3 +357,58 @@ func TestClone(t *testing.T) {
 	assert.NotNil(t, p)
 	assert.Nil(t, err)
 }
+
+func TestListWithQuery(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var query queryRequest
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &query)
+		if err != nil || r.URL.Path != "/query" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		response := queryResponse{}
+		if query.Page.Token == "" {
+			response.Results = []queryResult{
+				{Key: "a", Data: map[string]interface{}{"metadata": map[string]interface{}{"name": "a"}}, ETag: "1"},
+				{Key: "b", Data: map[string]interface{}{"metadata": map[string]interface{}{"name": "b"}}, ETag: "2"},
+			}
+			response.Token = "2"
+		} else if query.Page.Token == "2" {
+			response.Results = []queryResult{
+				{Key: "c", Data: map[string]interface{}{"metadata": map[string]interface{}{"name": "c"}}, ETag: "3"},
+			}
+		}
+		if len(query.Sort) != 1 || query.Sort[0].Key != "metadata.name" || query.Sort[0].Order != "DESC" || query.Page.Limit != 2 {
+			http.Error(w, "unexpected query", http.StatusBadRequest)
+			return
+		}
+		jsonResponse, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonResponse)
+	}))
+	defer ts.Close()
+
+	provider := HttpStateProvider{}
+	err := provider.Init(HttpStateProviderConfig{
+		Url:      ts.URL,
+		QueryUrl: ts.URL + "/query",
+	})
+	assert.Nil(t, err)
+	entries, next, err := provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 2, SortBy: "-name"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "a", entries[0].ID)
+	assert.Equal(t, "2", entries[1].ETag)
+	assert.NotEqual(t, "", next)
+
+	entries, next, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 2, SortBy: "-name", Continue: next},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "c", entries[0].ID)
+	assert.Equal(t, "", next)
+}
@@ -43,6 +43,7 @@ type MemoryStateProvider struct {
 	Data    map[string]interface{}
 	Context *contexts.ManagerContext
 	mu      sync.RWMutex
+	watch   states.Watchers
 }
 
 func (s *MemoryStateProvider) ID() string {
@@ -112,6 +113,24 @@ func (s *MemoryStateProvider) Upsert(ctx context.Context, entry states.UpsertReq
 		sLog.ErrorfCtx(ctx, "  P (Memory State): failed to upsert %s states: %+v", entry.Value.ID, err)
 		return "", err
 	}
+	_, existed := list[entry.Value.ID]
+	if entry.ETag != nil {
+		// the entry is only written if its ETag is still the expected one, which is empty for
+		// entries that don't exist yet
+		current := ""
+		if existing, ok := list[entry.Value.ID].(states.StateEntry); ok {
+			current = existing.ETag
+		}
+		if current != *entry.ETag {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", entry.Value.ID, *entry.ETag, current), v1alpha2.Conflict)
+			sLog.ErrorfCtx(ctx, "  P (Memory State): failed to upsert %s state: %+v", entry.Value.ID, err)
+			return "", err
+		}
+		entry.Value.ETag = "1"
+		if v, err := strconv.ParseInt(current, 10, 64); err == nil {
+			entry.Value.ETag = strconv.FormatInt(v+1, 10)
+		}
+	}
 	if entry.Options.UpdateStatusOnly {
 		existing, ok := list[entry.Value.ID]
 		if !ok {
@@ -154,6 +173,12 @@ func (s *MemoryStateProvider) Upsert(ctx context.Context, entry states.UpsertReq
 
 	list[entry.Value.ID] = entry.Value
 
+	eventType := states.WatchAdded
+	if existed {
+		eventType = states.WatchModified
+	}
+	s.notify(entry.Metadata, eventType, entry.Value)
+
 	return entry.Value.ID, nil
 }
 func (s *MemoryStateProvider) List(ctx context.Context, request states.ListRequest) ([]states.StateEntry, string, error) {
@@ -166,7 +191,7 @@ func (s *MemoryStateProvider) List(ctx context.Context, request states.ListReque
 	defer observ_utils.CloseSpanWithError(span, &err)
 	defer observ_utils.EmitUserDiagnosticsLogs(ctx, &err)
 
-	var entities []states.StateEntry
+	var items []states.ListItem
 	namespace := ""
 	if n, ok := request.Metadata["namespace"]; ok {
 		if nstring, ok := n.(string); ok && nstring != "" {
@@ -185,34 +210,46 @@ func (s *MemoryStateProvider) List(ctx context.Context, request states.ListReque
 							var match bool
 							match, err = states.MatchFilter(vE, request.FilterType, request.FilterValue)
 							if err != nil {
-								return entities, "", err
+								return nil, "", err
 							} else if !match {
 								continue
 							}
 						}
-						var copy states.StateEntry
-						copy, err = s.ReturnDeepCopy(vE)
-						if err != nil {
-							err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to create a deep copy of entry '%s'", vE.ID), v1alpha2.InternalError)
-							sLog.ErrorfCtx(ctx, "  P (Memory State): failed to list states: %+v", err)
-							return entities, "", err
-						}
-						entities = append(entities, copy)
+						items = append(items, states.ListItem{Key: nKey + "/" + vE.ID, Entry: vE})
 					} else {
 						err = v1alpha2.NewCOAError(nil, "found invalid state entry", v1alpha2.InternalError)
 						sLog.ErrorfCtx(ctx, "  P (Memory State): failed to list states: %+v", err)
-						return entities, "", err
+						return nil, "", err
 					}
 				}
 			} else {
 				err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to convert entry list to map[string]interface{} for namespace %s", namespace), v1alpha2.InternalError)
 				sLog.ErrorfCtx(ctx, "  P (Memory State): failed to list states: %+v", err)
-				return entities, "", err
+				return nil, "", err
 			}
 		}
 	}
 
-	return entities, "", nil
+	var page []states.StateEntry
+	var next string
+	page, next, err = states.PageList(items, request)
+	if err != nil {
+		sLog.ErrorfCtx(ctx, "  P (Memory State): failed to list states: %+v", err)
+		return nil, "", err
+	}
+	var entities []states.StateEntry
+	for _, vE := range page {
+		var copy states.StateEntry
+		copy, err = s.ReturnDeepCopy(vE)
+		if err != nil {
+			err = v1alpha2.NewCOAError(nil, fmt.Sprintf("failed to create a deep copy of entry '%s'", vE.ID), v1alpha2.InternalError)
+			sLog.ErrorfCtx(ctx, "  P (Memory State): failed to list states: %+v", err)
+			return entities, "", err
+		}
+		entities = append(entities, copy)
+	}
+
+	return entities, next, nil
 }
 
 func (s *MemoryStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
@@ -244,16 +281,26 @@ func (s *MemoryStateProvider) Delete(ctx context.Context, request states.DeleteR
 		sLog.ErrorfCtx(ctx, "  P (Memory State): failed to delete %s: %+v", request.ID, err)
 		return err
 	}
-	if _, ok := list[request.ID]; !ok {
+	existing, ok := list[request.ID]
+	if !ok {
 		err = v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' is not found", request.ID), v1alpha2.NotFound)
 		sLog.ErrorfCtx(ctx, "  P (Memory State): failed to delete %s: %+v", request.ID, err)
 		return err
 	}
 	delete(list, request.ID)
 
+	if vE, ok := existing.(states.StateEntry); ok {
+		s.notify(request.Metadata, states.WatchDeleted, vE)
+	}
+
 	return nil
 }
 
+// SupportsConditionalUpsert is true, as upserts with an ETag are conditional
+func (s *MemoryStateProvider) SupportsConditionalUpsert() bool {
+	return true
+}
+
 func (s *MemoryStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -308,6 +355,31 @@ func (s *MemoryStateProvider) Get(ctx context.Context, request states.GetRequest
 	return states.StateEntry{}, err
 }
 
+func (s *MemoryStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	namespace := ""
+	if n, ok := request.Metadata["namespace"]; ok {
+		if nstring, ok := n.(string); ok {
+			namespace = nstring
+		}
+	}
+	sLog.DebugfCtx(ctx, "  P (Memory State): watch states in namespace %s", namespace)
+	return s.watch.Add(ctx, request), nil
+}
+
+// notify is called with the lock held, so watchers see changes in the order they're made
+func (s *MemoryStateProvider) notify(metadata map[string]interface{}, eventType string, entry states.StateEntry) {
+	copy, err := s.ReturnDeepCopy(entry)
+	if err != nil {
+		sLog.Errorf("  P (Memory State): failed to create a deep copy of entry '%s' for watchers: %+v", entry.ID, err)
+		return
+	}
+	s.watch.Notify(metadata, states.WatchEvent{
+		Type:            eventType,
+		Entry:           copy,
+		ResourceVersion: copy.ETag,
+	})
+}
+
 func toMemoryStateProviderConfig(config providers.IProviderConfig) (MemoryStateProviderConfig, error) {
 	ret := MemoryStateProviderConfig{}
 	data, err := json.Marshal(config)
@@ -823,3 +823,175 @@ func TestMultipleLabelsFilter(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(entity))
 }
+
+func TestWatch(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProvider{})
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := provider.Watch(ctx, states.WatchRequest{
+		Metadata: map[string]interface{}{
+			"namespace": "edge",
+			"resource":  "instances",
+		},
+	})
+	assert.Nil(t, err)
+
+	for _, namespace := range []string{"default", "edge", "edge"} {
+		_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   "a",
+				Body: map[string]interface{}{"spec": map[string]interface{}{"namespace": namespace}},
+			},
+			Metadata: map[string]interface{}{
+				"namespace": namespace,
+				"resource":  "instances",
+			},
+		})
+		assert.Nil(t, err)
+	}
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "b"},
+		Metadata: map[string]interface{}{
+			"namespace": "edge",
+			"resource":  "targets",
+		},
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID: "a",
+		Metadata: map[string]interface{}{
+			"namespace": "edge",
+			"resource":  "instances",
+		},
+	})
+	assert.Nil(t, err)
+
+	event := <-events
+	assert.Equal(t, states.WatchAdded, event.Type)
+	assert.Equal(t, "a", event.Entry.ID)
+	assert.Equal(t, "1", event.ResourceVersion)
+	assert.Equal(t, "edge", event.Entry.Body.(map[string]interface{})["spec"].(map[string]interface{})["namespace"])
+	event = <-events
+	assert.Equal(t, states.WatchModified, event.Type)
+	event = <-events
+	assert.Equal(t, states.WatchDeleted, event.Type)
+	assert.Equal(t, "a", event.Entry.ID)
+
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestListPaging(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProvider{})
+	assert.Nil(t, err)
+	for i, id := range []string{"c", "a", "e", "b", "d"} {
+		namespace := "default"
+		if i%2 == 1 {
+			namespace = "edge"
+		}
+		_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: TestPayload{Name: id, Value: 10 - i},
+			},
+			Metadata: map[string]interface{}{
+				"namespace": namespace,
+			},
+		})
+		assert.Nil(t, err)
+	}
+
+	ids := []string{}
+	request := states.ListRequest{ListOptions: states.ListOptions{Limit: 2}}
+	for {
+		entries, next, err := provider.List(context.Background(), request)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, len(entries), 2)
+		for _, entry := range entries {
+			ids = append(ids, entry.ID)
+		}
+		if next == "" {
+			break
+		}
+		request.Continue = next
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, ids)
+
+	entries, next, err := provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 3, SortBy: "-Value"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, "c", entries[0].ID)
+	assert.Equal(t, "a", entries[1].ID)
+	entries, next, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 3, SortBy: "-Value", Continue: next},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "d", entries[1].ID)
+	assert.Equal(t, "", next)
+
+	entries, _, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 1},
+		Metadata: map[string]interface{}{
+			"namespace": "edge",
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "a", entries[0].ID)
+
+	// a token only works with the sort order it was returned for
+	_, next, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 1},
+	})
+	assert.Nil(t, err)
+	_, _, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Limit: 1, SortBy: "Name", Continue: next},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err))
+	_, _, err = provider.List(context.Background(), states.ListRequest{
+		ListOptions: states.ListOptions{Continue: "not a token"},
+	})
+	assert.Equal(t, v1alpha2.BadRequest, v1alpha2.GetErrorState(err))
+}
+
+func TestUpsertWithETag(t *testing.T) {
+	provider := MemoryStateProvider{}
+	err := provider.Init(MemoryStateProvider{})
+	assert.Nil(t, err)
+	empty := ""
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "123", Body: TestPayload{Name: "a"}},
+		ETag:  &empty,
+	})
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "123"})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.ETag)
+
+	// only one of two writers that read the same entry can write it
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "123", Body: TestPayload{Name: "b"}},
+		ETag:  &entry.ETag,
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "123", Body: TestPayload{Name: "c"}},
+		ETag:  &entry.ETag,
+	})
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{ID: "123", Body: TestPayload{Name: "d"}},
+		ETag:  &empty,
+	})
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+	entry, err = provider.Get(context.Background(), states.GetRequest{ID: "123"})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", entry.ETag)
+	assert.Equal(t, "b", entry.Body.(map[string]interface{})["Name"])
+}
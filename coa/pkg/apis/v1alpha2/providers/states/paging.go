@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
+)
+
+// SortByName is the default sort order of List: by entry ID
+const SortByName = "name"
+
+// ListItem is an entry to be paged, with a key that's unique among the listed entries,
+// such as the namespace and the ID of the entry
+type ListItem struct {
+	Key   string
+	Entry StateEntry
+}
+
+// ContinueToken is the content of the opaque token returned by List when there are more entries.
+// Providers that page natively keep their own cursor in Cursor.
+type ContinueToken struct {
+	SortBy string      `json:"s,omitempty"`
+	Value  interface{} `json:"v"`
+	Key    string      `json:"k,omitempty"`
+	Cursor string      `json:"c,omitempty"`
+}
+
+func EncodeContinueToken(token ContinueToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeContinueToken reads a token returned by List. It fails if the token was returned
+// for a different sort order.
+func DecodeContinueToken(continueToken string, sortBy string) (ContinueToken, error) {
+	var ret ContinueToken
+	data, err := base64.RawURLEncoding.DecodeString(continueToken)
+	if err == nil {
+		err = json.Unmarshal(data, &ret)
+	}
+	if err != nil {
+		return ret, v1alpha2.NewCOAError(err, "invalid continue token", v1alpha2.BadRequest)
+	}
+	if ret.SortBy != NormalizeSortBy(sortBy) {
+		return ret, v1alpha2.NewCOAError(nil, fmt.Sprintf("continue token doesn't match sortBy '%s'", sortBy), v1alpha2.BadRequest)
+	}
+	return ret, nil
+}
+
+// IsSortedByName tells whether sortBy orders entries by ID, in either direction, in which case
+// a provider can page by key without reading the entries it skips
+func IsSortedByName(sortBy string) bool {
+	return strings.TrimPrefix(NormalizeSortBy(sortBy), "-") == SortByName
+}
+
+// PageList sorts the items by request.SortBy and returns the page after request.Continue, with
+// at most request.Limit entries, and the token of the next page. The token is empty on the
+// last page.
+func PageList(items []ListItem, request ListRequest) ([]StateEntry, string, error) {
+	sortBy := NormalizeSortBy(request.SortBy)
+	field, descending := parseSortBy(sortBy)
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = SortValue(item.Entry, field)
+	}
+	indexes := make([]int, len(items))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return compareListItems(values[indexes[i]], items[indexes[i]].Key, values[indexes[j]], items[indexes[j]].Key, descending) < 0
+	})
+
+	start := 0
+	if request.Continue != "" {
+		token, err := DecodeContinueToken(request.Continue, request.SortBy)
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(indexes), func(i int) bool {
+			return compareListItems(values[indexes[i]], items[indexes[i]].Key, token.Value, token.Key, descending) > 0
+		})
+	}
+	end := len(indexes)
+	if request.Limit > 0 && start+request.Limit < end {
+		end = start + request.Limit
+	}
+	ret := make([]StateEntry, 0, end-start)
+	for _, i := range indexes[start:end] {
+		ret = append(ret, items[i].Entry)
+	}
+	next := ""
+	if end < len(indexes) {
+		last := indexes[end-1]
+		next = EncodeContinueToken(ContinueToken{
+			SortBy: sortBy,
+			Value:  values[last],
+			Key:    items[last].Key,
+		})
+	}
+	return ret, next, nil
+}
+
+// SortValue returns the value of a dot-separated field of the entry body, such as
+// "spec.displayName", or the entry ID for "name"
+func SortValue(entry StateEntry, field string) interface{} {
+	if field == SortByName {
+		return entry.ID
+	}
+	var value interface{}
+	j, _ := json.Marshal(entry.Body)
+	if json.Unmarshal(j, &value) != nil {
+		return nil
+	}
+	for _, part := range strings.Split(field, ".") {
+		dict, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = dict[part]
+	}
+	switch value.(type) {
+	case string, float64, bool:
+		return value
+	}
+	return nil
+}
+
+// NormalizeSortBy returns the form of sortBy that's kept in continue tokens
+func NormalizeSortBy(sortBy string) string {
+	switch sortBy {
+	case "", "id", "metadata.name":
+		return SortByName
+	case "-id", "-metadata.name":
+		return "-" + SortByName
+	}
+	return sortBy
+}
+
+func parseSortBy(sortBy string) (string, bool) {
+	if strings.HasPrefix(sortBy, "-") {
+		return sortBy[1:], true
+	}
+	return sortBy, false
+}
+
+func compareListItems(value1 interface{}, key1 string, value2 interface{}, key2 string, descending bool) int {
+	ret := compareSortValues(value1, value2)
+	if ret == 0 {
+		ret = strings.Compare(key1, key2)
+	}
+	if descending {
+		return -ret
+	}
+	return ret
+}
+
+// compareSortValues orders missing values first, then booleans, numbers and strings
+func compareSortValues(value1 interface{}, value2 interface{}) int {
+	rank1, rank2 := sortValueRank(value1), sortValueRank(value2)
+	if rank1 != rank2 {
+		return rank1 - rank2
+	}
+	switch v1 := value1.(type) {
+	case bool:
+		v2 := value2.(bool)
+		if v1 == v2 {
+			return 0
+		} else if !v1 {
+			return -1
+		}
+		return 1
+	case float64:
+		v2 := value2.(float64)
+		if v1 < v2 {
+			return -1
+		} else if v1 > v2 {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(v1, value2.(string))
+	}
+	return 0
+}
+
+func sortValueRank(value interface{}) int {
+	switch value.(type) {
+	case bool:
+		return 1
+	case float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 0
+}
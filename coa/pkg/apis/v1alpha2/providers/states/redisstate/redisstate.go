@@ -11,6 +11,7 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -30,8 +31,18 @@ var rLog = logger.NewLogger("coa.runtime")
 const (
 	entryCountPerList = 100
 	separator         = "*"
+	// changes are published on a channel per object type for watchers
+	watchChannelPrefix = "symphony-watch"
 )
 
+type watchMessage struct {
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	ID        string `json:"id"`
+	ETag      string `json:"etag,omitempty"`
+	Values    string `json:"values"`
+}
+
 type RedisStateProviderConfig struct {
 	Name        string `json:"name"`
 	Host        string `json:"host"`
@@ -170,17 +181,84 @@ func (r *RedisStateProvider) Upsert(ctx context.Context, entry states.UpsertRequ
 		oldEntryDict["status"] = oldStatusDict
 		body, _ = json.Marshal(oldEntryDict)
 		_, err = r.Client.HSet(r.Ctx, key, "values", string(body)).Result()
+		if err == nil {
+			etag, _ := r.Client.HGet(r.Ctx, key, "etag").Result()
+			r.publish(ctx, entry.Metadata, states.WatchModified, entry.Value.ID, etag, string(body))
+		}
+		return entry.Value.ID, err
+	}
+
+	if entry.ETag != nil {
+		err = r.upsertIfUnchanged(ctx, key, *entry.ETag, body, entry)
 		return entry.Value.ID, err
 	}
 
+	var existed int64
+	existed, err = r.Client.Exists(r.Ctx, key).Result()
+	if err != nil {
+		return entry.Value.ID, err
+	}
 	properties := map[string]interface{}{
 		"values": string(body),
 		"etag":   entry.Value.ETag,
 	}
 	_, err = r.Client.HSet(r.Ctx, key, properties).Result()
+	if err == nil {
+		eventType := states.WatchAdded
+		if existed > 0 {
+			eventType = states.WatchModified
+		}
+		r.publish(ctx, entry.Metadata, eventType, entry.Value.ID, entry.Value.ETag, string(body))
+	}
 	return entry.Value.ID, err
 }
 
+// upsertIfUnchanged writes the entry only if its ETag is still the expected one, which is empty
+// for entries that don't exist yet, and gives it the next ETag, so that only one of the callers
+// that read the same entry can write it
+func (r *RedisStateProvider) upsertIfUnchanged(ctx context.Context, key string, etag string, body []byte, entry states.UpsertRequest) error {
+	var existed int64
+	var next string
+	err := r.Client.Watch(r.Ctx, func(tx *redis.Tx) error {
+		var err error
+		existed, err = tx.Exists(r.Ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		current, err := tx.HGet(r.Ctx, key, "etag").Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if current != etag {
+			return v1alpha2.NewCOAError(nil, fmt.Sprintf("entry '%s' has been modified, etag %s doesn't match %s", entry.Value.ID, etag, current), v1alpha2.Conflict)
+		}
+		next = "1"
+		if v, err := strconv.ParseInt(current, 10, 64); err == nil {
+			next = strconv.FormatInt(v+1, 10)
+		}
+		_, err = tx.TxPipelined(r.Ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(r.Ctx, key, map[string]interface{}{
+				"values": string(body),
+				"etag":   next,
+			})
+			return nil
+		})
+		return err
+	}, key)
+	if err == redis.TxFailedErr {
+		return v1alpha2.NewCOAError(err, fmt.Sprintf("entry '%s' has been modified", entry.Value.ID), v1alpha2.Conflict)
+	}
+	if err != nil {
+		return err
+	}
+	eventType := states.WatchAdded
+	if existed > 0 {
+		eventType = states.WatchModified
+	}
+	r.publish(ctx, entry.Metadata, eventType, entry.Value.ID, next, string(body))
+	return nil
+}
+
 func (r *RedisStateProvider) List(ctx context.Context, request states.ListRequest) ([]states.StateEntry, string, error) {
 	ctx, span := observability.StartSpan("Redis State Provider", ctx, &map[string]string{
 		"method": "List",
@@ -207,6 +285,9 @@ func (r *RedisStateProvider) List(ctx context.Context, request states.ListReques
 	filter := fmt.Sprintf("%s%s*", keyPrefix, separator)
 	var cursor uint64 = 0
 	var keys []string
+	var listed []listedKey
+	// A scan can return a key more than once
+	seen := make(map[string]bool)
 
 	for {
 		var err error
@@ -216,32 +297,16 @@ func (r *RedisStateProvider) List(ctx context.Context, request states.ListReques
 		}
 
 		for _, key := range keys {
-			result, err := r.Client.HGetAll(r.Ctx, key).Result()
-			if err != nil || len(result) == 0 {
-				rLog.Errorf("  P (Redis State): failed to get entry for key %s: %+v", key, err)
-				continue
-			}
 			parts := strings.Split(key, separator)
 			if len(parts) != 3 {
 				rLog.Errorf("  P (Redis State): key is not valid %s: %+v", key, err)
 				continue
 			}
-			entry, err := CastRedisPropertiesToStateEntry(parts[2], result)
-			if err != nil {
-				rLog.Errorf("  P (Redis State): failed to cast entry for key %s: %+v", key, err)
+			if seen[key] {
 				continue
 			}
-			if request.FilterType != "" && request.FilterValue != "" {
-				var match bool
-				match, err = states.MatchFilter(entry, request.FilterType, request.FilterValue)
-				if err != nil {
-					return entities, "", err
-				} else if !match {
-					continue
-				}
-			}
-
-			entities = append(entities, entry)
+			seen[key] = true
+			listed = append(listed, listedKey{id: parts[2], key: key})
 		}
 
 		if cursor == 0 {
@@ -249,9 +314,98 @@ func (r *RedisStateProvider) List(ctx context.Context, request states.ListReques
 		}
 	}
 
+	if !states.IsSortedByName(request.SortBy) {
+		// Entries have to be read to be sorted by a field
+		items := make([]states.ListItem, 0, len(listed))
+		for _, k := range listed {
+			var entry states.StateEntry
+			var ok bool
+			entry, ok, err = r.readListedEntry(k, request)
+			if err != nil {
+				return nil, "", err
+			} else if ok {
+				items = append(items, states.ListItem{Key: k.key, Entry: entry})
+			}
+		}
+		var next string
+		entities, next, err = states.PageList(items, request)
+		return entities, next, err
+	}
+
+	// Keys are sorted by name, so only the entries of the page are read
+	sortBy := states.NormalizeSortBy(request.SortBy)
+	descending := strings.HasPrefix(sortBy, "-")
+	sort.Slice(listed, func(i, j int) bool {
+		return listed[i].less(listed[j], descending)
+	})
+	start := 0
+	if request.Continue != "" {
+		var token states.ContinueToken
+		token, err = states.DecodeContinueToken(request.Continue, request.SortBy)
+		if err != nil {
+			return nil, "", err
+		}
+		last := listedKey{key: token.Key}
+		last.id, _ = token.Value.(string)
+		start = sort.Search(len(listed), func(i int) bool {
+			return last.less(listed[i], descending)
+		})
+	}
+	for i := start; i < len(listed); i++ {
+		if request.Limit > 0 && len(entities) == request.Limit {
+			last := listed[i-1]
+			return entities, states.EncodeContinueToken(states.ContinueToken{
+				SortBy: sortBy,
+				Value:  last.id,
+				Key:    last.key,
+			}), nil
+		}
+		var entry states.StateEntry
+		var ok bool
+		entry, ok, err = r.readListedEntry(listed[i], request)
+		if err != nil {
+			return entities, "", err
+		} else if ok {
+			entities = append(entities, entry)
+		}
+	}
+
 	return entities, "", nil
 }
 
+type listedKey struct {
+	id  string
+	key string
+}
+
+func (k listedKey) less(other listedKey, descending bool) bool {
+	if k.id == other.id {
+		return (k.key < other.key) != descending
+	}
+	return (k.id < other.id) != descending
+}
+
+// readListedEntry reads a listed key, and tells whether its entry matches the filter of the request
+func (r *RedisStateProvider) readListedEntry(k listedKey, request states.ListRequest) (states.StateEntry, bool, error) {
+	result, err := r.Client.HGetAll(r.Ctx, k.key).Result()
+	if err != nil || len(result) == 0 {
+		rLog.Errorf("  P (Redis State): failed to get entry for key %s: %+v", k.key, err)
+		return states.StateEntry{}, false, nil
+	}
+	entry, err := CastRedisPropertiesToStateEntry(k.id, result)
+	if err != nil {
+		rLog.Errorf("  P (Redis State): failed to cast entry for key %s: %+v", k.key, err)
+		return states.StateEntry{}, false, nil
+	}
+	if request.FilterType != "" && request.FilterValue != "" {
+		match, err := states.MatchFilter(entry, request.FilterType, request.FilterValue)
+		if err != nil || !match {
+			return states.StateEntry{}, false, err
+		}
+	}
+	return entry, true, nil
+}
+
 func (r *RedisStateProvider) Delete(ctx context.Context, request states.DeleteRequest) error {
 	ctx, span := observability.StartSpan("Redis State Provider", ctx, &map[string]string{
 		"method": "Delete",
@@ -269,10 +423,103 @@ func (r *RedisStateProvider) Delete(ctx context.Context, request states.DeleteRe
 	rLog.DebugfCtx(ctx, "  P (Redis State): delete state %s with keyPrefix %s", request.ID, keyPrefix)
 
 	HKey := fmt.Sprintf("%s%s%s", keyPrefix, separator, request.ID)
-	_, err = r.Client.Del(r.Ctx, HKey).Result()
+	existing, _ := r.Client.HGetAll(r.Ctx, HKey).Result()
+	var deleted int64
+	deleted, err = r.Client.Del(r.Ctx, HKey).Result()
+	if err == nil && deleted > 0 && len(existing) > 0 {
+		r.publish(ctx, request.Metadata, states.WatchDeleted, request.ID, existing["etag"], existing["values"])
+	}
 	return nil
 }
 
+func (r *RedisStateProvider) Watch(ctx context.Context, request states.WatchRequest) (<-chan states.WatchEvent, error) {
+	objectType, err := getObjectTypePrefixForList(request.Metadata)
+	if err != nil {
+		rLog.ErrorfCtx(ctx, "  P (Redis State): watch states failed to get key prefix with error %s", err.Error())
+		return nil, err
+	}
+	namespace := ""
+	if n, ok := request.Metadata["namespace"]; ok {
+		if nstring, ok := n.(string); ok {
+			namespace = nstring
+		}
+	}
+	rLog.DebugfCtx(ctx, "  P (Redis State): watch states of %s in namespace %s", objectType, namespace)
+
+	pubsub := r.Client.Subscribe(r.Ctx, watchChannelPrefix+separator+objectType)
+	// wait for the subscription so that no change made after Watch returns is missed
+	if _, err = pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		rLog.ErrorfCtx(ctx, "  P (Redis State): failed to subscribe to changes of %s: %+v", objectType, err)
+		return nil, v1alpha2.NewCOAError(err, fmt.Sprintf("failed to watch %s", objectType), v1alpha2.InternalError)
+	}
+	ret := make(chan states.WatchEvent)
+	go func() {
+		defer close(ret)
+		defer pubsub.Close()
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				var message watchMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
+					rLog.Errorf("  P (Redis State): failed to parse change of %s: %+v", objectType, err)
+					continue
+				}
+				if namespace != "" && namespace != message.Namespace {
+					continue
+				}
+				entry, err := CastRedisPropertiesToStateEntry(message.ID, map[string]string{
+					"values": message.Values,
+					"etag":   message.ETag,
+				})
+				if err != nil {
+					rLog.Errorf("  P (Redis State): failed to cast entry %s: %+v", message.ID, err)
+					continue
+				}
+				if request.FilterType != "" && request.FilterValue != "" {
+					if match, err := states.MatchFilter(entry, request.FilterType, request.FilterValue); err != nil || !match {
+						continue
+					}
+				}
+				select {
+				case ret <- states.WatchEvent{Type: message.Type, Entry: entry, ResourceVersion: entry.ETag}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ret, nil
+}
+
+func (r *RedisStateProvider) publish(ctx context.Context, metadata map[string]interface{}, eventType string, id string, etag string, values string) {
+	objectType, err := getObjectTypePrefixForList(metadata)
+	if err != nil {
+		return
+	}
+	data, _ := json.Marshal(watchMessage{
+		Type:      eventType,
+		Namespace: getNamespace(metadata),
+		ID:        id,
+		ETag:      etag,
+		Values:    values,
+	})
+	if err = r.Client.Publish(r.Ctx, watchChannelPrefix+separator+objectType, data).Err(); err != nil {
+		rLog.ErrorfCtx(ctx, "  P (Redis State): failed to publish change of %s: %+v", id, err)
+	}
+}
+
+// SupportsConditionalUpsert is true, as upserts with an ETag are conditional
+func (r *RedisStateProvider) SupportsConditionalUpsert() bool {
+	return true
+}
+
 func (r *RedisStateProvider) Get(ctx context.Context, request states.GetRequest) (states.StateEntry, error) {
 	ctx, span := observability.StartSpan("Redis State Provider", ctx, &map[string]string{
 		"method": "Get",
@@ -314,13 +561,18 @@ func toRedisStateProviderConfig(config providers.IProviderConfig) (RedisStatePro
 	return ret, err
 }
 
-func getKeyNamePrefix(metadata map[string]interface{}) (string, error) {
+func getNamespace(metadata map[string]interface{}) string {
 	namespace := "default"
 	if n, ok := metadata["namespace"]; ok {
 		if nstring, ok := n.(string); ok && nstring != "" {
 			namespace = nstring
 		}
 	}
+	return namespace
+}
+
+func getKeyNamePrefix(metadata map[string]interface{}) (string, error) {
+	namespace := getNamespace(metadata)
 	// Construct object type
 	objectType, err := getObjectTypePrefixForList(metadata)
 	if err != nil {
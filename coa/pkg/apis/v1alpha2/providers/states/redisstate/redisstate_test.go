@@ -12,6 +12,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	states "github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2/providers/states"
 	"github.com/stretchr/testify/assert"
@@ -879,3 +880,132 @@ func TestMultipleLabelsFilter(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+func TestWatch(t *testing.T) {
+	provider := initializeProvider(t)
+	metadata := map[string]interface{}{
+		"resource":  "watchresource",
+		"group":     "testgroup",
+		"namespace": "edge",
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := provider.Watch(ctx, states.WatchRequest{
+		Metadata: metadata,
+	})
+	assert.Nil(t, err)
+
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value: states.StateEntry{
+			ID:   "w1",
+			Body: TestPayload{Name: "Random name", Value: 12345},
+			ETag: "1",
+		},
+		Metadata: metadata,
+	})
+	assert.Nil(t, err)
+	err = provider.Delete(context.Background(), states.DeleteRequest{
+		ID:       "w1",
+		Metadata: metadata,
+	})
+	assert.Nil(t, err)
+
+	event := <-events
+	assert.Equal(t, states.WatchAdded, event.Type)
+	assert.Equal(t, "w1", event.Entry.ID)
+	assert.Equal(t, "1", event.ResourceVersion)
+	event = <-events
+	assert.Equal(t, states.WatchDeleted, event.Type)
+	assert.Equal(t, "w1", event.Entry.ID)
+}
+
+func TestListPaging(t *testing.T) {
+	provider := initializeProvider(t)
+	metadata := map[string]interface{}{
+		"resource":  "pagingresource",
+		"group":     "testgroup",
+		"namespace": "default",
+	}
+	for i, id := range []string{"p3", "p1", "p2"} {
+		_, err := provider.Upsert(context.Background(), states.UpsertRequest{
+			Value: states.StateEntry{
+				ID:   id,
+				Body: TestPayload{Name: id, Value: i},
+			},
+			Metadata: metadata,
+		})
+		assert.Nil(t, err)
+	}
+	defer func() {
+		for _, id := range []string{"p1", "p2", "p3"} {
+			provider.Delete(context.Background(), states.DeleteRequest{ID: id, Metadata: metadata})
+		}
+	}()
+
+	entries, next, err := provider.List(context.Background(), states.ListRequest{
+		Metadata:    metadata,
+		ListOptions: states.ListOptions{Limit: 2},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, "p1", entries[0].ID)
+	assert.Equal(t, "p2", entries[1].ID)
+	entries, next, err = provider.List(context.Background(), states.ListRequest{
+		Metadata:    metadata,
+		ListOptions: states.ListOptions{Limit: 2, Continue: next},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "p3", entries[0].ID)
+	assert.Equal(t, "", next)
+
+	entries, _, err = provider.List(context.Background(), states.ListRequest{
+		Metadata:    metadata,
+		ListOptions: states.ListOptions{Limit: 1, SortBy: "-Value"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "p2", entries[0].ID)
+}
+
+func TestUpsertWithETag(t *testing.T) {
+	server := miniredis.RunT(t)
+	provider := RedisStateProvider{}
+	err := provider.Init(RedisStateProviderConfig{
+		Name: "test",
+		Host: server.Addr(),
+	})
+	assert.Nil(t, err)
+	metadata := map[string]interface{}{
+		"resource": "etagresource",
+		"group":    "testgroup",
+	}
+	empty := ""
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "e1", Body: TestPayload{Name: "a"}},
+		ETag:     &empty,
+		Metadata: metadata,
+	})
+	assert.Nil(t, err)
+	entry, err := provider.Get(context.Background(), states.GetRequest{ID: "e1", Metadata: metadata})
+	assert.Nil(t, err)
+	assert.Equal(t, "1", entry.ETag)
+
+	// only one of two writers that read the same entry can write it
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "e1", Body: TestPayload{Name: "b"}},
+		ETag:     &entry.ETag,
+		Metadata: metadata,
+	})
+	assert.Nil(t, err)
+	_, err = provider.Upsert(context.Background(), states.UpsertRequest{
+		Value:    states.StateEntry{ID: "e1", Body: TestPayload{Name: "c"}},
+		ETag:     &entry.ETag,
+		Metadata: metadata,
+	})
+	assert.Equal(t, v1alpha2.Conflict, err.(v1alpha2.COAError).State)
+	entry, err = provider.Get(context.Background(), states.GetRequest{ID: "e1", Metadata: metadata})
+	assert.Nil(t, err)
+	assert.Equal(t, "2", entry.ETag)
+	assert.Equal(t, "b", entry.Body.(map[string]interface{})["name"])
+}
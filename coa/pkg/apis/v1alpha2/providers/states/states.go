@@ -32,6 +32,37 @@ type IStateProvider interface {
 	List(context.Context, ListRequest) ([]StateEntry, string, error)
 	SetContext(context *contexts.ManagerContext)
 }
+
+const (
+	WatchAdded    = "ADDED"
+	WatchModified = "MODIFIED"
+	WatchDeleted  = "DELETED"
+)
+
+// IWatchableStateProvider is implemented by state providers that can stream changes
+// instead of being polled with List
+type IWatchableStateProvider interface {
+	IStateProvider
+	// Watch sends an event for every entry added, updated or deleted after the call. The
+	// channel is closed when ctx is done, or when the watcher falls behind and the caller
+	// has to list and watch again.
+	Watch(context.Context, WatchRequest) (<-chan WatchEvent, error)
+}
+
+// IConditionalStateProvider is implemented by state providers that honour the ETag of upserts:
+// an entry is only written if its ETag is still the expected one, which is empty for entries
+// that don't exist yet. Managers that keep concurrent writers apart this way require it.
+type IConditionalStateProvider interface {
+	IStateProvider
+	SupportsConditionalUpsert() bool
+}
+
+// SupportsConditionalUpsert tells if a state provider honours the ETag of upserts
+func SupportsConditionalUpsert(provider IStateProvider) bool {
+	conditional, ok := provider.(IConditionalStateProvider)
+	return ok && conditional.SupportsConditionalUpsert()
+}
+
 type GetOption struct {
 	Consistency string `json:"consistency"` //eventual or strong
 }
@@ -65,6 +96,29 @@ type ListRequest struct {
 	FilterType  string                 `json:"filterType"`
 	FilterValue string                 `json:"filterValue"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	ListOptions
+}
+
+// ListOptions selects a page of the entries returned by List
+type ListOptions struct {
+	// Limit is the maximum number of entries returned, or 0 for all of them
+	Limit int `json:"limit,omitempty"`
+	// Continue is the token returned by the previous List call, to get the next page
+	Continue string `json:"continue,omitempty"`
+	// SortBy is "name" (the default) or a dot-separated field such as "spec.displayName".
+	// A leading "-" sorts in descending order.
+	SortBy string `json:"sortBy,omitempty"`
+}
+type WatchRequest struct {
+	FilterType  string                 `json:"filterType"`
+	FilterValue string                 `json:"filterValue"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+type WatchEvent struct {
+	Type string `json:"type"`
+	// Entry is the entry after the change, or the last known entry when it's deleted
+	Entry           StateEntry `json:"entry"`
+	ResourceVersion string     `json:"resourceVersion,omitempty"`
 }
 
 func GetObjectState(ctx context.Context, stateProvider IStateProvider, resourceType validation.ResourceType, name string, namespace string) (interface{}, error) {
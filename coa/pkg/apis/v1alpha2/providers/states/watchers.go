@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package states
+
+import (
+	"context"
+	"sync"
+)
+
+const watchBufferSize = 100
+
+// Watchers fans the changes of an in-process state store out to its watchers
+type Watchers struct {
+	lock     sync.Mutex
+	watchers map[*watcher]struct{}
+}
+
+type watcher struct {
+	request WatchRequest
+	ch      chan WatchEvent
+}
+
+// Add registers a watcher that's removed when ctx is done
+func (w *Watchers) Add(ctx context.Context, request WatchRequest) <-chan WatchEvent {
+	wt := &watcher{
+		request: request,
+		ch:      make(chan WatchEvent, watchBufferSize),
+	}
+	w.lock.Lock()
+	if w.watchers == nil {
+		w.watchers = make(map[*watcher]struct{})
+	}
+	w.watchers[wt] = struct{}{}
+	w.lock.Unlock()
+	go func() {
+		<-ctx.Done()
+		w.remove(wt)
+	}()
+	return wt.ch
+}
+
+// Notify sends an event to the watchers that match the metadata of the changed entry.
+// It never blocks: a watcher whose buffer is full is closed.
+func (w *Watchers) Notify(metadata map[string]interface{}, event WatchEvent) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for wt := range w.watchers {
+		if !matchWatch(wt.request, metadata, event.Entry) {
+			continue
+		}
+		select {
+		case wt.ch <- event:
+		default:
+			delete(w.watchers, wt)
+			close(wt.ch)
+		}
+	}
+}
+
+func (w *Watchers) remove(wt *watcher) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if _, ok := w.watchers[wt]; ok {
+		delete(w.watchers, wt)
+		close(wt.ch)
+	}
+}
+
+func matchWatch(request WatchRequest, metadata map[string]interface{}, entry StateEntry) bool {
+	// An empty namespace in the request watches all namespaces
+	if namespace := readString(request.Metadata, "namespace"); namespace != "" {
+		entryNamespace := readString(metadata, "namespace")
+		if entryNamespace == "" {
+			entryNamespace = "default"
+		}
+		if namespace != entryNamespace {
+			return false
+		}
+	}
+	for _, key := range []string{"group", "resource"} {
+		if v := readString(request.Metadata, key); v != "" && v != readString(metadata, key) {
+			return false
+		}
+	}
+	if request.FilterType != "" && request.FilterValue != "" {
+		match, err := MatchFilter(entry, request.FilterType, request.FilterValue)
+		if err != nil || !match {
+			return false
+		}
+	}
+	return true
+}
+
+func readString(metadata map[string]interface{}, key string) string {
+	if v, ok := metadata[key]; ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Verbs of access requests
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+)
+
+// RBACPolicy grants access to resources. A request is allowed when one of the rules grants it;
+// everything else is denied.
+type RBACPolicy struct {
+	Rules []RBACRule `json:"rules"`
+}
+
+// RBACRule grants the verbs on the resources to its subjects. "*" matches any verb, resource,
+// namespace or name.
+type RBACRule struct {
+	Subjects  []RBACSubject `json:"subjects"`
+	Verbs     []string      `json:"verbs"`
+	Resources []string      `json:"resources"`
+	// Namespaces limits the rule to these namespaces. A rule without namespaces applies to
+	// all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Names limits the rule to the objects with these names
+	Names []string `json:"names,omitempty"`
+	// LabelSelector limits the rule to objects with these labels. As the labels of an object
+	// are only known when it's written, a rule with a label selector only grants "update", and
+	// only if both the stored object, when there is one, and the written object have them.
+	LabelSelector map[string]string `json:"labelSelector,omitempty"`
+	// Parameters limits the rule to requests with these query parameters, for endpoints
+	// whose object isn't named by the route
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// RBACSubject is a role, or a user, that a rule applies to. "*" matches any authenticated
+// caller.
+type RBACSubject struct {
+	Role string `json:"role,omitempty"`
+	User string `json:"user,omitempty"`
+}
+
+// AccessRequest is a request of a caller to access a resource
+type AccessRequest struct {
+	User      string            `json:"user,omitempty"`
+	Roles     []string          `json:"roles,omitempty"`
+	Verb      string            `json:"verb"`
+	Resource  string            `json:"resource"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	// Parameters are the query parameters of the request
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Stored is the object that a write replaces, or nil if it isn't known
+	Stored *StoredObject `json:"stored,omitempty"`
+}
+
+// StoredObject describes the stored object that a write replaces, so that label selectors can't
+// be met by relabeling an object
+type StoredObject struct {
+	Exists bool              `json:"exists"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ObjectLabelsFunc reads the labels of a stored object. exists is false if there is no such
+// object.
+type ObjectLabelsFunc func(ctx context.Context, namespace string, name string) (labels map[string]string, exists bool, err error)
+
+// AccessDecision tells if a request is allowed, and why
+type AccessDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+}
+
+// AccessReview tells a caller what it can do. It has the decision of a single access request,
+// or else the rules that apply to the caller.
+type AccessReview struct {
+	User     string          `json:"user,omitempty"`
+	Roles    []string        `json:"roles,omitempty"`
+	Enforced bool            `json:"enforced"`
+	Decision *AccessDecision `json:"decision,omitempty"`
+	Rules    []RBACRule      `json:"rules,omitempty"`
+}
+
+// COAAccessPolicyKey carries the RBAC policy of the authentication middleware to request
+// handlers, in the request context
+const COAAccessPolicyKey ContextKey = "coa-access-policy"
+
+// COACallerPolicyKey carries the policy of a caller that is limited to some requests, such as an
+// enrolled target, from the authentication middleware to the bindings
+const COACallerPolicyKey ContextKey = "coa-caller-policy"
+
+// AccessPolicyFromContext returns the RBAC policy that the request was checked with, or nil
+// if there is none
+func AccessPolicyFromContext(ctx context.Context) *RBACPolicy {
+	if ctx == nil {
+		return nil
+	}
+	policy, _ := ctx.Value(COAAccessPolicyKey).(*RBACPolicy)
+	return policy
+}
+
+// Validate checks that the rules of a policy are complete and only have known verbs
+func (p *RBACPolicy) Validate() error {
+	for i, rule := range p.Rules {
+		if len(rule.Subjects) == 0 || len(rule.Verbs) == 0 || len(rule.Resources) == 0 {
+			return NewCOAError(nil, fmt.Sprintf("rule %d of the RBAC policy needs subjects, verbs and resources", i), BadConfig)
+		}
+		for _, s := range rule.Subjects {
+			if (s.Role == "") == (s.User == "") {
+				return NewCOAError(nil, fmt.Sprintf("subject of rule %d of the RBAC policy must have either a role or a user", i), BadConfig)
+			}
+		}
+		for _, v := range rule.Verbs {
+			switch v {
+			case VerbGet, VerbList, VerbUpdate, VerbDelete, "*":
+			default:
+				return NewCOAError(nil, fmt.Sprintf("verb '%s' of rule %d of the RBAC policy is not one of get, list, update, delete or *", v, i), BadConfig)
+			}
+		}
+	}
+	return nil
+}
+
+// Check tells if the policy allows a request
+func (p *RBACPolicy) Check(request AccessRequest) AccessDecision {
+	namespace := request.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+	for i, rule := range p.Rules {
+		if !rule.appliesTo(request.User, request.Roles) ||
+			!matchAny(rule.Verbs, request.Verb) ||
+			!matchResource(rule.Resources, request.Resource) ||
+			(len(rule.Namespaces) > 0 && !matchAny(rule.Namespaces, namespace)) ||
+			(len(rule.Names) > 0 && !matchAny(rule.Names, request.Name)) {
+			continue
+		}
+		if len(rule.LabelSelector) > 0 {
+			if request.Labels == nil || !matchLabels(rule.LabelSelector, request.Labels) {
+				continue
+			}
+			if request.Stored == nil || (request.Stored.Exists && !matchLabels(rule.LabelSelector, request.Stored.Labels)) {
+				continue
+			}
+		}
+		if len(rule.Parameters) > 0 && !matchLabels(rule.Parameters, request.Parameters) {
+			continue
+		}
+		return AccessDecision{Allowed: true, Reason: fmt.Sprintf("allowed by rule %d", i)}
+	}
+	return AccessDecision{Allowed: false, Reason: fmt.Sprintf("no rule allows '%s' of '%s' in namespace '%s'", request.Verb, request.Resource, namespace)}
+}
+
+// HasLabelSelectors tells if any rule of the policy has a label selector, which needs the
+// labels of stored objects to be checked
+func (p *RBACPolicy) HasLabelSelectors() bool {
+	if p == nil {
+		return false
+	}
+	for _, rule := range p.Rules {
+		if len(rule.LabelSelector) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// RulesFor returns the rules that apply to a caller, which are its effective permissions
+func (p *RBACPolicy) RulesFor(user string, roles []string) []RBACRule {
+	ret := make([]RBACRule, 0)
+	for _, rule := range p.Rules {
+		if rule.appliesTo(user, roles) {
+			ret = append(ret, rule)
+		}
+	}
+	return ret
+}
+
+func (r RBACRule) appliesTo(user string, roles []string) bool {
+	for _, s := range r.Subjects {
+		if s.User != "" && user != "" && (s.User == "*" || s.User == user) {
+			return true
+		}
+		if s.Role != "" {
+			for _, role := range roles {
+				if s.Role == "*" || s.Role == role {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if p == "*" || p == value {
+			return true
+		}
+	}
+	return false
+}
+
+// matchResource matches a resource such as "instances", or an action on a resource such as
+// "activations/approve", which "activations/*" also matches
+func matchResource(patterns []string, resource string) bool {
+	for _, p := range patterns {
+		if p == "*" || p == resource {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(p, "/*"); ok && strings.HasPrefix(resource, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLabels(selector map[string]string, labels map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
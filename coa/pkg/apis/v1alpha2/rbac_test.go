@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *RBACPolicy {
+	return &RBACPolicy{
+		Rules: []RBACRule{
+			{
+				Subjects:   []RBACSubject{{Role: "operator"}},
+				Verbs:      []string{VerbGet, VerbList, VerbUpdate},
+				Resources:  []string{"instances"},
+				Namespaces: []string{"line-3"},
+			},
+			{
+				Subjects:  []RBACSubject{{Role: "operator"}},
+				Verbs:     []string{VerbGet, VerbList},
+				Resources: []string{"targets"},
+			},
+			{
+				Subjects:      []RBACSubject{{User: "ci"}},
+				Verbs:         []string{"*"},
+				Resources:     []string{"solutions"},
+				LabelSelector: map[string]string{"owner": "ci"},
+			},
+			{
+				Subjects:  []RBACSubject{{Role: "approver"}},
+				Verbs:     []string{VerbUpdate},
+				Resources: []string{"activations/*"},
+				Names:     []string{"release"},
+			},
+			{
+				Subjects:  []RBACSubject{{User: "*"}},
+				Verbs:     []string{VerbGet},
+				Resources: []string{"auth/can-i"},
+			},
+			{
+				Subjects:   []RBACSubject{{User: "t1"}},
+				Verbs:      []string{VerbGet},
+				Resources:  []string{"solutionversion/queue"},
+				Parameters: map[string]string{"instance": "t1"},
+			},
+		},
+	}
+}
+
+func TestRBACPolicyCheck(t *testing.T) {
+	policy := testPolicy()
+	operator := []string{"operator"}
+	cases := []struct {
+		request AccessRequest
+		allowed bool
+	}{
+		{AccessRequest{Roles: operator, Verb: VerbUpdate, Resource: "instances", Namespace: "line-3"}, true},
+		{AccessRequest{Roles: operator, Verb: VerbDelete, Resource: "instances", Namespace: "line-3"}, false},
+		{AccessRequest{Roles: operator, Verb: VerbUpdate, Resource: "instances", Namespace: "line-4"}, false},
+		// requests without a namespace are to the default namespace
+		{AccessRequest{Roles: operator, Verb: VerbGet, Resource: "instances"}, false},
+		{AccessRequest{Roles: operator, Verb: VerbList, Resource: "targets", Namespace: "line-4"}, true},
+		{AccessRequest{Roles: operator, Verb: VerbUpdate, Resource: "targets"}, false},
+		{AccessRequest{Roles: []string{"reader"}, Verb: VerbGet, Resource: "targets"}, false},
+		// label selectors only match objects that are written with the labels, and that had
+		// them before if they're stored
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "ci", "app": "a"}, Stored: &StoredObject{}}, true},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "ci"}, Stored: &StoredObject{Exists: true, Labels: map[string]string{"owner": "ci"}}}, true},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "ci"}, Stored: &StoredObject{Exists: true, Labels: map[string]string{"owner": "someone"}}}, false},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "ci"}, Stored: &StoredObject{Exists: true}}, false},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "ci"}}, false},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{"owner": "someone"}, Stored: &StoredObject{}}, false},
+		{AccessRequest{User: "ci", Verb: VerbUpdate, Resource: "solutions", Labels: map[string]string{}, Stored: &StoredObject{}}, false},
+		{AccessRequest{User: "ci", Verb: VerbGet, Resource: "solutions"}, false},
+		{AccessRequest{Roles: []string{"approver"}, Verb: VerbUpdate, Resource: "activations/approve", Name: "release"}, true},
+		{AccessRequest{Roles: []string{"approver"}, Verb: VerbUpdate, Resource: "activations/approve", Name: "other"}, false},
+		{AccessRequest{Roles: []string{"approver"}, Verb: VerbUpdate, Resource: "activations", Name: "release"}, false},
+		{AccessRequest{User: "anyone", Verb: VerbGet, Resource: "auth/can-i"}, true},
+		{AccessRequest{User: "t1", Verb: VerbGet, Resource: "solutionversion/queue", Parameters: map[string]string{"instance": "t1"}}, true},
+		{AccessRequest{User: "t1", Verb: VerbGet, Resource: "solutionversion/queue", Parameters: map[string]string{"instance": "t2"}}, false},
+		{AccessRequest{User: "t1", Verb: VerbGet, Resource: "solutionversion/queue"}, false},
+		// callers without a user don't match user subjects
+		{AccessRequest{Verb: VerbGet, Resource: "auth/can-i"}, false},
+	}
+	for i, c := range cases {
+		decision := policy.Check(c.request)
+		assert.Equal(t, c.allowed, decision.Allowed, "case %d: %s", i, decision.Reason)
+		assert.NotEmpty(t, decision.Reason)
+	}
+}
+
+func TestRBACPolicyHasLabelSelectors(t *testing.T) {
+	assert.True(t, testPolicy().HasLabelSelectors())
+	assert.False(t, (&RBACPolicy{Rules: testPolicy().Rules[:2]}).HasLabelSelectors())
+	var policy *RBACPolicy
+	assert.False(t, policy.HasLabelSelectors())
+}
+
+func TestRBACPolicyRulesFor(t *testing.T) {
+	policy := testPolicy()
+	assert.Equal(t, 3, len(policy.RulesFor("alice", []string{"operator"})))
+	assert.Equal(t, 2, len(policy.RulesFor("ci", nil)))
+	assert.Equal(t, 2, len(policy.RulesFor("t1", nil)))
+	assert.Equal(t, 0, len(policy.RulesFor("", nil)))
+}
+
+func TestRBACPolicyValidate(t *testing.T) {
+	assert.Nil(t, testPolicy().Validate())
+	invalid := []RBACRule{
+		{Verbs: []string{VerbGet}, Resources: []string{"*"}},
+		{Subjects: []RBACSubject{{Role: "a"}}, Resources: []string{"*"}},
+		{Subjects: []RBACSubject{{Role: "a"}}, Verbs: []string{VerbGet}},
+		{Subjects: []RBACSubject{{}}, Verbs: []string{VerbGet}, Resources: []string{"*"}},
+		{Subjects: []RBACSubject{{Role: "a", User: "b"}}, Verbs: []string{VerbGet}, Resources: []string{"*"}},
+		{Subjects: []RBACSubject{{Role: "a"}}, Verbs: []string{"read"}, Resources: []string{"*"}},
+	}
+	for i, rule := range invalid {
+		policy := RBACPolicy{Rules: []RBACRule{rule}}
+		err := policy.Validate()
+		assert.NotNil(t, err, "case %d", i)
+		assert.Equal(t, BadConfig, err.(COAError).State)
+	}
+}
+
+func TestAccessPolicyFromContext(t *testing.T) {
+	assert.Nil(t, AccessPolicyFromContext(context.Background()))
+	policy := testPolicy()
+	ctx := context.WithValue(context.Background(), COAAccessPolicyKey, policy)
+	assert.Equal(t, policy, AccessPolicyFromContext(ctx))
+}
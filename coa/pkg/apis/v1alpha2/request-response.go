@@ -10,6 +10,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/eclipse-symphony/symphony/coa/pkg/logger/contexts"
 )
@@ -20,6 +21,19 @@ const (
 	COAFastHTTPContextKey ContextKey = "coa-fasthttp-context"
 )
 
+const (
+	// COAUserKey and COARolesKey carry the authenticated caller and its comma-separated roles
+	// from the authentication middleware to request handlers, in COARequest.Metadata
+	COAUserKey  = "__user"
+	COARolesKey = "__roles"
+	// COANamespacesKey carries the comma-separated namespaces that the caller is limited to,
+	// if it is, the same way
+	COANamespacesKey = "__namespaces"
+	// MCPSessionHeader is the header MCP clients name their session with. The HTTP binding passes
+	// it to handlers in COARequest.Metadata, and sends it back when it's in COAResponse.Metadata.
+	MCPSessionHeader = "Mcp-Session-Id"
+)
+
 type COARequest struct {
 	Context     context.Context   `json:"-"`
 	Method      string            `json:"method"`
@@ -226,6 +240,16 @@ type COAResponse struct {
 	State       State             `json:"state"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
 	RedirectUri string            `json:"redirectUri,omitempty"`
+	// Stream, when set, writes the body bit by bit after the status and headers are sent, and
+	// Body is ignored. It's only supported by the HTTP binding. It should return once writing
+	// to the client fails.
+	Stream func(w StreamWriter) `json:"-"`
+}
+
+// StreamWriter receives a streamed response body. Flush sends what has been written so far.
+type StreamWriter interface {
+	io.Writer
+	Flush() error
 }
 
 func (c COAResponse) String() string {
@@ -244,6 +268,35 @@ type Endpoint struct {
 	Route      string
 	Handler    COAHandler
 	Parameters []string
+	// Docs optionally describes the endpoint in the OpenAPI document of the host.
+	Docs *EndpointDocs
+	// ObjectLabels optionally reads the labels of the stored objects of the endpoint, which
+	// RBAC rules with a label selector need to grant writes.
+	ObjectLabels ObjectLabelsFunc
+}
+
+// EndpointDocs are hints for the OpenAPI document of an endpoint. Request and response bodies
+// are given as values of their types, such as model.InstanceState{}, which are turned into
+// schemas.
+type EndpointDocs struct {
+	Summary string
+	// Tags group the operations of the endpoint. The first segment of the route is used if
+	// they're not set.
+	Tags []string
+	// Query lists the query parameters the endpoint reads, such as "namespace".
+	Query []string
+	// Operations has hints for each method of the endpoint.
+	Operations map[string]OperationDocs
+}
+
+// OperationDocs are hints for the OpenAPI document of a method of an endpoint.
+type OperationDocs struct {
+	Summary  string
+	Request  interface{}
+	Response interface{}
+	// ListResponse, when set, is the response of the path without the optional parameters,
+	// such as the list returned when no object name is given.
+	ListResponse interface{}
 }
 
 func (e Endpoint) GetPath() string {
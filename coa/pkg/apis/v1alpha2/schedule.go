@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed 5-field cron expression (minute hour day-of-month month day-of-week)
+// evaluated in a fixed time zone.
+type CronSchedule struct {
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	Location *time.Location
+}
+
+type cronField struct {
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// day-of-week accepts 7 as an alias of Sunday
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+	cronDescriptors = map[string]string{
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+		"@monthly":  "0 0 1 * *",
+		"@weekly":   "0 0 * * 0",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@hourly":   "0 * * * *",
+	}
+)
+
+// cronSearchLimit bounds the search for the next activation time so impossible
+// expressions such as "0 0 30 2 *" terminate.
+const cronSearchLimit = 5
+
+// IsTimestampSchedule returns true if the schedule is a single RFC 3339 timestamp
+func IsTimestampSchedule(schedule string) bool {
+	_, err := time.Parse(time.RFC3339, schedule)
+	return err == nil
+}
+
+// ValidateSchedule checks that a schedule is either an RFC 3339 timestamp or a cron expression,
+// and that the time zone, if given, can be loaded.
+func ValidateSchedule(schedule string, timeZone string) error {
+	if schedule == "" || IsTimestampSchedule(schedule) {
+		return nil
+	}
+	location, err := LoadScheduleLocation(timeZone)
+	if err != nil {
+		return err
+	}
+	_, err = ParseCronSchedule(schedule, location)
+	return err
+}
+
+// NextScheduleTime returns the time a schedule fires next after the given time. A timestamp
+// schedule always returns the timestamp itself, which may be in the past.
+func NextScheduleTime(schedule string, timeZone string, after time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, schedule); err == nil {
+		return t, nil
+	}
+	location, err := LoadScheduleLocation(timeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	cron, err := ParseCronSchedule(schedule, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := cron.Next(after)
+	if next.IsZero() {
+		return time.Time{}, NewCOAError(nil, fmt.Sprintf("cron expression '%s' never fires", schedule), BadConfig)
+	}
+	return next, nil
+}
+
+// LoadScheduleLocation loads an IANA time zone name; empty means UTC
+func LoadScheduleLocation(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid time zone '%s'", timeZone), BadConfig)
+	}
+	return location, nil
+}
+
+// ParseCronSchedule parses a standard 5-field cron expression or one of the @yearly, @monthly,
+// @weekly, @daily and @hourly descriptors. An optional "CRON_TZ=<zone>" or "TZ=<zone>" prefix
+// overrides the given location.
+func ParseCronSchedule(expr string, location *time.Location) (*CronSchedule, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		i := strings.Index(spec, " ")
+		if i < 0 {
+			return nil, NewCOAError(nil, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+		}
+		zone := spec[strings.Index(spec, "=")+1 : i]
+		var err error
+		location, err = LoadScheduleLocation(zone)
+		if err != nil {
+			return nil, err
+		}
+		spec = strings.TrimSpace(spec[i:])
+	}
+	if descriptor, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, NewCOAError(nil, fmt.Sprintf("invalid cron expression '%s': expected 5 fields, got %d", expr, len(fields)), BadConfig)
+	}
+	schedule := &CronSchedule{Location: location}
+	var err error
+	if schedule.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+	}
+	if schedule.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+	}
+	if schedule.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+	}
+	if schedule.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+	}
+	if schedule.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, NewCOAError(err, fmt.Sprintf("invalid cron expression '%s'", expr), BadConfig)
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = fields[2] == "*" || fields[2] == "?"
+	schedule.dowStar = fields[4] == "*" || fields[4] == "?"
+	return schedule, nil
+}
+
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step = s
+			part = part[:i]
+		}
+		low, high := bounds.min, bounds.max
+		switch {
+		case part == "*" || part == "?":
+		case strings.Contains(part, "-"):
+			r := strings.SplitN(part, "-", 2)
+			var err error
+			if low, err = parseCronValue(r[0], bounds); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(r[1], bounds); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range '%s'", part)
+			}
+		default:
+			v, err := parseCronValue(part, bounds)
+			if err != nil {
+				return 0, err
+			}
+			low = v
+			if step == 1 {
+				high = v
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, bounds cronField) (int, error) {
+	if v, ok := bounds.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", value)
+	}
+	if v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, bounds.min, bounds.max)
+	}
+	return v, nil
+}
+
+// Next returns the first activation time strictly after t, or the zero time if the
+// expression does not fire within the search limit.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.Location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchLimit, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.Location)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.Location)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.Location)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows the usual cron rule: when both day-of-month and day-of-week are
+// restricted, a day matches if either of them does.
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
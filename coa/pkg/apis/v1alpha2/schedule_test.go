@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	base := time.Date(2024, 3, 14, 10, 17, 30, 0, time.UTC) // Thursday
+	cases := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2024, 3, 14, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 14, 10, 30, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 3, 15, 2, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC)},
+		{"0 3 * * 0", time.Date(2024, 3, 17, 3, 0, 0, 0, time.UTC)},
+		{"0 3 * * 7", time.Date(2024, 3, 17, 3, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan,jul *", time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * 1", time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 3, 14, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCronSchedule(c.expr, nil)
+		assert.Nil(t, err, c.expr)
+		assert.Equal(t, c.expected, schedule.Next(base).UTC(), c.expr)
+	}
+}
+
+func TestCronScheduleTimeZone(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+	base := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	schedule, err := ParseCronSchedule("0 2 * * *", location)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 11, 7, 0, 0, 0, time.UTC), schedule.Next(base).UTC())
+
+	schedule, err = ParseCronSchedule("CRON_TZ=Asia/Tokyo 0 2 * * *", location)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 10, 17, 0, 0, 0, time.UTC), schedule.Next(base).UTC())
+}
+
+func TestCronScheduleNeverFires(t *testing.T) {
+	schedule, err := ParseCronSchedule("0 0 30 2 *", nil)
+	assert.Nil(t, err)
+	assert.True(t, schedule.Next(time.Now()).IsZero())
+
+	_, err = NextScheduleTime("0 0 30 2 *", "", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestParseCronScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "a * * * *", "CRON_TZ=Mars/Base * * * * *"} {
+		_, err := ParseCronSchedule(expr, nil)
+		assert.NotNil(t, err, expr)
+		assert.True(t, IsBadConfig(err), expr)
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	assert.Nil(t, ValidateSchedule("", ""))
+	assert.Nil(t, ValidateSchedule("2024-01-10T12:00:00Z", ""))
+	assert.Nil(t, ValidateSchedule("0 2 * * *", "Europe/Berlin"))
+	assert.NotNil(t, ValidateSchedule("0 2 * * *", "Not/AZone"))
+	assert.NotNil(t, ValidateSchedule("tomorrow", ""))
+}
+
+func TestActivationDataResolveSchedule(t *testing.T) {
+	activationData := ActivationData{
+		Schedule: "0 2 * * *",
+	}
+	err := activationData.ResolveSchedule(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-11T02:00:00Z", activationData.Schedule)
+
+	// timestamps are left untouched
+	activationData.Schedule = "2024-01-10T12:00:00-07:00"
+	err = activationData.ResolveSchedule(time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, "2024-01-10T12:00:00-07:00", activationData.Schedule)
+}
+
+func TestActivationDataCronScheduleJSON(t *testing.T) {
+	data, err := json.Marshal(ActivationData{Schedule: "*/5 * * * *"})
+	assert.Nil(t, err)
+	var activationData ActivationData
+	err = json.Unmarshal(data, &activationData)
+	assert.Nil(t, err)
+	assert.Equal(t, "*/5 * * * *", activationData.Schedule)
+
+	err = json.Unmarshal([]byte(`{"schedule":"not a schedule"}`), &activationData)
+	assert.NotNil(t, err)
+}
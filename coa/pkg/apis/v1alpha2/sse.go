@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SSEContentType is the content type of a streamed response made of server-sent events. The HTTP
+// binding disables caching and proxy buffering for such responses.
+const SSEContentType = "text/event-stream"
+
+// SSEEvent is a server-sent event. ID and Event are optional; Data may span multiple lines.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  []byte
+}
+
+// WriteSSEEvent writes an event to a streamed response and flushes it to the client
+func WriteSSEEvent(w StreamWriter, event SSEEvent) error {
+	var buffer bytes.Buffer
+	if event.ID != "" {
+		fmt.Fprintf(&buffer, "id: %s\n", sseField(event.ID))
+	}
+	if event.Event != "" {
+		fmt.Fprintf(&buffer, "event: %s\n", sseField(event.Event))
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(string(event.Data), "\r\n", "\n"), "\n") {
+		fmt.Fprintf(&buffer, "data: %s\n", line)
+	}
+	buffer.WriteString("\n")
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// WriteSSEComment writes a comment line, which clients ignore. It keeps idle streams open and
+// finds out whether the client is gone.
+func WriteSSEComment(w StreamWriter, comment string) error {
+	if _, err := fmt.Fprintf(w, ": %s\n\n", sseField(comment)); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// sseField drops line breaks, which would end a single-line field early
+func sseField(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
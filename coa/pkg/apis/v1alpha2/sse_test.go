@@ -0,0 +1,39 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSSEEvent(t *testing.T) {
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	err := WriteSSEEvent(w, SSEEvent{ID: "1", Event: "message", Data: []byte("{\"a\":1}")})
+	assert.Nil(t, err)
+	assert.Equal(t, "id: 1\nevent: message\ndata: {\"a\":1}\n\n", buffer.String())
+}
+
+func TestWriteSSEEventMultiline(t *testing.T) {
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	err := WriteSSEEvent(w, SSEEvent{Event: "bad\nname", Data: []byte("line1\r\nline2")})
+	assert.Nil(t, err)
+	assert.Equal(t, "event: badname\ndata: line1\ndata: line2\n\n", buffer.String())
+}
+
+func TestWriteSSEComment(t *testing.T) {
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	err := WriteSSEComment(w, "keep-alive")
+	assert.Nil(t, err)
+	assert.Equal(t, ": keep-alive\n\n", buffer.String())
+}
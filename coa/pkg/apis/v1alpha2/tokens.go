@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import "context"
+
+// Principal is the caller that a token stands for
+type Principal struct {
+	User  string
+	Roles []string
+	// Namespaces limits the caller to these namespaces. It's empty if the caller isn't
+	// limited to namespaces.
+	Namespaces []string
+	// Policy limits the caller to the requests it allows, such as an enrolled target to its
+	// own routes. The bindings check it in addition to their own RBAC policy.
+	Policy *RBACPolicy
+}
+
+// AllNamespaces is the namespace of requests to all namespaces, and lets a caller limited to
+// namespaces make them
+const AllNamespaces = "*"
+
+// NamespaceAllowed tells if a request to a namespace is allowed for a caller that is limited
+// to the given namespaces. Requests without a namespace are to the "default" namespace, and
+// requests to AllNamespaces are only allowed if the caller has it.
+func NamespaceAllowed(namespaces []string, namespace string) bool {
+	if len(namespaces) == 0 {
+		return true
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	for _, n := range namespaces {
+		if n == AllNamespaces || n == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// ITokenValidator validates bearer tokens that aren't JWTs, such as API keys, or rejects JWTs
+// that are no longer valid. The authentication middleware of the bindings asks it before it
+// reads a token as a JWT.
+type ITokenValidator interface {
+	// ValidateToken returns false if the token is left to the JWT middleware, such as a token
+	// that the validator doesn't issue, and an error if it's one that isn't valid
+	ValidateToken(ctx context.Context, token string) (Principal, bool, error)
+}
+
+// TokenValidators asks each of its validators in turn, so that the tokens of several vendors
+// are accepted
+type TokenValidators []ITokenValidator
+
+func (v TokenValidators) ValidateToken(ctx context.Context, token string) (Principal, bool, error) {
+	for _, validator := range v {
+		if principal, ok, err := validator.ValidateToken(ctx, token); ok {
+			return principal, ok, err
+		}
+	}
+	return Principal{}, false, nil
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1alpha2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type prefixValidator string
+
+func (v prefixValidator) ValidateToken(ctx context.Context, token string) (Principal, bool, error) {
+	if !strings.HasPrefix(token, string(v)) {
+		return Principal{}, false, nil
+	}
+	if strings.HasSuffix(token, "bad") {
+		return Principal{}, true, errors.New("bad token")
+	}
+	return Principal{User: token}, true, nil
+}
+
+func TestTokenValidators(t *testing.T) {
+	validators := TokenValidators{prefixValidator("symk_"), prefixValidator("symt_")}
+	principal, ok, err := validators.ValidateToken(context.Background(), "symt_t1")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, "symt_t1", principal.User)
+
+	_, ok, err = validators.ValidateToken(context.Background(), "symk_bad")
+	assert.True(t, ok)
+	assert.NotNil(t, err)
+
+	_, ok, err = validators.ValidateToken(context.Background(), "eyJhbGciOi")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+}
@@ -59,6 +59,7 @@ const (
 	Updated        State = 8004
 	Deleted        State = 8005
 	// Workflow status
+	Cancelled      State = 9993
 	Running        State = 9994
 	Paused         State = 9995
 	Done           State = 9996
@@ -131,6 +132,7 @@ const (
 	TargetGetFailed                 State = 10060
 	DeleteSolutionVersionFailed            State = 10061
 	CreateSolutionVersionFailed            State = 10062
+	ApprovalRejected                State = 10063
 	GetARMDeploymentPropertyFailed  State = 10071
 	EnsureARMResourceGroupFailed    State = 10072
 	CreateARMDeploymentFailed       State = 10073
@@ -229,6 +231,8 @@ func (s State) String() string {
 		return "Updated"
 	case Deleted:
 		return "Deleted"
+	case Cancelled:
+		return "Cancelled"
 	case Running:
 		return "Running"
 	case Paused:
@@ -373,6 +377,8 @@ func (s State) String() string {
 		return "Object to Instance conversion failed"
 	case TimedOut:
 		return "Timed Out"
+	case ApprovalRejected:
+		return "Approval Rejected"
 	case TargetPropertyNotFound:
 		return "Target Property Not Found"
 	case GetComponentPropsFailed:
@@ -35,6 +35,7 @@ func TestStateString(t *testing.T) {
 		ValidateFailed:                "Validate Failed",
 		Updated:                       "Updated",
 		Deleted:                       "Deleted",
+		Cancelled:                     "Cancelled",
 		Running:                       "Running",
 		Paused:                        "Paused",
 		Done:                          "Done",
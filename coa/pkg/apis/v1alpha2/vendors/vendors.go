@@ -49,6 +49,13 @@ type ISecurityPolicyVendor interface {
 	GetSecurityPolicy() *contexts.SecurityPolicy
 }
 
+// ITokenValidatorVendor is implemented by vendors that issue tokens, such as API keys. The host
+// discovers it after initialization and passes its validator to the authentication middleware
+// of the bindings. Vendors that aren't configured to issue tokens return nil.
+type ITokenValidatorVendor interface {
+	GetTokenValidator() v1alpha2.ITokenValidator
+}
+
 type IVendorFactory interface {
 	CreateVendor(config VendorConfig) (IVendor, error)
 }
@@ -12,9 +12,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/eclipse-symphony/symphony/api/pkg/apis/v1alpha1/model"
+	"github.com/eclipse-symphony/symphony/coa/pkg/apis/v1alpha2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -214,6 +214,14 @@ type InstanceSpec struct {
 	// Now only periodic reconciliation is supported. If the interval is 0, it will only reconcile
 	// when the instance is created or updated.
 	ReconciliationPolicy *ReconciliationPolicySpec `json:"reconciliationPolicy,omitempty"`
+
+	// Optional RollbackPolicy to specify whether a failed deployment should be rolled back
+	// to the last successfully deployed state.
+	RollbackPolicy *model.RollbackPolicySpec `json:"rollbackPolicy,omitempty"`
+
+	// Optional RolloutStrategy to update the selected targets in waves instead of
+	// all at once.
+	RolloutStrategy *model.RolloutStrategySpec `json:"rolloutStrategy,omitempty"`
 }
 
 func (c InstanceSpec) DeepEquals(other InstanceSpec) bool {
@@ -261,6 +269,14 @@ func (c InstanceSpec) DeepEquals(other InstanceSpec) bool {
 		return false
 	}
 
+	if c.RollbackPolicy.IsEnabled() != other.RollbackPolicy.IsEnabled() {
+		return false
+	}
+
+	if !reflect.DeepEqual(c.RolloutStrategy, other.RolloutStrategy) {
+		return false
+	}
+
 	// check reconciliation policy
 	if c.ReconciliationPolicy == nil {
 		return other.ReconciliationPolicy == nil
@@ -390,8 +406,9 @@ type TaskSpec struct {
 	Config runtime.RawExtension `json:"config,omitempty"`
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +kubebuilder:validation:Schemaless
-	Inputs runtime.RawExtension `json:"inputs,omitempty"`
-	Target string               `json:"target,omitempty"`
+	Inputs  runtime.RawExtension `json:"inputs,omitempty"`
+	Target  string               `json:"target,omitempty"`
+	Timeout string               `json:"timeout,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -412,6 +429,11 @@ type StageSpec struct {
 	Target          string               `json:"target,omitempty"`
 	Tasks           []TaskSpec           `json:"tasks,omitempty"`
 	TaskOption      model.TaskOption     `json:"taskOption,omitempty"`
+
+	TimeZone           string                        `json:"timeZone,omitempty"`
+	MaintenanceWindows []model.MaintenanceWindowSpec `json:"maintenanceWindows,omitempty"`
+	Timeout            string                        `json:"timeout,omitempty"`
+	ForEach            string                        `json:"forEach,omitempty"`
 }
 
 // UnmarshalJSON customizes the JSON unmarshalling for StageSpec
@@ -430,11 +452,9 @@ func (s *StageSpec) UnmarshalJSON(data []byte) error {
 	s.Config = runtime.RawExtension{Raw: aux.Config}
 	s.Inputs = runtime.RawExtension{Raw: aux.Inputs}
 
-	// validate if Schedule meet RFC 3339
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return fmt.Errorf("invalid timestamp format: %v", err)
-		}
+	// validate if Schedule is a RFC 3339 timestamp or a cron expression
+	if err := v1alpha2.ValidateSchedule(s.Schedule, s.TimeZone); err != nil {
+		return fmt.Errorf("invalid schedule format: %v", err)
 	}
 	return nil
 }
@@ -442,10 +462,8 @@ func (s *StageSpec) UnmarshalJSON(data []byte) error {
 // MarshalJSON customizes the JSON marshalling for StageSpec
 func (s StageSpec) MarshalJSON() ([]byte, error) {
 	type Alias StageSpec
-	if s.Schedule != "" {
-		if _, err := time.Parse(time.RFC3339, s.Schedule); err != nil {
-			return nil, fmt.Errorf("invalid timestamp format: %v", err)
-		}
+	if err := v1alpha2.ValidateSchedule(s.Schedule, s.TimeZone); err != nil {
+		return nil, fmt.Errorf("invalid schedule format: %v", err)
 	}
 	return json.Marshal(&struct {
 		Config json.RawMessage `json:"config,omitempty"`
@@ -496,6 +514,47 @@ func (a ActivationSpec) MarshalJSON() ([]byte, error) {
 	})
 }
 
+// +kubebuilder:object:generate=true
+type RecurringActivationSpec struct {
+	CampaignVersion string `json:"campaignversion,omitempty"`
+	Stage           string `json:"stage,omitempty"`
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Inputs   runtime.RawExtension `json:"inputs,omitempty"`
+	Schedule string               `json:"schedule"`
+	TimeZone string               `json:"timeZone,omitempty"`
+	Suspend  bool                 `json:"suspend,omitempty"`
+}
+
+// UnmarshalJSON customizes the JSON unmarshalling for RecurringActivationSpec
+func (a *RecurringActivationSpec) UnmarshalJSON(data []byte) error {
+	type Alias RecurringActivationSpec
+	aux := &struct {
+		Inputs json.RawMessage `json:"inputs,omitempty"`
+		*Alias
+	}{
+		Alias: (*Alias)(a),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	a.Inputs = runtime.RawExtension{Raw: aux.Inputs}
+
+	return nil
+}
+
+// MarshalJSON customizes the JSON marshalling for RecurringActivationSpec
+func (a RecurringActivationSpec) MarshalJSON() ([]byte, error) {
+	type Alias RecurringActivationSpec
+	return json.Marshal(&struct {
+		Inputs json.RawMessage `json:"inputs,omitempty"`
+		*Alias
+	}{
+		Inputs: json.RawMessage(a.Inputs.Raw),
+		Alias:  (*Alias)(&a),
+	})
+}
+
 // +kubebuilder:object:generate=true
 type CampaignVersionSpec struct {
 	Name         string               `json:"name,omitempty"`
@@ -354,6 +354,16 @@ func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
 		*out = new(ReconciliationPolicySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RollbackPolicy != nil {
+		in, out := &in.RollbackPolicy, &out.RollbackPolicy
+		*out = new(model.RollbackPolicySpec)
+		**out = **in
+	}
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(model.RolloutStrategySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceSpec.
@@ -402,6 +412,22 @@ func (in *ReconciliationPolicySpec) DeepCopy() *ReconciliationPolicySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecurringActivationSpec) DeepCopyInto(out *RecurringActivationSpec) {
+	*out = *in
+	in.Inputs.DeepCopyInto(&out.Inputs)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecurringActivationSpec.
+func (in *RecurringActivationSpec) DeepCopy() *RecurringActivationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RecurringActivationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SidecarSpec) DeepCopyInto(out *SidecarSpec) {
 	*out = *in
@@ -475,6 +501,11 @@ func (in *StageSpec) DeepCopyInto(out *StageSpec) {
 		}
 	}
 	out.TaskOption = in.TaskOption
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]model.MaintenanceWindowSpec, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StageSpec.
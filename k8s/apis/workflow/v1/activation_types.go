@@ -20,6 +20,7 @@ type ActivationStatus struct {
 	ActivationGeneration string         `json:"activationGeneration,omitempty"`
 	UpdateTime           string         `json:"updateTime,omitempty"`
 	StageHistory         []StageStatus  `json:"stageHistory,omitempty"`
+	PauseRequested       bool           `json:"pauseRequested,omitempty"`
 }
 
 type StageStatus struct {
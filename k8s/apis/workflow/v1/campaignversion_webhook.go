@@ -94,6 +94,10 @@ func (r *CampaignVersion) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 			// if still finds nothing, we think there's no running activations
 			return false, nil
+		},
+		// look up campaignversions invoked by campaign stages
+		func(ctx context.Context, name string, namespace string) (interface{}, error) {
+			return dynamicclient.Get(ctx, validation.CampaignVersion, name, namespace)
 		})
 
 	return ctrl.NewWebhookManagedBy(mgr).
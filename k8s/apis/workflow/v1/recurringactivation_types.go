@@ -0,0 +1,45 @@
+/*
+ * Copyright (c) Microsoft Corporation.
+ * Licensed under the MIT license.
+ * SPDX-License-Identifier: MIT
+ */
+
+package v1
+
+import (
+	k8smodel "gopls-workspace/apis/model/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type RecurringActivationStatus struct {
+	LastScheduleTime string `json:"lastScheduleTime,omitempty"`
+	NextScheduleTime string `json:"nextScheduleTime,omitempty"`
+	LastActivation   string `json:"lastActivation,omitempty"`
+	ActivationCount  int    `json:"activationCount,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Last Activation",type=string,JSONPath=`.status.lastActivation`
+// RecurringActivation is the Schema for the recurringactivations API
+type RecurringActivation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   k8smodel.RecurringActivationSpec `json:"spec,omitempty"`
+	Status RecurringActivationStatus        `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// RecurringActivationList contains a list of RecurringActivation
+type RecurringActivationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RecurringActivation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RecurringActivation{}, &RecurringActivationList{})
+}